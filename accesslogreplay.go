@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// The operations of the S3 server access logs that are replayed, by the operation of s3tester they are replayed with.
+var replayedAccessLogOperations = map[string]string{
+	"REST.PUT.OBJECT":    "put",
+	"REST.GET.OBJECT":    "get",
+	"REST.HEAD.OBJECT":   "head",
+	"REST.DELETE.OBJECT": "delete",
+}
+
+// Splits a line of an S3 server access log into its fields. The time is in brackets and the request URI, referrer and
+// user agent are in quotes, they may hold spaces.
+func splitAccessLogLine(line string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ':
+			i++
+			continue
+		case '[':
+			end := indexFrom(line, i+1, ']')
+			if end < 0 {
+				return nil, errors.New("unterminated time")
+			}
+			fields = append(fields, line[i+1:end])
+			i = end + 1
+		case '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, errors.New("unterminated quoted field")
+			}
+			fields = append(fields, line[i+1:end])
+			i = end + 1
+		default:
+			end := indexFrom(line, i, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			fields = append(fields, line[i:end])
+			i = end
+		}
+	}
+	return fields, nil
+}
+
+func indexFrom(s string, from int, c byte) int {
+	for i := from; i < len(s); i++ {
+		if s[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseAccessLogLine returns the request of a line of an S3 server access log, and false if its operation isn't
+// replayed.
+func parseAccessLogLine(line string) (replayedRequest, bool, error) {
+	fields, err := splitAccessLogLine(line)
+	if err != nil {
+		return replayedRequest{}, false, err
+	}
+	// bucket owner, bucket, time, remote ip, requester, request id, operation, key, request uri, status, error code,
+	// bytes sent, object size, ...
+	if len(fields) < 13 {
+		return replayedRequest{}, false, fmt.Errorf("expected at least 13 fields but got %d", len(fields))
+	}
+	optype, ok := replayedAccessLogOperations[fields[6]]
+	if !ok || fields[7] == "-" {
+		return replayedRequest{}, false, nil
+	}
+	at, err := time.Parse("02/Jan/2006:15:04:05 -0700", fields[2])
+	if err != nil {
+		return replayedRequest{}, false, fmt.Errorf("invalid time %s", fields[2])
+	}
+	key, err := url.PathUnescape(fields[7])
+	if err != nil {
+		return replayedRequest{}, false, fmt.Errorf("invalid key %s", fields[7])
+	}
+	var size uint64
+	if fields[12] != "-" {
+		if size, err = strconv.ParseUint(fields[12], 10, 64); err != nil {
+			return replayedRequest{}, false, fmt.Errorf("invalid object size %s", fields[12])
+		}
+	}
+	return replayedRequest{op: s3op{Event: optype, Size: size, Bucket: fields[1], Key: key}, time: at}, true, nil
+}
+
+// Returns the access log files of the path, the files of a directory in the order of their names, which start with
+// the time they were written.
+func accessLogFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		return nil, errors.New("no access log files in " + path)
+	}
+	return files, nil
+}
+
+// readAccessLog sends the replayed requests of the access log to the channel in the order of the log and returns the
+// number of lines that were skipped, by operation, and that were invalid.
+func readAccessLog(r io.Reader, requests chan<- replayedRequest) (skipped map[string]int, invalid int, err error) {
+	skipped = make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() && !wasInterrupted() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		request, replayed, err := parseAccessLogLine(line)
+		if err != nil {
+			invalid++
+			continue
+		}
+		if !replayed {
+			if fields, _ := splitAccessLogLine(line); len(fields) > 6 {
+				skipped[fields[6]]++
+			}
+			continue
+		}
+		requests <- request
+	}
+	return skipped, invalid, scanner.Err()
+}
+
+// ReplayAccessLog replays the requests of the S3 server access logs of the run to the workers.
+func ReplayAccessLog(args *parameters, workerChans []*workerChan, credential *credentials.Credentials) {
+	workload := setupWorkloadParams(workerChans, args.concurrency, credential)
+	files, err := accessLogFiles(args.replayAccessLog)
+	if err != nil {
+		log.Fatalf("Failed reading the access logs %s: %v", args.replayAccessLog, err)
+	}
+
+	requests := make(chan replayedRequest, 1000)
+	go func() {
+		defer close(requests)
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				log.Fatalf("Failed reading the access log %s: %v", file, err)
+			}
+			skipped, invalid, err := readAccessLog(f, requests)
+			f.Close()
+			if err != nil {
+				log.Fatalf("Failed reading the access log %s: %v", file, err)
+			}
+			if invalid > 0 {
+				log.Printf("Skipped %d invalid lines of the access log %s", invalid, file)
+			}
+			for op, count := range skipped {
+				log.Printf("Skipped %d %s requests of the access log %s", count, op, file)
+			}
+		}
+	}()
+
+	sendReplayedRequests(args, workload, requests)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const accessLogPut = `79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be awsexamplebucket1 [06/Feb/2019:00:00:38 +0000] 192.0.2.3 79a59df900b949e55d96a1e698fbacedfd6e09d98eacf8f8d5218e7cd47ef2be 3E57427F3EXAMPLE REST.PUT.OBJECT photos/2019/cat%201.jpg "PUT /awsexamplebucket1/photos/2019/cat%201.jpg HTTP/1.1" 200 - - 65536 70 10 "-" "S3Console/0.4" - s9lzHYrFp76ZVxRcpX9+5cjAnEH2ROuNkd2BHfIa6UkFVdtjf5mKR3/eTPFvsiP/XV/VLi31234= SigV4 ECDHE-RSA-AES128-GCM-SHA256 AuthHeader awsexamplebucket1.s3.us-west-1.amazonaws.com TLSV1.2 - -`
+
+func TestSplitAccessLogLine(t *testing.T) {
+	fields, err := splitAccessLogLine(accessLogPut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 26 || fields[2] != "06/Feb/2019:00:00:38 +0000" || fields[8] != "PUT /awsexamplebucket1/photos/2019/cat%201.jpg HTTP/1.1" || fields[16] != "S3Console/0.4" {
+		t.Fatalf("Unexpected fields %q", fields)
+	}
+
+	if _, err := splitAccessLogLine(`- bucket [06/Feb/2019:00:00:38 +0000 - -`); err == nil {
+		t.Fatalf("Expected an unterminated time to be rejected")
+	}
+	if _, err := splitAccessLogLine(`- bucket [06/Feb/2019:00:00:38 +0000] - - id REST.GET.OBJECT key "GET /bucket/key HTTP/1.1`); err == nil {
+		t.Fatalf("Expected an unterminated request URI to be rejected")
+	}
+}
+
+func TestParseAccessLogLine(t *testing.T) {
+	request, replayed, err := parseAccessLogLine(accessLogPut)
+	if err != nil || !replayed {
+		t.Fatalf("Expected the put to be replayed but got %v (%v)", replayed, err)
+	}
+	expected := s3op{Event: "put", Size: 65536, Bucket: "awsexamplebucket1", Key: "photos/2019/cat 1.jpg"}
+	if request.op != expected || !request.time.Equal(time.Date(2019, 2, 6, 0, 0, 38, 0, time.UTC)) {
+		t.Fatalf("Expected %+v at 2019-02-06 00:00:38 but got %+v", expected, request)
+	}
+
+	listing := strings.Replace(strings.Replace(accessLogPut, "REST.PUT.OBJECT photos/2019/cat%201.jpg", "REST.GET.BUCKET -", 1), "65536", "-", 1)
+	if _, replayed, err := parseAccessLogLine(listing); replayed || err != nil {
+		t.Fatalf("Expected a listing not to be replayed but got %v (%v)", replayed, err)
+	}
+	if _, _, err := parseAccessLogLine("- bucket [yesterday] - - id REST.GET.OBJECT key \"GET /bucket/key HTTP/1.1\" 200 - 10 10 1 1"); err == nil {
+		t.Fatalf("Expected an invalid time to be rejected")
+	}
+
+	// the audit log of a run is replayed like the logs of the server
+	r := auditRequest(t, "GET", "/test/object-7", "GetObject", &s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("object-7")})
+	request, replayed, err = parseAccessLogLine(accessLogLine(r, r.AttemptTime))
+	if err != nil || !replayed || request.op != (s3op{Event: "get", Bucket: "test", Key: "object-7"}) {
+		t.Fatalf("Expected the get of the audit log to be replayed but got %+v %v (%v)", request, replayed, err)
+	}
+}
+
+func TestReadAccessLog(t *testing.T) {
+	listing := strings.Replace(accessLogPut, "REST.PUT.OBJECT photos/2019/cat%201.jpg", "REST.GET.BUCKET -", 1)
+	get := strings.Replace(accessLogPut, "REST.PUT.OBJECT", "REST.GET.OBJECT", 1)
+	content := strings.Join([]string{accessLogPut, listing, "", "not an access log line", get}, "\n")
+
+	requests := make(chan replayedRequest, 10)
+	skipped, invalid, err := readAccessLog(strings.NewReader(content), requests)
+	close(requests)
+	if err != nil || invalid != 1 || skipped["REST.GET.BUCKET"] != 1 {
+		t.Fatalf("Expected 1 invalid and 1 skipped line but got %d %v (%v)", invalid, skipped, err)
+	}
+	var ops []string
+	for request := range requests {
+		ops = append(ops, request.op.Event)
+	}
+	if strings.Join(ops, ",") != "put,get" {
+		t.Fatalf("Expected a put and a get in the order of the log but got %v", ops)
+	}
+}
+
+func TestAccessLogFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accesslogs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := accessLogFiles(dir); err == nil {
+		t.Fatalf("Expected a directory without logs to be rejected")
+	}
+	for _, name := range []string{"2019-02-06-00-10-00-B", "2019-02-06-00-00-00-A"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(accessLogPut+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := accessLogFiles(dir)
+	if err != nil || len(files) != 2 || filepath.Base(files[0]) != "2019-02-06-00-00-00-A" {
+		t.Fatalf("Expected the logs of the directory in order but got %v (%v)", files, err)
+	}
+
+	args, err := parse([]string{"-replay-accesslog=" + dir, "-replay-speed=10", "-concurrency=4", "-requests=4"})
+	if err != nil || args.replayAccessLog != dir || args.replaySpeed != 10 {
+		t.Fatalf("Expected the replay to parse but got %+v (%v)", args.replaySpeed, err)
+	}
+	for _, cmdline := range [][]string{
+		{"-replay-accesslog=" + dir, "-operation=get"},
+		{"-replay-accesslog=" + filepath.Join(dir, "missing")},
+		{"-replay-speed=10"},
+		{"-replay-accesslog=" + dir, "-replay-speed=-1"},
+	} {
+		if _, err := parse(cmdline); err == nil {
+			t.Fatalf("Expected %v to be rejected", cmdline)
+		}
+	}
+}
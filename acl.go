@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// cannedACL is the canned ACL of the objects written, empty if the objects get the default ACL of the bucket.
+type cannedACL string
+
+// Parses a canned ACL like public-read.
+func parseCannedACL(acl string) (cannedACL, error) {
+	if acl == "" {
+		return "", nil
+	}
+	for _, value := range s3.ObjectCannedACL_Values() {
+		if acl == value {
+			return cannedACL(acl), nil
+		}
+	}
+	return "", errors.New("acl must be one of " + strings.Join(s3.ObjectCannedACL_Values(), ", "))
+}
+
+// apply sets the canned ACL of the requests that write objects.
+func (a cannedACL) apply(params interface{}) {
+	switch p := params.(type) {
+	case *s3.PutObjectInput:
+		p.ACL = aws.String(string(a))
+	case *s3.CreateMultipartUploadInput:
+		p.ACL = aws.String(string(a))
+	case *s3.CopyObjectInput:
+		p.ACL = aws.String(string(a))
+	}
+}
+
+// install applies the canned ACL to every object written by the service.
+func (a cannedACL) install(svc *s3.S3) {
+	if a == "" {
+		return
+	}
+	svc.Client.Handlers.Validate.PushFront(func(r *request.Request) {
+		a.apply(r.Params)
+	})
+}
+
+func PutACL(svc s3iface.S3API, bucket, key string, acl cannedACL) error {
+	params := &s3.PutObjectAclInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		ACL:    aws.String(string(acl)),
+	}
+	_, err := svc.PutObjectAcl(params)
+
+	return err
+}
+
+func GetACL(svc s3iface.S3API, bucket, key string) error {
+	params := &s3.GetObjectAclInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	_, err := svc.GetObjectAcl(params)
+
+	return err
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestParseCannedACL(t *testing.T) {
+	if acl, err := parseCannedACL("public-read"); err != nil || acl != "public-read" {
+		t.Fatalf("Expected public-read but got %q: %v", acl, err)
+	}
+
+	if acl, err := parseCannedACL(""); err != nil || acl != "" {
+		t.Fatalf("Expected no ACL by default but got %q: %v", acl, err)
+	}
+
+	if _, err := parseCannedACL("public"); err == nil {
+		t.Fatalf("Unknown canned ACL should fail")
+	}
+}
+
+func TestApplyCannedACL(t *testing.T) {
+	acl := cannedACL("bucket-owner-full-control")
+
+	put := &s3.PutObjectInput{}
+	acl.apply(put)
+	create := &s3.CreateMultipartUploadInput{}
+	acl.apply(create)
+	copy := &s3.CopyObjectInput{}
+	acl.apply(copy)
+	if aws.StringValue(put.ACL) != string(acl) || aws.StringValue(create.ACL) != string(acl) || aws.StringValue(copy.ACL) != string(acl) {
+		t.Fatalf("Wrong ACLs %v %v %v", aws.StringValue(put.ACL), aws.StringValue(create.ACL), aws.StringValue(copy.ACL))
+	}
+
+	// requests that don't write objects are left alone
+	acl.apply(&s3.GetObjectInput{})
+}
+
+func (this *mockS3Client) PutObjectAcl(in *s3.PutObjectAclInput) (*s3.PutObjectAclOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.PutObjectAclOutput{}, nil
+}
+
+func (this *mockS3Client) GetObjectAcl(in *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.GetObjectAclOutput{}, nil
+}
+
+func TestPutACLOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutObjectAclInput)
+
+		if *i.Bucket != "b" || *i.Key != "k1" {
+			t.Fatalf("Expected object b/k1 but got: %s/%s", *i.Bucket, *i.Key)
+		}
+
+		if *i.ACL != "public-read" {
+			t.Fatalf("Expected ACL public-read but got: %s", *i.ACL)
+		}
+
+		return in
+	}
+
+	if err := PutACL(NewMockS3Client(handler), "b", "k1", "public-read"); err != nil {
+		t.Fatalf("Failed PUT ACL operation with error: %v", err)
+	}
+}
+
+func TestGetACLOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.GetObjectAclInput)
+
+		if *i.Bucket != "b" || *i.Key != "k1" {
+			t.Fatalf("Expected object b/k1 but got: %s/%s", *i.Bucket, *i.Key)
+		}
+
+		return in
+	}
+
+	if err := GetACL(NewMockS3Client(handler), "b", "k1"); err != nil {
+		t.Fatalf("Failed GET ACL operation with error: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// arrivalSchedule paces the starts of the requests of all workers at a fixed rate, independent of how long the
+// requests take. Request n is due at start + n/rate, a worker that is free before the next request is due waits for
+// it, a request that is due while all workers are busy is started late. The latency of the requests is measured from
+// when they were due, so that the time requests queued behind slow ones is part of it.
+type arrivalSchedule struct {
+	start time.Time
+	// time between two requests
+	interval time.Duration
+	next     int64
+}
+
+// Returns a schedule of the given number of requests per second starting now. Returns nil if the rate is 0.
+func NewArrivalSchedule(rate float64) *arrivalSchedule {
+	if rate == 0 {
+		return nil
+	}
+	return &arrivalSchedule{start: time.Now(), interval: time.Duration(float64(time.Second) / rate)}
+}
+
+// wait takes the next request of the schedule, waits until it is due and returns when it was due.
+func (a *arrivalSchedule) wait() time.Time {
+	n := atomic.AddInt64(&a.next, 1) - 1
+	due := a.start.Add(time.Duration(n) * a.interval)
+	if d := time.Until(due); d > 0 {
+		time.Sleep(d)
+	}
+	return due
+}
+
+// recordScheduleLag records how long after it was due a request of the arrival schedule was started.
+func (this *result) recordScheduleLag(lag time.Duration) {
+	if this.ScheduleLag == nil {
+		this.ScheduleLag = NewLatencyResult()
+	}
+	this.ScheduleLag.record(lag)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestArrivalSchedule(t *testing.T) {
+	if NewArrivalSchedule(0) != nil {
+		t.Fatalf("Expected no schedule without a rate")
+	}
+
+	schedule := NewArrivalSchedule(1000)
+	var mu sync.Mutex
+	var due []time.Time
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				d := schedule.wait()
+				if time.Now().Before(d) {
+					t.Errorf("Request started before it was due")
+				}
+				mu.Lock()
+				due = append(due, d)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].Before(due[j]) })
+	for i := 1; i < len(due); i++ {
+		if gap := due[i].Sub(due[i-1]); gap != time.Millisecond {
+			t.Fatalf("Expected requests to be due every millisecond but %d was due %s after the one before", i, gap)
+		}
+	}
+	if elapsed := time.Since(schedule.start); elapsed < 99*time.Millisecond {
+		t.Fatalf("100 requests at 1000/s should take at least 99ms but took %s", elapsed)
+	}
+}
+
+func TestArrivalScheduleBehind(t *testing.T) {
+	// all workers were busy for a second
+	schedule := &arrivalSchedule{start: time.Now().Add(-time.Second), interval: time.Millisecond}
+	r := NewResult()
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		due := schedule.wait()
+		if time.Since(start) > 100*time.Millisecond {
+			t.Fatalf("Requests that are overdue should start right away")
+		}
+		r.recordScheduleLag(time.Since(due))
+	}
+	r.ScheduleLag.setupStats()
+	if r.ScheduleLag.Count != 10 || r.ScheduleLag.AverageRequestTime < 990 {
+		t.Fatalf("Expected 10 requests at least 990ms late but got: %+v", r.ScheduleLag)
+	}
+}
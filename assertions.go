@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// statusAssertion is an expected outcome of the requests of a workload stage: the share of its responses with an HTTP
+// status, like 204 for at least 99.9% of the deletes or 404 for every get of keys that don't exist.
+type statusAssertion struct {
+	Status int `json:"status"`
+	// percentages of the responses, the status is expected for every response if neither is given
+	AtLeast *float64 `json:"atLeast"`
+	AtMost  *float64 `json:"atMost"`
+}
+
+func (a statusAssertion) validate() error {
+	if a.Status < 100 || a.Status > 599 {
+		return fmt.Errorf("assertion status %d is not an HTTP status", a.Status)
+	}
+	for _, percent := range []*float64{a.AtLeast, a.AtMost} {
+		if percent != nil && (*percent < 0 || *percent > 100) {
+			return errors.New("assertion atLeast and atMost must be percentages between 0 and 100")
+		}
+	}
+	if a.AtLeast != nil && a.AtMost != nil && *a.AtLeast > *a.AtMost {
+		return errors.New("assertion atLeast must not be more than atMost")
+	}
+	return nil
+}
+
+func (a statusAssertion) String() string {
+	switch {
+	case a.AtLeast != nil && a.AtMost != nil:
+		return fmt.Sprintf("status %d for %g%% to %g%% of the responses", a.Status, *a.AtLeast, *a.AtMost)
+	case a.AtLeast != nil:
+		return fmt.Sprintf("status %d for at least %g%% of the responses", a.Status, *a.AtLeast)
+	case a.AtMost != nil:
+		return fmt.Sprintf("status %d for at most %g%% of the responses", a.Status, *a.AtMost)
+	}
+	return fmt.Sprintf("status %d for every response", a.Status)
+}
+
+// check returns the percentage of the responses with the status and whether it is as expected. A stage without
+// responses fails every assertion.
+func (a statusAssertion) check(statusCodes map[int]int) (float64, bool) {
+	total := 0
+	for _, count := range statusCodes {
+		total += count
+	}
+	if total == 0 {
+		return 0, false
+	}
+	share := 100 * float64(statusCodes[a.Status]) / float64(total)
+	if a.AtLeast == nil && a.AtMost == nil {
+		return share, statusCodes[a.Status] == total
+	}
+	return share, (a.AtLeast == nil || share >= *a.AtLeast) && (a.AtMost == nil || share <= *a.AtMost)
+}
+
+// assertionOutcome is the outcome of an assertion of a workload stage.
+type assertionOutcome struct {
+	Stage     string  `json:"stage"`
+	Assertion string  `json:"assertion"`
+	Share     float64 `json:"share (%)"`
+	Responses int     `json:"responses"`
+	Passed    bool    `json:"passed"`
+}
+
+// checkAssertions returns the outcomes of the assertions of the stage on the responses of its run.
+func (s *workloadStage) checkAssertions(statusCodes map[int]int) []assertionOutcome {
+	responses := 0
+	for _, count := range statusCodes {
+		responses += count
+	}
+	outcomes := make([]assertionOutcome, 0, len(s.Assert))
+	for _, a := range s.Assert {
+		share, passed := a.check(statusCodes)
+		outcomes = append(outcomes, assertionOutcome{Stage: s.Name, Assertion: a.String(), Share: share, Responses: responses, Passed: passed})
+	}
+	return outcomes
+}
+
+func assertionFailures(outcomes []assertionOutcome) int {
+	failures := 0
+	for _, o := range outcomes {
+		if !o.Passed {
+			failures++
+		}
+	}
+	return failures
+}
+
+// recordStatusCodes counts the HTTP status of the response to every request the service completes, 0 for requests
+// that got no response.
+func (this *result) recordStatusCodes(svc *s3.S3) {
+	this.StatusCodes = make(map[int]int)
+	// parts of multipart operations are sent concurrently
+	var mu sync.Mutex
+	svc.Client.Handlers.Complete.PushBack(func(r *request.Request) {
+		status := 0
+		if r.HTTPResponse != nil {
+			status = r.HTTPResponse.StatusCode
+		}
+		mu.Lock()
+		this.StatusCodes[status]++
+		mu.Unlock()
+	})
+}
+
+func printAssertionReport(outcomes []assertionOutcome, isJson bool) {
+	if isJson {
+		jsonReport, err := json.Marshal(map[string][]assertionOutcome{"assertions": outcomes})
+		if err != nil {
+			fmt.Println("Error when parsing assertion report to json")
+			return
+		}
+		fmt.Println(string(jsonReport))
+		return
+	}
+
+	fmt.Println("\n\t--- Assertions ---")
+	for _, o := range outcomes {
+		verdict := "passed"
+		if !o.Passed {
+			verdict = "FAILED"
+		}
+		fmt.Printf("%s: %s: %s (%.3f%% of %d responses)\n", verdict, o.Stage, o.Assertion, o.Share, o.Responses)
+	}
+	fmt.Printf("%d of %d assertions failed\n", assertionFailures(outcomes), len(outcomes))
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func percent(p float64) *float64 {
+	return &p
+}
+
+func TestStatusAssertionCheck(t *testing.T) {
+	statusCodes := map[int]int{204: 999, 503: 1}
+	tests := []struct {
+		assertion statusAssertion
+		passed    bool
+	}{
+		{statusAssertion{Status: 204, AtLeast: percent(99.9)}, true},
+		{statusAssertion{Status: 204, AtLeast: percent(99.95)}, false},
+		{statusAssertion{Status: 204}, false},
+		{statusAssertion{Status: 503, AtMost: percent(0.1)}, true},
+		{statusAssertion{Status: 503, AtMost: percent(0.05)}, false},
+		{statusAssertion{Status: 404, AtMost: percent(0)}, true},
+		{statusAssertion{Status: 204, AtLeast: percent(50), AtMost: percent(99)}, false},
+	}
+	for _, test := range tests {
+		if _, passed := test.assertion.check(statusCodes); passed != test.passed {
+			t.Fatalf("Expected %s to pass: %v", test.assertion, test.passed)
+		}
+	}
+
+	if share, passed := (statusAssertion{Status: 404}).check(map[int]int{404: 20}); !passed || share != 100 {
+		t.Fatalf("Expected a stage of 404s only to pass but got %.1f%%", share)
+	}
+	if _, passed := (statusAssertion{Status: 404, AtMost: percent(100)}).check(nil); passed {
+		t.Fatalf("Expected a stage without responses to fail its assertions")
+	}
+}
+
+func TestStatusAssertionValidate(t *testing.T) {
+	for _, a := range []statusAssertion{{Status: 0}, {Status: 204, AtLeast: percent(101)}, {Status: 204, AtMost: percent(-1)}, {Status: 204, AtLeast: percent(60), AtMost: percent(50)}} {
+		if a.validate() == nil {
+			t.Fatalf("Expected %+v to be rejected", a)
+		}
+	}
+	if err := (statusAssertion{Status: 204, AtLeast: percent(99.9)}).validate(); err != nil {
+		t.Fatalf("Expected a valid assertion but got: %v", err)
+	}
+}
+
+func TestStageAssertions(t *testing.T) {
+	path := writeWorkloadFile(t, `{"stages": [
+		{"name": "delete", "operation": "delete", "keys": "0-999", "assert": [{"status": 204, "atLeast": 99.9}]},
+		{"name": "get-miss", "operation": "get", "keys": "0-999", "assert": [{"status": 404}]},
+		{"name": "get", "operation": "get", "keys": "0-999"}
+	]}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	args, err := parse([]string{"-workload=" + path})
+	if err != nil || len(args.stages) != 3 {
+		t.Fatalf("Expected 3 stages but got %v (%v)", args.stages, err)
+	}
+	if !args.stages[0].args.statusCodes || !args.stages[1].args.statusCodes || args.stages[2].args.statusCodes {
+		t.Fatalf("Expected the responses of the stages with assertions to be counted")
+	}
+
+	outcomes := args.stages[0].checkAssertions(map[int]int{204: 998, 500: 2})
+	outcomes = append(outcomes, args.stages[1].checkAssertions(map[int]int{404: 1000})...)
+	if len(outcomes) != 2 || outcomes[0].Passed || !outcomes[1].Passed || outcomes[0].Responses != 1000 || outcomes[0].Share != 99.8 {
+		t.Fatalf("Unexpected outcomes %+v", outcomes)
+	}
+	if assertionFailures(outcomes) != 1 {
+		t.Fatalf("Expected 1 failed assertion")
+	}
+
+	invalid := writeWorkloadFile(t, `{"stages": [{"name": "bad", "operation": "get", "assert": [{"status": 204, "atLeast": 120}]}]}`)
+	defer os.RemoveAll(filepath.Dir(invalid))
+	if _, err := parse([]string{"-workload=" + invalid}); err == nil {
+		t.Fatalf("Expected an invalid assertion to be rejected")
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// audit writes every request the run sends to the audit log given with -audit-log, nil otherwise.
+var audit *auditLog
+
+// The operations of the S3 server access logs of the requests of the SDK. Others are logged as REST.<method>.OBJECT or
+// REST.<method>.BUCKET.
+var accessLogOperations = map[string]string{
+	"AbortMultipartUpload":    "REST.DELETE.UPLOAD",
+	"CompleteMultipartUpload": "REST.POST.UPLOAD",
+	"CopyObject":              "REST.COPY.OBJECT",
+	"CreateMultipartUpload":   "REST.POST.UPLOADS",
+	"DeleteObjectTagging":     "REST.DELETE.OBJECT_TAGGING",
+	"DeleteObjects":           "REST.POST.MULTI_OBJECT_DELETE",
+	"GetBucketVersioning":     "REST.GET.VERSIONING",
+	"GetObjectAcl":            "REST.GET.ACL",
+	"GetObjectLegalHold":      "REST.GET.LEGAL_HOLD",
+	"GetObjectRetention":      "REST.GET.RETENTION",
+	"GetObjectTagging":        "REST.GET.OBJECT_TAGGING",
+	"ListMultipartUploads":    "REST.GET.UPLOADS",
+	"ListObjectVersions":      "REST.GET.BUCKETVERSIONS",
+	"ListParts":               "REST.GET.UPLOAD",
+	"PutBucketLifecycle":      "REST.PUT.LIFECYCLE",
+	"PutBucketVersioning":     "REST.PUT.VERSIONING",
+	"PutObjectAcl":            "REST.PUT.ACL",
+	"PutObjectLegalHold":      "REST.PUT.LEGAL_HOLD",
+	"PutObjectRetention":      "REST.PUT.RETENTION",
+	"PutObjectTagging":        "REST.PUT.OBJECT_TAGGING",
+	"RestoreObject":           "REST.POST.RESTORE",
+	"SelectObjectContent":     "REST.POST.SELECT",
+	"UploadPart":              "REST.PUT.PART",
+	"UploadPartCopy":          "REST.COPY.PART",
+}
+
+// auditLog writes a line in the format of the S3 server access logs for every attempt of every request the client
+// sends, so that the log of the client can be compared with the logs of the server line by line, e.g. to find the
+// requests that never reached the server.
+type auditLog struct {
+	path string
+	file *os.File
+	// the workers write concurrently
+	mu     sync.Mutex
+	writer *bufio.Writer
+	err    error
+	// stops the log at the memory limit, nil without one
+	watchdog *memoryWatchdog
+}
+
+func NewAuditLog(path string) (*auditLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{path: path, file: f, writer: bufio.NewWriter(f)}, nil
+}
+
+// install logs every attempt of the requests of the service: the failed attempts once they failed and the successful
+// one once the request completed. Does nothing without an audit log.
+func (this *auditLog) install(svc *s3.S3) {
+	if this == nil {
+		return
+	}
+	svc.Client.Handlers.Retry.PushFront(func(r *request.Request) {
+		this.write(accessLogLine(r, time.Now()))
+	})
+	svc.Client.Handlers.Complete.PushBack(func(r *request.Request) {
+		// a failed request was logged by its last attempt, or was never sent
+		if r.Error == nil {
+			this.write(accessLogLine(r, time.Now()))
+		}
+	})
+}
+
+func (this *auditLog) write(line string) {
+	if this.watchdog.sheds("audit-log") {
+		return
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if _, err := this.writer.WriteString(line + "\n"); err != nil && this.err == nil {
+		this.err = err
+	}
+}
+
+// close writes the rest of the log and returns the first error writing it. Does nothing without an audit log.
+func (this *auditLog) close() error {
+	if this == nil {
+		return nil
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if err := this.writer.Flush(); err != nil && this.err == nil {
+		this.err = err
+	}
+	if err := this.file.Close(); err != nil && this.err == nil {
+		this.err = err
+	}
+	return this.err
+}
+
+// Returns the line of the S3 server access log of the attempt of a request that ended at the given time. Fields the
+// client doesn't know, like the bucket owner and the requester, are "-" as in the logs of the server.
+func accessLogLine(r *request.Request, end time.Time) string {
+	start := r.AttemptTime
+	if start.IsZero() {
+		start = end
+	}
+	bucket := paramString(r.Params, "Bucket")
+	key := paramString(r.Params, "Key")
+	status, errorCode, bytesSent, objectSize := "-", "-", "-", "-"
+	requestId, hostId, cipherSuite, tlsVersion := "-", "-", "-", "-"
+	if r.HTTPResponse != nil {
+		status = strconv.Itoa(r.HTTPResponse.StatusCode)
+		requestId = headerOrDash(r.HTTPResponse.Header, "X-Amz-Request-Id")
+		hostId = headerOrDash(r.HTTPResponse.Header, "X-Amz-Id-2")
+		if r.HTTPRequest.Method == "GET" && r.HTTPResponse.ContentLength > 0 {
+			bytesSent = strconv.FormatInt(r.HTTPResponse.ContentLength, 10)
+		}
+		if (r.HTTPRequest.Method == "GET" || r.HTTPRequest.Method == "HEAD") && key != "" && r.HTTPResponse.ContentLength >= 0 && r.Error == nil {
+			objectSize = strconv.FormatInt(r.HTTPResponse.ContentLength, 10)
+		}
+		if state := r.HTTPResponse.TLS; state != nil {
+			cipherSuite = tls.CipherSuiteName(state.CipherSuite)
+			tlsVersion = tlsVersionName(state.Version)
+		}
+	}
+	if r.HTTPRequest.Method == "PUT" && r.HTTPRequest.ContentLength > 0 {
+		objectSize = strconv.FormatInt(r.HTTPRequest.ContentLength, 10)
+	}
+	if err, ok := r.Error.(awserr.Error); ok {
+		errorCode = err.Code()
+	}
+	versionId := r.HTTPRequest.URL.Query().Get("versionId")
+	if versionId == "" {
+		versionId = "-"
+	}
+	host := r.HTTPRequest.Host
+	if host == "" {
+		host = r.HTTPRequest.URL.Host
+	}
+
+	return strings.Join([]string{
+		"-",
+		dashIfEmpty(bucket),
+		start.UTC().Format("[02/Jan/2006:15:04:05 -0700]"),
+		"-",
+		"-",
+		requestId,
+		accessLogOperation(r, key),
+		dashIfEmpty((&url.URL{Path: key}).EscapedPath()),
+		strconv.Quote(r.HTTPRequest.Method + " " + r.HTTPRequest.URL.RequestURI() + " " + r.HTTPRequest.Proto),
+		status,
+		errorCode,
+		bytesSent,
+		objectSize,
+		strconv.FormatInt(int64(end.Sub(start)/time.Millisecond), 10),
+		"-",
+		`"-"`,
+		strconv.Quote(r.HTTPRequest.Header.Get("User-Agent")),
+		versionId,
+		hostId,
+		signatureVersion(r.HTTPRequest),
+		cipherSuite,
+		authType(r.HTTPRequest),
+		host,
+		tlsVersion,
+		"-",
+		"-",
+	}, " ")
+}
+
+func accessLogOperation(r *request.Request, key string) string {
+	if r.Operation != nil {
+		if op, ok := accessLogOperations[r.Operation.Name]; ok {
+			return op
+		}
+	}
+	if key != "" {
+		return "REST." + r.HTTPRequest.Method + ".OBJECT"
+	}
+	return "REST." + r.HTTPRequest.Method + ".BUCKET"
+}
+
+// Returns the value of a string field of the parameters of a request, like its bucket or key, empty if it has none.
+func paramString(params interface{}, name string) string {
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	field := v.FieldByName(name)
+	if !field.IsValid() || field.Type() != reflect.TypeOf((*string)(nil)) {
+		return ""
+	}
+	return aws.StringValue(field.Interface().(*string))
+}
+
+func headerOrDash(header http.Header, name string) string {
+	return dashIfEmpty(header.Get(name))
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func signatureVersion(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	switch {
+	case strings.HasPrefix(authorization, "AWS4-HMAC-SHA256"), r.URL.Query().Get("X-Amz-Algorithm") != "":
+		return "SigV4"
+	case strings.HasPrefix(authorization, "AWS "), r.URL.Query().Get("Signature") != "":
+		return "SigV2"
+	}
+	return "-"
+}
+
+func authType(r *http.Request) string {
+	switch {
+	case r.Header.Get("Authorization") != "":
+		return "AuthHeader"
+	case r.URL.Query().Get("X-Amz-Signature") != "", r.URL.Query().Get("Signature") != "":
+		return "QueryString"
+	}
+	return "-"
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLSv1"
+	case tls.VersionTLS11:
+		return "TLSv1.1"
+	case tls.VersionTLS12:
+		return "TLSv1.2"
+	case tls.VersionTLS13:
+		return "TLSv1.3"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
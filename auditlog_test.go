@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func auditRequest(t *testing.T, method, uri, operation string, params interface{}) *request.Request {
+	httpRequest, err := http.NewRequest(method, "https://10.96.105.5:8082"+uri, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpRequest.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKID/20250601/us-east-1/s3/aws4_request")
+	httpRequest.Header.Set("User-Agent", "s3tester/aws-sdk-go")
+	return &request.Request{
+		Operation:   &request.Operation{Name: operation},
+		HTTPRequest: httpRequest,
+		Params:      params,
+		AttemptTime: time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestAccessLogLine(t *testing.T) {
+	r := auditRequest(t, "GET", "/test/photos/2025/cat%201.jpg?versionId=v1", "GetObject", &s3.GetObjectInput{Bucket: aws.String("test"), Key: aws.String("photos/2025/cat 1.jpg")})
+	header := http.Header{}
+	header.Set("X-Amz-Request-Id", "3E57427F3EXAMPLE")
+	header.Set("X-Amz-Id-2", "host-id")
+	r.HTTPResponse = &http.Response{StatusCode: 200, Header: header, ContentLength: 1024, TLS: &tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}}
+
+	expected := `- test [01/Jun/2025:12:30:00 +0000] - - 3E57427F3EXAMPLE REST.GET.OBJECT photos/2025/cat%201.jpg "GET /test/photos/2025/cat%201.jpg?versionId=v1 HTTP/1.1" 200 - 1024 1024 25 - "-" "s3tester/aws-sdk-go" v1 host-id SigV4 TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 AuthHeader 10.96.105.5:8082 TLSv1.2 - -`
+	if line := accessLogLine(r, r.AttemptTime.Add(25*time.Millisecond)); line != expected {
+		t.Fatalf("Expected\n%s\nbut got\n%s", expected, line)
+	}
+
+	// the server responded with an error
+	r = auditRequest(t, "PUT", "/test/object-1", "PutObject", &s3.PutObjectInput{Bucket: aws.String("test"), Key: aws.String("object-1")})
+	r.HTTPRequest.ContentLength = 4096
+	r.HTTPResponse = &http.Response{StatusCode: 503, Header: http.Header{}}
+	r.Error = awserr.New("SlowDown", "Please reduce your request rate.", nil)
+	fields := strings.Fields(accessLogLine(r, r.AttemptTime.Add(time.Second)))
+	if fields[7] != "REST.PUT.OBJECT" || fields[8] != "object-1" || fields[12] != "503" || fields[13] != "SlowDown" || fields[14] != "-" || fields[15] != "4096" || fields[16] != "1000" {
+		t.Fatalf("Unexpected fields of a failed put %v", fields)
+	}
+
+	// the request never reached the server
+	r = auditRequest(t, "POST", "/test?delete=", "DeleteObjects", &s3.DeleteObjectsInput{Bucket: aws.String("test")})
+	r.Error = awserr.New("RequestError", "send request failed", errors.New("connection reset by peer"))
+	fields = strings.Fields(accessLogLine(r, r.AttemptTime))
+	if fields[7] != "REST.POST.MULTI_OBJECT_DELETE" || fields[8] != "-" || fields[12] != "-" || fields[13] != "RequestError" || fields[5] != "-" {
+		t.Fatalf("Unexpected fields of a request without a response %v", fields)
+	}
+
+	// operations without a known name of the access logs
+	r = auditRequest(t, "HEAD", "/test", "HeadBucket", &s3.HeadBucketInput{Bucket: aws.String("test")})
+	if op := accessLogOperation(r, ""); op != "REST.HEAD.BUCKET" {
+		t.Fatalf("Expected REST.HEAD.BUCKET but got %s", op)
+	}
+}
+
+func TestAuditLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	a, err := NewAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.watchdog = NewMemoryWatchdog(64)
+	a.write("first")
+	a.write("second")
+	// the lines written before the memory limit was exceeded are kept
+	a.watchdog.check(65 << 20)
+	a.write("third")
+	if err := a.close(); err != nil {
+		t.Fatalf("Expected the audit log to be written but got: %v", err)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if string(data) != "first\nsecond\n" {
+		t.Fatalf("Unexpected audit log %q", data)
+	}
+
+	var none *auditLog
+	none.install(nil)
+	if err := none.close(); err != nil {
+		t.Fatalf("Expected no audit log to close without error but got: %v", err)
+	}
+}
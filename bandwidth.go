@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// The most bytes a throttled body reads at once, and the burst of the bandwidth limiters.
+const bandwidthBurst = 64 << 10
+
+// Units of bandwidths in bytes per second.
+var bandwidthUnits = map[string]float64{
+	"B":    1,
+	"KB":   1e3,
+	"MB":   1e6,
+	"GB":   1e9,
+	"KIB":  1 << 10,
+	"MIB":  1 << 20,
+	"GIB":  1 << 30,
+	"KBIT": 1e3 / 8,
+	"MBIT": 1e6 / 8,
+	"GBIT": 1e9 / 8,
+}
+
+// Parses a bandwidth like 500MB/s, 1GiB/s or 100Mbit/s into bytes per second. A number without a unit is bytes per
+// second. Returns 0 for an empty bandwidth.
+func parseBandwidth(bandwidth string) (float64, error) {
+	if bandwidth == "" {
+		return 0, nil
+	}
+	value := strings.TrimSuffix(strings.TrimSpace(bandwidth), "/s")
+	i := strings.IndexFunc(value, func(c rune) bool { return (c < '0' || c > '9') && c != '.' })
+	unit := "B"
+	if i >= 0 {
+		value, unit = value[:i], strings.ToUpper(strings.TrimSpace(value[i:]))
+	}
+	scale, ok := bandwidthUnits[unit]
+	number, err := strconv.ParseFloat(value, 64)
+	if !ok || err != nil || number <= 0 {
+		return 0, errors.New("must be like 500MB/s, 1GiB/s or 100Mbit/s: " + bandwidth)
+	}
+	return number * scale, nil
+}
+
+// Returns a limiter of the given bytes per second, or nil if the bandwidth is 0.
+func NewBandwidthLimiter(bytesPerSecond float64) *rate.Limiter {
+	if bytesPerSecond == 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), bandwidthBurst)
+}
+
+// throttledTransport limits the bandwidth of the bodies of the requests sent and of the responses read through it to
+// every one of its limiters, e.g. one shared by all workers and one of the worker.
+type throttledTransport struct {
+	http.RoundTripper
+	limiters []*rate.Limiter
+}
+
+// Returns the transport throttled by the limiters that aren't nil, or the transport itself if they are all nil.
+func newThrottledTransport(transport http.RoundTripper, limiters ...*rate.Limiter) http.RoundTripper {
+	t := &throttledTransport{RoundTripper: transport}
+	for _, l := range limiters {
+		if l != nil {
+			t.limiters = append(t.limiters, l)
+		}
+	}
+	if len(t.limiters) == 0 {
+		return transport
+	}
+	return t
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = &throttledBody{ReadCloser: req.Body, ctx: req.Context(), limiters: t.limiters}
+	}
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err == nil {
+		resp.Body = &throttledBody{ReadCloser: resp.Body, ctx: req.Context(), limiters: t.limiters}
+	}
+	return resp, err
+}
+
+// throttledBody waits for the limiters after every read, so that a body is read no faster than their bandwidth.
+type throttledBody struct {
+	io.ReadCloser
+	ctx      context.Context
+	limiters []*rate.Limiter
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > bandwidthBurst {
+		p = p[:bandwidthBurst]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		for _, l := range b.limiters {
+			if werr := l.WaitN(b.ctx, n); werr != nil {
+				return n, werr
+			}
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	cases := map[string]float64{
+		"":          0,
+		"500MB/s":   500e6,
+		"1GiB/s":    1 << 30,
+		"100Mbit/s": 12.5e6,
+		"2.5 gb/s":  2.5e9,
+		"4096":      4096,
+	}
+	for bandwidth, expected := range cases {
+		if parsed, err := parseBandwidth(bandwidth); err != nil || parsed != expected {
+			t.Fatalf("Expected %s to be %f bytes/s but got %f: %v", bandwidth, expected, parsed, err)
+		}
+	}
+
+	for _, bandwidth := range []string{"fast", "500XB/s", "0MB/s", "-1MB/s"} {
+		if _, err := parseBandwidth(bandwidth); err == nil {
+			t.Fatalf("bandwidth %s should be invalid", bandwidth)
+		}
+	}
+}
+
+func TestThrottledTransport(t *testing.T) {
+	data := make([]byte, 256<<10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		w.Write(data)
+	}))
+	defer ts.Close()
+
+	if transport := makeTransport(); newThrottledTransport(transport, nil, nil) != transport {
+		t.Fatalf("the transport should not be throttled without limiters")
+	}
+
+	// the upload and the download share the bandwidth of 1MiB/s, less the burst of 64KiB
+	client := &http.Client{Transport: newThrottledTransport(makeTransport(), NewBandwidthLimiter(1<<20), nil)}
+	start := time.Now()
+	resp, err := client.Post(ts.URL, "application/octet-stream", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || len(read) != len(data) {
+		t.Fatalf("Expected %d bytes but read %d: %v", len(data), len(read), err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("512KiB at 1MiB/s should take about 450ms but took %s", elapsed)
+	}
+}
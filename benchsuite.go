@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// benchPhase is one workload of a bench suite.
+type benchPhase struct {
+	name   string
+	optype string
+	size   int64
+	// objects are written and read under <prefix>-<phase prefix>
+	prefix string
+	// the phase sends the number of requests of the run divided by this, at least one per worker
+	divisor int
+	// phases that only clean up are run but not scored
+	scored bool
+}
+
+// The bench suites by version. A suite must never change once it has been released, add a new version instead,
+// so that scorecards of the same suite version are comparable.
+var benchSuites = map[string][]benchPhase{
+	"v1": {
+		{name: "small-write", optype: "put", size: 4 << 10, prefix: "small", divisor: 1, scored: true},
+		{name: "large-write", optype: "put", size: 16 << 20, prefix: "large", divisor: 10, scored: true},
+		{name: "large-read", optype: "get", size: 16 << 20, prefix: "large", divisor: 10, scored: true},
+		{name: "list", optype: "list", prefix: "small", divisor: 100, scored: true},
+		{name: "mixed", optype: "pipeline", size: 64 << 10, prefix: "mixed", divisor: 1, scored: true},
+		{name: "delete", optype: "delete", prefix: "small", divisor: 1, scored: true},
+		{name: "cleanup", optype: "delete", prefix: "large", divisor: 10},
+	},
+}
+
+func benchSuiteVersions() []string {
+	versions := make([]string, 0, len(benchSuites))
+	for v := range benchSuites {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// phaseScore holds the normalized results of a phase.
+type phaseScore struct {
+	Name              string  `json:"name"`
+	Operation         string  `json:"operation"`
+	Requests          int     `json:"totalRequests"`
+	Failures          int     `json:"failedRequests"`
+	RequestsPerSec    float64 `json:"requestsPerSec"`
+	ContentThroughput float64 `json:"contentThroughput (MB/s)"`
+	P50               float64 `json:"p50 (ms)"`
+	P99               float64 `json:"p99 (ms)"`
+	// requests/s relative to the same phase of the baseline, 100 is as fast as the baseline
+	Score float64 `json:"score,omitempty"`
+}
+
+// scorecard holds the results of a bench suite run. Runs of the same suite version with the same concurrency and
+// requests are comparable, and a scorecard saved to a file can be the baseline of later runs.
+type scorecard struct {
+	Suite       string       `json:"suite"`
+	Version     string       `json:"s3testerVersion"`
+	Endpoints   []string     `json:"endpoints"`
+	Concurrency int          `json:"concurrency"`
+	Requests    int          `json:"requests"`
+	Phases      []phaseScore `json:"phases"`
+	// geometric mean of the scores of the phases
+	Score float64 `json:"score,omitempty"`
+}
+
+// Returns the number of requests of a phase, a multiple of the concurrency so that every worker sends the same number.
+func phaseRequests(requests, concurrency, divisor int) int {
+	perWorker := requests / divisor / concurrency
+	if perWorker < 1 {
+		perWorker = 1
+	}
+	return perWorker * concurrency
+}
+
+// Returns the parameters of a phase of the suite.
+func phaseArgs(args parameters, p benchPhase) parameters {
+	phase := args
+	phase.optype = p.optype
+	phase.objectprefix = args.objectprefix + "-" + p.prefix
+	if p.size != 0 {
+		phase.osize = p.size
+	}
+	phase.nrequests = &intFlag{value: phaseRequests(args.nrequests.value, args.concurrency, p.divisor), set: true}
+	return phase
+}
+
+// runBenchSuite runs the phases of the suite one after the other and returns its scorecard, compared with the
+// baseline if there is one.
+func runBenchSuite(args parameters, baseline *scorecard, run func(parameters) results) scorecard {
+	card := scorecard{
+		Suite:       args.benchSuite,
+		Version:     VERSION,
+		Endpoints:   args.endpoints,
+		Concurrency: args.concurrency,
+		Requests:    args.nrequests.value,
+	}
+	for _, p := range benchSuites[args.benchSuite] {
+		if !args.isJson {
+			fmt.Printf("\n\t--- Bench phase: %s ---\n", p.name)
+		}
+		r := run(phaseArgs(args, p)).CummulativeResult
+		if wasInterrupted() {
+			// the phase was cut short, its score would be misleading
+			break
+		}
+		if !p.scored {
+			continue
+		}
+		card.Phases = append(card.Phases, phaseScore{
+			Name:              p.name,
+			Operation:         p.optype,
+			Requests:          r.Count,
+			Failures:          r.Failcount,
+			RequestsPerSec:    r.ActualRequestsPerSec,
+			ContentThroughput: r.ContentThroughput,
+			P50:               r.Percentiles["50"],
+			P99:               r.Percentiles["99"],
+		})
+	}
+	card.compare(baseline)
+	return card
+}
+
+// compare scores the phases against the same phases of the baseline.
+func (card *scorecard) compare(baseline *scorecard) {
+	if baseline == nil {
+		return
+	}
+	base := make(map[string]float64)
+	for _, p := range baseline.Phases {
+		base[p.Name] = p.RequestsPerSec
+	}
+	logSum, scored := 0.0, 0
+	for i := range card.Phases {
+		p := &card.Phases[i]
+		if base[p.Name] <= 0 || p.RequestsPerSec <= 0 {
+			continue
+		}
+		p.Score = roundFloat(100*p.RequestsPerSec/base[p.Name], 1)
+		logSum += math.Log(p.Score)
+		scored++
+	}
+	if scored > 0 {
+		card.Score = roundFloat(math.Exp(logSum/float64(scored)), 1)
+	}
+}
+
+func (card scorecard) failures() int {
+	failures := 0
+	for _, p := range card.Phases {
+		failures += p.Failures
+	}
+	return failures
+}
+
+// Loads a scorecard saved by an earlier run of the same suite.
+func loadScorecard(path, suite string) (*scorecard, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var card scorecard
+	if err := json.NewDecoder(f).Decode(&card); err != nil {
+		return nil, err
+	}
+	if card.Suite != suite {
+		return nil, errors.New("the baseline is a scorecard of suite " + card.Suite + " instead of " + suite)
+	}
+	return &card, nil
+}
+
+func saveScorecard(path string, card scorecard) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(card)
+}
+
+func printScorecard(card scorecard, isJson bool) {
+	if isJson {
+		jsonCard, err := json.Marshal(map[string]scorecard{"scorecard": card})
+		if err != nil {
+			fmt.Println("Error when parsing scorecard to json")
+			return
+		}
+		fmt.Println(string(jsonCard))
+		return
+	}
+
+	fmt.Println("\n\t--- Bench Scorecard ---")
+	fmt.Printf("Suite: %s (s3tester %s)\n", card.Suite, card.Version)
+	fmt.Printf("Endpoints: %s\n", strings.Join(card.Endpoints, ","))
+	fmt.Printf("Concurrency: %d\n", card.Concurrency)
+	fmt.Printf("Requests: %d\n", card.Requests)
+	fmt.Printf("%-12s %10s %10s %12s %10s %10s %8s\n", "Phase", "Failed", "Req/s", "MB/s", "p50 (ms)", "p99 (ms)", "Score")
+	for _, p := range card.Phases {
+		score := "-"
+		if p.Score != 0 {
+			score = convertFloatToString(p.Score)
+		}
+		fmt.Printf("%-12s %10d %10.1f %12.3f %10v %10v %8s\n", p.Name, p.Failures, p.RequestsPerSec, p.ContentThroughput, p.P50, p.P99, score)
+	}
+	if card.Score != 0 {
+		fmt.Printf("Overall score: %v\n", card.Score)
+	}
+}
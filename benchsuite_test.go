@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPhaseRequests(t *testing.T) {
+	cases := [][4]int{
+		// requests, concurrency, divisor, expected
+		{1000, 10, 1, 1000},
+		{1000, 10, 10, 100},
+		{1000, 10, 100, 10},
+		{1000, 64, 100, 64},
+		{1000, 64, 1, 960},
+	}
+	for _, c := range cases {
+		if actual := phaseRequests(c[0], c[1], c[2]); actual != c[3] {
+			t.Fatalf("Expected %d requests for %v but got %d", c[3], c, actual)
+		}
+	}
+}
+
+func TestBenchSuite(t *testing.T) {
+	args := testArgs("put", "https://127.0.0.1:18082")
+	args.benchSuite = "v1"
+	args.objectprefix = "bench"
+	args.concurrency = 10
+	args.nrequests = &intFlag{value: 1000, set: true}
+
+	var phases []parameters
+	run := func(phase parameters) results {
+		phases = append(phases, phase)
+		r := NewResult()
+		r.Count = phase.nrequests.value
+		r.ActualRequestsPerSec = float64(len(phases) * 100)
+		r.Percentiles = map[string]float64{"50": 1, "99": 5}
+		return results{CummulativeResult: r}
+	}
+	card := runBenchSuite(args, nil, run)
+
+	if len(phases) != len(benchSuites["v1"]) || len(card.Phases) != len(benchSuites["v1"])-1 {
+		t.Fatalf("Expected every phase to run and all but the cleanup to be scored, ran %d and scored %d", len(phases), len(card.Phases))
+	}
+
+	// large objects are read back with the same keys they were written with
+	write, read := phases[1], phases[2]
+	if write.objectprefix != "bench-large" || read.objectprefix != write.objectprefix || read.nrequests.value != 100 || write.osize != 16<<20 {
+		t.Fatalf("Wrong large object phases: %s %d %d, %s %d", write.objectprefix, write.nrequests.value, write.osize, read.objectprefix, read.nrequests.value)
+	}
+	if args.nrequests.value != 1000 || args.objectprefix != "bench" {
+		t.Fatalf("The phases must not change the parameters of the run")
+	}
+
+	if card.Score != 0 || card.Phases[0].Score != 0 {
+		t.Fatalf("Expected no scores without a baseline")
+	}
+
+	// the same run twice as fast as the baseline
+	baseline := card
+	baseline.Phases = append([]phaseScore(nil), card.Phases...)
+	for i := range baseline.Phases {
+		baseline.Phases[i].RequestsPerSec /= 2
+	}
+	phases = nil
+	card = runBenchSuite(args, &baseline, run)
+	if card.Score != 200 || card.Phases[3].Score != 200 {
+		t.Fatalf("Expected a score of 200 but got %v", card.Score)
+	}
+}
+
+func TestScorecardFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scorecard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scorecard.json")
+
+	card := scorecard{Suite: "v1", Phases: []phaseScore{{Name: "small-write", RequestsPerSec: 100}}}
+	if err := saveScorecard(path, card); err != nil {
+		t.Fatalf("Failed saving the scorecard: %v", err)
+	}
+
+	loaded, err := loadScorecard(path, "v1")
+	if err != nil || len(loaded.Phases) != 1 || loaded.Phases[0].RequestsPerSec != 100 {
+		t.Fatalf("Wrong scorecard loaded: %+v (%v)", loaded, err)
+	}
+
+	if _, err := loadScorecard(path, "v2"); err == nil {
+		t.Fatalf("Expected the scorecard of another suite to be rejected")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Bucket names are made of the name and a number of up to 12 digits, which keeps them within the limit of 63 characters.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{2,49}$`)
+
+// Checks that names made of the name and a number, like the buckets of the createbucket operation, of bucket-per-worker
+// and of num-buckets, are valid bucket names.
+func validateBucketName(flag, name string) error {
+	if !bucketNamePattern.MatchString(name) {
+		return fmt.Errorf("%s must be 3-50 lowercase letters, digits, dots and hyphens starting with a letter or digit to name buckets: %s", flag, name)
+	}
+	return nil
+}
+
+// Returns the n-th bucket of the name, the bucket of a worker with bucket-per-worker or of a key with num-buckets.
+func numberedBucket(bucket string, n int) string {
+	return bucket + "-" + strconv.Itoa(n)
+}
+
+func CreateBucket(svc s3iface.S3API, bucket, region string) error {
+	params := &s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	}
+	// us-east-1 is the default location, which can't be given as a location constraint
+	if region != "" && region != "us-east-1" {
+		params.CreateBucketConfiguration = &s3.CreateBucketConfiguration{LocationConstraint: aws.String(region)}
+	}
+	_, err := svc.CreateBucket(params)
+
+	return err
+}
+
+func DeleteBucket(svc s3iface.S3API, bucket string) error {
+	_, err := svc.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String(bucket)})
+
+	return err
+}
+
+// ensureBucket creates the bucket unless it is owned by the caller already.
+func ensureBucket(svc s3iface.S3API, bucket, region string) error {
+	err := CreateBucket(svc, bucket, region)
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestValidateBucketName(t *testing.T) {
+	for _, name := range []string{"bucket", "s3tester.run-1", "abc"} {
+		if err := validateBucketName("prefix", name); err != nil {
+			t.Fatalf("Expected %s to be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"Bucket", "ab", "-bucket", "bucket_1", "a23456789012345678901234567890123456789012345678901"} {
+		if err := validateBucketName("prefix", name); err == nil {
+			t.Fatalf("Expected %s to be invalid", name)
+		}
+	}
+}
+
+func TestNumberedBucket(t *testing.T) {
+	if bucket := numberedBucket("test", 12); bucket != "test-12" {
+		t.Fatalf("Wrong numbered bucket %s", bucket)
+	}
+}
+
+// the mock fails creating buckets whose name is the error code
+func (this *mockS3Client) CreateBucket(in *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	this.S3OpHandler(in)
+
+	switch *in.Bucket {
+	case s3.ErrCodeBucketAlreadyOwnedByYou, s3.ErrCodeBucketAlreadyExists:
+		return nil, awserr.New(*in.Bucket, "", nil)
+	}
+	return &s3.CreateBucketOutput{}, nil
+}
+
+func TestCreateBucketOp(t *testing.T) {
+	for region, constraint := range map[string]string{"us-east-1": "", "eu-west-1": "eu-west-1"} {
+		handler := func(in interface{}) interface{} {
+			i := in.(*s3.CreateBucketInput)
+
+			if *i.Bucket != "b-1" {
+				t.Fatalf("Expected bucket b-1 but got: %s", *i.Bucket)
+			}
+
+			if (constraint == "") != (i.CreateBucketConfiguration == nil) || (constraint != "" && *i.CreateBucketConfiguration.LocationConstraint != constraint) {
+				t.Fatalf("Wrong location constraint of region %s: %+v", region, i.CreateBucketConfiguration)
+			}
+
+			return in
+		}
+
+		if err := CreateBucket(NewMockS3Client(handler), "b-1", region); err != nil {
+			t.Fatalf("Failed create bucket operation with error: %v", err)
+		}
+	}
+}
+
+func TestEnsureBucket(t *testing.T) {
+	svc := NewMockS3Client(func(in interface{}) interface{} { return in })
+
+	if err := ensureBucket(svc, s3.ErrCodeBucketAlreadyOwnedByYou, "us-east-1"); err != nil {
+		t.Fatalf("A bucket owned already should be used: %v", err)
+	}
+
+	if err := ensureBucket(svc, s3.ErrCodeBucketAlreadyExists, "us-east-1"); err == nil {
+		t.Fatalf("A bucket owned by someone else should fail")
+	}
+}
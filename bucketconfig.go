@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Objects under the prefix expire after this many days with the lifecycle configuration of putlifecycle.
+const lifecycleExpirationDays = 365
+
+// Returns the policy of putpolicy: the content of the policy file, or a policy that denies requests over plain HTTP.
+func bucketPolicy(bucket, policyFile string) (string, error) {
+	if policyFile != "" {
+		policy, err := ioutil.ReadFile(policyFile)
+		if err != nil {
+			return "", err
+		}
+		if !json.Valid(policy) {
+			return "", errors.New("bucket-policy must be a JSON file")
+		}
+		return string(policy), nil
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Sid":       "DenyInsecureTransport",
+			"Effect":    "Deny",
+			"Principal": "*",
+			"Action":    "s3:*",
+			"Resource":  []string{"arn:aws:s3:::" + bucket, "arn:aws:s3:::" + bucket + "/*"},
+			"Condition": map[string]interface{}{"Bool": map[string]string{"aws:SecureTransport": "false"}},
+		}},
+	})
+	return string(policy), err
+}
+
+// Returns the notification configuration of putnotification, which sends the ObjectCreated events of the prefix to
+// the SQS queue or SNS topic of the ARN. Without an ARN the configuration is empty, which removes all notifications.
+func notificationConfiguration(arn, prefix string) (*s3.NotificationConfiguration, error) {
+	config := &s3.NotificationConfiguration{}
+	events := []*string{aws.String("s3:ObjectCreated:*")}
+	filter := &s3.NotificationConfigurationFilter{Key: &s3.KeyFilter{FilterRules: []*s3.FilterRule{{Name: aws.String(s3.FilterRuleNamePrefix), Value: aws.String(prefix)}}}}
+	switch {
+	case arn == "":
+	case strings.HasPrefix(arn, "arn:aws:sqs:"):
+		config.QueueConfigurations = []*s3.QueueConfiguration{{Id: aws.String("s3tester-" + prefix), Events: events, Filter: filter, QueueArn: aws.String(arn)}}
+	case strings.HasPrefix(arn, "arn:aws:sns:"):
+		config.TopicConfigurations = []*s3.TopicConfiguration{{Id: aws.String("s3tester-" + prefix), Events: events, Filter: filter, TopicArn: aws.String(arn)}}
+	default:
+		return nil, errors.New("notification-arn must be the ARN of an SQS queue or SNS topic")
+	}
+	return config, nil
+}
+
+func PutLifecycle(svc s3iface.S3API, bucket, prefix string) error {
+	params := &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucket),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{{
+				ID:         aws.String("s3tester-" + prefix),
+				Filter:     &s3.LifecycleRuleFilter{Prefix: aws.String(prefix)},
+				Status:     aws.String(s3.ExpirationStatusEnabled),
+				Expiration: &s3.LifecycleExpiration{Days: aws.Int64(lifecycleExpirationDays)},
+			}},
+		},
+	}
+	_, err := svc.PutBucketLifecycleConfiguration(params)
+
+	return err
+}
+
+func GetLifecycle(svc s3iface.S3API, bucket string) error {
+	_, err := svc.GetBucketLifecycleConfiguration(&s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(bucket)})
+
+	return err
+}
+
+func DeleteLifecycle(svc s3iface.S3API, bucket string) error {
+	_, err := svc.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{Bucket: aws.String(bucket)})
+
+	return err
+}
+
+func PutPolicy(svc s3iface.S3API, bucket, policy string) error {
+	_, err := svc.PutBucketPolicy(&s3.PutBucketPolicyInput{Bucket: aws.String(bucket), Policy: aws.String(policy)})
+
+	return err
+}
+
+func GetPolicy(svc s3iface.S3API, bucket string) error {
+	_, err := svc.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: aws.String(bucket)})
+
+	return err
+}
+
+func DeletePolicy(svc s3iface.S3API, bucket string) error {
+	_, err := svc.DeleteBucketPolicy(&s3.DeleteBucketPolicyInput{Bucket: aws.String(bucket)})
+
+	return err
+}
+
+// PutCORS allows GETs and HEADs of the bucket from any origin.
+func PutCORS(svc s3iface.S3API, bucket string) error {
+	params := &s3.PutBucketCorsInput{
+		Bucket: aws.String(bucket),
+		CORSConfiguration: &s3.CORSConfiguration{
+			CORSRules: []*s3.CORSRule{{
+				AllowedMethods: aws.StringSlice([]string{"GET", "HEAD"}),
+				AllowedOrigins: aws.StringSlice([]string{"*"}),
+				MaxAgeSeconds:  aws.Int64(3600),
+			}},
+		},
+	}
+	_, err := svc.PutBucketCors(params)
+
+	return err
+}
+
+func GetCORS(svc s3iface.S3API, bucket string) error {
+	_, err := svc.GetBucketCors(&s3.GetBucketCorsInput{Bucket: aws.String(bucket)})
+
+	return err
+}
+
+func DeleteCORS(svc s3iface.S3API, bucket string) error {
+	_, err := svc.DeleteBucketCors(&s3.DeleteBucketCorsInput{Bucket: aws.String(bucket)})
+
+	return err
+}
+
+func PutNotification(svc s3iface.S3API, bucket, arn, prefix string) error {
+	config, err := notificationConfiguration(arn, prefix)
+	if err != nil {
+		return err
+	}
+	params := &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    aws.String(bucket),
+		NotificationConfiguration: config,
+	}
+	_, err = svc.PutBucketNotificationConfiguration(params)
+
+	return err
+}
+
+func GetNotification(svc s3iface.S3API, bucket string) error {
+	_, err := svc.GetBucketNotificationConfiguration(&s3.GetBucketNotificationConfigurationRequest{Bucket: aws.String(bucket)})
+
+	return err
+}
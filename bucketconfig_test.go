@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestBucketPolicy(t *testing.T) {
+	policy, err := bucketPolicy("b", "")
+	if err != nil || !json.Valid([]byte(policy)) {
+		t.Fatalf("Expected a valid default policy but got %s: %v", policy, err)
+	}
+	if !strings.Contains(policy, `"arn:aws:s3:::b/*"`) || !strings.Contains(policy, "aws:SecureTransport") {
+		t.Fatalf("Expected the default policy to deny plain HTTP requests to the bucket but got %s", policy)
+	}
+
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policy.json")
+	ioutil.WriteFile(file, []byte(`{"Version":"2012-10-17","Statement":[]}`), 0644)
+	if policy, err = bucketPolicy("b", file); err != nil || policy != `{"Version":"2012-10-17","Statement":[]}` {
+		t.Fatalf("Expected the policy of the file but got %s: %v", policy, err)
+	}
+
+	ioutil.WriteFile(file, []byte(`{"Version":`), 0644)
+	if _, err = bucketPolicy("b", file); err == nil {
+		t.Fatalf("Invalid JSON policy should fail")
+	}
+}
+
+func TestNotificationConfiguration(t *testing.T) {
+	config, err := notificationConfiguration("", "p")
+	if err != nil || len(config.QueueConfigurations) != 0 || len(config.TopicConfigurations) != 0 {
+		t.Fatalf("Expected an empty configuration but got %+v: %v", config, err)
+	}
+
+	config, err = notificationConfiguration("arn:aws:sqs:us-east-1:123456789012:queue", "p")
+	if err != nil || len(config.QueueConfigurations) != 1 || *config.QueueConfigurations[0].Filter.Key.FilterRules[0].Value != "p" {
+		t.Fatalf("Expected a queue configuration of the prefix but got %+v: %v", config, err)
+	}
+
+	config, err = notificationConfiguration("arn:aws:sns:us-east-1:123456789012:topic", "p")
+	if err != nil || len(config.TopicConfigurations) != 1 {
+		t.Fatalf("Expected a topic configuration but got %+v: %v", config, err)
+	}
+
+	if _, err = notificationConfiguration("arn:aws:lambda:us-east-1:123456789012:function:f", "p"); err == nil {
+		t.Fatalf("Unsupported notification destination should fail")
+	}
+}
+
+func (this *mockS3Client) PutBucketLifecycleConfiguration(in *s3.PutBucketLifecycleConfigurationInput) (*s3.PutBucketLifecycleConfigurationOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.PutBucketLifecycleConfigurationOutput{}, nil
+}
+
+func (this *mockS3Client) PutBucketCors(in *s3.PutBucketCorsInput) (*s3.PutBucketCorsOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.PutBucketCorsOutput{}, nil
+}
+
+func (this *mockS3Client) DeleteBucketPolicy(in *s3.DeleteBucketPolicyInput) (*s3.DeleteBucketPolicyOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.DeleteBucketPolicyOutput{}, nil
+}
+
+func TestPutLifecycleOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutBucketLifecycleConfigurationInput)
+
+		if *i.Bucket != "b" {
+			t.Fatalf("Expected bucket b but got: %s", *i.Bucket)
+		}
+
+		rule := i.LifecycleConfiguration.Rules[0]
+		if *rule.Filter.Prefix != "p" || *rule.Status != "Enabled" || *rule.Expiration.Days != lifecycleExpirationDays {
+			t.Fatalf("Wrong lifecycle rule: %+v", rule)
+		}
+
+		return in
+	}
+
+	if err := PutLifecycle(NewMockS3Client(handler), "b", "p"); err != nil {
+		t.Fatalf("Failed PUT lifecycle operation with error: %v", err)
+	}
+}
+
+func TestPutCORSOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutBucketCorsInput)
+
+		if *i.Bucket != "b" || len(i.CORSConfiguration.CORSRules) != 1 || *i.CORSConfiguration.CORSRules[0].AllowedOrigins[0] != "*" {
+			t.Fatalf("Wrong CORS configuration of %s: %+v", *i.Bucket, i.CORSConfiguration)
+		}
+
+		return in
+	}
+
+	if err := PutCORS(NewMockS3Client(handler), "b"); err != nil {
+		t.Fatalf("Failed PUT CORS operation with error: %v", err)
+	}
+}
+
+func TestDeletePolicyOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.DeleteBucketPolicyInput)
+
+		if *i.Bucket != "b" {
+			t.Fatalf("Expected bucket b but got: %s", *i.Bucket)
+		}
+
+		return in
+	}
+
+	if err := DeletePolicy(NewMockS3Client(handler), "b"); err != nil {
+		t.Fatalf("Failed DELETE policy operation with error: %v", err)
+	}
+}
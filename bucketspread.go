@@ -0,0 +1,104 @@
+package main
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// The ways keys are placed in the buckets with num-buckets.
+const (
+	bucketPlacementRoundRobin = "roundrobin"
+	bucketPlacementHash       = "hash"
+)
+
+// Returns the number of the bucket of a key out of the given number of buckets. Round robin placement places keys
+// with consecutive numbers in consecutive buckets, hash placement places a key by the hash of its name regardless
+// of the run, so objects written by one run can be read by a run with other settings.
+func keyBucket(placement, key string, number int64, buckets int) int {
+	if placement == bucketPlacementHash {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return int(h.Sum32() % uint32(buckets))
+	}
+	return int(number % int64(buckets))
+}
+
+// prepareBuckets creates the buckets of num-buckets that don't exist yet.
+func prepareBuckets(args parameters) error {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		return err
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+	created.install(svc)
+	audit.install(svc)
+	for i := 0; i < args.numBuckets; i++ {
+		if err := ensureBucket(svc, numberedBucket(args.bucketname, i), args.region); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bucketStat holds the requests and the data transferred to one of the buckets of num-buckets, to show whether the
+// load is spread evenly and whether some buckets are slower than the others.
+type bucketStat struct {
+	Bucket               string  `json:"bucket"`
+	Count                int     `json:"totalRequests"`
+	Failcount            int     `json:"failedRequests"`
+	ActualRequestsPerSec float64 `json:"actualRequestsPerSec"`
+	ContentThroughput    float64 `json:"contentThroughput (MB/s)"`
+
+	number  int
+	objSize int64
+}
+
+func (this *result) recordBucket(number int, bucket string, size int64, failed bool) {
+	if this.bucketStats == nil {
+		this.bucketStats = make(map[string]*bucketStat)
+	}
+	s, ok := this.bucketStats[bucket]
+	if !ok {
+		s = &bucketStat{Bucket: bucket, number: number}
+		this.bucketStats[bucket] = s
+	}
+	s.Count++
+	if failed {
+		s.Failcount++
+	}
+	s.objSize += size
+}
+
+func (this *result) mergeBucketStats(other *result) {
+	for bucket, o := range other.bucketStats {
+		if this.bucketStats == nil {
+			this.bucketStats = make(map[string]*bucketStat)
+		}
+		s, ok := this.bucketStats[bucket]
+		if !ok {
+			s = &bucketStat{Bucket: bucket, number: o.number}
+			this.bucketStats[bucket] = s
+		}
+		s.Count += o.Count
+		s.Failcount += o.Failcount
+		s.objSize += o.objSize
+	}
+}
+
+// setupBucketStats reports the throughput of every bucket over the elapsed time of the result, in bucket order.
+func (this *result) setupBucketStats() {
+	if len(this.bucketStats) == 0 {
+		return
+	}
+	seconds := this.elapsedTime.Seconds()
+	this.BucketStats = make([]bucketStat, 0, len(this.bucketStats))
+	for _, s := range this.bucketStats {
+		s.ActualRequestsPerSec = roundFloat(float64(s.Count)/seconds, 1)
+		s.ContentThroughput = roundFloat(float64(s.objSize)/1024/1024/seconds, 6)
+		this.BucketStats = append(this.BucketStats, *s)
+	}
+	sort.Slice(this.BucketStats, func(i, j int) bool {
+		return this.BucketStats[i].number < this.BucketStats[j].number
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyBucket(t *testing.T) {
+	for i := int64(0); i < 8; i++ {
+		if n := keyBucket(bucketPlacementRoundRobin, "testobject", i, 4); n != int(i%4) {
+			t.Fatalf("Expected key %d in bucket %d but got %d", i, i%4, n)
+		}
+	}
+
+	placed := make(map[int]int)
+	for i := int64(0); i < 1000; i++ {
+		key := objectKey(&parameters{objectprefix: "testobject"}, 0, 1000, i)
+		n := keyBucket(bucketPlacementHash, key, i, 4)
+		if n != keyBucket(bucketPlacementHash, key, i+1, 4) {
+			t.Fatalf("Hash placement of %s should not depend on its index", key)
+		}
+		placed[n]++
+	}
+	for n := 0; n < 4; n++ {
+		if placed[n] < 150 {
+			t.Fatalf("Expected keys to be spread over the buckets but got %v", placed)
+		}
+	}
+}
+
+func TestKeyDistributionBuckets(t *testing.T) {
+	testHelper := initS3TesterHelper(t, "get")
+	defer testHelper.Shutdown()
+	testHelper.args.nrequests.value = 50
+	testHelper.args.keyOffset = 2
+	testHelper.args.numBuckets = 4
+	testHelper.args.bucketPlacement = bucketPlacementRoundRobin
+	testHelper.args.keyDistribution, _ = parseKeyDistribution("uniform")
+	testHelper.runTester(t)
+
+	// the keys picked at random are read from the buckets the put run wrote them to
+	for _, r := range *testHelper.Requests {
+		path := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		n, err := strconv.Atoi(strings.TrimPrefix(path[1], "object-"))
+		if err != nil {
+			t.Fatalf("Unexpected request %s", r.URL.Path)
+		}
+		if path[0] != numberedBucket("test", n%4) {
+			t.Fatalf("Expected key %d in bucket %s but it was read from %s", n, numberedBucket("test", n%4), path[0])
+		}
+	}
+}
+
+func TestBucketStats(t *testing.T) {
+	r := NewResult()
+	r.recordBucket(1, "test-1", 1024*1024, false)
+	r.recordBucket(0, "test-0", 0, true)
+
+	other := NewResult()
+	other.recordBucket(1, "test-1", 1024*1024, false)
+	r.mergeBucketStats(&other)
+
+	r.elapsedTime = 2 * time.Second
+	r.setupBucketStats()
+
+	if len(r.BucketStats) != 2 || r.BucketStats[0].Bucket != "test-0" || r.BucketStats[1].Bucket != "test-1" {
+		t.Fatalf("Expected the stats of the buckets in order but got %+v", r.BucketStats)
+	}
+
+	if s := r.BucketStats[0]; s.Count != 1 || s.Failcount != 1 {
+		t.Fatalf("Wrong stats of test-0: %+v", s)
+	}
+
+	if s := r.BucketStats[1]; s.Count != 2 || s.ActualRequestsPerSec != 1 || s.ContentThroughput != 1 {
+		t.Fatalf("Wrong stats of test-1: %+v", s)
+	}
+}
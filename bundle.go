@@ -0,0 +1,224 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// artifacts collects the artifacts of the run with -bundle, nil otherwise.
+var artifacts *bundle
+
+// bundle collects every artifact of a run, the command line, the log, the results and latency histograms of every
+// phase and the files written by the run, into one gzipped tar archive with a manifest, so complete evidence can be
+// attached to a support ticket.
+type bundle struct {
+	path    string
+	command []string
+	started time.Time
+	// a copy of everything logged during the run
+	log     *os.File
+	entries []bundleEntry
+}
+
+type bundleEntry struct {
+	name        string
+	description string
+	// either the data of the entry or the path of a file written by the run, which is read when the bundle is written
+	data []byte
+	file string
+}
+
+// bundleManifest describes the run and every artifact in the bundle, it is saved as manifest.json.
+type bundleManifest struct {
+	Version   string             `json:"version"`
+	Host      string             `json:"host"`
+	Command   []string           `json:"command"`
+	Started   time.Time          `json:"started"`
+	Finished  time.Time          `json:"finished"`
+	Artifacts []manifestArtifact `json:"artifacts"`
+}
+
+type manifestArtifact struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// NewBundle starts collecting the artifacts of the run. Everything logged from now on is copied into the bundle.
+func NewBundle(path string, command []string) (*bundle, error) {
+	logFile, err := ioutil.TempFile("", "s3tester-log")
+	if err != nil {
+		return nil, err
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+	return &bundle{path: path, command: redactCommand(command), started: time.Now(), log: logFile}, nil
+}
+
+// addRun adds the results and the latency histogram of a phase of the run. It is safe to call on a nil bundle.
+func (b *bundle) addRun(label string, testResult results) {
+	if b == nil {
+		return
+	}
+	name := fmt.Sprintf("run-%d-%s", len(phases), label)
+	if data, err := json.MarshalIndent(testResult, "", "  "); err == nil {
+		b.entries = append(b.entries, bundleEntry{name: name + "/results.json", description: "results of " + label, data: data})
+	}
+	var histogram strings.Builder
+	fmt.Fprintf(&histogram, "from(ms) to(ms) count(operations)\n")
+	for _, v := range testResult.CummulativeResult.latencies.Distribution() {
+		fmt.Fprintf(&histogram, "%f %f %d\n", float64(v.From)/1e2, float64(v.To)/1e2, v.Count)
+	}
+	b.entries = append(b.entries, bundleEntry{name: name + "/latency.txt", description: "latency histogram of " + label, data: []byte(histogram.String())})
+}
+
+// addFile adds a file written by the run, unless no file is given. It is safe to call on a nil bundle.
+func (b *bundle) addFile(path, description string) {
+	if b == nil || path == "" {
+		return
+	}
+	b.entries = append(b.entries, bundleEntry{name: "files/" + strings.TrimLeft(path, "/"), description: description, file: path})
+}
+
+// The flags whose values can be secrets, like customer keys or the tokens of Authorization headers and presigned
+// query parameters.
+var secretFlags = []string{"sse-c-key", "header", "query-param"}
+
+// Returns the command line with the values of the flags that can be secrets replaced, for files shared with others.
+// The names of headers and query parameters are kept.
+func redactCommand(command []string) []string {
+	redacted := make([]string, len(command))
+	copy(redacted, command)
+	for i := 0; i < len(redacted); i++ {
+		if !strings.HasPrefix(redacted[i], "-") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimLeft(redacted[i], "-"), "=", 2)
+		secret := false
+		for _, name := range secretFlags {
+			secret = secret || parts[0] == name
+		}
+		if !secret {
+			continue
+		}
+		if len(parts) == 2 {
+			redacted[i] = redacted[i][:len(redacted[i])-len(parts[1])] + redactValue(parts[0], parts[1])
+		} else if i+1 < len(redacted) {
+			i++
+			redacted[i] = redactValue(parts[0], redacted[i])
+		}
+	}
+	return redacted
+}
+
+// Returns the value of a secret flag with the secret replaced, keeping the name of a header or query parameter.
+func redactValue(flag, value string) string {
+	separator := map[string]string{"header": ":", "query-param": "="}[flag]
+	if separator == "" {
+		return "REDACTED"
+	}
+	if i := strings.Index(value, separator); i >= 0 {
+		return value[:i+1] + "REDACTED"
+	}
+	// a query parameter without a value
+	return value
+}
+
+// Returns the command line as it would be typed into a shell.
+func quoteCommand(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		if strings.ContainsAny(arg, " \t\"'&*{}$") {
+			arg = strconv.Quote(arg)
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// write saves the bundle. Files of the run that can't be read are left out with a warning. It is safe to call on a nil
+// bundle, which writes nothing.
+func (b *bundle) write() error {
+	if b == nil {
+		return nil
+	}
+	log.SetOutput(os.Stderr)
+	defer os.Remove(b.log.Name())
+	defer b.log.Close()
+
+	entries := []bundleEntry{{name: "command.txt", description: "command line of the run", data: []byte(quoteCommand(b.command) + "\n")}}
+	entries = append(entries, bundleEntry{name: "s3tester.log", description: "log of the run", file: b.log.Name()})
+	entries = append(entries, b.entries...)
+
+	f, err := os.Create(b.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	archive := tar.NewWriter(gz)
+
+	host, _ := os.Hostname()
+	manifest := bundleManifest{Version: VERSION, Host: host, Command: b.command, Started: b.started, Finished: time.Now()}
+	for _, entry := range entries {
+		data := entry.data
+		if entry.file != "" {
+			if data, err = ioutil.ReadFile(entry.file); err != nil {
+				log.Printf("Leaving %s out of the bundle: %v", entry.file, err)
+				continue
+			}
+		}
+		if err = writeTarEntry(archive, entry.name, data, manifest.Finished); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Artifacts = append(manifest.Artifacts, manifestArtifact{Name: entry.name, Description: entry.description, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = writeTarEntry(archive, "manifest.json", data, manifest.Finished); err != nil {
+		return err
+	}
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// finish writes the bundle and logs where it was saved. It is safe to call on a nil bundle.
+func (b *bundle) finish() {
+	if b == nil {
+		return
+	}
+	if err := b.write(); err != nil {
+		log.Printf("Failed writing the bundle %s: %v", b.path, err)
+		return
+	}
+	log.Printf("Saved the artifacts of the run in %s", b.path)
+}
+
+func writeTarEntry(archive *tar.Writer, name string, data []byte, modified time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modified}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := archive.Write(data)
+	return err
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Returns the entries of the bundle by name.
+func readBundle(t *testing.T, path string) map[string][]byte {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := tar.NewReader(gz)
+	entries := make(map[string][]byte)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[header.Name], _ = ioutil.ReadAll(archive)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	detail := filepath.Join(dir, "detail.csv")
+	ioutil.WriteFile(detail, []byte("0.1,0.02\n"), 0644)
+
+	b, err := NewBundle(filepath.Join(dir, "run.tgz"), []string{"s3tester", "-operation=put", "-metadata=owner=me&team=qa", "-sse-c-key=secretkey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Printf("bundled log line")
+	r := NewResult()
+	r.RecordLatency(10 * time.Millisecond)
+	b.addRun("put-1", results{CummulativeResult: r})
+	b.addFile(detail, "detailed log of the requests")
+	b.addFile(filepath.Join(dir, "missing.csv"), "never written")
+	if err = b.write(); err != nil {
+		t.Fatalf("Writing the bundle should succeed: %v", err)
+	}
+
+	entries := readBundle(t, filepath.Join(dir, "run.tgz"))
+	if string(entries["command.txt"]) != "s3tester -operation=put \"-metadata=owner=me&team=qa\" -sse-c-key=REDACTED\n" {
+		t.Fatalf("Wrong command line: %s", entries["command.txt"])
+	}
+
+	var manifest bundleManifest
+	if err = json.Unmarshal(entries["manifest.json"], &manifest); err != nil {
+		t.Fatalf("Bundle should have a manifest: %v", err)
+	}
+
+	// the missing file is left out
+	if len(manifest.Artifacts) != 5 || len(entries) != 6 {
+		t.Fatalf("Expected 5 artifacts and the manifest but got %+v", manifest.Artifacts)
+	}
+
+	for _, artifact := range manifest.Artifacts {
+		sum := sha256.Sum256(entries[artifact.Name])
+		if artifact.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Fatalf("Wrong checksum of %s in the manifest", artifact.Name)
+		}
+	}
+
+	if _, ok := entries["files/"+detail[1:]]; !ok {
+		t.Fatalf("Expected the detailed log in the bundle but got %v", manifest.Artifacts)
+	}
+
+	if len(entries["s3tester.log"]) == 0 {
+		t.Fatalf("Expected the log in the bundle")
+	}
+}
+
+func TestRedactCommand(t *testing.T) {
+	command := []string{"s3tester", "-sse-c-key=secretkey", "--header", "Authorization: Bearer token", "-header=X-Team: qa",
+		"-query-param", "X-Amz-Security-Token=token", "-query-param=versioning", "-prefix", "header"}
+	expected := []string{"s3tester", "-sse-c-key=REDACTED", "--header", "Authorization:REDACTED", "-header=X-Team:REDACTED",
+		"-query-param", "X-Amz-Security-Token=REDACTED", "-query-param=versioning", "-prefix", "header"}
+	redacted := redactCommand(command)
+	if !reflect.DeepEqual(redacted, expected) {
+		t.Fatalf("Wrong redacted command line: %v", redacted)
+	}
+	if command[1] != "-sse-c-key=secretkey" {
+		t.Fatalf("The command line should be left as it is")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var checksumAlgorithms = []string{s3.ChecksumAlgorithmCrc32, s3.ChecksumAlgorithmCrc32c, s3.ChecksumAlgorithmSha1, s3.ChecksumAlgorithmSha256}
+
+func newChecksumHash(algorithm string) hash.Hash {
+	switch algorithm {
+	case s3.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE()
+	case s3.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case s3.ChecksumAlgorithmSha1:
+		return sha1.New()
+	}
+	return sha256.New()
+}
+
+// Returns the field of the checksum of the given algorithm among the checksum fields of a request or response.
+func checksumField(algorithm string, crc32Field, crc32cField, sha1Field, sha256Field **string) **string {
+	switch algorithm {
+	case s3.ChecksumAlgorithmCrc32:
+		return crc32Field
+	case s3.ChecksumAlgorithmCrc32c:
+		return crc32cField
+	case s3.ChecksumAlgorithmSha1:
+		return sha1Field
+	}
+	return sha256Field
+}
+
+// checksumResult sends the checksums of the data uploaded by a worker, verifies the checksums of the data it downloads
+// and keeps track of the CPU time spent computing them.
+type checksumResult struct {
+	Algorithm string `json:"algorithm"`
+	// checksums computed of uploads and downloads
+	Computed int64 `json:"computedChecksums"`
+	// downloads whose checksum matched the one returned by the server
+	Verified int64 `json:"verifiedChecksums"`
+	// total time spent computing checksums
+	Time float64 `json:"checksumTime (ms)"`
+
+	// parts and ranges are transferred concurrently
+	elapsed int64
+}
+
+func NewChecksumResult(algorithm string) *checksumResult {
+	return &checksumResult{Algorithm: algorithm}
+}
+
+// compute returns the base64 encoded checksum of the body and rewinds it.
+func (this *checksumResult) compute(body io.ReadSeeker) (string, error) {
+	start := time.Now()
+	h := newChecksumHash(this.Algorithm)
+	if _, err := io.Copy(h, body); err != nil {
+		return "", err
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	this.add(time.Since(start))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func (this *checksumResult) add(elapsed time.Duration) {
+	atomic.AddInt64(&this.Computed, 1)
+	atomic.AddInt64(&this.elapsed, int64(elapsed))
+}
+
+// signPut sends the checksum of the object with the PUT.
+func (this *checksumResult) signPut(params *s3.PutObjectInput) error {
+	checksum, err := this.compute(params.Body)
+	if err != nil {
+		return err
+	}
+	params.ChecksumAlgorithm = aws.String(this.Algorithm)
+	*checksumField(this.Algorithm, &params.ChecksumCRC32, &params.ChecksumCRC32C, &params.ChecksumSHA1, &params.ChecksumSHA256) = aws.String(checksum)
+	return nil
+}
+
+// signPart sends the checksum of the part with the upload.
+func (this *checksumResult) signPart(params *s3.UploadPartInput) error {
+	checksum, err := this.compute(params.Body)
+	if err != nil {
+		return err
+	}
+	params.ChecksumAlgorithm = aws.String(this.Algorithm)
+	*checksumField(this.Algorithm, &params.ChecksumCRC32, &params.ChecksumCRC32C, &params.ChecksumSHA1, &params.ChecksumSHA256) = aws.String(checksum)
+	return nil
+}
+
+// checksumReader computes the checksum of a download while it is read.
+type checksumReader struct {
+	io.Reader
+	hash   hash.Hash
+	result *checksumResult
+	took   time.Duration
+}
+
+func (this *checksumResult) reader(body io.Reader) *checksumReader {
+	return &checksumReader{Reader: body, hash: newChecksumHash(this.Algorithm), result: this}
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	start := time.Now()
+	r.hash.Write(p[:n])
+	r.took += time.Since(start)
+	return n, err
+}
+
+// verify compares the checksum of the data read with the checksum the server returned. Responses without a checksum of
+// the whole object, such as ranged GETs and the composite checksums of multipart uploads, are not verified.
+func (r *checksumReader) verify(key string, header http.Header) error {
+	r.result.add(r.took)
+	expected := header.Get("X-Amz-Checksum-" + r.result.Algorithm)
+	if expected == "" || strings.Contains(expected, "-") || header.Get("Content-Range") != "" {
+		return nil
+	}
+	if actual := base64.StdEncoding.EncodeToString(r.hash.Sum(nil)); actual != expected {
+		return &softFailure{softFailureChecksum, fmt.Errorf("%s checksum mismatch of %s: expected %s but read %s", r.result.Algorithm, key, expected, actual)}
+	}
+	atomic.AddInt64(&r.result.Verified, 1)
+	return nil
+}
+
+// merge adds the other result to this one and returns the merged result, which is newly allocated if this one is nil.
+func (this *checksumResult) merge(other *checksumResult) *checksumResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = NewChecksumResult(other.Algorithm)
+	}
+	this.Computed += other.Computed
+	this.Verified += other.Verified
+	this.elapsed += other.elapsed
+	return this
+}
+
+func (this *checksumResult) setupStats() {
+	if this != nil {
+		this.Time = roundFloat(float64(this.elapsed)/float64(time.Millisecond), 3)
+	}
+}
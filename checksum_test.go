@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestComputeChecksum(t *testing.T) {
+	expected := map[string]string{
+		s3.ChecksumAlgorithmCrc32:  "NhCmhg==",
+		s3.ChecksumAlgorithmSha1:   "qvTGHdzF6KLavt4PO0gs2a6pQ00=",
+		s3.ChecksumAlgorithmSha256: "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=",
+	}
+	for algorithm, checksum := range expected {
+		c := NewChecksumResult(algorithm)
+		body := strings.NewReader("hello")
+		actual, err := c.compute(body)
+		if err != nil || actual != checksum {
+			t.Fatalf("Expected %s checksum %s but got %s (%v)", algorithm, checksum, actual, err)
+		}
+		// the body is sent after its checksum was computed
+		if data, _ := ioutil.ReadAll(body); string(data) != "hello" {
+			t.Fatalf("Expected the body to be rewound but read %q", data)
+		}
+		if c.Computed != 1 {
+			t.Fatalf("Expected 1 computed checksum but got %d", c.Computed)
+		}
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	c := NewChecksumResult(s3.ChecksumAlgorithmCrc32)
+	read := func(header http.Header) error {
+		r := c.reader(strings.NewReader("hello"))
+		ioutil.ReadAll(r)
+		return r.verify("k1", header)
+	}
+
+	if err := read(http.Header{"X-Amz-Checksum-Crc32": []string{"NhCmhg=="}}); err != nil {
+		t.Fatalf("Expected the checksum to match but got: %v", err)
+	}
+	if err, ok := read(http.Header{"X-Amz-Checksum-Crc32": []string{"AAAAAA=="}}).(*softFailure); !ok || err.kind != softFailureChecksum {
+		t.Fatalf("Expected a checksum mismatch")
+	}
+	// composite checksums of multipart uploads, ranges and responses without a checksum are not verified
+	if err := read(http.Header{"X-Amz-Checksum-Crc32": []string{"AAAAAA==-3"}}); err != nil {
+		t.Fatalf("Expected a composite checksum to be skipped but got: %v", err)
+	}
+	if err := read(http.Header{"X-Amz-Checksum-Crc32": []string{"AAAAAA=="}, "Content-Range": []string{"bytes 0-4/10"}}); err != nil {
+		t.Fatalf("Expected a range to be skipped but got: %v", err)
+	}
+	if err := read(http.Header{}); err != nil {
+		t.Fatalf("Expected a response without a checksum to be skipped but got: %v", err)
+	}
+
+	if c.Computed != 5 || c.Verified != 1 {
+		t.Fatalf("Expected 5 computed and 1 verified checksum but got %d and %d", c.Computed, c.Verified)
+	}
+}
+
+func TestPutChecksum(t *testing.T) {
+	c := NewChecksumResult(s3.ChecksumAlgorithmSha256)
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutObjectInput)
+		if aws.StringValue(i.ChecksumAlgorithm) != s3.ChecksumAlgorithmSha256 || i.ChecksumSHA256 == nil || i.ChecksumCRC32 != nil {
+			t.Fatalf("Expected a SHA256 checksum but got: %v", i)
+		}
+		data, _ := ioutil.ReadAll(i.Body)
+		if expected, _ := NewChecksumResult(s3.ChecksumAlgorithmSha256).compute(bytes.NewReader(data)); *i.ChecksumSHA256 != expected {
+			t.Fatalf("Expected checksum %s but got %s", expected, *i.ChecksumSHA256)
+		}
+		return in
+	}
+
+	if _, err := Put(NewMockS3Client(handler), "b", "k1", "", "", 100, map[string]*string{}, payloadOptions{checksums: c}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if c.Computed != 1 {
+		t.Fatalf("Expected 1 computed checksum but got %d", c.Computed)
+	}
+}
+
+func TestMultipartPutChecksum(t *testing.T) {
+	var partSize int64 = 5 * (1 << 20)
+	c := NewChecksumResult(s3.ChecksumAlgorithmCrc32c)
+	handler := func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.CreateMultipartUploadInput:
+			if aws.StringValue(i.ChecksumAlgorithm) != s3.ChecksumAlgorithmCrc32c {
+				t.Fatalf("Expected the upload to be created with CRC32C but got: %v", i.ChecksumAlgorithm)
+			}
+		case *s3.UploadPartInput:
+			if i.ChecksumCRC32C == nil {
+				t.Fatalf("Expected part %d to carry a CRC32C checksum", *i.PartNumber)
+			}
+		case *s3.CompleteMultipartUploadInput:
+			for _, part := range i.MultipartUpload.Parts {
+				if part.ChecksumCRC32C == nil {
+					t.Fatalf("Expected the completed part %d to carry its checksum", *part.PartNumber)
+				}
+			}
+		}
+		return in
+	}
+
+	if _, err := MultipartPut(NewMockS3Client(handler), "b", "k1", "", s3.StorageClassStandard, 2*partSize+1, partSize, 2, map[string]*string{}, payloadOptions{checksums: c}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if c.Computed != 3 {
+		t.Fatalf("Expected 3 computed checksums but got %d", c.Computed)
+	}
+}
+
+func TestMergeChecksumResult(t *testing.T) {
+	var merged *checksumResult
+	merged = merged.merge(&checksumResult{Algorithm: s3.ChecksumAlgorithmCrc32, Computed: 2, Verified: 1, elapsed: 2e6})
+	merged = merged.merge(nil)
+	merged = merged.merge(&checksumResult{Algorithm: s3.ChecksumAlgorithmCrc32, Computed: 3, Verified: 2, elapsed: 1e6})
+	merged.setupStats()
+	if merged.Algorithm != s3.ChecksumAlgorithmCrc32 || merged.Computed != 5 || merged.Verified != 3 || merged.Time != 3 {
+		t.Fatalf("Wrong merged checksum result: %+v", merged)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// created tracks the objects and buckets created by the run with -cleanup, nil otherwise.
+var created *createdResources
+
+// createdResources are the objects and buckets the run created, which are deleted once the run is done or interrupted
+// so that aborted runs don't leave their data behind.
+type createdResources struct {
+	mu      sync.Mutex
+	objects map[string]map[string]struct{}
+	buckets map[string]struct{}
+	// the objects copyacross wrote to dest-endpoint, deleted through it, nil for the resources of dest-endpoint
+	dest *createdResources
+}
+
+func NewCreatedResources() *createdResources {
+	this := newCreatedResources()
+	this.dest = newCreatedResources()
+	return this
+}
+
+func newCreatedResources() *createdResources {
+	return &createdResources{objects: make(map[string]map[string]struct{}), buckets: make(map[string]struct{})}
+}
+
+// destination returns the resources created on dest-endpoint. It is safe to call without cleanup and returns nil.
+func (this *createdResources) destination() *createdResources {
+	if this == nil {
+		return nil
+	}
+	return this.dest
+}
+
+// addObject tracks an object written without the SDK, e.g. through a presigned URL. Does nothing without cleanup.
+func (this *createdResources) addObject(bucket, key string) {
+	if this == nil {
+		return
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	keys, ok := this.objects[bucket]
+	if !ok {
+		keys = make(map[string]struct{})
+		this.objects[bucket] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func (this *createdResources) addBucket(bucket string) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.buckets[bucket] = struct{}{}
+}
+
+// install records every object and bucket the service creates successfully. Does nothing without cleanup.
+func (this *createdResources) install(svc *s3.S3) {
+	if this == nil {
+		return
+	}
+	svc.Client.Handlers.Complete.PushBack(this.record)
+}
+
+// Records the object or bucket created by a successful request.
+func (this *createdResources) record(r *request.Request) {
+	if r.Error != nil {
+		return
+	}
+	switch params := r.Params.(type) {
+	case *s3.PutObjectInput:
+		this.addObject(aws.StringValue(params.Bucket), aws.StringValue(params.Key))
+	case *s3.CompleteMultipartUploadInput:
+		this.addObject(aws.StringValue(params.Bucket), aws.StringValue(params.Key))
+	case *s3.CopyObjectInput:
+		this.addObject(aws.StringValue(params.Bucket), aws.StringValue(params.Key))
+	case *s3.CreateBucketInput:
+		this.addBucket(aws.StringValue(params.Bucket))
+	}
+}
+
+// DeleteCreated deletes the objects with DeleteObjects requests of up to batchSize keys, concurrency of them at a time,
+// followed by the buckets, which are empty by then unless they hold objects of others. Returns the number of objects
+// and buckets deleted and the number that failed to be deleted, which are logged.
+func DeleteCreated(svc s3iface.S3API, objects map[string][]string, buckets []string, batchSize, concurrency int) (deleted, failed int64) {
+	type batch struct {
+		bucket string
+		keys   []string
+	}
+	batches := make(chan batch, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				n, err := MultiDelete(svc, b.bucket, b.keys)
+				atomic.AddInt64(&deleted, int64(n))
+				if err != nil {
+					atomic.AddInt64(&failed, int64(len(b.keys)-n))
+					log.Printf("Failed deleting %d objects of bucket '%s': %v", len(b.keys)-n, b.bucket, err)
+				}
+			}
+		}()
+	}
+	for bucket, keys := range objects {
+		for start := 0; start < len(keys); start += batchSize {
+			end := start + batchSize
+			if end > len(keys) {
+				end = len(keys)
+			}
+			batches <- batch{bucket, keys[start:end]}
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	for _, bucket := range buckets {
+		if err := DeleteBucket(svc, bucket); err != nil {
+			failed++
+			log.Printf("Failed deleting bucket '%s': %v", bucket, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, failed
+}
+
+// cleanup deletes everything the run created through the first endpoint, and the objects written to dest-endpoint
+// through it, and returns whether it succeeded. Does nothing without cleanup.
+func (this *createdResources) cleanup(args parameters) bool {
+	if this == nil {
+		return true
+	}
+	succeeded := this.deleteThrough(args, args.endpoints[0], args.profile)
+	if this.dest != nil {
+		destProfile := args.profile
+		if args.destProfile != "" {
+			destProfile = args.destProfile
+		}
+		succeeded = this.dest.deleteThrough(args, args.destEndpoint, destProfile) && succeeded
+	}
+	return succeeded
+}
+
+// deleteThrough deletes the resources through the endpoint with the credentials of the profile and returns whether it
+// succeeded.
+func (this *createdResources) deleteThrough(args parameters, endpoint, profile string) bool {
+	this.mu.Lock()
+	objects := make(map[string][]string)
+	count := 0
+	for bucket, keys := range this.objects {
+		for key := range keys {
+			objects[bucket] = append(objects[bucket], key)
+		}
+		// deletes the keys in order
+		sort.Strings(objects[bucket])
+		count += len(keys)
+	}
+	var buckets []string
+	for bucket := range this.buckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+	this.mu.Unlock()
+	if count == 0 && len(buckets) == 0 {
+		return true
+	}
+
+	credential, err := loadCredentialProfile(profile, args.nosign)
+	if err != nil {
+		log.Printf("Failed loading credentials: %v", err)
+		return false
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, endpoint, args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+	audit.install(svc)
+
+	log.Printf("Cleaning up %d objects and %d buckets created by the run through %s", count, len(buckets), endpoint)
+	concurrency := args.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	deleted, failed := DeleteCreated(svc, objects, buckets, args.batchSize, concurrency)
+	log.Printf("Cleaned up %d objects and buckets created by the run through %s, %d failed", deleted, endpoint, failed)
+	return failed == 0
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func (this *mockS3Client) DeleteBucket(in *s3.DeleteBucketInput) (*s3.DeleteBucketOutput, error) {
+	if err, ok := this.S3OpHandler(in).(error); ok {
+		return nil, err
+	}
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+func TestCreatedResourcesRecord(t *testing.T) {
+	tracked := NewCreatedResources()
+	for _, r := range []*request.Request{
+		{Params: &s3.CreateBucketInput{Bucket: aws.String("b")}},
+		{Params: &s3.PutObjectInput{Bucket: aws.String("b"), Key: aws.String("k1")}},
+		{Params: &s3.PutObjectInput{Bucket: aws.String("b"), Key: aws.String("k1")}},
+		{Params: &s3.PutObjectInput{Bucket: aws.String("b"), Key: aws.String("failed")}, Error: errors.New("AccessDenied")},
+		{Params: &s3.CompleteMultipartUploadInput{Bucket: aws.String("b"), Key: aws.String("k2")}},
+		{Params: &s3.CopyObjectInput{Bucket: aws.String("c"), Key: aws.String("k3"), CopySource: aws.String("b/k1")}},
+		{Params: &s3.GetObjectInput{Bucket: aws.String("b"), Key: aws.String("k4")}},
+	} {
+		tracked.record(r)
+	}
+
+	expected := map[string]map[string]struct{}{"b": {"k1": {}, "k2": {}}, "c": {"k3": {}}}
+	if !reflect.DeepEqual(tracked.objects, expected) || !reflect.DeepEqual(tracked.buckets, map[string]struct{}{"b": {}}) {
+		t.Fatalf("Wrong resources tracked: %v %v", tracked.objects, tracked.buckets)
+	}
+
+	// nothing is tracked without cleanup
+	var untracked *createdResources
+	untracked.install(nil)
+	untracked.destination().install(nil)
+	untracked.addObject("b", "k1")
+	if !untracked.cleanup(parameters{}) {
+		t.Fatalf("Cleaning up without cleanup should succeed")
+	}
+}
+
+func TestDeleteCreated(t *testing.T) {
+	var mu sync.Mutex
+	var batches []string
+	var buckets []string
+	handler := func(in interface{}) interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		switch i := in.(type) {
+		case *s3.DeleteObjectsInput:
+			var keys []string
+			for _, object := range i.Delete.Objects {
+				keys = append(keys, *object.Key)
+			}
+			batches = append(batches, *i.Bucket+":"+strings.Join(keys, ","))
+			if *i.Bucket == "c" {
+				return &s3.DeleteObjectsOutput{Errors: []*s3.Error{{Key: aws.String("k5"), Code: aws.String("AccessDenied")}}}
+			}
+			return &s3.DeleteObjectsOutput{}
+		case *s3.DeleteBucketInput:
+			buckets = append(buckets, *i.Bucket)
+			if *i.Bucket == "c" {
+				return awserr.New("BucketNotEmpty", "The bucket you tried to delete is not empty", nil)
+			}
+		}
+		return nil
+	}
+
+	objects := map[string][]string{"b": {"k1", "k2", "k3"}, "c": {"k4", "k5"}}
+	deleted, failed := DeleteCreated(NewMockS3Client(handler), objects, []string{"b", "c"}, 2, 3)
+	sort.Strings(batches)
+	if !reflect.DeepEqual(batches, []string{"b:k1,k2", "b:k3", "c:k4,k5"}) || !reflect.DeepEqual(buckets, []string{"b", "c"}) {
+		t.Fatalf("Wrong deletes: %v %v", batches, buckets)
+	}
+	if deleted != 5 || failed != 2 {
+		t.Fatalf("Expected 5 deleted and 2 failed but got %d and %d", deleted, failed)
+	}
+}
+
+func TestCreatedResourcesOfEveryWriter(t *testing.T) {
+	created = NewCreatedResources()
+	defer func() { created = nil }()
+
+	source := initS3TesterHelper(t, "copyacross")
+	defer source.Shutdown()
+	destination := initS3TesterHelper(t, "put")
+	defer destination.Shutdown()
+	source.args.destEndpoint = destination.Endpoint
+	source.args.copyBucket = "copy"
+	source.runTesterWithoutValidation(t)
+
+	presign := initS3TesterHelper(t, "presign")
+	defer presign.Shutdown()
+	presign.args.presignTransfer = true
+	presign.args.presignMethod = "PUT"
+	presign.args.osize = 10
+	presign.runTesterWithoutValidation(t)
+
+	// the copies are deleted through dest-endpoint and the presigned uploads through the endpoint
+	if !reflect.DeepEqual(created.dest.objects, map[string]map[string]struct{}{"copy": {"copy-0": {}}}) {
+		t.Fatalf("Expected the copy to be tracked on dest-endpoint but got %v", created.dest.objects)
+	}
+	if !reflect.DeepEqual(created.objects, map[string]map[string]struct{}{"test": {"object-0": {}}}) {
+		t.Fatalf("Expected the presigned upload to be tracked but got %v", created.objects)
+	}
+}
+
+func TestCleanupOption(t *testing.T) {
+	if args, err := parse([]string{"-cleanup", "-batch-size=500"}); err != nil || !args.cleanup || args.batchSize != 500 {
+		t.Fatalf("valid cleanup should succeed: %v %v", args.cleanup, err)
+	}
+}
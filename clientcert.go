@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// clientCert is the client certificate of mTLS endpoints given with -client-cert, nil otherwise.
+var clientCert *clientCertificate
+
+// clientCertificate holds the client certificate presented to mTLS endpoints and reloads it from its files when they
+// change, so that long runs against short-lived certificates keep connecting after the certificates were rotated.
+type clientCertificate struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+	// the later modification time of the files when the certificate was loaded
+	modTime time.Time
+}
+
+// loadClientCertificate loads the PEM encoded certificate and key of the files.
+func loadClientCertificate(certFile, keyFile string) (*clientCertificate, error) {
+	c := &clientCertificate{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Returns the later modification time of the certificate and key files.
+func (c *clientCertificate) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reload loads the certificate from its files. The certificate loaded before is kept if they can't be loaded, e.g.
+// while only one of them was replaced.
+func (c *clientCertificate) reload() error {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+	return nil
+}
+
+// refresh reloads the certificate if its files changed since it was loaded and returns whether it was reloaded.
+func (c *clientCertificate) refresh() (bool, error) {
+	modTime, err := c.filesModTime()
+	if err != nil {
+		return false, err
+	}
+	c.mu.RLock()
+	changed := !modTime.Equal(c.modTime)
+	c.mu.RUnlock()
+	if !changed {
+		return false, nil
+	}
+	return true, c.reload()
+}
+
+// get returns the current certificate to the TLS handshakes of new connections.
+func (c *clientCertificate) get(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cert == nil {
+		return nil, errors.New("no client certificate loaded")
+	}
+	return c.cert, nil
+}
+
+func (c *clientCertificate) notAfter() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert.Leaf.NotAfter
+}
+
+// watch reloads the certificate on SIGHUP and whenever its files change, checking them every interval, for the rest
+// of the process. An interval of 0 only reloads on SIGHUP.
+func (c *clientCertificate) watch(interval time.Duration) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	var ticks <-chan time.Time
+	if interval > 0 {
+		ticks = time.NewTicker(interval).C
+	}
+	go func() {
+		for {
+			select {
+			case <-hangups:
+				if err := c.reload(); err != nil {
+					log.Printf("Failed reloading the client certificate %s on SIGHUP, keeping the current one: %v", c.certFile, err)
+					continue
+				}
+				log.Printf("Reloaded the client certificate %s on SIGHUP, valid until %s", c.certFile, c.notAfter().Format(time.RFC3339))
+			case <-ticks:
+				reloaded, err := c.refresh()
+				if err != nil {
+					log.Printf("Failed reloading the changed client certificate %s, keeping the current one: %v", c.certFile, err)
+					continue
+				}
+				if reloaded {
+					log.Printf("Reloaded the changed client certificate %s, valid until %s", c.certFile, c.notAfter().Format(time.RFC3339))
+				}
+			}
+		}
+	}()
+}
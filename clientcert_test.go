@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writes a self-signed certificate with the common name and its key to cert.pem and key.pem in the directory
+func writeTestCertificate(t *testing.T, dir, name string, modTime time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	os.Chtimes(certFile, modTime, modTime)
+	os.Chtimes(keyFile, modTime, modTime)
+	return certFile, keyFile
+}
+
+func TestClientCertificateRefresh(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	start := time.Now().Add(-time.Minute)
+	certFile, keyFile := writeTestCertificate(t, dir, "first", start)
+
+	c, err := loadClientCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("valid certificate should load: %v", err)
+	}
+	if reloaded, err := c.refresh(); reloaded || err != nil {
+		t.Fatalf("unchanged certificate shouldn't be reloaded: %v %v", reloaded, err)
+	}
+
+	writeTestCertificate(t, dir, "second", start.Add(time.Second))
+	if reloaded, err := c.refresh(); !reloaded || err != nil {
+		t.Fatalf("changed certificate should be reloaded: %v %v", reloaded, err)
+	}
+	if cert, _ := c.get(nil); cert.Leaf.Subject.CommonName != "second" {
+		t.Fatalf("Expected the rotated certificate but got %s", cert.Leaf.Subject.CommonName)
+	}
+
+	// a half written rotation keeps the current certificate
+	ioutil.WriteFile(keyFile, []byte("garbage"), 0600)
+	os.Chtimes(keyFile, start.Add(2*time.Second), start.Add(2*time.Second))
+	if _, err := c.refresh(); err == nil {
+		t.Fatalf("invalid key should fail to reload")
+	}
+	if cert, _ := c.get(nil); cert.Leaf.Subject.CommonName != "second" {
+		t.Fatalf("Expected the current certificate to be kept but got %s", cert.Leaf.Subject.CommonName)
+	}
+}
+
+func TestClientCertificateHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCertificate(t, dir, "tester", time.Now())
+
+	var peer string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer = r.TLS.PeerCertificates[0].Subject.CommonName
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	if clientCert, err = loadClientCertificate(certFile, keyFile); err != nil {
+		t.Fatalf("valid certificate should load: %v", err)
+	}
+	defer func() { clientCert = nil }()
+	resp, err := MakeHTTPClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with a client certificate should succeed: %v", err)
+	}
+	resp.Body.Close()
+	if peer != "tester" {
+		t.Fatalf("Expected the client certificate tester but got %q", peer)
+	}
+}
+
+func TestClientCertificateOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCertificate(t, dir, "tester", time.Now())
+
+	args, err := parse([]string{"-client-cert=" + certFile, "-client-key=" + keyFile, "-client-cert-refresh=1m"})
+	if err != nil || args.clientCert == nil || args.clientCertRefresh != time.Minute {
+		t.Fatalf("client-cert should succeed: %v", err)
+	}
+
+	for _, invalid := range [][]string{
+		{"-client-cert=" + certFile},
+		{"-client-cert=" + certFile, "-client-key=" + certFile},
+		{"-client-cert=" + certFile, "-client-key=" + keyFile, "-client-cert-refresh=-1s"},
+	} {
+		if _, err := parse(invalid); err == nil {
+			t.Fatalf("%v should be invalid", invalid)
+		}
+	}
+}
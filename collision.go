@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// collisionReport summarizes the final state of keys that all workers wrote concurrently in collision mode.
+type collisionReport struct {
+	Keys     int `json:"keys"`
+	Verified int `json:"verified"`
+	// Mismatched keys hold data that does not correspond to exactly one writer's payload, e.g. a torn write.
+	Mismatched int `json:"mismatched"`
+	Failed     int `json:"failed"`
+	// Winners maps a worker id to the number of keys whose final content it wrote.
+	Winners map[int]int `json:"winners"`
+}
+
+// In collision mode every worker seeds its object data with its own id so that the writer of an object can be identified.
+func collisionSeedSuffix(workerId int) string {
+	return "#w" + strconv.Itoa(workerId)
+}
+
+// Reads back every key written in collision mode and checks that it holds exactly one writer's payload.
+func verifyCollisions(args parameters) collisionReport {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		log.Fatal(err)
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+	args.encryption.install(svc)
+
+	report := collisionReport{Winners: make(map[int]int)}
+	keys := args.nrequests.value / args.concurrency
+	for j := 0; j < keys; j++ {
+		key := objectKey(&args, 0, int64(keys), int64(j))
+		writer, err := findCollisionWriter(svc, args, key)
+		report.Keys++
+		switch {
+		case err == errCollisionMismatch:
+			report.Mismatched++
+			log.Printf("Object '%s/%s' does not hold exactly one writer's data", args.bucketname, key)
+		case err != nil:
+			report.Failed++
+			log.Printf("Failed verifying object '%s/%s': %v", args.bucketname, key, err)
+		default:
+			report.Verified++
+			report.Winners[writer]++
+		}
+	}
+	return report
+}
+
+var errCollisionMismatch = errors.New("object data does not match any writer")
+
+// Identifies the writer from the first block of the object, then verifies the rest of the object against that writer's payload.
+func findCollisionWriter(svc s3iface.S3API, args parameters, key string) (int, error) {
+	out, err := svc.GetObject(&s3.GetObjectInput{Bucket: aws.String(args.bucketname), Key: aws.String(key)})
+	if err != nil {
+		return 0, err
+	}
+	defer out.Body.Close()
+
+	head := make([]byte, objectDataBlockSize)
+	n, err := io.ReadFull(out.Body, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, err
+	}
+	head = head[:n]
+
+	verify := 1
+	if args.optype == "multipartput" {
+		verify = 2
+	}
+
+	expected := make([]byte, n)
+	for w := 0; w < args.concurrency; w++ {
+		payload := args.payload
+		payload.seedSuffix = collisionSeedSuffix(w)
+		io.ReadFull(NewPayloadReader(int64(n), key, payload), expected)
+		if !bytes.Equal(head, expected) {
+			continue
+		}
+		if err := verifyObjectData(io.MultiReader(bytes.NewReader(head), out.Body), key, 0, verify, args.partsize, payload); err != nil {
+			return w, errCollisionMismatch
+		}
+		return w, nil
+	}
+	return 0, errCollisionMismatch
+}
+
+func printCollisionReport(report collisionReport, isJson bool) {
+	if isJson {
+		jsonReport, err := json.Marshal(map[string]collisionReport{"collisionReport": report})
+		if err != nil {
+			fmt.Println("Error when parsing collision report to json")
+			return
+		}
+		fmt.Println(string(jsonReport))
+		return
+	}
+
+	fmt.Println("\n\t--- Collision Results ---")
+	fmt.Printf("Keys written by all workers: %d\n", report.Keys)
+	fmt.Printf("Keys holding exactly one writer's data: %d\n", report.Verified)
+	fmt.Printf("Keys with mismatched data: %d\n", report.Mismatched)
+	fmt.Printf("Keys that could not be read: %d\n", report.Failed)
+
+	writers := make([]int, 0, len(report.Winners))
+	for w := range report.Winners {
+		writers = append(writers, w)
+	}
+	sort.Ints(writers)
+	fmt.Println("Final writer : Keys")
+	for _, w := range writers {
+		fmt.Printf("%-12d : %d\n", w, report.Winners[w])
+	}
+}
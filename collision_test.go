@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func (this *mockS3Client) GetObject(in *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	return this.S3OpHandler(in).(*s3.GetObjectOutput), nil
+}
+
+func collisionArgs() parameters {
+	args := parseAndValidate([]string{"-operation=put", "-overwrite=2", "-collision", "-concurrency=4", "-requests=8"})
+	args.osize = 3*objectDataBlockSize + 10
+	return args
+}
+
+func writerData(args parameters, key string, writer int) []byte {
+	payload := args.payload
+	payload.seedSuffix = collisionSeedSuffix(writer)
+	data, _ := ioutil.ReadAll(NewPayloadReader(args.osize, key, payload))
+	return data
+}
+
+func mockObject(data []byte) *mockS3Client {
+	return NewMockS3Client(func(in interface{}) interface{} {
+		return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(data))}
+	})
+}
+
+func TestFindCollisionWriter(t *testing.T) {
+	args := collisionArgs()
+
+	for w := 0; w < args.concurrency; w++ {
+		writer, err := findCollisionWriter(mockObject(writerData(args, "testobject-1", w)), args, "testobject-1")
+		if err != nil {
+			t.Fatalf("Expected object to verify but got: %v", err)
+		}
+
+		if writer != w {
+			t.Fatalf("Expected writer %d but got %d", w, writer)
+		}
+	}
+}
+
+func TestFindCollisionWriterTornWrite(t *testing.T) {
+	args := collisionArgs()
+
+	// the object starts with one writer's data and ends with another's
+	data := writerData(args, "testobject-1", 1)
+	copy(data[2*objectDataBlockSize:], writerData(args, "testobject-1", 2)[2*objectDataBlockSize:])
+
+	if _, err := findCollisionWriter(mockObject(data), args, "testobject-1"); err != errCollisionMismatch {
+		t.Fatalf("Expected a mismatch but got: %v", err)
+	}
+}
+
+func TestFindCollisionWriterUnknownWriter(t *testing.T) {
+	args := collisionArgs()
+
+	if _, err := findCollisionWriter(mockObject(writerData(args, "testobject-1", args.concurrency)), args, "testobject-1"); err != errCollisionMismatch {
+		t.Fatalf("Expected a mismatch but got: %v", err)
+	}
+}
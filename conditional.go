@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Outcomes of conditional requests, counted separately from failed requests.
+const (
+	conditionSatisfied          = "satisfied"
+	conditionNotModified        = "notModified"
+	conditionPreconditionFailed = "preconditionFailed"
+)
+
+// conditions holds the conditional headers sent with the GET, HEAD and PUT requests.
+type conditions struct {
+	ifMatch         string
+	ifNoneMatch     string
+	ifModifiedSince time.Time
+}
+
+// Parses the conditional headers. If-Modified-Since is an RFC 3339 date like 2030-01-01T00:00:00Z. Returns nil if
+// nothing is set.
+func parseConditions(ifMatch, ifNoneMatch, ifModifiedSince string) (*conditions, error) {
+	if ifMatch == "" && ifNoneMatch == "" && ifModifiedSince == "" {
+		return nil, nil
+	}
+	c := &conditions{ifMatch: ifMatch, ifNoneMatch: ifNoneMatch}
+	if ifModifiedSince != "" {
+		date, err := time.Parse(time.RFC3339, ifModifiedSince)
+		if err != nil {
+			return nil, fmt.Errorf("if-modified-since must be an RFC 3339 date: %s", ifModifiedSince)
+		}
+		c.ifModifiedSince = date
+	}
+	return c, nil
+}
+
+// apply sets the conditional headers of a request. PUTs only support If-Match and If-None-Match, the latter with *
+// to only create objects that don't exist yet.
+func (c *conditions) apply(operation string, header http.Header) {
+	switch operation {
+	case "GetObject", "HeadObject":
+		if !c.ifModifiedSince.IsZero() {
+			header.Set("If-Modified-Since", c.ifModifiedSince.UTC().Format(http.TimeFormat))
+		}
+	case "PutObject":
+	default:
+		return
+	}
+	if c.ifMatch != "" {
+		header.Set("If-Match", c.ifMatch)
+	}
+	if c.ifNoneMatch != "" {
+		header.Set("If-None-Match", c.ifNoneMatch)
+	}
+}
+
+// install sends the conditional headers with every GET, HEAD and PUT of the service. The headers are set on the HTTP
+// request, the request parameters of PUTs don't have conditional fields in older SDK versions.
+func (c *conditions) install(svc *s3.S3) {
+	if c == nil {
+		return
+	}
+	svc.Client.Handlers.Build.PushBack(func(r *request.Request) {
+		c.apply(r.Operation.Name, r.HTTPRequest.Header)
+	})
+}
+
+// Returns the outcome of a conditional request from its error, or an empty string if the request failed otherwise.
+func conditionOutcome(err error) string {
+	if err == nil {
+		return conditionSatisfied
+	}
+	if failure, ok := err.(awserr.RequestFailure); ok {
+		switch failure.StatusCode() {
+		case http.StatusNotModified:
+			return conditionNotModified
+		case http.StatusPreconditionFailed:
+			return conditionPreconditionFailed
+		}
+	}
+	return ""
+}
+
+func (this *result) recordCondition(outcome string) {
+	if this.Conditions == nil {
+		this.Conditions = make(map[string]int)
+	}
+	this.Conditions[outcome]++
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestParseConditions(t *testing.T) {
+	c, err := parseConditions("\"abc\"", "", "2025-06-01T12:30:00Z")
+	if err != nil {
+		t.Fatalf("valid conditions should succeed: %v", err)
+	}
+
+	if c.ifMatch != "\"abc\"" || !c.ifModifiedSince.Equal(time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Fatalf("wrong conditions: %+v", c)
+	}
+
+	if c, _ := parseConditions("", "", ""); c != nil {
+		t.Fatalf("no conditions should give nil: %+v", c)
+	}
+
+	if _, err := parseConditions("", "", "yesterday"); err == nil {
+		t.Fatalf("invalid if-modified-since should fail")
+	}
+}
+
+func TestApplyConditions(t *testing.T) {
+	c := &conditions{ifNoneMatch: "*", ifModifiedSince: time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)}
+
+	get := http.Header{}
+	c.apply("GetObject", get)
+	if get.Get("If-None-Match") != "*" || get.Get("If-Modified-Since") != "Sun, 01 Jun 2025 12:30:00 GMT" {
+		t.Fatalf("Expected the conditional headers of the GET but got %v", get)
+	}
+
+	put := http.Header{}
+	c.apply("PutObject", put)
+	if put.Get("If-None-Match") != "*" || put.Get("If-Modified-Since") != "" {
+		t.Fatalf("Expected only If-None-Match on the PUT but got %v", put)
+	}
+
+	part := http.Header{}
+	c.apply("UploadPart", part)
+	if len(part) != 0 {
+		t.Fatalf("Expected no conditional headers on other requests but got %v", part)
+	}
+}
+
+func TestConditionOutcome(t *testing.T) {
+	outcomes := map[error]string{
+		nil: conditionSatisfied,
+		awserr.NewRequestFailure(awserr.New("NotModified", "", nil), 304, "1"):        conditionNotModified,
+		awserr.NewRequestFailure(awserr.New("PreconditionFailed", "", nil), 412, "2"): conditionPreconditionFailed,
+		awserr.NewRequestFailure(awserr.New("NoSuchKey", "", nil), 404, "3"):          "",
+		errors.New("connection reset"):                                                "",
+	}
+
+	for err, outcome := range outcomes {
+		if conditionOutcome(err) != outcome {
+			t.Fatalf("Expected outcome %q of %v but got %q", outcome, err, conditionOutcome(err))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// Runs with more requests than this are not bootstrapped, their percentiles are stable and resampling them is slow.
+const bootstrapMaxRequests = 100000
+
+// confidenceInterval is the range the true value of a percentile lies in with 95% confidence, in ms.
+type confidenceInterval struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+// bootstrapPercentiles estimates 95% confidence intervals of the percentiles of the latencies from the given number of
+// resamples of the latencies recorded. Every resample draws as many latencies as were recorded, with replacement, and
+// the interval is the range of the middle 95% of the percentiles of the resamples. Returns nil without latencies.
+func bootstrapPercentiles(latencies *hdrhistogram.Histogram, resamples int, seed int64) map[string]confidenceInterval {
+	var values, cumulative []int64
+	var n int64
+	for _, bar := range latencies.Distribution() {
+		if bar.Count > 0 {
+			n += bar.Count
+			values = append(values, bar.To)
+			cumulative = append(cumulative, n)
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+
+	source := rand.New(rand.NewSource(seed))
+	estimates := make([][]float64, len(percentiles))
+	counts := make([]int64, len(values))
+	for i := 0; i < resamples; i++ {
+		for j := range counts {
+			counts[j] = 0
+		}
+		for j := int64(0); j < n; j++ {
+			r := source.Int63n(n)
+			counts[sort.Search(len(cumulative), func(k int) bool { return cumulative[k] > r })]++
+		}
+		for p, percentile := range percentiles {
+			estimates[p] = append(estimates[p], float64(valueAtPercentile(values, counts, n, percentile))/1e2)
+		}
+	}
+
+	intervals := make(map[string]confidenceInterval)
+	for p, percentile := range percentiles {
+		sort.Float64s(estimates[p])
+		intervals[convertFloatToString(percentile)] = confidenceInterval{
+			Low:  estimates[p][int(0.025*float64(resamples))],
+			High: estimates[p][int(0.975*float64(resamples-1)+0.5)],
+		}
+	}
+	return intervals
+}
+
+// Returns the value at the percentile of the values with the counts, the way the histogram computes it.
+func valueAtPercentile(values, counts []int64, n int64, percentile float64) int64 {
+	target := int64(percentile/100*float64(n) + 0.5)
+	if target < 1 {
+		target = 1
+	}
+	var total int64
+	for i, count := range counts {
+		total += count
+		if total >= target {
+			return values[i]
+		}
+	}
+	return values[len(values)-1]
+}
+
+// setupConfidenceIntervals bootstraps the percentiles of the run with bootstrap resamples, unless the run has too
+// many requests to need it.
+func (this *result) setupConfidenceIntervals(resamples int, seed int64) {
+	if resamples == 0 || this.Count == 0 {
+		return
+	}
+	if this.Count > bootstrapMaxRequests {
+		log.Printf("Not bootstrapping the percentiles of %d requests, runs of more than %d requests have stable percentiles", this.Count, bootstrapMaxRequests)
+		return
+	}
+	this.PercentileIntervals = bootstrapPercentiles(this.latencies, resamples, seed)
+	this.Resamples = resamples
+}
+
+func printConfidenceIntervals(intervals map[string]confidenceInterval, resamples int) {
+	fmt.Printf("Response Time Percentiles 95%% Confidence Intervals (%d resamples)\n", resamples)
+	for _, percentile := range percentiles {
+		key := convertFloatToString(percentile)
+		i := intervals[key]
+		fmt.Printf("%-5v  :   %v - %v ms\n", key, convertFloatToString(i.Low), convertFloatToString(i.High))
+	}
+}
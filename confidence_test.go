@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBootstrapPercentiles(t *testing.T) {
+	r := NewResult()
+	for i := int64(1); i <= 1000; i++ {
+		r.latencies.RecordValue(i * 100)
+	}
+	r.Count = 1000
+	setupResultStat(&r)
+
+	intervals := bootstrapPercentiles(r.latencies, 200, 1)
+	if len(intervals) != len(percentiles) {
+		t.Fatalf("Wrong number of intervals: %v", intervals)
+	}
+	for key, percentile := range r.Percentiles {
+		interval := intervals[key]
+		if interval.Low > interval.High || percentile < interval.Low || percentile > interval.High {
+			t.Fatalf("Interval %v of p%v doesn't contain %v", interval, key, percentile)
+		}
+	}
+	if intervals["99.9"].High-intervals["99.9"].Low > 10 || intervals["50"].High-intervals["50"].Low > 100 {
+		t.Fatalf("Intervals are too wide: %v", intervals)
+	}
+
+	if again := bootstrapPercentiles(r.latencies, 200, 1); again["50"] != intervals["50"] {
+		t.Fatalf("Resamples with the same seed should match: %v %v", again["50"], intervals["50"])
+	}
+	if empty := bootstrapPercentiles(NewResult().latencies, 200, 1); empty != nil {
+		t.Fatalf("No latencies should have no intervals: %v", empty)
+	}
+}
+
+func TestValueAtPercentile(t *testing.T) {
+	values := []int64{10, 20, 30, 40}
+	counts := []int64{1, 0, 2, 1}
+	for percentile, expected := range map[float64]int64{0: 10, 25: 10, 50: 30, 75: 30, 99: 40, 100: 40} {
+		if v := valueAtPercentile(values, counts, 4, percentile); v != expected {
+			t.Fatalf("p%v should be %v: %v", percentile, expected, v)
+		}
+	}
+}
+
+func TestSetupConfidenceIntervals(t *testing.T) {
+	r := NewResult()
+	r.latencies.RecordValue(500)
+	r.Count = 1
+	r.setupConfidenceIntervals(0, 1)
+	if r.PercentileIntervals != nil || r.Resamples != 0 {
+		t.Fatalf("Bootstrap should be off: %v", r.PercentileIntervals)
+	}
+
+	r.setupConfidenceIntervals(100, 1)
+	if r.Resamples != 100 || r.PercentileIntervals["99"] != (confidenceInterval{Low: 5, High: 5}) {
+		t.Fatalf("Wrong intervals: %v %v", r.PercentileIntervals, r.Resamples)
+	}
+
+	r = NewResult()
+	r.latencies.RecordValue(500)
+	r.Count = bootstrapMaxRequests + 1
+	r.setupConfidenceIntervals(100, 1)
+	if r.PercentileIntervals != nil {
+		t.Fatalf("Large runs shouldn't be bootstrapped: %v", r.PercentileIntervals)
+	}
+}
+
+func TestBootstrapOption(t *testing.T) {
+	if args, err := parse([]string{"-bootstrap=1000"}); err != nil || args.bootstrap != 1000 {
+		t.Fatalf("valid bootstrap should succeed: %v %v", args.bootstrap, err)
+	}
+	for _, invalid := range []string{"-bootstrap=-1", "-bootstrap=10"} {
+		if _, err := parse([]string{invalid}); err == nil {
+			t.Fatalf("%s should be invalid", invalid)
+		}
+	}
+}
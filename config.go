@@ -1,10 +1,14 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"golang.org/x/time/rate"
 	"log"
 	"math"
@@ -12,6 +16,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // intFlag is used to differentiate user-defined value from default value
@@ -35,6 +40,41 @@ func (intf *intFlag) String() string {
 	return strconv.Itoa(intf.value)
 }
 
+// durationFlag is a test duration, either a number of seconds or a duration like 10m
+type durationFlag struct {
+	set   bool
+	value time.Duration
+}
+
+func (d *durationFlag) Set(content string) error {
+	if seconds, err := strconv.Atoi(content); err == nil {
+		d.value = time.Duration(seconds) * time.Second
+	} else if d.value, err = time.ParseDuration(content); err != nil {
+		return errors.New("duration must be a number of seconds or a duration like 10m")
+	}
+	if d.value <= 0 {
+		return errors.New("duration must be > 0")
+	}
+	d.set = true
+	return nil
+}
+
+func (d *durationFlag) String() string {
+	return d.value.String()
+}
+
+// repeatedFlag collects the values of a flag that can be given any number of times
+type repeatedFlag []string
+
+func (f *repeatedFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
 type parameters struct {
 	concurrency        int
 	osize              int64
@@ -46,82 +86,391 @@ type parameters struct {
 	metadata           string
 	consistencyControl string
 	ratePerSecond      rate.Limit
+	rate               float64
+	maxBandwidth       float64
+	workerBandwidth    float64
 	logging            bool
 	logdetail          string
+	logdetailPhase     bool
+	bundle             string
+	auditLog           string
+	detailedLog        string
+	stateDigest        bool
+	expectStateDigest  string
 	loglatency         string
 	objrange           string
+	responseOverrides  responseOverrides
 	reducedRedundancy  bool
+	storageClasses     *storageClassMix
+	mix                *operationMix
+	keyOffset          int64
+	stages             []*workloadStage
+	statusCodes        bool
 	overwrite          int
 	retries            int
 	retrySleep         int
+	retryBudget        *retryBudget
+	timeouts           timeoutConfig
+	httpPercent        int
+	httpPort           string
 	lockstep           bool
 	attempts           int
 	region             string
 	partsize           int64
+	partConcurrency    int
+	rangeSize          int64
+	rangeConcurrency   int
+	rangeThreshold     int64
+	rangeDist          string
+	rangeLength        int64
 	verify             int
 	min                int64
 	max                int64
 	jsonDecoder        *json.Decoder
+	replayAccessLog    string
+	replaySpeed        float64
+	replayTrace        string
 	nrequests          *intFlag
-	duration           *intFlag
+	duration           *durationFlag
+	maxDuration        time.Duration
+	overflow           string
 	cpuprofile         string
 	isJson             bool
+	resultsFormat      string
+	resultsFile        string
+	benchSuite         string
+	benchBaseline      *scorecard
+	benchOutput        string
 	tier               string
 	days               int64
+	restorePoll        time.Duration
+	restoreTimeout     time.Duration
+	selectExpression   string
+	selectFormat       string
+	shuffleSeed        int64
+	keyDistribution    *keyDistribution
+	keyTemplate        *keyTemplate
+	keyList            *keyList
+	tree               *namespaceTree
+	species            *speciesMix
+	speciesName        string
+	listInventory      bool
+	inventorySample    float64
+	inventoryMaxKeys   int
+	heatmapPrefix      int
+	heatmapTop         int
 	profile            string
 	nosign             bool
+	memWatchdog        *memoryWatchdog
+	gogc               int
+	gcMemoryLimit      int
+	resultStream       *resultStream
+	discovery          *endpointDiscovery
+	ramp               *rampSchedule
+	loadShape          *loadShape
+	phases             map[string]bool
+	bootstrap          int
+	cleanup            bool
+	objectLock         *objectLock
+	acl                cannedACL
+	bucketPerWorker    bool
+	numBuckets         int
+	bucketPlacement    string
+	bucketPolicy       string
+	notificationArn    string
+	encryption         *encryption
+	pipelineStages     []string
+	pipelineDepth      int
+	stampIdentity      bool
+	runId              string
+	isolateRun         bool
+	abortIncomplete    bool
+	verifyManifest     string
+	conditions         *conditions
+	requestExtras      *requestExtras
+	metadataTemplate   *metadataTemplate
+	payload            payloadOptions
+	dataPipe           string
+	dataCmd            string
+	collision          bool
+	generations        bool
+	checksumAlgorithm  string
+	writeLimit         *writeLimit
+	dryrun             bool
+	cost               bool
+	costModel          costModel
+	versionRatio       int
+	versionFile        string
+	versionsPerKey     int
+	// the n-th key is key n modulo overwriteKeys, which are rewritten again and again
+	overwriteKeys       int64
+	overwriteVersioning string
+	readWhileWrite      int
+	heavyConcurrency    int
+	heavyOperation      string
+	heavySize           int64
+	heavyRatePerSecond  rate.Limit
+	heavyStart          time.Duration
+	writeAcks           *writeAcks
+	recordedVersions    map[string][]string
+	listApi             string
+	listMode            string
+	delimiter           string
+	maxKeys             int64
+	batchSize           int
+	copyBucket          string
+	copyPrefix          string
+	copyThreshold       int64
+	destEndpoint        string
+	destProfile         string
+	maxConnsPerHost     int
+	dnsRefresh          time.Duration
+	clientCert          *clientCertificate
+	clientCertRefresh   time.Duration
+	mpuThreshold        int64
+	metadataDirective   string
+	presignMethod       string
+	presignExpiry       time.Duration
+	presignTransfer     bool
+	presignExport       *presignExport
+	presignedURLs       map[string]presignedURL
+	// the keys deleted by the next multidelete request
+	batchKeys []string
+	// the marker of the page listed by the next list request
+	listMarker string
+	// the scheme of the requests of this worker, only set when traffic is split with httpPercent
+	scheme string
+	// the traffic class of the requests of this worker, only set with heavyConcurrency
+	trafficClass string
+	// the responses to the requests of this worker, only recorded with detailedLog
+	responses *responseRecorder
+	// the storage class of the object written by the next request, only set with storageClasses
+	storageClass string
+	// the version targeted by the next versionedget or versioneddelete request, empty for the latest version
+	versionId string
+	// the multipart upload of the key of the next listparts request
+	uploadId string
+	// the version id marker of the page listed by the next listversions request
+	versionIdMarker string
+	// the number of the bucket of the next request, only set with numBuckets
+	bucketNumber int
+	// the credentials of dest-endpoint, loaded once per run
+	destCredentials *credentials.Credentials
+	// the service of dest-endpoint of the worker, only set with copyacross
+	destination s3iface.S3API
+	// the arrival schedule of the run shared by all workers, only set with rate
+	arrivals *arrivalSchedule
+	// the bandwidth limiter of the run shared by all workers, only set with maxBandwidth
+	bandwidth *rate.Limiter
+	// the worker sending the requests
+	workerId int
 }
 
 func parseArgs() parameters {
+	if len(os.Args) > 1 && os.Args[1] == "mktree" {
+		args, err := parseMakeTree(os.Args[2:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Writing a tree of %d directories with %d objects of %d bytes under '%s/%s'", args.tree.directories(), args.tree.objects(), args.osize, args.bucketname, args.objectprefix)
+		return args
+	}
 	return parseAndValidate(os.Args[1:])
 }
 
+// newFlagSet returns the flag set parse defines the flags of a run in. Tests replace it to look the flags up.
+var newFlagSet = func() *flag.FlagSet {
+	return flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+}
+
 func parse(cmdline []string) (parameters, error) {
-	optypes := []string{"put", "multipartput", "get", "puttagging", "updatemeta", "randget", "delete", "options", "head", "restore"}
+	optypes := []string{"put", "multipartput", "get", "puttagging", "gettagging", "deletetagging", "updatemeta", "randget", "delete", "options", "head", "restore", "initmultipart", "listmultipartuploads", "listparts", "abortmultipart", "versionedget", "list", "parallelget", "rangeget", "multidelete", "copy", "copyacross", "presign", "presignedurl", "listversions", "versioneddelete", "putretention", "getretention", "putlegalhold", "getlegalhold", "pipeline", "select", "putacl", "getacl", "putlifecycle", "getlifecycle", "deletelifecycle", "putpolicy", "getpolicy", "deletepolicy", "putcors", "getcors", "deletecors", "putnotification", "getnotification", "createbucket", "deletebucket"}
 	operationListString := strings.Join(optypes[:], ", ")
 
 	consistencyControlTypes := []string{"all", "available", "strong-global", "strong-site", "read-after-new-write", "weak"}
 	consistencyControlString := strings.Join(consistencyControlTypes[:], ", ")
 
-	var duration intFlag
+	var duration durationFlag
 	nrequests := intFlag{value: 1000, set: false}
 
-	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags := newFlagSet()
 
-	flags.Var(&duration, "duration", "Test duration in seconds")
+	var maxDuration durationFlag
+	flags.Var(&maxDuration, "max-duration", "Time box of a run with requests, a number of seconds or a duration like 10m. What happens when it is up before all requests were sent is up to overflow.")
+	var overflow = flags.String("overflow", overflowTruncate, "Policy of a run whose max-duration is up before all its requests were sent: truncate stops and reports the requests sent, fail stops as well and counts the run as failed, extend sends the rest of the requests and reports by how long the run overran.")
+	flags.Var(&duration, "duration", "Test duration, a number of seconds or a duration like 10m. Reads and mixes with requests go over the keys of the requests again and again until the time is up.")
 	flags.Var(&nrequests, "requests", "Total number of requests")
 
 	var concurrency = flags.Int("concurrency", 1, "Maximum concurrent requests (0=scan concurrency, run with ulimit -n 16384)")
 	var osize = flags.Int64("size", 30*1024, "Object size. Note that s3tester is not ideal for very large objects as the entire body must be read for v4 signing and the aws sdk does not support v4 chunked. Performance may degrade as size increases due to the use of v4 signing without chunked support")
 	var consistencyControl = flags.String("consistency", "", "The StorageGRID consistency control to use for all requests. Does nothing against non StorageGRID systems. ("+consistencyControlString+")")
-	var endpoint = flags.String("endpoint", "https://127.0.0.1:18082", "target endpoint(s). If multiple endpoints are specified separate them with a ','. Note: the concurrency must be a multiple of the number of endpoints.")
+	var endpoint = flags.String("endpoint", "https://127.0.0.1:18082", "target endpoint(s). If multiple endpoints are specified separate them with a ','. An endpoint can include the path under which a gateway serves the S3 API. Note: the concurrency must be a multiple of the number of endpoints.")
 	var optype = flags.String("operation", "put", "operation type: "+operationListString)
 	var bucketname = flags.String("bucket", "test", "bucket name (needs to exist)")
 	var objectprefix = flags.String("prefix", "testobject", "object name prefix")
-	var tagging = flags.String("tagging", "", "The tag-set for the object. The tag-set must be formatted as such: 'tag1=value1&tage2=value2'. Used for put, puttagging, putget and putget9010r.")
-	var metadata = flags.String("metadata", "", "The metadata to use for the objects. The string must be formatted as such: 'key1=value1&key2=value2'. Used for put, updatemeta, multipartput, putget and putget9010r.")
+	var bucketPerWorker = flags.Bool("bucket-per-worker", false, "Every worker uses a bucket of its own named \"<bucket>-<worker>\", which it creates unless it exists already, to spread the objects over many buckets")
+	var numBuckets = flags.Int("num-buckets", 0, "Spread the keys over this many buckets named \"<bucket>-0\" to \"<bucket>-<n-1>\", which writes create unless they exist already, and report the requests and throughput per bucket. Default (0) uses the bucket only.")
+	var bucketPlacement = flags.String("bucket-placement", bucketPlacementRoundRobin, "How keys are placed in the buckets of num-buckets: "+bucketPlacementRoundRobin+" places consecutive keys in consecutive buckets, "+bucketPlacementHash+" places a key by the hash of its name")
+	var tagging = flags.String("tagging", "", "The tag-set for the object. The tag-set must be formatted as such: 'tag1=value1&tage2=value2'. Used for put, multipartput, initmultipart, puttagging, putget and putget9010r.")
+	var metadata repeatedFlag
+	flags.Var(&metadata, "metadata", "The metadata to use for the objects. The string must be formatted as such: 'key1=value1&key2=value2', the flag can be repeated. Values can be templates like '{{.Key}}', '{{.WorkerID}}', '{{random 8}}' or '{{blob 1024}}'. Used for put, updatemeta, copy, multipartput, putget and putget9010r.")
 	var cpuprofile = flags.String("cpuprofile", "", "write cpu profile to file")
+	var detailedLog = flags.String("detailed-log", "", "Write a record of every request to this file as it completes, with its start time, operation, bucket, key, bytes transferred, HTTP status, time to first byte, total latency and error code, for offline analysis. The file is CSV with a header line, or JSON lines if its name ends in .json, .jsonl or .ndjson.")
+	var auditLogPath = flags.String("audit-log", "", "Write a line in the format of the S3 server access logs for every attempt of every request sent to this file, with the request id, operation, key, status, error code and times of the attempt, so that it can be compared with the server access logs, e.g. to find the requests that never reached the server")
+	var bundlePath = flags.String("bundle", "", "Package every artifact of the run, i.e. the command line, the log, the results and latency histograms of every phase and the files written by the run, with a manifest into this tar.gz file")
 	var logdetail = flags.String("logdetail", "", "write detailed log to file")
-	var loglatency = flags.String("loglatency", "", "write latency histogram to file")
+	var logdetailPhase = flags.Bool("logdetail-phase", false, "End every line of logdetail in the label of the phase of the run the request belongs to, like put-8")
+	var loglatency = flags.String("loglatency", "", "write latency histogram to file. A file ending in .hgrm gets the percentile distribution in the format of HdrHistogram instead, with a file of its own for every operation of runs broken down per operation.")
 	var maxRate = flags.Float64("ratelimit", math.MaxFloat64, "the total number of operations per second across all threads")
+	var maxBandwidth = flags.String("max-bandwidth", "", "Total bandwidth of the object data sent and received by all workers, like 500MB/s, 1GiB/s or 100Mbit/s. Uploads and downloads are throttled to it together, to emulate clients on constrained links or to avoid saturating a shared network. Default is no limit.")
+	var workerBandwidth = flags.String("worker-max-bandwidth", "", "Bandwidth of the object data sent and received by every worker, like max-bandwidth. Default is no limit.")
+	var arrivalRate = flags.Float64("rate", 0, "Start requests at this total number of operations per second across all threads, independent of how long they take (open loop), instead of every worker sending its next request once the last one completed. Requests due while all workers are busy start late, and their latency is measured from when they were due. Default (0) is closed loop.")
 	var objrange = flags.String("range", "", "Specify range header for GET requests")
+	var overrides = flags.String("response-overrides", "", "Response headers the server is asked to override with the response-* query parameters on GET requests, formatted as 'content-type=text/plain&cache-control=no-cache'. One of cache-control, content-disposition, content-encoding, content-language, content-type or expires. Responses that don't carry the requested values fail.")
 	var reducedRedundancy = flags.Bool("rr", false, "Reduced redundancy storage for PUT requests")
+	var storageClass = flags.String("storage-class", "", "Storage class of the objects written by put, multipartput, initmultipart and copy, e.g. STANDARD_IA or GLACIER_IR. A weighted mix like STANDARD=70,STANDARD_IA=20,GLACIER_IR=10 picks the class of every object at random and reports the latency per class.")
 	var overwrite = flags.Int("overwrite", 0, "Turns a PUT/GET/HEAD into an operation on the same s3 key. (1=all writes/reads are to same object, 2=threads clobber each other but each write/read is to unique objects).")
+	var clientCertFile = flags.String("client-cert", "", "PEM file of the client certificate presented to endpoints that require mutual TLS, with client-key")
+	var clientKeyFile = flags.String("client-key", "", "PEM file of the private key of client-cert")
+	var clientCertRefresh = flags.Duration("client-cert-refresh", 10*time.Second, "Interval at which the files of client-cert and client-key are checked for changes, e.g. by a certificate rotation. A changed certificate is reloaded and used by the connections established from then on, as it is on SIGHUP. 0 only reloads on SIGHUP.")
+	var dnsRefresh = flags.Duration("dns-refresh", 0, "Close the idle connections of every worker at this interval, e.g. 30s, so that the next requests connect again and look the endpoint up again, like clients that honour a short DNS TTL. The results report the time and failures of the DNS lookups. Default (0) keeps connections alive as long as the server does.")
+	var maxConnsPerHost = flags.Int("max-conns-per-host", 0, "Maximum number of connections of the whole client to every endpoint host. Requests wait for a free connection once the limit is reached, and the time they waited is reported. Default (0) is no limit, every worker has connections of its own.")
 	var retries = flags.Int("retries", 0, "Number of retry attempts. Default is 0.")
+	var retryBudget = flags.Float64("retry-budget", 0, "Maximum retries of all workers together as a percentage of their requests, e.g. 10. Requests that would exceed the budget fail without being retried, so that a failing backend isn't hit by a storm of retries. Default (0) is no budget.")
 	var retrySleep = flags.Int("retrysleep", 0, "How long to sleep in between each retry in milliseconds. Default (0) is to use the default retry method which is an exponential backoff.")
+	var connectTimeout = flags.String("connect-timeout", "", "Timeout for establishing a connection, including the TLS handshake, e.g. 2s. Either one duration or a comma separated list where class=duration entries set the timeout of the read, write or delete requests, e.g. '2s,write=5s'. Default is no timeout.")
+	var headerTimeout = flags.String("header-timeout", "", "Timeout from sending a request until the first byte of the response is received. Same format as connect-timeout. Default is no timeout.")
+	var requestTimeout = flags.String("request-timeout", "", "Total timeout of each request including reading the response body. Same format as connect-timeout, e.g. '30s,write=1h'. Default is no timeout.")
+	var httpPercent = flags.Int("http-percent", 0, "Percentage (0-100) of the workers of each endpoint that send plain HTTP instead of HTTPS requests to the same endpoint, to compare the TLS overhead within one run. The results include the latency of each scheme.")
+	var httpPort = flags.String("http-port", "", "Port of the plain HTTP requests sent with http-percent. Default is the port of the endpoint.")
 	var lockstep = flags.Bool("lockstep", false, "Force all threads to advance at the same rate rather than run independently")
+	var overwriteKeys = flags.Int64("overwrite-keys", 0, "Rewrite a fixed set of this many keys again and again instead of writing new keys: the n-th request goes to key n modulo overwrite-keys, so a put run of more requests, or one limited by duration only, keeps overwriting the same objects. Reads and deletes go over the same keys. Default (0) is off.")
+	var readWhileWrite = flags.Int("read-while-write", 0, "Read the keys of overwrite-keys while they are overwritten: this many of the workers put new generations of the keys again and again while the others get them, with write generations. Every read is compared with the writes acknowledged before it started and reports whether it returned an older generation and for how long, and whether its data mixes generations. Default (0) is off.")
+	var heavyConcurrency = flags.Int("heavy-concurrency", 0, "Run a bandwidth-heavy traffic class of this many of the workers next to the latency-sensitive class of the others, which send operation with size and ratelimit as usual. The results of both classes are reported separately, with how much the heavy class degraded the latency and throughput of the sensitive class. Requires duration. Default (0) is off.")
+	var heavyOperation = flags.String("heavy-operation", "put", "Operation of the heavy class of heavy-concurrency: put, multipartput or get. Its keys are under the prefix <prefix>-heavy.")
+	var heavySize = flags.Int64("heavy-size", 64*1024*1024, "Object size of the heavy class of heavy-concurrency")
+	var heavyRate = flags.Float64("heavy-ratelimit", math.MaxFloat64, "The total number of operations per second across the workers of the heavy class of heavy-concurrency, ratelimit limits the sensitive class only")
+	var heavyStart = flags.Duration("heavy-start", 0, "Time the sensitive class of heavy-concurrency runs alone before the heavy class starts, to measure its latency without contention first, e.g. 1m of a 3m duration. Default (0) starts both classes together.")
+	var maxStaleness = flags.Duration("max-staleness", 0, "Time a read of read-while-write may return an older generation than a write acknowledged before the read started without being reported as stale, e.g. 1s for an eventually consistent store. Default (0) expects every read to return the latest acknowledged write.")
+	var overwriteVersioning = flags.String("overwrite-versioning", "", "Versioning the bucket is set to before an overwrite-keys run: enabled keeps every overwritten object as a noncurrent version, suspended replaces the null version of the key. Default leaves the versioning of the bucket as it is.")
 	var repeat = flags.Int("repeat", 0, "Repeat each S3 operation this many times, by default doesn't repeat (i.e. repeat=0)")
 	var region = flags.String("region", "us-east-1", "Region to send requests to")
-	var partsize = flags.Int64("partsize", 5*(1<<20), "Size of each part (min 5MiB); only has an effect when a multipart put is used")
-	var verify = flags.Int("verify", 0, "Verify the retrieved data on a get operation - (0=disable verify(default), 1=normal put data, 2=multipart put data). If verify=2, partsize is required and default partsize is set to 5242880.")
+	var partsize = flags.Int64("partsize", 0, "Size of each part (5MiB-5GiB); only has an effect when a multipart put, initmultipart or multipart copy is used. Default (0) picks the smallest whole MiB part size of at least 5MiB that uploads an object of the given size in at most 10000 parts.")
+	var partConcurrency = flags.Int("part-concurrency", 1, "Number of parts of a multipart put, initmultipart or multipart copy that are uploaded in parallel by each worker")
+	var rangeSize = flags.Int64("range-size", 5*(1<<20), "Size of each ranged GET of a parallelget")
+	var rangeThreshold = flags.Int64("range-threshold", 0, "Objects of a parallelget up to this size are downloaded with a single GET and larger ones with ranged GETs, the size is found with a HEAD first like the SDK transfer managers do. Default (0) always starts with the first ranged GET.")
+	var rangeConcurrency = flags.Int("range-concurrency", 5, "Number of ranged GETs of a parallelget that are sent in parallel by each worker")
+	var rangeDist = flags.String("range-dist", "fixed", "How rangeget picks the offset of each range within an object of the given size: fixed (start of the object), aligned (random multiple of range-length) or unaligned (random byte offset)")
+	var rangeLength = flags.Int64("range-length", 1<<20, "Length of the range read by each rangeget request")
+	var verify = flags.Int("verify", 0, "Verify the retrieved data on a get operation - (0=disable verify(default), 1=normal put data, 2=multipart put data). If verify=2, partsize must be the part size the objects were written with, which the default picks for objects of the same size. Object data is derived from the key only, so ranged GETs of objects written by an earlier run can be verified as well.")
 
 	var uniformDist = flags.String("uniformDist", "", "Generates a uniform distribution of object sizes given a min-max size (10-20)")
 	var isJson = flags.Bool("json", false, "The result will be printed out in JSON format if this flag exists")
+	var resultsFormat = flags.String("results-format", "text", "Format of the results: text, or json for a document summarizing every run with the requests, bytes, throughput and latency percentiles of every operation and the failed requests per error code, printed once the process is done instead of the results of every run")
+	var resultsFile = flags.String("results-file", "", "Write the results document of results-format json to this file, the output is printed as usual")
 	var tier = flags.String("tier", "standard", "The retrieval option for restoring an object. One of expedited, standard, or bulk. AWS default option is standard if not specified")
 	var days = flags.Int64("days", 1, "The number of days that the restored object will be available for")
-	var workload = flags.String("workload", "", "Filepath to a Mixedworkload JSON formatted file which allows a user to specify a mixture of operations. A sample mixed workload file must be in the format\n'{'mixedWorkload':\n[{'operation':'put','ratio':25},\n{'operationType':'get','ratio':25},\n{'operationType':'updatemeta','ratio':25},\n{'operationType':'delete','ratio':25}]}'.  \nNOTE: The order of operations specified will generate the requests in the same order.\nI.E. If you have delete followed by a put, but no objects on your grid to delete, all your deletes will fail.")
+	var restorePoll = flags.Duration("restore-poll", 0, "Interval at which the restore operation HEADs every object after its restore request until the restore is completed, to measure the restore turnaround time. Default (0) sends the restore requests only.")
+	var heatmapPrefixLength = flags.Int("heatmap-prefix-length", 0, "Aggregate the latency of the requests per key prefix of this many characters and report the prefixes with the highest average latency, to find hot partitions of the backend. Default (0) is off.")
+	var heatmapTop = flags.Int("heatmap-top", 10, "Number of key prefixes with the highest average latency reported with heatmap-prefix-length")
+	var keyDistribution = flags.String("key-distribution", "sequential", "How get, head, rangeget and delete pick the keys of the objects written by a put run with the same requests: sequential goes through the keys in sequence, uniform picks them at random, zipfian[:s] picks a few keys most of the time (s > 1, default 1.1) and hotspot:x/y sends x% of the requests to y% of the keys.")
+	var shuffleSeed = flags.Int64("shuffle-seed", 0, "Every worker accesses its keys in a pseudo-random order given by this seed instead of in sequence. Runs with the same seed, concurrency and requests access the keys in the same order, e.g. to compare repeated reads of a population. Default (0) accesses the keys in sequence.")
+	var selectExpression = flags.String("select-expression", "SELECT * FROM S3Object s", "SQL expression of the select operation")
+	var selectFormat = flags.String("select-format", "csv", "Format of the objects queried by the select operation: csv (with a header line), json (one document per line) or parquet")
+	var restoreTimeout = flags.Duration("restore-timeout", 48*time.Hour, "Time after which a polled restore that is not completed fails")
+	var mixOperations = flags.String("mix", "", "Weighted mix of operations like get:70,put:20,delete:5,head:5 sent in one run instead of a single operation. The operation of every request is picked at random in proportion to the weights and the results are broken down per operation.")
+	var keyTemplateFlag = flags.String("key-template", "", "Name the keys by a template instead of <prefix>-<n>, like {{hash8}}/{{prefix}}-{{counter}}. The placeholders are {{prefix}}, {{counter}} or {{counter:N}} padded with zeros to N digits, {{uuid}} and {{hashN}}, the first N hex characters of the MD5 of the key, derived from the key number, and {{date}}, the UTC date the run started like 2026/10/15.")
+	var speciesFile = flags.String("species", "", "JSON file of the species of the objects, like [{\"name\": \"thumb\", \"size\": 16384, \"contentType\": \"image/png\", \"compressRatio\": 1, \"share\": 60}, ...]. Every key belongs to a species picked by the hash of the key and the share of the species, which sets the size, content type and generated data (compressRatio like compress-ratio) of its object, and the results are reported per species.")
+	var keysFromFile = flags.String("keys-from-file", "", "File of existing keys, one per line or key,size CSV lines, that get, head, rangeget, randget and delete go through instead of the keys of a put run. The number of requests defaults to the number of keys and the size of a key is used as its object size.")
+	var listInventory = flags.Bool("list-inventory", false, "Before the run, list the objects under prefix and go through their keys with get, head, rangeget, randget or delete instead of the keys of a put run. The number of requests defaults to the number of keys and the size of a key is used as its object size.")
+	var inventorySample = flags.Float64("inventory-sample", 1, "Share (0-1] of the objects listed with list-inventory that are picked at random for the inventory")
+	var inventoryMaxKeys = flags.Int("inventory-max-keys", 0, "Maximum number of keys of the inventory of list-inventory, picked at random from all objects listed. Default (0) is no limit.")
+	var keyOffset = flags.Int64("key-offset", 0, "Number of the first key, the keys are named <prefix>-<key-offset + n>. Runs with different offsets work on different ranges of keys.")
+	var replayAccessLog = flags.String("replay-accesslog", "", "Replay the PUT, GET, HEAD and DELETE object requests of S3 server access logs, a file or a directory of log files, with the keys and object sizes of the log instead of running an operation. Every key is replayed by the same worker in the order of the log, to a bucket named after the bucket of the log with the suffix s3tester, which is created by the replay.")
+	var replayTrace = flags.String("replay-trace", "", "Replay the put, get, head and delete requests of a trace file instead of running an operation, a CSV file ending in .csv whose header names its timestamp, op, key, size, range and bucket columns, or JSON objects with these fields. The timestamp is an RFC 3339 time or seconds, size and range (bytes=0-1023) are optional and the bucket defaults to bucket. Every key is replayed by the same worker in the order of the trace, to a bucket named after its bucket with the suffix s3tester, which is created by the replay.")
+	var replaySpeed = flags.Float64("replay-speed", 0, "Send the requests of replay-accesslog or replay-trace at their original times relative to the first request, this many times as fast, e.g. 1 for the original timing or 10 for ten times as fast. Default (0) sends them as fast as the workers go.")
+	var workload = flags.String("workload", "", "Filepath to a Mixedworkload JSON formatted file which allows a user to specify a mixture of operations. A sample mixed workload file must be in the format\n'{'mixedWorkload':\n[{'operation':'put','ratio':25},\n{'operationType':'get','ratio':25},\n{'operationType':'updatemeta','ratio':25},\n{'operationType':'delete','ratio':25}]}'.  \nNOTE: The order of operations specified will generate the requests in the same order.\nI.E. If you have delete followed by a put, but no objects on your grid to delete, all your deletes will fail.\nA workload file with a list of 'stages' runs the stages one after the other instead, each with its own operation or mix, concurrency, requests or duration, object size and key range.")
 	var profile = flags.String("profile", "", "Use a specific profile from AWS CLI credential file (https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-profiles.html).")
 	var nosign = flags.Bool("no-sign-request", false, "Do not sign requests. Credentials will not be loaded if this argument is provided.")
+	var compressRatio = flags.Float64("compress-ratio", 0, "Target compressibility of the generated object data, e.g. 2 for 2:1 or 4 for 4:1 (must be >= 1). Data is a mix of random bytes and runs of zeros. Default (0) repeats the object key which is highly compressible.")
+	var dedupeRatio = flags.String("dedupe-ratio", "", "Generate dedupable object data at the given ratio across all requests of the run, e.g. 3:1. Objects are made of chunks drawn from a shared pool of distinct chunks.")
+	var dedupeChunk = flags.Int64("dedupe-chunk", 128*1024, "Size of the chunks used with dedupe-ratio in bytes. Must be a multiple of 4096.")
+	var payloadFile = flags.String("payload-file", "", "Use the content of this local file as the payload of every PUT instead of generated data. The object size is the file size.")
+	var payloadDir = flags.String("payload-dir", "", "Use the files in this local directory as PUT payloads in round-robin order instead of generated data. The object size is the file size.")
+	var dataPipe = flags.String("data-pipe", "", "Read the payloads of PUTs from this named pipe, which an external generator writes to, instead of generating them. Every object takes the next size bytes written to the pipe.")
+	var dataCmd = flags.String("data-cmd", "", "Read the payloads of PUTs from the output of this shell command, e.g. an external generator of domain specific data, instead of generating them. Every object takes the next size bytes of the output.")
+	var payloadCache = flags.Bool("payload-cache", false, "Read the payload-file or payload-dir files into memory once instead of reading them from disk for every PUT.")
+	var collision = flags.Bool("collision", false, "Object name collision mode for put and multipartput with overwrite=2: every worker writes its own deterministic payload to the same keys, afterwards each key is read back to check it holds exactly one writer's data and the outcomes are reported.")
+	var generations = flags.Bool("generations", false, "Stale read detection for overwrite workloads: every object written starts with a 16 byte header holding the time its write started, and get, parallelget and versionedget requests of the latest version report reads of an older write than one already read of the key by the same worker.")
+	var maxObjects = flags.Int64("max-objects", 0, "Safety cap on the number of objects written over the whole run, by put, multipartput, initmultipart, copy, copyacross, pipeline and uploads with presigned PUT URLs. Write requests stop once it is reached. Default (0) is no limit.")
+	var maxBytes = flags.Int64("max-bytes", 0, "Safety cap on the number of bytes written over the whole run, by put, multipartput, initmultipart, copy, copyacross, pipeline and uploads with presigned PUT URLs. Copies are charged with the object size. Write requests stop once it is reached. Default (0) is no limit.")
+	var copyBucket = flags.String("copy-bucket", "", "Destination bucket of the copy operation (needs to exist). Default is the source bucket.")
+	var copyPrefix = flags.String("copy-prefix", "copy", "Object name prefix of the destination keys of the copy operation. The key \"<prefix>-N-M\" is copied to \"<copy-prefix>-N-M\".")
+	var mpuThreshold = flags.Int64("mpu-threshold", 0, "PUTs of objects larger than this size are sent as multipart uploads of partsize parts instead, like the transfer managers of the SDKs. The results report the number of requests that used multipart uploads. Default (0) always sends single PUTs.")
+	var destEndpoint = flags.String("dest-endpoint", "", "Endpoint the copyacross operation writes the objects to, which are read from the endpoint.")
+	var destProfile = flags.String("dest-profile", "", "Profile of the AWS CLI credential file used for the requests to dest-endpoint. Default is the credentials of the endpoint.")
+	var copyThreshold = flags.Int64("copy-threshold", 5*(1<<30), "Objects of the copy operation larger than this size are copied with a multipart upload of UploadPartCopy requests of partsize bytes instead of a single CopyObject, which is limited to 5GiB. The object size is given with -size.")
+	var metadataDirective = flags.String("metadata-directive", "COPY", "Metadata directive of the copy operation: COPY keeps the metadata of the source object, REPLACE sets the metadata given with -metadata instead")
+	var presignMethod = flags.String("presign-method", "GET", "HTTP method of the URLs generated by the presign operation: GET or PUT")
+	var presignExpiry = flags.Duration("presign-expiry", 15*time.Minute, "Expiry of the URLs generated by the presign operation, at most 168h")
+	var presignExportFile = flags.String("presign-export", "", "File the presign operation writes the URLs it generates to, as key,method,expires,url CSV lines, for other tools or a presignedurl run on another machine")
+	var presignExpiryLadder = flags.String("presign-expiry-ladder", "", "Expiries like 1m,15m,1h,24h that the URLs written with presign-export go round, instead of presign-expiry for all of them")
+	var presignURLs = flags.String("presign-urls", "", "File written with presign-export whose URLs the presignedurl operation transfers the objects with")
+	var presignTransfer = flags.Bool("presign-transfer", false, "Every request of the presign operation transfers the object with the URL it generated, using a plain HTTP client without any SDK signing, and the presign and transfer times are reported separately")
+	var batchSize = flags.Int("batch-size", 1000, "Number of keys deleted by each multidelete request (1-1000)")
+	var listApi = flags.String("list-api", "v2", "The listing API used by the list operation: v1 (marker based paging) or v2 (continuation token based paging)")
+	var listMode = flags.String("list-mode", "page", "What each request of the list operation lists: page (the next page, starting over after the last page) or full (every page under the prefix)")
+	var delimiter = flags.String("delimiter", "", "Delimiter of the list operation, keys that contain it after the prefix are rolled up into common prefixes")
+	var maxKeys = flags.Int64("max-keys", 0, "Maximum number of keys per page of the list operation (1-1000). Default (0) uses the server's default of usually 1000.")
+	var versionRatio = flags.Int("version-ratio", 50, "Percentage (0-100) of versionedget and versioneddelete requests that target an explicitly chosen version of the object. The remaining requests target the latest version.")
+	var versionsPerKey = flags.Int("versions-per-key", 0, "Prepares a versioned population for versionedget, versioneddelete and listversions: put or multipartput enables versioning on the bucket and writes this many versions of every key, which are recorded in the version-file. Cannot be combined with repeat.")
+	var versionFile = flags.String("version-file", "", "With put or multipartput, write the key and version id of every object written to a versioned bucket to this CSV file. With versionedget or versioneddelete, pick the versions to target from this file instead of listing the versions of each object.")
+	var pipelineStages = flags.String("pipeline-stages", "write,read,delete", "Stages every object of the pipeline operation flows through in order, some of write (put), read (get), tag (puttagging) and delete")
+	var pipelineDepth = flags.Int("pipeline-depth", 10, "Number of objects that can wait for each stage of the pipeline operation before the stages in front of it are blocked")
+	var sse = flags.String("sse", "", "Server side encryption of the objects written: s3 (SSE-S3, AES256) or kms (SSE-KMS, aws:kms)")
+	var sseKmsKeyId = flags.String("sse-kms-key-id", "", "KMS key id of the objects written with SSE-KMS. Default is the AWS managed key of the bucket.")
+	var checksumAlgorithm = flags.String("checksum-algorithm", "", "Flexible checksum algorithm: CRC32, CRC32C, SHA1 or SHA256. The checksum of the data is sent with put and multipart part uploads, and get requests ask for the stored checksum and verify it against the data read. The time spent computing checksums is reported.")
+	var verifyETag = flags.Bool("verify-etag", false, "Compare the MD5 of the objects read by get requests with their ETag. Mismatches and bodies cut short of their Content-Length are reported as soft failures, which are not counted as failed requests. Ranges and the ETags of multipart uploads and of SSE-KMS and SSE-C objects are not verified. With copyacross the ETags the destination returns for the objects and parts written are compared with the MD5 of the data instead.")
+	var sseCustomerKey = flags.String("sse-c-key", "", "Base64 encoded 256 bit key of SSE-C, sent with every request that writes or reads object data. Requires HTTPS endpoints.")
+	var benchSuite = flags.String("bench-suite", "", "Run the given version of the standard bench suite instead of a single operation: small writes, large writes and reads, listing, a mixed pipeline and deletes, followed by a scorecard of the phases. Versions: "+strings.Join(benchSuiteVersions(), ", "))
+	var benchBaseline = flags.String("bench-baseline", "", "Scorecard saved with bench-output by an earlier run of the same bench suite, e.g. against another vendor. Every phase is scored relative to it.")
+	var benchOutput = flags.String("bench-output", "", "Save the scorecard of the bench suite to this JSON file")
+	var lockMode = flags.String("lock-mode", "", "Object Lock retention mode of the objects written (GOVERNANCE or COMPLIANCE) and of the putretention operation. Requires lock-retain-until.")
+	var lockRetainUntil = flags.String("lock-retain-until", "", "Object Lock retention of the objects written and of the putretention operation: an RFC 3339 date like 2030-01-01T00:00:00Z, or a duration like 24h after each request.")
+	var acl = flags.String("acl", "", "Canned ACL of the objects written by put, multipartput, initmultipart and copy, and of the putacl operation, e.g. private or public-read")
+	var policyFile = flags.String("bucket-policy", "", "JSON file with the bucket policy set by the putpolicy operation. Default is a policy that denies requests over plain HTTP.")
+	var notificationArn = flags.String("notification-arn", "", "ARN of the SQS queue or SNS topic that the putnotification operation sends the ObjectCreated events of the prefix to. Default puts an empty notification configuration, which removes all notifications.")
+	var legalHold = flags.String("legal-hold", "", "Object Lock legal hold status of the objects written and of the putlegalhold operation: ON or OFF")
+	var stampIdentity = flags.Bool("stamp-identity", false, "Stamp every object written with the x-amz-meta-s3tester-run, x-amz-meta-s3tester-worker and x-amz-meta-s3tester-seq metadata, i.e. the run id, the worker and the worker's request number that wrote it.")
+	var runId = flags.String("run-id", "", "Run id of the objects written with stamp-identity and of the keys of isolate-run. Default is the host name, the process id and the start time of the run.")
+	var verifyManifest = flags.String("verify-manifest", "", "Verify the objects listed in a manifest written by another tool instead of running the operation: every object is read from the bucket, concurrency of them at a time, and its size and MD5 are compared with the manifest. Reads the output of md5sum and rclone md5sum, the JSON output of aws s3api list-objects-v2 and CSV lines of key,size[,md5].")
+	var stateDigestFlag = flags.Bool("state-digest", false, "After the run, list the objects under prefix in bucket and print a digest of their keys, sizes and ETags that doesn't depend on the order they are listed in, to tell whether two runs or two sites ended in the same state")
+	var expectStateDigest = flags.String("expect-state-digest", "", "Digest the state after the run like state-digest and fail the run if it isn't this digest, e.g. the one printed by a run against another site")
+	var cleanup = flags.Bool("cleanup", false, "Track every object and bucket created by the run and delete them when the run is done or interrupted, the objects with DeleteObjects requests of batch-size keys, concurrency of them at a time, so aborted runs don't leave their data behind")
+	var abortIncomplete = flags.Bool("abort-all-incomplete", false, "Abort every in-progress multipart upload under the prefix in the bucket, concurrency of them at a time, instead of running the operation. Cleans up the uploads left behind by initmultipart and by failed or interrupted runs.")
+	var ifMatch = flags.String("if-match", "", "If-Match header of the get, randget, head and put requests, an ETag or *")
+	var ifNoneMatch = flags.String("if-none-match", "", "If-None-Match header of the get, randget, head and put requests, an ETag or *. With put, * only creates objects that don't exist yet.")
+	var ifModifiedSince = flags.String("if-modified-since", "", "If-Modified-Since header of the get, randget and head requests, an RFC 3339 date like 2030-01-01T00:00:00Z")
+	var headers, queryParams repeatedFlag
+	flags.Var(&headers, "header", "Extra header sent with every request, formatted as 'Name: value'. Can be repeated.")
+	flags.Var(&queryParams, "query-param", "Extra query parameter sent with every request, formatted as 'name=value' or 'name'. Can be repeated.")
+	var isolateRun = flags.Bool("isolate-run", false, "Place the keys of the run under its run id, i.e. \"<run-id>/<prefix>-N\", so that concurrent runs against the same bucket don't collide. Give the run id of a run with run-id to read or delete its objects.")
+	var streamResults = flags.String("stream-results", "", "Send a summary of the requests completed during every stream-interval to a remote collector as JSON lines while the test runs. host:port for TCP or udp://host:port for UDP.")
+	var discover = flags.String("discover", "", "Discover the endpoints instead of giving them with endpoint, from DNS SRV records like srv://_s3._tcp.storage.example.com or the healthy instances of a Consul service like consul://10.0.0.5:8500/s3. The endpoints are looked up again every discover-interval, so the run follows nodes added to or removed from the cluster.")
+	var discoverInterval = flags.Duration("discover-interval", 30*time.Second, "Interval at which the endpoints of discover are looked up again")
+	var discoverScheme = flags.String("discover-scheme", "https", "Scheme of the endpoints of discover, http or https")
+	var rampFlag = flags.String("ramp", "", "Change the concurrency over time instead of running at a fixed concurrency, like '0->200 over 5m, hold 10m, 200->0 over 2m'. The ramp runs in steps of ramp-step at a fixed concurrency each, the results are reported per step and summarized at the end to find the concurrency at which the throughput stops growing.")
+	var rampStep = flags.Duration("ramp-step", 30*time.Second, "Length of the steps of ramp")
+	var bootstrap = flags.Int("bootstrap", 0, "Report 95% confidence intervals of the response time percentiles, bootstrapped from this many resamples of the latencies (at least 100, e.g. 1000), so that the percentiles of short runs aren't over-interpreted. Runs of more than 100000 requests aren't bootstrapped. Default (0) is off.")
+	var phasesFlag = flags.String("phases", "run", "Phases of the run: prepare writes the objects of the keys of the run, run sends the measured requests and cleanup deletes the objects of the keys afterwards, e.g. prepare,run,cleanup. Leave out phases to reuse a dataset that was prepared before or to keep it.")
+	var loadShapeFile = flags.String("load-shape", "", "File of 'offset,concurrency,rate' lines like '1m30s,200,5000' that change the number of workers and the requests per second of all workers (0 is no limit) during the run, for load shapes like steps, spikes or a sawtooth. The offsets start at 0s and the run ends at the last line, which must have a concurrency of 0.")
+	var streamInterval = flags.Duration("stream-interval", time.Second, "Interval of the summaries sent with stream-results")
+	var dryrun = flags.Bool("dryrun", false, "Print the estimated AWS S3 request, storage and egress cost of the planned workload and exit without sending any requests.")
+	var cost = flags.Bool("cost", false, "Report the estimated AWS S3 request, storage and egress cost of the run along with the results.")
+	var prices = flags.String("prices", "", "Prices in USD used for cost estimates, formatted as 'put=0.005&get=0.0004&storage=0.023&egress=0.09' (per 1000 PUT/COPY/POST/LIST requests, per 1000 GET and other requests, per GB-month stored and per GB transferred out). Unspecified prices default to S3 Standard in us-east-1.")
+	var gogc = flags.Int("gogc", 0, "Garbage collection target percentage of the tester, like the GOGC environment variable. Higher values collect less often at the cost of memory, -1 turns the collector off until gc-memory-limit is reached. Default (0) keeps GOGC or its default of 100.")
+	var gcMemoryLimit = flags.Int("gc-memory-limit", 0, "Soft memory limit of the tester in MiB, like the GOMEMLIMIT environment variable. The garbage collector runs as often as needed to stay below it. Default (0) is no limit.")
+	var memlimit = flags.Int("memlimit", 0, "Resident memory limit in MiB. Once exceeded, the per-request diagnostics logdetail, detailed-log, audit-log, version-file, heatmap-prefix-length and the header fingerprints stop capturing with a warning instead of risking an OOM kill, keeping what they captured so far. Default (0) is no limit.")
 
 	flags.Usage = func() {
 		fmt.Fprintf(os.Stderr, "This tool is for generating high performance S3 load against an S3 server.\n")
@@ -148,8 +497,34 @@ func parse(cmdline []string) (parameters, error) {
 	}
 	flags.Parse(cmdline)
 
+	if *workload != "" {
+		staged, err := isStagedWorkload(*workload)
+		if err != nil {
+			return parameters{}, fmt.Errorf("Error opening workload file: %s", err)
+		}
+		if staged {
+			return parseStagedWorkload(cmdline, *workload)
+		}
+	}
+
 	var ratePerSecond = rate.Limit(*maxRate)
 
+	bandwidth, err := parseBandwidth(*maxBandwidth)
+	if err != nil {
+		return parameters{}, errors.New("max-bandwidth " + err.Error())
+	}
+	perWorkerBandwidth, err := parseBandwidth(*workerBandwidth)
+	if err != nil {
+		return parameters{}, errors.New("worker-max-bandwidth " + err.Error())
+	}
+
+	if *arrivalRate < 0 {
+		return parameters{}, errors.New("rate must be >= 0")
+	}
+	if *arrivalRate > 0 && (isFlagSet(flags, "ratelimit") || *optype == "pipeline") {
+		return parameters{}, errors.New("rate cannot be combined with ratelimit or the pipeline operation")
+	}
+
 	var opTypeExists = false
 	for op := range optypes {
 		if optypes[op] == *optype {
@@ -157,6 +532,43 @@ func parse(cmdline []string) (parameters, error) {
 		}
 	}
 
+	mix, err := parseMix(*mixOperations)
+	if err != nil {
+		return parameters{}, err
+	}
+	if mix != nil {
+		if isFlagSet(flags, "operation") {
+			return parameters{}, errors.New("mix cannot be combined with operation")
+		}
+		if *workload != "" || *benchSuite != "" {
+			return parameters{}, errors.New("mix cannot be combined with workload or bench-suite")
+		}
+		// the operation of every request is picked from the mix
+		*optype, opTypeExists = "mix", true
+	}
+
+	if *readWhileWrite != 0 {
+		if *readWhileWrite < 0 || *readWhileWrite >= *concurrency {
+			return parameters{}, errors.New("read-while-write must be between 1 and concurrency - 1 writers")
+		}
+		if *overwriteKeys <= 0 {
+			return parameters{}, errors.New("read-while-write requires overwrite-keys")
+		}
+		if isFlagSet(flags, "operation") || mix != nil || *workload != "" || *benchSuite != "" || *rampFlag != "" {
+			return parameters{}, errors.New("read-while-write cannot be combined with operation, mix, workload, bench-suite or ramp")
+		}
+		// the workers other than the writers get the keys, with write generations to tell the writes apart
+		*optype, *generations = "get", true
+		if !isFlagSet(flags, "key-distribution") {
+			*keyDistribution = "uniform"
+		}
+	} else if *maxStaleness != 0 {
+		return parameters{}, errors.New("max-staleness requires read-while-write")
+	}
+	if *maxStaleness < 0 {
+		return parameters{}, errors.New("max-staleness must be >= 0")
+	}
+
 	if !opTypeExists {
 		return parameters{}, fmt.Errorf("operation type must be one of: %s", operationListString)
 	}
@@ -178,22 +590,140 @@ func parse(cmdline []string) (parameters, error) {
 		return parameters{}, errors.New("Number of requests must be > 0")
 	}
 
+	var keys *keyList
+	if *keysFromFile != "" {
+		switch *optype {
+		case "get", "head", "rangeget", "randget", "delete":
+		default:
+			return parameters{}, errors.New("keys-from-file is only supported by the get, head, rangeget, randget and delete operations")
+		}
+		if *overwrite != 0 || *keyTemplateFlag != "" || *workload != "" {
+			return parameters{}, errors.New("keys-from-file cannot be combined with overwrite, key-template or workload")
+		}
+		if keys, err = loadKeyFile(*keysFromFile); err != nil {
+			return parameters{}, fmt.Errorf("Error loading key file: %s", err)
+		}
+		if !nrequests.set {
+			// every key is requested once
+			nrequests = intFlag{value: len(keys.keys), set: true}
+		}
+	}
+
+	var species *speciesMix
+	if *speciesFile != "" {
+		if isFlagSet(flags, "size") || *uniformDist != "" || *keysFromFile != "" || *listInventory || *compressRatio != 0 || *dedupeRatio != "" {
+			return parameters{}, errors.New("species cannot be combined with size, uniformDist, keys-from-file, list-inventory, compress-ratio or dedupe-ratio")
+		}
+		if *payloadFile != "" || *payloadDir != "" || *dataPipe != "" || *dataCmd != "" {
+			return parameters{}, errors.New("species cannot be combined with payload files or sources")
+		}
+		if species, err = loadSpecies(*speciesFile); err != nil {
+			return parameters{}, err
+		}
+		// the part size has to upload the largest objects
+		*osize = species.largest()
+	}
+
+	if *listInventory {
+		switch *optype {
+		case "get", "head", "rangeget", "randget", "delete":
+		default:
+			return parameters{}, errors.New("list-inventory is only supported by the get, head, rangeget, randget and delete operations")
+		}
+		if *keysFromFile != "" || *overwrite != 0 || *keyTemplateFlag != "" || *workload != "" || *numBuckets > 0 {
+			return parameters{}, errors.New("list-inventory cannot be combined with keys-from-file, overwrite, key-template, workload or num-buckets")
+		}
+		if *inventorySample <= 0 || *inventorySample > 1 || *inventoryMaxKeys < 0 {
+			return parameters{}, errors.New("inventory-sample must be > 0 and <= 1 and inventory-max-keys must be >= 0")
+		}
+	} else if isFlagSet(flags, "inventory-sample") || isFlagSet(flags, "inventory-max-keys") {
+		return parameters{}, errors.New("inventory-sample and inventory-max-keys require list-inventory")
+	}
+
+	var presignedURLs map[string]presignedURL
+	if (*optype == "presignedurl") != (*presignURLs != "") {
+		return parameters{}, errors.New("the presignedurl operation requires presign-urls, which is only supported by it")
+	}
+	if *presignURLs != "" {
+		if *keysFromFile != "" || *keyTemplateFlag != "" || *overwrite != 0 {
+			return parameters{}, errors.New("presign-urls cannot be combined with keys-from-file, key-template or overwrite")
+		}
+		var urlKeys []string
+		if urlKeys, presignedURLs, err = loadPresignedURLs(*presignURLs); err != nil {
+			return parameters{}, fmt.Errorf("Error loading URL file: %s", err)
+		}
+		// the URLs are gone through like the keys of a key file
+		keys = &keyList{keys: urlKeys}
+		if !nrequests.set {
+			nrequests = intFlag{value: len(urlKeys), set: true}
+		}
+	}
+
 	if *concurrency <= 0 {
 		return parameters{}, errors.New("Concurrency must be > 0")
 	}
 
+	if *keyOffset < 0 {
+		return parameters{}, errors.New("key-offset must be >= 0")
+	}
+
+	var ramp *rampSchedule
+	if *rampFlag != "" {
+		if duration.set || isFlagSet(flags, "concurrency") || *workload != "" {
+			return parameters{}, errors.New("ramp sets the concurrency and duration of its steps and cannot be combined with concurrency, duration or workload")
+		}
+		var err error
+		if ramp, err = parseRamp(*rampFlag, *rampStep); err != nil {
+			return parameters{}, err
+		}
+		// the steps of a ramp are duration based runs
+		duration = durationFlag{set: true, value: *rampStep}
+	}
+
+	var shape *loadShape
+	if *loadShapeFile != "" {
+		if ramp != nil || duration.set || isFlagSet(flags, "concurrency") || *workload != "" || *benchSuite != "" {
+			return parameters{}, errors.New("load-shape sets the concurrency and duration of the run and cannot be combined with ramp, concurrency, duration, workload or bench-suite")
+		}
+		if *arrivalRate > 0 || isFlagSet(flags, "ratelimit") || *optype == "pipeline" {
+			return parameters{}, errors.New("load-shape sets the rate of the run and cannot be combined with rate, ratelimit or the pipeline operation")
+		}
+		var err error
+		if shape, err = loadLoadShape(*loadShapeFile); err != nil {
+			return parameters{}, fmt.Errorf("Error loading load shape: %s", err)
+		}
+		if nrequests.set && nrequests.value < shape.peak() {
+			return parameters{}, errors.New("Number of requests must be greater or equal to the highest concurrency of the load shape")
+		}
+		// workers for the highest concurrency run for the length of the shape
+		*concurrency = shape.peak()
+		duration = durationFlag{set: true, value: shape.length()}
+	}
+
 	if duration.set {
-		// TODO: because of the new naming schema, duration with "get"/"randget"/"puttagging"/"updatemeta"/"head"/"restore" won't work
-		if *optype == "get" || *optype == "randget" || *optype == "puttagging" || *optype == "updatemeta" || *optype == "head" || *optype == "restore" {
+		if *optype == "restore" || *optype == "listparts" || *optype == "abortmultipart" || *optype == "versionedget" || *optype == "versioneddelete" || *optype == "putretention" || *optype == "putlegalhold" || *optype == "deletebucket" {
 			return parameters{}, fmt.Errorf("Using \"duration\" with operation type  \"%s\" is not supported.", *optype)
 		}
-		if (*optype == "get" || *optype == "randget") && !nrequests.set {
+		// reads go over the keys of a previous put run until the time is up
+		if isRepeatableRead(*optype) && *overwrite != 1 && *overwriteKeys == 0 && !nrequests.set {
 			return parameters{}, fmt.Errorf("Using \"duration\" with operation type  \"%s\" requires \"requests\" to be set to that of a previous put run.", *optype)
-		} else if !(*optype == "get" || *optype == "randget") && nrequests.set {
+		} else if !isRepeatableRead(*optype) && *optype != "mix" && nrequests.set {
 			return parameters{}, errors.New("Using both \"duration\" and \"requests\" is not supported. Please choose only one of these options.")
 		}
 	}
 
+	if maxDuration.set {
+		// a ramp runs its steps for a duration
+		if !nrequests.set || duration.set || *benchSuite != "" {
+			return parameters{}, errors.New("max-duration requires requests and cannot be combined with duration, ramp or bench-suite")
+		}
+		if *overflow != overflowTruncate && *overflow != overflowFail && *overflow != overflowExtend {
+			return parameters{}, errors.New("overflow must be one of truncate, fail or extend")
+		}
+	} else if isFlagSet(flags, "overflow") {
+		return parameters{}, errors.New("overflow requires max-duration")
+	}
+
 	if nrequests.value < *concurrency {
 		return parameters{}, errors.New("Number of requests must be greater or equal to concurrency")
 	}
@@ -204,6 +734,54 @@ func parse(cmdline []string) (parameters, error) {
 	}
 	var jsonDecoder *json.Decoder
 
+	if *replayAccessLog != "" {
+		if *workload != "" || isFlagSet(flags, "operation") || mix != nil || *benchSuite != "" || ramp != nil || *readWhileWrite != 0 {
+			return parameters{}, errors.New("replay-accesslog cannot be combined with workload, operation, mix, bench-suite, ramp or read-while-write")
+		}
+		if len(endpoints) != 1 {
+			return parameters{}, errors.New("Cannot specify an access log to replay and additional endpoints. Only one of these is supported at a time")
+		}
+		if _, err = accessLogFiles(*replayAccessLog); err != nil {
+			return parameters{}, fmt.Errorf("Error opening access log: %s", err)
+		}
+	}
+	if *replayTrace != "" {
+		if *replayAccessLog != "" || *workload != "" || isFlagSet(flags, "operation") || mix != nil || *benchSuite != "" || ramp != nil || *readWhileWrite != 0 {
+			return parameters{}, errors.New("replay-trace cannot be combined with replay-accesslog, workload, operation, mix, bench-suite, ramp or read-while-write")
+		}
+		if len(endpoints) != 1 {
+			return parameters{}, errors.New("Cannot specify a trace to replay and additional endpoints. Only one of these is supported at a time")
+		}
+		if _, err = os.Stat(*replayTrace); err != nil {
+			return parameters{}, fmt.Errorf("Error opening trace: %s", err)
+		}
+	}
+	if *heavyConcurrency != 0 {
+		if *heavyConcurrency < 0 || *heavyConcurrency >= *concurrency {
+			return parameters{}, errors.New("heavy-concurrency must be between 1 and concurrency - 1 workers")
+		}
+		if !duration.set {
+			return parameters{}, errors.New("heavy-concurrency requires duration")
+		}
+		if mix != nil || *workload != "" || *benchSuite != "" || ramp != nil || *readWhileWrite != 0 || *replayAccessLog != "" || *replayTrace != "" {
+			return parameters{}, errors.New("heavy-concurrency cannot be combined with mix, workload, bench-suite, ramp, read-while-write, replay-accesslog or replay-trace")
+		}
+		if *heavyOperation != "put" && *heavyOperation != "multipartput" && *heavyOperation != "get" {
+			return parameters{}, errors.New("heavy-operation must be one of put, multipartput or get")
+		}
+		if *heavySize <= 0 || *heavyRate <= 0 {
+			return parameters{}, errors.New("heavy-size and heavy-ratelimit must be > 0")
+		}
+		if *heavyStart < 0 || *heavyStart >= duration.value {
+			return parameters{}, errors.New("heavy-start must be >= 0 and shorter than duration")
+		}
+	} else if isFlagSet(flags, "heavy-operation") || isFlagSet(flags, "heavy-size") || isFlagSet(flags, "heavy-ratelimit") || isFlagSet(flags, "heavy-start") {
+		return parameters{}, errors.New("heavy-operation, heavy-size, heavy-ratelimit and heavy-start require heavy-concurrency")
+	}
+	if *replaySpeed < 0 || (*replaySpeed != 0 && *replayAccessLog == "" && *replayTrace == "") {
+		return parameters{}, errors.New("replay-speed must be >= 0 and requires replay-accesslog or replay-trace")
+	}
+
 	if *workload != "" {
 		if jsonDecoder, err = openFile(*workload); err != nil {
 			return parameters{}, fmt.Errorf("Error opening workload file: %s", err)
@@ -213,6 +791,21 @@ func parse(cmdline []string) (parameters, error) {
 		}
 	}
 
+	if ramp != nil {
+		// the concurrency of every step is rounded to a multiple of the endpoints
+		*concurrency = len(endpoints)
+		peak := ramp.peak()
+		if peak%len(endpoints) != 0 {
+			peak += len(endpoints) - peak%len(endpoints)
+		}
+		if nrequests.set && nrequests.value < peak {
+			return parameters{}, errors.New("Number of requests must be greater or equal to the highest concurrency of the ramp, rounded up to a multiple of the endpoints")
+		}
+	}
+	if shape != nil && *concurrency%len(endpoints) != 0 {
+		*concurrency += len(endpoints) - *concurrency%len(endpoints)
+	}
+
 	if (*concurrency)%len(endpoints) != 0 {
 		return parameters{}, errors.New("The concurrency must be multiple of endpoint list length")
 	}
@@ -221,10 +814,49 @@ func parse(cmdline []string) (parameters, error) {
 		return parameters{}, errors.New("Retries must be >= 0")
 	}
 
+	if *retryBudget < 0 || *retryBudget > 100 {
+		return parameters{}, errors.New("retry-budget must be a percentage between 0 and 100")
+	}
+	if *retryBudget != 0 && *retries == 0 {
+		return parameters{}, errors.New("retry-budget requires retries")
+	}
+
 	if *repeat < 0 {
 		return parameters{}, errors.New("Repeat must be >= 0")
 	}
 
+	if *compressRatio != 0 && *compressRatio < 1 {
+		return parameters{}, errors.New("Compress ratio must be >= 1")
+	}
+
+	if *collision {
+		if *optype != "put" && *optype != "multipartput" {
+			return parameters{}, errors.New("Collision mode is only supported for put and multipartput")
+		}
+		if *overwrite != 2 {
+			return parameters{}, errors.New("Collision mode requires overwrite=2 so that workers write the same keys")
+		}
+		if duration.set {
+			return parameters{}, errors.New("Collision mode cannot be used with duration")
+		}
+	}
+
+	if *maxObjects < 0 || *maxBytes < 0 {
+		return parameters{}, errors.New("Max objects and max bytes must be >= 0")
+	}
+
+	if (*bundlePath != "" || *auditLogPath != "" || *detailedLog != "") && *dryrun {
+		return parameters{}, errors.New("bundle, audit-log and detailed-log cannot be combined with dryrun")
+	}
+
+	if *gcMemoryLimit < 0 {
+		return parameters{}, errors.New("gc-memory-limit must be >= 0")
+	}
+
+	if *memlimit < 0 {
+		return parameters{}, errors.New("Memory limit must be >= 0")
+	}
+
 	if *nosign && *profile != "" {
 		return parameters{}, errors.New("Cannot load credential profile if argument nosign is provided")
 	}
@@ -232,11 +864,44 @@ func parse(cmdline []string) (parameters, error) {
 	// attempts indicate the number of times we perform S3 operation, the default attempts is 1
 	attempts := 1 + *repeat
 
-	if *optype == "multipartput" {
-		if *partsize < 5*(1<<20) {
-			return parameters{}, errors.New("Part size should be 5MiB at minimum")
+	var cert *clientCertificate
+	if (*clientCertFile == "") != (*clientKeyFile == "") {
+		return parameters{}, errors.New("client-cert and client-key must be given together")
+	}
+	if *clientCertRefresh < 0 {
+		return parameters{}, errors.New("client-cert-refresh must be >= 0")
+	}
+	if *clientCertFile != "" {
+		if cert, err = loadClientCertificate(*clientCertFile, *clientKeyFile); err != nil {
+			return parameters{}, fmt.Errorf("Error loading the client certificate: %s", err)
 		}
-		if int(math.Ceil(float64(*osize)/float64(*partsize))) > 10000 {
+	}
+
+	if *dnsRefresh < 0 {
+		return parameters{}, errors.New("dns-refresh must be >= 0")
+	}
+
+	if *maxConnsPerHost < 0 {
+		return parameters{}, errors.New("max-conns-per-host must be >= 0")
+	}
+
+	if *copyThreshold < 0 {
+		return parameters{}, errors.New("copy-threshold must be >= 0")
+	}
+
+	autoPartsize := *partsize == 0
+	if autoPartsize {
+		*partsize = autoPartSize(*osize)
+	}
+
+	if *optype == "multipartput" || *optype == "initmultipart" || *optype == "copyacross" || (*optype == "copy" && *osize > *copyThreshold) {
+		if autoPartsize && *partsize > maxPartSize {
+			return parameters{}, errors.New("The object size is too large for a multipart upload (max 10000 parts of 5GiB)")
+		}
+		if *partsize < minPartSize || *partsize > maxPartSize {
+			return parameters{}, errors.New("Part size should be between 5MiB and 5GiB")
+		}
+		if int(math.Ceil(float64(*osize)/float64(*partsize))) > maxPartCount {
 			return parameters{}, errors.New("The multipart upload will use too many parts (max 10000)")
 		}
 	}
@@ -270,6 +935,34 @@ func parse(cmdline []string) (parameters, error) {
 		}
 	}
 
+	if *mpuThreshold < 0 {
+		return parameters{}, errors.New("mpu-threshold must be >= 0")
+	}
+	if *mpuThreshold > 0 {
+		if *optype != "put" {
+			return parameters{}, errors.New("mpu-threshold is only supported by the put operation")
+		}
+		if *payloadFile != "" || *payloadDir != "" || *dataPipe != "" || *dataCmd != "" {
+			return parameters{}, errors.New("mpu-threshold cannot be combined with payload files or sources")
+		}
+		// the part size has to upload the largest object
+		largest := *osize
+		if max > largest {
+			largest = max
+		}
+		if autoPartsize {
+			*partsize = autoPartSize(largest)
+		}
+		if largest > *mpuThreshold {
+			if *partsize < minPartSize || *partsize > maxPartSize {
+				return parameters{}, errors.New("Part size should be between 5MiB and 5GiB")
+			}
+			if int(math.Ceil(float64(largest)/float64(*partsize))) > maxPartCount {
+				return parameters{}, errors.New("The multipart upload will use too many parts (max 10000)")
+			}
+		}
+	}
+
 	if !strings.EqualFold(*tier, "Standard") && !strings.EqualFold(*tier, "Expedited") && !strings.EqualFold(*tier, "Bulk") {
 		return parameters{}, errors.New("Restore tier must be one of Standard, Expedited, or Bulk. Case Insensitive")
 	}
@@ -278,46 +971,718 @@ func parse(cmdline []string) (parameters, error) {
 		return parameters{}, errors.New("Restore days must be a positive, non-zero integer")
 	}
 
+	if *heatmapPrefixLength < 0 || *heatmapTop < 1 {
+		return parameters{}, errors.New("heatmap-prefix-length must be >= 0 and heatmap-top must be >= 1")
+	}
+
+	// the keys of a duration based run without requests are not known in advance
+	if *shuffleSeed != 0 && ((duration.set && !nrequests.set) || *optype == "multidelete") {
+		return parameters{}, errors.New("shuffle-seed is only supported with duration when requests are set, and not with multidelete")
+	}
+
+	*selectFormat = strings.ToLower(*selectFormat)
+	formatExists := false
+	for _, format := range selectFormats {
+		if format == *selectFormat {
+			formatExists = true
+		}
+	}
+	if !formatExists {
+		return parameters{}, errors.New("select-format must be one of " + strings.Join(selectFormats, ", "))
+	}
+
+	if *restorePoll < 0 || *restoreTimeout <= 0 {
+		return parameters{}, errors.New("restore-poll must be >= 0 and restore-timeout must be > 0")
+	}
+
+	payload := payloadOptions{compressRatio: *compressRatio, verifyETag: *verifyETag}
+	if *dedupeRatio != "" {
+		ratio, err := parseDedupeRatio(*dedupeRatio)
+		if err != nil {
+			return parameters{}, err
+		}
+		if *dedupeChunk <= 0 || *dedupeChunk%objectDataBlockSize != 0 {
+			return parameters{}, fmt.Errorf("Dedupe chunk size must be a positive multiple of %d", objectDataBlockSize)
+		}
+		objectSize := *osize
+		if max > objectSize {
+			objectSize = max
+		}
+		chunksPerObject := int64(math.Ceil(float64(objectSize) / float64(*dedupeChunk)))
+		payload.dedupeChunk = *dedupeChunk
+		payload.dedupePool = dedupePoolSize(int64(nrequests.value)*chunksPerObject, ratio)
+	}
+
+	if *payloadFile != "" || *payloadDir != "" {
+		if *payloadFile != "" && *payloadDir != "" {
+			return parameters{}, errors.New("Only one of payload-file and payload-dir can be specified")
+		}
+		if *optype != "put" && *workload == "" {
+			return parameters{}, errors.New("Payload files are only supported for put")
+		}
+		if *compressRatio != 0 || *dedupeRatio != "" || *collision {
+			return parameters{}, errors.New("Payload files cannot be combined with compress-ratio, dedupe-ratio or collision")
+		}
+		if payload.files, err = NewPayloadFiles(*payloadFile, *payloadDir, *payloadCache); err != nil {
+			return parameters{}, fmt.Errorf("Error loading payload files: %s", err)
+		}
+	}
+
+	if *dataPipe != "" || *dataCmd != "" {
+		if *dataPipe != "" && *dataCmd != "" {
+			return parameters{}, errors.New("Only one of data-pipe and data-cmd can be specified")
+		}
+		if *optype != "put" && *workload == "" {
+			return parameters{}, errors.New("Payload sources are only supported for put")
+		}
+		if payload.files != nil || *compressRatio != 0 || *dedupeRatio != "" || *collision || *generations {
+			return parameters{}, errors.New("Payload sources cannot be combined with payload files, compress-ratio, dedupe-ratio, collision or generations")
+		}
+	}
+
+	if *generations {
+		if (*uniformDist == "" && *osize < generationHeaderSize) || (*uniformDist != "" && min < generationHeaderSize) {
+			return parameters{}, fmt.Errorf("Write generations require objects of at least %d bytes", generationHeaderSize)
+		}
+		if payload.files != nil || *collision {
+			return parameters{}, errors.New("Write generations cannot be combined with payload files or collision")
+		}
+	}
+
+	storageClasses, err := parseStorageClasses(*storageClass)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	if storageClasses != nil && *reducedRedundancy {
+		return parameters{}, errors.New("rr cannot be combined with storage-class, use storage-class=REDUCED_REDUNDANCY")
+	}
+
+	stages, err := parsePipelineStages(*pipelineStages)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	if *optype == "pipeline" {
+		if *pipelineDepth < 1 {
+			return parameters{}, errors.New("pipeline-depth must be >= 1")
+		}
+		for _, stage := range stages {
+			if stage == "tag" && *tagging == "" {
+				return parameters{}, errors.New("The tag stage of the pipeline requires tagging")
+			}
+		}
+	}
+
+	sseSettings, err := parseEncryption(*sse, *sseKmsKeyId, *sseCustomerKey)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	if sseSettings != nil && sseSettings.customerKey != "" {
+		if *httpPercent > 0 {
+			return parameters{}, errors.New("SSE-C keys cannot be sent over plain HTTP, http-percent must be 0")
+		}
+		for _, e := range endpoints {
+			if strings.HasPrefix(strings.ToLower(e), "http://") {
+				return parameters{}, errors.New("SSE-C keys cannot be sent over plain HTTP, use HTTPS endpoints")
+			}
+		}
+	}
+
+	*checksumAlgorithm = strings.ToUpper(*checksumAlgorithm)
+	if *checksumAlgorithm != "" {
+		algorithmExists := false
+		for _, algorithm := range checksumAlgorithms {
+			if algorithm == *checksumAlgorithm {
+				algorithmExists = true
+			}
+		}
+		if !algorithmExists {
+			return parameters{}, errors.New("checksum-algorithm must be one of " + strings.Join(checksumAlgorithms, ", "))
+		}
+	}
+
+	lock, err := parseObjectLock(*lockMode, *lockRetainUntil, *legalHold)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	if *optype == "putretention" && (lock == nil || lock.mode == "") {
+		return parameters{}, errors.New("putretention requires lock-mode and lock-retain-until")
+	}
+
+	if *optype == "putlegalhold" && (lock == nil || lock.legalHold == "") {
+		return parameters{}, errors.New("putlegalhold requires legal-hold")
+	}
+
+	objectACL, err := parseCannedACL(*acl)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	if *optype == "putacl" && objectACL == "" {
+		return parameters{}, errors.New("putacl requires acl")
+	}
+
+	if *optype == "createbucket" || *optype == "deletebucket" {
+		if *bucketPerWorker {
+			return parameters{}, errors.New("bucket-per-worker cannot be combined with createbucket or deletebucket")
+		}
+		if err = validateBucketName("prefix", *objectprefix); err != nil {
+			return parameters{}, err
+		}
+	}
+	if *bucketPerWorker {
+		if err = validateBucketName("bucket", *bucketname); err != nil {
+			return parameters{}, err
+		}
+	}
+
+	if *numBuckets < 0 {
+		return parameters{}, errors.New("num-buckets must be >= 0")
+	}
+	if *bucketPlacement != bucketPlacementRoundRobin && *bucketPlacement != bucketPlacementHash {
+		return parameters{}, errors.New("bucket-placement must be " + bucketPlacementRoundRobin + " or " + bucketPlacementHash)
+	}
+	if *numBuckets > 0 {
+		if *bucketPerWorker || *workload != "" || *versionsPerKey > 0 {
+			return parameters{}, errors.New("num-buckets cannot be combined with bucket-per-worker, workload or versions-per-key")
+		}
+		switch *optype {
+		case "createbucket", "deletebucket", "multidelete", "pipeline":
+			// batches and pipelines of keys are sent to one bucket
+			return parameters{}, errors.New("num-buckets is not supported by the " + *optype + " operation")
+		}
+		if err = validateBucketName("bucket", *bucketname); err != nil {
+			return parameters{}, err
+		}
+	}
+
+	var policy string
+	if *optype == "putpolicy" {
+		if policy, err = bucketPolicy(*bucketname, *policyFile); err != nil {
+			return parameters{}, err
+		}
+	} else if *policyFile != "" {
+		return parameters{}, errors.New("bucket-policy is only supported by the putpolicy operation")
+	}
+
+	if _, err = notificationConfiguration(*notificationArn, *objectprefix); err != nil {
+		return parameters{}, err
+	}
+
+	if *runId != "" && !*stampIdentity && !*isolateRun {
+		return parameters{}, errors.New("run-id requires stamp-identity or isolate-run")
+	}
+	if (*stampIdentity || *isolateRun) && *runId == "" {
+		*runId = defaultRunId(time.Now())
+	}
+	templatedMetadata, err := parseMetadataTemplate(strings.Join(metadata, "&"))
+	if err != nil {
+		return parameters{}, err
+	}
+
+	extras, err := parseRequestExtras(headers, queryParams)
+	if err != nil {
+		return parameters{}, err
+	}
+	if len(headers) > 0 && *presignTransfer {
+		// the headers would be signed into the URL, the plain HTTP client doesn't send them
+		return parameters{}, errors.New("header cannot be combined with presign-transfer")
+	}
+
+	objectConditions, err := parseConditions(*ifMatch, *ifNoneMatch, *ifModifiedSince)
+	if err != nil {
+		return parameters{}, err
+	}
+	if objectConditions != nil {
+		switch *optype {
+		case "get", "randget", "head":
+		case "put":
+			if *ifModifiedSince != "" {
+				return parameters{}, errors.New("if-modified-since is not supported by the put operation")
+			}
+		default:
+			return parameters{}, errors.New("conditional headers are only supported by the get, randget, head and put operations")
+		}
+	}
+
+	if *abortIncomplete && (*concurrency <= 0 || *benchSuite != "" || *workload != "") {
+		return parameters{}, errors.New("abort-all-incomplete requires concurrency > 0 and cannot be combined with bench-suite or workload")
+	}
+
+	if *bootstrap != 0 && *bootstrap < 100 {
+		return parameters{}, errors.New("bootstrap must be 0 or at least 100 resamples")
+	}
+
+	phases, err := parsePhases(*phasesFlag)
+	if err != nil {
+		return parameters{}, err
+	}
+	if phases[phasePrepare] || phases[phaseCleanup] {
+		if *workload != "" || *benchSuite != "" || ramp != nil || *concurrency == 0 || *collision || *abortIncomplete || *verifyManifest != "" {
+			return parameters{}, errors.New("the prepare and cleanup phases cannot be combined with workload, bench-suite, ramp, collision, abort-all-incomplete, verify-manifest or a concurrency of 0")
+		}
+		if *keysFromFile != "" || *listInventory || *presignURLs != "" || *overwrite == 1 {
+			return parameters{}, errors.New("the prepare and cleanup phases write and delete the keys of the run, they cannot be combined with keys-from-file, list-inventory, presign-urls or overwrite=1")
+		}
+		if duration.set && !nrequests.set {
+			return parameters{}, errors.New("the prepare and cleanup phases of a duration based run require requests to be set to the number of keys")
+		}
+	}
+
+	if *verifyManifest != "" && (*benchSuite != "" || *workload != "" || *abortIncomplete || ramp != nil || duration.set) {
+		return parameters{}, errors.New("verify-manifest cannot be combined with bench-suite, workload, abort-all-incomplete, ramp or duration")
+	}
+
+	if *resultsFormat != "text" && *resultsFormat != "json" {
+		return parameters{}, errors.New("results-format must be text or json")
+	}
+	if *resultsFile != "" {
+		if isFlagSet(flags, "results-format") && *resultsFormat != "json" {
+			return parameters{}, errors.New("results-file writes the results document of results-format json")
+		}
+		*resultsFormat = "json"
+	} else if *resultsFormat == "json" {
+		// the other reports are printed as JSON so that everything printed can be parsed
+		*isJson = true
+	}
+
+	if *expectStateDigest != "" {
+		if digest, err := hex.DecodeString(*expectStateDigest); err != nil || len(digest) != sha256.Size {
+			return parameters{}, errors.New("expect-state-digest must be a digest printed by state-digest, 64 hex digits")
+		}
+		*expectStateDigest = strings.ToLower(*expectStateDigest)
+		*stateDigestFlag = true
+	}
+	if *stateDigestFlag {
+		if phases[phaseCleanup] || *cleanup || *verifyManifest != "" || *abortIncomplete || *dryrun || *numBuckets > 0 || *bucketPerWorker {
+			return parameters{}, errors.New("state-digest cannot be combined with the cleanup phase, cleanup, verify-manifest, abort-all-incomplete, dryrun, num-buckets or bucket-per-worker")
+		}
+	}
+
+	if *isolateRun {
+		if *optype == "createbucket" || *optype == "deletebucket" || *workload != "" {
+			return parameters{}, errors.New("isolate-run cannot be combined with createbucket, deletebucket or workload")
+		}
+		*objectprefix = runPrefix(*runId, *objectprefix)
+		*copyPrefix = runPrefix(*runId, *copyPrefix)
+	}
+
+	resultStream, err := NewResultStream(*streamResults, *streamInterval)
+	if err != nil {
+		return parameters{}, fmt.Errorf("Invalid stream-results: %v", err)
+	}
+
+	var discovery *endpointDiscovery
+	if *discover != "" {
+		if isFlagSet(flags, "endpoint") || *httpPercent > 0 || *workload != "" {
+			return parameters{}, errors.New("discover cannot be combined with endpoint, http-percent or workload")
+		}
+		if *discoverInterval <= 0 {
+			return parameters{}, errors.New("discover-interval must be > 0")
+		}
+		if discovery, err = NewEndpointDiscovery(*discover, *discoverScheme, *discoverInterval); err != nil {
+			return parameters{}, err
+		}
+	}
+
+	if *rangeSize <= 0 || *rangeConcurrency < 1 || *rangeThreshold < 0 {
+		return parameters{}, errors.New("range-size must be > 0, range-concurrency must be >= 1 and range-threshold must be >= 0")
+	}
+
+	keyDist, err := parseKeyDistribution(*keyDistribution)
+	if err != nil {
+		return parameters{}, err
+	}
+	if keyDist != nil {
+		if *optype != "get" && *optype != "head" && *optype != "rangeget" && *optype != "delete" {
+			return parameters{}, errors.New("key-distribution is only supported by the get, head, rangeget and delete operations")
+		}
+		if *overwrite != 0 || *shuffleSeed != 0 {
+			return parameters{}, errors.New("key-distribution cannot be combined with overwrite or shuffle-seed")
+		}
+	}
+
+	keyTemp, err := parseKeyTemplate(*keyTemplateFlag, time.Now())
+	if err != nil {
+		return parameters{}, err
+	}
+	if keyTemp != nil && *overwrite == 1 {
+		return parameters{}, errors.New("key-template cannot be combined with overwrite=1")
+	}
+
+	if *rangeDist != "fixed" && *rangeDist != "aligned" && *rangeDist != "unaligned" {
+		return parameters{}, errors.New("range-dist must be one of fixed, aligned or unaligned")
+	}
+
+	if *rangeLength <= 0 {
+		return parameters{}, errors.New("range-length must be > 0")
+	}
+
+	if *partConcurrency < 1 {
+		return parameters{}, errors.New("part-concurrency must be >= 1")
+	}
+
+	if *httpPercent < 0 || *httpPercent > 100 {
+		return parameters{}, errors.New("http-percent must be between 0 and 100")
+	}
+
+	if *httpPort != "" {
+		if port, err := strconv.Atoi(*httpPort); err != nil || port < 1 || port > 65535 {
+			return parameters{}, errors.New("http-port must be a port number between 1 and 65535")
+		}
+	}
+
+	if *httpPercent > 0 {
+		for _, e := range endpoints {
+			if _, err := plainHTTPEndpoint(e, *httpPort); err != nil {
+				return parameters{}, fmt.Errorf("Cannot send plain HTTP requests to endpoint %s: %s", e, err)
+			}
+		}
+	}
+
+	requestTimeouts, err := parseTimeouts(*connectTimeout, *headerTimeout, *requestTimeout)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	responseOverrides, err := parseResponseOverrides(*overrides)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	if *batchSize < 1 || *batchSize > 1000 {
+		return parameters{}, errors.New("batch-size must be between 1 and 1000")
+	}
+
+	if *copyBucket == "" {
+		*copyBucket = *bucketname
+	}
+
+	*metadataDirective = strings.ToUpper(*metadataDirective)
+	if *metadataDirective != "COPY" && *metadataDirective != "REPLACE" {
+		return parameters{}, errors.New("metadata-directive must be one of COPY or REPLACE")
+	}
+
+	if *logdetailPhase && *logdetail == "" {
+		return parameters{}, errors.New("logdetail-phase requires logdetail")
+	}
+
+	if *optype == "copyacross" {
+		if *destEndpoint == "" {
+			return parameters{}, errors.New("The copyacross operation requires dest-endpoint")
+		}
+		if _, err := url.ParseRequestURI(*destEndpoint); err != nil {
+			return parameters{}, errors.New("URL \"" + *destEndpoint + "\" is not a valid endpoint")
+		}
+		if *nosign && *destProfile != "" {
+			return parameters{}, errors.New("Cannot load credential profile if argument nosign is provided")
+		}
+		if sseSettings != nil && sseSettings.customerKey != "" && strings.HasPrefix(strings.ToLower(*destEndpoint), "http://") {
+			return parameters{}, errors.New("SSE-C keys cannot be sent over plain HTTP, use an HTTPS dest-endpoint")
+		}
+	} else if *destEndpoint != "" || *destProfile != "" {
+		return parameters{}, errors.New("dest-endpoint and dest-profile are only supported by the copyacross operation")
+	}
+
+	if *copyBucket == *bucketname && *copyPrefix == *objectprefix && *metadataDirective == "COPY" && *optype != "copyacross" {
+		return parameters{}, errors.New("Copying objects onto themselves requires metadata-directive=REPLACE")
+	}
+
+	*presignMethod = strings.ToUpper(*presignMethod)
+	if *presignMethod != "GET" && *presignMethod != "PUT" {
+		return parameters{}, errors.New("presign-method must be one of GET or PUT")
+	}
+
+	if *presignExpiry < time.Second || *presignExpiry > 7*24*time.Hour {
+		return parameters{}, errors.New("presign-expiry must be between 1s and 168h")
+	}
+
+	if *presignTransfer && *optype != "presign" {
+		return parameters{}, errors.New("presign-transfer is only supported by the presign operation")
+	}
+
+	expiryLadder := []time.Duration{*presignExpiry}
+	if *presignExportFile != "" {
+		if *optype != "presign" || *presignTransfer {
+			return parameters{}, errors.New("presign-export is only supported by the presign operation without presign-transfer")
+		}
+		if *presignExpiryLadder != "" {
+			if expiryLadder, err = parseExpiryLadder(*presignExpiryLadder); err != nil {
+				return parameters{}, err
+			}
+		}
+	} else if *presignExpiryLadder != "" {
+		return parameters{}, errors.New("presign-expiry-ladder requires presign-export")
+	}
+
+	if *listApi != "v1" && *listApi != "v2" {
+		return parameters{}, errors.New("list-api must be one of v1 or v2")
+	}
+
+	if *listMode != "page" && *listMode != "full" {
+		return parameters{}, errors.New("list-mode must be one of page or full")
+	}
+
+	if *maxKeys < 0 || *maxKeys > 1000 {
+		return parameters{}, errors.New("max-keys must be between 1 and 1000")
+	}
+
+	if *versionRatio < 0 || *versionRatio > 100 {
+		return parameters{}, errors.New("version-ratio must be between 0 and 100")
+	}
+
+	if *versionsPerKey < 0 {
+		return parameters{}, errors.New("versions-per-key must be >= 0")
+	}
+	if *versionsPerKey > 0 {
+		if *optype != "put" && *optype != "multipartput" {
+			return parameters{}, errors.New("versions-per-key is only supported for put and multipartput")
+		}
+		if *versionFile == "" {
+			return parameters{}, errors.New("versions-per-key requires version-file to record the versions")
+		}
+		if *repeat != 0 || duration.set || *bucketPerWorker {
+			return parameters{}, errors.New("versions-per-key cannot be combined with repeat, duration or bucket-per-worker")
+		}
+		// every key is written once per version
+		attempts = *versionsPerKey
+	}
+
+	if *overwriteKeys < 0 {
+		return parameters{}, errors.New("overwrite-keys must be >= 0")
+	}
+	if *overwriteKeys > 0 && (*overwrite != 0 || *keysFromFile != "" || *listInventory || *versionsPerKey > 0 || *bucketPerWorker) {
+		return parameters{}, errors.New("overwrite-keys cannot be combined with overwrite, keys-from-file, list-inventory, versions-per-key or bucket-per-worker")
+	}
+	overwriteVersioningStatus, err := parseOverwriteVersioning(*overwriteVersioning)
+	if err != nil {
+		return parameters{}, err
+	}
+	if overwriteVersioningStatus != "" && (*overwriteKeys == 0 || *numBuckets > 0) {
+		return parameters{}, errors.New("overwrite-versioning requires overwrite-keys and cannot be combined with num-buckets")
+	}
+
+	var recordedVersions map[string][]string
+	if *versionFile != "" {
+		switch *optype {
+		case "put", "multipartput":
+		case "versionedget", "versioneddelete":
+			if recordedVersions, err = loadVersionFile(*versionFile); err != nil {
+				return parameters{}, fmt.Errorf("Error loading version file: %s", err)
+			}
+		default:
+			return parameters{}, errors.New("version-file is only supported for put, multipartput, versionedget and versioneddelete")
+		}
+	}
+
+	if *dryrun {
+		if duration.set {
+			return parameters{}, errors.New("Cannot estimate the cost of a duration based run, specify requests instead")
+		}
+		if *workload != "" || *concurrency == 0 {
+			return parameters{}, errors.New("Cost can only be estimated for a single operation with a fixed concurrency")
+		}
+	}
+
+	model, err := parseCostModel(*prices)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	var acks *writeAcks
+	if *readWhileWrite > 0 {
+		acks = NewWriteAcks(*maxStaleness)
+	}
+
+	var baseline *scorecard
+	if *benchSuite != "" {
+		if _, ok := benchSuites[*benchSuite]; !ok {
+			return parameters{}, errors.New("bench-suite must be one of " + strings.Join(benchSuiteVersions(), ", "))
+		}
+		if duration.set || *workload != "" || *dryrun || *loglatency != "" || *versionFile != "" {
+			return parameters{}, errors.New("bench-suite cannot be combined with duration, workload, dryrun, loglatency or version-file")
+		}
+		if *benchBaseline != "" {
+			if baseline, err = loadScorecard(*benchBaseline, *benchSuite); err != nil {
+				return parameters{}, fmt.Errorf("Error loading bench baseline: %s", err)
+			}
+		}
+	} else if *benchBaseline != "" || *benchOutput != "" {
+		return parameters{}, errors.New("bench-baseline and bench-output require bench-suite")
+	}
+
 	args := parameters{
-		concurrency:        *concurrency,
-		osize:              *osize,
-		consistencyControl: *consistencyControl,
-		endpoints:          endpoints,
-		optype:             *optype,
-		bucketname:         *bucketname,
-		objectprefix:       *objectprefix,
-		ratePerSecond:      ratePerSecond,
-		logging:            *logdetail != "",
-		logdetail:          *logdetail,
-		loglatency:         *loglatency,
-		objrange:           *objrange,
-		reducedRedundancy:  *reducedRedundancy,
-		overwrite:          *overwrite,
-		retries:            *retries,
-		retrySleep:         *retrySleep,
-		lockstep:           *lockstep,
-		attempts:           attempts,
-		region:             *region,
-		jsonDecoder:        jsonDecoder,
-		partsize:           *partsize,
-		verify:             *verify,
-		tagging:            *tagging,
-		metadata:           *metadata,
-		min:                min,
-		max:                max,
-		nrequests:          &nrequests,
-		duration:           &duration,
-		cpuprofile:         *cpuprofile,
-		isJson:             *isJson,
-		tier:               *tier,
-		days:               *days,
-		profile:            *profile,
-		nosign:             *nosign,
+		concurrency:         *concurrency,
+		osize:               *osize,
+		consistencyControl:  *consistencyControl,
+		endpoints:           endpoints,
+		optype:              *optype,
+		bucketname:          *bucketname,
+		objectprefix:        *objectprefix,
+		ratePerSecond:       ratePerSecond,
+		rate:                *arrivalRate,
+		maxBandwidth:        bandwidth,
+		workerBandwidth:     perWorkerBandwidth,
+		logging:             *logdetail != "",
+		logdetail:           *logdetail,
+		logdetailPhase:      *logdetailPhase,
+		bundle:              *bundlePath,
+		auditLog:            *auditLogPath,
+		detailedLog:         *detailedLog,
+		stateDigest:         *stateDigestFlag,
+		expectStateDigest:   *expectStateDigest,
+		loglatency:          *loglatency,
+		objrange:            *objrange,
+		responseOverrides:   responseOverrides,
+		reducedRedundancy:   *reducedRedundancy,
+		storageClasses:      storageClasses,
+		mix:                 mix,
+		keyOffset:           *keyOffset,
+		overwrite:           *overwrite,
+		retries:             *retries,
+		retrySleep:          *retrySleep,
+		retryBudget:         NewRetryBudget(*retryBudget),
+		timeouts:            requestTimeouts,
+		httpPercent:         *httpPercent,
+		httpPort:            *httpPort,
+		lockstep:            *lockstep,
+		attempts:            attempts,
+		region:              *region,
+		jsonDecoder:         jsonDecoder,
+		replayAccessLog:     *replayAccessLog,
+		replaySpeed:         *replaySpeed,
+		replayTrace:         *replayTrace,
+		partsize:            *partsize,
+		partConcurrency:     *partConcurrency,
+		rangeSize:           *rangeSize,
+		rangeConcurrency:    *rangeConcurrency,
+		rangeThreshold:      *rangeThreshold,
+		rangeDist:           *rangeDist,
+		rangeLength:         *rangeLength,
+		verify:              *verify,
+		tagging:             *tagging,
+		metadata:            strings.Join(metadata, "&"),
+		metadataTemplate:    templatedMetadata,
+		min:                 min,
+		max:                 max,
+		nrequests:           &nrequests,
+		duration:            &duration,
+		maxDuration:         maxDuration.value,
+		overflow:            *overflow,
+		cpuprofile:          *cpuprofile,
+		isJson:              *isJson,
+		resultsFormat:       *resultsFormat,
+		resultsFile:         *resultsFile,
+		tier:                *tier,
+		days:                *days,
+		restorePoll:         *restorePoll,
+		restoreTimeout:      *restoreTimeout,
+		selectExpression:    *selectExpression,
+		selectFormat:        *selectFormat,
+		shuffleSeed:         *shuffleSeed,
+		keyDistribution:     keyDist,
+		keyTemplate:         keyTemp,
+		keyList:             keys,
+		species:             species,
+		listInventory:       *listInventory,
+		inventorySample:     *inventorySample,
+		inventoryMaxKeys:    *inventoryMaxKeys,
+		heatmapPrefix:       *heatmapPrefixLength,
+		heatmapTop:          *heatmapTop,
+		profile:             *profile,
+		nosign:              *nosign,
+		memWatchdog:         NewMemoryWatchdog(*memlimit),
+		gogc:                *gogc,
+		gcMemoryLimit:       *gcMemoryLimit,
+		resultStream:        resultStream,
+		discovery:           discovery,
+		ramp:                ramp,
+		loadShape:           shape,
+		phases:              phases,
+		bootstrap:           *bootstrap,
+		cleanup:             *cleanup,
+		objectLock:          lock,
+		acl:                 objectACL,
+		bucketPerWorker:     *bucketPerWorker,
+		numBuckets:          *numBuckets,
+		bucketPlacement:     *bucketPlacement,
+		bucketPolicy:        policy,
+		notificationArn:     *notificationArn,
+		encryption:          sseSettings,
+		pipelineStages:      stages,
+		pipelineDepth:       *pipelineDepth,
+		stampIdentity:       *stampIdentity,
+		runId:               *runId,
+		isolateRun:          *isolateRun,
+		abortIncomplete:     *abortIncomplete,
+		verifyManifest:      *verifyManifest,
+		conditions:          objectConditions,
+		requestExtras:       extras,
+		payload:             payload,
+		dataPipe:            *dataPipe,
+		dataCmd:             *dataCmd,
+		collision:           *collision,
+		generations:         *generations,
+		checksumAlgorithm:   *checksumAlgorithm,
+		writeLimit:          NewWriteLimit(*maxObjects, *maxBytes),
+		dryrun:              *dryrun,
+		cost:                *cost,
+		costModel:           model,
+		benchSuite:          *benchSuite,
+		benchBaseline:       baseline,
+		benchOutput:         *benchOutput,
+		versionRatio:        *versionRatio,
+		versionFile:         *versionFile,
+		versionsPerKey:      *versionsPerKey,
+		overwriteKeys:       *overwriteKeys,
+		readWhileWrite:      *readWhileWrite,
+		heavyConcurrency:    *heavyConcurrency,
+		heavyOperation:      *heavyOperation,
+		heavySize:           *heavySize,
+		heavyRatePerSecond:  rate.Limit(*heavyRate),
+		heavyStart:          *heavyStart,
+		writeAcks:           acks,
+		overwriteVersioning: overwriteVersioningStatus,
+		recordedVersions:    recordedVersions,
+		listApi:             *listApi,
+		listMode:            *listMode,
+		delimiter:           *delimiter,
+		maxKeys:             *maxKeys,
+		batchSize:           *batchSize,
+		copyBucket:          *copyBucket,
+		copyPrefix:          *copyPrefix,
+		copyThreshold:       *copyThreshold,
+		destEndpoint:        *destEndpoint,
+		destProfile:         *destProfile,
+		maxConnsPerHost:     *maxConnsPerHost,
+		dnsRefresh:          *dnsRefresh,
+		clientCert:          cert,
+		clientCertRefresh:   *clientCertRefresh,
+		mpuThreshold:        *mpuThreshold,
+		metadataDirective:   *metadataDirective,
+		presignMethod:       *presignMethod,
+		presignExpiry:       *presignExpiry,
+		presignTransfer:     *presignTransfer,
+		presignExport:       NewPresignExport(*presignExportFile, expiryLadder),
+		presignedURLs:       presignedURLs,
 	}
 
 	return args, nil
 }
 
+// Parses a dedupe ratio given either as "N:1" or "N"
+func parseDedupeRatio(ratio string) (float64, error) {
+	value := strings.TrimSuffix(ratio, ":1")
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 1 {
+		return 0, errors.New("Dedupe ratio must be formatted as 'N:1' with N >= 1")
+	}
+	return parsed, nil
+}
+
 func parseAndValidate(cmdline []string) (args parameters) {
 	var err error
 	args, err = parse(cmdline)
@@ -327,6 +1692,17 @@ func parseAndValidate(cmdline []string) (args parameters) {
 	return
 }
 
+// Returns whether the flag was given on the command line rather than left at its default.
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 // Validate input enpoint string, reject invalid URLs and duplicate URLs
 func validateEndpoint(endpoint string) ([]string, error) {
 	endpointSet := make(map[string]struct{})
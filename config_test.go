@@ -4,7 +4,9 @@ import (
 	"golang.org/x/time/rate"
 	"math"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultArgs(t *testing.T) {
@@ -129,6 +131,14 @@ func TestDefaultArgs(t *testing.T) {
 	if args.nosign != false {
 		t.Fatalf("wrong default nosign")
 	}
+
+	if args.memWatchdog != nil {
+		t.Fatalf("memory watchdog should be disabled by default")
+	}
+
+	if args.payload.compressRatio != 0 {
+		t.Fatalf("wrong default compress ratio: %v", args.payload.compressRatio)
+	}
 }
 
 func TestNonDefaultMetadata(t *testing.T) {
@@ -158,6 +168,43 @@ func TestNotAllowToSetDurationAndNumberRequests(t *testing.T) {
 	}
 }
 
+func TestDurationFormats(t *testing.T) {
+	args, err := parse([]string{"-duration=90"})
+	if err != nil || !args.duration.set || args.duration.value != 90*time.Second {
+		t.Fatalf("duration in seconds: %v %+v", err, args.duration)
+	}
+	if args, err = parse([]string{"-duration=10m"}); err != nil || args.duration.value != 10*time.Minute {
+		t.Fatalf("duration like 10m: %v %+v", err, args.duration)
+	}
+	var d durationFlag
+	for _, invalid := range []string{"ten", "0", "-5s"} {
+		if err := d.Set(invalid); err == nil {
+			t.Fatalf("duration %s should be invalid", invalid)
+		}
+	}
+}
+
+func TestDurationWithReads(t *testing.T) {
+	if _, err := parse([]string{"-operation=get", "-duration=10m", "-requests=1000"}); err != nil {
+		t.Fatalf("get with duration and requests should be supported: %v", err)
+	}
+	if _, err := parse([]string{"-operation=head", "-duration=10m"}); err == nil {
+		t.Fatalf("head with duration should require requests")
+	}
+	if _, err := parse([]string{"-operation=get", "-duration=10m", "-overwrite=1"}); err != nil {
+		t.Fatalf("get of a single object with duration should be supported: %v", err)
+	}
+	if _, err := parse([]string{"-mix=get:90,put:10", "-duration=10m", "-requests=1000"}); err != nil {
+		t.Fatalf("mix with duration and requests should be supported: %v", err)
+	}
+	if _, err := parse([]string{"-operation=restore", "-duration=10m", "-requests=1000"}); err == nil {
+		t.Fatalf("restore with duration should fail")
+	}
+	if _, err := parse([]string{"-operation=get", "-duration=10m", "-requests=1000", "-shuffle-seed=7"}); err != nil {
+		t.Fatalf("shuffle-seed with a duration based get should be supported: %v", err)
+	}
+}
+
 func TestRepeatMustBeGreaterThanZero(t *testing.T) {
 	cmdline := []string{"-repeat=-1"}
 	_, err := parse(cmdline)
@@ -430,3 +477,1107 @@ func TestProfileAndNosign(t *testing.T) {
 		t.Fatalf("invalid profile and nosign should fail")
 	}
 }
+
+func TestInvalidMemoryLimit(t *testing.T) {
+	cmdline := []string{"-memlimit=-1"}
+	_, err := parse(cmdline)
+
+	if err == nil {
+		t.Fatalf("expected error for negative memory limit")
+	}
+}
+
+func TestValidCompressRatio(t *testing.T) {
+	args, err := parse([]string{"-compress-ratio=2.5"})
+
+	if err != nil {
+		t.Fatalf("valid compress ratio should succeed: %v", err)
+	}
+
+	if args.payload.compressRatio != 2.5 {
+		t.Fatalf("wrong compress ratio: %v", args.payload.compressRatio)
+	}
+
+	if _, err = parse([]string{"-compress-ratio=0.5"}); err == nil {
+		t.Fatalf("compress ratio below 1 should fail")
+	}
+}
+
+func TestCollisionRequiresOverwrite(t *testing.T) {
+	if _, err := parse([]string{"-collision"}); err == nil {
+		t.Fatalf("collision mode without overwrite=2 should fail")
+	}
+
+	if _, err := parse([]string{"-collision", "-overwrite=2", "-operation=get"}); err == nil {
+		t.Fatalf("collision mode with get should fail")
+	}
+
+	args, err := parse([]string{"-collision", "-overwrite=2"})
+	if err != nil {
+		t.Fatalf("collision mode with put and overwrite=2 should succeed: %v", err)
+	}
+
+	if !args.collision {
+		t.Fatalf("collision mode should be set")
+	}
+}
+
+func TestGenerationsOptions(t *testing.T) {
+	if _, err := parse([]string{"-generations", "-size=10"}); err == nil {
+		t.Fatalf("write generations with objects smaller than the header should fail")
+	}
+
+	if _, err := parse([]string{"-generations", "-uniformDist=10-100"}); err == nil {
+		t.Fatalf("write generations with a minimum size smaller than the header should fail")
+	}
+
+	if _, err := parse([]string{"-generations", "-collision", "-overwrite=2"}); err == nil {
+		t.Fatalf("write generations with collision mode should fail")
+	}
+
+	args, err := parse([]string{"-generations", "-operation=get", "-overwrite=1"})
+	if err != nil {
+		t.Fatalf("write generations with get should succeed: %v", err)
+	}
+
+	if !args.generations {
+		t.Fatalf("write generations should be set")
+	}
+}
+
+func TestStreamResultsOptions(t *testing.T) {
+	if _, err := parse([]string{"-stream-results=collector"}); err == nil {
+		t.Fatalf("stream-results without a port should fail")
+	}
+
+	args, err := parse([]string{"-stream-results=udp://collector:9000", "-stream-interval=5s"})
+	if err != nil {
+		t.Fatalf("valid stream-results should succeed: %v", err)
+	}
+
+	if args.resultStream == nil || args.resultStream.network != "udp" || args.resultStream.interval != 5*time.Second {
+		t.Fatalf("wrong result stream: %+v", args.resultStream)
+	}
+}
+
+func TestObjectLockOptions(t *testing.T) {
+	if _, err := parse([]string{"-operation=putretention"}); err == nil {
+		t.Fatalf("putretention without lock mode should fail")
+	}
+
+	if _, err := parse([]string{"-operation=putlegalhold", "-lock-mode=GOVERNANCE", "-lock-retain-until=1h"}); err == nil {
+		t.Fatalf("putlegalhold without legal hold should fail")
+	}
+
+	args, err := parse([]string{"-operation=putretention", "-lock-mode=compliance", "-lock-retain-until=1h"})
+	if err != nil {
+		t.Fatalf("valid putretention should succeed: %v", err)
+	}
+
+	if args.objectLock == nil || args.objectLock.mode != "COMPLIANCE" || args.objectLock.retainFor != time.Hour {
+		t.Fatalf("wrong object lock: %+v", args.objectLock)
+	}
+
+	if args, err = parse([]string{}); err != nil || args.objectLock != nil {
+		t.Fatalf("no object lock expected by default: %+v, %v", args.objectLock, err)
+	}
+}
+
+func TestVerifyETagOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=get", "-verify-etag"})
+	if err != nil || !args.payload.verifyETag {
+		t.Fatalf("verify-etag should be enabled: %v", err)
+	}
+
+	if args, err = parse([]string{}); err != nil || args.payload.verifyETag {
+		t.Fatalf("verify-etag should be disabled by default: %v", err)
+	}
+}
+
+func TestBucketOptions(t *testing.T) {
+	if _, err := parse([]string{"-operation=createbucket", "-prefix=s3tester"}); err != nil {
+		t.Fatalf("createbucket with a valid prefix should succeed: %v", err)
+	}
+
+	if _, err := parse([]string{"-operation=createbucket", "-prefix=TestObject"}); err == nil {
+		t.Fatalf("createbucket with a prefix that is not a valid bucket name should fail")
+	}
+
+	if _, err := parse([]string{"-operation=deletebucket", "-prefix=s3tester", "-duration=10"}); err == nil {
+		t.Fatalf("deletebucket with duration should fail")
+	}
+
+	args, err := parse([]string{"-operation=put", "-bucket=s3tester", "-bucket-per-worker"})
+	if err != nil || !args.bucketPerWorker {
+		t.Fatalf("bucket-per-worker should succeed: %v", err)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-bucket=S3Tester", "-bucket-per-worker"}); err == nil {
+		t.Fatalf("bucket-per-worker with a bucket that can't name buckets should fail")
+	}
+
+	if _, err = parse([]string{"-operation=createbucket", "-prefix=s3tester", "-bucket-per-worker"}); err == nil {
+		t.Fatalf("bucket-per-worker with createbucket should fail")
+	}
+}
+
+func TestBucketConfigOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=putpolicy", "-bucket=b"})
+	if err != nil || !strings.Contains(args.bucketPolicy, "arn:aws:s3:::b") {
+		t.Fatalf("putpolicy should default to a policy of the bucket: %s, %v", args.bucketPolicy, err)
+	}
+
+	if _, err = parse([]string{"-operation=getpolicy", "-bucket-policy=policy.json"}); err == nil {
+		t.Fatalf("bucket-policy with operations other than putpolicy should fail")
+	}
+
+	if _, err = parse([]string{"-operation=putpolicy", "-bucket-policy=does-not-exist.json"}); err == nil {
+		t.Fatalf("missing bucket policy file should fail")
+	}
+
+	if _, err = parse([]string{"-operation=putnotification", "-notification-arn=arn:aws:s3:::b"}); err == nil {
+		t.Fatalf("notification arn other than SQS or SNS should fail")
+	}
+
+	if _, err = parse([]string{"-operation=putlifecycle", "-duration=10"}); err != nil {
+		t.Fatalf("bucket configuration operations should support duration: %v", err)
+	}
+}
+
+func TestACLOptions(t *testing.T) {
+	if _, err := parse([]string{"-operation=putacl"}); err == nil {
+		t.Fatalf("putacl without acl should fail")
+	}
+
+	if _, err := parse([]string{"-acl=everyone"}); err == nil {
+		t.Fatalf("unknown canned acl should fail")
+	}
+
+	args, err := parse([]string{"-operation=putacl", "-acl=public-read"})
+	if err != nil || args.acl != "public-read" {
+		t.Fatalf("valid putacl should succeed: %q, %v", args.acl, err)
+	}
+
+	if _, err = parse([]string{"-operation=getacl", "-duration=10"}); err == nil {
+		t.Fatalf("getacl with duration should fail")
+	}
+}
+
+func TestStampIdentityOptions(t *testing.T) {
+	if _, err := parse([]string{"-run-id=run-1"}); err == nil {
+		t.Fatalf("run id without stamp-identity should fail")
+	}
+
+	args, err := parse([]string{"-stamp-identity"})
+	if err != nil {
+		t.Fatalf("stamp-identity should succeed: %v", err)
+	}
+
+	if !args.stampIdentity || args.runId == "" {
+		t.Fatalf("expected a default run id but got %q", args.runId)
+	}
+
+	if args, err = parse([]string{"-stamp-identity", "-run-id=run-1"}); err != nil || args.runId != "run-1" {
+		t.Fatalf("expected run id run-1 but got %q, %v", args.runId, err)
+	}
+}
+
+func TestAutoPartSizeOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=multipartput", "-size=1099511627776"})
+	if err != nil {
+		t.Fatalf("multipartput of a 1TiB object should succeed: %v", err)
+	}
+
+	if args.partsize != 105*(1<<20) {
+		t.Fatalf("wrong automatic partsize: %v", args.partsize)
+	}
+
+	if _, err = parse([]string{"-operation=multipartput", "-size=53687091201"}); err != nil {
+		t.Fatalf("multipartput of an object just above 10000 parts of 5MiB should succeed: %v", err)
+	}
+
+	if _, err = parse([]string{"-operation=multipartput", "-size=53687091200001"}); err == nil {
+		t.Fatalf("multipartput of an object above 10000 parts of 5GiB should fail")
+	}
+
+	if _, err = parse([]string{"-operation=multipartput", "-size=10737418240", "-partsize=6442450944"}); err == nil {
+		t.Fatalf("partsize above 5GiB should fail")
+	}
+}
+
+func TestEncryptionOptions(t *testing.T) {
+	args, err := parse([]string{"-sse-kms-key-id=key-1"})
+	if err != nil {
+		t.Fatalf("sse-kms-key-id should succeed: %v", err)
+	}
+
+	if args.encryption == nil || args.encryption.sse != "aws:kms" {
+		t.Fatalf("wrong encryption: %+v", args.encryption)
+	}
+
+	key := "a2tra2tra2tra2tra2tra2tra2tra2tra2tra2tra2s="
+	if _, err = parse([]string{"-sse-c-key=" + key, "-endpoint=http://127.0.0.1:18082"}); err == nil {
+		t.Fatalf("SSE-C over plain HTTP should fail")
+	}
+
+	if _, err = parse([]string{"-sse-c-key=" + key}); err != nil {
+		t.Fatalf("SSE-C over HTTPS should succeed: %v", err)
+	}
+}
+
+func TestPipelineOptions(t *testing.T) {
+	if _, err := parse([]string{"-operation=pipeline", "-pipeline-stages=write,tag"}); err == nil {
+		t.Fatalf("tag stage without tagging should fail")
+	}
+
+	if _, err := parse([]string{"-operation=pipeline", "-pipeline-depth=0"}); err == nil {
+		t.Fatalf("pipeline depth of 0 should fail")
+	}
+
+	args, err := parse([]string{"-operation=pipeline", "-pipeline-stages=write,tag,delete", "-tagging=a=b"})
+	if err != nil {
+		t.Fatalf("valid pipeline should succeed: %v", err)
+	}
+
+	if len(args.pipelineStages) != 3 || args.pipelineDepth != 10 {
+		t.Fatalf("wrong pipeline: %v %d", args.pipelineStages, args.pipelineDepth)
+	}
+}
+
+func TestStorageClassOptions(t *testing.T) {
+	if _, err := parse([]string{"-rr", "-storage-class=STANDARD_IA"}); err == nil {
+		t.Fatalf("rr with storage-class should fail")
+	}
+
+	args, err := parse([]string{"-storage-class=STANDARD=3,GLACIER_IR=1"})
+	if err != nil {
+		t.Fatalf("valid storage class mix should succeed: %v", err)
+	}
+
+	if args.storageClasses == nil || args.storageClasses.total != 4 {
+		t.Fatalf("wrong storage classes: %+v", args.storageClasses)
+	}
+}
+
+func TestBenchSuiteOptions(t *testing.T) {
+	if _, err := parse([]string{"-bench-suite=v0"}); err == nil {
+		t.Fatalf("unknown bench suite should fail")
+	}
+
+	if _, err := parse([]string{"-bench-output=scorecard.json"}); err == nil {
+		t.Fatalf("bench-output without bench-suite should fail")
+	}
+
+	args, err := parse([]string{"-bench-suite=v1"})
+	if err != nil || args.benchSuite != "v1" {
+		t.Fatalf("valid bench suite should succeed: %v", err)
+	}
+}
+
+func TestSelectOptions(t *testing.T) {
+	if _, err := parse([]string{"-operation=select", "-select-format=xml"}); err == nil {
+		t.Fatalf("unknown select format should fail")
+	}
+
+	args, err := parse([]string{"-operation=select", "-select-format=JSON", "-select-expression=SELECT s.id FROM S3Object s"})
+	if err != nil {
+		t.Fatalf("valid select should succeed: %v", err)
+	}
+
+	if args.selectFormat != "json" || args.selectExpression != "SELECT s.id FROM S3Object s" {
+		t.Fatalf("wrong select: %s %s", args.selectFormat, args.selectExpression)
+	}
+}
+
+func TestShuffleSeed(t *testing.T) {
+	if _, err := parse([]string{"-operation=multidelete", "-shuffle-seed=7"}); err == nil {
+		t.Fatalf("shuffle-seed with multidelete should fail")
+	}
+
+	if _, err := parse([]string{"-operation=put", "-shuffle-seed=7", "-duration=10"}); err == nil {
+		t.Fatalf("shuffle-seed with a duration based put should fail")
+	}
+
+	args, err := parse([]string{"-operation=get", "-shuffle-seed=7"})
+	if err != nil || args.shuffleSeed != 7 {
+		t.Fatalf("valid shuffle seed should succeed: %v", err)
+	}
+}
+
+func TestChecksumAlgorithm(t *testing.T) {
+	if _, err := parse([]string{"-checksum-algorithm=md5"}); err == nil {
+		t.Fatalf("unknown checksum algorithm should fail")
+	}
+
+	args, err := parse([]string{"-checksum-algorithm=crc32c"})
+	if err != nil {
+		t.Fatalf("valid checksum algorithm should succeed: %v", err)
+	}
+
+	if args.checksumAlgorithm != "CRC32C" {
+		t.Fatalf("wrong checksum algorithm: %s", args.checksumAlgorithm)
+	}
+}
+
+func TestDedupeRatio(t *testing.T) {
+	args, err := parse([]string{"-dedupe-ratio=3:1", "-dedupe-chunk=8192", "-size=32768", "-requests=30"})
+	if err != nil {
+		t.Fatalf("valid dedupe ratio should succeed: %v", err)
+	}
+
+	if args.payload.dedupeChunk != 8192 {
+		t.Fatalf("wrong dedupe chunk: %d", args.payload.dedupeChunk)
+	}
+
+	// 30 objects of 4 chunks each dedupe 3:1 into 40 distinct chunks
+	if args.payload.dedupePool != 40 {
+		t.Fatalf("wrong dedupe pool: %d", args.payload.dedupePool)
+	}
+
+	if _, err = parse([]string{"-dedupe-ratio=0.5:1"}); err == nil {
+		t.Fatalf("dedupe ratio below 1 should fail")
+	}
+
+	if _, err = parse([]string{"-dedupe-ratio=3:1", "-dedupe-chunk=1000"}); err == nil {
+		t.Fatalf("dedupe chunk that is not a multiple of the block size should fail")
+	}
+}
+
+func TestWriteLimits(t *testing.T) {
+	args, err := parse([]string{"-max-objects=10", "-max-bytes=2048"})
+	if err != nil {
+		t.Fatalf("valid write limits should succeed: %v", err)
+	}
+
+	if args.writeLimit == nil || args.writeLimit.maxObjects != 10 || args.writeLimit.maxBytes != 2048 {
+		t.Fatalf("wrong write limit: %+v", args.writeLimit)
+	}
+
+	if _, err = parse([]string{"-max-objects=-1"}); err == nil {
+		t.Fatalf("negative max objects should fail")
+	}
+}
+
+func TestPayloadFileOptions(t *testing.T) {
+	if _, err := parse([]string{"-payload-file=a", "-payload-dir=b"}); err == nil {
+		t.Fatalf("payload file and payload dir together should fail")
+	}
+
+	if _, err := parse([]string{"-payload-file=a", "-operation=multipartput"}); err == nil {
+		t.Fatalf("payload file with multipartput should fail")
+	}
+
+	if _, err := parse([]string{"-payload-file=/nonexistent/s3tester/payload"}); err == nil {
+		t.Fatalf("missing payload file should fail")
+	}
+}
+
+func TestPayloadSourceOptions(t *testing.T) {
+	args, err := parse([]string{"-data-cmd=gen --size 1M"})
+	if err != nil || args.dataCmd != "gen --size 1M" {
+		t.Fatalf("expected the data command but got %q, %v", args.dataCmd, err)
+	}
+
+	if _, err = parse([]string{"-data-pipe=a", "-data-cmd=b"}); err == nil {
+		t.Fatalf("data pipe and data command together should fail")
+	}
+
+	if _, err = parse([]string{"-data-pipe=a", "-operation=get"}); err == nil {
+		t.Fatalf("data pipe with get should fail")
+	}
+
+	if _, err = parse([]string{"-data-cmd=gen", "-compress-ratio=4"}); err == nil {
+		t.Fatalf("data command with compress ratio should fail")
+	}
+}
+
+func TestMixOptions(t *testing.T) {
+	args, err := parse([]string{"-mix=get:70,put:30"})
+	if err != nil || args.optype != "mix" || args.mix == nil || args.mix.total != 100 {
+		t.Fatalf("expected a mix of get and put but got %q, %+v, %v", args.optype, args.mix, err)
+	}
+
+	if _, err = parse([]string{"-mix=get:70,put:30", "-operation=put"}); err == nil {
+		t.Fatalf("mix with operation should fail")
+	}
+
+	if _, err = parse([]string{"-mix=get:70,list:30"}); err == nil {
+		t.Fatalf("mix with list should fail")
+	}
+}
+
+func TestDiscoverOptions(t *testing.T) {
+	args, err := parse([]string{"-discover=srv://_s3._tcp.storage.example.com", "-discover-interval=10s"})
+	if err != nil || args.discovery == nil || args.discovery.interval != 10*time.Second {
+		t.Fatalf("expected endpoint discovery every 10s but got %+v, %v", args.discovery, err)
+	}
+
+	if _, err = parse([]string{"-discover=srv://_s3._tcp.storage.example.com", "-endpoint=http://10.0.0.5:9000"}); err == nil {
+		t.Fatalf("discover with endpoint should fail")
+	}
+
+	if _, err = parse([]string{"-discover=srv://_s3._tcp.storage.example.com", "-discover-interval=0s"}); err == nil {
+		t.Fatalf("discover with a zero interval should fail")
+	}
+}
+
+func TestRetryBudgetOptions(t *testing.T) {
+	args, err := parse([]string{"-retries=3", "-retry-budget=10"})
+	if err != nil || args.retryBudget == nil || args.retryBudget.ratio != 0.1 {
+		t.Fatalf("expected a retry budget of 10%% but got %+v, %v", args.retryBudget, err)
+	}
+
+	if args, err = parse([]string{"-retries=3"}); err != nil || args.retryBudget != nil {
+		t.Fatalf("expected no retry budget by default but got %+v, %v", args.retryBudget, err)
+	}
+
+	if _, err = parse([]string{"-retry-budget=10"}); err == nil {
+		t.Fatalf("retry-budget without retries should fail")
+	}
+
+	if _, err = parse([]string{"-retries=3", "-retry-budget=150"}); err == nil {
+		t.Fatalf("retry-budget over 100 should fail")
+	}
+}
+
+func TestRampOptions(t *testing.T) {
+	args, err := parse([]string{"-ramp=0->200 over 5m, hold 10m", "-ramp-step=1m"})
+	if err != nil || args.ramp == nil || args.ramp.step != time.Minute || !args.duration.set {
+		t.Fatalf("expected a ramp in steps of 1m but got %+v, %v", args.ramp, err)
+	}
+
+	if _, err = parse([]string{"-ramp=0->200 over 5m", "-concurrency=10"}); err == nil {
+		t.Fatalf("ramp with concurrency should fail")
+	}
+
+	if _, err = parse([]string{"-ramp=0->200 over 5m", "-duration=10m"}); err == nil {
+		t.Fatalf("ramp with duration should fail")
+	}
+
+	if _, err = parse([]string{"-ramp=0->200 over 5m", "-operation=get", "-requests=100"}); err == nil {
+		t.Fatalf("ramp with fewer requests than its peak concurrency should fail")
+	}
+
+	if _, err = parse([]string{"-ramp=0->200 over 5m", "-operation=get", "-requests=200", "-endpoint=https://127.0.0.1:18082,https://127.0.0.2:18082,https://127.0.0.3:18082"}); err == nil {
+		t.Fatalf("ramp with fewer requests than its peak concurrency rounded to the endpoints should fail")
+	}
+
+	if _, err = parse([]string{"-ramp=0->200 over 5m", "-operation=get", "-requests=100000"}); err != nil {
+		t.Fatalf("ramp of reads should be supported: %v", err)
+	}
+}
+
+func TestVerifyManifestOptions(t *testing.T) {
+	args, err := parse([]string{"-verify-manifest=archive.md5", "-concurrency=64"})
+	if err != nil || args.verifyManifest != "archive.md5" {
+		t.Fatalf("expected the manifest archive.md5 but got %q, %v", args.verifyManifest, err)
+	}
+
+	if _, err = parse([]string{"-verify-manifest=archive.md5", "-abort-all-incomplete"}); err == nil {
+		t.Fatalf("verify-manifest with abort-all-incomplete should fail")
+	}
+}
+
+func TestDryRunOptions(t *testing.T) {
+	if _, err := parse([]string{"-dryrun", "-duration=10"}); err == nil {
+		t.Fatalf("dry run with duration should fail")
+	}
+
+	if _, err := parse([]string{"-dryrun", "-concurrency=0"}); err == nil {
+		t.Fatalf("dry run with concurrency scan should fail")
+	}
+
+	if _, err := parse([]string{"-prices=put=abc"}); err == nil {
+		t.Fatalf("invalid price should fail")
+	}
+
+	args, err := parse([]string{"-dryrun", "-prices=get=0.001"})
+	if err != nil {
+		t.Fatalf("valid dry run should succeed: %v", err)
+	}
+
+	if !args.dryrun || args.costModel.get != 0.001 || args.costModel.put != defaultCostModel.put {
+		t.Fatalf("wrong dry run args: %v %+v", args.dryrun, args.costModel)
+	}
+}
+
+func TestVersionRatio(t *testing.T) {
+	args, err := parse([]string{"-operation=versionedget", "-version-ratio=20"})
+	if err != nil {
+		t.Fatalf("valid version ratio should succeed: %v", err)
+	}
+
+	if args.versionRatio != 20 {
+		t.Fatalf("wrong version ratio: %v", args.versionRatio)
+	}
+
+	if _, err = parse([]string{"-operation=versionedget", "-version-ratio=101"}); err == nil {
+		t.Fatalf("version ratio above 100 should fail")
+	}
+}
+
+func TestPartConcurrency(t *testing.T) {
+	args, err := parse([]string{"-operation=multipartput", "-part-concurrency=4"})
+	if err != nil {
+		t.Fatalf("valid part concurrency should succeed: %v", err)
+	}
+
+	if args.partConcurrency != 4 {
+		t.Fatalf("wrong part concurrency: %v", args.partConcurrency)
+	}
+
+	if _, err = parse([]string{"-operation=multipartput", "-part-concurrency=0"}); err == nil {
+		t.Fatalf("part concurrency of 0 should fail")
+	}
+}
+
+func TestListApi(t *testing.T) {
+	args, err := parse([]string{"-operation=list"})
+	if err != nil {
+		t.Fatalf("list operation should succeed: %v", err)
+	}
+
+	if args.listApi != "v2" {
+		t.Fatalf("wrong default list api: %v", args.listApi)
+	}
+
+	if _, err = parse([]string{"-operation=list", "-list-api=v3"}); err == nil {
+		t.Fatalf("unknown list api should fail")
+	}
+}
+
+func TestRangeOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=parallelget", "-range-size=1048576", "-range-concurrency=8"})
+	if err != nil {
+		t.Fatalf("valid range options should succeed: %v", err)
+	}
+
+	if args.rangeSize != 1048576 || args.rangeConcurrency != 8 {
+		t.Fatalf("wrong range options: %v %v", args.rangeSize, args.rangeConcurrency)
+	}
+
+	if _, err = parse([]string{"-operation=parallelget", "-range-size=0"}); err == nil {
+		t.Fatalf("range size of 0 should fail")
+	}
+
+	if _, err = parse([]string{"-operation=parallelget", "-range-concurrency=0"}); err == nil {
+		t.Fatalf("range concurrency of 0 should fail")
+	}
+}
+
+func TestResponseOverrides(t *testing.T) {
+	args, err := parse([]string{"-operation=get", "-response-overrides=content-language=de"})
+	if err != nil {
+		t.Fatalf("valid response overrides should succeed: %v", err)
+	}
+
+	if args.responseOverrides["Content-Language"] != "de" {
+		t.Fatalf("wrong response overrides: %v", args.responseOverrides)
+	}
+
+	if _, err = parse([]string{"-operation=get", "-response-overrides=etag=abc"}); err == nil {
+		t.Fatalf("unsupported response override should fail")
+	}
+}
+
+func TestRangeDistribution(t *testing.T) {
+	args, err := parse([]string{"-operation=rangeget", "-range-dist=unaligned", "-range-length=4096"})
+	if err != nil {
+		t.Fatalf("valid range distribution should succeed: %v", err)
+	}
+
+	if args.rangeDist != "unaligned" || args.rangeLength != 4096 {
+		t.Fatalf("wrong range distribution: %v %v", args.rangeDist, args.rangeLength)
+	}
+
+	if _, err = parse([]string{"-operation=rangeget", "-range-dist=zipf"}); err == nil {
+		t.Fatalf("unknown range distribution should fail")
+	}
+
+	if _, err = parse([]string{"-operation=rangeget", "-range-length=0"}); err == nil {
+		t.Fatalf("range length of 0 should fail")
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	args, err := parse([]string{"-operation=multidelete", "-batch-size=500"})
+	if err != nil {
+		t.Fatalf("valid batch size should succeed: %v", err)
+	}
+
+	if args.batchSize != 500 {
+		t.Fatalf("wrong batch size: %v", args.batchSize)
+	}
+
+	if _, err = parse([]string{"-operation=multidelete", "-batch-size=1001"}); err == nil {
+		t.Fatalf("batch size above 1000 should fail")
+	}
+}
+
+func TestTimeoutOptions(t *testing.T) {
+	args, err := parse([]string{"-connect-timeout=1s", "-header-timeout=read=5s"})
+	if err != nil {
+		t.Fatalf("valid timeouts should succeed: %v", err)
+	}
+
+	if args.timeouts["read"].header != 5*time.Second || args.timeouts["write"].header != 0 || args.timeouts["delete"].connect != time.Second {
+		t.Fatalf("wrong timeouts: %+v", args.timeouts)
+	}
+
+	if _, err = parse([]string{"-request-timeout=forever"}); err == nil {
+		t.Fatalf("invalid timeout should fail")
+	}
+}
+
+func TestCopyOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=copy", "-metadata-directive=replace"})
+	if err != nil {
+		t.Fatalf("valid copy options should succeed: %v", err)
+	}
+
+	if args.copyBucket != args.bucketname || args.copyPrefix != "copy" || args.metadataDirective != "REPLACE" {
+		t.Fatalf("wrong copy options: %s %s %s", args.copyBucket, args.copyPrefix, args.metadataDirective)
+	}
+
+	if _, err = parse([]string{"-operation=copy", "-metadata-directive=MOVE"}); err == nil {
+		t.Fatalf("unknown metadata directive should fail")
+	}
+
+	if _, err = parse([]string{"-operation=copy", "-copy-prefix=testobject"}); err == nil {
+		t.Fatalf("copying objects onto themselves without REPLACE should fail")
+	}
+
+	if _, err = parse([]string{"-operation=copy", "-copy-prefix=testobject", "-copy-bucket=other"}); err != nil {
+		t.Fatalf("copying to another bucket should succeed: %v", err)
+	}
+}
+
+func TestCopyAcrossOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=copyacross", "-dest-endpoint=https://10.0.0.2:443", "-dest-profile=target", "-copy-prefix=testobject"})
+	if err != nil {
+		t.Fatalf("valid copyacross options should succeed: %v", err)
+	}
+
+	if args.destEndpoint != "https://10.0.0.2:443" || args.destProfile != "target" || args.copyBucket != args.bucketname {
+		t.Fatalf("wrong copyacross options: %s %s %s", args.destEndpoint, args.destProfile, args.copyBucket)
+	}
+
+	if _, err = parse([]string{"-operation=copyacross"}); err == nil {
+		t.Fatalf("copyacross without dest-endpoint should fail")
+	}
+
+	if _, err = parse([]string{"-operation=copy", "-dest-endpoint=https://10.0.0.2:443"}); err == nil {
+		t.Fatalf("dest-endpoint without copyacross should fail")
+	}
+
+	if _, err = parse([]string{"-operation=copyacross", "-dest-endpoint=https://10.0.0.2:443", "-partsize=1048576"}); err == nil {
+		t.Fatalf("copyacross with a part size below 5MiB should fail")
+	}
+}
+
+func TestArrivalRate(t *testing.T) {
+	args, err := parse([]string{"-rate=250.5"})
+	if err != nil {
+		t.Fatalf("valid rate should succeed: %v", err)
+	}
+
+	if args.rate != 250.5 {
+		t.Fatalf("wrong rate: %f", args.rate)
+	}
+
+	if _, err = parse([]string{"-rate=-1"}); err == nil {
+		t.Fatalf("negative rate should fail")
+	}
+
+	if _, err = parse([]string{"-rate=100", "-ratelimit=50"}); err == nil {
+		t.Fatalf("rate with ratelimit should fail")
+	}
+}
+
+func TestMaxBandwidth(t *testing.T) {
+	args, err := parse([]string{"-max-bandwidth=500MB/s", "-worker-max-bandwidth=100Mbit/s"})
+	if err != nil {
+		t.Fatalf("valid bandwidths should succeed: %v", err)
+	}
+
+	if args.maxBandwidth != 500e6 || args.workerBandwidth != 12.5e6 {
+		t.Fatalf("wrong bandwidths: %f %f", args.maxBandwidth, args.workerBandwidth)
+	}
+
+	if _, err = parse([]string{"-max-bandwidth=fast"}); err == nil {
+		t.Fatalf("invalid max bandwidth should fail")
+	}
+
+	if _, err = parse([]string{"-worker-max-bandwidth=0MB/s"}); err == nil {
+		t.Fatalf("worker bandwidth of 0 should fail")
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	args, err := parse([]string{"-requests=1000", "-max-duration=10m", "-overflow=fail"})
+	if err != nil {
+		t.Fatalf("valid max duration should succeed: %v", err)
+	}
+
+	if args.maxDuration != 10*time.Minute || args.overflow != overflowFail {
+		t.Fatalf("wrong time box: %s %s", args.maxDuration, args.overflow)
+	}
+
+	if args, err = parse([]string{"-requests=1000", "-max-duration=90"}); err != nil || args.maxDuration != 90*time.Second || args.overflow != overflowTruncate {
+		t.Fatalf("max duration in seconds should truncate by default: %s %s %v", args.maxDuration, args.overflow, err)
+	}
+
+	if _, err = parse([]string{"-max-duration=10m"}); err == nil {
+		t.Fatalf("max duration without requests should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-requests=1000", "-duration=5m", "-max-duration=10m"}); err == nil {
+		t.Fatalf("max duration with duration should fail")
+	}
+
+	if _, err = parse([]string{"-requests=1000", "-max-duration=10m", "-overflow=retry"}); err == nil {
+		t.Fatalf("unknown overflow policy should fail")
+	}
+
+	if _, err = parse([]string{"-requests=1000", "-overflow=extend"}); err == nil {
+		t.Fatalf("overflow without max duration should fail")
+	}
+}
+
+func TestDNSRefresh(t *testing.T) {
+	args, err := parse([]string{"-dns-refresh=30s"})
+	if err != nil || args.dnsRefresh != 30*time.Second {
+		t.Fatalf("valid dns refresh should succeed: %s %v", args.dnsRefresh, err)
+	}
+
+	if _, err = parse([]string{"-dns-refresh=-1s"}); err == nil {
+		t.Fatalf("negative dns refresh should fail")
+	}
+}
+
+func TestKeyDistribution(t *testing.T) {
+	args, err := parse([]string{"-operation=get", "-requests=100000", "-key-distribution=hotspot:90/10"})
+	if err != nil {
+		t.Fatalf("valid key distribution should succeed: %v", err)
+	}
+
+	if args.keyDistribution == nil || args.keyDistribution.kind != "hotspot" {
+		t.Fatalf("wrong key distribution: %+v", args.keyDistribution)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-key-distribution=uniform"}); err == nil {
+		t.Fatalf("key distribution with put should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-key-distribution=zipfian", "-shuffle-seed=42"}); err == nil {
+		t.Fatalf("key distribution with shuffle seed should fail")
+	}
+}
+
+func TestHttpPercent(t *testing.T) {
+	args, err := parse([]string{"-http-percent=25", "-http-port=8080"})
+	if err != nil {
+		t.Fatalf("valid http percent should succeed: %v", err)
+	}
+
+	if args.httpPercent != 25 || args.httpPort != "8080" {
+		t.Fatalf("wrong http split: %d %s", args.httpPercent, args.httpPort)
+	}
+
+	if _, err = parse([]string{"-http-percent=101"}); err == nil {
+		t.Fatalf("http percent above 100 should fail")
+	}
+
+	if _, err = parse([]string{"-http-percent=50", "-http-port=http"}); err == nil {
+		t.Fatalf("invalid http port should fail")
+	}
+}
+
+func TestCopyThreshold(t *testing.T) {
+	args, err := parse([]string{"-operation=copy", "-size=10485760", "-copy-threshold=5242880"})
+	if err != nil {
+		t.Fatalf("valid copy threshold should succeed: %v", err)
+	}
+
+	if args.copyThreshold != 5242880 {
+		t.Fatalf("wrong copy threshold: %d", args.copyThreshold)
+	}
+
+	if _, err = parse([]string{"-operation=copy", "-size=10485760", "-copy-threshold=5242880", "-partsize=1048576"}); err == nil {
+		t.Fatalf("multipart copy with a part size below 5MiB should fail")
+	}
+
+	if _, err = parse([]string{"-operation=copy", "-size=10485760", "-partsize=1048576"}); err != nil {
+		t.Fatalf("part size should not matter below the copy threshold: %v", err)
+	}
+}
+
+func TestMPUThreshold(t *testing.T) {
+	args, err := parse([]string{"-operation=put", "-size=1073741824", "-mpu-threshold=104857600"})
+	if err != nil {
+		t.Fatalf("valid mpu threshold should succeed: %v", err)
+	}
+
+	if args.mpuThreshold != 104857600 || args.partsize != minPartSize {
+		t.Fatalf("wrong mpu threshold %d or part size %d", args.mpuThreshold, args.partsize)
+	}
+
+	// the part size is picked for the largest object
+	if args, err = parse([]string{"-operation=put", "-uniformDist=1000-107374182400", "-mpu-threshold=104857600"}); err != nil || args.partsize != autoPartSize(107374182400) {
+		t.Fatalf("expected the part size of the largest object but got %d: %v", args.partsize, err)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-size=1073741824", "-mpu-threshold=104857600", "-partsize=1048576"}); err == nil {
+		t.Fatalf("mpu threshold with a part size below 5MiB should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-mpu-threshold=104857600"}); err == nil {
+		t.Fatalf("mpu threshold with operations other than put should fail")
+	}
+
+	if _, err = parse([]string{"-operation=put", "-mpu-threshold=-1"}); err == nil {
+		t.Fatalf("negative mpu threshold should fail")
+	}
+}
+
+func TestPresignOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=presign", "-presign-method=put", "-presign-expiry=1h"})
+	if err != nil {
+		t.Fatalf("valid presign options should succeed: %v", err)
+	}
+
+	if args.presignMethod != "PUT" || args.presignExpiry != time.Hour {
+		t.Fatalf("wrong presign options: %s %s", args.presignMethod, args.presignExpiry)
+	}
+
+	if _, err = parse([]string{"-operation=presign", "-presign-method=DELETE"}); err == nil {
+		t.Fatalf("unsupported presign method should fail")
+	}
+
+	if _, err = parse([]string{"-operation=presign", "-presign-expiry=169h"}); err == nil {
+		t.Fatalf("presign expiry above 7 days should fail")
+	}
+
+	if args, err = parse([]string{"-operation=presign", "-presign-transfer"}); err != nil || !args.presignTransfer {
+		t.Fatalf("presign-transfer with the presign operation should succeed: %v", err)
+	}
+
+	if _, err = parse([]string{"-operation=get", "-presign-transfer"}); err == nil {
+		t.Fatalf("presign-transfer with operations other than presign should fail")
+	}
+}
+
+func TestListOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=list", "-list-mode=full", "-delimiter=/", "-max-keys=100"})
+	if err != nil {
+		t.Fatalf("valid list options should succeed: %v", err)
+	}
+
+	if args.listMode != "full" || args.delimiter != "/" || args.maxKeys != 100 {
+		t.Fatalf("wrong list options: %s %s %d", args.listMode, args.delimiter, args.maxKeys)
+	}
+
+	if _, err = parse([]string{"-operation=list", "-list-mode=all"}); err == nil {
+		t.Fatalf("unknown list mode should fail")
+	}
+
+	if _, err = parse([]string{"-operation=list", "-max-keys=1001"}); err == nil {
+		t.Fatalf("max keys above 1000 should fail")
+	}
+}
+
+func TestVersionFileOptions(t *testing.T) {
+	if _, err := parse([]string{"-operation=put", "-version-file=versions.csv"}); err != nil {
+		t.Fatalf("recording versions of a put should succeed: %v", err)
+	}
+
+	if _, err := parse([]string{"-operation=versioneddelete", "-version-file=missing.csv"}); err == nil {
+		t.Fatalf("missing version file should fail")
+	}
+
+	if _, err := parse([]string{"-operation=get", "-version-file=versions.csv"}); err == nil {
+		t.Fatalf("version file with get should fail")
+	}
+}
+
+func TestVersionsPerKeyOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=put", "-versions-per-key=5", "-version-file=versions.csv"})
+	if err != nil {
+		t.Fatalf("versions per key of a put should succeed: %v", err)
+	}
+
+	if args.versionsPerKey != 5 || args.attempts != 5 {
+		t.Fatalf("expected every key to be written 5 times but got %d attempts", args.attempts)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-versions-per-key=5"}); err == nil {
+		t.Fatalf("versions per key without version file should fail")
+	}
+
+	if _, err = parse([]string{"-operation=put", "-versions-per-key=5", "-version-file=versions.csv", "-repeat=1"}); err == nil {
+		t.Fatalf("versions per key with repeat should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-versions-per-key=5", "-version-file=versions.csv"}); err == nil {
+		t.Fatalf("versions per key with get should fail")
+	}
+}
+
+func TestMaxConnsPerHostOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=get", "-max-conns-per-host=8"})
+	if err != nil {
+		t.Fatalf("max conns per host should succeed: %v", err)
+	}
+
+	if args.maxConnsPerHost != 8 {
+		t.Fatalf("expected a limit of 8 connections per host but got %d", args.maxConnsPerHost)
+	}
+
+	if _, err = parse([]string{"-operation=get", "-max-conns-per-host=-1"}); err == nil {
+		t.Fatalf("negative max conns per host should fail")
+	}
+}
+
+func TestNumBucketsOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=put", "-num-buckets=4", "-bucket-placement=hash"})
+	if err != nil {
+		t.Fatalf("num buckets should succeed: %v", err)
+	}
+
+	if args.numBuckets != 4 || args.bucketPlacement != bucketPlacementHash {
+		t.Fatalf("expected 4 buckets with hash placement but got %d with %s", args.numBuckets, args.bucketPlacement)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-num-buckets=4", "-bucket-placement=random"}); err == nil {
+		t.Fatalf("unknown bucket placement should fail")
+	}
+
+	if _, err = parse([]string{"-operation=put", "-num-buckets=4", "-bucket-per-worker"}); err == nil {
+		t.Fatalf("num buckets with bucket per worker should fail")
+	}
+
+	if _, err = parse([]string{"-operation=multidelete", "-num-buckets=4"}); err == nil {
+		t.Fatalf("num buckets with multidelete should fail")
+	}
+
+	if _, err = parse([]string{"-operation=put", "-num-buckets=4", "-bucket=Test"}); err == nil {
+		t.Fatalf("num buckets with an invalid bucket name should fail")
+	}
+}
+
+func TestIsolateRunOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=put", "-isolate-run", "-copy-prefix=copy"})
+	if err != nil {
+		t.Fatalf("isolate-run should succeed: %v", err)
+	}
+
+	if args.runId == "" || args.objectprefix != args.runId+"/testobject" || args.copyPrefix != args.runId+"/copy" {
+		t.Fatalf("expected the keys under a default run id but got prefix %s and copy prefix %s", args.objectprefix, args.copyPrefix)
+	}
+
+	if args, err = parse([]string{"-operation=delete", "-isolate-run", "-run-id=run-1"}); err != nil || args.objectprefix != "run-1/testobject" {
+		t.Fatalf("expected the keys of run run-1 but got %q, %v", args.objectprefix, err)
+	}
+
+	if _, err = parse([]string{"-operation=createbucket", "-isolate-run"}); err == nil {
+		t.Fatalf("isolate-run with createbucket should fail")
+	}
+}
+
+func TestAbortIncompleteOptions(t *testing.T) {
+	args, err := parse([]string{"-abort-all-incomplete", "-prefix=mpu", "-concurrency=16"})
+	if err != nil {
+		t.Fatalf("abort-all-incomplete should succeed: %v", err)
+	}
+
+	if !args.abortIncomplete || args.objectprefix != "mpu" {
+		t.Fatalf("expected to abort the uploads under mpu but got %v under %s", args.abortIncomplete, args.objectprefix)
+	}
+
+	if _, err = parse([]string{"-abort-all-incomplete", "-concurrency=0"}); err == nil {
+		t.Fatalf("abort-all-incomplete without concurrency should fail")
+	}
+
+	if _, err = parse([]string{"-operation=abortmultipart", "-duration=10"}); err == nil {
+		t.Fatalf("abortmultipart with duration should fail")
+	}
+}
+
+func TestConditionalOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=put", "-if-none-match=*"})
+	if err != nil {
+		t.Fatalf("conditional create should succeed: %v", err)
+	}
+
+	if args.conditions == nil || args.conditions.ifNoneMatch != "*" {
+		t.Fatalf("expected If-None-Match * but got %+v", args.conditions)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-if-modified-since=2025-06-01T12:30:00Z"}); err == nil {
+		t.Fatalf("if-modified-since with put should fail")
+	}
+
+	if _, err = parse([]string{"-operation=delete", "-if-match=*"}); err == nil {
+		t.Fatalf("conditional headers with delete should fail")
+	}
+}
+
+func TestRepeatedMetadataOptions(t *testing.T) {
+	args, err := parse([]string{"-metadata=key1=value1", "-metadata=key2={{.Key}}&key3=value3"})
+	if err != nil {
+		t.Fatalf("repeated metadata should succeed: %v", err)
+	}
+
+	if args.metadata != "key1=value1&key2={{.Key}}&key3=value3" || args.metadataTemplate == nil {
+		t.Fatalf("expected the metadata of both flags with a template but got %s", args.metadata)
+	}
+
+	if _, err = parse([]string{"-metadata=key1"}); err == nil {
+		t.Fatalf("invalid metadata should fail")
+	}
+}
+
+func TestGCOptions(t *testing.T) {
+	args, err := parse([]string{"-gogc=400", "-gc-memory-limit=2048"})
+	if err != nil {
+		t.Fatalf("gc options should succeed: %v", err)
+	}
+
+	if args.gogc != 400 || args.gcMemoryLimit != 2048 {
+		t.Fatalf("expected GOGC 400 and a limit of 2048 MiB but got %d and %d", args.gogc, args.gcMemoryLimit)
+	}
+
+	if _, err = parse([]string{"-gc-memory-limit=-1"}); err == nil {
+		t.Fatalf("negative gc memory limit should fail")
+	}
+}
+
+func TestRequestExtrasOptions(t *testing.T) {
+	args, err := parse([]string{"-header=X-Debug: on", "-header=X-Amz-Request-Payer: requester", "-query-param=debug"})
+	if err != nil {
+		t.Fatalf("extra headers should succeed: %v", err)
+	}
+
+	if args.requestExtras == nil || len(args.requestExtras.header) != 2 || len(args.requestExtras.query) != 1 {
+		t.Fatalf("expected 2 headers and 1 query parameter but got %+v", args.requestExtras)
+	}
+
+	if _, err = parse([]string{"-header=X-Debug"}); err == nil {
+		t.Fatalf("header without value should fail")
+	}
+
+	if _, err = parse([]string{"-operation=presign", "-presign-transfer", "-header=X-Debug: on"}); err == nil {
+		t.Fatalf("header with presign-transfer should fail")
+	}
+}
+
+func TestBundleOptions(t *testing.T) {
+	args, err := parse([]string{"-bundle=run.tgz"})
+	if err != nil || args.bundle != "run.tgz" {
+		t.Fatalf("expected the bundle run.tgz but got %q, %v", args.bundle, err)
+	}
+
+	if _, err = parse([]string{"-bundle=run.tgz", "-dryrun"}); err == nil {
+		t.Fatalf("bundle with dryrun should fail")
+	}
+}
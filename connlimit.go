@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+var (
+	limitedTransport     *http.Transport
+	limitedTransportOnce sync.Once
+)
+
+// Returns the transport shared by all workers when the connections per host are limited, so that the limit applies to
+// the whole client rather than to every worker. Requests wait in the transport for a connection of their host to become
+// free once the limit is reached.
+func sharedLimitedTransport(maxConnsPerHost int) *http.Transport {
+	limitedTransportOnce.Do(func() {
+		limitedTransport = makeTransport()
+		limitedTransport.MaxConnsPerHost = maxConnsPerHost
+		limitedTransport.MaxIdleConns = 0
+		limitedTransport.MaxIdleConnsPerHost = maxConnsPerHost
+	})
+	return limitedTransport
+}
+
+// connWaitRecorder records the time requests wait for a connection, from asking the transport for one until it is
+// handed a free or newly established connection. Parts and ranges of a worker's request are sent concurrently.
+type connWaitRecorder struct {
+	http.RoundTripper
+	mu    sync.Mutex
+	waits *latencyResult
+}
+
+func newConnWaitRecorder(transport http.RoundTripper) *connWaitRecorder {
+	return &connWaitRecorder{RoundTripper: transport, waits: NewLatencyResult()}
+}
+
+func (t *connWaitRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var start time.Time
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			start = time.Now()
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			t.record(time.Since(start))
+		},
+	}
+	return t.RoundTripper.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+func (t *connWaitRecorder) record(wait time.Duration) {
+	t.mu.Lock()
+	t.waits.record(wait)
+	t.mu.Unlock()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConnWaitRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	transport := makeTransport()
+	transport.MaxConnsPerHost = 1
+	recorder := newConnWaitRecorder(transport)
+	client := &http.Client{Transport: recorder}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(ts.URL)
+			if err != nil {
+				t.Errorf("request should succeed: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if recorder.waits.Count != 3 {
+		t.Fatalf("expected the connection wait of 3 requests but got %d", recorder.waits.Count)
+	}
+
+	// the last request waits for the two before it to release the only connection
+	if longest := time.Duration(recorder.waits.latencies.Max() * 1e4); longest < 150*time.Millisecond {
+		t.Fatalf("expected requests to wait for the connection but the longest wait was %v", longest)
+	}
+}
+
+func TestSharedLimitedTransport(t *testing.T) {
+	transport := sharedLimitedTransport(4)
+	if transport.MaxConnsPerHost != 4 || transport.MaxIdleConnsPerHost != 4 {
+		t.Fatalf("expected a limit of 4 connections per host but got %d", transport.MaxConnsPerHost)
+	}
+
+	if sharedLimitedTransport(4) != transport {
+		t.Fatalf("workers should share the transport")
+	}
+}
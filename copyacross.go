@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// CopyAcross reads the object from the source and writes it to the destination through the client, the way a migration
+// between two systems copies objects. Objects of up to partSize bytes are read into memory and written with a single
+// PUT, larger objects are streamed with a multipart upload of partSize parts, so at most one part is held in memory.
+// With verify the ETag the destination returned for every PUT and part is compared with the MD5 of the data read.
+// Returns the number of bytes copied and the latency of the parts.
+func CopyAcross(src, dest s3iface.S3API, bucket, key, destBucket, destKey, storageClass string, partSize int64, verify bool) (int64, []time.Duration, error) {
+	out, err := src.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer out.Body.Close()
+	size := aws.Int64Value(out.ContentLength)
+
+	if size <= partSize {
+		data := make([]byte, size)
+		if _, err = io.ReadFull(out.Body, data); err != nil {
+			return 0, nil, err
+		}
+		params := &s3.PutObjectInput{
+			Bucket:        aws.String(destBucket),
+			Key:           aws.String(destKey),
+			Body:          bytes.NewReader(data),
+			ContentLength: aws.Int64(size),
+			ContentType:   out.ContentType,
+			Metadata:      out.Metadata,
+		}
+		if storageClass != "" {
+			params.StorageClass = aws.String(storageClass)
+		}
+		put, err := dest.PutObject(params)
+		if err != nil {
+			return 0, nil, err
+		}
+		if verify {
+			if err = verifyCopiedData(destKey, data, put.ETag); err != nil {
+				return size, nil, err
+			}
+		}
+		return size, nil, nil
+	}
+
+	params := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(destBucket),
+		Key:         aws.String(destKey),
+		ContentType: out.ContentType,
+		Metadata:    out.Metadata,
+	}
+	if storageClass != "" {
+		params.StorageClass = aws.String(storageClass)
+	}
+	// the parts are read from the source in order, one at a time
+	buffer := make([]byte, partSize)
+	latencies, err := uploadParts(dest, params, size, partSize, 1, true, func(uploadId *string, partnum, offset, length int64) (*s3.CompletedPart, error) {
+		data := buffer[:length]
+		if _, err := io.ReadFull(out.Body, data); err != nil {
+			return nil, err
+		}
+		output, err := dest.UploadPart(&s3.UploadPartInput{
+			Bucket:        aws.String(destBucket),
+			Key:           aws.String(destKey),
+			ContentLength: aws.Int64(length),
+			Body:          bytes.NewReader(data),
+			UploadId:      uploadId,
+			PartNumber:    aws.Int64(partnum),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if verify {
+			if err = verifyCopiedData(destKey, data, output.ETag); err != nil {
+				return nil, err
+			}
+		}
+		part := &s3.CompletedPart{}
+		part.SetPartNumber(partnum)
+		part.SetETag(aws.StringValue(output.ETag))
+		return part, nil
+	})
+	if err != nil {
+		return 0, latencies, err
+	}
+	return size, latencies, nil
+}
+
+// Returns a soft failure if the ETag the destination returned for the data written to the key isn't its MD5.
+func verifyCopiedData(key string, data []byte, etag *string) error {
+	sum := md5.Sum(data)
+	expected := hex.EncodeToString(sum[:])
+	if actual := strings.Trim(aws.StringValue(etag), "\""); actual != expected {
+		return &softFailure{softFailureETag, fmt.Errorf("ETag mismatch of copied %s: expected %s but the destination returned %s", key, expected, actual)}
+	}
+	return nil
+}
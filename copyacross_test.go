@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// md5S3Client returns the MD5 of the data written as the ETag, like S3 does for unencrypted objects.
+type md5S3Client struct {
+	*mockS3Client
+}
+
+func (this md5S3Client) PutObject(in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	this.S3OpHandler(in)
+	data, _ := ioutil.ReadAll(in.Body)
+	sum := md5.Sum(data)
+	return &s3.PutObjectOutput{ETag: aws.String("\"" + hex.EncodeToString(sum[:]) + "\"")}, nil
+}
+
+func sourceClient(data []byte, metadata map[string]*string) *mockS3Client {
+	return NewMockS3Client(func(in interface{}) interface{} {
+		return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(data)), ContentLength: aws.Int64(int64(len(data))), ContentType: aws.String("text/plain"), Metadata: metadata}
+	})
+}
+
+func TestCopyAcross(t *testing.T) {
+	data := []byte("hello world")
+	v1 := "val1"
+	src := sourceClient(data, map[string]*string{"attribute1": &v1})
+
+	var written []byte
+	dest := NewMockS3Client(func(in interface{}) interface{} {
+		i := in.(*s3.PutObjectInput)
+		if *i.Bucket != "b2" || *i.Key != "k2" || *i.StorageClass != "STANDARD_IA" {
+			t.Fatalf("Expected destination b2/k2 of STANDARD_IA but got: %s/%s %s", *i.Bucket, *i.Key, *i.StorageClass)
+		}
+		if *i.ContentType != "text/plain" || *i.Metadata["attribute1"] != v1 {
+			t.Fatalf("Expected the metadata of the source to be copied")
+		}
+		written, _ = ioutil.ReadAll(i.Body)
+		return in
+	})
+
+	n, latencies, err := CopyAcross(src, dest, "b", "k1", "b2", "k2", "STANDARD_IA", minPartSize, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if n != int64(len(data)) || latencies != nil || !bytes.Equal(written, data) {
+		t.Fatalf("Wrong copy of %d bytes: %q", n, written)
+	}
+}
+
+func TestMultipartCopyAcross(t *testing.T) {
+	var partSize int64 = minPartSize
+	data := make([]byte, 2*partSize+1)
+	NewDummyReader(int64(len(data)), "k1").Read(data)
+	src := sourceClient(data, nil)
+
+	var mu sync.Mutex
+	parts := make(map[int64][]byte)
+	completed := 0
+	dest := NewMockS3Client(func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.UploadPartInput:
+			part, _ := ioutil.ReadAll(i.Body)
+			mu.Lock()
+			parts[*i.PartNumber] = part
+			mu.Unlock()
+		case *s3.CompleteMultipartUploadInput:
+			completed = len(i.MultipartUpload.Parts)
+		}
+		return in
+	})
+
+	n, latencies, err := CopyAcross(src, dest, "b", "k1", "b2", "k2", "", partSize, false)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if n != int64(len(data)) || len(latencies) != 3 || completed != 3 {
+		t.Fatalf("Expected %d bytes in 3 parts but got %d bytes in %d parts", len(data), n, len(latencies))
+	}
+	if !bytes.Equal(append(append(parts[1], parts[2]...), parts[3]...), data) {
+		t.Fatalf("The parts don't add up to the source object")
+	}
+}
+
+func TestCopyAcrossVerify(t *testing.T) {
+	src := sourceClient([]byte("hello world"), nil)
+	dest := md5S3Client{NewMockS3Client(func(in interface{}) interface{} { return in })}
+	if _, _, err := CopyAcross(src, dest, "b", "k1", "b2", "k2", "", minPartSize, true); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// the mock returns no ETag
+	src = sourceClient([]byte("hello world"), nil)
+	_, _, err := CopyAcross(src, NewMockS3Client(func(in interface{}) interface{} { return in }), "b", "k1", "b2", "k2", "", minPartSize, true)
+	if soft, ok := err.(*softFailure); !ok || soft.kind != softFailureETag {
+		t.Fatalf("Expected an ETag mismatch but got: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// costModel holds the prices in USD used to estimate what a workload costs when run against AWS S3.
+type costModel struct {
+	// per 1000 PUT, COPY, POST and LIST requests
+	put float64
+	// per 1000 GET, HEAD and all other requests
+	get float64
+	// per GB stored for a month
+	storage float64
+	// per GB transferred out
+	egress float64
+}
+
+// S3 Standard prices in us-east-1 with data transferred out to the internet.
+var defaultCostModel = costModel{put: 0.005, get: 0.0004, storage: 0.023, egress: 0.09}
+
+// costEstimate is the estimated cost of a workload in USD.
+type costEstimate struct {
+	Requests float64 `json:"requests"`
+	// cost of keeping the written data for one month
+	Storage float64 `json:"storagePerMonth"`
+	Egress  float64 `json:"egress"`
+	Total   float64 `json:"total"`
+}
+
+// Parses prices formatted as 'put=0.005&get=0.0004&storage=0.023&egress=0.09'. Prices that are not specified keep their default.
+func parseCostModel(prices string) (costModel, error) {
+	model := defaultCostModel
+	if prices == "" {
+		return model, nil
+	}
+	for _, pair := range strings.Split(prices, "&") {
+		keyvalue := strings.Split(pair, "=")
+		if len(keyvalue) != 2 {
+			return model, errors.New("Prices must be formatted like: 'put=0.005&get=0.0004&storage=0.023&egress=0.09'")
+		}
+		price, err := strconv.ParseFloat(keyvalue[1], 64)
+		if err != nil || price < 0 {
+			return model, fmt.Errorf("Invalid price for %s: %s", keyvalue[0], keyvalue[1])
+		}
+		switch keyvalue[0] {
+		case "put":
+			model.put = price
+		case "get":
+			model.get = price
+		case "storage":
+			model.storage = price
+		case "egress":
+			model.egress = price
+		default:
+			return model, fmt.Errorf("Unknown price %s, must be one of put, get, storage or egress", keyvalue[0])
+		}
+	}
+	return model, nil
+}
+
+// Returns the number of billable requests S3 receives for a single s3tester operation.
+func billableRequests(op string, size, partSize int64) int64 {
+	switch op {
+	case "parallelget":
+		// one ranged GET per partSize bytes
+		if ranges := int64(math.Ceil(float64(size) / float64(partSize))); ranges > 1 {
+			return ranges
+		}
+		return 1
+	case "multipartput", "initmultipart":
+		parts := int64(math.Ceil(float64(size) / float64(partSize)))
+		if op == "multipartput" {
+			// create, every part and complete
+			return parts + 2
+		}
+		return parts + 1
+	case "copyacross":
+		// the GET of the source and the PUT or the create, every part and complete of the destination
+		if size > partSize {
+			return int64(math.Ceil(float64(size)/float64(partSize))) + 3
+		}
+		return 2
+	}
+	return 1
+}
+
+// Estimates the cost of count operations of the given type that transferred the given number of bytes in total.
+func estimateCost(model costModel, op string, count, bytes, size, partSize int64) costEstimate {
+	var estimate costEstimate
+	gigabytes := float64(bytes) / (1 << 30)
+	requests := float64(count * billableRequests(op, size, partSize))
+
+	switch op {
+	case "put", "multipartput", "initmultipart":
+		estimate.Requests = requests / 1000 * model.put
+		estimate.Storage = gigabytes * model.storage
+	case "puttagging", "updatemeta", "restore", "listmultipartuploads", "listparts", "list", "copy", "listversions", "putretention", "putlegalhold", "putacl", "putlifecycle", "putpolicy", "putcors", "putnotification", "createbucket":
+		estimate.Requests = requests / 1000 * model.put
+	case "get", "randget", "versionedget", "parallelget", "rangeget":
+		estimate.Requests = requests / 1000 * model.get
+		estimate.Egress = gigabytes * model.egress
+	case "copyacross":
+		// the source is read with a single GET and the object leaves it, the rest is written to the destination
+		estimate.Requests = float64(count)/1000*model.get + (requests-float64(count))/1000*model.put
+		estimate.Storage = gigabytes * model.storage
+		estimate.Egress = gigabytes * model.egress
+	case "abortmultipart":
+		// the upload ids are looked up with a ListMultipartUploads, the aborts are free
+		estimate.Requests = requests / 1000 * model.put
+	case "delete", "multidelete", "versioneddelete", "deletetagging", "deletelifecycle", "deletepolicy", "deletecors", "deletebucket":
+		// DELETE requests are free
+	case "presign":
+		// URLs are signed locally without sending any requests
+	default:
+		estimate.Requests = requests / 1000 * model.get
+	}
+	estimate.Total = estimate.Requests + estimate.Storage + estimate.Egress
+	return estimate
+}
+
+// add returns the sum of both estimates.
+func (e costEstimate) add(other costEstimate) costEstimate {
+	e.Requests += other.Requests
+	e.Storage += other.Storage
+	e.Egress += other.Egress
+	e.Total += other.Total
+	return e
+}
+
+// Estimates the cost of the workload described by the arguments before running it.
+func estimatePlannedCost(args parameters) costEstimate {
+	size := args.osize
+	if args.max != 0 {
+		size = (args.min + args.max) / 2
+	}
+	if args.optype == "rangeget" && args.rangeLength < size {
+		size = args.rangeLength
+	}
+	count := int64(args.nrequests.value / args.concurrency * args.concurrency * args.attempts)
+	if args.mix != nil {
+		// the requests are split between the operations by their weights
+		var estimate costEstimate
+		for op, share := range args.mix.share(count) {
+			args.optype = op
+			estimate = estimate.add(estimateCost(args.costModel, billedOperation(args), share, share*size, size, chunkSize(args)))
+		}
+		return estimate
+	}
+	return estimateCost(args.costModel, billedOperation(args), count, count*size, size, chunkSize(args))
+}
+
+// Estimates the cost of a completed run from the requests that were sent and the bytes that were transferred.
+func estimateRunCost(r result, args parameters) costEstimate {
+	var size int64
+	if r.Count > 0 {
+		size = r.sumObjSize / int64(r.Count)
+	}
+	if r.OperationResults != nil {
+		var estimate costEstimate
+		for op, s := range r.OperationResults {
+			args.optype, size = op, 0
+			if s.Count > 0 {
+				size = s.Bytes / int64(s.Count)
+			}
+			estimate = estimate.add(estimateCost(args.costModel, billedOperation(args), int64(s.Count), s.Bytes, size, chunkSize(args)))
+		}
+		return estimate
+	}
+	count := int64(r.Count)
+	if r.PageResult != nil {
+		// every page of a full listing is a request
+		count = int64(r.PageResult.Count)
+	}
+	return estimateCost(args.costModel, billedOperation(args), count, r.sumObjSize, size, chunkSize(args))
+}
+
+// Returns the operation the requests are billed as. Presigned transfers are billed as the GET or PUT they send
+// and PUTs above the mpu threshold as multipart uploads.
+func billedOperation(args parameters) string {
+	if args.optype == "presign" && args.presignTransfer {
+		return strings.ToLower(args.presignMethod)
+	}
+	if args.optype == "put" && args.mpuThreshold > 0 && args.osize > args.mpuThreshold {
+		return "multipartput"
+	}
+	return args.optype
+}
+
+// Returns the size of the parts or ranges an object is transferred in.
+func chunkSize(args parameters) int64 {
+	if args.optype == "parallelget" {
+		return args.rangeSize
+	}
+	return args.partsize
+}
+
+func printCostEstimate(estimate costEstimate, isJson bool) {
+	if isJson {
+		jsonEstimate, err := json.Marshal(map[string]costEstimate{"estimatedCost": estimate})
+		if err != nil {
+			fmt.Println("Error when parsing cost estimate to json")
+			return
+		}
+		fmt.Println(string(jsonEstimate))
+		return
+	}
+	printCost(estimate)
+}
+
+func printCost(estimate costEstimate) {
+	fmt.Println("Estimated cost (USD)")
+	fmt.Printf("Requests            :   $%.4f\n", estimate.Requests)
+	fmt.Printf("Storage (per month) :   $%.4f\n", estimate.Storage)
+	fmt.Printf("Egress              :   $%.4f\n", estimate.Egress)
+	fmt.Printf("Total               :   $%.4f\n", estimate.Total)
+}
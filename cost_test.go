@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func costEquals(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestParseCostModel(t *testing.T) {
+	model, err := parseCostModel("")
+	if err != nil || model != defaultCostModel {
+		t.Fatalf("empty prices should use the defaults: %+v %v", model, err)
+	}
+
+	model, err = parseCostModel("put=1&egress=2")
+	if err != nil {
+		t.Fatalf("valid prices should succeed: %v", err)
+	}
+	if model.put != 1 || model.egress != 2 || model.get != defaultCostModel.get || model.storage != defaultCostModel.storage {
+		t.Fatalf("wrong cost model: %+v", model)
+	}
+
+	for _, prices := range []string{"put", "put=-1", "bandwidth=1", "put=1&"} {
+		if _, err := parseCostModel(prices); err == nil {
+			t.Fatalf("prices %q should fail", prices)
+		}
+	}
+}
+
+func TestEstimatePutCost(t *testing.T) {
+	model := costModel{put: 5, get: 1, storage: 2, egress: 3}
+	estimate := estimateCost(model, "put", 2000, 1<<30, 1<<19, 5<<20)
+
+	if !costEquals(estimate.Requests, 10) || !costEquals(estimate.Storage, 2) || estimate.Egress != 0 {
+		t.Fatalf("wrong put estimate: %+v", estimate)
+	}
+	if !costEquals(estimate.Total, 12) {
+		t.Fatalf("wrong total: %v", estimate.Total)
+	}
+}
+
+func TestEstimateGetCost(t *testing.T) {
+	model := costModel{put: 5, get: 1, storage: 2, egress: 3}
+	estimate := estimateCost(model, "get", 1000, 2<<30, 2<<20, 5<<20)
+
+	if !costEquals(estimate.Requests, 1) || estimate.Storage != 0 || !costEquals(estimate.Egress, 6) {
+		t.Fatalf("wrong get estimate: %+v", estimate)
+	}
+}
+
+func TestEstimateMultipartCost(t *testing.T) {
+	model := costModel{put: 1000}
+	// 3 parts, plus the create and complete requests
+	estimate := estimateCost(model, "multipartput", 1, 0, 11<<20, 5<<20)
+
+	if !costEquals(estimate.Requests, 5) {
+		t.Fatalf("wrong multipart estimate: %+v", estimate)
+	}
+
+	if estimate := estimateCost(model, "delete", 1000, 0, 0, 0); estimate.Total != 0 {
+		t.Fatalf("deletes should be free: %+v", estimate)
+	}
+}
+
+func TestEstimatePlannedCost(t *testing.T) {
+	args := parseAndValidate([]string{"-dryrun", "-requests=1000", "-concurrency=10", "-repeat=1", "-size=1024", "-prices=put=1&storage=1"})
+	estimate := estimatePlannedCost(args)
+
+	if !costEquals(estimate.Requests, 2) {
+		t.Fatalf("wrong request cost: %+v", estimate)
+	}
+	if !costEquals(estimate.Storage, 2000*1024/float64(1<<30)) {
+		t.Fatalf("wrong storage cost: %+v", estimate)
+	}
+}
+
+func TestEstimateMixCost(t *testing.T) {
+	args := parseAndValidate([]string{"-dryrun", "-requests=1000", "-concurrency=10", "-size=1024", "-mix=get:75,put:20,delete:5", "-prices=put=1&get=1&storage=1&egress=1"})
+	estimate := estimatePlannedCost(args)
+
+	// deletes are free
+	if !costEquals(estimate.Requests, 0.95) {
+		t.Fatalf("wrong request cost: %+v", estimate)
+	}
+	if !costEquals(estimate.Storage, 200*1024/float64(1<<30)) || !costEquals(estimate.Egress, 750*1024/float64(1<<30)) {
+		t.Fatalf("wrong storage or egress cost: %+v", estimate)
+	}
+
+	r := NewResult()
+	r.recordOperation("put", time.Millisecond, 2048, false)
+	r.recordOperation("get", time.Millisecond, 1024, false)
+	r.recordOperation("get", time.Millisecond, 1024, false)
+	if estimate = estimateRunCost(r, args); !costEquals(estimate.Requests, 0.003) || !costEquals(estimate.Egress, 2048/float64(1<<30)) {
+		t.Fatalf("wrong run cost: %+v", estimate)
+	}
+}
+
+func TestEstimateParallelGetCost(t *testing.T) {
+	model := costModel{get: 1000}
+	// one ranged GET per started range
+	if estimate := estimateCost(model, "parallelget", 1, 0, 11<<20, 5<<20); !costEquals(estimate.Requests, 3) {
+		t.Fatalf("wrong parallel get estimate: %+v", estimate)
+	}
+
+	if estimate := estimateCost(model, "parallelget", 1, 0, 1<<20, 5<<20); !costEquals(estimate.Requests, 1) {
+		t.Fatalf("wrong parallel get estimate for a small object: %+v", estimate)
+	}
+}
+
+func TestBilledOperation(t *testing.T) {
+	if op := billedOperation(parameters{optype: "presign", presignTransfer: true, presignMethod: "PUT"}); op != "put" {
+		t.Fatalf("presigned transfers should be billed as the request they send but got %s", op)
+	}
+	if op := billedOperation(parameters{optype: "presign", presignMethod: "PUT"}); op != "presign" {
+		t.Fatalf("presigning alone should not be billed as a PUT but got %s", op)
+	}
+	if op := billedOperation(parameters{optype: "put", osize: 200, mpuThreshold: 100}); op != "multipartput" {
+		t.Fatalf("puts above the mpu threshold should be billed as multipart uploads but got %s", op)
+	}
+	if op := billedOperation(parameters{optype: "put", osize: 100, mpuThreshold: 100}); op != "put" {
+		t.Fatalf("puts up to the mpu threshold should be billed as puts but got %s", op)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// endpointDiscovery keeps the endpoints of an elastic cluster up to date by looking them up periodically, in DNS SRV
+// records or in the healthy instances of a Consul service, so that a run follows nodes that are added or removed.
+// Every worker sends its requests to one of the current endpoints.
+type endpointDiscovery struct {
+	source   string
+	interval time.Duration
+	// looks up the current endpoints
+	lookup func() ([]string, error)
+
+	mu        sync.RWMutex
+	endpoints []string
+}
+
+// Parses a discovery source like srv://_s3._tcp.storage.example.com or consul://10.0.0.5:8500/s3. The discovered
+// hosts are reached with the given scheme.
+func NewEndpointDiscovery(source, scheme string, interval time.Duration) (*endpointDiscovery, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, errors.New("discover-scheme must be http or https")
+	}
+	d := &endpointDiscovery{source: source, interval: interval}
+	switch u.Scheme {
+	case "srv":
+		if u.Host == "" {
+			return nil, errors.New("discover must name the SRV record like srv://_s3._tcp.storage.example.com")
+		}
+		d.lookup = func() ([]string, error) { return lookupSRV(u.Host, scheme) }
+	case "consul":
+		service := strings.Trim(u.Path, "/")
+		if u.Host == "" || service == "" {
+			return nil, errors.New("discover must name the Consul agent and service like consul://10.0.0.5:8500/s3")
+		}
+		d.lookup = func() ([]string, error) { return lookupConsul(u.Host, service, scheme) }
+	default:
+		return nil, errors.New("discover must be a srv:// or consul:// URL")
+	}
+	return d, nil
+}
+
+// Returns the endpoints of the targets of the SRV record.
+func lookupSRV(name, scheme string) ([]string, error) {
+	_, records, err := net.LookupSRV("", "", name)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		endpoints = append(endpoints, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+	return endpoints, nil
+}
+
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// Returns the endpoints of the instances of the service that pass their Consul health checks.
+func lookupConsul(agent, service, scheme string) ([]string, error) {
+	resp, err := http.Get("http://" + agent + "/v1/health/service/" + url.PathEscape(service) + "?passing")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Consul returned %s", resp.Status)
+	}
+	var entries []consulServiceEntry
+	if err = json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	endpoints := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// the service address is empty when the service uses the address of its node
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		endpoints = append(endpoints, scheme+"://"+net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	return endpoints, nil
+}
+
+// refresh looks up the endpoints and replaces the current ones. A failed lookup or one that finds no endpoints keeps
+// the current endpoints.
+func (this *endpointDiscovery) refresh() error {
+	endpoints, err := this.lookup()
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return errors.New("no endpoints found")
+	}
+	// the workers are assigned to the endpoints in order, sorting keeps them on their endpoint while it is there
+	sort.Strings(endpoints)
+
+	this.mu.Lock()
+	previous := this.endpoints
+	this.endpoints = endpoints
+	this.mu.Unlock()
+	if !reflect.DeepEqual(previous, endpoints) {
+		log.Printf("Discovered %d endpoints of %s: %s", len(endpoints), this.source, strings.Join(endpoints, ", "))
+	}
+	return nil
+}
+
+// start looks up the endpoints and keeps refreshing them every interval for the rest of the process.
+func (this *endpointDiscovery) start() error {
+	if err := this.refresh(); err != nil {
+		return err
+	}
+	go func() {
+		for range time.Tick(this.interval) {
+			if err := this.refresh(); err != nil {
+				log.Printf("Failed refreshing the endpoints of %s, keeping the known endpoints: %v", this.source, err)
+			}
+		}
+	}()
+	return nil
+}
+
+// endpoint returns the current endpoint of the worker.
+func (this *endpointDiscovery) endpoint(worker int) string {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.endpoints[worker%len(this.endpoints)]
+}
+
+// route sends the request to the current endpoint of the worker.
+func (this *endpointDiscovery) route(req *http.Request, worker int) error {
+	endpoint, err := url.Parse(this.endpoint(worker))
+	if err != nil {
+		return err
+	}
+	req.URL.Scheme = endpoint.Scheme
+	req.URL.Host = endpoint.Host
+	return nil
+}
+
+// install sends every request of the worker to its current endpoint. The request is redirected before it is signed,
+// retries go to the endpoint of the first attempt. It is safe to call on a nil discovery, which does nothing.
+func (this *endpointDiscovery) install(svc *s3.S3, worker int) {
+	if this == nil {
+		return
+	}
+	svc.Client.Handlers.Build.PushBack(func(r *request.Request) {
+		if err := this.route(r.HTTPRequest, worker); err != nil {
+			r.Error = err
+		}
+	})
+}
+
+func (this *result) recordDiscoveredLatency(endpoint string, l time.Duration) {
+	if this.DiscoveredResults == nil {
+		this.DiscoveredResults = make(map[string]*latencyResult)
+	}
+	s, ok := this.DiscoveredResults[endpoint]
+	if !ok {
+		s = NewLatencyResult()
+		this.DiscoveredResults[endpoint] = s
+	}
+	s.record(l)
+}
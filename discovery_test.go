@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewEndpointDiscovery(t *testing.T) {
+	for _, source := range []string{"srv://_s3._tcp.storage.example.com", "consul://10.0.0.5:8500/s3"} {
+		if _, err := NewEndpointDiscovery(source, "https", time.Second); err != nil {
+			t.Fatalf("Expected %s to be accepted: %v", source, err)
+		}
+	}
+
+	for _, source := range []string{"dns://storage.example.com", "srv://", "consul://10.0.0.5:8500", "10.0.0.5"} {
+		if _, err := NewEndpointDiscovery(source, "https", time.Second); err == nil {
+			t.Fatalf("Expected %s to be rejected", source)
+		}
+	}
+
+	if _, err := NewEndpointDiscovery("srv://_s3._tcp.storage.example.com", "ftp", time.Second); err == nil {
+		t.Fatalf("Expected the ftp scheme to be rejected")
+	}
+}
+
+func TestConsulDiscovery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/s3" || !strings.Contains(r.URL.RawQuery, "passing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"Node":{"Address":"10.0.0.8"},"Service":{"Address":"","Port":9000}},
+			{"Node":{"Address":"10.0.0.9"},"Service":{"Address":"10.1.0.7","Port":9000}}]`))
+	}))
+	defer server.Close()
+
+	d, err := NewEndpointDiscovery("consul://"+strings.TrimPrefix(server.URL, "http://")+"/s3", "http", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.refresh(); err != nil {
+		t.Fatalf("Discovering the endpoints should succeed: %v", err)
+	}
+
+	expected := []string{"http://10.0.0.8:9000", "http://10.1.0.7:9000"}
+	if !reflect.DeepEqual(d.endpoints, expected) {
+		t.Fatalf("Expected endpoints %v but got %v", expected, d.endpoints)
+	}
+
+	d, _ = NewEndpointDiscovery("consul://"+strings.TrimPrefix(server.URL, "http://")+"/swift", "http", time.Second)
+	if err = d.refresh(); err == nil {
+		t.Fatalf("Expected an error for an unknown service")
+	}
+}
+
+func TestDiscoveryRefresh(t *testing.T) {
+	found := []string{"https://b:443", "https://a:443"}
+	var lookupErr error
+	d := &endpointDiscovery{source: "test", lookup: func() ([]string, error) { return found, lookupErr }}
+	if err := d.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if d.endpoint(0) != "https://a:443" || d.endpoint(3) != "https://b:443" {
+		t.Fatalf("Workers should be assigned to the sorted endpoints in turn")
+	}
+
+	// a node is added
+	found = []string{"https://a:443", "https://b:443", "https://c:443"}
+	d.refresh()
+	if d.endpoint(2) != "https://c:443" {
+		t.Fatalf("Expected worker 2 to move to the new endpoint but got %s", d.endpoint(2))
+	}
+
+	// failed and empty lookups keep the endpoints
+	for _, lookupErr = range []error{errors.New("timeout"), nil} {
+		found = nil
+		if err := d.refresh(); err == nil || len(d.endpoints) != 3 {
+			t.Fatalf("Expected the endpoints to be kept after a failed lookup but got %v", d.endpoints)
+		}
+	}
+}
+
+func TestDiscoveryRoute(t *testing.T) {
+	d := &endpointDiscovery{endpoints: []string{"http://10.0.0.7:9000", "http://10.0.0.8:9000"}}
+	req, _ := http.NewRequest("GET", "https://127.0.0.1:18082/test/object-0?versionId=1", nil)
+	if err := d.route(req, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL.String() != "http://10.0.0.8:9000/test/object-0?versionId=1" {
+		t.Fatalf("Expected the request to go to the endpoint of the worker but got %s", req.URL)
+	}
+}
+
+func TestMergeDiscoveredResults(t *testing.T) {
+	r1, r2, merged := NewResult(), NewResult(), NewResult()
+	r1.recordDiscoveredLatency("http://a:9000", time.Millisecond)
+	r2.recordDiscoveredLatency("http://a:9000", 3*time.Millisecond)
+	r2.recordDiscoveredLatency("http://b:9000", time.Millisecond)
+	mergeResult(&merged, &r1)
+	mergeResult(&merged, &r2)
+
+	if merged.DiscoveredResults["http://a:9000"].Count != 2 || merged.DiscoveredResults["http://b:9000"].Count != 1 {
+		t.Fatalf("Wrong merged discovered endpoint results: %v", merged.DiscoveredResults)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// dnsResult holds the lookups of the endpoint host names done when connections were established.
+type dnsResult struct {
+	Lookups  *latencyResult `json:"lookupTime"`
+	Failures int            `json:"failedLookups"`
+	// hosts resolved to an address different from the one before, e.g. as a round robin record or a failover moved
+	AddressChanges int `json:"addressChanges"`
+}
+
+func NewDNSResult() *dnsResult {
+	return &dnsResult{Lookups: NewLatencyResult()}
+}
+
+// merge adds the other result, results without any lookups are left out so that runs against IP addresses don't
+// report DNS.
+func (this *dnsResult) merge(other *dnsResult) *dnsResult {
+	if other == nil || other.Lookups.Count == 0 {
+		return this
+	}
+	if this == nil {
+		this = NewDNSResult()
+	}
+	this.Lookups = this.Lookups.merge(other.Lookups)
+	this.Failures += other.Failures
+	this.AddressChanges += other.AddressChanges
+	return this
+}
+
+// dnsRecorder records the time and the outcome of the DNS lookups of the connections established by a worker. Parts
+// and ranges of a worker's request are sent concurrently.
+type dnsRecorder struct {
+	http.RoundTripper
+	mu     sync.Mutex
+	result *dnsResult
+	// the first address every host was resolved to last
+	addresses map[string]string
+}
+
+func newDNSRecorder(transport http.RoundTripper) *dnsRecorder {
+	return &dnsRecorder{RoundTripper: transport, result: NewDNSResult(), addresses: make(map[string]string)}
+}
+
+func (t *dnsRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var start time.Time
+	var host string
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			start = time.Now()
+			host = info.Host
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			address := ""
+			if len(info.Addrs) > 0 {
+				address = info.Addrs[0].String()
+			}
+			t.record(host, address, time.Since(start), info.Err)
+		},
+	}
+	return t.RoundTripper.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
+
+func (t *dnsRecorder) record(host, address string, lookup time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.result.Lookups.record(lookup)
+	if err != nil {
+		t.result.Failures++
+		return
+	}
+	if previous, ok := t.addresses[host]; ok && previous != address {
+		t.result.AddressChanges++
+	}
+	t.addresses[host] = address
+}
+
+// refreshConnections closes the idle connections of the transport every interval until stop is closed, so that the
+// next requests connect again and resolve the endpoint again instead of sticking to the addresses of their kept alive
+// connections.
+func refreshConnections(transport *http.Transport, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			transport.CloseIdleConnections()
+		case <-stop:
+			return
+		}
+	}
+}
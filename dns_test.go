@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+func getLocalhost(t *testing.T, client *http.Client, url string) {
+	resp, err := client.Get(strings.Replace(url, "127.0.0.1", "localhost", 1))
+	if err != nil {
+		t.Fatalf("request should succeed: %v", err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+}
+
+func TestDNSRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	transport := makeTransport()
+	recorder := newDNSRecorder(transport)
+	client := &http.Client{Transport: recorder}
+	getLocalhost(t, client, ts.URL)
+	// the connection is kept alive
+	getLocalhost(t, client, ts.URL)
+	if recorder.result.Lookups.Count != 1 || recorder.result.Failures != 0 {
+		t.Fatalf("Expected 1 lookup but got %d and %d failures", recorder.result.Lookups.Count, recorder.result.Failures)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go refreshConnections(transport, 20*time.Millisecond, stop)
+	time.Sleep(100 * time.Millisecond)
+	getLocalhost(t, client, ts.URL)
+	if recorder.result.Lookups.Count != 2 {
+		t.Fatalf("Expected the refreshed connection to look the host up again but got %d lookups", recorder.result.Lookups.Count)
+	}
+}
+
+func TestDNSRecorderAddresses(t *testing.T) {
+	recorder := newDNSRecorder(nil)
+	recorder.record("s3.example.com", "10.0.0.1", time.Millisecond, nil)
+	recorder.record("s3.example.com", "10.0.0.1", time.Millisecond, nil)
+	recorder.record("s3.example.com", "10.0.0.2", time.Millisecond, nil)
+	recorder.record("s3.example.com", "", time.Second, errors.New("no such host"))
+	if r := recorder.result; r.Lookups.Count != 4 || r.Failures != 1 || r.AddressChanges != 1 {
+		t.Fatalf("Expected 4 lookups, 1 failure and 1 address change but got %d, %d and %d", r.Lookups.Count, r.Failures, r.AddressChanges)
+	}
+}
+
+func TestMergeDNSResults(t *testing.T) {
+	var merged *dnsResult
+	if merged = merged.merge(NewDNSResult()); merged != nil {
+		t.Fatalf("Results without lookups should be left out")
+	}
+
+	recorder := newDNSRecorder(nil)
+	recorder.record("s3.example.com", "", time.Second, errors.New("no such host"))
+	merged = merged.merge(recorder.result).merge(recorder.result)
+	if merged.Lookups.Count != 2 || merged.Failures != 2 {
+		t.Fatalf("Expected 2 lookups and 2 failures but got %d and %d", merged.Lookups.Count, merged.Failures)
+	}
+}
+
+func TestMakeS3ServiceWithDNSRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3tester-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bundle, _ := writeTestCertificate(t, dir, "ca", time.Now())
+	previous, set := os.LookupEnv("AWS_CA_BUNDLE")
+	os.Setenv("AWS_CA_BUNDLE", bundle)
+	defer func() {
+		if set {
+			os.Setenv("AWS_CA_BUNDLE", previous)
+		} else {
+			os.Unsetenv("AWS_CA_BUNDLE")
+		}
+	}()
+
+	// the SDK only loads the CA bundle into an *http.Transport
+	client := &http.Client{Transport: newDNSRecorder(makeTransport())}
+	svc := MakeS3Service(client, 0, 0, "http://127.0.0.1:18082", "us-east-1", "", credentials.AnonymousCredentials)
+	if svc.Client.Config.HTTPClient != client {
+		t.Fatalf("Expected the service to send its requests through the DNS recorder")
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"math"
+	"math/rand"
 	"strings"
+	"time"
 )
 
 // For performance reasons we need to generate data in blocks as opposed to one character at a time. This is especially true
@@ -14,21 +19,141 @@ import (
 // This MUST be a power of two to allow for fast modulo optimizations.
 const objectDataBlockSize = 4096
 
+// payloadOptions control the content of the object data generated for a key.
+type payloadOptions struct {
+	// Target compressibility of the data, e.g. 4 for 4:1. Zero keeps the default data that repeats the key.
+	compressRatio float64
+	// Appended to the key when seeding the data, e.g. to identify the writer of an object.
+	seedSuffix string
+	// When dedupePool is set objects are made of dedupeChunk sized chunks drawn from a pool of dedupePool
+	// distinct chunks shared by all objects. dedupeChunk must be a multiple of objectDataBlockSize.
+	dedupeChunk int64
+	dedupePool  int64
+	// When set, PUT payloads are read from these files instead of being generated.
+	files *payloadFiles
+	// When set, PUT payloads are read from this external generator instead of being generated, and the time spent
+	// waiting for it is recorded into streamWaits.
+	stream      *payloadStream
+	streamWaits *latencyResult
+	// When set, generated objects start with a write generation header and reads are checked for stale data.
+	generations *generationResult
+	// When set, the write generation of the next object, whose data is seeded by its generation as well.
+	generation int64
+	// When set, reads are checked against the writes acknowledged before and for data of a single generation.
+	freshness *freshnessResult
+	// When set, uploads send and downloads verify checksums of this algorithm.
+	checksums *checksumResult
+	// When set, downloads of whole objects are checked against their ETag if it is the MD5 of the object.
+	verifyETag bool
+}
+
+// Compressible and dedupable data can't repeat a single block because compressors and dedupe engines
+// would find the repetition, so every block of the object is generated separately.
+func (o payloadOptions) perBlock() bool {
+	return o.compressRatio > 0 || o.dedupePool > 0
+}
+
+// fillBlock generates the data for block number index of the object with the given key.
+// The data is fully determined by the key and index so that it can be regenerated for verification.
+// The source of the reader is seeded again for every block rather than allocating a new one.
+func (o payloadOptions) fillBlock(block []byte, key string, index int64, source *rand.Rand) {
+	if !o.perBlock() {
+		copy(block, generateDataFromKey(key, len(block)))
+		return
+	}
+
+	h := fnv.New64a()
+	if o.dedupePool > 0 {
+		// Every chunk of the object is picked from the shared pool based on the key, and the block is seeded
+		// by the chunk it belongs to rather than the key so that identical chunks hold identical data.
+		offset := index * int64(len(block))
+		h.Write([]byte(key))
+		binary.Write(h, binary.LittleEndian, offset/o.dedupeChunk)
+		chunk := h.Sum64() % uint64(o.dedupePool)
+
+		h.Reset()
+		h.Write([]byte("dedupe-chunk"))
+		binary.Write(h, binary.LittleEndian, chunk)
+		binary.Write(h, binary.LittleEndian, offset%o.dedupeChunk)
+	} else {
+		h.Write([]byte(key))
+		binary.Write(h, binary.LittleEndian, index)
+	}
+	source.Seed(int64(h.Sum64()))
+
+	// Mix a run of random bytes with a run of zeros so that the block compresses at the requested ratio.
+	random := len(block)
+	if o.compressRatio > 0 {
+		random = int(float64(len(block)) / o.compressRatio)
+	}
+	source.Read(block[:random])
+	for i := random; i < len(block); i++ {
+		block[i] = 0
+	}
+}
+
+// Returns the number of distinct chunks needed for the given number of chunks to dedupe at ratio:1.
+func dedupePoolSize(chunks int64, ratio float64) int64 {
+	pool := int64(math.Ceil(float64(chunks) / ratio))
+	if pool < 1 {
+		pool = 1
+	}
+	return pool
+}
+
 // implements io.ReadSeeker
 type DummyReader struct {
-	size   int64
-	offset int64
-	data   *bytes.Reader
+	size       int64
+	offset     int64
+	data       *bytes.Reader
+	block      []byte
+	key        string
+	blockIndex int64
+	payload    payloadOptions
+	// the source of the random data of the blocks, nil unless the data differs per block
+	source *rand.Rand
+	// the write generation stamped at the start of the object, 0 if none
+	generation int64
 }
 
 func NewDummyReader(size int64, seed string) *DummyReader {
-	d := DummyReader{size: size}
-	data := generateDataFromKey(seed, objectDataBlockSize)
-	d.data = bytes.NewReader(data)
+	return NewPayloadReader(size, seed, payloadOptions{})
+}
+
+func NewPayloadReader(size int64, seed string, payload payloadOptions) *DummyReader {
+	seed += payload.seedSuffix
+	if payload.generation != 0 {
+		seed = generationSeed(seed, payload.generation)
+	}
+	d := DummyReader{size: size, key: seed, payload: payload, block: make([]byte, objectDataBlockSize)}
+	if payload.perBlock() {
+		d.source = rand.New(rand.NewSource(0))
+	}
+	payload.fillBlock(d.block, seed, 0, d.source)
+	if payload.generations != nil {
+		d.generation = time.Now().UnixNano()
+		if payload.generation != 0 {
+			d.generation = payload.generation
+		}
+		stampGeneration(d.block, d.generation)
+	}
+	d.data = bytes.NewReader(d.block)
 
 	return &d
 }
 
+// moves to the given block of the object, regenerating the data if it differs per block
+func (r *DummyReader) setBlock(index int64) {
+	if (r.payload.perBlock() || r.generation != 0) && index != r.blockIndex {
+		r.payload.fillBlock(r.block, r.key, index, r.source)
+		if index == 0 && r.generation != 0 {
+			stampGeneration(r.block, r.generation)
+		}
+		r.data.Reset(r.block)
+	}
+	r.blockIndex = index
+}
+
 func (r *DummyReader) Size() int64 {
 	return r.size
 }
@@ -60,6 +185,7 @@ func (r *DummyReader) Read(p []byte) (n int, err error) {
 		bytesTransferred, _ = r.data.Read(p[i:read])
 
 		if r.data.Len() == 0 {
+			r.setBlock(r.blockIndex + 1)
 			r.data.Seek(0, io.SeekStart)
 		}
 	}
@@ -72,6 +198,7 @@ func (r *DummyReader) Read(p []byte) (n int, err error) {
 func (r *DummyReader) Seek(offset int64, whence int) (int64, error) {
 	updateDummyDataOffset := func() {
 		if r.data != nil {
+			r.setBlock(r.offset / r.data.Size())
 			r.data.Seek(r.offset%r.data.Size(), io.SeekStart)
 		}
 	}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"bytes"
+	"compress/flate"
 	"io"
+	"io/ioutil"
+	"strconv"
 	"testing"
 )
 
@@ -134,6 +138,132 @@ func TestGenerateData(t *testing.T) {
 	}
 }
 
+// Large objects must be synthesized from a single small block rather than materialized in memory.
+func TestLargeObjectUsesDataBlock(t *testing.T) {
+	var size int64 = 1 << 30
+	key := "large-object"
+	d := NewDummyReader(size, key)
+
+	if d.data.Size() != objectDataBlockSize {
+		t.Fatalf("expected a %d byte data block but got %d bytes", objectDataBlockSize, d.data.Size())
+	}
+
+	// Read across the last block boundary of the object to make sure seeking keeps data aligned with the block.
+	offset := size - objectDataBlockSize - 2
+	if _, err := d.Seek(offset, io.SeekStart); err != nil {
+		t.Fatalf("expected no error but got %s", err)
+	}
+
+	buff := make([]byte, objectDataBlockSize+10)
+	bytesRead, err := d.Read(buff)
+
+	if err != nil {
+		t.Fatalf("expected no error but got %s", err)
+	}
+
+	if int64(bytesRead) != size-offset {
+		t.Fatalf("expected to read %d bytes but got %d", size-offset, bytesRead)
+	}
+
+	block := generateDataFromKey(key, objectDataBlockSize)
+	for i := 0; i < bytesRead; i++ {
+		if buff[i] != block[(offset+int64(i))%objectDataBlockSize] {
+			t.Fatalf("unexpected data at offset %d", offset+int64(i))
+		}
+	}
+}
+
+func compressedSize(t *testing.T, data []byte) int {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatalf("expected no error but got %s", err)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Len()
+}
+
+func TestCompressRatioOfPayload(t *testing.T) {
+	var size int64 = 1 << 20
+
+	for _, ratio := range []float64{1, 2, 4} {
+		data, err := ioutil.ReadAll(NewPayloadReader(size, "compressible", payloadOptions{compressRatio: ratio}))
+		if err != nil {
+			t.Fatalf("expected no error but got %s", err)
+		}
+
+		if int64(len(data)) != size {
+			t.Fatalf("expected to read %d bytes but got %d", size, len(data))
+		}
+
+		actual := float64(size) / float64(compressedSize(t, data))
+		if actual < ratio*0.9 || actual > ratio*1.1 {
+			t.Fatalf("expected a compress ratio of %v but got %v", ratio, actual)
+		}
+	}
+}
+
+// Compressible data differs per block but must be reproducible from the key for verification.
+func TestCompressiblePayloadIsDeterministic(t *testing.T) {
+	payload := payloadOptions{compressRatio: 2}
+	var size int64 = 4 * objectDataBlockSize
+
+	first, _ := ioutil.ReadAll(NewPayloadReader(size, "key", payload))
+	second, _ := ioutil.ReadAll(NewPayloadReader(size, "key", payload))
+	if !bytes.Equal(first, second) {
+		t.Fatalf("expected identical data for the same key")
+	}
+
+	if bytes.Equal(first[:objectDataBlockSize], first[objectDataBlockSize:2*objectDataBlockSize]) {
+		t.Fatalf("expected blocks to differ")
+	}
+
+	other, _ := ioutil.ReadAll(NewPayloadReader(size, "other-key", payload))
+	if bytes.Equal(first, other) {
+		t.Fatalf("expected different data for different keys")
+	}
+
+	// seeking into the middle of the object must yield the same bytes as a sequential read
+	d := NewPayloadReader(size, "key", payload)
+	offset := int64(2*objectDataBlockSize + 7)
+	d.Seek(offset, io.SeekStart)
+	rest, _ := ioutil.ReadAll(d)
+	if !bytes.Equal(rest, first[offset:]) {
+		t.Fatalf("expected data after seek to match sequential read")
+	}
+}
+
+func TestDedupeRatioOfPayload(t *testing.T) {
+	var chunk int64 = 2 * objectDataBlockSize
+	var size int64 = 8 * chunk
+	objects := 40
+	ratio := 3.0
+	payload := payloadOptions{dedupeChunk: chunk, dedupePool: dedupePoolSize(int64(objects)*size/chunk, ratio)}
+
+	unique := make(map[string]bool)
+	for i := 0; i < objects; i++ {
+		data, err := ioutil.ReadAll(NewPayloadReader(size, "dedupe-"+strconv.Itoa(i), payload))
+		if err != nil {
+			t.Fatalf("expected no error but got %s", err)
+		}
+		for c := int64(0); c < size; c += chunk {
+			unique[string(data[c:c+chunk])] = true
+		}
+	}
+
+	actual := float64(int64(objects)*size/chunk) / float64(len(unique))
+	if actual < ratio*0.85 || actual > ratio*1.25 {
+		t.Fatalf("expected a dedupe ratio of %v but got %v", ratio, actual)
+	}
+
+	// chunks must not be compressible so that only dedupe reduces the data
+	data, _ := ioutil.ReadAll(NewPayloadReader(size, "dedupe-0", payload))
+	if compressed := compressedSize(t, data); float64(compressed) < float64(size)*0.95 {
+		t.Fatalf("expected incompressible chunks but %d bytes compressed to %d", size, compressed)
+	}
+}
+
 ///// BENCHMARKS /////
 func BenchmarkGenerateData(b *testing.B) {
 	for n := 0; n < b.N; n++ {
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The unit of the CPU times of /proc/self/stat, USER_HZ, which is 100 on all common Linux platforms.
+const clockTicksPerSecond = 100
+
+// efficiencyResult holds the CPU time the tester used during a run and what it got done per CPU-second, so that the
+// number of load generators needed to saturate a cluster can be planned from the runs of a single one.
+type efficiencyResult struct {
+	CPUSeconds           float64 `json:"cpuSeconds"`
+	CoresUsed            float64 `json:"coresUsed"`
+	RequestsPerCPUSecond float64 `json:"requestsPerCpuSecond"`
+	MiBPerCPUSecond      float64 `json:"MiBPerCpuSecond"`
+
+	cpu     time.Duration
+	elapsed time.Duration
+}
+
+// cpuMeter measures the CPU time of the tester while a run is going on.
+type cpuMeter struct {
+	start    time.Time
+	cpuStart time.Duration
+}
+
+// startCPUMeter returns a meter of the CPU time of the run, nil on platforms without /proc.
+func startCPUMeter() *cpuMeter {
+	cpu, ok := processCPUTime()
+	if !ok {
+		return nil
+	}
+	return &cpuMeter{start: time.Now(), cpuStart: cpu}
+}
+
+// halt returns the CPU time used since the meter was started, nil without a meter.
+func (m *cpuMeter) halt() *efficiencyResult {
+	if m == nil {
+		return nil
+	}
+	cpu, ok := processCPUTime()
+	if !ok {
+		return nil
+	}
+	return &efficiencyResult{cpu: cpu - m.cpuStart, elapsed: time.Since(m.start)}
+}
+
+// processCPUTime returns the user and system CPU time the process used so far, false on platforms without /proc.
+func processCPUTime() (time.Duration, bool) {
+	stat, err := ioutil.ReadFile("/proc/self/stat")
+	if err != nil {
+		return 0, false
+	}
+	return parseProcStat(string(stat))
+}
+
+// Returns the user and system CPU time of the contents of /proc/<pid>/stat, the 14th and 15th fields. The name of the
+// command in the 2nd field is in parentheses and may hold spaces.
+func parseProcStat(stat string) (time.Duration, bool) {
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, false
+	}
+	// the fields from the 3rd on
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 13 {
+		return 0, false
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(utime+stime) * time.Second / clockTicksPerSecond, true
+}
+
+func (this *efficiencyResult) setupStats(count int, bytes int64) {
+	if this == nil {
+		return
+	}
+	this.CPUSeconds = roundFloat(this.cpu.Seconds(), 2)
+	if this.elapsed > 0 {
+		this.CoresUsed = roundFloat(this.cpu.Seconds()/this.elapsed.Seconds(), 2)
+	}
+	if this.cpu > 0 {
+		this.RequestsPerCPUSecond = roundFloat(float64(count)/this.cpu.Seconds(), 1)
+		this.MiBPerCPUSecond = roundFloat(float64(bytes)/1024/1024/this.cpu.Seconds(), 3)
+	}
+}
+
+func printEfficiency(efficiency *efficiencyResult) {
+	fmt.Printf("CPU of the tester: %.2f CPU-seconds (%.2f cores), %.1f requests and %.3f MiB per CPU-second\n", efficiency.CPUSeconds, efficiency.CoresUsed, efficiency.RequestsPerCPUSecond, efficiency.MiBPerCPUSecond)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseProcStat(t *testing.T) {
+	// the name of the command may hold spaces and parentheses
+	stat := "4242 (s3 tester (x)) R 1 4242 4242 0 -1 4194560 1500 0 0 0 250 75 0 0 20 0 12 0 100 0 0"
+	cpu, ok := parseProcStat(stat)
+	if !ok || cpu != 3250*time.Millisecond {
+		t.Fatalf("Expected 3.25s of CPU time but got %s (%v)", cpu, ok)
+	}
+	for _, invalid := range []string{"", "4242 (s3tester) R 1 2 3", "4242 (s3tester) R 1 4242 4242 0 -1 4194560 1500 0 0 0 x 75 0"} {
+		if _, ok := parseProcStat(invalid); ok {
+			t.Fatalf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestEfficiencySetupStats(t *testing.T) {
+	e := &efficiencyResult{cpu: 4 * time.Second, elapsed: 2 * time.Second}
+	e.setupStats(10000, 400*1024*1024)
+	if e.CPUSeconds != 4 || e.CoresUsed != 2 || e.RequestsPerCPUSecond != 2500 || e.MiBPerCPUSecond != 100 {
+		t.Fatalf("Wrong efficiency stats: %+v", e)
+	}
+
+	var none *efficiencyResult
+	none.setupStats(10, 10)
+}
+
+func TestCPUMeter(t *testing.T) {
+	m := startCPUMeter()
+	if m == nil {
+		t.Skip("no /proc on this platform")
+	}
+	for end := time.Now().Add(50 * time.Millisecond); time.Now().Before(end); {
+	}
+	if r := m.halt(); r == nil || r.elapsed <= 0 {
+		t.Fatalf("Expected the CPU time of the run but got %+v", r)
+	}
+}
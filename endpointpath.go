@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// endpointPath is the path prefix of an endpoint like https://gw.example.com/object-api, under which an API gateway
+// serves the S3 API.
+type endpointPath struct {
+	path string
+	// the escaped path, for requests whose path the SDK escaped itself
+	rawPath string
+}
+
+// Splits the endpoint into the endpoint without its path and the path prefix, which is nil for an endpoint without
+// a path.
+func splitEndpointPath(endpoint string) (string, *endpointPath) {
+	u, err := url.Parse(endpoint)
+	if err != nil || !strings.Contains(endpoint, "://") || strings.Trim(u.Path, "/") == "" {
+		return endpoint, nil
+	}
+	p := &endpointPath{path: strings.TrimRight(u.Path, "/"), rawPath: strings.TrimRight(u.EscapedPath(), "/")}
+	u.Path, u.RawPath = "", ""
+	return u.String(), p
+}
+
+// apply puts the path prefix in front of the path of the request.
+func (p *endpointPath) apply(req *http.Request) {
+	req.URL.Path = p.path + req.URL.Path
+	if req.URL.RawPath != "" {
+		req.URL.RawPath = p.rawPath + req.URL.RawPath
+	}
+}
+
+// install puts the path prefix in front of the path of every request of the service. It is added before the request is
+// signed, so the canonical path of the signature is the prefixed path the gateway receives.
+func (p *endpointPath) install(svc *s3.S3) {
+	if p == nil {
+		return
+	}
+	svc.Client.Handlers.Build.PushBack(func(r *request.Request) {
+		p.apply(r.HTTPRequest)
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSplitEndpointPath(t *testing.T) {
+	base, prefix := splitEndpointPath("https://gw.example.com:8443/object-api/")
+	if base != "https://gw.example.com:8443" || prefix == nil || prefix.path != "/object-api" {
+		t.Fatalf("Wrong split: %s %+v", base, prefix)
+	}
+
+	base, prefix = splitEndpointPath("https://gw.example.com/tenants/a%2Fb/s3")
+	if base != "https://gw.example.com" || prefix.path != "/tenants/a/b/s3" || prefix.rawPath != "/tenants/a%2Fb/s3" {
+		t.Fatalf("Wrong split of an escaped path: %s %+v", base, prefix)
+	}
+
+	for _, endpoint := range []string{"https://127.0.0.1:18082", "https://127.0.0.1:18082/", "10.96.105.5:8082"} {
+		if base, prefix := splitEndpointPath(endpoint); base != endpoint || prefix != nil {
+			t.Fatalf("%s has no path prefix: %s %+v", endpoint, base, prefix)
+		}
+	}
+}
+
+func TestApplyEndpointPath(t *testing.T) {
+	_, prefix := splitEndpointPath("https://gw.example.com/object-api/")
+	req, _ := http.NewRequest("GET", "https://gw.example.com/test/object-0?versionId=1", nil)
+	prefix.apply(req)
+	if req.URL.Path != "/object-api/test/object-0" || req.URL.RawQuery != "versionId=1" {
+		t.Fatalf("Wrong URL: %s", req.URL)
+	}
+
+	req, _ = http.NewRequest("GET", "https://gw.example.com/test/a%2Fb", nil)
+	prefix.apply(req)
+	if req.URL.EscapedPath() != "/object-api/test/a%2Fb" {
+		t.Fatalf("Wrong escaped path: %s", req.URL.EscapedPath())
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+// Code of the errors that didn't come with an S3 error code, like errors of the client itself.
+const errorCodeOther = "Other"
+
+// s3ErrorBody is the XML body of an S3 error response.
+type s3ErrorBody struct {
+	Code      string `xml:"Code"`
+	Message   string `xml:"Message"`
+	RequestId string `xml:"RequestId"`
+}
+
+// Returns the error of a failed response to a plain HTTP request, like the SDK does for its requests. The code is
+// parsed from the XML error body, responses without one, like those to HEAD requests, get the status text instead.
+func responseError(resp *http.Response) error {
+	var body s3ErrorBody
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &body) != nil || body.Code == "" {
+		body.Code = http.StatusText(resp.StatusCode)
+		body.Message = resp.Status
+	}
+	if body.RequestId == "" {
+		body.RequestId = resp.Header.Get("X-Amz-Request-Id")
+	}
+	return awserr.NewRequestFailure(awserr.New(body.Code, body.Message, nil), resp.StatusCode, body.RequestId)
+}
+
+// Returns the S3 error code of a failed request and its HTTP status, e.g. "SlowDown (503)". A status like 400 is
+// shared by many distinct causes, like InvalidArgument and SignatureDoesNotMatch. Errors that didn't reach the
+// server have the code of the SDK, like RequestError, or Other.
+func errorCode(err error) string {
+	switch e := err.(type) {
+	case awserr.RequestFailure:
+		return fmt.Sprintf("%s (%d)", e.Code(), e.StatusCode())
+	case awserr.Error:
+		return e.Code()
+	}
+	return errorCodeOther
+}
+
+func (this *result) recordErrorCode(code string) {
+	if this.ErrorCodes == nil {
+		this.ErrorCodes = make(map[string]int)
+	}
+	this.ErrorCodes[code]++
+}
+
+// printErrorCodes prints the number of failed requests per error code, the most frequent first.
+func printErrorCodes(codes map[string]int) {
+	sorted := make([]string, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if codes[sorted[i]] != codes[sorted[j]] {
+			return codes[sorted[i]] > codes[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	for _, code := range sorted {
+		fmt.Printf("%10d  %s\n", codes[code], code)
+	}
+}
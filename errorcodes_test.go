@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestResponseError(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.WriteHeader(http.StatusForbidden)
+	w.WriteString(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>SignatureDoesNotMatch</Code><Message>The request signature we calculated does not match</Message><RequestId>4442587FB7D0A2F9</RequestId></Error>`)
+
+	err := responseError(w.Result())
+	if code := errorCode(err); code != "SignatureDoesNotMatch (403)" {
+		t.Fatalf("Expected the code of the error body but got %s", code)
+	}
+
+	if failure := err.(awserr.RequestFailure); failure.RequestID() != "4442587FB7D0A2F9" {
+		t.Fatalf("Expected the request id of the error body but got %s", failure.RequestID())
+	}
+
+	w = httptest.NewRecorder()
+	w.WriteHeader(http.StatusNotFound)
+	if code := errorCode(responseError(w.Result())); code != "Not Found (404)" {
+		t.Fatalf("Expected the status text of a response without body but got %s", code)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	codes := map[error]string{
+		awserr.NewRequestFailure(awserr.New("SlowDown", "", nil), 503, "1"): "SlowDown (503)",
+		awserr.New("RequestError", "send request failed", nil):              "RequestError",
+		errors.New("panic: runtime error"):                                  errorCodeOther,
+	}
+
+	for err, code := range codes {
+		if errorCode(err) != code {
+			t.Fatalf("Expected code %s of %v but got %s", code, err, errorCode(err))
+		}
+	}
+}
+
+func TestMergeErrorCodes(t *testing.T) {
+	r := NewResult()
+	r.recordErrorCode("SlowDown (503)")
+	other := NewResult()
+	other.recordErrorCode("SlowDown (503)")
+	other.recordErrorCode("InternalError (500)")
+	mergeResult(&r, &other)
+
+	if r.ErrorCodes["SlowDown (503)"] != 2 || r.ErrorCodes["InternalError (500)"] != 1 {
+		t.Fatalf("Wrong merged error codes: %v", r.ErrorCodes)
+	}
+}
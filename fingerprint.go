@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Response headers whose values identify a single request or object rather than the server that sent the response.
+// Only their presence is part of a fingerprint, otherwise every response would have a fingerprint of its own.
+var perResponseHeaders = []string{"x-amz-request-id", "x-amz-id-2", "x-amz-version-id", "x-amz-copy-source-version-id",
+	"x-amz-delete-marker", "x-amz-expiration", "x-amz-restore", "x-amz-tagging-count", "x-amz-mp-parts-count", "x-amz-meta-", "x-amz-checksum-"}
+
+func isPerResponseHeader(name string) bool {
+	for _, h := range perResponseHeaders {
+		if name == h || (strings.HasSuffix(h, "-") && strings.HasPrefix(name, h)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the fingerprint of a response made of its Server header and x-amz-* headers, e.g.
+// 'server=AmazonS3; x-amz-id-2; x-amz-request-id; x-amz-server-side-encryption=AES256'.
+func headerFingerprint(header http.Header) string {
+	parts := make([]string, 0, len(header))
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if name != "server" && !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+		if isPerResponseHeader(name) {
+			parts = append(parts, name)
+		} else {
+			parts = append(parts, name+"="+strings.Join(values, ","))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+// recordFingerprints counts the fingerprint of every response the service receives, including retried and failed requests,
+// until the watchdog sheds them.
+func (this *result) recordFingerprints(svc *s3.S3, watchdog *memoryWatchdog) {
+	this.Fingerprints = make(map[string]int)
+	// parts of multipart operations are sent concurrently
+	var mu sync.Mutex
+	svc.Client.Handlers.Send.PushBack(func(r *request.Request) {
+		if r.HTTPResponse == nil || watchdog.sheds("header fingerprints") {
+			return
+		}
+		fingerprint := headerFingerprint(r.HTTPResponse.Header)
+		mu.Lock()
+		this.Fingerprints[fingerprint]++
+		mu.Unlock()
+	})
+}
+
+func printFingerprints(fingerprints map[string]int) {
+	sorted := make([]string, 0, len(fingerprints))
+	for fingerprint := range fingerprints {
+		sorted = append(sorted, fingerprint)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if fingerprints[sorted[i]] != fingerprints[sorted[j]] {
+			return fingerprints[sorted[i]] > fingerprints[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+	for _, fingerprint := range sorted {
+		if fingerprint == "" {
+			fmt.Printf("%10d  (no Server or x-amz-* headers)\n", fingerprints[fingerprint])
+		} else {
+			fmt.Printf("%10d  %s\n", fingerprints[fingerprint], fingerprint)
+		}
+	}
+}
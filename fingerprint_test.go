@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHeaderFingerprint(t *testing.T) {
+	header := http.Header{}
+	header.Set("Server", "AmazonS3")
+	header.Set("X-Amz-Request-Id", "4442587FB7D0A2F9")
+	header.Set("X-Amz-Id-2", "vlR7PnpV2Ce81l0PRw6jlUpck7Jo5ZsQjryTjKlc5aLWGVHPZLj5NeC6qMa0emYBDXOo6QBU0Wo=")
+	header.Set("X-Amz-Meta-Attribute1", "val1")
+	header.Set("X-Amz-Server-Side-Encryption", "AES256")
+	header.Set("Content-Length", "1024")
+
+	expected := "server=AmazonS3; x-amz-id-2; x-amz-meta-attribute1; x-amz-request-id; x-amz-server-side-encryption=AES256"
+	if fingerprint := headerFingerprint(header); fingerprint != expected {
+		t.Fatalf("expected fingerprint %q but got %q", expected, fingerprint)
+	}
+
+	other := http.Header{}
+	other.Set("Server", "AmazonS3")
+	other.Set("X-Amz-Request-Id", "8A0F5B0E6D3B1C2A")
+	other.Set("X-Amz-Id-2", "Q2VQl3xJx3Bn")
+	other.Set("X-Amz-Meta-Attribute1", "val2")
+	other.Set("X-Amz-Server-Side-Encryption", "AES256")
+	if headerFingerprint(header) != headerFingerprint(other) {
+		t.Fatalf("responses of the same server should have the same fingerprint: %q %q", headerFingerprint(header), headerFingerprint(other))
+	}
+
+	other.Set("Server", "nginx")
+	if headerFingerprint(header) == headerFingerprint(other) {
+		t.Fatalf("responses of different servers should have different fingerprints")
+	}
+}
+
+func TestMergeFingerprints(t *testing.T) {
+	r := NewResult()
+	r.Fingerprints = map[string]int{"server=a": 2, "server=b": 1}
+
+	total := NewResult()
+	mergeResult(&total, &r)
+	mergeResult(&total, &r)
+
+	if total.Fingerprints["server=a"] != 4 || total.Fingerprints["server=b"] != 2 {
+		t.Fatalf("wrong merged fingerprints: %v", total.Fingerprints)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"log"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// How often the monitor samples the garbage collections of the tester. The runtime keeps the pauses of the last 256
+// collections only, which is more than happen within the interval even at very high request rates.
+const gcSampleInterval = time.Second
+
+// Applies the garbage collector settings of the run. A gogc of 0 keeps the GOGC environment variable or its default
+// of 100, a negative one turns the collector off until the memory limit is reached.
+func applyGCSettings(gogc, memoryLimitMiB int) {
+	if gogc > 0 {
+		debug.SetGCPercent(gogc)
+	} else if gogc < 0 {
+		debug.SetGCPercent(-1)
+	}
+	if memoryLimitMiB > 0 {
+		debug.SetMemoryLimit(int64(memoryLimitMiB) << 20)
+	}
+	if gogc < 0 && memoryLimitMiB == 0 {
+		log.Printf("WARNING: the garbage collector is off without a gc-memory-limit, the memory of the tester grows until the run ends")
+	}
+}
+
+// gcResult holds the garbage collections of the tester during a run. Every collection pauses the workers, which shows
+// up as latency of the requests in flight, so pauses can distort the highest percentiles at high request rates.
+type gcResult struct {
+	Collections   uint32  `json:"collections"`
+	TotalPause    float64 `json:"totalPause (ms)"`
+	MaximumPause  float64 `json:"maximumPause (ms)"`
+	PauseFraction float64 `json:"pauseFraction (%)"`
+
+	pauseTotal time.Duration
+	pauseMax   time.Duration
+	elapsed    time.Duration
+}
+
+// gcMonitor samples the garbage collections of the tester at an interval while a run is going on.
+type gcMonitor struct {
+	start  time.Time
+	numGC  uint32
+	result *gcResult
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func startGCMonitor() *gcMonitor {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	m := &gcMonitor{
+		start:  time.Now(),
+		numGC:  stats.NumGC,
+		result: &gcResult{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(gcSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.sample()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+	return m
+}
+
+// sample records the pauses of the collections since the last sample.
+func (m *gcMonitor) sample() {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	m.record(&stats)
+}
+
+func (m *gcMonitor) record(stats *runtime.MemStats) {
+	first := m.numGC + 1
+	if stats.NumGC-m.numGC > uint32(len(stats.PauseNs)) {
+		// older pauses were overwritten already, they are missing from the maximum only
+		first = stats.NumGC - uint32(len(stats.PauseNs)) + 1
+	}
+	for n := first; n <= stats.NumGC; n++ {
+		pause := time.Duration(stats.PauseNs[(n+uint32(len(stats.PauseNs))-1)%uint32(len(stats.PauseNs))])
+		m.result.pauseTotal += pause
+		if pause > m.result.pauseMax {
+			m.result.pauseMax = pause
+		}
+	}
+	m.result.Collections += stats.NumGC - m.numGC
+	m.numGC = stats.NumGC
+}
+
+// halt stops the monitor and returns the collections of the run, or nil if there were none.
+func (m *gcMonitor) halt() *gcResult {
+	close(m.stop)
+	<-m.done
+	m.sample()
+	m.result.elapsed = time.Since(m.start)
+	if m.result.Collections == 0 {
+		return nil
+	}
+	return m.result
+}
+
+func (this *gcResult) setupStats() {
+	if this == nil {
+		return
+	}
+	this.TotalPause = roundFloat(float64(this.pauseTotal)/float64(time.Millisecond), 3)
+	this.MaximumPause = roundFloat(float64(this.pauseMax)/float64(time.Millisecond), 3)
+	this.PauseFraction = roundFloat(float64(this.pauseTotal)/float64(this.elapsed)*100, 3)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestGCMonitorRecord(t *testing.T) {
+	m := &gcMonitor{numGC: 2, result: &gcResult{}}
+	var stats runtime.MemStats
+	stats.NumGC = 4
+	stats.PauseNs[1] = uint64(time.Millisecond)
+	stats.PauseNs[2] = uint64(3 * time.Millisecond)
+	stats.PauseNs[3] = uint64(2 * time.Millisecond)
+	m.record(&stats)
+
+	if m.result.Collections != 2 || m.result.pauseTotal != 5*time.Millisecond || m.result.pauseMax != 3*time.Millisecond {
+		t.Fatalf("Expected the pauses of collections 3 and 4 but got %+v", m.result)
+	}
+
+	// more collections than pauses kept by the runtime
+	stats.NumGC = 4 + 300
+	m.record(&stats)
+	if m.result.Collections != 302 || m.numGC != 304 {
+		t.Fatalf("Expected every collection to be counted but got %d", m.result.Collections)
+	}
+
+	m.result.elapsed = time.Second
+	m.result.setupStats()
+	if m.result.MaximumPause != 3 || m.result.PauseFraction != m.result.TotalPause/10 {
+		t.Fatalf("Wrong GC stats: %+v", m.result)
+	}
+}
+
+func TestGCMonitor(t *testing.T) {
+	m := startGCMonitor()
+	runtime.GC()
+	if r := m.halt(); r == nil || r.Collections == 0 {
+		t.Fatalf("Expected the forced collection to be recorded but got %+v", r)
+	}
+}
+
+func TestApplyGCSettings(t *testing.T) {
+	defer debug.SetGCPercent(debug.SetGCPercent(100))
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+
+	applyGCSettings(400, 1024)
+	if percent := debug.SetGCPercent(100); percent != 400 {
+		t.Fatalf("Expected GOGC 400 but got %d", percent)
+	}
+
+	if limit := debug.SetMemoryLimit(-1); limit != 1024<<20 {
+		t.Fatalf("Expected a memory limit of 1GiB but got %d", limit)
+	}
+}
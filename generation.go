@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// With write generations every object written starts with a header holding the time its write started, which
+// readers compare with the newest generation they have seen of the key to detect stale reads.
+const generationHeaderSize = 16
+
+var generationMagic = []byte("s3tgen01")
+
+func stampGeneration(block []byte, generation int64) {
+	copy(block, generationMagic)
+	binary.BigEndian.PutUint64(block[len(generationMagic):generationHeaderSize], uint64(generation))
+}
+
+// generationResult counts the reads checked for stale data by a worker. It also serves as the worker's
+// record of the newest generation it has read of every key.
+type generationResult struct {
+	Reads      int `json:"checkedReads"`
+	StaleReads int `json:"staleReads"`
+	// the furthest a stale read went back in time from the newest generation seen of the key
+	MaxTimeTravel float64 `json:"maxTimeTravel (ms)"`
+
+	// ranges of a parallelget are read concurrently
+	mu     sync.Mutex
+	latest map[string]int64
+}
+
+func NewGenerationResult() *generationResult {
+	return &generationResult{latest: make(map[string]int64)}
+}
+
+// check reads the generation header at the start of the body, records whether it is older than
+// the newest generation read of the key before and returns it. Objects without a header fail the check.
+func (this *generationResult) check(key string, body io.Reader) (int64, error) {
+	header := make([]byte, generationHeaderSize)
+	if _, err := io.ReadFull(body, header); err != nil {
+		return 0, fmt.Errorf("Error reading write generation of %s: %v", key, err)
+	}
+	if !bytes.Equal(header[:len(generationMagic)], generationMagic) {
+		return 0, fmt.Errorf("Object %s was not written with write generations", key)
+	}
+	generation := int64(binary.BigEndian.Uint64(header[len(generationMagic):]))
+	this.observe(key, generation)
+	return generation, nil
+}
+
+func (this *generationResult) observe(key string, generation int64) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.Reads++
+	latest, seen := this.latest[key]
+	if !seen || generation >= latest {
+		this.latest[key] = generation
+		return
+	}
+	this.StaleReads++
+	travel := time.Duration(latest - generation)
+	if ms := float64(travel) / float64(time.Millisecond); ms > this.MaxTimeTravel {
+		this.MaxTimeTravel = ms
+	}
+	log.Printf("Stale read of '%s': read a generation written %s before the newest one read before", key, travel)
+}
+
+// merge adds the other result to this one and returns the merged result, which is newly allocated if this one is nil.
+func (this *generationResult) merge(other *generationResult) *generationResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = NewGenerationResult()
+	}
+	this.Reads += other.Reads
+	this.StaleReads += other.StaleReads
+	if other.MaxTimeTravel > this.MaxTimeTravel {
+		this.MaxTimeTravel = other.MaxTimeTravel
+	}
+	return this
+}
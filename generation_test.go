@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGenerationPayload(t *testing.T) {
+	key := "object-0"
+	var size int64 = 3*objectDataBlockSize + 100
+	generations := NewGenerationResult()
+	payload := payloadOptions{compressRatio: 2, generations: generations}
+
+	reader := NewPayloadReader(size, key, payload)
+	data, _ := ioutil.ReadAll(reader)
+	if !bytes.Equal(data[:len(generationMagic)], generationMagic) {
+		t.Fatalf("Expected the object to start with a write generation header")
+	}
+
+	// the data after the header is verified like any other object
+	payload.generations = nil
+	if err := verifyObjectData(bytes.NewReader(data[generationHeaderSize:]), key, generationHeaderSize, 1, 0, payload); err != nil {
+		t.Fatalf("Expected data after the header to verify but got: %v", err)
+	}
+
+	// retried requests seek back to the start and send the same generation again
+	reader.Seek(0, io.SeekStart)
+	retried, _ := ioutil.ReadAll(reader)
+	if !bytes.Equal(data, retried) {
+		t.Fatalf("Expected a retried request to send the same data")
+	}
+
+	if _, err := generations.check(key, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Expected generation check to succeed but got: %v", err)
+	}
+
+	if generations.Reads != 1 || generations.StaleReads != 0 {
+		t.Fatalf("Expected 1 checked read and no stale reads but got %+v", generations)
+	}
+
+	unstamped, _ := ioutil.ReadAll(NewPayloadReader(size, key, payload))
+	if _, err := generations.check(key, bytes.NewReader(unstamped)); err == nil {
+		t.Fatalf("Expected object without a write generation to fail the check")
+	}
+}
+
+func TestStaleReads(t *testing.T) {
+	r := NewGenerationResult()
+	r.observe("a", 1000)
+	r.observe("a", 3000000)
+	r.observe("a", 3000000)
+	r.observe("b", 1000)
+
+	if r.StaleReads != 0 {
+		t.Fatalf("Expected no stale reads but got %d", r.StaleReads)
+	}
+
+	r.observe("a", 2000000)
+	r.observe("a", 1000)
+	if r.Reads != 6 || r.StaleReads != 2 {
+		t.Fatalf("Expected 6 reads and 2 stale reads but got %+v", r)
+	}
+
+	// 3ms - 1us
+	if r.MaxTimeTravel != 2.999 {
+		t.Fatalf("Expected maximum time travel of 2.999ms but got %v", r.MaxTimeTravel)
+	}
+}
+
+func TestMergeGenerationResult(t *testing.T) {
+	var total *generationResult
+	total = total.merge(nil)
+	if total != nil {
+		t.Fatalf("Expected merging nothing to stay nil")
+	}
+
+	total = total.merge(&generationResult{Reads: 4, StaleReads: 1, MaxTimeTravel: 5})
+	total = total.merge(&generationResult{Reads: 2, StaleReads: 1, MaxTimeTravel: 3})
+	if total.Reads != 6 || total.StaleReads != 2 || total.MaxTimeTravel != 5 {
+		t.Fatalf("Wrong merged result %+v", total)
+	}
+}
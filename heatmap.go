@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// prefixStat holds the latency of the requests to the keys of a key prefix. Backends partition their index by key
+// prefix, so a hot partition shows up as prefixes that are slower than the rest.
+type prefixStat struct {
+	Prefix             string  `json:"prefix"`
+	Count              int     `json:"totalRequests"`
+	Failcount          int     `json:"failedRequests"`
+	AverageRequestTime float64 `json:"averageRequestTime (ms)"`
+	MaximumRequestTime float64 `json:"maximumRequestTime (ms)"`
+
+	elapsedSum time.Duration
+	elapsedMax time.Duration
+}
+
+// Returns the first length characters of the key, or the whole key if it is shorter.
+func keyPrefix(key string, length int) string {
+	if len(key) <= length {
+		return key
+	}
+	return key[:length]
+}
+
+func (this *result) recordPrefixLatency(prefix string, l time.Duration, failed bool) {
+	if this.prefixStats == nil {
+		this.prefixStats = make(map[string]*prefixStat)
+	}
+	s, ok := this.prefixStats[prefix]
+	if !ok {
+		s = &prefixStat{Prefix: prefix}
+		this.prefixStats[prefix] = s
+	}
+	s.Count++
+	if failed {
+		s.Failcount++
+	}
+	s.elapsedSum += l
+	if l > s.elapsedMax {
+		s.elapsedMax = l
+	}
+}
+
+func (this *result) mergePrefixStats(other *result) {
+	for prefix, o := range other.prefixStats {
+		if this.prefixStats == nil {
+			this.prefixStats = make(map[string]*prefixStat)
+		}
+		s, ok := this.prefixStats[prefix]
+		if !ok {
+			s = &prefixStat{Prefix: prefix}
+			this.prefixStats[prefix] = s
+		}
+		s.Count += o.Count
+		s.Failcount += o.Failcount
+		s.elapsedSum += o.elapsedSum
+		if o.elapsedMax > s.elapsedMax {
+			s.elapsedMax = o.elapsedMax
+		}
+	}
+}
+
+// setupPrefixStats reports the top key prefixes with the highest average latency.
+func (this *result) setupPrefixStats(top int) {
+	if len(this.prefixStats) == 0 {
+		return
+	}
+	this.PrefixCount = len(this.prefixStats)
+	worst := make([]prefixStat, 0, len(this.prefixStats))
+	for _, s := range this.prefixStats {
+		s.AverageRequestTime = roundFloat(float64(s.elapsedSum)/float64(s.Count)/float64(time.Millisecond), 3)
+		s.MaximumRequestTime = roundFloat(float64(s.elapsedMax)/float64(time.Millisecond), 3)
+		worst = append(worst, *s)
+	}
+	sort.Slice(worst, func(i, j int) bool {
+		if worst[i].AverageRequestTime != worst[j].AverageRequestTime {
+			return worst[i].AverageRequestTime > worst[j].AverageRequestTime
+		}
+		return worst[i].Prefix < worst[j].Prefix
+	})
+	if len(worst) > top {
+		worst = worst[:top]
+	}
+	this.WorstPrefixes = worst
+}
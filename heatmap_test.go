@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	if keyPrefix("logs/2024/a", 5) != "logs/" || keyPrefix("abc", 5) != "abc" {
+		t.Fatalf("Wrong key prefixes")
+	}
+}
+
+func TestWorstPrefixes(t *testing.T) {
+	r1, r2, merged := NewResult(), NewResult(), NewResult()
+	r1.recordPrefixLatency("a", 10*time.Millisecond, false)
+	r1.recordPrefixLatency("b", 30*time.Millisecond, true)
+	r2.recordPrefixLatency("b", 10*time.Millisecond, false)
+	r2.recordPrefixLatency("c", 5*time.Millisecond, false)
+	r2.recordPrefixLatency("d", 10*time.Millisecond, false)
+	mergeResult(&merged, &r1)
+	mergeResult(&merged, &r2)
+	merged.setupPrefixStats(3)
+
+	if merged.PrefixCount != 4 || len(merged.WorstPrefixes) != 3 {
+		t.Fatalf("Expected the worst 3 of 4 prefixes but got %d of %d", len(merged.WorstPrefixes), merged.PrefixCount)
+	}
+
+	b := merged.WorstPrefixes[0]
+	if b.Prefix != "b" || b.Count != 2 || b.Failcount != 1 || b.AverageRequestTime != 20 || b.MaximumRequestTime != 30 {
+		t.Fatalf("Wrong slowest prefix: %+v", b)
+	}
+
+	// prefixes of the same latency are ordered by name
+	if merged.WorstPrefixes[1].Prefix != "a" || merged.WorstPrefixes[2].Prefix != "d" {
+		t.Fatalf("Wrong order of prefixes: %+v", merged.WorstPrefixes)
+	}
+}
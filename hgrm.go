@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// The percentiles of the latency summary per operation, which is followed by the maximum.
+var summaryPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+// Returns whether the latency histogram written to the file is in the percentile distribution format of HdrHistogram.
+func isHgrmFile(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".hgrm")
+}
+
+// Returns the path of the latency histogram of the requests of an operation, next to the one of all requests.
+func operationHgrmPath(path, op string) string {
+	return path[:len(path)-len(".hgrm")] + "." + op + ".hgrm"
+}
+
+// writePercentileDistribution writes the latencies in the percentile distribution format of HdrHistogram's
+// outputPercentileDistribution, in milliseconds, which the HdrHistogram plotter and other tools read.
+func writePercentileDistribution(w io.Writer, h *hdrhistogram.Histogram) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "%12s %14s %10s %14s\n\n", "Value", "Percentile", "TotalCount", "1/(1-Percentile)")
+	for _, b := range h.CumulativeDistribution() {
+		// the histograms count in units of 10us
+		value := float64(b.ValueAt) / 1e2
+		if b.Quantile >= 100 {
+			fmt.Fprintf(out, "%12.3f %2.12f %10d\n", value, 1.0, b.Count)
+			continue
+		}
+		fmt.Fprintf(out, "%12.3f %2.12f %10d %14.2f\n", value, b.Quantile/100, b.Count, 1/(1-b.Quantile/100))
+	}
+	fmt.Fprintf(out, "#[Mean    = %12.3f, StdDeviation   = %12.3f]\n", h.Mean()/1e2, h.StdDev()/1e2)
+	fmt.Fprintf(out, "#[Max     = %12.3f, Total count    = %12d]\n", float64(h.Max())/1e2, h.TotalCount())
+	return out.Flush()
+}
+
+// writeLatencyHistograms writes the histogram of the latency of all requests to the file, and the ones of every
+// operation of runs that break their results down per operation next to it.
+func writeLatencyHistograms(path string, r result) error {
+	histograms := map[string]*hdrhistogram.Histogram{path: r.latencies}
+	for op, o := range r.OperationResults {
+		histograms[operationHgrmPath(path, op)] = o.latencies
+	}
+	for file, h := range histograms {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		err = writePercentileDistribution(f, h)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printLatencySummary prints the percentiles and the maximum of the latency of the requests of every operation of the
+// run, or of all requests of a run of a single operation.
+func printLatencySummary(results result) {
+	ops := make([]string, 0, len(results.OperationResults))
+	for op := range results.OperationResults {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	if len(ops) == 0 && results.Count == 0 {
+		return
+	}
+
+	fmt.Println("Latency per operation (ms)")
+	fmt.Printf("%-16s %10s", "Operation", "Requests")
+	for _, p := range summaryPercentiles {
+		fmt.Printf(" %10s", "p"+convertFloatToString(p))
+	}
+	fmt.Printf(" %10s\n", "max")
+	row := func(op string, h *hdrhistogram.Histogram) {
+		fmt.Printf("%-16s %10d", op, h.TotalCount())
+		for _, p := range summaryPercentiles {
+			fmt.Printf(" %10.3f", float64(h.ValueAtQuantile(p))/1e2)
+		}
+		fmt.Printf(" %10.3f\n", float64(h.Max())/1e2)
+	}
+	if len(ops) == 0 {
+		row(results.Operation, results.latencies)
+	}
+	for _, op := range ops {
+		row(op, results.OperationResults[op].latencies)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePercentileDistribution(t *testing.T) {
+	r := NewResult()
+	for i := 1; i <= 1000; i++ {
+		r.RecordLatency(time.Duration(i) * 10 * time.Microsecond)
+	}
+	var out bytes.Buffer
+	if err := writePercentileDistribution(&out, r.latencies); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.Contains(lines[0], "Value") || !strings.Contains(lines[0], "1/(1-Percentile)") || lines[1] != "" {
+		t.Fatalf("Expected the header of the percentile distribution but got %q", lines[:2])
+	}
+	if lines[len(lines)-1] != "#[Max     =       10.000, Total count    =         1000]" {
+		t.Fatalf("Unexpected footer %q", lines[len(lines)-1])
+	}
+
+	// the percentiles go up to 1 and the values and counts never go down
+	previous := []float64{0, 0, 0}
+	for _, line := range lines[2 : len(lines)-2] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			t.Fatalf("Unexpected line %q", line)
+		}
+		for i := 0; i < 3; i++ {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil || v < previous[i] {
+				t.Fatalf("Expected increasing values in %q", line)
+			}
+			previous[i] = v
+		}
+	}
+	if previous[0] != 10 || previous[1] != 1 || previous[2] != 1000 {
+		t.Fatalf("Expected the distribution to end at the maximum of 10ms but got %v", previous)
+	}
+}
+
+func TestWriteLatencyHistograms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hgrm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := NewResult()
+	r.RecordLatency(time.Millisecond)
+	r.recordOperation("get", time.Millisecond, 1024, false)
+	r.recordOperation("put", 2*time.Millisecond, 1024, false)
+	path := filepath.Join(dir, "run.hgrm")
+	if !isHgrmFile(path) || isHgrmFile(filepath.Join(dir, "run.csv")) {
+		t.Fatalf("Expected only .hgrm files in the HdrHistogram format")
+	}
+	if err := writeLatencyHistograms(path, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"run.hgrm", "run.get.hgrm", "run.put.hgrm"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Fatalf("Expected the histogram %s: %v", file, err)
+		}
+	}
+}
+
+func TestLatencyResultMaximum(t *testing.T) {
+	l := NewLatencyResult()
+	l.record(time.Millisecond)
+	l.record(25 * time.Millisecond)
+	l.setupStats()
+	if l.MaximumRequestTime != 25 {
+		t.Fatalf("Expected a maximum of 25ms but got %v", l.MaximumRequestTime)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Returns whether the worker with the given index among the workers of its endpoint sends plain HTTP requests.
+// The first httpPercent percent of the workers of every endpoint do, so that each endpoint sees the same split
+// and every worker keeps a single persistent connection.
+func usesPlainHTTP(httpPercent, index, workersPerEndpoint int) bool {
+	return index < int(math.Round(float64(workersPerEndpoint*httpPercent)/100))
+}
+
+// Returns the plain HTTP URL of the endpoint, on the given port if one is specified.
+func plainHTTPEndpoint(endpoint, port string) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	u.Scheme = "http"
+	if port != "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u.String(), nil
+}
+
+func (this *result) recordSchemeLatency(scheme string, l time.Duration) {
+	if this.SchemeResults == nil {
+		this.SchemeResults = make(map[string]*latencyResult)
+	}
+	s, ok := this.SchemeResults[scheme]
+	if !ok {
+		s = NewLatencyResult()
+		this.SchemeResults[scheme] = s
+	}
+	s.record(l)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsesPlainHTTP(t *testing.T) {
+	plain := 0
+	for i := 0; i < 8; i++ {
+		if usesPlainHTTP(25, i, 8) {
+			plain++
+		}
+	}
+	if plain != 2 {
+		t.Fatalf("25%% of 8 workers should use HTTP but got %d", plain)
+	}
+
+	if usesPlainHTTP(0, 0, 8) || !usesPlainHTTP(100, 7, 8) {
+		t.Fatalf("0%% should never and 100%% should always use HTTP")
+	}
+}
+
+func TestPlainHTTPEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint, port, expected string
+	}{
+		{"https://10.96.105.5:8082", "", "http://10.96.105.5:8082"},
+		{"https://10.96.105.5:8082", "8084", "http://10.96.105.5:8084"},
+		{"10.96.105.5:8082", "80", "http://10.96.105.5:80"},
+		{"https://s3.example.com", "8080", "http://s3.example.com:8080"},
+		{"https://[::1]:8082", "8084", "http://[::1]:8084"},
+	}
+	for _, test := range tests {
+		endpoint, err := plainHTTPEndpoint(test.endpoint, test.port)
+		if err != nil || endpoint != test.expected {
+			t.Fatalf("expected %s for %s on port '%s' but got %s, %v", test.expected, test.endpoint, test.port, endpoint, err)
+		}
+	}
+}
+
+func TestSchemeResults(t *testing.T) {
+	r := NewResult()
+	r.recordSchemeLatency("http", time.Millisecond)
+	r.recordSchemeLatency("https", 3*time.Millisecond)
+
+	total := NewResult()
+	mergeResult(&total, &r)
+	mergeResult(&total, &r)
+	for _, s := range total.SchemeResults {
+		s.setupStats()
+	}
+
+	if total.SchemeResults["http"].Count != 2 || total.SchemeResults["https"].AverageRequestTime != 3 {
+		t.Fatalf("wrong scheme results: %+v %+v", total.SchemeResults["http"], total.SchemeResults["https"])
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// User metadata keys of the writer identity, sent as x-amz-meta-s3tester-run etc.
+const (
+	identityRunKey    = "s3tester-run"
+	identityWorkerKey = "s3tester-worker"
+	identitySeqKey    = "s3tester-seq"
+)
+
+// Returns the default run id, made of the host name, the process id and the start time so that runs of different hosts
+// and runs started at the same time on one host can be told apart.
+func defaultRunId(now time.Time) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), now.UTC().Format("20060102T150405Z"))
+}
+
+// Returns the prefix of the keys of a run isolated with isolate-run, which places its keys under the run id.
+func runPrefix(runId, prefix string) string {
+	return runId + "/" + prefix
+}
+
+// Adds the writer identity to the metadata of the requests that write objects. A copy that keeps the metadata
+// of its source is left alone.
+func stampIdentity(params interface{}, run string, worker, seq int) {
+	var metadata *map[string]*string
+	switch p := params.(type) {
+	case *s3.PutObjectInput:
+		metadata = &p.Metadata
+	case *s3.CreateMultipartUploadInput:
+		metadata = &p.Metadata
+	case *s3.CopyObjectInput:
+		if aws.StringValue(p.MetadataDirective) != s3.MetadataDirectiveReplace {
+			return
+		}
+		metadata = &p.Metadata
+	default:
+		return
+	}
+	// the metadata may be shared with other requests
+	stamped := make(map[string]*string, len(*metadata)+3)
+	for k, v := range *metadata {
+		stamped[k] = v
+	}
+	stamped[identityRunKey] = aws.String(run)
+	stamped[identityWorkerKey] = aws.String(strconv.Itoa(worker))
+	stamped[identitySeqKey] = aws.String(strconv.Itoa(seq))
+	*metadata = stamped
+}
+
+// stampIdentity stamps every object written by the worker with the run, the worker and the number of the worker's
+// request that wrote it, so that any object found on the server can be traced back to the request.
+func (this *result) stampIdentity(svc *s3.S3, run string, worker int) {
+	svc.Client.Handlers.Validate.PushFront(func(r *request.Request) {
+		switch r.Params.(type) {
+		case *s3.PutObjectInput, *s3.CreateMultipartUploadInput, *s3.CopyObjectInput:
+			// objects are written by the worker's goroutine, other requests may be sent concurrently
+			stampIdentity(r.Params, run, worker, this.Count)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestStampIdentity(t *testing.T) {
+	shared := map[string]*string{"attribute1": aws.String("val1")}
+	put := &s3.PutObjectInput{Metadata: shared}
+	stampIdentity(put, "run-1", 7, 42)
+
+	expected := map[string]string{"attribute1": "val1", identityRunKey: "run-1", identityWorkerKey: "7", identitySeqKey: "42"}
+	if len(put.Metadata) != len(expected) {
+		t.Fatalf("Expected metadata %v but got %v", expected, aws.StringValueMap(put.Metadata))
+	}
+	for k, v := range expected {
+		if aws.StringValue(put.Metadata[k]) != v {
+			t.Fatalf("Expected %s=%s but got %s", k, v, aws.StringValue(put.Metadata[k]))
+		}
+	}
+
+	if len(shared) != 1 {
+		t.Fatalf("Expected the shared metadata to be left alone but got %v", aws.StringValueMap(shared))
+	}
+
+	create := &s3.CreateMultipartUploadInput{}
+	stampIdentity(create, "run-1", 0, 1)
+	if aws.StringValue(create.Metadata[identitySeqKey]) != "1" {
+		t.Fatalf("Expected multipart upload to be stamped but got %v", aws.StringValueMap(create.Metadata))
+	}
+
+	copyKeep := &s3.CopyObjectInput{MetadataDirective: aws.String(s3.MetadataDirectiveCopy)}
+	stampIdentity(copyKeep, "run-1", 0, 1)
+	if copyKeep.Metadata != nil {
+		t.Fatalf("Expected copy that keeps the source metadata not to be stamped")
+	}
+
+	copyReplace := &s3.CopyObjectInput{MetadataDirective: aws.String(s3.MetadataDirectiveReplace)}
+	stampIdentity(copyReplace, "run-1", 0, 1)
+	if aws.StringValue(copyReplace.Metadata[identityRunKey]) != "run-1" {
+		t.Fatalf("Expected copy that replaces the metadata to be stamped")
+	}
+}
+
+func TestDefaultRunId(t *testing.T) {
+	id := defaultRunId(time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC))
+	if !strings.HasSuffix(id, "-20250601T123000Z") {
+		t.Fatalf("Expected run id to end with the start time but got %s", id)
+	}
+
+	if !strings.Contains(id, "-"+strconv.Itoa(os.Getpid())+"-") {
+		t.Fatalf("Expected run id to hold the process id but got %s", id)
+	}
+}
+
+func TestRunPrefix(t *testing.T) {
+	if prefix := runPrefix("run-1", "testobject"); prefix != "run-1/testobject" {
+		t.Fatalf("Wrong run prefix %s", prefix)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// inventorySampler keeps a share of the keys listed, and at most maxKeys of them picked uniformly from all keys kept.
+type inventorySampler struct {
+	// share of the keys kept, 0-1
+	sample float64
+	// 0 keeps every key of the sample
+	maxKeys int
+	source  *rand.Rand
+	// keys seen by the reservoir, the keys of the sample
+	seen int
+	keys *keyList
+}
+
+func newInventorySampler(sample float64, maxKeys int, seed int64) *inventorySampler {
+	return &inventorySampler{sample: sample, maxKeys: maxKeys, source: rand.New(rand.NewSource(seed)), keys: &keyList{sizes: make(map[string]int64)}}
+}
+
+func (s *inventorySampler) add(key string, size int64) {
+	if s.sample < 1 && s.source.Float64() >= s.sample {
+		return
+	}
+	s.seen++
+	if s.maxKeys == 0 || len(s.keys.keys) < s.maxKeys {
+		s.keys.keys = append(s.keys.keys, key)
+		s.keys.sizes[key] = size
+		return
+	}
+	// reservoir sampling, every key of the sample is kept with the same probability
+	if i := s.source.Intn(s.seen); i < s.maxKeys {
+		delete(s.keys.sizes, s.keys.keys[i])
+		s.keys.keys[i] = key
+		s.keys.sizes[key] = size
+	}
+}
+
+// ListInventory lists the objects under the prefix with the v1 or v2 listing API and returns the keys and sizes of a
+// sample of them, and the number of objects listed.
+func ListInventory(svc s3iface.S3API, bucket, prefix, api string, sample float64, maxKeys int, seed int64) (*keyList, int, error) {
+	sampler := newInventorySampler(sample, maxKeys, seed)
+	listed := 0
+	marker := ""
+	for {
+		objects, next, err := listInventoryPage(svc, bucket, prefix, marker, api)
+		if err != nil {
+			return nil, listed, err
+		}
+		for _, object := range objects {
+			sampler.add(aws.StringValue(object.Key), aws.Int64Value(object.Size))
+		}
+		listed += len(objects)
+		if next == "" {
+			break
+		}
+		marker = next
+	}
+	if len(sampler.keys.keys) == 0 {
+		return nil, listed, errors.New("no objects in the inventory")
+	}
+	return sampler.keys, listed, nil
+}
+
+// Lists a page of objects and returns the marker or continuation token of the next page, empty after the last page.
+func listInventoryPage(svc s3iface.S3API, bucket, prefix, marker, api string) ([]*s3.Object, string, error) {
+	if api == "v1" {
+		params := &s3.ListObjectsInput{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+		if marker != "" {
+			params.Marker = aws.String(marker)
+		}
+		out, err := svc.ListObjects(params)
+		if err != nil {
+			return nil, "", err
+		}
+		if !aws.BoolValue(out.IsTruncated) || len(out.Contents) == 0 {
+			return out.Contents, "", nil
+		}
+		// without a delimiter the next page starts after the last key
+		return out.Contents, aws.StringValue(out.Contents[len(out.Contents)-1].Key), nil
+	}
+
+	params := &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)}
+	if marker != "" {
+		params.ContinuationToken = aws.String(marker)
+	}
+	out, err := svc.ListObjectsV2(params)
+	if err != nil {
+		return nil, "", err
+	}
+	if !aws.BoolValue(out.IsTruncated) {
+		return out.Contents, "", nil
+	}
+	return out.Contents, aws.StringValue(out.NextContinuationToken), nil
+}
+
+// prepareInventory lists the objects under the prefix for the run to go through instead of the keys of a put run.
+// Without requests every key of the inventory is requested once.
+func prepareInventory(args *parameters) error {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		return err
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+
+	keys, listed, err := ListInventory(svc, args.bucketname, args.objectprefix, args.listApi, args.inventorySample, args.inventoryMaxKeys, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	log.Printf("Listed %d objects under '%s/%s', %d of them are in the inventory", listed, args.bucketname, args.objectprefix, len(keys.keys))
+	args.keyList = keys
+	if !args.nrequests.set {
+		args.nrequests.value = len(keys.keys)
+	}
+	if args.nrequests.value < args.concurrency {
+		return errors.New("the inventory has fewer keys than the concurrency, set requests to at least the concurrency")
+	}
+	return nil
+}
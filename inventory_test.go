@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Returns a listing of n objects of size 10 per page, the i-th page of objects p-<page>-<i>.
+func inventoryListing(t *testing.T, pages, n int) func(in interface{}) interface{} {
+	return func(in interface{}) interface{} {
+		page := 0
+		switch i := in.(type) {
+		case *s3.ListObjectsV2Input:
+			if i.ContinuationToken != nil {
+				page, _ = strconv.Atoi(*i.ContinuationToken)
+			}
+		case *s3.ListObjectsInput:
+			if i.Marker != nil {
+				page, _ = strconv.Atoi((*i.Marker)[2:3])
+				page++
+			}
+		default:
+			t.Fatalf("Expected a listing but got: %T", in)
+		}
+		var contents []*s3.Object
+		for k := 0; k < n; k++ {
+			contents = append(contents, &s3.Object{Key: aws.String("p-" + strconv.Itoa(page) + "-" + strconv.Itoa(k)), Size: aws.Int64(10)})
+		}
+		truncated := page < pages-1
+		if _, ok := in.(*s3.ListObjectsInput); ok {
+			return &s3.ListObjectsOutput{IsTruncated: aws.Bool(truncated), Contents: contents}
+		}
+		return &s3.ListObjectsV2Output{IsTruncated: aws.Bool(truncated), Contents: contents, NextContinuationToken: aws.String(strconv.Itoa(page + 1))}
+	}
+}
+
+func TestListInventory(t *testing.T) {
+	for _, api := range []string{"v1", "v2"} {
+		svc := NewMockS3Client(inventoryListing(t, 3, 4))
+		keys, listed, err := ListInventory(svc, "b", "p", api, 1, 0, 1)
+		if err != nil || listed != 12 || len(keys.keys) != 12 {
+			t.Fatalf("Expected all 12 objects of the %s listing but got %d of %d: %v", api, len(keys.keys), listed, err)
+		}
+
+		if keys.keys[11] != "p-2-3" {
+			t.Fatalf("Expected the last key p-2-3 but got %s", keys.keys[11])
+		}
+
+		if size, ok := keys.size("p-1-0"); !ok || size != 10 {
+			t.Fatalf("Expected the size of p-1-0 to be 10 but got %d", size)
+		}
+	}
+}
+
+func TestListInventorySample(t *testing.T) {
+	svc := NewMockS3Client(inventoryListing(t, 10, 100))
+	keys, listed, err := ListInventory(svc, "b", "p", "v2", 1, 50, 1)
+	if err != nil || listed != 1000 || len(keys.keys) != 50 || len(keys.sizes) != 50 {
+		t.Fatalf("Expected 50 of 1000 objects but got %d of %d: %v", len(keys.keys), listed, err)
+	}
+
+	// the reservoir keeps keys of every page
+	late := 0
+	for _, key := range keys.keys {
+		if key[:4] >= "p-5-" {
+			late++
+		}
+	}
+	if late < 10 {
+		t.Fatalf("Expected the keys to be picked from all pages but got %v", keys.keys)
+	}
+
+	svc = NewMockS3Client(inventoryListing(t, 10, 100))
+	if keys, _, _ = ListInventory(svc, "b", "p", "v2", 0.1, 0, 1); len(keys.keys) < 50 || len(keys.keys) > 150 {
+		t.Fatalf("Expected about 100 keys of a 10%% sample but got %d", len(keys.keys))
+	}
+
+	svc = NewMockS3Client(inventoryListing(t, 1, 0))
+	if _, _, err = ListInventory(svc, "b", "p", "v2", 1, 0, 1); err == nil {
+		t.Fatalf("an empty inventory should fail")
+	}
+}
+
+func TestListInventoryOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=head", "-list-inventory", "-inventory-sample=0.5", "-inventory-max-keys=1000"})
+	if err != nil || !args.listInventory || args.inventorySample != 0.5 || args.inventoryMaxKeys != 1000 {
+		t.Fatalf("list-inventory should succeed: %v", err)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-list-inventory"}); err == nil {
+		t.Fatalf("list-inventory with put should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-list-inventory", "-inventory-sample=0"}); err == nil {
+		t.Fatalf("inventory-sample of 0 should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-inventory-max-keys=10"}); err == nil {
+		t.Fatalf("inventory-max-keys without list-inventory should fail")
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// keyDistribution is how the keys of reads and deletes are picked from the keys of a previous put run instead of
+// going through them in sequence.
+type keyDistribution struct {
+	// uniform, zipfian or hotspot
+	kind string
+	// exponent of zipfian, > 1, the larger the more the requests go to the first keys
+	s float64
+	// hotspot: the share of the requests that go to the share of the keys, both in percent
+	hotRequests float64
+	hotKeys     float64
+}
+
+// Parses a key distribution like uniform, zipfian, zipfian:1.2 or hotspot:90/10, where 90% of the requests go to 10%
+// of the keys. Returns nil for sequential, the default.
+func parseKeyDistribution(distribution string) (*keyDistribution, error) {
+	kind, param := distribution, ""
+	if i := strings.Index(distribution, ":"); i >= 0 {
+		kind, param = distribution[:i], distribution[i+1:]
+	}
+	switch kind {
+	case "", "sequential":
+		if param != "" {
+			break
+		}
+		return nil, nil
+	case "uniform":
+		if param != "" {
+			break
+		}
+		return &keyDistribution{kind: kind}, nil
+	case "zipfian":
+		d := &keyDistribution{kind: kind, s: 1.1}
+		if param == "" {
+			return d, nil
+		}
+		s, err := strconv.ParseFloat(param, 64)
+		if err != nil || s <= 1 {
+			return nil, errors.New("the exponent of zipfian must be > 1, like zipfian:1.1")
+		}
+		d.s = s
+		return d, nil
+	case "hotspot":
+		shares := strings.Split(strings.Replace(param, "%", "", -1), "/")
+		if len(shares) != 2 {
+			break
+		}
+		requests, err := strconv.ParseFloat(shares[0], 64)
+		keys, kerr := strconv.ParseFloat(shares[1], 64)
+		if err != nil || kerr != nil || requests <= 0 || requests > 100 || keys <= 0 || keys >= 100 {
+			return nil, errors.New("hotspot must send 0-100% of the requests to 0-100% of the keys, like hotspot:90/10")
+		}
+		return &keyDistribution{kind: kind, hotRequests: requests, hotKeys: keys}, nil
+	}
+	return nil, errors.New("key-distribution must be one of sequential, uniform, zipfian[:s] or hotspot:x/y")
+}
+
+// keyPicker picks the keys of a worker from n keys by their distribution.
+type keyPicker struct {
+	dist   *keyDistribution
+	n      int64
+	source *rand.Rand
+	zipf   *rand.Zipf
+}
+
+// Returns the key picker of a worker, or nil without a distribution. The keys picked only depend on the seed.
+func (d *keyDistribution) picker(n, seed int64) *keyPicker {
+	if d == nil {
+		return nil
+	}
+	p := &keyPicker{dist: d, n: n, source: rand.New(rand.NewSource(seed))}
+	if d.kind == "zipfian" {
+		p.zipf = rand.NewZipf(p.source, d.s, 1, uint64(n-1))
+	}
+	return p
+}
+
+// next returns the number of the next key, the most popular keys of zipfian and hotspot have the lowest numbers.
+func (p *keyPicker) next() int64 {
+	switch p.dist.kind {
+	case "zipfian":
+		return int64(p.zipf.Uint64())
+	case "hotspot":
+		hot := int64(float64(p.n) * p.dist.hotKeys / 100)
+		if hot < 1 {
+			hot = 1
+		}
+		if hot >= p.n || p.source.Float64()*100 < p.dist.hotRequests {
+			return p.source.Int63n(hot)
+		}
+		return hot + p.source.Int63n(p.n-hot)
+	}
+	return p.source.Int63n(p.n)
+}
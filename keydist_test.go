@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeyDistribution(t *testing.T) {
+	cases := map[string]*keyDistribution{
+		"":                nil,
+		"sequential":      nil,
+		"uniform":         {kind: "uniform"},
+		"zipfian":         {kind: "zipfian", s: 1.1},
+		"zipfian:1.5":     {kind: "zipfian", s: 1.5},
+		"hotspot:90/10":   {kind: "hotspot", hotRequests: 90, hotKeys: 10},
+		"hotspot:80%/20%": {kind: "hotspot", hotRequests: 80, hotKeys: 20},
+	}
+	for distribution, expected := range cases {
+		if parsed, err := parseKeyDistribution(distribution); err != nil || !reflect.DeepEqual(parsed, expected) {
+			t.Fatalf("Expected %s to be %+v but got %+v: %v", distribution, expected, parsed, err)
+		}
+	}
+
+	for _, distribution := range []string{"random", "uniform:2", "zipfian:1", "zipfian:x", "hotspot", "hotspot:90", "hotspot:90/100", "hotspot:0/10"} {
+		if _, err := parseKeyDistribution(distribution); err == nil {
+			t.Fatalf("key distribution %s should be invalid", distribution)
+		}
+	}
+}
+
+// Returns how often each of n keys was picked in count picks.
+func pickKeys(t *testing.T, distribution string, n int64, count int) []int {
+	dist, err := parseKeyDistribution(distribution)
+	if err != nil {
+		t.Fatal(err)
+	}
+	picker := dist.picker(n, 42)
+	picks := make([]int, n)
+	for i := 0; i < count; i++ {
+		key := picker.next()
+		if key < 0 || key >= n {
+			t.Fatalf("%s picked key %d out of %d keys", distribution, key, n)
+		}
+		picks[key]++
+	}
+	return picks
+}
+
+func TestKeyPicker(t *testing.T) {
+	if (*keyDistribution)(nil).picker(1000, 42) != nil {
+		t.Fatalf("Expected no picker without a distribution")
+	}
+
+	uniform := pickKeys(t, "uniform", 10, 10000)
+	for key, n := range uniform {
+		if n < 800 || n > 1200 {
+			t.Fatalf("uniform should pick every key about 1000 times but picked key %d %d times", key, n)
+		}
+	}
+
+	zipfian := pickKeys(t, "zipfian:1.5", 1000, 10000)
+	if zipfian[0] < zipfian[1] || zipfian[1] < zipfian[10] || zipfian[0] < 3000 {
+		t.Fatalf("zipfian should pick the first keys most often: %v", zipfian[:11])
+	}
+
+	hotspot := pickKeys(t, "hotspot:90/10", 1000, 10000)
+	hot := 0
+	for _, n := range hotspot[:100] {
+		hot += n
+	}
+	if hot < 8800 || hot > 9200 {
+		t.Fatalf("hotspot:90/10 should send about 9000 of 10000 requests to the first 100 keys but sent %d", hot)
+	}
+}
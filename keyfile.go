@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// keyList is an inventory of existing keys, like those of a production dataset, that reads and deletes go through
+// instead of the keys of a put run.
+type keyList struct {
+	keys []string
+	// the sizes of the keys that have one in the file
+	sizes map[string]int64
+}
+
+// loadKeyFile loads the keys of a file of one key per line, or CSV lines of key,size, with an optional header line.
+func loadKeyFile(path string) (*keyList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readKeyFile(f)
+}
+
+func readKeyFile(r io.Reader) (*keyList, error) {
+	l := &keyList{sizes: make(map[string]int64)}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(record) > 2 || record[0] == "" {
+			return nil, fmt.Errorf("line %d of the key file must be key or key,size", line)
+		}
+		if len(record) == 2 {
+			size, err := strconv.ParseInt(record[1], 10, 64)
+			if err != nil && line == 1 {
+				// a header line
+				continue
+			}
+			if err != nil || size < 0 {
+				return nil, fmt.Errorf("line %d of the key file has an invalid size: %s", line, record[1])
+			}
+			l.sizes[record[0]] = size
+		}
+		l.keys = append(l.keys, record[0])
+	}
+	if len(l.keys) == 0 {
+		return nil, errors.New("the key file has no keys")
+	}
+	return l, nil
+}
+
+// key returns the n-th key, the keys start over after the last one.
+func (l *keyList) key(n int64) string {
+	return l.keys[n%int64(len(l.keys))]
+}
+
+// size returns the size of the key if the file has it.
+func (l *keyList) size(key string) (int64, bool) {
+	if l == nil {
+		return 0, false
+	}
+	size, ok := l.sizes[key]
+	return size, ok
+}
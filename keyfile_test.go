@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadKeyFile(t *testing.T) {
+	l, err := readKeyFile(strings.NewReader("key,size\nlogs/a.gz,1024\n\"b,c\",0\nd\n"))
+	if err != nil {
+		t.Fatalf("valid key file should succeed: %v", err)
+	}
+
+	if len(l.keys) != 3 || l.keys[0] != "logs/a.gz" || l.keys[1] != "b,c" || l.keys[2] != "d" {
+		t.Fatalf("Wrong keys: %v", l.keys)
+	}
+
+	if size, ok := l.size("logs/a.gz"); !ok || size != 1024 {
+		t.Fatalf("Expected size 1024 but got %d", size)
+	}
+
+	if _, ok := l.size("d"); ok {
+		t.Fatalf("key without size should have no size")
+	}
+
+	if l.key(0) != "logs/a.gz" || l.key(4) != "b,c" {
+		t.Fatalf("keys should start over after the last one: %s %s", l.key(0), l.key(4))
+	}
+
+	for _, invalid := range []string{"", "a,1\nb,x\n", "a,-1\n", "a,1,2\n", ",1\n"} {
+		if _, err := readKeyFile(strings.NewReader(invalid)); err == nil {
+			t.Fatalf("key file %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestKeysFromFileOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys.csv")
+	ioutil.WriteFile(file, []byte("a,10\nb,20\nc,30\nd,40\n"), 0644)
+
+	args, err := parse([]string{"-operation=get", "-concurrency=2", "-keys-from-file=" + file})
+	if err != nil {
+		t.Fatalf("keys-from-file should succeed: %v", err)
+	}
+
+	if args.nrequests.value != 4 || args.keyList == nil {
+		t.Fatalf("expected one request per key but got %d", args.nrequests.value)
+	}
+
+	if key := objectKey(&args, 1, 2, 1); key != "d" {
+		t.Fatalf("expected the last key of the second worker to be d but got %s", key)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-keys-from-file=" + file}); err == nil {
+		t.Fatalf("keys-from-file with put should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-keys-from-file=" + file, "-key-template={{counter}}"}); err == nil {
+		t.Fatalf("keys-from-file with key-template should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-keys-from-file=" + filepath.Join(dir, "missing")}); err == nil {
+		t.Fatalf("missing key file should fail")
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// keyTemplate names the keys by a template like {{hash8}}/{{counter}} instead of <prefix>-<n>, to reproduce the key
+// layouts that spread over or pile up on the partitions of the backend.
+type keyTemplate struct {
+	segments []keySegment
+}
+
+// keySegment is a literal text or a placeholder of a key template.
+type keySegment struct {
+	// empty for a literal text
+	placeholder string
+	text        string
+	// zero padded width of counter, number of characters of hash
+	width int
+}
+
+// Parses a key template. The placeholders are {{prefix}}, {{counter}}, {{counter:N}} padded with zeros to N digits,
+// {{uuid}} and {{hashN}}, the first N hex characters of the MD5 of the key, both derived from the key number, and
+// {{date}}, the UTC date at which the run started like 2026/10/15. The template must contain {{counter}}, {{uuid}} or
+// {{hash32}} to give every key a name of its own. Returns nil for an empty template, which names the keys
+// <prefix>-<n>.
+func parseKeyTemplate(template string, start time.Time) (*keyTemplate, error) {
+	if template == "" {
+		return nil, nil
+	}
+	t := &keyTemplate{}
+	unique := false
+	for rest := template; rest != ""; {
+		open := strings.Index(rest, "{{")
+		if open < 0 {
+			t.segments = append(t.segments, keySegment{text: rest})
+			break
+		}
+		if open > 0 {
+			t.segments = append(t.segments, keySegment{text: rest[:open]})
+		}
+		end := strings.Index(rest[open:], "}}")
+		if end < 0 {
+			return nil, errors.New("key-template has a {{ without }}")
+		}
+		name := rest[open+2 : open+end]
+		rest = rest[open+end+2:]
+
+		switch {
+		case name == "prefix" || name == "uuid" || name == "counter":
+			t.segments = append(t.segments, keySegment{placeholder: name})
+			unique = unique || name != "prefix"
+		case name == "date":
+			t.segments = append(t.segments, keySegment{text: start.UTC().Format("2006/01/02")})
+		case strings.HasPrefix(name, "counter:"):
+			width, err := strconv.Atoi(name[len("counter:"):])
+			if err != nil || width < 1 || width > 20 {
+				return nil, errors.New("the width of {{counter:N}} in key-template must be 1-20")
+			}
+			t.segments = append(t.segments, keySegment{placeholder: "counter", width: width})
+			unique = true
+		case strings.HasPrefix(name, "hash"):
+			width, err := strconv.Atoi(name[len("hash"):])
+			if err != nil || width < 1 || width > 32 {
+				return nil, errors.New("the length of {{hashN}} in key-template must be 1-32")
+			}
+			t.segments = append(t.segments, keySegment{placeholder: "hash", width: width})
+			unique = unique || width == 32
+		default:
+			return nil, fmt.Errorf("unknown placeholder {{%s}} in key-template", name)
+		}
+	}
+	if !unique {
+		return nil, errors.New("key-template must contain {{counter}}, {{uuid}} or {{hash32}}")
+	}
+	return t, nil
+}
+
+// name returns the name of the n-th key under the prefix, which is <prefix>-<n> without a template.
+func (t *keyTemplate) name(prefix string, n int64) string {
+	key := prefix + "-" + strconv.FormatInt(n, 10)
+	if t == nil {
+		return key
+	}
+	// the uuid and hash are derived from the default name, so they don't change with the template
+	sum := md5.Sum([]byte(key))
+	var b strings.Builder
+	for _, s := range t.segments {
+		switch s.placeholder {
+		case "":
+			b.WriteString(s.text)
+		case "prefix":
+			b.WriteString(prefix)
+		case "counter":
+			fmt.Fprintf(&b, "%0*d", s.width, n)
+		case "hash":
+			b.WriteString(hex.EncodeToString(sum[:])[:s.width])
+		case "uuid":
+			b.WriteString(nameUUID(sum))
+		}
+	}
+	return b.String()
+}
+
+// Formats the MD5 as a name based (version 3) UUID.
+func nameUUID(sum [16]byte) string {
+	sum[6] = sum[6]&0x0f | 0x30
+	sum[8] = sum[8]&0x3f | 0x80
+	h := hex.EncodeToString(sum[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeyTemplate(t *testing.T) {
+	start := time.Date(2026, 10, 15, 23, 0, 0, 0, time.UTC)
+	if k, err := parseKeyTemplate("", start); k != nil || err != nil {
+		t.Fatalf("no template should give nil: %+v %v", k, err)
+	}
+
+	cases := map[string]string{
+		"{{prefix}}-{{counter}}":           "3-42",
+		"{{prefix}}/{{counter:6}}":         "3/000042",
+		"logs/{{date}}/{{counter}}":        "logs/2026/10/15/42",
+		"{{hash8}}/{{prefix}}-{{counter}}": "0562872b/3-42",
+		"{{hash32}}":                       "0562872b8a91ecc41abc8e6a6b263914",
+		"{{uuid}}":                         "0562872b-8a91-3cc4-9abc-8e6a6b263914",
+	}
+	for template, expected := range cases {
+		k, err := parseKeyTemplate(template, start)
+		if err != nil {
+			t.Fatalf("%s should be valid: %v", template, err)
+		}
+		if name := k.name("3", 42); name != expected {
+			t.Fatalf("Expected %s to name key 42 %s but got %s", template, expected, name)
+		}
+	}
+
+	for _, template := range []string{"{{prefix}}", "{{hash8}}/{{date}}", "{{counter", "{{counter:0}}", "{{hash33}}-{{counter}}", "{{random}}-{{counter}}"} {
+		if _, err := parseKeyTemplate(template, start); err == nil {
+			t.Fatalf("key template %s should be invalid", template)
+		}
+	}
+}
+
+func TestKeyTemplateDefaultName(t *testing.T) {
+	var k *keyTemplate
+	if name := k.name("testobject", 7); name != "testobject-7" {
+		t.Fatalf("Wrong default name: %s", name)
+	}
+
+	k, _ = parseKeyTemplate("{{hash2}}/{{counter}}", time.Now())
+	prefixes := make(map[string]bool)
+	for n := int64(0); n < 1000; n++ {
+		prefixes[strings.Split(k.name("testobject", n), "/")[0]] = true
+	}
+	if len(prefixes) < 200 {
+		t.Fatalf("hash prefixes should spread the keys, got %d prefixes", len(prefixes))
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// loadShapePoint is a line of a load shape: from the offset on, the number of workers send requests at the rate.
+type loadShapePoint struct {
+	offset      time.Duration
+	concurrency int
+	// requests per second of all workers, 0 is no limit
+	rate float64
+}
+
+// loadShape changes the number of active workers and their request rate during a single run, for load shapes like
+// steps, spikes or a sawtooth. The run starts the workers of the highest concurrency of the shape, the workers beyond
+// the current concurrency wait until they are needed again.
+type loadShape struct {
+	points []loadShapePoint
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	// workers with a rank below active send requests
+	active int
+	done   bool
+}
+
+// loadLoadShape loads a load shape file of 'offset,concurrency,rate' CSV lines like '1m30s,200,5000'.
+func loadLoadShape(path string) (*loadShape, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLoadShape(f)
+}
+
+// readLoadShape reads the points of a load shape. Lines starting with # are comments. The offsets start at 0 and
+// increase, the run ends at the last line, which must have a concurrency of 0.
+func readLoadShape(r io.Reader) (*loadShape, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	s := &loadShape{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		var p loadShapePoint
+		p.offset, err = time.ParseDuration(record[0])
+		if err != nil || p.offset < 0 || (len(s.points) == 0 && p.offset != 0) || (len(s.points) > 0 && p.offset <= s.points[len(s.points)-1].offset) {
+			return nil, fmt.Errorf("line %d of the load shape must have an offset after the previous one, starting at 0s: %s", line, record[0])
+		}
+		if p.concurrency, err = strconv.Atoi(strings.TrimSpace(record[1])); err != nil || p.concurrency < 0 {
+			return nil, fmt.Errorf("line %d of the load shape has an invalid concurrency: %s", line, record[1])
+		}
+		if p.rate, err = strconv.ParseFloat(strings.TrimSpace(record[2]), 64); err != nil || p.rate < 0 {
+			return nil, fmt.Errorf("line %d of the load shape has an invalid rate: %s", line, record[2])
+		}
+		s.points = append(s.points, p)
+	}
+	if len(s.points) < 2 || s.points[len(s.points)-1].concurrency != 0 {
+		return nil, errors.New("the load shape must end with a line of concurrency 0")
+	}
+	if s.peak() == 0 {
+		return nil, errors.New("the load shape has no workers")
+	}
+	return s, nil
+}
+
+// peak returns the highest concurrency of the shape.
+func (s *loadShape) peak() int {
+	peak := 0
+	for _, p := range s.points {
+		if p.concurrency > peak {
+			peak = p.concurrency
+		}
+	}
+	return peak
+}
+
+// length returns the length of the run.
+func (s *loadShape) length() time.Duration {
+	return s.points[len(s.points)-1].offset
+}
+
+// start changes the active workers and the limit of the limiter at the offsets of the points until the end of the
+// shape or an interrupt. Does nothing for a nil shape.
+func (s *loadShape) start(limiter *rate.Limiter, runstart time.Time) {
+	if s == nil {
+		return
+	}
+	s.cond = sync.NewCond(&s.mu)
+	s.active, s.done = 0, false
+	s.apply(s.points[0], limiter)
+	go func() {
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		// the point applied and the first point that hasn't been reached
+		applied, next := 0, 1
+		for range ticker.C {
+			elapsed := time.Since(runstart)
+			for next < len(s.points) && s.points[next].offset <= elapsed {
+				next++
+			}
+			if next == len(s.points) || wasInterrupted() {
+				s.finish()
+				return
+			}
+			if next-1 != applied {
+				applied = next - 1
+				s.apply(s.points[applied], limiter)
+			}
+		}
+	}()
+}
+
+func (s *loadShape) apply(p loadShapePoint, limiter *rate.Limiter) {
+	limit := rate.Inf
+	if p.rate > 0 {
+		limit = rate.Limit(p.rate)
+	}
+	limiter.SetLimit(limit)
+	log.Printf("Load shape at %s: %d workers, %s requests/s", p.offset, p.concurrency, rateString(p.rate))
+	s.mu.Lock()
+	s.active = p.concurrency
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+func rateString(rate float64) string {
+	if rate == 0 {
+		return "unlimited"
+	}
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
+
+func (s *loadShape) finish() {
+	s.mu.Lock()
+	s.done = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// admit waits until the worker of the rank is active and returns false once the shape has ended. A nil shape admits
+// every worker.
+func (s *loadShape) admit(rank int) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for rank >= s.active && !s.done {
+		s.cond.Wait()
+	}
+	return !s.done
+}
+
+// Returns the rank of the worker in the order workers are activated, which takes the workers of the endpoints in
+// turn, so that every endpoint gets its share of the active workers.
+func shapeRank(id, concurrency, endpoints int) int {
+	workersPerEndpoint := concurrency / endpoints
+	return id%workersPerEndpoint*endpoints + id/workersPerEndpoint
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestReadLoadShape(t *testing.T) {
+	s, err := readLoadShape(strings.NewReader("# spike\n0s,10,100\n1m, 200, 0\n1m30s,10,100\n2m,0,0\n"))
+	if err != nil {
+		t.Fatalf("valid load shape should succeed: %v", err)
+	}
+
+	if len(s.points) != 4 || s.points[1] != (loadShapePoint{offset: time.Minute, concurrency: 200}) || s.points[2].rate != 100 {
+		t.Fatalf("Wrong points: %+v", s.points)
+	}
+
+	if s.peak() != 200 || s.length() != 2*time.Minute {
+		t.Fatalf("Expected a peak of 200 over 2m but got %d over %s", s.peak(), s.length())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"0s,10,100\n",
+		"0s,10,100\n1m,10,100\n",
+		"1s,10,100\n1m,0,0\n",
+		"0s,10,100\n1m,10,100\n1m,0,0\n",
+		"0s,-1,100\n1m,0,0\n",
+		"0s,10,fast\n1m,0,0\n",
+		"0s,10\n1m,0,0\n",
+		"0s,0,0\n1m,0,0\n",
+	} {
+		if _, err := readLoadShape(strings.NewReader(invalid)); err == nil {
+			t.Fatalf("load shape %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestLoadShapeController(t *testing.T) {
+	s, _ := readLoadShape(strings.NewReader("0s,2,0\n300ms,1,50\n600ms,0,0\n"))
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	start := time.Now()
+	s.start(limiter, start)
+
+	if !s.admit(1) || limiter.Limit() != rate.Inf {
+		t.Fatalf("both workers should be active without a limit at the start")
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if !s.admit(0) || limiter.Limit() != 50 {
+		t.Fatalf("the first worker should be active with a limit of 50 but got %v", limiter.Limit())
+	}
+
+	// the second worker waits until the end of the shape
+	if s.admit(1) || time.Since(start) < 600*time.Millisecond {
+		t.Fatalf("the second worker should wait while the concurrency is 1 and not be admitted after the end")
+	}
+
+	if s.admit(0) {
+		t.Fatalf("no worker should be admitted after the end of the shape")
+	}
+
+	var none *loadShape
+	if !none.admit(5) {
+		t.Fatalf("without a load shape every worker should be admitted")
+	}
+}
+
+func TestShapeRank(t *testing.T) {
+	// workers 0-2 go to the first endpoint and 3-5 to the second
+	expected := []int{0, 2, 4, 1, 3, 5}
+	for id, rank := range expected {
+		if r := shapeRank(id, 6, 2); r != rank {
+			t.Fatalf("Expected worker %d to have rank %d but got %d", id, rank, r)
+		}
+	}
+}
+
+func TestLoadShapeOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shape")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "shape.csv")
+	ioutil.WriteFile(file, []byte("0s,10,100\n1m,25,0\n2m,0,0\n"), 0644)
+
+	args, err := parse([]string{"-operation=put", "-load-shape=" + file, "-endpoint=https://10.0.0.1:443,https://10.0.0.2:443"})
+	if err != nil {
+		t.Fatalf("load-shape should succeed: %v", err)
+	}
+
+	if args.concurrency != 26 || !args.duration.set || args.duration.value != 2*time.Minute || args.loadShape == nil {
+		t.Fatalf("Expected 26 workers for 2m but got %d for %s", args.concurrency, args.duration.value)
+	}
+
+	if _, err = parse([]string{"-operation=put", "-load-shape=" + file, "-concurrency=10"}); err == nil {
+		t.Fatalf("load-shape with concurrency should fail")
+	}
+
+	if _, err = parse([]string{"-operation=put", "-load-shape=" + file, "-ratelimit=10"}); err == nil {
+		t.Fatalf("load-shape with ratelimit should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-load-shape=" + file}); err == nil {
+		t.Fatalf("reads of a load shape without requests should fail")
+	}
+}
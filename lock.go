@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// objectLock holds the Object Lock settings of the objects written to a bucket with Object Lock enabled.
+type objectLock struct {
+	// GOVERNANCE or COMPLIANCE, empty if no retention is set
+	mode string
+	// objects are retained until a fixed date, or for a duration after they are written if the date is zero
+	retainUntil time.Time
+	retainFor   time.Duration
+	// ON or OFF, empty if no legal hold is set
+	legalHold string
+}
+
+// Parses the Object Lock settings. The retention is either an RFC 3339 date like 2030-01-01T00:00:00Z or
+// a duration like 24h after each write. Returns nil if nothing is set.
+func parseObjectLock(mode, retainUntil, legalHold string) (*objectLock, error) {
+	if mode == "" && retainUntil == "" && legalHold == "" {
+		return nil, nil
+	}
+	lock := &objectLock{mode: strings.ToUpper(mode), legalHold: strings.ToUpper(legalHold)}
+	if (lock.mode == "") != (retainUntil == "") {
+		return nil, fmt.Errorf("lock-mode and lock-retain-until must be set together")
+	}
+	if lock.mode != "" && lock.mode != s3.ObjectLockModeGovernance && lock.mode != s3.ObjectLockModeCompliance {
+		return nil, fmt.Errorf("lock-mode must be GOVERNANCE or COMPLIANCE")
+	}
+	if lock.legalHold != "" && lock.legalHold != s3.ObjectLockLegalHoldStatusOn && lock.legalHold != s3.ObjectLockLegalHoldStatusOff {
+		return nil, fmt.Errorf("legal-hold must be ON or OFF")
+	}
+	if retainUntil != "" {
+		if date, err := time.Parse(time.RFC3339, retainUntil); err == nil {
+			lock.retainUntil = date
+		} else if d, err := time.ParseDuration(retainUntil); err == nil && d > 0 {
+			lock.retainFor = d
+		} else {
+			return nil, fmt.Errorf("lock-retain-until must be an RFC 3339 date or a duration > 0: %s", retainUntil)
+		}
+	}
+	return lock, nil
+}
+
+// Returns the date until which an object written at the given time is retained.
+func (l *objectLock) retainUntilDate(now time.Time) time.Time {
+	if l.retainFor != 0 {
+		return now.Add(l.retainFor).UTC().Truncate(time.Second)
+	}
+	return l.retainUntil
+}
+
+// apply sets the Object Lock headers of the requests that write objects.
+func (l *objectLock) apply(params interface{}, now time.Time) {
+	var mode, legalHold **string
+	var retainUntil **time.Time
+	switch p := params.(type) {
+	case *s3.PutObjectInput:
+		mode, legalHold, retainUntil = &p.ObjectLockMode, &p.ObjectLockLegalHoldStatus, &p.ObjectLockRetainUntilDate
+	case *s3.CreateMultipartUploadInput:
+		mode, legalHold, retainUntil = &p.ObjectLockMode, &p.ObjectLockLegalHoldStatus, &p.ObjectLockRetainUntilDate
+	case *s3.CopyObjectInput:
+		mode, legalHold, retainUntil = &p.ObjectLockMode, &p.ObjectLockLegalHoldStatus, &p.ObjectLockRetainUntilDate
+	default:
+		return
+	}
+	if l.mode != "" {
+		*mode = aws.String(l.mode)
+		*retainUntil = aws.Time(l.retainUntilDate(now))
+	}
+	if l.legalHold != "" {
+		*legalHold = aws.String(l.legalHold)
+	}
+}
+
+// install applies the settings to every object written by the service.
+func (l *objectLock) install(svc *s3.S3) {
+	if l == nil {
+		return
+	}
+	svc.Client.Handlers.Validate.PushFront(func(r *request.Request) {
+		l.apply(r.Params, time.Now())
+	})
+}
+
+func PutRetention(svc s3iface.S3API, bucket, key, mode string, retainUntil time.Time) error {
+	params := &s3.PutObjectRetentionInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Retention: &s3.ObjectLockRetention{
+			Mode:            aws.String(mode),
+			RetainUntilDate: aws.Time(retainUntil),
+		},
+	}
+	_, err := svc.PutObjectRetention(params)
+
+	return err
+}
+
+func GetRetention(svc s3iface.S3API, bucket, key string) error {
+	params := &s3.GetObjectRetentionInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	_, err := svc.GetObjectRetention(params)
+
+	return err
+}
+
+func PutLegalHold(svc s3iface.S3API, bucket, key, status string) error {
+	params := &s3.PutObjectLegalHoldInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		LegalHold: &s3.ObjectLockLegalHold{Status: aws.String(status)},
+	}
+	_, err := svc.PutObjectLegalHold(params)
+
+	return err
+}
+
+func GetLegalHold(svc s3iface.S3API, bucket, key string) error {
+	params := &s3.GetObjectLegalHoldInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	_, err := svc.GetObjectLegalHold(params)
+
+	return err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestParseObjectLock(t *testing.T) {
+	if lock, err := parseObjectLock("", "", ""); lock != nil || err != nil {
+		t.Fatalf("Expected no lock settings but got %+v, %v", lock, err)
+	}
+
+	lock, err := parseObjectLock("governance", "2030-01-01T00:00:00Z", "on")
+	if err != nil {
+		t.Fatalf("Expected valid lock settings but got: %v", err)
+	}
+
+	if lock.mode != "GOVERNANCE" || lock.legalHold != "ON" || !lock.retainUntil.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Wrong lock settings %+v", lock)
+	}
+
+	lock, err = parseObjectLock("COMPLIANCE", "24h", "")
+	if err != nil || lock.retainFor != 24*time.Hour {
+		t.Fatalf("Expected retention of 24h but got %+v, %v", lock, err)
+	}
+
+	invalid := [][]string{{"GOVERNANCE", "", ""}, {"", "24h", ""}, {"LOCKED", "24h", ""}, {"", "", "MAYBE"}, {"COMPLIANCE", "tomorrow", ""}, {"COMPLIANCE", "-1h", ""}}
+	for _, settings := range invalid {
+		if _, err := parseObjectLock(settings[0], settings[1], settings[2]); err == nil {
+			t.Fatalf("Expected invalid lock settings %v to fail", settings)
+		}
+	}
+}
+
+func TestApplyObjectLock(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 500, time.UTC)
+	lock := &objectLock{mode: "COMPLIANCE", retainFor: time.Hour, legalHold: "ON"}
+
+	put := &s3.PutObjectInput{}
+	lock.apply(put, now)
+	if aws.StringValue(put.ObjectLockMode) != "COMPLIANCE" || aws.StringValue(put.ObjectLockLegalHoldStatus) != "ON" {
+		t.Fatalf("Wrong lock headers %v %v", aws.StringValue(put.ObjectLockMode), aws.StringValue(put.ObjectLockLegalHoldStatus))
+	}
+
+	if !aws.TimeValue(put.ObjectLockRetainUntilDate).Equal(time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected retention until an hour after the write but got %v", aws.TimeValue(put.ObjectLockRetainUntilDate))
+	}
+
+	create := &s3.CreateMultipartUploadInput{}
+	(&objectLock{legalHold: "ON"}).apply(create, now)
+	if aws.StringValue(create.ObjectLockLegalHoldStatus) != "ON" || create.ObjectLockMode != nil || create.ObjectLockRetainUntilDate != nil {
+		t.Fatalf("Expected only a legal hold but got %+v", create)
+	}
+
+	// requests that don't write objects are left alone
+	lock.apply(&s3.GetObjectInput{}, now)
+}
+
+func (this *mockS3Client) PutObjectRetention(in *s3.PutObjectRetentionInput) (*s3.PutObjectRetentionOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.PutObjectRetentionOutput{}, nil
+}
+
+func (this *mockS3Client) PutObjectLegalHold(in *s3.PutObjectLegalHoldInput) (*s3.PutObjectLegalHoldOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.PutObjectLegalHoldOutput{}, nil
+}
+
+func TestPutRetentionOp(t *testing.T) {
+	retainUntil := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutObjectRetentionInput)
+
+		if *i.Bucket != "b" || *i.Key != "k1" {
+			t.Fatalf("Expected object b/k1 but got: %s/%s", *i.Bucket, *i.Key)
+		}
+
+		if *i.Retention.Mode != "GOVERNANCE" || !i.Retention.RetainUntilDate.Equal(retainUntil) {
+			t.Fatalf("Wrong retention %v until %v", *i.Retention.Mode, *i.Retention.RetainUntilDate)
+		}
+
+		return in
+	}
+
+	if err := PutRetention(NewMockS3Client(handler), "b", "k1", "GOVERNANCE", retainUntil); err != nil {
+		t.Fatalf("Failed PUT retention operation with error: %v", err)
+	}
+}
+
+func TestPutLegalHoldOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutObjectLegalHoldInput)
+
+		if *i.Bucket != "b" || *i.Key != "k1" {
+			t.Fatalf("Expected object b/k1 but got: %s/%s", *i.Bucket, *i.Key)
+		}
+
+		if *i.LegalHold.Status != "OFF" {
+			t.Fatalf("Expected legal hold OFF but got: %s", *i.LegalHold.Status)
+		}
+
+		return in
+	}
+
+	if err := PutLegalHold(NewMockS3Client(handler), "b", "k1", "OFF"); err != nil {
+		t.Fatalf("Failed PUT legal hold operation with error: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// manifestEntry is an object listed in a manifest written by another tool.
+type manifestEntry struct {
+	key string
+	// -1 when the manifest doesn't have the size
+	size int64
+	// hex encoded, empty when the manifest doesn't have the MD5
+	md5 string
+}
+
+// manifestReport summarizes the verification of the objects of a manifest.
+type manifestReport struct {
+	Objects  int `json:"objects"`
+	Verified int `json:"verified"`
+	// objects verified by their size only, because the manifest has no MD5 of them
+	SizeOnly         int     `json:"verifiedBySizeOnly"`
+	Missing          int     `json:"missing"`
+	SizeMismatch     int     `json:"sizeMismatch"`
+	ChecksumMismatch int     `json:"checksumMismatch"`
+	Failed           int     `json:"failed"`
+	Bytes            int64   `json:"totalBytes"`
+	ElapsedTime      float64 `json:"totalElapsedTime (s)"`
+	ObjectsPerSec    float64 `json:"objectsPerSec"`
+	Throughput       float64 `json:"contentThroughput (MB/s)"`
+}
+
+func (r *manifestReport) record(entry manifestEntry, err error) {
+	r.Objects++
+	mismatch, _ := err.(*manifestMismatch)
+	switch {
+	case err == nil:
+		r.Verified++
+		if entry.md5 == "" {
+			r.SizeOnly++
+		}
+	case mismatch == nil:
+		r.Failed++
+	case mismatch.kind == manifestMissing:
+		r.Missing++
+	case mismatch.kind == manifestSize:
+		r.SizeMismatch++
+	case mismatch.kind == manifestChecksum:
+		r.ChecksumMismatch++
+	}
+}
+
+func (r manifestReport) failures() int {
+	return r.Missing + r.SizeMismatch + r.ChecksumMismatch + r.Failed
+}
+
+// readManifest sends the entries of the manifest to entries. It reads the output of md5sum and rclone md5sum, the
+// JSON output of aws s3api list-objects-v2 and CSV lines of key,size[,md5] like those of rclone lsf --format psh
+// --csv, with an optional header line.
+func readManifest(r io.Reader, entries chan<- manifestEntry) error {
+	br := bufio.NewReader(r)
+	first, _ := br.Peek(34)
+	switch {
+	case len(bytes.TrimSpace(first)) > 0 && bytes.TrimSpace(first)[0] == '{':
+		return readListObjectsManifest(br, entries)
+	case isMD5SumLine(string(first)):
+		return readMD5SumManifest(br, entries)
+	}
+	return readCSVManifest(br, entries)
+}
+
+// Returns whether the line starts like a line of md5sum: the MD5, a space and a space or an asterisk.
+func isMD5SumLine(line string) bool {
+	if len(line) < 34 || (line[32] != ' ') || (line[33] != ' ' && line[33] != '*') {
+		return false
+	}
+	_, err := hex.DecodeString(line[:32])
+	return err == nil
+}
+
+func readMD5SumManifest(r io.Reader, entries chan<- manifestEntry) error {
+	scanner := bufio.NewScanner(r)
+	// keys can be up to 1024 bytes long
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		if !isMD5SumLine(text) {
+			return fmt.Errorf("line %d of the manifest is not like the output of md5sum: %s", line, text)
+		}
+		entries <- manifestEntry{key: text[34:], size: -1, md5: strings.ToLower(text[:32])}
+	}
+	return scanner.Err()
+}
+
+func readCSVManifest(r io.Reader, entries chan<- manifestEntry) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return fmt.Errorf("line %d of the manifest must be key,size or key,size,md5", line)
+		}
+		size, err := strconv.ParseInt(record[1], 10, 64)
+		if err != nil && line == 1 {
+			// a header line
+			continue
+		}
+		if err != nil || size < 0 {
+			return fmt.Errorf("line %d of the manifest has an invalid size: %s", line, record[1])
+		}
+		entry := manifestEntry{key: record[0], size: size}
+		if len(record) == 3 {
+			entry.md5 = strings.ToLower(record[2])
+			if _, err := hex.DecodeString(entry.md5); err != nil || len(entry.md5) != 32 {
+				return fmt.Errorf("line %d of the manifest has an invalid MD5: %s", line, record[2])
+			}
+		}
+		entries <- entry
+	}
+}
+
+// listObjectsOutput is the JSON output of aws s3api list-objects-v2.
+type listObjectsOutput struct {
+	Contents []struct {
+		Key  string
+		Size int64
+		ETag string
+	}
+}
+
+func readListObjectsManifest(r io.Reader, entries chan<- manifestEntry) error {
+	decoder := json.NewDecoder(r)
+	// the outputs of several listings can be concatenated, e.g. one per prefix
+	for decoder.More() {
+		var output listObjectsOutput
+		if err := decoder.Decode(&output); err != nil {
+			return err
+		}
+		for _, object := range output.Contents {
+			entry := manifestEntry{key: object.Key, size: object.Size}
+			etag := strings.Trim(object.ETag, "\"")
+			// the ETags of multipart uploads and of encrypted objects are not their MD5
+			if _, err := hex.DecodeString(etag); err == nil && len(etag) == 32 {
+				entry.md5 = strings.ToLower(etag)
+			}
+			entries <- entry
+		}
+	}
+	return nil
+}
+
+// Kinds of objects that don't match their manifest entry.
+const (
+	manifestMissing  = "missing"
+	manifestSize     = "sizeMismatch"
+	manifestChecksum = "checksumMismatch"
+)
+
+// manifestMismatch is the error of an object that was read, or found missing, but doesn't match its manifest entry.
+type manifestMismatch struct {
+	kind string
+	err  error
+}
+
+func (e *manifestMismatch) Error() string {
+	return e.err.Error()
+}
+
+// verifyManifestObject reads the object and compares its size and MD5 with the entry of the manifest. Returns the
+// number of bytes read.
+func verifyManifestObject(svc s3iface.S3API, bucket string, entry manifestEntry) (int64, error) {
+	out, err := svc.GetObject(&s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(entry.key)})
+	if err != nil {
+		if e, ok := err.(awserr.RequestFailure); ok && e.StatusCode() == 404 {
+			return 0, &manifestMismatch{kind: manifestMissing, err: errors.New("object is missing")}
+		}
+		return 0, err
+	}
+	defer out.Body.Close()
+
+	hash := md5.New()
+	n, err := io.Copy(hash, out.Body)
+	if err != nil {
+		return n, err
+	}
+	if entry.size >= 0 && n != entry.size {
+		return n, &manifestMismatch{kind: manifestSize, err: fmt.Errorf("%d bytes instead of %d", n, entry.size)}
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); entry.md5 != "" && sum != entry.md5 {
+		return n, &manifestMismatch{kind: manifestChecksum, err: fmt.Errorf("MD5 %s instead of %s", sum, entry.md5)}
+	}
+	return n, nil
+}
+
+// VerifyManifest verifies every object of the manifest in the bucket, concurrency of them at a time. Every object that
+// doesn't match the manifest is logged.
+func VerifyManifest(svc s3iface.S3API, bucket string, manifest io.Reader, concurrency int) (manifestReport, error) {
+	start := time.Now()
+	entries := make(chan manifestEntry, concurrency)
+	var report manifestReport
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range entries {
+				n, err := verifyManifestObject(svc, bucket, entry)
+				mu.Lock()
+				report.Bytes += n
+				report.record(entry, err)
+				mu.Unlock()
+				if err != nil {
+					log.Printf("Object '%s/%s' failed verification: %v", bucket, entry.key, err)
+				}
+			}
+		}()
+	}
+
+	err := readManifest(manifest, entries)
+	close(entries)
+	wg.Wait()
+
+	elapsed := time.Since(start).Seconds()
+	report.ElapsedTime = elapsed
+	report.ObjectsPerSec = float64(report.Objects) / elapsed
+	report.Throughput = float64(report.Bytes) / elapsed / 1024 / 1024
+	return report, err
+}
+
+// verifyManifest verifies the manifest of verify-manifest against the bucket on the first endpoint and returns the
+// report. Fails if the manifest can't be read to the end.
+func verifyManifest(args parameters) (manifestReport, error) {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		return manifestReport{}, err
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+	args.encryption.install(svc)
+
+	f, err := os.Open(args.verifyManifest)
+	if err != nil {
+		return manifestReport{}, err
+	}
+	defer f.Close()
+	return VerifyManifest(svc, args.bucketname, f, args.concurrency)
+}
+
+func printManifestReport(report manifestReport, isJson bool) {
+	if isJson {
+		jsonReport, err := json.Marshal(map[string]manifestReport{"manifestReport": report})
+		if err != nil {
+			fmt.Println("Error when parsing manifest report to json")
+			return
+		}
+		fmt.Println(string(jsonReport))
+		return
+	}
+
+	fmt.Println("\n\t--- Manifest Results ---")
+	fmt.Printf("Objects in the manifest: %d\n", report.Objects)
+	fmt.Printf("Objects matching the manifest: %d (%d by size only)\n", report.Verified, report.SizeOnly)
+	fmt.Printf("Missing objects: %d\n", report.Missing)
+	fmt.Printf("Objects with a different size: %d\n", report.SizeMismatch)
+	fmt.Printf("Objects with a different MD5: %d\n", report.ChecksumMismatch)
+	fmt.Printf("Objects that could not be read: %d\n", report.Failed)
+	fmt.Printf("Total elapsed time: %s\n", time.Duration(report.ElapsedTime*float64(time.Second)))
+	fmt.Printf("Objects/s: %.1f\n", report.ObjectsPerSec)
+	fmt.Printf("Content throughput: %.6f MB/s\n", report.Throughput)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func readManifestEntries(t *testing.T, manifest string) []manifestEntry {
+	entries := make(chan manifestEntry, 10)
+	if err := readManifest(strings.NewReader(manifest), entries); err != nil {
+		t.Fatal(err)
+	}
+	close(entries)
+	var read []manifestEntry
+	for entry := range entries {
+		read = append(read, entry)
+	}
+	return read
+}
+
+func TestReadManifest(t *testing.T) {
+	md5sum := "5d41402abc4b2a76b9719d911017c592  data/hello.txt\r\n7D793037A0760186574B0282F2F435E7 *data/world.txt\n"
+	expected := []manifestEntry{{"data/hello.txt", -1, "5d41402abc4b2a76b9719d911017c592"}, {"data/world.txt", -1, "7d793037a0760186574b0282f2f435e7"}}
+	if entries := readManifestEntries(t, md5sum); !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("Wrong entries of md5sum: %+v", entries)
+	}
+
+	csv := "key,size,md5\ndata/hello.txt,5,5d41402abc4b2a76b9719d911017c592\n\"data/a,b.txt\",7\n"
+	expected = []manifestEntry{{"data/hello.txt", 5, "5d41402abc4b2a76b9719d911017c592"}, {"data/a,b.txt", 7, ""}}
+	if entries := readManifestEntries(t, csv); !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("Wrong entries of CSV: %+v", entries)
+	}
+
+	listing := `{"Contents": [{"Key": "data/hello.txt", "Size": 5, "ETag": "\"5d41402abc4b2a76b9719d911017c592\""},
+		{"Key": "data/large.bin", "Size": 20971520, "ETag": "\"9b2cf535f27731c974343645a3985328-4\""}]}`
+	expected = []manifestEntry{{"data/hello.txt", 5, "5d41402abc4b2a76b9719d911017c592"}, {"data/large.bin", 20971520, ""}}
+	if entries := readManifestEntries(t, listing); !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("Wrong entries of the listing: %+v", entries)
+	}
+}
+
+func TestReadInvalidManifest(t *testing.T) {
+	for _, manifest := range []string{"data/hello.txt\n", "data/hello.txt,5\ndata/world.txt,five\n", "data/hello.txt,5,xyz\n", `{"Contents": [`} {
+		entries := make(chan manifestEntry, 10)
+		if err := readManifest(strings.NewReader(manifest), entries); err == nil {
+			t.Fatalf("manifest %q should be invalid", manifest)
+		}
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	objects := map[string]string{"hello": "hello", "world": "world!", "other": "other"}
+	svc := NewMockS3Client(func(in interface{}) interface{} {
+		data := objects[aws.StringValue(in.(*s3.GetObjectInput).Key)]
+		return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader([]byte(data)))}
+	})
+	sum := md5.Sum([]byte("hello"))
+	manifest := "hello,5," + hex.EncodeToString(sum[:]) + "\nworld,5\nother,5," + hex.EncodeToString(sum[:]) + "\nsized,0\n"
+
+	report, err := VerifyManifest(svc, "b", strings.NewReader(manifest), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Objects != 4 || report.Verified != 2 || report.SizeOnly != 1 || report.SizeMismatch != 1 || report.ChecksumMismatch != 1 || report.Bytes != 16 {
+		t.Fatalf("Wrong report: %+v", report)
+	}
+	if report.failures() != 2 {
+		t.Fatalf("Expected 2 failures but got %d", report.failures())
+	}
+}
+
+func TestManifestReportRecord(t *testing.T) {
+	var report manifestReport
+	report.record(manifestEntry{key: "k"}, &manifestMismatch{kind: manifestMissing, err: errors.New("object is missing")})
+	report.record(manifestEntry{key: "k"}, errors.New("connection reset"))
+	if report.Objects != 2 || report.Missing != 1 || report.Failed != 1 {
+		t.Fatalf("Wrong report: %+v", report)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// How often the watchdog samples the resident set size of the process.
+const memoryWatchdogInterval = 500 * time.Millisecond
+
+// memoryWatchdog monitors the memory used by the tester and, once it goes past the configured limit,
+// sheds optional per-request diagnostics (such as the -logdetail capture) so that a long soak test
+// completes with a warning instead of being OOM-killed part way through. What was captured before
+// is kept, the diagnostics only stop growing.
+type memoryWatchdog struct {
+	limit uint64
+	shed  int32
+	// the diagnostics that were warned about being shed
+	warned sync.Map
+	// the watchdog is started for every run, halt stops the goroutine of the last start
+	stop     chan struct{}
+	stopOnce *sync.Once
+}
+
+func NewMemoryWatchdog(limitMiB int) *memoryWatchdog {
+	if limitMiB <= 0 {
+		return nil
+	}
+	return &memoryWatchdog{limit: uint64(limitMiB) << 20}
+}
+
+func (w *memoryWatchdog) start() {
+	if w == nil {
+		return
+	}
+	stop := make(chan struct{})
+	w.stop, w.stopOnce = stop, &sync.Once{}
+	go func() {
+		ticker := time.NewTicker(memoryWatchdogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if w.check(residentMemory()) {
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func (w *memoryWatchdog) halt() {
+	if w != nil && w.stop != nil {
+		stop := w.stop
+		w.stopOnce.Do(func() { close(stop) })
+	}
+}
+
+// check compares the sampled memory usage against the limit and returns true once diagnostics have been shed.
+func (w *memoryWatchdog) check(used uint64) bool {
+	if used < w.limit {
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&w.shed, 0, 1) {
+		log.Printf("WARNING: memory usage %d MiB exceeds the limit of %d MiB. Per-request diagnostics are disabled for the rest of the run.", used>>20, w.limit>>20)
+	}
+	return true
+}
+
+// shedding is safe to call on a nil watchdog, which never sheds.
+func (w *memoryWatchdog) shedding() bool {
+	return w != nil && atomic.LoadInt32(&w.shed) == 1
+}
+
+// sheds returns true if the diagnostic named feature has to stop capturing, and warns once per diagnostic that it
+// only covers the run up to the point the limit was exceeded.
+func (w *memoryWatchdog) sheds(feature string) bool {
+	if !w.shedding() {
+		return false
+	}
+	if _, warned := w.warned.LoadOrStore(feature, true); !warned {
+		log.Printf("WARNING: %s stopped capturing at the memory limit and only covers the run up to this point.", feature)
+	}
+	return true
+}
+
+// residentMemory returns the resident set size of the process. On platforms without /proc
+// the memory obtained from the OS by the Go runtime is used as an approximation.
+func residentMemory() uint64 {
+	if statm, err := ioutil.ReadFile("/proc/self/statm"); err == nil {
+		fields := strings.Fields(string(statm))
+		if len(fields) > 1 {
+			if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.Sys
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMemoryWatchdogDisabledByDefault(t *testing.T) {
+	if w := NewMemoryWatchdog(0); w != nil {
+		t.Fatalf("watchdog should be disabled for a zero limit")
+	}
+
+	var w *memoryWatchdog
+	if w.shedding() {
+		t.Fatalf("a nil watchdog should never shed diagnostics")
+	}
+}
+
+func TestMemoryWatchdogBelowLimit(t *testing.T) {
+	w := NewMemoryWatchdog(64)
+
+	if w.check(63 << 20) {
+		t.Fatalf("watchdog tripped below the limit")
+	}
+
+	if w.shedding() {
+		t.Fatalf("diagnostics shed below the limit")
+	}
+}
+
+func TestMemoryWatchdogShedsPastLimit(t *testing.T) {
+	w := NewMemoryWatchdog(64)
+
+	if !w.check(65 << 20) {
+		t.Fatalf("watchdog did not trip past the limit")
+	}
+
+	if !w.shedding() {
+		t.Fatalf("diagnostics should be shed past the limit")
+	}
+
+	// once shed, diagnostics stay disabled for the rest of the run
+	w.check(1 << 20)
+	if !w.shedding() {
+		t.Fatalf("diagnostics were re-enabled after shedding")
+	}
+}
+
+func TestResidentMemory(t *testing.T) {
+	if residentMemory() == 0 {
+		t.Fatalf("resident memory should be non-zero")
+	}
+}
+
+func TestMemoryWatchdogRestart(t *testing.T) {
+	w := NewMemoryWatchdog(1 << 20)
+
+	// every run starts and halts the watchdog, halting twice is harmless
+	for run := 0; run < 3; run++ {
+		w.start()
+		w.halt()
+		w.halt()
+	}
+
+	var none *memoryWatchdog
+	none.start()
+	none.halt()
+}
+
+func TestMemoryWatchdogShedsFeatures(t *testing.T) {
+	var none *memoryWatchdog
+	if none.sheds("logdetail") {
+		t.Fatalf("no watchdog should never shed")
+	}
+
+	w := NewMemoryWatchdog(64)
+	if w.sheds("logdetail") {
+		t.Fatalf("diagnostics shed below the limit")
+	}
+	w.check(65 << 20)
+	// every diagnostic stops, each is warned about once
+	for i := 0; i < 2; i++ {
+		for _, feature := range []string{"logdetail", "audit-log"} {
+			if !w.sheds(feature) {
+				t.Fatalf("%s should be shed past the limit", feature)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+)
+
+// metadataData is what the templates of the metadata values are executed with.
+type metadataData struct {
+	Key      string
+	Bucket   string
+	WorkerID int
+	Size     int64
+}
+
+const randomTokenChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Returns n random letters and digits.
+func randomToken(n int) string {
+	token := make([]byte, n)
+	for i := range token {
+		token[i] = randomTokenChars[rand.Intn(len(randomTokenChars))]
+	}
+	return string(token)
+}
+
+// Returns n random bytes, base64 encoded since metadata values are sent as headers.
+func randomBlob(n int) string {
+	blob := make([]byte, n)
+	rand.Read(blob)
+	return base64.StdEncoding.EncodeToString(blob)
+}
+
+var metadataFuncs = template.FuncMap{
+	// a short random token, e.g. {{random 8}}
+	"random": randomToken,
+	// a random value of a fixed size, e.g. {{blob 1024}}, to grow the metadata of the objects
+	"blob": randomBlob,
+}
+
+// metadataTemplate holds the metadata values that are templates, which are executed for every object written.
+type metadataTemplate struct {
+	keys   []string
+	values []*template.Template
+}
+
+// Parses the metadata given as 'key1=value1&key2=value2'. Values can be templates like '{{.Key}}', '{{.WorkerID}}',
+// '{{random 8}}' or '{{blob 1024}}'. Returns nil if no value is a template.
+func parseMetadataTemplate(metadata string) (*metadataTemplate, error) {
+	if metadata == "" {
+		return nil, nil
+	}
+	m := &metadataTemplate{}
+	templated := false
+	for _, pair := range strings.Split(metadata, "&") {
+		keyvalue := strings.SplitN(pair, "=", 2)
+		if len(keyvalue) != 2 || keyvalue[0] == "" {
+			return nil, fmt.Errorf("Invalid metadata supplied: %s. Format must be: 'key1=value1&key2=value2...'", metadata)
+		}
+		value, err := template.New(keyvalue[0]).Funcs(metadataFuncs).Parse(keyvalue[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid metadata template of %s: %v", keyvalue[0], err)
+		}
+		// references to unknown fields only fail when the template is executed
+		if err = value.Execute(&bytes.Buffer{}, metadataData{}); err != nil {
+			return nil, fmt.Errorf("Invalid metadata template of %s: %v", keyvalue[0], err)
+		}
+		templated = templated || strings.Contains(keyvalue[1], "{{")
+		m.keys = append(m.keys, keyvalue[0])
+		m.values = append(m.values, value)
+	}
+	if !templated {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// render returns the metadata of an object.
+func (m *metadataTemplate) render(data metadataData) map[string]*string {
+	metadata := make(map[string]*string, len(m.keys))
+	for i, key := range m.keys {
+		var value bytes.Buffer
+		// validated when parsing the arguments
+		m.values[i].Execute(&value, data)
+		rendered := value.String()
+		metadata[key] = &rendered
+	}
+	return metadata
+}
+
+// Returns the metadata of the object of the key, rendered from the templates if there are any.
+func objectMetadata(args *parameters, key string) map[string]*string {
+	if args.metadataTemplate == nil {
+		return parseMetadataString(args.metadata)
+	}
+	return args.metadataTemplate.render(metadataData{Key: key, Bucket: args.bucketname, WorkerID: args.workerId, Size: args.osize})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseMetadataTemplate(t *testing.T) {
+	m, err := parseMetadataTemplate("owner=s3tester&source={{.Key}}-{{.WorkerID}}&token={{random 8}}&blob={{blob 30}}")
+	if err != nil {
+		t.Fatalf("valid metadata templates should succeed: %v", err)
+	}
+
+	metadata := m.render(metadataData{Key: "testobject-7", WorkerID: 3})
+	if *metadata["owner"] != "s3tester" || *metadata["source"] != "testobject-7-3" {
+		t.Fatalf("Wrong rendered metadata: owner=%s source=%s", *metadata["owner"], *metadata["source"])
+	}
+
+	if len(*metadata["token"]) != 8 {
+		t.Fatalf("Expected a token of 8 characters but got %s", *metadata["token"])
+	}
+
+	if blob, err := base64.StdEncoding.DecodeString(*metadata["blob"]); err != nil || len(blob) != 30 {
+		t.Fatalf("Expected a blob of 30 bytes but got %s", *metadata["blob"])
+	}
+
+	if other := m.render(metadataData{Key: "testobject-7", WorkerID: 3}); *other["token"] == *metadata["token"] {
+		t.Fatalf("Expected a random token per object")
+	}
+
+	if m, _ := parseMetadataTemplate("key1=value1&key2=value2"); m != nil {
+		t.Fatalf("metadata without templates should give nil: %+v", m)
+	}
+
+	for _, invalid := range []string{"key1", "=value", "key={{.Unknown}}", "key={{random"} {
+		if _, err := parseMetadataTemplate(invalid); err == nil {
+			t.Fatalf("metadata %q should fail", invalid)
+		}
+	}
+}
+
+func TestObjectMetadata(t *testing.T) {
+	args := &parameters{metadata: "key1=value1"}
+	if metadata := objectMetadata(args, "k"); *metadata["key1"] != "value1" {
+		t.Fatalf("Wrong metadata: %v", metadata)
+	}
+
+	args.metadataTemplate, _ = parseMetadataTemplate("key1={{.Bucket}}/{{.Key}}")
+	args.bucketname = "test"
+	if metadata := objectMetadata(args, "k"); *metadata["key1"] != "test/k" {
+		t.Fatalf("Wrong rendered metadata: %s", *metadata["key1"])
+	}
+}
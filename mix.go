@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The operations a mix can be made of. Each of them sends a single request for a key of the worker.
+var mixOperations = []string{"put", "get", "head", "updatemeta", "copy", "puttagging", "gettagging", "deletetagging", "delete"}
+
+// operationMix holds the operations of a mixed workload and their weights.
+type operationMix struct {
+	operations []string
+	weights    []int
+	total      int
+}
+
+// Parses a weighted mix of operations like get:70,put:20,delete:5,head:5. The weights are relative and don't have to
+// add up to 100. Returns nil if no mix is given.
+func parseMix(operations string) (*operationMix, error) {
+	if operations == "" {
+		return nil, nil
+	}
+	mix := &operationMix{}
+	for _, entry := range strings.Split(operations, ",") {
+		i := strings.Index(entry, ":")
+		if i < 0 {
+			return nil, errors.New("mix must be formatted like get:70,put:20,delete:5,head:5: " + entry)
+		}
+		op := entry[:i]
+		weight, err := strconv.Atoi(entry[i+1:])
+		if err != nil || weight < 1 {
+			return nil, errors.New("mix weights must be integers >= 1: " + entry)
+		}
+		if !validMixOperation(op) {
+			return nil, errors.New("mix operations must be some of " + strings.Join(mixOperations, ", ") + ": " + op)
+		}
+		for _, o := range mix.operations {
+			if o == op {
+				return nil, errors.New("mix operation " + op + " is listed more than once")
+			}
+		}
+		mix.operations = append(mix.operations, op)
+		mix.weights = append(mix.weights, weight)
+		mix.total += weight
+	}
+	return mix, nil
+}
+
+func validMixOperation(op string) bool {
+	for _, o := range mixOperations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns the operation of the next request, given a random number generator like rand.Intn.
+func (m *operationMix) pick(intn func(int) int) string {
+	n := intn(m.total)
+	for i, weight := range m.weights {
+		if n < weight {
+			return m.operations[i]
+		}
+		n -= weight
+	}
+	return m.operations[len(m.operations)-1]
+}
+
+// writes returns whether the mix writes objects. It is safe to call on a nil mix.
+func (m *operationMix) writes() bool {
+	if m == nil {
+		return false
+	}
+	for _, op := range m.operations {
+		if isWriteOperation(op) {
+			return true
+		}
+	}
+	return false
+}
+
+// share returns the expected number of the given number of requests that are sent as each operation.
+func (m *operationMix) share(count int64) map[string]int64 {
+	shares := make(map[string]int64, len(m.operations))
+	for i, op := range m.operations {
+		shares[op] = count * int64(m.weights[i]) / int64(m.total)
+	}
+	return shares
+}
+
+// operationResult holds the requests of one operation of a mixed workload.
+type operationResult struct {
+	*latencyResult
+	Failcount int   `json:"failedRequests"`
+	Bytes     int64 `json:"totalBytes"`
+}
+
+func (this *result) recordOperation(op string, l time.Duration, bytes int64, failed bool) {
+	if this.OperationResults == nil {
+		this.OperationResults = make(map[string]*operationResult)
+	}
+	s, ok := this.OperationResults[op]
+	if !ok {
+		s = &operationResult{latencyResult: NewLatencyResult()}
+		this.OperationResults[op] = s
+	}
+	s.record(l)
+	s.Bytes += bytes
+	if failed {
+		s.Failcount++
+	}
+}
+
+func (this *result) mergeOperationResults(other *result) {
+	for op, o := range other.OperationResults {
+		if this.OperationResults == nil {
+			this.OperationResults = make(map[string]*operationResult)
+		}
+		s, ok := this.OperationResults[op]
+		if !ok {
+			s = &operationResult{}
+			this.OperationResults[op] = s
+		}
+		s.latencyResult = s.latencyResult.merge(o.latencyResult)
+		s.Failcount += o.Failcount
+		s.Bytes += o.Bytes
+	}
+}
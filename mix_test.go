@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("get:70,put:20,delete:5,head:5")
+	if err != nil || !reflect.DeepEqual(mix.operations, []string{"get", "put", "delete", "head"}) || mix.total != 100 {
+		t.Fatalf("Wrong mix: %+v (%v)", mix, err)
+	}
+
+	for _, invalid := range []string{"get", "get:0", "get:x", "list:10", "get:1,get:2"} {
+		if _, err := parseMix(invalid); err == nil {
+			t.Fatalf("Expected %s to be rejected", invalid)
+		}
+	}
+
+	if mix, err := parseMix(""); mix != nil || err != nil {
+		t.Fatalf("Expected no mix but got %+v (%v)", mix, err)
+	}
+}
+
+func TestPickMixOperation(t *testing.T) {
+	mix, _ := parseMix("get:70,put:20,delete:5,head:5")
+	counts := make(map[string]int)
+	for n := 0; n < mix.total; n++ {
+		counts[mix.pick(func(int) int { return n })]++
+	}
+
+	expected := map[string]int{"get": 70, "put": 20, "delete": 5, "head": 5}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("Expected %v but picked %v", expected, counts)
+	}
+
+	if shares := mix.share(1000); !reflect.DeepEqual(shares, map[string]int64{"get": 700, "put": 200, "delete": 50, "head": 50}) {
+		t.Fatalf("Wrong shares of the requests: %v", shares)
+	}
+}
+
+func TestMixWrites(t *testing.T) {
+	reads, _ := parseMix("get:1,head:1")
+	writes, _ := parseMix("get:1,put:1")
+	var none *operationMix
+	if reads.writes() || !writes.writes() || none.writes() {
+		t.Fatalf("Only mixes with put should write")
+	}
+}
+
+func TestMergeOperationResults(t *testing.T) {
+	r1, r2, merged := NewResult(), NewResult(), NewResult()
+	r1.recordOperation("get", time.Millisecond, 100, false)
+	r2.recordOperation("get", 3*time.Millisecond, 100, true)
+	r2.recordOperation("put", time.Millisecond, 50, false)
+	mergeResult(&merged, &r1)
+	mergeResult(&merged, &r2)
+
+	get, put := merged.OperationResults["get"], merged.OperationResults["put"]
+	if get.Count != 2 || get.Failcount != 1 || get.Bytes != 200 || put.Count != 1 || put.Bytes != 50 {
+		t.Fatalf("Wrong merged operation results: get %+v, put %+v", get, put)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// namespaceTree names the keys of a tree of directories, to build the hierarchical namespaces that list, browse and
+// crawl benchmarks run against. Every directory of the depth levels under the prefix holds files objects, and the ones
+// above the last level fanout subdirectories.
+type namespaceTree struct {
+	depth, fanout, files int
+	// the number of directories of every level, from the first
+	levels []int64
+	// zero padded width of the directory and file numbers, so that the keys are listed in the order of their numbers
+	dirWidth, fileWidth int
+}
+
+func newNamespaceTree(depth, fanout, files int) (*namespaceTree, error) {
+	if depth < 1 || fanout < 1 || files < 1 {
+		return nil, errors.New("depth, fanout and files-per-dir must be at least 1")
+	}
+	t := &namespaceTree{depth: depth, fanout: fanout, files: files}
+	t.dirWidth = len(strconv.Itoa(fanout - 1))
+	t.fileWidth = len(strconv.Itoa(files - 1))
+	dirs := int64(1)
+	for level := 0; level < depth; level++ {
+		if dirs > math.MaxInt32/int64(fanout) {
+			return nil, errors.New("the tree has too many directories, lower depth or fanout")
+		}
+		dirs *= int64(fanout)
+		t.levels = append(t.levels, dirs)
+	}
+	if t.directories() > math.MaxInt32/int64(files) {
+		return nil, errors.New("the tree has too many objects, lower depth, fanout or files-per-dir")
+	}
+	return t, nil
+}
+
+// directories returns the number of directories of the tree.
+func (t *namespaceTree) directories() int64 {
+	var dirs int64
+	for _, n := range t.levels {
+		dirs += n
+	}
+	return dirs
+}
+
+// objects returns the number of objects of the tree.
+func (t *namespaceTree) objects() int64 {
+	return t.directories() * int64(t.files)
+}
+
+// key returns the n-th key of the tree under the prefix, like <prefix>/d3/d7/f042. The keys are numbered directory by
+// directory, a level after the other, so consecutive keys are in the same directory. Numbers past the last key start
+// over at the first.
+func (t *namespaceTree) key(prefix string, n int64) string {
+	n %= t.objects()
+	dir, file := n/int64(t.files), n%int64(t.files)
+	level := 0
+	for dir >= t.levels[level] {
+		dir -= t.levels[level]
+		level++
+	}
+	path := make([]string, level+1)
+	for i := level; i >= 0; i-- {
+		path[i] = fmt.Sprintf("d%0*d", t.dirWidth, dir%int64(t.fanout))
+		dir /= int64(t.fanout)
+	}
+	return prefix + "/" + strings.Join(path, "/") + fmt.Sprintf("/f%0*d", t.fileWidth, file)
+}
+
+// Parses a size in bytes, with an optional k, m or g suffix for KiB, MiB or GiB, like 4k.
+func parseTreeSize(size string) (int64, error) {
+	if size == "" {
+		return 0, errors.New("size must be given")
+	}
+	multiplier := int64(1)
+	switch strings.ToLower(size[len(size)-1:]) {
+	case "k":
+		multiplier = 1 << 10
+	case "m":
+		multiplier = 1 << 20
+	case "g":
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		size = size[:len(size)-1]
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/multiplier {
+		return 0, errors.New("size must be bytes or a number with a k, m or g suffix, like 4k")
+	}
+	return n * multiplier, nil
+}
+
+// The flags of a put run that mktree sets itself or that name the keys differently.
+var mktreeExcludedFlags = []string{"operation", "requests", "duration", "mix", "workload", "key-template", "keys-from-file", "overwrite", "overwrite-keys", "replay-accesslog", "replay-trace", "phases", "bench-suite"}
+
+// parseMakeTree parses the command line of the mktree command, the flags of the tree and any flag of a put run like the
+// endpoint, bucket, prefix and concurrency. The run puts every object of the tree once, a few more times if the
+// concurrency doesn't divide the objects.
+func parseMakeTree(cmdline []string) (parameters, error) {
+	flags := flag.NewFlagSet("mktree", flag.ContinueOnError)
+	depth := flags.Int("depth", 3, "Levels of directories under prefix")
+	fanout := flags.Int("fanout", 10, "Subdirectories of every directory above the last level")
+	files := flags.Int("files-per-dir", 100, "Objects in every directory")
+	size := flags.String("size", "4k", "Object size in bytes, or with a k, m or g suffix for KiB, MiB or GiB")
+
+	// the flags of the tree are parsed here and the rest by the put run
+	var treeArgs, runArgs []string
+	for i := 0; i < len(cmdline); i++ {
+		name := strings.SplitN(strings.TrimLeft(cmdline[i], "-"), "=", 2)[0]
+		if !strings.HasPrefix(cmdline[i], "-") || flags.Lookup(name) == nil {
+			runArgs = append(runArgs, cmdline[i])
+			continue
+		}
+		treeArgs = append(treeArgs, cmdline[i])
+		if !strings.Contains(cmdline[i], "=") && i+1 < len(cmdline) {
+			i++
+			treeArgs = append(treeArgs, cmdline[i])
+		}
+	}
+	if err := flags.Parse(treeArgs); err != nil {
+		return parameters{}, err
+	}
+	for _, arg := range runArgs {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		for _, excluded := range mktreeExcludedFlags {
+			if name == excluded {
+				return parameters{}, fmt.Errorf("mktree cannot be combined with %s", excluded)
+			}
+		}
+	}
+
+	tree, err := newNamespaceTree(*depth, *fanout, *files)
+	if err != nil {
+		return parameters{}, err
+	}
+	osize, err := parseTreeSize(*size)
+	if err != nil {
+		return parameters{}, err
+	}
+
+	args, err := parse(append(runArgs, "-operation=put", "-requests="+strconv.FormatInt(tree.objects(), 10), "-size="+strconv.FormatInt(osize, 10)))
+	if err != nil {
+		return parameters{}, err
+	}
+	if args.concurrency < 1 {
+		return parameters{}, errors.New("mktree needs a concurrency of at least 1")
+	}
+	// round the requests up so that every worker puts as many objects and every object is put
+	concurrency := int64(args.concurrency)
+	args.nrequests.value = int((tree.objects() + concurrency - 1) / concurrency * concurrency)
+	args.tree = tree
+	return args, nil
+}
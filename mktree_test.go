@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceTreeKeys(t *testing.T) {
+	tree, err := newNamespaceTree(2, 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.directories() != 12 || tree.objects() != 120 {
+		t.Fatalf("Expected 12 directories with 120 objects but got %d and %d", tree.directories(), tree.objects())
+	}
+
+	expected := map[int64]string{0: "tree/d0/f0", 9: "tree/d0/f9", 10: "tree/d1/f0", 29: "tree/d2/f9", 30: "tree/d0/d0/f0", 45: "tree/d0/d1/f5", 119: "tree/d2/d2/f9", 120: "tree/d0/f0"}
+	for n, key := range expected {
+		if got := tree.key("tree", n); got != key {
+			t.Fatalf("Expected key %d to be %s but got %s", n, key, got)
+		}
+	}
+
+	keys := make(map[string]bool)
+	for n := int64(0); n < tree.objects(); n++ {
+		keys[tree.key("tree", n)] = true
+	}
+	if len(keys) != 120 {
+		t.Fatalf("Expected 120 distinct keys but got %d", len(keys))
+	}
+
+	wide, _ := newNamespaceTree(1, 12, 100)
+	if key := wide.key("p", 305); key != "p/d03/f05" {
+		t.Fatalf("Expected the numbers to be padded but got %s", key)
+	}
+
+	for _, invalid := range [][]int{{0, 10, 100}, {3, 0, 100}, {3, 10, 0}, {10, 100, 100}} {
+		if _, err := newNamespaceTree(invalid[0], invalid[1], invalid[2]); err == nil {
+			t.Fatalf("Expected the tree %v to be rejected", invalid)
+		}
+	}
+}
+
+func TestParseTreeSize(t *testing.T) {
+	for size, expected := range map[string]int64{"0": 0, "4096": 4096, "4k": 4096, "4K": 4096, "2m": 2 << 20, "1g": 1 << 30} {
+		if n, err := parseTreeSize(size); err != nil || n != expected {
+			t.Fatalf("Expected %s to be %d bytes but got %d (%v)", size, expected, n, err)
+		}
+	}
+	for _, invalid := range []string{"", "k", "4kb", "-1", "x"} {
+		if _, err := parseTreeSize(invalid); err == nil {
+			t.Fatalf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestParseMakeTree(t *testing.T) {
+	args, err := parseMakeTree([]string{"-depth", "2", "-fanout=3", "-files-per-dir", "10", "-size", "4k", "-concurrency=8", "-prefix=tree"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.optype != "put" || args.osize != 4096 || args.concurrency != 8 || args.tree == nil {
+		t.Fatalf("Unexpected run %s %d %d", args.optype, args.osize, args.concurrency)
+	}
+	// 120 objects rounded up to a multiple of the concurrency
+	if args.nrequests.value != 120 {
+		t.Fatalf("Expected 120 requests but got %d", args.nrequests.value)
+	}
+	if args, _ = parseMakeTree([]string{"-depth=2", "-fanout=3", "-files-per-dir=10", "-concurrency=16"}); args.nrequests.value != 128 {
+		t.Fatalf("Expected 128 requests but got %d", args.nrequests.value)
+	}
+	if key := numberedKey(&args, 119); !strings.HasSuffix(key, "/d2/d2/f9") {
+		t.Fatalf("Expected the keys of the tree but got %s", key)
+	}
+
+	for _, cmdline := range [][]string{
+		{"-operation=get"},
+		{"-requests", "10"},
+		{"-depth=0"},
+		{"-size=4x"},
+		{"-depth=1", "-fanout=1", "-files-per-dir=1", "-concurrency=2"},
+	} {
+		if _, err := parseMakeTree(cmdline); err == nil {
+			t.Fatalf("Expected %v to be rejected", cmdline)
+		}
+	}
+}
+
+func TestMakeTreeExcludedFlags(t *testing.T) {
+	var flags *flag.FlagSet
+	defer func(f func() *flag.FlagSet) { newFlagSet = f }(newFlagSet)
+	newFlagSet = func() *flag.FlagSet {
+		flags = flag.NewFlagSet("s3tester", flag.ContinueOnError)
+		return flags
+	}
+	if _, err := parse([]string{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the names must be the flags of a run, or the flags they stand for are passed on to the put run unchecked
+	for _, name := range mktreeExcludedFlags {
+		if flags.Lookup(name) == nil {
+			t.Fatalf("%s is not a flag of a run", name)
+		}
+	}
+}
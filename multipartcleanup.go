@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// AbortIncompleteUploads lists every in-progress multipart upload under the prefix and aborts them, concurrency of
+// them at a time. Returns the number of uploads aborted and the number that failed to be aborted, which are logged.
+// Listing stops at the first page that fails.
+func AbortIncompleteUploads(svc s3iface.S3API, bucket, prefix string, concurrency int) (aborted, failed int64, err error) {
+	uploads := make(chan *s3.MultipartUpload, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for upload := range uploads {
+				_, err := svc.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+					Bucket:   aws.String(bucket),
+					Key:      upload.Key,
+					UploadId: upload.UploadId,
+				})
+				if err != nil {
+					atomic.AddInt64(&failed, 1)
+					log.Printf("Failed aborting multipart upload %s of '%s/%s': %v", aws.StringValue(upload.UploadId), bucket, aws.StringValue(upload.Key), err)
+					continue
+				}
+				atomic.AddInt64(&aborted, 1)
+			}
+		}()
+	}
+
+	params := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		var page *s3.ListMultipartUploadsOutput
+		if page, err = svc.ListMultipartUploads(params); err != nil {
+			break
+		}
+		for _, upload := range page.Uploads {
+			uploads <- upload
+		}
+		if !aws.BoolValue(page.IsTruncated) {
+			break
+		}
+		params.KeyMarker = page.NextKeyMarker
+		params.UploadIdMarker = page.NextUploadIdMarker
+	}
+	close(uploads)
+	wg.Wait()
+
+	return aborted, failed, err
+}
+
+// abortAllIncomplete runs the abort-all-incomplete cleanup against the first endpoint and returns whether it succeeded.
+func abortAllIncomplete(args parameters) bool {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		log.Printf("Failed loading credentials: %v", err)
+		return false
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+
+	aborted, failed, err := AbortIncompleteUploads(svc, args.bucketname, args.objectprefix, args.concurrency)
+	if err != nil {
+		log.Printf("Failed listing multipart uploads under '%s/%s': %v", args.bucketname, args.objectprefix, err)
+	}
+	log.Printf("Aborted %d incomplete multipart uploads under '%s/%s', %d failed", aborted, args.bucketname, args.objectprefix, failed)
+	return err == nil && failed == 0
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestAbortIncompleteUploads(t *testing.T) {
+	var mu sync.Mutex
+	aborted := make(map[string]bool)
+
+	handler := func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.ListMultipartUploadsInput:
+			if *i.Prefix != "mpu" {
+				t.Errorf("Expected prefix: %s but got: %s", "mpu", *i.Prefix)
+			}
+			// two pages of three uploads, one of which fails to be aborted
+			first := 0
+			if i.KeyMarker != nil {
+				first = 3
+			}
+			page := &s3.ListMultipartUploadsOutput{IsTruncated: aws.Bool(first == 0), NextKeyMarker: aws.String("mpu-2"), NextUploadIdMarker: aws.String("upload-2")}
+			for n := first; n < first+3; n++ {
+				id := "upload-" + strconv.Itoa(n)
+				if n == 4 {
+					id = "fail"
+				}
+				page.Uploads = append(page.Uploads, &s3.MultipartUpload{Key: aws.String("mpu-" + strconv.Itoa(n)), UploadId: aws.String(id)})
+			}
+			return page
+		case *s3.AbortMultipartUploadInput:
+			mu.Lock()
+			aborted[*i.UploadId] = true
+			mu.Unlock()
+		}
+		return in
+	}
+
+	done, failed, err := AbortIncompleteUploads(NewMockS3Client(handler), "b", "mpu", 2)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if done != 5 || failed != 1 || len(aborted) != 6 {
+		t.Fatalf("Expected 5 uploads aborted and 1 failed but got %d and %d of %v", done, failed, aborted)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -11,8 +12,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
@@ -36,8 +41,24 @@ func Options(hclient *http.Client, endpoint string) error {
 	return nil
 }
 
-func Put(svc s3iface.S3API, bucket, key, tagging, storageClass string, size int64, metadata map[string]*string) error {
-	obj := NewDummyReader(size, key)
+// Returns the number of bytes written, which differs from size when payloads come from files.
+func Put(svc s3iface.S3API, bucket, key, tagging, storageClass string, size int64, metadata map[string]*string, payload payloadOptions) (int64, error) {
+	var obj io.ReadSeeker
+	if payload.files != nil {
+		file, fileSize, closeFile, err := payload.files.open()
+		if err != nil {
+			return 0, err
+		}
+		defer closeFile()
+		obj, size = file, fileSize
+	} else if payload.stream != nil {
+		var err error
+		if obj, err = payload.stream.next(size, payload.streamWaits); err != nil {
+			return 0, err
+		}
+	} else {
+		obj = NewPayloadReader(size, key, payload)
+	}
 
 	params := &s3.PutObjectInput{
 		Bucket:        aws.String(bucket),
@@ -52,9 +73,17 @@ func Put(svc s3iface.S3API, bucket, key, tagging, storageClass string, size int6
 		params.SetTagging(tagging)
 	}
 
-	_, err := svc.PutObject(params)
+	if payload.checksums != nil {
+		if err := payload.checksums.signPut(params); err != nil {
+			return 0, err
+		}
+	}
 
-	return err
+	if _, err := svc.PutObject(params); err != nil {
+		return 0, err
+	}
+
+	return size, nil
 }
 
 func parseTags(tags string) s3.Tagging {
@@ -93,6 +122,26 @@ func PutTagging(svc s3iface.S3API, bucket, key, tagging string) error {
 	return err
 }
 
+func GetTagging(svc s3iface.S3API, bucket, key string) error {
+	params := &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	_, err := svc.GetObjectTagging(params)
+
+	return err
+}
+
+func DeleteTagging(svc s3iface.S3API, bucket, key string) error {
+	params := &s3.DeleteObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	_, err := svc.DeleteObjectTagging(params)
+
+	return err
+}
+
 func UpdateMetadata(svc s3iface.S3API, bucket, key string, metadata map[string]*string) error {
 	params := &s3.CopyObjectInput{
 		Bucket:            aws.String(bucket),
@@ -106,111 +155,419 @@ func UpdateMetadata(svc s3iface.S3API, bucket, key string, metadata map[string]*
 	return err
 }
 
-func MultipartPut(svc s3iface.S3API, bucket, key, storageClass string, size, partSize int64, metadata map[string]*string) error {
-	// Because the object is uploaded in parts we need to generate part sized objects.
-	obj := NewDummyReader(partSize, key)
+// Copy copies the source object to the destination with a server side copy. The metadata directive is either
+// COPY, which keeps the metadata of the source, or REPLACE, which sets the given metadata instead. The copy is
+// written to the given storage class, or the default one if it is empty.
+func Copy(svc s3iface.S3API, bucket, key, destBucket, destKey, storageClass, metadataDirective string, metadata map[string]*string) error {
+	params := &s3.CopyObjectInput{
+		Bucket:            aws.String(destBucket),
+		Key:               aws.String(destKey),
+		CopySource:        aws.String(bucket + "/" + key),
+		MetadataDirective: aws.String(metadataDirective),
+	}
+	if metadataDirective == s3.MetadataDirectiveReplace {
+		params.Metadata = metadata
+	}
+	if storageClass != "" {
+		params.StorageClass = aws.String(storageClass)
+	}
+	_, err := svc.CopyObject(params)
+
+	return err
+}
+
+// Returns the destination key of a copy, which is the source key with the object prefix replaced by the copy prefix.
+func copyKey(key, prefix, copyPrefix string) string {
+	return copyPrefix + strings.TrimPrefix(key, prefix)
+}
+
+// MultipartPut uploads an object in parts of partSize, partConcurrency of them at a time, and returns the latency of every part upload.
+func MultipartPut(svc s3iface.S3API, bucket, key, tagging, storageClass string, size, partSize int64, partConcurrency int, metadata map[string]*string, payload payloadOptions) ([]time.Duration, error) {
+	return multipartUpload(svc, bucket, key, tagging, storageClass, size, partSize, partConcurrency, metadata, payload, true)
+}
+
+// Starts a multipart upload and uploads all of its parts but leaves it in progress, so that
+// there are uploads for the multipart listing operations to work on.
+func InitMultipart(svc s3iface.S3API, bucket, key, tagging, storageClass string, size, partSize int64, partConcurrency int, metadata map[string]*string, payload payloadOptions) ([]time.Duration, error) {
+	return multipartUpload(svc, bucket, key, tagging, storageClass, size, partSize, partConcurrency, metadata, payload, false)
+}
 
+func multipartUpload(svc s3iface.S3API, bucket, key, tagging, storageClass string, size, partSize int64, partConcurrency int, metadata map[string]*string, payload payloadOptions, complete bool) ([]time.Duration, error) {
 	params := &s3.CreateMultipartUploadInput{
 		Bucket:       aws.String(bucket),
 		Key:          aws.String(key),
 		StorageClass: &storageClass,
 		Metadata:     metadata,
 	}
+	if tagging != "" {
+		params.SetTagging(tagging)
+	}
+	if payload.checksums != nil {
+		params.ChecksumAlgorithm = aws.String(payload.checksums.Algorithm)
+	}
+	return uploadParts(svc, params, size, partSize, partConcurrency, complete, func(uploadId *string, partnum, offset, length int64) (*s3.CompletedPart, error) {
+		return uploadPart(svc, bucket, key, uploadId, partnum, length, payload)
+	})
+}
 
-	numparts := int64(math.Ceil(float64(size) / float64(partSize)))
+// Limits of S3 multipart uploads.
+const (
+	minPartSize  = 5 * (1 << 20)
+	maxPartSize  = 5 * (1 << 30)
+	maxPartCount = 10000
+)
 
-	// this is for if the last part won't be the same size
-	lastobj := obj
-	if numparts != size/partSize {
-		lastobj = NewDummyReader(size-partSize*(numparts-1), key)
+// Returns the smallest whole MiB part size of at least 5MiB that uploads an object of the given size in at most 10000 parts.
+// The result is larger than the maximum part size if the object is too large for a multipart upload.
+func autoPartSize(size int64) int64 {
+	partSize := int64(math.Ceil(float64(size)/maxPartCount/(1<<20))) << 20
+	if partSize < minPartSize {
+		return minPartSize
 	}
+	return partSize
+}
+
+// Creates a multipart upload and uploads its parts with the upload function, partConcurrency of them at a time. The upload is
+// completed if complete is set and aborted if a part fails. Returns the latency of every part that was uploaded.
+func uploadParts(svc s3iface.S3API, params *s3.CreateMultipartUploadInput, size, partSize int64, partConcurrency int, complete bool, upload func(uploadId *string, partnum, offset, length int64) (*s3.CompletedPart, error)) ([]time.Duration, error) {
+	bucket, key := aws.StringValue(params.Bucket), aws.StringValue(params.Key)
+	numparts := int64(math.Ceil(float64(size) / float64(partSize)))
 
-	var output *s3.CreateMultipartUploadOutput
 	output, err := svc.CreateMultipartUpload(params)
+	if err != nil {
+		return nil, err
+	}
+	uploadId := output.UploadId
+
+	partdata := make([]*s3.CompletedPart, numparts)
+	latencies := make([]time.Duration, numparts)
+	errs := make([]error, numparts)
+	// closed once a part upload failed
+	failed := make(chan struct{})
+	var failOnce sync.Once
 
-	if err == nil {
-		uploadId := output.UploadId
-		partdata := make([]*s3.CompletedPart, 0, numparts)
+	if partConcurrency < 1 {
+		partConcurrency = 1
+	}
+	partnums := make(chan int64)
+	var wg sync.WaitGroup
+	for i := 0; i < partConcurrency && int64(i) < numparts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for partnum := range partnums {
+				// a part handed out while another one failed isn't uploaded
+				select {
+				case <-failed:
+					continue
+				default:
+				}
+				// this is for if the last part won't be the same size
+				length := partSize
+				if partnum == numparts {
+					length = size - partSize*(numparts-1)
+				}
+				start := time.Now()
+				partdata[partnum-1], errs[partnum-1] = upload(uploadId, partnum, partSize*(partnum-1), length)
+				latencies[partnum-1] = time.Since(start)
+				if errs[partnum-1] != nil {
+					failOnce.Do(func() { close(failed) })
+				}
+			}
+		}()
+	}
 
-		uparams := &s3.UploadPartInput{
-			Bucket:        aws.String(bucket),
-			Key:           aws.String(key),
-			ContentLength: &partSize,
-			Body:          obj,
-			UploadId:      uploadId,
+	// Don't upload any more parts once a part upload has failed.
+send:
+	for partnum := int64(1); partnum <= numparts; partnum++ {
+		select {
+		case partnums <- partnum:
+		case <-failed:
+			break send
 		}
+	}
+	close(partnums)
+	wg.Wait()
 
-		for partnum := int64(1); partnum <= numparts-1; partnum++ {
-			/* In a more realistic scenario we would want to upload parts concurrently,
-			   but concurrency is already one of the test options... might want to figure out
-			   if/how this should consider the concurrency option before adding concurrency here.
-			*/
-			uparams.SetPartNumber(partnum)
+	uploaded := make([]time.Duration, 0, numparts)
+	for i := range partdata {
+		if errs[i] != nil && err == nil {
+			err = errs[i]
+		}
+		if partdata[i] != nil {
+			uploaded = append(uploaded, latencies[i])
+		}
+	}
 
-			var uoutput *s3.UploadPartOutput
-			uoutput, err = svc.UploadPart(uparams)
-			if err != nil {
-				break
-			}
-			part := &s3.CompletedPart{}
-			part.SetPartNumber(partnum)
-			part.SetETag(*uoutput.ETag)
-			partdata = append(partdata, part)
-			// We have to reset the object, since it is re-used for all
-			// parts and its size is set to be equal to that of a single part.
-			// If we don't reset the offset the second part read will get an EOF and have
-			// a zero byte body.
-			obj.Seek(0, io.SeekStart)
-		}
-
-		// Don't upload the last part if we had any part upload failures.
-		if err == nil {
-			uparams.SetBody(lastobj)
-			uparams.SetContentLength(lastobj.Size())
-			uparams.SetPartNumber(numparts)
-			var uoutput *s3.UploadPartOutput
-			uoutput, err = svc.UploadPart(uparams)
-			if err == nil {
-				part := &s3.CompletedPart{}
-				part.SetPartNumber(numparts)
-				part.SetETag(*uoutput.ETag)
-				partdata = append(partdata, part)
-			}
+	if err != nil {
+		aparams := &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			UploadId: uploadId,
 		}
+		svc.AbortMultipartUpload(aparams)
+	} else if complete {
+		cparams := &s3.CompleteMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			UploadId: uploadId,
+		}
+
+		cpartdata := &s3.CompletedMultipartUpload{Parts: partdata}
+		cparams.SetMultipartUpload(cpartdata)
+
+		_, err = svc.CompleteMultipartUpload(cparams)
+	}
 
+	return uploaded, err
+}
+
+// Every part holds the data of an object of the part's length generated from the key, so parts verify independently of each other.
+func uploadPart(svc s3iface.S3API, bucket, key string, uploadId *string, partnum, length int64, payload payloadOptions) (*s3.CompletedPart, error) {
+	if partnum > 1 {
+		// only the start of the object holds the write generation
+		payload.generations = nil
+	}
+	uparams := &s3.UploadPartInput{
+		Bucket:        aws.String(bucket),
+		Key:           aws.String(key),
+		ContentLength: aws.Int64(length),
+		Body:          NewPayloadReader(length, key, payload),
+		UploadId:      uploadId,
+		PartNumber:    aws.Int64(partnum),
+	}
+	if payload.checksums != nil {
+		if err := payload.checksums.signPart(uparams); err != nil {
+			return nil, err
+		}
+	}
+
+	uoutput, err := svc.UploadPart(uparams)
+	if err != nil {
+		return nil, err
+	}
+	part := &s3.CompletedPart{}
+	part.SetPartNumber(partnum)
+	part.SetETag(*uoutput.ETag)
+	// the checksums of the parts are required to complete an upload that was created with a checksum algorithm
+	part.ChecksumCRC32, part.ChecksumCRC32C, part.ChecksumSHA1, part.ChecksumSHA256 = uoutput.ChecksumCRC32, uoutput.ChecksumCRC32C, uoutput.ChecksumSHA1, uoutput.ChecksumSHA256
+	return part, nil
+}
+
+// MultipartCopy copies an object that is too large for a single CopyObject with a multipart upload of UploadPartCopy
+// requests of partSize bytes, partConcurrency of them at a time. The source is looked up with a HEAD first for its size
+// and, with the COPY metadata directive, its metadata. Returns the latency of every part that was copied.
+func MultipartCopy(svc s3iface.S3API, bucket, key, destBucket, destKey, storageClass, metadataDirective string, metadata map[string]*string, partSize int64, partConcurrency int) ([]time.Duration, error) {
+	head, err := svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params := &s3.CreateMultipartUploadInput{
+		Bucket:   aws.String(destBucket),
+		Key:      aws.String(destKey),
+		Metadata: metadata,
+	}
+	if metadataDirective == s3.MetadataDirectiveCopy {
+		params.Metadata = head.Metadata
+		params.ContentType = head.ContentType
+	}
+	if storageClass != "" {
+		params.StorageClass = aws.String(storageClass)
+	}
+	return uploadParts(svc, params, aws.Int64Value(head.ContentLength), partSize, partConcurrency, true, func(uploadId *string, partnum, offset, length int64) (*s3.CompletedPart, error) {
+		return uploadPartCopy(svc, bucket, key, destBucket, destKey, uploadId, partnum, offset, length)
+	})
+}
+
+func uploadPartCopy(svc s3iface.S3API, bucket, key, destBucket, destKey string, uploadId *string, partnum, offset, length int64) (*s3.CompletedPart, error) {
+	params := &s3.UploadPartCopyInput{
+		Bucket:          aws.String(destBucket),
+		Key:             aws.String(destKey),
+		CopySource:      aws.String(bucket + "/" + key),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+		UploadId:        uploadId,
+		PartNumber:      aws.Int64(partnum),
+	}
+
+	output, err := svc.UploadPartCopy(params)
+	if err != nil {
+		return nil, err
+	}
+	part := &s3.CompletedPart{}
+	part.SetPartNumber(partnum)
+	if output.CopyPartResult != nil {
+		part.SetETag(aws.StringValue(output.CopyPartResult.ETag))
+	}
+	return part, nil
+}
+
+// ListObjects lists one page of the objects under the prefix with the V1 (marker based) or V2 (continuation token based) API,
+// starting at the given marker. With a delimiter, keys are rolled up into common prefixes. maxKeys limits the size of the page,
+// 0 uses the server's default. It returns the marker of the next page, which is empty once the last page has been listed,
+// and the number of keys and common prefixes on the page.
+func ListObjects(svc s3iface.S3API, bucket, prefix, delimiter, marker, api string, maxKeys int64) (string, int, error) {
+	if api == "v1" {
+		params := &s3.ListObjectsInput{
+			Bucket: aws.String(bucket),
+			Prefix: aws.String(prefix),
+		}
+		if delimiter != "" {
+			params.Delimiter = aws.String(delimiter)
+		}
+		if maxKeys > 0 {
+			params.MaxKeys = aws.Int64(maxKeys)
+		}
+		if marker != "" {
+			params.Marker = aws.String(marker)
+		}
+		out, err := svc.ListObjects(params)
 		if err != nil {
-			aparams := &s3.AbortMultipartUploadInput{
-				Bucket:   aws.String(bucket),
-				Key:      aws.String(key),
-				UploadId: uploadId,
-			}
-			svc.AbortMultipartUpload(aparams)
-		} else {
-			cparams := &s3.CompleteMultipartUploadInput{
-				Bucket:   aws.String(bucket),
-				Key:      aws.String(key),
-				UploadId: uploadId,
-			}
+			return "", 0, err
+		}
+		keys := len(out.Contents) + len(out.CommonPrefixes)
+		if !aws.BoolValue(out.IsTruncated) {
+			return "", keys, nil
+		}
+		// NextMarker is only returned when a delimiter is used, otherwise the next page starts after the last key.
+		if out.NextMarker != nil {
+			return *out.NextMarker, keys, nil
+		}
+		if len(out.Contents) == 0 {
+			return "", keys, nil
+		}
+		return aws.StringValue(out.Contents[len(out.Contents)-1].Key), keys, nil
+	}
 
-			cpartdata := &s3.CompletedMultipartUpload{Parts: partdata}
-			cparams.SetMultipartUpload(cpartdata)
+	params := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if delimiter != "" {
+		params.Delimiter = aws.String(delimiter)
+	}
+	if maxKeys > 0 {
+		params.MaxKeys = aws.Int64(maxKeys)
+	}
+	if marker != "" {
+		params.ContinuationToken = aws.String(marker)
+	}
+	out, err := svc.ListObjectsV2(params)
+	if err != nil {
+		return "", 0, err
+	}
+	keys := len(out.Contents) + len(out.CommonPrefixes)
+	if !aws.BoolValue(out.IsTruncated) {
+		return "", keys, nil
+	}
+	return aws.StringValue(out.NextContinuationToken), keys, nil
+}
 
-			_, err = svc.CompleteMultipartUpload(cparams)
+// ListAllObjects lists every page of the objects under the prefix. It returns the latency of every page that was listed
+// and the total number of keys and common prefixes.
+func ListAllObjects(svc s3iface.S3API, bucket, prefix, delimiter, api string, maxKeys int64) ([]time.Duration, int, error) {
+	var latencies []time.Duration
+	total := 0
+	marker := ""
+	for {
+		start := time.Now()
+		next, keys, err := ListObjects(svc, bucket, prefix, delimiter, marker, api, maxKeys)
+		latencies = append(latencies, time.Since(start))
+		total += keys
+		if err != nil || next == "" {
+			return latencies, total, err
 		}
+		marker = next
 	}
+}
+
+// Lists one page of the in-progress multipart uploads under the given prefix.
+func ListMultipartUploads(svc s3iface.S3API, bucket, prefix string) error {
+	params := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	_, err := svc.ListMultipartUploads(params)
 
 	return err
+}
+
+// Looks up the ids of the in-progress multipart uploads of the key, of which there is at least one.
+func uploadIds(svc s3iface.S3API, bucket, key string) ([]*string, error) {
+	uploads, err := svc.ListMultipartUploads(&s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []*string
+	for _, upload := range uploads.Uploads {
+		if aws.StringValue(upload.Key) == key {
+			ids = append(ids, upload.UploadId)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("No multipart upload in progress for %s/%s", bucket, key)
+	}
+	return ids, nil
+}
+
+// Lists one page of the parts of the in-progress multipart upload of the key with the upload id, looked up with
+// uploadIds before.
+func ListParts(svc s3iface.S3API, bucket, key, uploadId string) error {
+	params := &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadId),
+	}
+	_, err := svc.ListParts(params)
+
+	return err
+}
 
+// Aborts the in-progress multipart uploads of the key. The upload ids are looked up first.
+func AbortMultipart(svc s3iface.S3API, bucket, key string) error {
+	ids, err := uploadIds(svc, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		params := &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(bucket),
+			Key:      aws.String(key),
+			UploadId: id,
+		}
+		if _, err = svc.AbortMultipartUpload(params); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func Get(svc s3iface.S3API, bucket, key, byteRange string, verify int, partSize int64) (int64, error) {
+func Get(svc s3iface.S3API, bucket, key, byteRange string, overrides responseOverrides, verify int, partSize int64, payload payloadOptions) (int64, error) {
+	return GetVersion(svc, bucket, key, "", byteRange, overrides, verify, partSize, payload)
+}
+
+// GetVersion reads the given version of an object, or its latest version if versionId is empty.
+func GetVersion(svc s3iface.S3API, bucket, key, versionId, byteRange string, overrides responseOverrides, verify int, partSize int64, payload payloadOptions) (int64, error) {
 	params := &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 		Range:  aws.String(byteRange),
 	}
+	if versionId != "" {
+		params.VersionId = aws.String(versionId)
+	}
+	overrides.apply(params)
 
-	out, err := identityGetObject(svc, params, verify, partSize)
+	out, err := identityGetObject(svc, params, verify, partSize, payload)
 	if err != nil {
 		return 0, err
 	}
@@ -218,6 +575,149 @@ func Get(svc s3iface.S3API, bucket, key, byteRange string, verify int, partSize
 	return *out.ContentLength, err
 }
 
+// Returns the offset of the range read by a rangeget request of length bytes from an object of the given size.
+// The fixed distribution always reads the start of the object, aligned picks a random multiple of the length
+// and unaligned a random byte offset.
+func rangeOffset(dist string, size, length int64, int63n func(int64) int64) int64 {
+	if length >= size {
+		return 0
+	}
+	switch dist {
+	case "aligned":
+		return int63n(size/length) * length
+	case "unaligned":
+		return int63n(size - length + 1)
+	}
+	return 0
+}
+
+// ParallelGet downloads an object the way the AWS SDK download manager does: the first ranged GET tells the size of the
+// object, then the remaining ranges are fetched by rangeConcurrency concurrent ranged GETs. With a threshold it works like
+// the transfer managers instead, which HEAD the object first and download objects up to the threshold with a single GET.
+// The data is verified range by range rather than reassembled in memory, so objects of many GB can be downloaded.
+// Returns the number of bytes downloaded.
+func ParallelGet(svc s3iface.S3API, bucket, key string, rangeSize, threshold int64, rangeConcurrency int, overrides responseOverrides, verify int, partSize int64, payload payloadOptions) (int64, error) {
+	var downloaded, size int64
+	first := int64(0)
+	if threshold > 0 {
+		head, err := svc.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+		if err != nil {
+			return 0, err
+		}
+		size = aws.Int64Value(head.ContentLength)
+		if size <= threshold {
+			return Get(svc, bucket, key, "", overrides, verify, partSize, payload)
+		}
+	} else {
+		out, err := getRange(svc, bucket, key, 0, rangeSize, overrides, verify, partSize, payload)
+		if err != nil {
+			return 0, err
+		}
+		downloaded = aws.Int64Value(out.ContentLength)
+
+		// a server that ignores the range returns the whole object without a Content-Range
+		size = contentRangeSize(aws.StringValue(out.ContentRange))
+		if size <= rangeSize {
+			return downloaded, nil
+		}
+		first = 1
+	}
+
+	numranges := int64(math.Ceil(float64(size) / float64(rangeSize)))
+	errs := make([]error, numranges)
+	var failed int32
+
+	ranges := make(chan int64)
+	var wg sync.WaitGroup
+	for i := 0; i < rangeConcurrency && int64(i) < numranges-first; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range ranges {
+				out, err := getRange(svc, bucket, key, index*rangeSize, rangeSize, overrides, verify, partSize, payload)
+				if err != nil {
+					errs[index] = err
+					atomic.StoreInt32(&failed, 1)
+					continue
+				}
+				atomic.AddInt64(&downloaded, aws.Int64Value(out.ContentLength))
+			}
+		}()
+	}
+
+	// Don't request any more ranges once a range has failed.
+	for index := first; index < numranges && atomic.LoadInt32(&failed) == 0; index++ {
+		ranges <- index
+	}
+	close(ranges)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return downloaded, err
+		}
+	}
+	return downloaded, nil
+}
+
+func getRange(svc s3iface.S3API, bucket, key string, start, length int64, overrides responseOverrides, verify int, partSize int64, payload payloadOptions) (*s3.GetObjectOutput, error) {
+	params := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", start, start+length-1)),
+	}
+	overrides.apply(params)
+	return identityGetObject(svc, params, verify, partSize, payload)
+}
+
+// ObjectVersions returns the version ids of an object, listing at most one page of versions.
+func ObjectVersions(svc s3iface.S3API, bucket, key string) ([]string, error) {
+	out, err := svc.ListObjectVersions(&s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []string
+	for _, version := range out.Versions {
+		if aws.StringValue(version.Key) == key {
+			versions = append(versions, aws.StringValue(version.VersionId))
+		}
+	}
+	if len(versions) == 0 {
+		return nil, errors.New("no versions found for key " + key)
+	}
+	return versions, nil
+}
+
+// ListVersions lists one page of the object versions and delete markers under the prefix, starting at the given key and
+// version id markers. It returns the markers of the next page, which are empty once the last page has been listed,
+// and the number of versions and delete markers on the page.
+func ListVersions(svc s3iface.S3API, bucket, prefix, keyMarker, versionIdMarker string, maxKeys int64) (string, string, int, error) {
+	params := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if maxKeys > 0 {
+		params.MaxKeys = aws.Int64(maxKeys)
+	}
+	if keyMarker != "" {
+		params.KeyMarker = aws.String(keyMarker)
+		params.VersionIdMarker = aws.String(versionIdMarker)
+	}
+	out, err := svc.ListObjectVersions(params)
+	if err != nil {
+		return "", "", 0, err
+	}
+	versions := len(out.Versions) + len(out.DeleteMarkers)
+	if !aws.BoolValue(out.IsTruncated) {
+		return "", "", versions, nil
+	}
+	return aws.StringValue(out.NextKeyMarker), aws.StringValue(out.NextVersionIdMarker), versions, nil
+}
+
 func Head(svc s3iface.S3API, bucket, key string) error {
 	params := &s3.HeadObjectInput{
 		Bucket: aws.String(bucket),
@@ -230,15 +730,66 @@ func Head(svc s3iface.S3API, bucket, key string) error {
 }
 
 func Delete(svc s3iface.S3API, bucket, key string) error {
+	return DeleteVersion(svc, bucket, key, "")
+}
+
+// DeleteVersion permanently deletes the given version of an object. Without a version id the latest version is deleted,
+// which adds a delete marker in a versioned bucket.
+func DeleteVersion(svc s3iface.S3API, bucket, key, versionId string) error {
 	params := &s3.DeleteObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	}
+	if versionId != "" {
+		params.VersionId = aws.String(versionId)
+	}
 	_, err := svc.DeleteObject(params)
 
 	return err
 }
 
+// MultiDelete deletes the keys with a single DeleteObjects request and returns the number of keys that were deleted.
+func MultiDelete(svc s3iface.S3API, bucket string, keys []string) (int, error) {
+	objects := make([]*s3.ObjectIdentifier, len(keys))
+	for i, key := range keys {
+		objects[i] = &s3.ObjectIdentifier{Key: aws.String(key)}
+	}
+	params := &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	}
+	out, err := svc.DeleteObjects(params)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(out.Errors) > 0 {
+		first := out.Errors[0]
+		return len(keys) - len(out.Errors), fmt.Errorf("%d of %d keys were not deleted, e.g. %s: %s", len(out.Errors), len(keys), aws.StringValue(first.Key), aws.StringValue(first.Code))
+	}
+	return len(keys), nil
+}
+
+// Presign generates a presigned URL for a GET or PUT of the object. Signing happens locally, no request is sent.
+func Presign(svc s3iface.S3API, bucket, key, method string, expiry time.Duration, overrides responseOverrides) (string, error) {
+	var req *request.Request
+	if method == "PUT" {
+		req, _ = svc.PutObjectRequest(&s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+	} else {
+		input := &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		}
+		// the response-* query parameters are signed into the URL
+		overrides.apply(input)
+		req, _ = svc.GetObjectRequest(input)
+	}
+	return req.Presign(expiry)
+}
+
 func parseMetadataString(metaString string) map[string]*string {
 	meta := make(map[string]*string)
 	if metaString != "" {
@@ -256,61 +807,131 @@ func parseMetadataString(metaString string) map[string]*string {
 }
 
 // Retrieves objects from Amazon S3.
-func identityGetObject(c s3iface.S3API, input *s3.GetObjectInput, verify int, partsize int64) (output *s3.GetObjectOutput, err error) {
+func identityGetObject(c s3iface.S3API, input *s3.GetObjectInput, verify int, partsize int64, payload payloadOptions) (output *s3.GetObjectOutput, err error) {
+	if payload.checksums != nil {
+		input.ChecksumMode = aws.String(s3.ChecksumModeEnabled)
+	}
 	req, out := c.GetObjectRequest(input)
 	output = out
 	req.HTTPRequest.Header.Set("Accept-Encoding", "identity")
+	sent := time.Now()
 	err = req.Send()
 	if err == nil && req.HTTPResponse.Body != nil {
-		if verify == 0 {
-			_, err = io.Copy(ioutil.Discard, req.HTTPResponse.Body)
+		received := newBodyReader(req.HTTPResponse.Body, payload.verifyETag)
+		body := io.Reader(received)
+		var checksum *checksumReader
+		if payload.checksums != nil {
+			checksum = payload.checksums.reader(body)
+			body = checksum
+		}
+		// A ranged GET starts at the offset the server says it returned.
+		start := contentRangeStart(req.HTTPResponse.Header.Get("Content-Range"))
+		if payload.generations != nil && start == 0 && input.VersionId == nil && req.HTTPResponse.ContentLength >= generationHeaderSize {
+			// an explicitly requested version is expected to be older than the latest one
+			var generation int64
+			generation, err = payload.generations.check(*input.Key, body)
+			start = generationHeaderSize
+			if err == nil && payload.freshness != nil && input.Range == nil {
+				// the rest of the body is verified against the data of its generation instead
+				payload.generations = nil
+				err = payload.freshness.check(*input.Key, generation, sent, body, payload)
+				verify = 0
+			}
+		}
+		// the expected data has no header to compare with
+		payload.generations = nil
+		if err == nil && verify == 0 {
+			_, err = io.Copy(ioutil.Discard, body)
 			if err != nil {
 				err = fmt.Errorf("Error while reading body of %s/%s. %v", *input.Bucket, *input.Key, err)
 			}
-		} else {
-			key := []byte(*input.Key)
-			buffer := make([]byte, 1024)
-			index := 0
-			var read int
-			var readError error = nil
-			keylen := len(key)
-			// keep reading until we reach EOF (or some other error)
-		loop:
-			for readError == nil {
-				read, readError = req.HTTPResponse.Body.Read(buffer)
-				for i := 0; i < read; i++ {
-					//deal with the retrieved data that comes from multipartput data, which repeat every partsize bytes
-					if verify == 2 && int64(index) == partsize {
-						index = 0
-					}
-
-					// Due to the performance optimizations for generating object data in generateDataFromKey the offset when validating the data
-					// on the read path needs to be modulo the block size passed to generateDataFromKey. This is because the keys can get cut off
-					// at block boundaries and start at the first character at the beginning of a new block. So for a key "abcd" with a block size
-					// of 3, and a 9 byte object we get "abc|abc|abc" (| are block boundaries) instead of fully repeating keys "abcdabcda" which was the previous behaviour.
-					//
-					// This uses a modulo optimization for powers of 2. To ge the modulo some value x if x is a power of two you can use
-					// val & (x-1). In this case we are taking modulo objectDataBlockSize.
-					//
-					// We can further optimize this call by dealing with larger blocks as opposed to single characters but it's probably not worth it right now
-					// since this is a special non-performance path that validates all data read.
-					offset := (index & (objectDataBlockSize - 1)) % keylen
-
-					if buffer[i] != key[offset] {
-						readError = errors.New("Retrieved data different from expected")
-						break loop
-					}
-					index++
+		} else if err == nil {
+			err = verifyObjectData(body, *input.Key, start, verify, partsize, payload)
+		}
+		req.HTTPResponse.Body.Close()
+		err = received.check(*input.Key, req.HTTPResponse.ContentLength, req.HTTPResponse.Header, err)
+		if err == nil && checksum != nil {
+			err = checksum.verify(*input.Key, req.HTTPResponse.Header)
+		}
+		if err == nil {
+			err = checkResponseOverrides(input, req.HTTPResponse.Header)
+		}
+	}
+	return
+}
+
+// Object data is generated deterministically from the key, so it is verified by regenerating the
+// expected bytes starting at the given offset of the object.
+func verifyObjectData(body io.Reader, key string, index int64, verify int, partsize int64, payload payloadOptions) error {
+	buffer := make([]byte, 1024)
+	expectedBuffer := make([]byte, len(buffer))
+	if verify == 2 {
+		index %= partsize
+	}
+	expected := NewPayloadReader(math.MaxInt64, key, payload)
+	expected.Seek(index, io.SeekStart)
+	var read int
+	var readError error = nil
+	// keep reading until we reach EOF (or some other error)
+	for readError == nil {
+		read, readError = body.Read(buffer)
+		for i := 0; i < read; {
+			n := read - i
+			//deal with the retrieved data that comes from multipartput data, which repeat every partsize bytes
+			if verify == 2 {
+				if index == partsize {
+					index = 0
+					expected.Seek(0, io.SeekStart)
+				}
+				if remaining := partsize - index; int64(n) > remaining {
+					n = int(remaining)
 				}
 			}
 
-			if readError != io.EOF {
-				err = readError
+			expected.Read(expectedBuffer[:n])
+			if !bytes.Equal(buffer[i:i+n], expectedBuffer[:n]) {
+				return errors.New("Retrieved data different from expected")
 			}
+			i += n
+			index += int64(n)
 		}
-		req.HTTPResponse.Body.Close()
 	}
-	return
+
+	if readError != io.EOF {
+		return readError
+	}
+	return nil
+}
+
+// Returns the first byte offset of a Content-Range response header of the form "bytes start-end/size",
+// or 0 if the response is not a partial one.
+func contentRangeStart(contentRange string) int64 {
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return 0
+	}
+	byteRange := strings.TrimPrefix(contentRange, "bytes ")
+	dash := strings.Index(byteRange, "-")
+	if dash <= 0 {
+		return 0
+	}
+	start, err := strconv.ParseInt(byteRange[:dash], 10, 64)
+	if err != nil || start < 0 {
+		return 0
+	}
+	return start
+}
+
+// Returns the complete size of the object given in a Content-Range header like "bytes 0-99/1000", or -1 if it is unknown.
+func contentRangeSize(contentRange string) int64 {
+	slash := strings.LastIndex(contentRange, "/")
+	if !strings.HasPrefix(contentRange, "bytes ") || slash < 0 {
+		return -1
+	}
+	size, err := strconv.ParseInt(contentRange[slash+1:], 10, 64)
+	if err != nil || size < 0 {
+		return -1
+	}
+	return size
 }
 
 func RestoreObject(svc s3iface.S3API, bucket string, key string, tier string, days int64) error {
@@ -335,6 +956,9 @@ func DispatchOperation(svc s3iface.S3API, hclient *http.Client, op, keyName stri
 	if args.reducedRedundancy {
 		sc = s3.StorageClassReducedRedundancy
 	}
+	if args.storageClass != "" {
+		sc = args.storageClass
+	}
 
 	switch op {
 	case "options":
@@ -342,26 +966,193 @@ func DispatchOperation(svc s3iface.S3API, hclient *http.Client, op, keyName stri
 			r.Failcount++
 		}
 	case "put":
-		if err = Put(svc, args.bucketname, keyName, args.tagging, sc, args.osize, parseMetadataString(args.metadata)); err == nil {
-			r.sumObjSize += args.osize
+		if args.mpuThreshold > 0 && args.osize > args.mpuThreshold {
+			var partLatencies []time.Duration
+			partLatencies, err = MultipartPut(svc, args.bucketname, keyName, args.tagging, sc, args.osize, args.partsize, args.partConcurrency, objectMetadata(args, keyName), args.payload)
+			r.recordPartLatencies(partLatencies)
+			r.MultipartCount++
+			if err == nil {
+				r.sumObjSize += args.osize
+			}
+			break
+		}
+		var writtenBytes int64
+		if writtenBytes, err = Put(svc, args.bucketname, keyName, args.tagging, sc, args.osize, objectMetadata(args, keyName), args.payload); err == nil {
+			r.sumObjSize += writtenBytes
 		}
 	case "puttagging":
 		err = PutTagging(svc, args.bucketname, keyName, args.tagging)
+	case "gettagging":
+		err = GetTagging(svc, args.bucketname, keyName)
+	case "deletetagging":
+		err = DeleteTagging(svc, args.bucketname, keyName)
+	case "putretention":
+		err = PutRetention(svc, args.bucketname, keyName, args.objectLock.mode, args.objectLock.retainUntilDate(time.Now()))
+	case "getretention":
+		err = GetRetention(svc, args.bucketname, keyName)
+	case "putlegalhold":
+		err = PutLegalHold(svc, args.bucketname, keyName, args.objectLock.legalHold)
+	case "getlegalhold":
+		err = GetLegalHold(svc, args.bucketname, keyName)
+	case "createbucket":
+		err = CreateBucket(svc, keyName, args.region)
+	case "deletebucket":
+		err = DeleteBucket(svc, keyName)
+	case "putlifecycle":
+		err = PutLifecycle(svc, args.bucketname, args.objectprefix)
+	case "getlifecycle":
+		err = GetLifecycle(svc, args.bucketname)
+	case "deletelifecycle":
+		err = DeleteLifecycle(svc, args.bucketname)
+	case "putpolicy":
+		err = PutPolicy(svc, args.bucketname, args.bucketPolicy)
+	case "getpolicy":
+		err = GetPolicy(svc, args.bucketname)
+	case "deletepolicy":
+		err = DeletePolicy(svc, args.bucketname)
+	case "putcors":
+		err = PutCORS(svc, args.bucketname)
+	case "getcors":
+		err = GetCORS(svc, args.bucketname)
+	case "deletecors":
+		err = DeleteCORS(svc, args.bucketname)
+	case "putnotification":
+		err = PutNotification(svc, args.bucketname, args.notificationArn, args.objectprefix)
+	case "getnotification":
+		err = GetNotification(svc, args.bucketname)
+	case "putacl":
+		err = PutACL(svc, args.bucketname, keyName, args.acl)
+	case "getacl":
+		err = GetACL(svc, args.bucketname, keyName)
 	case "updatemeta":
-		err = UpdateMetadata(svc, args.bucketname, keyName, parseMetadataString(args.metadata))
+		err = UpdateMetadata(svc, args.bucketname, keyName, objectMetadata(args, keyName))
+	case "copy":
+		destKey := copyKey(keyName, args.objectprefix, args.copyPrefix)
+		if args.osize > args.copyThreshold {
+			var partLatencies []time.Duration
+			partLatencies, err = MultipartCopy(svc, args.bucketname, keyName, args.copyBucket, destKey, args.storageClass, args.metadataDirective, objectMetadata(args, keyName), args.partsize, args.partConcurrency)
+			r.recordPartLatencies(partLatencies)
+		} else {
+			err = Copy(svc, args.bucketname, keyName, args.copyBucket, destKey, args.storageClass, args.metadataDirective, objectMetadata(args, keyName))
+		}
+	case "copyacross":
+		var copiedBytes int64
+		var partLatencies []time.Duration
+		start := time.Now()
+		verify := args.payload.verifyETag && args.encryption.etagIsMD5()
+		copiedBytes, partLatencies, err = CopyAcross(svc, args.destination, args.bucketname, keyName, args.copyBucket, copyKey(keyName, args.objectprefix, args.copyPrefix), args.storageClass, args.partsize, verify)
+		r.recordPartLatencies(partLatencies)
+		if copiedBytes > 0 {
+			r.sumObjSize += copiedBytes
+			r.recordObjectThroughput(copiedBytes, time.Since(start))
+		}
 	case "multipartput":
-		if err = MultipartPut(svc, args.bucketname, keyName, sc, args.osize, args.partsize, parseMetadataString(args.metadata)); err == nil {
+		var partLatencies []time.Duration
+		partLatencies, err = MultipartPut(svc, args.bucketname, keyName, args.tagging, sc, args.osize, args.partsize, args.partConcurrency, objectMetadata(args, keyName), args.payload)
+		r.recordPartLatencies(partLatencies)
+		if err == nil {
 			r.sumObjSize += args.osize
 		}
+	case "initmultipart":
+		var partLatencies []time.Duration
+		partLatencies, err = InitMultipart(svc, args.bucketname, keyName, args.tagging, sc, args.osize, args.partsize, args.partConcurrency, objectMetadata(args, keyName), args.payload)
+		r.recordPartLatencies(partLatencies)
+		if err == nil {
+			r.sumObjSize += args.osize
+		}
+	case "listmultipartuploads":
+		err = ListMultipartUploads(svc, args.bucketname, args.objectprefix)
+	case "listparts":
+		err = ListParts(svc, args.bucketname, keyName, args.uploadId)
+	case "abortmultipart":
+		err = AbortMultipart(svc, args.bucketname, keyName)
+	case "list":
+		var keys int
+		if args.listMode == "full" {
+			var pageLatencies []time.Duration
+			pageLatencies, keys, err = ListAllObjects(svc, args.bucketname, args.objectprefix, args.delimiter, args.listApi, args.maxKeys)
+			r.recordPageLatencies(pageLatencies)
+		} else {
+			// every request lists the next page, starting over once the last page has been listed
+			args.listMarker, keys, err = ListObjects(svc, args.bucketname, args.objectprefix, args.delimiter, args.listMarker, args.listApi, args.maxKeys)
+		}
+		r.KeyCount += keys
 	case "get":
 		var retrievedBytes int64
-		if retrievedBytes, err = Get(svc, args.bucketname, keyName, args.objrange, args.verify, args.partsize); err == nil {
+		if retrievedBytes, err = Get(svc, args.bucketname, keyName, args.objrange, args.responseOverrides, args.verify, args.partsize, args.payload); err == nil {
 			r.sumObjSize += retrievedBytes
 		}
+	case "versionedget":
+		var retrievedBytes int64
+		if retrievedBytes, err = GetVersion(svc, args.bucketname, keyName, args.versionId, args.objrange, args.responseOverrides, args.verify, args.partsize, args.payload); err == nil {
+			r.sumObjSize += retrievedBytes
+		}
+	case "rangeget":
+		offset := rangeOffset(args.rangeDist, args.osize, args.rangeLength, rand.Int63n)
+		byteRange := fmt.Sprintf("bytes=%d-%d", offset, offset+args.rangeLength-1)
+		var retrievedBytes int64
+		if retrievedBytes, err = Get(svc, args.bucketname, keyName, byteRange, args.responseOverrides, args.verify, args.partsize, args.payload); err == nil {
+			r.sumObjSize += retrievedBytes
+		}
+	case "parallelget":
+		var retrievedBytes int64
+		start := time.Now()
+		if retrievedBytes, err = ParallelGet(svc, args.bucketname, keyName, args.rangeSize, args.rangeThreshold, args.rangeConcurrency, args.responseOverrides, args.verify, args.partsize, args.payload); err == nil {
+			r.sumObjSize += retrievedBytes
+			r.recordObjectThroughput(retrievedBytes, time.Since(start))
+		}
 	case "head":
 		err = Head(svc, args.bucketname, keyName)
+	case "presign":
+		if args.presignExport != nil {
+			expiry := args.presignExport.expiry()
+			signedAt := time.Now()
+			var url string
+			if url, err = Presign(svc, args.bucketname, keyName, args.presignMethod, expiry, args.responseOverrides); err == nil {
+				err = args.presignExport.write(keyName, args.presignMethod, signedAt.Add(expiry), url)
+			}
+			break
+		}
+		if !args.presignTransfer {
+			_, err = Presign(svc, args.bucketname, keyName, args.presignMethod, args.presignExpiry, args.responseOverrides)
+			break
+		}
+		start := time.Now()
+		var transferredBytes int64
+		var signed time.Duration
+		if transferredBytes, signed, err = PresignedTransfer(svc, hclient, args.bucketname, keyName, args.presignMethod, args.presignExpiry, args.osize, args.payload, args.responseOverrides); err == nil {
+			r.sumObjSize += transferredBytes
+			r.recordPresignedTransfer(signed, time.Since(start)-signed)
+			if args.presignMethod == "PUT" {
+				// the SDK doesn't send the transfer, so the object isn't tracked by the handlers of the service
+				created.addObject(args.bucketname, keyName)
+			}
+		}
+	case "presignedurl":
+		u := args.presignedURLs[keyName]
+		var transferredBytes int64
+		if transferredBytes, err = transferURL(hclient, u.url, u.method, keyName, args.osize, args.payload, nil); err == nil {
+			r.sumObjSize += transferredBytes
+		}
+		if time.Now().After(u.expires) {
+			r.recordExpiredURL(err == nil)
+			if err == nil {
+				log.Printf("Expired URL of object '%s' was accepted, it expired at %s", keyName, u.expires.Format(time.RFC3339))
+			}
+		}
 	case "delete":
 		err = Delete(svc, args.bucketname, keyName)
+	case "versioneddelete":
+		err = DeleteVersion(svc, args.bucketname, keyName, args.versionId)
+	case "listversions":
+		// every request lists the next page, starting over once the last page has been listed
+		var versions int
+		args.listMarker, args.versionIdMarker, versions, err = ListVersions(svc, args.bucketname, args.objectprefix, args.listMarker, args.versionIdMarker, args.maxKeys)
+		r.KeyCount += versions
+	case "multidelete":
+		var deleted int
+		deleted, err = MultiDelete(svc, args.bucketname, args.batchKeys)
+		r.KeyCount += deleted
 	case "randget":
 		var objnum int64
 		if randMax <= 0 {
@@ -370,13 +1161,26 @@ func DispatchOperation(svc s3iface.S3API, hclient *http.Client, op, keyName stri
 			objnum = rand.Int63n(randMax)
 		}
 
-		key := args.objectprefix + "-" + strconv.FormatInt(objnum, 10)
+		key := numberedKey(args, args.keyOffset+objnum)
 		var retrievedBytes int64
-		if retrievedBytes, err = Get(svc, args.bucketname, key, args.objrange, args.verify, args.partsize); err == nil {
+		if retrievedBytes, err = Get(svc, args.bucketname, key, args.objrange, args.responseOverrides, args.verify, args.partsize, args.payload); err == nil {
 			r.sumObjSize += retrievedBytes
 		}
+	case "select":
+		if r.Select == nil {
+			r.Select = &selectResult{}
+		}
+		var returnedBytes int64
+		returnedBytes, err = SelectObject(svc, args.bucketname, keyName, args.selectExpression, args.selectFormat, r.Select)
+		r.sumObjSize += returnedBytes
 	case "restore":
+		start := time.Now()
 		err = RestoreObject(svc, args.bucketname, keyName, args.tier, args.days)
+		if args.restorePoll > 0 && (err == nil || restoreInProgress(err)) {
+			if err = WaitForRestore(svc, args.bucketname, keyName, args.restorePoll, args.restoreTimeout); err == nil {
+				r.recordRestoreLatency(time.Since(start))
+			}
+		}
 	}
 	return err
 }
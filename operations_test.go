@@ -1,9 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 )
@@ -56,7 +65,7 @@ func TestPutOp(t *testing.T) {
 
 	svc := NewMockS3Client(handler)
 
-	Put(svc, "b", "k1", "", s3.StorageClassStandard, numBytes, map[string]*string{})
+	Put(svc, "b", "k1", "", s3.StorageClassStandard, numBytes, map[string]*string{}, payloadOptions{})
 }
 
 func TestPutWithTagsOp(t *testing.T) {
@@ -97,7 +106,7 @@ func TestPutWithTagsOp(t *testing.T) {
 
 	svc := NewMockS3Client(handler)
 
-	err := Put(svc, "b", "k1", tags, s3.StorageClassStandard, numBytes, map[string]*string{})
+	_, err := Put(svc, "b", "k1", tags, s3.StorageClassStandard, numBytes, map[string]*string{}, payloadOptions{})
 
 	if err != nil {
 		t.Fatalf("Failed PUT operation with error: %v", err)
@@ -140,6 +149,62 @@ func TestPutTaggingOp(t *testing.T) {
 	}
 }
 
+func (this *mockS3Client) GetObjectTagging(in *s3.GetObjectTaggingInput) (*s3.GetObjectTaggingOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.GetObjectTaggingOutput{}, nil
+}
+
+func TestGetTaggingOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.GetObjectTaggingInput)
+
+		if *i.Bucket != "b" {
+			t.Fatalf("Expected bucket: %s but got: %s", "b", *i.Bucket)
+		}
+
+		if *i.Key != "k1" {
+			t.Fatalf("Expected key: %s but got: %s", "k1", *i.Key)
+		}
+
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	if err := GetTagging(svc, "b", "k1"); err != nil {
+		t.Fatalf("Failed GET tags operation with error: %v", err)
+	}
+}
+
+func (this *mockS3Client) DeleteObjectTagging(in *s3.DeleteObjectTaggingInput) (*s3.DeleteObjectTaggingOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.DeleteObjectTaggingOutput{}, nil
+}
+
+func TestDeleteTaggingOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.DeleteObjectTaggingInput)
+
+		if *i.Bucket != "b" {
+			t.Fatalf("Expected bucket: %s but got: %s", "b", *i.Bucket)
+		}
+
+		if *i.Key != "k1" {
+			t.Fatalf("Expected key: %s but got: %s", "k1", *i.Key)
+		}
+
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	if err := DeleteTagging(svc, "b", "k1"); err != nil {
+		t.Fatalf("Failed DELETE tags operation with error: %v", err)
+	}
+}
+
 func (this *mockS3Client) CopyObject(in *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
 	this.S3OpHandler(in)
 
@@ -183,8 +248,47 @@ func TestUpdateMetadataOp(t *testing.T) {
 	}
 }
 
+func TestCopyOp(t *testing.T) {
+	v1 := "val1"
+	metadata := map[string]*string{"attribute1": &v1}
+
+	for _, directive := range []string{"COPY", "REPLACE"} {
+		handler := func(in interface{}) interface{} {
+			i := in.(*s3.CopyObjectInput)
+
+			if *i.Bucket != "b2" || *i.Key != "copy-0-1" {
+				t.Fatalf("Expected destination b2/copy-0-1 but got: %s/%s", *i.Bucket, *i.Key)
+			}
+
+			if *i.CopySource != "b/testobject-0-1" {
+				t.Fatalf("Expected copy source: %s but got: %s", "b/testobject-0-1", *i.CopySource)
+			}
+
+			if *i.MetadataDirective != directive {
+				t.Fatalf("Expected metadata directive: %s but got: %s", directive, *i.MetadataDirective)
+			}
+
+			if (directive == "REPLACE") != (i.Metadata != nil) {
+				t.Fatalf("Metadata must only be sent with REPLACE but got: %v", i.Metadata)
+			}
+
+			return in
+		}
+
+		svc := NewMockS3Client(handler)
+
+		err := Copy(svc, "b", "testobject-0-1", "b2", copyKey("testobject-0-1", "testobject", "copy"), "", directive, metadata)
+
+		if err != nil {
+			t.Fatalf("Failed copy operation with error: %v", err)
+		}
+	}
+}
+
 func (this *mockS3Client) HeadObject(in *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
-	this.S3OpHandler(in)
+	if out, ok := this.S3OpHandler(in).(*s3.HeadObjectOutput); ok {
+		return out, nil
+	}
 
 	return &s3.HeadObjectOutput{}, nil
 }
@@ -250,3 +354,557 @@ func TestParseMetadataString(t *testing.T) {
 		parseMetadataString(m)
 	}
 }
+
+func TestContentRangeStart(t *testing.T) {
+	cases := map[string]int64{
+		"":                    0,
+		"bytes 0-99/1000":     0,
+		"bytes 100-199/1000":  100,
+		"bytes 8192-8195/*":   8192,
+		"bytes */1000":        0,
+		"items 10-20/30":      0,
+		"bytes garbage-20/30": 0,
+	}
+
+	for header, expected := range cases {
+		if start := contentRangeStart(header); start != expected {
+			t.Fatalf("Expected start offset %d for %q but got %d", expected, header, start)
+		}
+	}
+}
+
+func TestVerifyObjectData(t *testing.T) {
+	key := "object-0"
+	var size int64 = 3*objectDataBlockSize + 100
+	compressible := payloadOptions{compressRatio: 2}
+
+	for _, payload := range []payloadOptions{{}, compressible} {
+		data, _ := ioutil.ReadAll(NewPayloadReader(size, key, payload))
+
+		if err := verifyObjectData(bytes.NewReader(data), key, 0, 1, 0, payload); err != nil {
+			t.Fatalf("Expected data to verify but got: %v", err)
+		}
+
+		// ranged GETs are verified from the start offset of the range
+		if err := verifyObjectData(bytes.NewReader(data[5000:6000]), key, 5000, 1, 0, payload); err != nil {
+			t.Fatalf("Expected ranged data to verify but got: %v", err)
+		}
+
+		if err := verifyObjectData(bytes.NewReader(data[5003:6000]), key, 0, 1, 0, payload); err == nil {
+			t.Fatalf("Expected ranged data at the wrong offset to fail verification")
+		}
+
+		data[objectDataBlockSize+1]++
+		if err := verifyObjectData(bytes.NewReader(data), key, 0, 1, 0, payload); err == nil {
+			t.Fatalf("Expected corrupted data to fail verification")
+		}
+	}
+}
+
+func TestVerifyMultipartObjectData(t *testing.T) {
+	key := "object-0"
+	var partSize int64 = 2*objectDataBlockSize + 3
+
+	part, _ := ioutil.ReadAll(NewDummyReader(partSize, key))
+	lastPart, _ := ioutil.ReadAll(NewDummyReader(10, key))
+	data := append(append(append([]byte{}, part...), part...), lastPart...)
+
+	if err := verifyObjectData(bytes.NewReader(data), key, 0, 2, partSize, payloadOptions{}); err != nil {
+		t.Fatalf("Expected multipart data to verify but got: %v", err)
+	}
+
+	if err := verifyObjectData(bytes.NewReader(data), key, 0, 1, partSize, payloadOptions{}); err == nil {
+		t.Fatalf("Expected multipart data to fail verification as a single part object")
+	}
+}
+
+func (this *mockS3Client) CreateMultipartUpload(in *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+func (this *mockS3Client) UploadPart(in *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	this.S3OpHandler(in)
+
+	// the server returns the checksum sent with the part
+	return &s3.UploadPartOutput{ETag: aws.String("etag"), ChecksumCRC32: in.ChecksumCRC32, ChecksumCRC32C: in.ChecksumCRC32C, ChecksumSHA1: in.ChecksumSHA1, ChecksumSHA256: in.ChecksumSHA256}, nil
+}
+
+func (this *mockS3Client) CompleteMultipartUpload(in *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (this *mockS3Client) ListMultipartUploads(in *s3.ListMultipartUploadsInput) (*s3.ListMultipartUploadsOutput, error) {
+	return this.S3OpHandler(in).(*s3.ListMultipartUploadsOutput), nil
+}
+
+// the mock fails aborting uploads whose id is "fail"
+func (this *mockS3Client) AbortMultipartUpload(in *s3.AbortMultipartUploadInput) (*s3.AbortMultipartUploadOutput, error) {
+	this.S3OpHandler(in)
+
+	if aws.StringValue(in.UploadId) == "fail" {
+		return nil, awserr.New(s3.ErrCodeNoSuchUpload, "", nil)
+	}
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func (this *mockS3Client) ListParts(in *s3.ListPartsInput) (*s3.ListPartsOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.ListPartsOutput{}, nil
+}
+
+func TestInitMultipartOp(t *testing.T) {
+	var partSize int64 = 5 * (1 << 20)
+	parts := 0
+
+	handler := func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.CreateMultipartUploadInput:
+			if aws.StringValue(i.Tagging) != "tag1=blue" {
+				t.Fatalf("Expected tags: %s but got: %s", "tag1=blue", aws.StringValue(i.Tagging))
+			}
+		case *s3.UploadPartInput:
+			parts++
+		case *s3.CompleteMultipartUploadInput:
+			t.Fatalf("initmultipart must leave the upload in progress but it was completed for key %s", *i.Key)
+		}
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	if _, err := InitMultipart(svc, "b", "k1", "tag1=blue", s3.StorageClassStandard, 2*partSize+1, partSize, 1, map[string]*string{}, payloadOptions{}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if parts != 3 {
+		t.Fatalf("Expected 3 parts to be uploaded but got %d", parts)
+	}
+}
+
+func TestAutoPartSize(t *testing.T) {
+	sizes := map[int64]int64{
+		1:                 minPartSize,
+		10000 * (5 << 20): minPartSize,
+		// just above 10000 parts of 5MiB rounds up to a whole MiB
+		10000*(5<<20) + 1: 6 << 20,
+		1 << 40:           105 << 20,
+		10000 * (5 << 30): maxPartSize,
+	}
+	for size, expected := range sizes {
+		if partSize := autoPartSize(size); partSize != expected {
+			t.Fatalf("Expected part size %d for object size %d but got %d", expected, size, partSize)
+		}
+	}
+}
+
+func TestPutAboveMPUThreshold(t *testing.T) {
+	var puts, parts int32
+	handler := func(in interface{}) interface{} {
+		switch in.(type) {
+		case *s3.PutObjectInput:
+			atomic.AddInt32(&puts, 1)
+		case *s3.UploadPartInput:
+			atomic.AddInt32(&parts, 1)
+		}
+		return in
+	}
+	svc := NewMockS3Client(handler)
+	args := parameters{bucketname: "b", osize: 5 << 20, partsize: 5 << 20, partConcurrency: 1, mpuThreshold: 10 << 20}
+	r := NewResult()
+
+	if err := DispatchOperation(svc, nil, "put", "k1", &args, &r, 0); err != nil || puts != 1 || r.MultipartCount != 0 {
+		t.Fatalf("Expected a single PUT below the threshold but got %d PUTs, %d multipart: %v", puts, r.MultipartCount, err)
+	}
+
+	args.osize = 15 << 20
+	if err := DispatchOperation(svc, nil, "put", "k2", &args, &r, 0); err != nil || puts != 1 || parts != 3 || r.MultipartCount != 1 {
+		t.Fatalf("Expected a multipart upload above the threshold but got %d PUTs, %d parts, %d multipart: %v", puts, parts, r.MultipartCount, err)
+	}
+
+	if r.sumObjSize != 20<<20 || r.PartResult.Count != 3 {
+		t.Fatalf("Expected both objects and the parts to be recorded but got %d bytes, %d parts", r.sumObjSize, r.PartResult.Count)
+	}
+}
+
+func TestMultipartPutParallelParts(t *testing.T) {
+	var partSize int64 = 5 * (1 << 20)
+	var inflight, maxInflight int32
+	var mu sync.Mutex
+	sizes := make(map[int64]int64)
+
+	handler := func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.UploadPartInput:
+			current := atomic.AddInt32(&inflight, 1)
+			mu.Lock()
+			sizes[*i.PartNumber] = *i.ContentLength
+			if current > maxInflight {
+				maxInflight = current
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&inflight, -1)
+		case *s3.CompleteMultipartUploadInput:
+			for n, part := range i.MultipartUpload.Parts {
+				if *part.PartNumber != int64(n+1) {
+					t.Fatalf("Expected part %d but got part %d", n+1, *part.PartNumber)
+				}
+			}
+		}
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	latencies, err := MultipartPut(svc, "b", "k1", "", s3.StorageClassStandard, 4*partSize+1, partSize, 3, map[string]*string{}, payloadOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(latencies) != 5 || len(sizes) != 5 {
+		t.Fatalf("Expected 5 parts to be uploaded but got %d latencies and %d parts", len(latencies), len(sizes))
+	}
+
+	if sizes[5] != 1 || sizes[1] != partSize {
+		t.Fatalf("Wrong part sizes: %v", sizes)
+	}
+
+	if maxInflight < 2 || maxInflight > 3 {
+		t.Fatalf("Expected between 2 and 3 parts in flight but got %d", maxInflight)
+	}
+}
+
+func (this *mockS3Client) UploadPartCopy(in *s3.UploadPartCopyInput) (*s3.UploadPartCopyOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.UploadPartCopyOutput{CopyPartResult: &s3.CopyPartResult{ETag: aws.String("etag")}}, nil
+}
+
+func TestMultipartCopyOp(t *testing.T) {
+	var partSize int64 = 5 * (1 << 20)
+	var mu sync.Mutex
+	ranges := make(map[int64]string)
+	contentType := "text/plain"
+	v1 := "val1"
+
+	handler := func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.HeadObjectInput:
+			if *i.Bucket != "b" || *i.Key != "k1" {
+				t.Fatalf("Expected HEAD of the source b/k1 but got: %s/%s", *i.Bucket, *i.Key)
+			}
+			return &s3.HeadObjectOutput{ContentLength: aws.Int64(2*partSize + 1), ContentType: &contentType, Metadata: map[string]*string{"attribute1": &v1}}
+		case *s3.CreateMultipartUploadInput:
+			if *i.Bucket != "b2" || *i.Key != "k2" {
+				t.Fatalf("Expected destination b2/k2 but got: %s/%s", *i.Bucket, *i.Key)
+			}
+			if *i.ContentType != contentType || *i.Metadata["attribute1"] != v1 {
+				t.Fatalf("Expected the metadata of the source to be copied")
+			}
+		case *s3.UploadPartCopyInput:
+			if *i.CopySource != "b/k1" {
+				t.Fatalf("Expected copy source: %s but got: %s", "b/k1", *i.CopySource)
+			}
+			mu.Lock()
+			ranges[*i.PartNumber] = *i.CopySourceRange
+			mu.Unlock()
+		case *s3.CompleteMultipartUploadInput:
+			if len(i.MultipartUpload.Parts) != 3 {
+				t.Fatalf("Expected 3 parts to be completed but got %d", len(i.MultipartUpload.Parts))
+			}
+		}
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	latencies, err := MultipartCopy(svc, "b", "k1", "b2", "k2", "", "COPY", nil, partSize, 2)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(latencies) != 3 {
+		t.Fatalf("Expected 3 parts to be copied but got %d", len(latencies))
+	}
+
+	if ranges[1] != "bytes=0-5242879" || ranges[3] != "bytes=10485760-10485760" {
+		t.Fatalf("Wrong part ranges: %v", ranges)
+	}
+}
+
+func TestContentRangeSize(t *testing.T) {
+	cases := map[string]int64{
+		"bytes 0-99/1000":    1000,
+		"bytes 900-999/1000": 1000,
+		"bytes 0-99/*":       -1,
+		"":                   -1,
+		"bytes 0-99":         -1,
+	}
+
+	for contentRange, expected := range cases {
+		if size := contentRangeSize(contentRange); size != expected {
+			t.Fatalf("Expected size %d for %q but got %d", expected, contentRange, size)
+		}
+	}
+}
+
+func TestRangeOffset(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	if offset := rangeOffset("fixed", 1000, 100, random.Int63n); offset != 0 {
+		t.Fatalf("fixed ranges should start at 0 but got %d", offset)
+	}
+
+	if offset := rangeOffset("unaligned", 100, 200, random.Int63n); offset != 0 {
+		t.Fatalf("ranges longer than the object should start at 0 but got %d", offset)
+	}
+
+	unaligned := false
+	for i := 0; i < 1000; i++ {
+		offset := rangeOffset("aligned", 1050, 100, random.Int63n)
+		if offset%100 != 0 || offset+100 > 1050 {
+			t.Fatalf("aligned range at offset %d is not aligned or past the end of the object", offset)
+		}
+
+		offset = rangeOffset("unaligned", 1050, 100, random.Int63n)
+		if offset < 0 || offset+100 > 1050 {
+			t.Fatalf("unaligned range at offset %d is past the end of the object", offset)
+		}
+		unaligned = unaligned || offset%100 != 0
+	}
+
+	if !unaligned {
+		t.Fatalf("unaligned ranges should not all be aligned")
+	}
+}
+
+func (this *mockS3Client) DeleteObjects(in *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	return this.S3OpHandler(in).(*s3.DeleteObjectsOutput), nil
+}
+
+func TestMultiDeleteOp(t *testing.T) {
+	keys := []string{"k1", "k2", "k3"}
+
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.DeleteObjectsInput)
+		if len(i.Delete.Objects) != len(keys) {
+			t.Fatalf("Expected %d keys but got %d", len(keys), len(i.Delete.Objects))
+		}
+		for n, object := range i.Delete.Objects {
+			if *object.Key != keys[n] {
+				t.Fatalf("Expected key: %s but got: %s", keys[n], *object.Key)
+			}
+		}
+		return &s3.DeleteObjectsOutput{}
+	}
+
+	deleted, err := MultiDelete(NewMockS3Client(handler), "b", keys)
+	if err != nil || deleted != 3 {
+		t.Fatalf("Expected 3 keys to be deleted but got %d, %v", deleted, err)
+	}
+
+	failing := func(in interface{}) interface{} {
+		return &s3.DeleteObjectsOutput{Errors: []*s3.Error{{Key: aws.String("k2"), Code: aws.String("AccessDenied")}}}
+	}
+
+	deleted, err = MultiDelete(NewMockS3Client(failing), "b", keys)
+	if err == nil || deleted != 2 {
+		t.Fatalf("Expected 2 keys to be deleted and an error but got %d, %v", deleted, err)
+	}
+}
+
+func TestListPartsOp(t *testing.T) {
+	key := "k1"
+
+	handler := func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.ListMultipartUploadsInput:
+			if *i.Prefix != key {
+				t.Fatalf("Expected prefix: %s but got: %s", key, *i.Prefix)
+			}
+			return &s3.ListMultipartUploadsOutput{Uploads: []*s3.MultipartUpload{
+				{Key: aws.String("k10"), UploadId: aws.String("upload-10")},
+				{Key: aws.String("k1"), UploadId: aws.String("upload-1")},
+			}}
+		case *s3.ListPartsInput:
+			if *i.UploadId != "upload-1" {
+				t.Fatalf("Expected upload id: %s but got: %s", "upload-1", *i.UploadId)
+			}
+		}
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	ids, err := uploadIds(svc, "b", key)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("Expected the upload of the key but got %v: %v", ids, err)
+	}
+	if err := ListParts(svc, "b", key, *ids[0]); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	key = "k2"
+	if _, err := uploadIds(svc, "b", key); err == nil {
+		t.Fatalf("Expected an error when no upload is in progress for the key")
+	}
+}
+
+func TestAbortMultipartOp(t *testing.T) {
+	var aborted []string
+
+	handler := func(in interface{}) interface{} {
+		switch i := in.(type) {
+		case *s3.ListMultipartUploadsInput:
+			return &s3.ListMultipartUploadsOutput{Uploads: []*s3.MultipartUpload{
+				{Key: aws.String("k1"), UploadId: aws.String("upload-1")},
+				{Key: aws.String("k10"), UploadId: aws.String("upload-10")},
+				{Key: aws.String("k1"), UploadId: aws.String("upload-2")},
+			}}
+		case *s3.AbortMultipartUploadInput:
+			if *i.Key != "k1" {
+				t.Fatalf("Expected key: %s but got: %s", "k1", *i.Key)
+			}
+			aborted = append(aborted, *i.UploadId)
+		}
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	if err := AbortMultipart(svc, "b", "k1"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(aborted) != 2 || aborted[0] != "upload-1" || aborted[1] != "upload-2" {
+		t.Fatalf("Expected both uploads of the key to be aborted but got: %v", aborted)
+	}
+
+	if err := AbortMultipart(svc, "b", "k2"); err == nil {
+		t.Fatalf("Expected an error when no upload is in progress for the key")
+	}
+}
+
+func (this *mockS3Client) ListObjects(in *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	return this.S3OpHandler(in).(*s3.ListObjectsOutput), nil
+}
+
+func (this *mockS3Client) ListObjectsV2(in *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	return this.S3OpHandler(in).(*s3.ListObjectsV2Output), nil
+}
+
+func TestListObjectsV1Op(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i, ok := in.(*s3.ListObjectsInput)
+		if !ok {
+			t.Fatalf("Expected a V1 listing but got: %T", in)
+		}
+		if i.Marker == nil {
+			return &s3.ListObjectsOutput{IsTruncated: aws.Bool(true), Contents: []*s3.Object{{Key: aws.String("p-1")}, {Key: aws.String("p-2")}}}
+		}
+		if *i.Marker != "p-2" {
+			t.Fatalf("Expected marker: %s but got: %s", "p-2", *i.Marker)
+		}
+		return &s3.ListObjectsOutput{IsTruncated: aws.Bool(false), Contents: []*s3.Object{{Key: aws.String("p-3")}}}
+	}
+
+	svc := NewMockS3Client(handler)
+
+	marker, keys, err := ListObjects(svc, "b", "p", "", "", "v1", 0)
+	if err != nil || marker != "p-2" || keys != 2 {
+		t.Fatalf("Expected marker p-2 after 2 keys but got: %q after %d keys, %v", marker, keys, err)
+	}
+
+	if marker, _, err = ListObjects(svc, "b", "p", "", marker, "v1", 0); err != nil || marker != "" {
+		t.Fatalf("Expected no marker after the last page but got: %q, %v", marker, err)
+	}
+}
+
+func TestListObjectsV2Op(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i, ok := in.(*s3.ListObjectsV2Input)
+		if !ok {
+			t.Fatalf("Expected a V2 listing but got: %T", in)
+		}
+		if i.ContinuationToken == nil {
+			return &s3.ListObjectsV2Output{IsTruncated: aws.Bool(true), NextContinuationToken: aws.String("token")}
+		}
+		if *i.ContinuationToken != "token" {
+			t.Fatalf("Expected continuation token: %s but got: %s", "token", *i.ContinuationToken)
+		}
+		return &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}
+	}
+
+	svc := NewMockS3Client(handler)
+
+	marker, _, err := ListObjects(svc, "b", "p", "", "", "v2", 0)
+	if err != nil || marker != "token" {
+		t.Fatalf("Expected continuation token but got: %q, %v", marker, err)
+	}
+
+	if marker, _, err = ListObjects(svc, "b", "p", "", marker, "v2", 0); err != nil || marker != "" {
+		t.Fatalf("Expected no continuation token after the last page but got: %q, %v", marker, err)
+	}
+}
+
+func TestListAllObjectsOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.ListObjectsV2Input)
+		if *i.Delimiter != "/" || *i.MaxKeys != 2 {
+			t.Fatalf("Expected delimiter / and max keys 2 but got: %s %d", *i.Delimiter, *i.MaxKeys)
+		}
+		if i.ContinuationToken == nil {
+			return &s3.ListObjectsV2Output{IsTruncated: aws.Bool(true), NextContinuationToken: aws.String("token"),
+				Contents: []*s3.Object{{Key: aws.String("p-1")}}, CommonPrefixes: []*s3.CommonPrefix{{Prefix: aws.String("p-dir/")}}}
+		}
+		return &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false), Contents: []*s3.Object{{Key: aws.String("p-2")}}}
+	}
+
+	svc := NewMockS3Client(handler)
+
+	latencies, keys, err := ListAllObjects(svc, "b", "p", "/", "v2", 2)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(latencies) != 2 || keys != 3 {
+		t.Fatalf("Expected 2 pages with 3 keys but got %d pages with %d keys", len(latencies), keys)
+	}
+}
+
+func TestPutWithPayloadFileOp(t *testing.T) {
+	dir := createPayloadDir(t)
+	defer os.RemoveAll(dir)
+	files, _ := NewPayloadFiles(filepath.Join(dir, "b"), "", true)
+
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutObjectInput)
+
+		if *i.ContentLength != int64(len("second payload")) {
+			t.Fatalf("Expected object size: %d but got: %d", len("second payload"), *i.ContentLength)
+		}
+
+		data, _ := ioutil.ReadAll(i.Body)
+		if string(data) != "second payload" {
+			t.Fatalf("Expected body %q but got %q", "second payload", string(data))
+		}
+
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	written, err := Put(svc, "b", "k1", "", s3.StorageClassStandard, 100, map[string]*string{}, payloadOptions{files: files})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if written != int64(len("second payload")) {
+		t.Fatalf("Expected %d bytes written but got %d", len("second payload"), written)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Parses the versioning of an overwrite-keys run, enabled or suspended, into the status of the bucket. Returns an empty
+// status if none is given.
+func parseOverwriteVersioning(versioning string) (string, error) {
+	switch strings.ToLower(versioning) {
+	case "":
+		return "", nil
+	case "enabled":
+		return s3.BucketVersioningStatusEnabled, nil
+	case "suspended":
+		return s3.BucketVersioningStatusSuspended, nil
+	}
+	return "", errors.New("overwrite-versioning must be enabled or suspended")
+}
+
+// SetVersioning sets the versioning status of the bucket, Enabled or Suspended.
+func SetVersioning(svc s3iface.S3API, bucket, status string) error {
+	params := &s3.PutBucketVersioningInput{
+		Bucket:                  aws.String(bucket),
+		VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String(status)},
+	}
+	_, err := svc.PutBucketVersioning(params)
+
+	return err
+}
+
+// prepareOverwriteVersioning sets the versioning of the bucket before an overwrite-keys run rewrites its keys, so that
+// every overwrite either keeps the previous object as a noncurrent version or replaces it.
+func prepareOverwriteVersioning(args parameters) error {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		return err
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+	return SetVersioning(svc, args.bucketname, args.overwriteVersioning)
+}
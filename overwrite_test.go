@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestParseOverwriteVersioning(t *testing.T) {
+	for versioning, expected := range map[string]string{"": "", "enabled": "Enabled", "Suspended": "Suspended"} {
+		if status, err := parseOverwriteVersioning(versioning); err != nil || status != expected {
+			t.Fatalf("%q should be %q: %q %v", versioning, expected, status, err)
+		}
+	}
+	if _, err := parseOverwriteVersioning("disabled"); err == nil {
+		t.Fatalf("disabled should be invalid")
+	}
+}
+
+func TestSetVersioning(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutBucketVersioningInput)
+		if *i.Bucket != "b" || *i.VersioningConfiguration.Status != "Suspended" {
+			t.Fatalf("Expected versioning of b to be suspended but got %s: %s", *i.Bucket, *i.VersioningConfiguration.Status)
+		}
+		return in
+	}
+
+	if err := SetVersioning(NewMockS3Client(handler), "b", "Suspended"); err != nil {
+		t.Fatalf("Failed suspending versioning with error: %v", err)
+	}
+}
+
+func TestOverwriteKeys(t *testing.T) {
+	args, err := parse([]string{"-operation=put", "-requests=100", "-concurrency=4", "-overwrite-keys=10", "-overwrite-versioning=enabled", "-prefix=p"})
+	if err != nil {
+		t.Fatalf("overwrite-keys should succeed: %v", err)
+	}
+	if args.overwriteKeys != 10 || args.overwriteVersioning != "Enabled" {
+		t.Fatalf("Wrong overwrite settings: %d %s", args.overwriteKeys, args.overwriteVersioning)
+	}
+
+	// the third worker of 25 requests each starts at request 50
+	if key := objectKey(&args, 2, 25, 3); key != "p-3" {
+		t.Fatalf("Expected key p-3 but got %s", key)
+	}
+	if key := objectKey(&args, 3, 25, 24); key != "p-9" {
+		t.Fatalf("Expected key p-9 but got %s", key)
+	}
+
+	r := NewResult()
+	r.UniqObjNum = 100
+	r.correctEndpointUniqObjCountWithOverwriteSetting(0, 25, args.overwriteKeys)
+	if r.UniqObjNum != 10 {
+		t.Fatalf("Expected 10 unique objects but got %d", r.UniqObjNum)
+	}
+
+	for _, invalid := range [][]string{
+		{"-operation=put", "-overwrite-keys=-1"},
+		{"-operation=put", "-overwrite-keys=10", "-overwrite=2"},
+		{"-operation=put", "-overwrite-keys=10", "-bucket-per-worker"},
+		{"-operation=put", "-overwrite-versioning=enabled"},
+		{"-operation=put", "-overwrite-keys=10", "-overwrite-versioning=on"},
+	} {
+		if _, err := parse(invalid); err == nil {
+			t.Fatalf("%v should be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// payloadFiles supplies PUT object data from real local files instead of generated data, which matters
+// for content-aware storage features. Workers go through the files in round-robin order.
+type payloadFiles struct {
+	paths []string
+	next  uint64
+	// holds the content of every file when in-memory caching is enabled
+	cache [][]byte
+	// the size of the largest file
+	largest int64
+}
+
+// Uses the given file, or every regular file in the given directory, as object payloads.
+func NewPayloadFiles(file, dir string, cache bool) (*payloadFiles, error) {
+	p := &payloadFiles{}
+	if file != "" {
+		p.paths = []string{file}
+	} else {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() {
+				p.paths = append(p.paths, filepath.Join(dir, entry.Name()))
+			}
+		}
+		if len(p.paths) == 0 {
+			return nil, errors.New("Payload directory " + dir + " does not contain any files")
+		}
+	}
+
+	for _, path := range p.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > p.largest {
+			p.largest = info.Size()
+		}
+		if cache {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			p.cache = append(p.cache, data)
+		}
+	}
+	return p, nil
+}
+
+// open returns the next payload in round-robin order along with its size. The returned
+// close function must be called once the payload has been sent.
+func (p *payloadFiles) open() (io.ReadSeeker, int64, func(), error) {
+	index := (atomic.AddUint64(&p.next, 1) - 1) % uint64(len(p.paths))
+	if p.cache != nil {
+		data := p.cache[index]
+		return bytes.NewReader(data), int64(len(data)), func() {}, nil
+	}
+
+	f, err := os.Open(p.paths[index])
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, nil, err
+	}
+	return f, info.Size(), func() { f.Close() }, nil
+}
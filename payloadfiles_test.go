@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createPayloadDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "s3tester-payload")
+	if err != nil {
+		t.Fatalf("Failed to create payload directory: %v", err)
+	}
+	for name, content := range map[string]string{"a": "first", "b": "second payload", "c": "3"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create payload file: %v", err)
+		}
+	}
+	// directories are not payloads
+	os.Mkdir(filepath.Join(dir, "subdir"), 0755)
+	return dir
+}
+
+func readPayloads(t *testing.T, p *payloadFiles, count int) []string {
+	payloads := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		r, size, closeFile, err := p.open()
+		if err != nil {
+			t.Fatalf("Failed to open payload: %v", err)
+		}
+		data, _ := ioutil.ReadAll(r)
+		closeFile()
+		if int64(len(data)) != size {
+			t.Fatalf("Expected payload size %d but read %d bytes", size, len(data))
+		}
+		payloads = append(payloads, string(data))
+	}
+	return payloads
+}
+
+func TestPayloadDirRoundRobin(t *testing.T) {
+	dir := createPayloadDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, cache := range []bool{false, true} {
+		p, err := NewPayloadFiles("", dir, cache)
+		if err != nil {
+			t.Fatalf("Failed to load payload directory: %v", err)
+		}
+		if p.largest != int64(len("second payload")) {
+			t.Fatalf("Expected the largest payload to be %d bytes but got %d", len("second payload"), p.largest)
+		}
+
+		expected := []string{"first", "second payload", "3", "first"}
+		for i, payload := range readPayloads(t, p, len(expected)) {
+			if payload != expected[i] {
+				t.Fatalf("Expected payload %q but got %q", expected[i], payload)
+			}
+		}
+	}
+}
+
+func TestPayloadFile(t *testing.T) {
+	dir := createPayloadDir(t)
+	defer os.RemoveAll(dir)
+
+	p, err := NewPayloadFiles(filepath.Join(dir, "b"), "", false)
+	if err != nil {
+		t.Fatalf("Failed to load payload file: %v", err)
+	}
+
+	for _, payload := range readPayloads(t, p, 2) {
+		if payload != "second payload" {
+			t.Fatalf("Expected payload %q but got %q", "second payload", payload)
+		}
+	}
+}
+
+func TestPayloadFilesMissing(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "s3tester-payload")
+	defer os.RemoveAll(dir)
+
+	if _, err := NewPayloadFiles("", dir, false); err == nil {
+		t.Fatalf("Expected an error for an empty payload directory")
+	}
+
+	if _, err := NewPayloadFiles(filepath.Join(dir, "missing"), "", false); err == nil {
+		t.Fatalf("Expected an error for a missing payload file")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// payloadStream supplies PUT object data from an external generator, either a named pipe it writes to or the output
+// of a command, so domain specific content like genomics or media data can be sent. Every object takes the next size
+// bytes of the stream, workers take turns reading it.
+type payloadStream struct {
+	mu     sync.Mutex
+	source io.ReadCloser
+	// the generator command, nil when reading from a pipe
+	cmd *exec.Cmd
+}
+
+// Reads payloads from the given named pipe or from the standard output of the given shell command.
+func NewPayloadStream(pipe, command string) (*payloadStream, error) {
+	if pipe != "" {
+		// opening a named pipe blocks until the generator opens it for writing
+		f, err := os.Open(pipe)
+		if err != nil {
+			return nil, err
+		}
+		return &payloadStream{source: f}, nil
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &payloadStream{source: stdout, cmd: cmd}, nil
+}
+
+// next reads the payload of an object of the given size into memory, so that it can be signed and resent on retries.
+// The time spent waiting for the generator is recorded into waits unless it is nil.
+func (p *payloadStream) next(size int64, waits *latencyResult) (io.ReadSeeker, error) {
+	start := time.Now()
+	data := make([]byte, size)
+	p.mu.Lock()
+	n, err := io.ReadFull(p.source, data)
+	p.mu.Unlock()
+	if waits != nil {
+		waits.record(time.Since(start))
+	}
+	if err == io.EOF {
+		return nil, errors.New("The payload source has no more data")
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("The payload source ended after %d bytes of an object of %d bytes", n, size)
+	} else if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// close stops reading the stream and stops the generator command. It is safe to call on a nil stream.
+func (p *payloadStream) close() {
+	if p == nil {
+		return
+	}
+	if p.cmd != nil {
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+		return
+	}
+	p.source.Close()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func readStream(t *testing.T, p *payloadStream, size int64) string {
+	r, err := p.next(size, nil)
+	if err != nil {
+		t.Fatalf("Failed to read payload: %v", err)
+	}
+	data, _ := ioutil.ReadAll(r)
+	return string(data)
+}
+
+func TestPayloadStreamCommand(t *testing.T) {
+	p, err := NewPayloadStream("", "printf abcdefgh")
+	if err != nil {
+		t.Fatalf("Failed to start the payload command: %v", err)
+	}
+	defer p.close()
+
+	waits := NewLatencyResult()
+	if r, err := p.next(3, waits); err != nil || waits.Count != 1 {
+		t.Fatalf("Expected the wait of the payload to be recorded but got %d, %v", waits.Count, err)
+	} else if data, _ := ioutil.ReadAll(r); string(data) != "abc" {
+		t.Fatalf("Expected payload %q but got %q", "abc", data)
+	}
+	if payload := readStream(t, p, 3); payload != "def" {
+		t.Fatalf("Expected payload %q but got %q", "def", payload)
+	}
+
+	// an object the output doesn't fill fails, and so does every object after the end of the output
+	if _, err = p.next(3, nil); err == nil {
+		t.Fatalf("Expected an error for a partial payload")
+	}
+	if _, err = p.next(3, nil); err == nil {
+		t.Fatalf("Expected an error after the end of the output")
+	}
+}
+
+func TestPayloadStreamPipe(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "s3tester-payload")
+	defer os.RemoveAll(dir)
+	pipe := filepath.Join(dir, "payloads")
+	if err := syscall.Mkfifo(pipe, 0600); err != nil {
+		t.Skipf("Named pipes are not supported: %v", err)
+	}
+
+	go func() {
+		f, err := os.OpenFile(pipe, os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		f.Write([]byte("first second"))
+		f.Close()
+	}()
+
+	p, err := NewPayloadStream(pipe, "")
+	if err != nil {
+		t.Fatalf("Failed to open the payload pipe: %v", err)
+	}
+	defer p.close()
+
+	for _, expected := range []string{"first", " seco"} {
+		if payload := readStream(t, p, 5); payload != expected {
+			t.Fatalf("Expected payload %q but got %q", expected, payload)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/time/rate"
+)
+
+// Phases of a run, in the order they run. The prepare phase writes the objects the run reads, the cleanup phase
+// deletes them afterwards, only the run itself is the measured workload.
+const (
+	phasePrepare = "prepare"
+	phaseRun     = "run"
+	phaseCleanup = "cleanup"
+)
+
+// Parses a comma separated list of phases like prepare,run,cleanup. A phase that is left out is skipped, e.g. to
+// prepare a dataset once and reuse it in later runs.
+func parsePhases(phases string) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, p := range strings.Split(phases, ",") {
+		p = strings.TrimSpace(p)
+		if p != phasePrepare && p != phaseRun && p != phaseCleanup {
+			return nil, errors.New("phases must be a list of prepare, run and cleanup")
+		}
+		set[p] = true
+	}
+	return set, nil
+}
+
+// Returns the parameters of the prepare or cleanup phase of the run, which writes or deletes every key of the run once
+// as fast as the workers go.
+func datasetPhaseArgs(args parameters, optype string) parameters {
+	phase := args
+	phase.optype = optype
+	phase.mix = nil
+	phase.readWhileWrite = 0
+	phase.heavyConcurrency = 0
+	phase.duration = &durationFlag{}
+	phase.maxDuration = 0
+	phase.attempts = 1
+	phase.keyDistribution = nil
+	phase.shuffleSeed = 0
+	phase.ratePerSecond = rate.Inf
+	phase.rate = 0
+	phase.loadShape = nil
+	return phase
+}
+
+// runPhases runs the phases of the run in order with run, and returns the results of the measured run and the number
+// of failed requests of the prepare and cleanup phases. An interrupted phase skips the rest.
+func runPhases(args parameters, run func(parameters) results) (results, int) {
+	var measured results
+	failures := 0
+	for _, p := range []string{phasePrepare, phaseRun, phaseCleanup} {
+		if !args.phases[p] || wasInterrupted() {
+			continue
+		}
+		if !args.isJson && len(args.phases) > 1 {
+			fmt.Printf("\n\t--- Phase: %s ---\n", p)
+		}
+		switch p {
+		case phasePrepare:
+			failures += run(datasetPhaseArgs(args, "put")).CummulativeResult.Failcount
+		case phaseRun:
+			measured = run(args)
+		case phaseCleanup:
+			failures += run(datasetPhaseArgs(args, "delete")).CummulativeResult.Failcount
+		}
+	}
+	return measured, failures
+}
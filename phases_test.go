@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestParsePhases(t *testing.T) {
+	phases, err := parsePhases("prepare, run,cleanup")
+	if err != nil || !reflect.DeepEqual(phases, map[string]bool{phasePrepare: true, phaseRun: true, phaseCleanup: true}) {
+		t.Fatalf("Wrong phases: %v %v", phases, err)
+	}
+
+	for _, invalid := range []string{"", "prepare,", "measure"} {
+		if _, err := parsePhases(invalid); err == nil {
+			t.Fatalf("phases %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestRunPhases(t *testing.T) {
+	args, err := parse([]string{"-operation=get", "-requests=1000", "-duration=10m", "-ratelimit=100", "-key-distribution=uniform", "-phases=cleanup,prepare,run"})
+	if err != nil {
+		t.Fatalf("valid phases should succeed: %v", err)
+	}
+	var ran []parameters
+	run := func(phase parameters) results {
+		ran = append(ran, phase)
+		r := NewResult()
+		r.Count = 1000
+		if phase.optype == "delete" {
+			r.Failcount = 3
+		}
+		return results{CummulativeResult: r}
+	}
+
+	measured, failures := runPhases(args, run)
+	if len(ran) != 3 || ran[0].optype != "put" || ran[1].optype != "get" || ran[2].optype != "delete" {
+		t.Fatalf("Expected prepare, run and cleanup in order but got %d phases", len(ran))
+	}
+
+	if failures != 3 || measured.CummulativeResult.Count != 1000 {
+		t.Fatalf("Expected the failures of the cleanup only but got %d", failures)
+	}
+
+	prepare := ran[0]
+	if prepare.duration.set || prepare.ratePerSecond != rate.Inf || prepare.keyDistribution != nil || prepare.nrequests.value != 1000 {
+		t.Fatalf("the prepare phase should write every key once as fast as possible: %+v", prepare)
+	}
+
+	if !ran[1].duration.set || ran[1].keyDistribution == nil {
+		t.Fatalf("the run should keep its settings")
+	}
+
+	ran = nil
+	args.phases = map[string]bool{phaseRun: true}
+	if runPhases(args, run); len(ran) != 1 || ran[0].optype != "get" {
+		t.Fatalf("Expected the run only")
+	}
+}
+
+func TestPhasesOptions(t *testing.T) {
+	if _, err := parse([]string{"-operation=get", "-phases=prepare,run"}); err != nil {
+		t.Fatalf("prepare and run should succeed: %v", err)
+	}
+
+	if _, err := parse([]string{"-operation=put", "-duration=10m", "-phases=run,cleanup"}); err == nil {
+		t.Fatalf("cleanup of a duration based run without requests should fail")
+	}
+
+	if _, err := parse([]string{"-operation=get", "-list-inventory", "-phases=run,cleanup"}); err == nil {
+		t.Fatalf("cleanup of the keys of an inventory should fail")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"golang.org/x/time/rate"
+)
+
+// The stages an object can flow through in the pipeline operation, in the order they are reported, and their operations.
+var pipelineStageNames = []string{"write", "read", "tag", "delete"}
+
+var pipelineStageOps = map[string]string{"write": "put", "read": "get", "tag": "puttagging", "delete": "delete"}
+
+// Parses a comma separated list of pipeline stages like 'write,read,delete'.
+func parsePipelineStages(stages string) ([]string, error) {
+	parsed := strings.Split(stages, ",")
+	seen := make(map[string]bool)
+	for _, stage := range parsed {
+		if _, ok := pipelineStageOps[stage]; !ok {
+			return nil, errors.New("pipeline stages must be some of " + strings.Join(pipelineStageNames, ", "))
+		}
+		if seen[stage] {
+			return nil, errors.New("pipeline stage " + stage + " is listed more than once")
+		}
+		seen[stage] = true
+	}
+	return parsed, nil
+}
+
+type pipelineObject struct {
+	key   string
+	start time.Time
+}
+
+// pipeline passes the objects of a worker through the stages. The worker runs the first stage itself and every other
+// stage runs in a goroutine of its own, connected by bounded queues. A stage that falls behind fills its queue and
+// blocks the stages before it, so the pipeline runs at the pace of its slowest stage.
+type pipeline struct {
+	stages []string
+	queues []chan pipelineObject
+	// each stage records into a result of its own, they are merged into the worker's result when the pipeline finishes
+	results   []result
+	lifecycle *latencyResult
+	done      sync.WaitGroup
+	// sends the request of a stage and returns whether it succeeded
+	send func(stage int, key string) bool
+}
+
+func NewPipeline(svc *s3.S3, httpClient *http.Client, args parameters, endpoint string, limiter *rate.Limiter) *pipeline {
+	p := &pipeline{
+		stages:    args.pipelineStages,
+		queues:    make([]chan pipelineObject, len(args.pipelineStages)),
+		results:   make([]result, len(args.pipelineStages)),
+		lifecycle: NewLatencyResult(),
+	}
+	stageArgs := make([]parameters, len(p.stages))
+	for i, stage := range p.stages {
+		p.results[i] = NewResult()
+		p.results[i].Endpoint = endpoint
+		stageArgs[i] = args
+		stageArgs[i].optype = pipelineStageOps[stage]
+	}
+	p.send = func(stage int, key string) bool {
+		r := &p.results[stage]
+		failed := r.Failcount
+		sendRequest(svc, httpClient, stageArgs[stage].optype, key, &stageArgs[stage], r, limiter)
+		return r.Failcount == failed
+	}
+	p.start(args.pipelineDepth)
+	return p
+}
+
+func (p *pipeline) start(depth int) {
+	for i := 1; i < len(p.stages); i++ {
+		p.queues[i] = make(chan pipelineObject, depth)
+		p.done.Add(1)
+		go func(stage int) {
+			defer p.done.Done()
+			for object := range p.queues[stage] {
+				p.process(stage, object)
+			}
+			if stage+1 < len(p.stages) {
+				close(p.queues[stage+1])
+			}
+		}(i)
+	}
+}
+
+// process runs a stage on the object and hands it on to the next stage. An object that fails a stage leaves the pipeline.
+func (p *pipeline) process(stage int, object pipelineObject) {
+	if !p.send(stage, object.key) {
+		return
+	}
+	if stage+1 < len(p.stages) {
+		p.queues[stage+1] <- object
+	} else {
+		// only the last stage records, so the lifecycle isn't shared between goroutines
+		p.lifecycle.record(time.Since(object.start))
+	}
+}
+
+// submit runs the object through the first stage, blocking while the queue of the second stage is full.
+func (p *pipeline) submit(key string) {
+	p.process(0, pipelineObject{key: key, start: time.Now()})
+}
+
+// finish waits for the objects in the pipeline to leave it and merges the stage results into the worker's result.
+// It is safe to call on a nil pipeline, which does nothing.
+func (p *pipeline) finish(r *result) {
+	if p == nil {
+		return
+	}
+	if len(p.stages) > 1 {
+		close(p.queues[1])
+	}
+	p.done.Wait()
+	for i, stage := range p.stages {
+		s := &p.results[i]
+		r.recordStage(stage, &latencyResult{Count: s.Count, elapsedSum: s.elapsedSum, latencies: s.latencies})
+		mergeResult(r, s)
+		r.data = append(r.data, s.data...)
+	}
+	r.LifecycleResult = r.LifecycleResult.merge(p.lifecycle)
+}
+
+func (this *result) recordStage(stage string, l *latencyResult) {
+	if this.StageResults == nil {
+		this.StageResults = make(map[string]*latencyResult)
+	}
+	this.StageResults[stage] = this.StageResults[stage].merge(l)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParsePipelineStages(t *testing.T) {
+	stages, err := parsePipelineStages("write,tag,read,delete")
+	if err != nil || len(stages) != 4 || stages[1] != "tag" {
+		t.Fatalf("Expected 4 stages but got %v, %v", stages, err)
+	}
+
+	for _, invalid := range []string{"write,copy", "write,read,write", ""} {
+		if _, err := parsePipelineStages(invalid); err == nil {
+			t.Fatalf("Expected stages %q to fail", invalid)
+		}
+	}
+}
+
+func testPipeline(stages []string, depth int, send func(stage int, key string) bool) *pipeline {
+	p := &pipeline{
+		stages:    stages,
+		queues:    make([]chan pipelineObject, len(stages)),
+		results:   make([]result, len(stages)),
+		lifecycle: NewLatencyResult(),
+		send:      send,
+	}
+	for i := range stages {
+		p.results[i] = NewResult()
+	}
+	p.start(depth)
+	return p
+}
+
+func TestPipeline(t *testing.T) {
+	var mu sync.Mutex
+	processed := make(map[string][]int)
+	p := testPipeline([]string{"write", "read", "delete"}, 2, func(stage int, key string) bool {
+		mu.Lock()
+		processed[key] = append(processed[key], stage)
+		mu.Unlock()
+		// the read of k2 fails
+		return !(key == "k2" && stage == 1)
+	})
+
+	for _, key := range []string{"k1", "k2", "k3"} {
+		p.submit(key)
+	}
+	r := NewResult()
+	p.finish(&r)
+
+	if len(processed["k1"]) != 3 || len(processed["k3"]) != 3 {
+		t.Fatalf("Expected k1 and k3 to pass all stages but got %v", processed)
+	}
+
+	if len(processed["k2"]) != 2 {
+		t.Fatalf("Expected k2 to leave the pipeline after its failed read but got %v", processed["k2"])
+	}
+
+	if r.LifecycleResult.Count != 2 {
+		t.Fatalf("Expected 2 completed lifecycles but got %d", r.LifecycleResult.Count)
+	}
+
+	if len(r.StageResults) != 3 {
+		t.Fatalf("Expected results of 3 stages but got %v", r.StageResults)
+	}
+}
+
+func TestPipelineBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	p := testPipeline([]string{"write", "delete"}, 1, func(stage int, key string) bool {
+		if stage == 1 {
+			<-release
+		}
+		return true
+	})
+
+	submitted := make(chan struct{})
+	go func() {
+		// the delete stage holds k1, k2 waits in its queue and k3 can't be handed on
+		for _, key := range []string{"k1", "k2", "k3"} {
+			p.submit(key)
+		}
+		close(submitted)
+	}()
+
+	select {
+	case <-submitted:
+		t.Fatalf("Expected the write stage to be blocked by the full queue of the delete stage")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-submitted
+	r := NewResult()
+	p.finish(&r)
+	if r.LifecycleResult.Count != 3 {
+		t.Fatalf("Expected 3 completed lifecycles but got %d", r.LifecycleResult.Count)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+func TestPresignedTransfer(t *testing.T) {
+	var uploaded []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("X-Amz-Signature") == "" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Method == "PUT" {
+			uploaded, _ = ioutil.ReadAll(r.Body)
+			return
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+	svc := MakeS3Service(server.Client(), 0, 0, server.URL, "us-east-1", "", credentials.NewStaticCredentials("id", "secret", ""))
+
+	n, signed, err := PresignedTransfer(svc, server.Client(), "test", "object-0", "PUT", time.Hour, 100, payloadOptions{}, nil)
+	if err != nil || n != 100 || len(uploaded) != 100 || signed <= 0 {
+		t.Fatalf("Presigned PUT failed: %d bytes, %d uploaded, signed in %s: %v", n, len(uploaded), signed, err)
+	}
+
+	if n, _, err = PresignedTransfer(svc, server.Client(), "test", "object-0", "GET", time.Hour, 100, payloadOptions{}, nil); err != nil || n != 10 {
+		t.Fatalf("Presigned GET failed: %d bytes: %v", n, err)
+	}
+}
+
+func TestPresignedTransferFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+	svc := MakeS3Service(server.Client(), 0, 0, server.URL, "us-east-1", "", credentials.NewStaticCredentials("id", "secret", ""))
+
+	if _, _, err := PresignedTransfer(svc, server.Client(), "test", "object-0", "GET", time.Hour, 100, payloadOptions{}, nil); err == nil {
+		t.Fatalf("Presigned GET answered with 403 should fail")
+	}
+}
+
+func TestPresignedTransferResponseOverrides(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType := r.URL.Query().Get("response-content-type"); contentType != "" && r.URL.Path == "/test/object-0" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+	svc := MakeS3Service(server.Client(), 0, 0, server.URL, "us-east-1", "", credentials.NewStaticCredentials("id", "secret", ""))
+	overrides, _ := parseResponseOverrides("content-type=text/x-test")
+
+	if _, _, err := PresignedTransfer(svc, server.Client(), "test", "object-0", "GET", time.Hour, 100, payloadOptions{}, overrides); err != nil {
+		t.Fatalf("Presigned GET with its overrides honored should succeed: %v", err)
+	}
+
+	// the server ignores the overrides of other objects
+	if _, _, err := PresignedTransfer(svc, server.Client(), "test", "object-1", "GET", time.Hour, 100, payloadOptions{}, overrides); err == nil {
+		t.Fatalf("Presigned GET without the requested overrides should fail")
+	}
+}
+
+func TestRecordPresignedTransfer(t *testing.T) {
+	r := NewResult()
+	r.recordPresignedTransfer(time.Millisecond, 9*time.Millisecond)
+	r.recordPresignedTransfer(3*time.Millisecond, 11*time.Millisecond)
+	r.PresignResult.setupStats()
+	r.TransferResult.setupStats()
+
+	if r.PresignResult.Count != 2 || r.PresignResult.AverageRequestTime != 2 || r.TransferResult.AverageRequestTime != 10 {
+		t.Fatalf("Wrong presign and transfer times: %+v %+v", r.PresignResult, r.TransferResult)
+	}
+}
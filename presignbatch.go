@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// presignExport writes the URLs generated by the presign operation to a file, for tools, browsers or another s3tester
+// on a different machine to use them. The expiries of the URLs go round a ladder of durations.
+type presignExport struct {
+	path   string
+	ladder []time.Duration
+
+	mu     sync.Mutex
+	next   int
+	file   *os.File
+	writer *csv.Writer
+	// the file is truncated when the first run starts, later runs like the steps of a ramp add to it
+	started bool
+}
+
+// Parses a ladder of expiries like 1m,15m,1h,24h, each between 1s and 168h.
+func parseExpiryLadder(ladder string) ([]time.Duration, error) {
+	var expiries []time.Duration
+	for _, s := range strings.Split(ladder, ",") {
+		expiry, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil || expiry < time.Second || expiry > 7*24*time.Hour {
+			return nil, fmt.Errorf("expiry %q of presign-expiry-ladder must be a duration between 1s and 168h", s)
+		}
+		expiries = append(expiries, expiry)
+	}
+	return expiries, nil
+}
+
+// NewPresignExport returns nil without a path.
+func NewPresignExport(path string, ladder []time.Duration) *presignExport {
+	if path == "" {
+		return nil
+	}
+	return &presignExport{path: path, ladder: ladder}
+}
+
+// expiry returns the expiry of the next URL.
+func (e *presignExport) expiry() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	expiry := e.ladder[e.next%len(e.ladder)]
+	e.next++
+	return expiry
+}
+
+// start opens the file the URLs are written to.
+func (e *presignExport) start() {
+	if e == nil {
+		return
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !e.started {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(e.path, flags, 0644)
+	if err != nil {
+		log.Fatalf("Failed to open the presigned URL file %s: %v", e.path, err)
+	}
+	e.file, e.writer, e.started = file, csv.NewWriter(file), true
+}
+
+// write adds a URL as a 'key,method,expires,url' CSV line, with the expiry time in RFC 3339.
+func (e *presignExport) write(key, method string, expires time.Time, url string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.writer.Write([]string{key, method, expires.UTC().Format(time.RFC3339), url})
+}
+
+func (e *presignExport) halt() {
+	if e == nil || e.file == nil {
+		return
+	}
+	e.writer.Flush()
+	if err := e.writer.Error(); err != nil {
+		log.Printf("Failed writing the presigned URL file %s: %v", e.path, err)
+	}
+	e.file.Close()
+	e.file = nil
+}
+
+// presignedURL is a URL written by a presign run with presign-export.
+type presignedURL struct {
+	method  string
+	expires time.Time
+	url     string
+}
+
+// loadPresignedURLs loads the URLs of a file written with presign-export, mapping every key to its URL.
+func loadPresignedURLs(path string) ([]string, map[string]presignedURL, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return readPresignedURLs(f)
+}
+
+func readPresignedURLs(r io.Reader) ([]string, map[string]presignedURL, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	var keys []string
+	urls := make(map[string]presignedURL)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		expires, err := time.Parse(time.RFC3339, record[2])
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d of the URL file has an invalid expiry time: %s", line, record[2])
+		}
+		if record[1] != "GET" && record[1] != "PUT" {
+			return nil, nil, fmt.Errorf("line %d of the URL file has an unsupported method: %s", line, record[1])
+		}
+		if _, ok := urls[record[0]]; ok {
+			// the URLs are looked up by key
+			return nil, nil, fmt.Errorf("key %s is in the URL file twice, the URLs of different runs must be used in separate runs", record[0])
+		}
+		keys = append(keys, record[0])
+		urls[record[0]] = presignedURL{method: record[1], expires: expires, url: record[3]}
+	}
+	if len(keys) == 0 {
+		return nil, nil, errors.New("the URL file has no URLs")
+	}
+	return keys, urls, nil
+}
+
+// recordExpiredURL counts a request sent with a URL after its expiry, which the server should have rejected.
+func (this *result) recordExpiredURL(accepted bool) {
+	if accepted {
+		this.ExpiredURLsAccepted++
+	} else {
+		this.ExpiredURLsRejected++
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExpiryLadder(t *testing.T) {
+	ladder, err := parseExpiryLadder("1m, 15m,24h")
+	if err != nil || len(ladder) != 3 || ladder[0] != time.Minute || ladder[2] != 24*time.Hour {
+		t.Fatalf("Wrong ladder: %v %v", ladder, err)
+	}
+
+	for _, invalid := range []string{"", "1m,", "500ms", "169h", "1x"} {
+		if _, err := parseExpiryLadder(invalid); err == nil {
+			t.Fatalf("ladder %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestPresignExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "presign")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "urls.csv")
+
+	e := NewPresignExport(file, []time.Duration{time.Minute, time.Hour})
+	e.start()
+	signedAt := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	for _, key := range []string{"object-0", "object-1", "object-2"} {
+		expiry := e.expiry()
+		e.write(key, "GET", signedAt.Add(expiry), "https://10.0.0.1/test/"+key+"?X-Amz-Expires="+expiry.String())
+	}
+	e.halt()
+
+	keys, urls, err := loadPresignedURLs(file)
+	if err != nil || len(keys) != 3 || keys[2] != "object-2" {
+		t.Fatalf("Wrong keys %v: %v", keys, err)
+	}
+
+	if u := urls["object-1"]; u.method != "GET" || !u.expires.Equal(signedAt.Add(time.Hour)) || !strings.HasPrefix(u.url, "https://10.0.0.1/test/object-1") {
+		t.Fatalf("Wrong URL of object-1: %+v", u)
+	}
+
+	if u := urls["object-2"]; !u.expires.Equal(signedAt.Add(time.Minute)) {
+		t.Fatalf("expiries should go round the ladder: %+v", u)
+	}
+
+	// a new run starts a new file
+	e = NewPresignExport(file, []time.Duration{time.Minute})
+	e.start()
+	e.write("object-9", "PUT", signedAt, "https://10.0.0.1/test/object-9")
+	e.halt()
+	if keys, _, _ = loadPresignedURLs(file); len(keys) != 1 {
+		t.Fatalf("Expected 1 URL but got %v", keys)
+	}
+
+	if NewPresignExport("", nil) != nil {
+		t.Fatalf("no file should give no export")
+	}
+}
+
+func TestReadPresignedURLsInvalid(t *testing.T) {
+	for _, invalid := range []string{
+		"",
+		"a,GET,2026-10-15T12:00:00Z\n",
+		"a,GET,tomorrow,https://10.0.0.1/test/a\n",
+		"a,DELETE,2026-10-15T12:00:00Z,https://10.0.0.1/test/a\n",
+		"a,GET,2026-10-15T12:00:00Z,https://10.0.0.1/test/a\na,GET,2026-10-15T13:00:00Z,https://10.0.0.1/test/a\n",
+	} {
+		if _, _, err := readPresignedURLs(strings.NewReader(invalid)); err == nil {
+			t.Fatalf("URL file %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestPresignedURLExpiry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/test/rejected" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	expired := time.Now().Add(-time.Minute)
+	args := parameters{optype: "presignedurl", presignedURLs: map[string]presignedURL{
+		"valid":    {method: "GET", expires: time.Now().Add(time.Hour), url: server.URL + "/test/valid"},
+		"rejected": {method: "GET", expires: expired, url: server.URL + "/test/rejected"},
+		"accepted": {method: "GET", expires: expired, url: server.URL + "/test/accepted"},
+	}}
+	r := NewResult()
+	for _, key := range []string{"valid", "rejected", "accepted"} {
+		err := DispatchOperation(nil, server.Client(), "presignedurl", key, &args, &r, 0)
+		if (err != nil) != (key == "rejected") {
+			t.Fatalf("Unexpected outcome of %s: %v", key, err)
+		}
+	}
+
+	if r.ExpiredURLsRejected != 1 || r.ExpiredURLsAccepted != 1 || r.sumObjSize != 20 {
+		t.Fatalf("Wrong expired URLs: %d rejected, %d accepted, %d bytes", r.ExpiredURLsRejected, r.ExpiredURLsAccepted, r.sumObjSize)
+	}
+}
+
+func TestPresignBatchOptions(t *testing.T) {
+	args, err := parse([]string{"-operation=presign", "-presign-export=urls.csv", "-presign-expiry-ladder=1m,1h"})
+	if err != nil || args.presignExport == nil || len(args.presignExport.ladder) != 2 {
+		t.Fatalf("presign-export should succeed: %+v %v", args.presignExport, err)
+	}
+
+	if args, _ = parse([]string{"-operation=presign", "-presign-export=urls.csv", "-presign-expiry=2h"}); args.presignExport.ladder[0] != 2*time.Hour {
+		t.Fatalf("without a ladder every URL should have the presign expiry: %v", args.presignExport.ladder)
+	}
+
+	if _, err = parse([]string{"-operation=presign", "-presign-expiry-ladder=1m,1h"}); err == nil {
+		t.Fatalf("presign-expiry-ladder without presign-export should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-presign-export=urls.csv"}); err == nil {
+		t.Fatalf("presign-export with get should fail")
+	}
+
+	dir, _ := ioutil.TempDir("", "presign")
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "urls.csv")
+	ioutil.WriteFile(file, []byte("a,GET,2026-10-15T12:00:00Z,https://10.0.0.1/test/a\nb,PUT,2026-10-15T12:00:00Z,https://10.0.0.1/test/b\n"), 0644)
+
+	if args, err = parse([]string{"-operation=presignedurl", "-concurrency=2", "-presign-urls=" + file}); err != nil || args.nrequests.value != 2 || len(args.presignedURLs) != 2 {
+		t.Fatalf("presignedurl should send a request per URL: %v", err)
+	}
+
+	if _, err = parse([]string{"-operation=presignedurl"}); err == nil {
+		t.Fatalf("presignedurl without presign-urls should fail")
+	}
+
+	if _, err = parse([]string{"-operation=get", "-presign-urls=" + file}); err == nil {
+		t.Fatalf("presign-urls with get should fail")
+	}
+}
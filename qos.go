@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// The traffic classes of a run with heavy-concurrency: the heavy class of bandwidth-heavy requests and the sensitive
+// class of latency-sensitive requests, whose requests are reported separately for the time before the heavy class
+// started and after.
+const (
+	heavyClass              = "heavy"
+	sensitiveClass          = "sensitive"
+	sensitiveAloneClass     = "sensitive-alone"
+	sensitiveContendedClass = "sensitive-contended"
+)
+
+// Waits until the given time, or until the run is interrupted.
+func waitUntil(t time.Time) {
+	for wait := time.Until(t); wait > 0 && !wasInterrupted(); wait = time.Until(t) {
+		if wait > 100*time.Millisecond {
+			wait = 100 * time.Millisecond
+		}
+		time.Sleep(wait)
+	}
+}
+
+// recordClass records a request of the traffic class of the worker. The requests of the sensitive class sent before
+// the heavy class started are recorded apart from the ones sent while both ran.
+func (this *result) recordClass(class string, alone bool, l time.Duration, bytes int64, failed bool) {
+	if class == sensitiveClass {
+		class = sensitiveContendedClass
+		if alone {
+			class = sensitiveAloneClass
+		}
+	}
+	if this.ClassResults == nil {
+		this.ClassResults = make(map[string]*operationResult)
+	}
+	s, ok := this.ClassResults[class]
+	if !ok {
+		s = &operationResult{latencyResult: NewLatencyResult()}
+		this.ClassResults[class] = s
+	}
+	s.record(l)
+	s.Bytes += bytes
+	if failed {
+		s.Failcount++
+	}
+}
+
+func (this *result) mergeClassResults(other *result) {
+	for class, o := range other.ClassResults {
+		if this.ClassResults == nil {
+			this.ClassResults = make(map[string]*operationResult)
+		}
+		s, ok := this.ClassResults[class]
+		if !ok {
+			s = &operationResult{}
+			this.ClassResults[class] = s
+		}
+		s.latencyResult = s.latencyResult.merge(o.latencyResult)
+		s.Failcount += o.Failcount
+		s.Bytes += o.Bytes
+	}
+}
+
+// qosChange is a measure of the sensitive class alone and while the heavy class ran, and its change in percent.
+type qosChange struct {
+	Alone     float64 `json:"alone"`
+	Contended float64 `json:"contended"`
+	Change    float64 `json:"change (%)"`
+}
+
+func newQoSChange(alone, contended float64) qosChange {
+	c := qosChange{Alone: alone, Contended: contended}
+	if alone > 0 {
+		c.Change = roundFloat(100*(contended-alone)/alone, 1)
+	}
+	return c
+}
+
+// qosReport tells how much the heavy class degraded the sensitive class of a run with traffic classes.
+type qosReport struct {
+	HeavyStart float64 `json:"heavyStart (s)"`
+	// the average and percentiles of the latency of the sensitive class, only with requests before the heavy class started
+	Latency    map[string]qosChange `json:"sensitiveLatency (ms),omitempty"`
+	Throughput *qosChange           `json:"sensitiveRequestsPerSec,omitempty"`
+	// the requests of the heavy class and its throughput while it ran
+	HeavyRequests   int     `json:"heavyRequests"`
+	HeavyThroughput float64 `json:"heavyThroughput (MiB/s)"`
+}
+
+// Returns the QoS report of a run with traffic classes whose heavy class started after heavyStart, nil for other runs.
+func NewQoSReport(heavyConcurrency int, heavyStart time.Duration, r result) *qosReport {
+	if heavyConcurrency == 0 {
+		return nil
+	}
+	report := &qosReport{HeavyStart: heavyStart.Seconds()}
+	contendedTime := r.elapsedTime - heavyStart
+	if heavy := r.ClassResults[heavyClass]; heavy != nil && contendedTime > 0 {
+		report.HeavyRequests = heavy.Count
+		report.HeavyThroughput = roundFloat(float64(heavy.Bytes)/1024/1024/contendedTime.Seconds(), 3)
+	}
+	alone, contended := r.ClassResults[sensitiveAloneClass], r.ClassResults[sensitiveContendedClass]
+	if alone == nil || contended == nil || alone.Count == 0 || contended.Count == 0 || contendedTime <= 0 {
+		return report
+	}
+	report.Latency = map[string]qosChange{"average": newQoSChange(alone.AverageRequestTime, contended.AverageRequestTime)}
+	for p, latency := range alone.Percentiles {
+		report.Latency[p] = newQoSChange(latency, contended.Percentiles[p])
+	}
+	throughput := newQoSChange(roundFloat(float64(alone.Count)/heavyStart.Seconds(), 1), roundFloat(float64(contended.Count)/contendedTime.Seconds(), 1))
+	report.Throughput = &throughput
+	return report
+}
+
+func printQoSReport(q *qosReport) {
+	if q == nil {
+		return
+	}
+	fmt.Println("\n\t--- Traffic Classes ---")
+	fmt.Printf("Heavy class: %d requests, %.3f MiB/s after %s\n", q.HeavyRequests, q.HeavyThroughput, time.Duration(q.HeavyStart*float64(time.Second)))
+	if q.Latency == nil {
+		fmt.Println("No requests of the sensitive class alone and with the heavy class to compare, heavy-start must leave time for both")
+		return
+	}
+	fmt.Printf("%-24s %12s %12s %10s\n", "Sensitive class", "Alone", "Contended", "Change")
+	rows := []string{"average"}
+	for _, p := range percentiles {
+		rows = append(rows, convertFloatToString(p))
+	}
+	for _, row := range rows {
+		c, ok := q.Latency[row]
+		if !ok {
+			continue
+		}
+		name := "Average (ms)"
+		if row != "average" {
+			name = fmt.Sprintf("%sth percentile (ms)", row)
+		}
+		fmt.Printf("%-24s %12.3f %12.3f %9.1f%%\n", name, c.Alone, c.Contended, c.Change)
+	}
+	fmt.Printf("%-24s %12.1f %12.1f %9.1f%%\n", "Requests/s", q.Throughput.Alone, q.Throughput.Contended, q.Throughput.Change)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordClass(t *testing.T) {
+	r := NewResult()
+	r.recordClass(sensitiveClass, true, 2*time.Millisecond, 100, false)
+	r.recordClass(sensitiveClass, false, 4*time.Millisecond, 100, true)
+	r.recordClass(heavyClass, false, time.Second, 64<<20, false)
+
+	other := NewResult()
+	other.recordClass(sensitiveClass, false, 6*time.Millisecond, 100, false)
+	r.mergeClassResults(&other)
+
+	alone, contended, heavy := r.ClassResults[sensitiveAloneClass], r.ClassResults[sensitiveContendedClass], r.ClassResults[heavyClass]
+	if alone == nil || alone.Count != 1 || contended == nil || contended.Count != 2 || contended.Failcount != 1 || heavy == nil || heavy.Bytes != 64<<20 {
+		t.Fatalf("Unexpected class results %+v", r.ClassResults)
+	}
+}
+
+func TestQoSReport(t *testing.T) {
+	if NewQoSReport(0, 0, NewResult()) != nil {
+		t.Fatalf("Expected no report without traffic classes")
+	}
+
+	r := NewResult()
+	for i := 0; i < 100; i++ {
+		r.recordClass(sensitiveClass, true, 2*time.Millisecond, 0, false)
+	}
+	for i := 0; i < 400; i++ {
+		r.recordClass(sensitiveClass, false, 3*time.Millisecond, 0, false)
+	}
+	r.recordClass(heavyClass, false, time.Second, 200<<20, false)
+	for _, s := range r.ClassResults {
+		s.setupStats()
+	}
+	r.elapsedTime = 30 * time.Second
+
+	q := NewQoSReport(4, 10*time.Second, r)
+	if q.HeavyRequests != 1 || q.HeavyThroughput != 10 {
+		t.Fatalf("Expected 200MiB in 20s of the heavy class but got %+v", q)
+	}
+	if average := q.Latency["average"]; average.Alone != 2 || average.Contended != 3 || average.Change != 50 {
+		t.Fatalf("Expected the average latency to grow by 50%% but got %+v", average)
+	}
+	if p99 := q.Latency["99"]; p99.Change != 50 {
+		t.Fatalf("Expected the 99th percentile to grow by 50%% but got %+v", p99)
+	}
+	if q.Throughput.Alone != 10 || q.Throughput.Contended != 20 || q.Throughput.Change != 100 {
+		t.Fatalf("Unexpected throughput %+v", q.Throughput)
+	}
+
+	// without a heavy start there is nothing to compare with
+	delete(r.ClassResults, sensitiveAloneClass)
+	if q := NewQoSReport(4, 0, r); q.Latency != nil || q.Throughput != nil {
+		t.Fatalf("Expected no comparison without requests of the sensitive class alone but got %+v", q)
+	}
+}
+
+func TestTrafficClassFlags(t *testing.T) {
+	args, err := parse([]string{"-operation=head", "-requests=10000", "-concurrency=16", "-duration=3m", "-heavy-concurrency=4", "-heavy-size=1048576", "-heavy-ratelimit=20", "-heavy-start=1m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.heavyConcurrency != 4 || args.heavyOperation != "put" || args.heavySize != 1<<20 || args.heavyRatePerSecond != 20 || args.heavyStart != time.Minute {
+		t.Fatalf("Unexpected heavy class %d %s %d %v %s", args.heavyConcurrency, args.heavyOperation, args.heavySize, args.heavyRatePerSecond, args.heavyStart)
+	}
+	if phase := datasetPhaseArgs(args, "put"); phase.heavyConcurrency != 0 {
+		t.Fatalf("Expected the dataset phases to run without traffic classes")
+	}
+
+	for _, cmdline := range [][]string{
+		{"-concurrency=16", "-heavy-concurrency=4"},
+		{"-concurrency=16", "-duration=3m", "-heavy-concurrency=16"},
+		{"-concurrency=16", "-duration=3m", "-heavy-concurrency=4", "-heavy-operation=delete"},
+		{"-concurrency=16", "-duration=3m", "-heavy-concurrency=4", "-heavy-start=3m"},
+		{"-concurrency=16", "-duration=3m", "-heavy-concurrency=4", "-heavy-size=0"},
+		{"-concurrency=16", "-duration=3m", "-heavy-concurrency=4", "-mix=put:1,get:1"},
+		{"-concurrency=16", "-duration=3m", "-heavy-start=1m"},
+	} {
+		if _, err := parse(cmdline); err == nil {
+			t.Fatalf("Expected %v to be rejected", cmdline)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rampSegment is a part of a concurrency ramp, in which the concurrency goes from from to to over the duration. The
+// concurrency of a hold stays the same.
+type rampSegment struct {
+	from     int
+	to       int
+	duration time.Duration
+}
+
+// rampSchedule changes the number of workers of a run over time, to find the concurrency at which the throughput
+// stops growing in a single run. The segments are split into steps of about the step length, each step is a duration
+// based run at a fixed concurrency.
+type rampSchedule struct {
+	segments []rampSegment
+	step     time.Duration
+}
+
+// rampStep is a run of a ramp at one concurrency.
+type rampStep struct {
+	concurrency int
+	duration    time.Duration
+}
+
+// Parses a ramp like "0->200 over 5m, hold 10m, 200->0 over 2m". A hold keeps the concurrency the previous segment
+// ended with.
+func parseRamp(schedule string, step time.Duration) (*rampSchedule, error) {
+	if step <= 0 {
+		return nil, errors.New("ramp-step must be > 0")
+	}
+	ramp := &rampSchedule{step: step}
+	for _, entry := range strings.Split(schedule, ",") {
+		entry = strings.TrimSpace(entry)
+		var segment rampSegment
+		var length string
+		if strings.HasPrefix(entry, "hold ") {
+			if len(ramp.segments) == 0 {
+				return nil, errors.New("ramp must start with a concurrency like 0->200 over 5m before a hold: " + entry)
+			}
+			segment.from = ramp.segments[len(ramp.segments)-1].to
+			segment.to = segment.from
+			length = strings.TrimPrefix(entry, "hold ")
+		} else {
+			format := errors.New("ramp must be formatted like 0->200 over 5m, hold 10m, 200->0 over 2m: " + entry)
+			i := strings.Index(entry, " over ")
+			if i < 0 {
+				return nil, format
+			}
+			bounds := strings.Split(entry[:i], "->")
+			if len(bounds) != 2 {
+				return nil, format
+			}
+			from, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err == nil {
+				segment.to, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			}
+			if err != nil || from < 0 || segment.to < 0 {
+				return nil, errors.New("ramp concurrencies must be integers >= 0: " + entry)
+			}
+			segment.from = from
+			length = entry[i+len(" over "):]
+		}
+		var err error
+		if segment.duration, err = time.ParseDuration(strings.TrimSpace(length)); err != nil || segment.duration <= 0 {
+			return nil, errors.New("ramp durations must be like 5m: " + entry)
+		}
+		if segment.from == 0 && segment.to == 0 {
+			return nil, errors.New("ramp segment has no workers: " + entry)
+		}
+		ramp.segments = append(ramp.segments, segment)
+	}
+	return ramp, nil
+}
+
+// peak returns the highest concurrency of the ramp.
+func (r *rampSchedule) peak() int {
+	peak := 0
+	for _, s := range r.segments {
+		if s.from > peak {
+			peak = s.from
+		}
+		if s.to > peak {
+			peak = s.to
+		}
+	}
+	return peak
+}
+
+// steps splits the segments into steps of equal length. The concurrency of a step is the concurrency in the middle
+// of it, rounded up to a multiple of the number of endpoints.
+func (r *rampSchedule) steps(endpoints int) []rampStep {
+	var steps []rampStep
+	for _, s := range r.segments {
+		n := int((s.duration + r.step - 1) / r.step)
+		for i := 0; i < n; i++ {
+			concurrency := int(math.Round(float64(s.from) + float64(s.to-s.from)*float64(2*i+1)/float64(2*n)))
+			if remainder := concurrency % endpoints; remainder != 0 || concurrency == 0 {
+				concurrency += endpoints - remainder
+			}
+			steps = append(steps, rampStep{concurrency: concurrency, duration: s.duration / time.Duration(n)})
+		}
+	}
+	return steps
+}
+
+// runRamp runs the steps of the ramp one after the other, reporting the results of every step, and returns the
+// number of failed requests of all of them.
+func runRamp(args parameters) int {
+	steps := args.ramp.steps(len(args.endpoints))
+	rates := make([]float64, 0, len(steps))
+	p99s := make([]float64, 0, len(steps))
+	failures := 0
+	for i, step := range steps {
+		if !args.isJson {
+			fmt.Printf("\n\t--- Ramp step %d/%d: concurrency %d for %s ---\n", i+1, len(steps), step.concurrency, step.duration)
+		}
+		args.concurrency = step.concurrency
+		args.duration = &durationFlag{set: true, value: step.duration}
+		rate, r := runtest(args)
+		rates = append(rates, rate)
+		p99s = append(p99s, r.CummulativeResult.Percentiles["99"])
+		failures += r.CummulativeResult.Failcount
+		if wasInterrupted() {
+			steps = steps[:i+1]
+			break
+		}
+	}
+	if !args.isJson {
+		printRampSummary(steps, rates, p99s)
+	}
+	return failures
+}
+
+// printRampSummary prints the throughput of every step and the step with the highest throughput.
+func printRampSummary(steps []rampStep, rates, p99s []float64) {
+	fmt.Println("\n\t--- Ramp summary ---")
+	best := 0
+	for i, step := range steps {
+		fmt.Printf("Concurrency %d ===> %.1f requests/s, p99 %.2f ms\n", step.concurrency, rates[i], p99s[i])
+		if rates[i] > rates[best] {
+			best = i
+		}
+	}
+	fmt.Printf("Highest throughput: %.1f requests/s at concurrency %d\n", rates[best], steps[best].concurrency)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRamp(t *testing.T) {
+	ramp, err := parseRamp("0->200 over 5m, hold 10m, 200->0 over 2m", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []rampSegment{{0, 200, 5 * time.Minute}, {200, 200, 10 * time.Minute}, {200, 0, 2 * time.Minute}}
+	if !reflect.DeepEqual(ramp.segments, expected) {
+		t.Fatalf("Wrong segments: %+v", ramp.segments)
+	}
+	if ramp.peak() != 200 {
+		t.Fatalf("Wrong peak %d", ramp.peak())
+	}
+
+	for _, invalid := range []string{"hold 5m", "0->200", "0-200 over 5m", "a->200 over 5m", "0->200 over 5", "0->0 over 5m", "0->200 over 5m, hold"} {
+		if _, err := parseRamp(invalid, time.Minute); err == nil {
+			t.Fatalf("ramp %s should be invalid", invalid)
+		}
+	}
+	if _, err := parseRamp("0->200 over 5m", 0); err == nil {
+		t.Fatalf("ramp step 0 should be invalid")
+	}
+}
+
+func TestRampSteps(t *testing.T) {
+	ramp, _ := parseRamp("0->200 over 5m, hold 2m, 200->0 over 90s", time.Minute)
+	var concurrencies []int
+	var total time.Duration
+	for _, step := range ramp.steps(1) {
+		concurrencies = append(concurrencies, step.concurrency)
+		total += step.duration
+	}
+	if !reflect.DeepEqual(concurrencies, []int{20, 60, 100, 140, 180, 200, 200, 150, 50}) {
+		t.Fatalf("Wrong concurrency of the steps: %v", concurrencies)
+	}
+	if total != 8*time.Minute+30*time.Second {
+		t.Fatalf("Wrong total duration of the steps: %s", total)
+	}
+
+	// rounded up to a multiple of the endpoints
+	ramp, _ = parseRamp("0->5 over 2m", time.Minute)
+	concurrencies = nil
+	for _, step := range ramp.steps(4) {
+		concurrencies = append(concurrencies, step.concurrency)
+	}
+	if !reflect.DeepEqual(concurrencies, []int{4, 4}) {
+		t.Fatalf("Wrong concurrency of the steps with 4 endpoints: %v", concurrencies)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The acknowledged writes kept per key, the staleness of a read is measured from the oldest of them that is newer
+// than the generation read.
+const ackHistory = 16
+
+// Returns the seed of the data of a generation of an object, so that every write of a key has data of its own and a
+// read mixing the data of two writes can be told apart.
+func generationSeed(key string, generation int64) string {
+	return key + "@" + strconv.FormatInt(generation, 10)
+}
+
+// writeAck is a write the server acknowledged, the generation of the object and the time the write returned.
+type writeAck struct {
+	generation int64
+	at         time.Time
+}
+
+// writeAcks holds the writes acknowledged to the writers of a read-while-write run, which the readers compare the
+// generations they read with. It is shared by all workers.
+type writeAcks struct {
+	mu   sync.Mutex
+	keys map[string][]writeAck
+	// reads that are behind the acknowledged writes for up to this long are not stale
+	maxStaleness time.Duration
+	// the generation of the first write of the writers, older generations were written before the run with the data
+	// of their key
+	first int64
+}
+
+func NewWriteAcks(maxStaleness time.Duration) *writeAcks {
+	return &writeAcks{keys: make(map[string][]writeAck), maxStaleness: maxStaleness}
+}
+
+// begin records the generation of a write the writers are about to send.
+func (a *writeAcks) begin(generation int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.first == 0 || generation < a.first {
+		a.first = generation
+	}
+}
+
+// Returns the seed of the data of the generation of a key, which is the key itself for objects written before the
+// writers started.
+func (a *writeAcks) seed(key string, generation int64) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.first == 0 || generation < a.first {
+		return key
+	}
+	return generationSeed(key, generation)
+}
+
+func (a *writeAcks) acknowledge(key string, generation int64, at time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	acks := append(a.keys[key], writeAck{generation, at})
+	// writes of a key can be acknowledged out of order
+	for i := len(acks) - 1; i > 0 && acks[i].generation < acks[i-1].generation; i-- {
+		acks[i], acks[i-1] = acks[i-1], acks[i]
+	}
+	if len(acks) > ackHistory {
+		acks = acks[len(acks)-ackHistory:]
+	}
+	a.keys[key] = acks
+}
+
+// behind returns how long a newer write than the generation had been acknowledged when a read of the key started,
+// and false if the read returned the newest write acknowledged by then.
+func (a *writeAcks) behind(key string, generation int64, readStart time.Time) (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var window time.Duration
+	behind := false
+	for _, ack := range a.keys[key] {
+		if ack.generation > generation && ack.at.Before(readStart) {
+			if since := readStart.Sub(ack.at); since > window {
+				window = since
+			}
+			behind = true
+		}
+	}
+	return window, behind
+}
+
+// freshnessResult holds the reads of a read-while-write run compared with the writes acknowledged before they
+// started. A read is behind if it returned an older generation than one acknowledged before, and stale if it was
+// behind for longer than the staleness allowed. A torn read returned data that doesn't match its generation.
+type freshnessResult struct {
+	Reads       int `json:"checkedReads"`
+	BehindReads int `json:"behindReads"`
+	StaleReads  int `json:"staleReads"`
+	TornReads   int `json:"tornReads"`
+	// how long the acknowledged writes had been hidden from the reads that were behind
+	Windows      *latencyResult `json:"stalenessWindows"`
+	MaxStaleness float64        `json:"maxStaleness (ms)"`
+
+	acks *writeAcks
+	// ranges of a parallelget are read concurrently
+	mu sync.Mutex
+}
+
+func NewFreshnessResult(acks *writeAcks) *freshnessResult {
+	return &freshnessResult{Windows: NewLatencyResult(), MaxStaleness: float64(acks.maxStaleness) / float64(time.Millisecond), acks: acks}
+}
+
+// check compares the generation read of the key with the writes acknowledged before the read started and verifies
+// that the rest of the body holds the data of the generation.
+func (this *freshnessResult) check(key string, generation int64, readStart time.Time, body io.Reader, payload payloadOptions) error {
+	window, behind := this.acks.behind(key, generation, readStart)
+	torn := verifyObjectData(body, this.acks.seed(key, generation), generationHeaderSize, 1, 0, payload) != nil
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.Reads++
+	if behind {
+		this.BehindReads++
+		this.Windows.record(window)
+		if window > this.acks.maxStaleness {
+			this.StaleReads++
+			log.Printf("Stale read of '%s': a newer write had been acknowledged %s before the read started", key, window)
+		}
+	}
+	if torn {
+		this.TornReads++
+		return fmt.Errorf("Torn read of %s: the data doesn't match the write of generation %d", key, generation)
+	}
+	return nil
+}
+
+// merge adds the other result to this one and returns the merged result, which is newly allocated if this one is nil.
+func (this *freshnessResult) merge(other *freshnessResult) *freshnessResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = NewFreshnessResult(other.acks)
+	}
+	this.Reads += other.Reads
+	this.BehindReads += other.BehindReads
+	this.StaleReads += other.StaleReads
+	this.TornReads += other.TornReads
+	this.Windows = this.Windows.merge(other.Windows)
+	return this
+}
+
+func (this *freshnessResult) setupStats() {
+	if this == nil {
+		return
+	}
+	this.Windows.setupStats()
+}
+
+func printFreshnessResult(freshness *freshnessResult) {
+	fmt.Printf("Reads checked against acknowledged writes: %d\n", freshness.Reads)
+	fmt.Printf("Reads behind an acknowledged write: %d\n", freshness.BehindReads)
+	fmt.Printf("Stale reads (behind for more than %s): %d\n", time.Duration(freshness.MaxStaleness*float64(time.Millisecond)), freshness.StaleReads)
+	fmt.Printf("Torn reads: %d\n", freshness.TornReads)
+	if freshness.Windows.Count > 0 {
+		fmt.Println("Staleness windows")
+		printLatencyResult(freshness.Windows)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteAcksBehind(t *testing.T) {
+	acks := NewWriteAcks(time.Second)
+	start := time.Unix(1000, 0)
+	acks.acknowledge("a", 20, start.Add(2*time.Second))
+	acks.acknowledge("a", 10, start)
+	acks.acknowledge("a", 30, start.Add(5*time.Second))
+
+	if _, behind := acks.behind("a", 30, start.Add(10*time.Second)); behind {
+		t.Fatalf("Expected a read of the latest generation not to be behind")
+	}
+	if _, behind := acks.behind("b", 10, start.Add(10*time.Second)); behind {
+		t.Fatalf("Expected a read of a key without acknowledged writes not to be behind")
+	}
+	// the write of generation 30 was not acknowledged before the read started
+	if _, behind := acks.behind("a", 20, start.Add(3*time.Second)); behind {
+		t.Fatalf("Expected a read of the latest acknowledged generation not to be behind")
+	}
+	// writes acknowledged out of order are measured from the oldest newer one
+	if window, behind := acks.behind("a", 10, start.Add(6*time.Second)); !behind || window != 4*time.Second {
+		t.Fatalf("Expected the read to be behind for 4s but got %s, %v", window, behind)
+	}
+
+	for i := int64(0); i < 2*ackHistory; i++ {
+		acks.acknowledge("c", i, start)
+	}
+	if len(acks.keys["c"]) != ackHistory || acks.keys["c"][0].generation != ackHistory {
+		t.Fatalf("Expected the %d newest acknowledged writes to be kept but got %v", ackHistory, acks.keys["c"])
+	}
+}
+
+func TestFreshnessCheck(t *testing.T) {
+	key := "object-0"
+	var size int64 = 2*objectDataBlockSize + 100
+	acks := NewWriteAcks(time.Second)
+	start := time.Now()
+	acks.begin(start.UnixNano())
+
+	write := func(generation int64) []byte {
+		payload := payloadOptions{generations: NewGenerationResult(), generation: generation}
+		data, _ := ioutil.ReadAll(NewPayloadReader(size, key, payload))
+		return data
+	}
+	read := func(freshness *freshnessResult, data []byte, readStart time.Time) error {
+		generation, err := NewGenerationResult().check(key, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Expected the generation check to succeed but got: %v", err)
+		}
+		return freshness.check(key, generation, readStart, bytes.NewReader(data[generationHeaderSize:]), payloadOptions{})
+	}
+
+	first, second := start.UnixNano(), start.Add(time.Millisecond).UnixNano()
+	older, newer := write(first), write(second)
+	acks.acknowledge(key, first, start)
+	acks.acknowledge(key, second, start.Add(time.Second))
+
+	freshness := NewFreshnessResult(acks)
+	if err := read(freshness, newer, start.Add(3*time.Second)); err != nil {
+		t.Fatalf("Expected a read of the latest write to succeed but got: %v", err)
+	}
+	if err := read(freshness, older, start.Add(1500*time.Millisecond)); err != nil {
+		t.Fatalf("Expected a read behind the latest write to succeed but got: %v", err)
+	}
+	if err := read(freshness, older, start.Add(3*time.Second)); err != nil {
+		t.Fatalf("Expected a stale read to succeed but got: %v", err)
+	}
+	if freshness.Reads != 3 || freshness.BehindReads != 2 || freshness.StaleReads != 1 || freshness.TornReads != 0 {
+		t.Fatalf("Expected 3 reads, 2 behind and 1 stale but got %+v", freshness)
+	}
+
+	// the header of one write followed by the data of another
+	torn := append(append([]byte{}, older[:generationHeaderSize]...), newer[generationHeaderSize:]...)
+	if err := read(freshness, torn, start.Add(3*time.Second)); err == nil || !strings.Contains(err.Error(), "Torn read") {
+		t.Fatalf("Expected a torn read but got: %v", err)
+	}
+	if freshness.TornReads != 1 {
+		t.Fatalf("Expected 1 torn read but got %d", freshness.TornReads)
+	}
+
+	// objects written before the writers started hold the data of their key
+	before, _ := ioutil.ReadAll(NewPayloadReader(size, key, payloadOptions{generations: NewGenerationResult()}))
+	freshness = NewFreshnessResult(NewWriteAcks(0))
+	freshness.acks.begin(time.Now().Add(time.Hour).UnixNano())
+	if err := read(freshness, before, time.Now()); err != nil {
+		t.Fatalf("Expected an object written before the run to verify but got: %v", err)
+	}
+}
+
+func TestFreshnessMerge(t *testing.T) {
+	acks := NewWriteAcks(0)
+	a := NewFreshnessResult(acks)
+	a.Reads, a.BehindReads, a.StaleReads, a.TornReads = 3, 2, 1, 0
+	a.Windows.record(time.Second)
+	b := NewFreshnessResult(acks)
+	b.Reads, b.TornReads = 2, 1
+
+	var merged *freshnessResult
+	merged = merged.merge(a).merge(b).merge(nil)
+	merged.setupStats()
+	if merged.Reads != 5 || merged.BehindReads != 2 || merged.StaleReads != 1 || merged.TornReads != 1 || merged.Windows.Count != 1 {
+		t.Fatalf("Unexpected merged result %+v", merged)
+	}
+}
+
+func TestReadWhileWriteOptions(t *testing.T) {
+	args, err := parse([]string{"-read-while-write=2", "-concurrency=8", "-overwrite-keys=100", "-size=1024", "-duration=10", "-max-staleness=1s"})
+	if err != nil {
+		t.Fatalf("Expected read-while-write to parse but got: %v", err)
+	}
+	if args.optype != "get" || !args.generations || args.readWhileWrite != 2 || args.writeAcks == nil || args.writeAcks.maxStaleness != time.Second || args.keyDistribution == nil {
+		t.Fatalf("Unexpected read-while-write parameters %+v", args)
+	}
+
+	// the prepare phase writes the keys once with every worker
+	args, err = parse([]string{"-read-while-write=8", "-concurrency=64", "-overwrite-keys=1000", "-requests=1000", "-phases=prepare,run", "-size=65536", "-duration=300"})
+	if err != nil {
+		t.Fatalf("Expected read-while-write with a prepare phase to parse but got: %v", err)
+	}
+	if prepare := datasetPhaseArgs(args, "put"); prepare.readWhileWrite != 0 {
+		t.Fatalf("Expected the prepare phase not to split the workers")
+	}
+
+	invalid := [][]string{
+		{"-read-while-write=8", "-concurrency=8", "-overwrite-keys=100"},
+		{"-read-while-write=2", "-concurrency=8"},
+		{"-read-while-write=2", "-concurrency=8", "-overwrite-keys=100", "-operation=put"},
+		{"-read-while-write=2", "-concurrency=8", "-overwrite-keys=100", "-mix=get:50,put:50"},
+		{"-read-while-write=2", "-concurrency=8", "-overwrite-keys=100", "-size=8"},
+		{"-max-staleness=1s"},
+	}
+	for _, cmdline := range invalid {
+		if _, err := parse(cmdline); err == nil {
+			t.Fatalf("Expected %v to fail", cmdline)
+		}
+	}
+}
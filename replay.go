@@ -9,9 +9,9 @@ import (
 	"log"
 	"math"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 type s3op struct {
@@ -19,6 +19,13 @@ type s3op struct {
 	Size   uint64 `json:"size"`
 	Bucket string `json:"bucket"`
 	Key    string `json:"key"`
+	Range  string `json:"range"` // byte range of a get, the whole object if empty
+}
+
+// replayedRequest is a request of a log or trace that is replayed at the time it was originally sent.
+type replayedRequest struct {
+	op   s3op
+	time time.Time
 }
 
 type workerChan struct {
@@ -122,9 +129,33 @@ func Replay(args *parameters, workload *workloadParams) {
 	}
 }
 
+// Sends the replayed requests to the workers. Every key is replayed by the same worker, so that the requests of a key
+// are sent in their original order. With a replay speed, the requests are sent at their original times relative to the
+// first request, speed times as fast, otherwise as fast as the workers go.
+func sendReplayedRequests(args *parameters, workload *workloadParams, requests <-chan replayedRequest) {
+	var first time.Time
+	start := time.Now()
+	for request := range requests {
+		if args.replaySpeed > 0 {
+			if first.IsZero() {
+				first = request.time
+			}
+			offset := time.Duration(float64(request.time.Sub(first)) / args.replaySpeed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		splitS3ops(workload, []s3op{request.op}, args.endpoints[0], args.region)
+	}
+}
+
 // Splits up each []s3op into single s3op and sends to approriate worker
 func splitS3ops(params *workloadParams, ops []s3op, endpoint string, region string) {
 	for _, op := range ops {
+		// the workers stop sending once the run is interrupted, the rest of the file is skipped
+		if wasInterrupted() {
+			return
+		}
 		// add s3tester to bucket name so it is a distinct/fresh bucket
 		bucketReplay := op.Bucket + "s3tester"
 		if _, ok := params.bucketMap[bucketReplay]; !ok {
@@ -185,7 +216,7 @@ func generateRequests(args *parameters, ratios []opTrack, workload *workloadPara
 		leftover := math.Min(100.0, float64(totalOps-sent))
 		for _, v := range ratios {
 			for i := 0; i < int(math.Floor((float64(v.Ratio)/100.0)*leftover)); i++ {
-				op := s3op{Event: v.Optype, Size: uint64(args.osize), Bucket: args.bucketname, Key: args.objectprefix + "-" + strconv.FormatInt(v.sent, 10)}
+				op := s3op{Event: v.Optype, Size: uint64(args.osize), Bucket: args.bucketname, Key: args.keyTemplate.name(args.objectprefix, v.sent)}
 				sent += 1
 				v.sent += 1
 				sendS3op(op, workload, args.endpoints[0], args.region)
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// requestExtras holds extra headers and query parameters sent with every request, to exercise vendor specific
+// extensions like debug headers or requester pays without code changes.
+type requestExtras struct {
+	header http.Header
+	query  url.Values
+}
+
+// Parses headers given as 'Name: value' and query parameters given as 'name=value' or just 'name'. Returns nil if
+// nothing is set.
+func parseRequestExtras(headers, params []string) (*requestExtras, error) {
+	if len(headers) == 0 && len(params) == 0 {
+		return nil, nil
+	}
+	e := &requestExtras{header: http.Header{}, query: url.Values{}}
+	for _, h := range headers {
+		nameValue := strings.SplitN(h, ":", 2)
+		name := strings.TrimSpace(nameValue[0])
+		if len(nameValue) != 2 || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("header must be formatted as 'Name: value': %s", h)
+		}
+		e.header.Add(textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(nameValue[1]))
+	}
+	for _, p := range params {
+		nameValue := strings.SplitN(p, "=", 2)
+		if nameValue[0] == "" {
+			return nil, fmt.Errorf("query-param must be formatted as 'name=value' or 'name': %s", p)
+		}
+		value := ""
+		if len(nameValue) == 2 {
+			value = nameValue[1]
+		}
+		e.query.Add(nameValue[0], value)
+	}
+	return e, nil
+}
+
+// apply adds the headers and query parameters to the request. Headers given more than once are sent with every value.
+func (e *requestExtras) apply(req *http.Request) {
+	for name, values := range e.header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	if len(e.query) > 0 {
+		query := req.URL.Query()
+		for name, values := range e.query {
+			for _, value := range values {
+				query.Add(name, value)
+			}
+		}
+		// S3 expects spaces encoded as %20 rather than +, as the signature is computed over the encoded query
+		req.URL.RawQuery = strings.Replace(query.Encode(), "+", "%20", -1)
+	}
+}
+
+// install sends the headers and query parameters with every request of the service. They are added before the
+// request is signed, so they are covered by the signature like the headers of the SDK.
+func (e *requestExtras) install(svc *s3.S3) {
+	if e == nil {
+		return
+	}
+	svc.Client.Handlers.Build.PushBack(func(r *request.Request) {
+		e.apply(r.HTTPRequest)
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseRequestExtras(t *testing.T) {
+	e, err := parseRequestExtras([]string{"x-debug-trace: on", "X-Amz-Request-Payer:requester"}, []string{"partNumber=1", "debug"})
+	if err != nil {
+		t.Fatalf("valid headers and query parameters should succeed: %v", err)
+	}
+
+	if e.header.Get("X-Debug-Trace") != "on" || e.header.Get("X-Amz-Request-Payer") != "requester" {
+		t.Fatalf("Wrong headers: %v", e.header)
+	}
+
+	if e.query.Get("partNumber") != "1" || len(e.query["debug"]) != 1 {
+		t.Fatalf("Wrong query parameters: %v", e.query)
+	}
+
+	if e, _ := parseRequestExtras(nil, nil); e != nil {
+		t.Fatalf("no extras should give nil: %+v", e)
+	}
+
+	for _, invalid := range []string{"x-debug-trace", ": on", "x debug: on"} {
+		if _, err := parseRequestExtras([]string{invalid}, nil); err == nil {
+			t.Fatalf("header %q should fail", invalid)
+		}
+	}
+
+	if _, err := parseRequestExtras(nil, []string{"=1"}); err == nil {
+		t.Fatalf("query parameter without name should fail")
+	}
+}
+
+func TestApplyRequestExtras(t *testing.T) {
+	e, _ := parseRequestExtras([]string{"X-Debug: a", "X-Debug: b"}, []string{"tag=hot data"})
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/test/object-0?versionId=1", nil)
+	e.apply(req)
+
+	if values := req.Header["X-Debug"]; len(values) != 2 || values[0] != "a" || values[1] != "b" {
+		t.Fatalf("Expected both values of the header but got %v", values)
+	}
+
+	if req.URL.RawQuery != "tag=hot%20data&versionId=1" {
+		t.Fatalf("Expected the query parameter next to the existing one but got %s", req.URL.RawQuery)
+	}
+}
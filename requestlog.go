@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// detailedRequests writes a record of every request of the run to the log given with -detailed-log, nil otherwise.
+var detailedRequests *requestLog
+
+// requestRecord is the record of a request of the detailed log.
+type requestRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Op        string    `json:"op"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	Size      int64     `json:"size"`
+	// the status of the last response, 0 without a response
+	Status int `json:"status"`
+	// time until the headers of the first response were received, nil without a response
+	TTFB  *float64 `json:"ttfbMs"`
+	Total float64  `json:"totalMs"`
+	Error string   `json:"error,omitempty"`
+}
+
+var requestRecordColumns = []string{"timestamp", "op", "bucket", "key", "size", "status", "ttfb_ms", "total_ms", "error"}
+
+// requestLog writes a record of every request to a CSV file with a header line, or to a file of JSON lines if its name
+// ends in .json, .jsonl or .ndjson, to analyze the latency of the requests offline.
+type requestLog struct {
+	file *os.File
+	json bool
+	// the workers write concurrently
+	mu     sync.Mutex
+	writer *bufio.Writer
+	csv    *csv.Writer
+	err    error
+}
+
+func NewRequestLog(path string) (*requestLog, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &requestLog{file: f, writer: bufio.NewWriter(f)}
+	lower := strings.ToLower(path)
+	l.json = strings.HasSuffix(lower, ".json") || strings.HasSuffix(lower, ".jsonl") || strings.HasSuffix(lower, ".ndjson")
+	if !l.json {
+		l.csv = csv.NewWriter(l.writer)
+		l.csv.Write(requestRecordColumns)
+	}
+	return l, nil
+}
+
+// write adds the record of a request to the log. Does nothing without a log.
+func (this *requestLog) write(record requestRecord) {
+	if this == nil {
+		return
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	var err error
+	if this.json {
+		var line []byte
+		if line, err = json.Marshal(record); err == nil {
+			_, err = this.writer.Write(append(line, '\n'))
+		}
+	} else {
+		ttfb := ""
+		if record.TTFB != nil {
+			ttfb = strconv.FormatFloat(*record.TTFB, 'f', 3, 64)
+		}
+		err = this.csv.Write([]string{record.Timestamp.Format(time.RFC3339Nano), record.Op, record.Bucket, record.Key, strconv.FormatInt(record.Size, 10), strconv.Itoa(record.Status), ttfb, strconv.FormatFloat(record.Total, 'f', 3, 64), record.Error})
+	}
+	if err != nil && this.err == nil {
+		this.err = err
+	}
+}
+
+// close writes the rest of the log and returns the first error writing it. Does nothing without a log.
+func (this *requestLog) close() error {
+	if this == nil {
+		return nil
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.csv != nil {
+		this.csv.Flush()
+		if err := this.csv.Error(); err != nil && this.err == nil {
+			this.err = err
+		}
+	}
+	if err := this.writer.Flush(); err != nil && this.err == nil {
+		this.err = err
+	}
+	if err := this.file.Close(); err != nil && this.err == nil {
+		this.err = err
+	}
+	return this.err
+}
+
+// responseRecorder keeps the time the first response to a request of a worker was received and the status of its last
+// response. Parts and ranges of a worker's request are sent concurrently.
+type responseRecorder struct {
+	mu        sync.Mutex
+	firstByte time.Time
+	status    int
+}
+
+// recordResponses returns a recorder of the responses to the requests of the service.
+func recordResponses(svc *s3.S3) *responseRecorder {
+	recorder := &responseRecorder{}
+	// the Send handlers return once the headers of the response were received
+	svc.Client.Handlers.Send.PushBack(func(r *request.Request) {
+		if r.HTTPResponse != nil {
+			recorder.received(time.Now())
+		}
+	})
+	svc.Client.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.HTTPResponse != nil {
+			recorder.completed(r.HTTPResponse.StatusCode)
+		}
+	})
+	return recorder
+}
+
+func (this *responseRecorder) received(at time.Time) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.firstByte.IsZero() {
+		this.firstByte = at
+	}
+}
+
+func (this *responseRecorder) completed(status int) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.status = status
+}
+
+// reset forgets the responses of the previous request. It is safe to call on a nil recorder.
+func (this *responseRecorder) reset() {
+	if this == nil {
+		return
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.firstByte, this.status = time.Time{}, 0
+}
+
+// record returns the record of a request started at start that took elapsed, with its responses.
+func (this *responseRecorder) record(start time.Time, elapsed time.Duration, op, bucket, key string, size int64, err error) requestRecord {
+	record := requestRecord{Timestamp: start.UTC(), Op: op, Bucket: bucket, Key: key, Size: size, Total: roundFloat(elapsed.Seconds()*1000, 3)}
+	if err != nil {
+		record.Error = errorCode(err)
+	}
+	if this == nil {
+		return record
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	record.Status = this.status
+	if !this.firstByte.IsZero() {
+		ttfb := roundFloat(this.firstByte.Sub(start).Seconds()*1000, 3)
+		record.TTFB = &ttfb
+	}
+	return record
+}
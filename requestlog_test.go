@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRequestLog(t *testing.T, name string, records ...requestRecord) string {
+	dir, err := ioutil.TempDir("", "requestlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, name)
+	l, err := NewRequestLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		l.write(record)
+	}
+	if err := l.close(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestResponseRecorder(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)
+	recorder := &responseRecorder{}
+	recorder.received(start.Add(5 * time.Millisecond))
+	// a later part doesn't move the first byte
+	recorder.received(start.Add(8 * time.Millisecond))
+	recorder.completed(206)
+
+	record := recorder.record(start, 12500*time.Microsecond, "get", "test", "object-1", 1024, nil)
+	if record.Status != 206 || record.TTFB == nil || *record.TTFB != 5 || record.Total != 12.5 || record.Size != 1024 || record.Error != "" {
+		t.Fatalf("Unexpected record %+v", record)
+	}
+
+	recorder.reset()
+	record = recorder.record(start, time.Second, "put", "test", "object-2", 0, errors.New("connection refused"))
+	if record.Status != 0 || record.TTFB != nil || record.Error == "" {
+		t.Fatalf("Expected a record without a response but got %+v", record)
+	}
+
+	var none *responseRecorder
+	none.reset()
+	if record := none.record(start, time.Second, "head", "test", "object-3", 0, nil); record.Status != 0 || record.TTFB != nil {
+		t.Fatalf("Expected a record without responses but got %+v", record)
+	}
+}
+
+func TestRequestLog(t *testing.T) {
+	ttfb := 4.25
+	records := []requestRecord{
+		{Timestamp: time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC), Op: "get", Bucket: "test", Key: "photos/cat,1.jpg", Size: 1024, Status: 200, TTFB: &ttfb, Total: 10.5},
+		{Timestamp: time.Date(2025, 6, 1, 12, 30, 1, 0, time.UTC), Op: "put", Bucket: "test", Key: "object-2", Total: 3, Error: "RequestError"},
+	}
+
+	csv := writeRequestLog(t, "requests.csv", records...)
+	expected := "timestamp,op,bucket,key,size,status,ttfb_ms,total_ms,error\n" +
+		"2025-06-01T12:30:00Z,get,test,\"photos/cat,1.jpg\",1024,200,4.250,10.500,\n" +
+		"2025-06-01T12:30:01Z,put,test,object-2,0,0,,3.000,RequestError\n"
+	if csv != expected {
+		t.Fatalf("Expected the CSV log\n%s\nbut got\n%s", expected, csv)
+	}
+
+	lines := strings.Split(strings.TrimSpace(writeRequestLog(t, "requests.NDJSON", records...)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a JSON line per request but got %v", lines)
+	}
+	var decoded requestRecord
+	if err := json.Unmarshal([]byte(lines[0]), &decoded); err != nil || decoded.Key != "photos/cat,1.jpg" || *decoded.TTFB != 4.25 || decoded.Status != 200 {
+		t.Fatalf("Unexpected JSON record %s (%v)", lines[0], err)
+	}
+	if !strings.Contains(lines[1], `"ttfbMs":null`) || !strings.Contains(lines[1], `"error":"RequestError"`) {
+		t.Fatalf("Expected a request without a response in %s", lines[1])
+	}
+
+	var none *requestLog
+	none.write(records[0])
+	if err := none.close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parse([]string{"-detailed-log=requests.csv", "-dryrun"}); err == nil {
+		t.Fatalf("Expected detailed-log to be rejected with dryrun")
+	}
+}
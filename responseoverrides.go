@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// responseOverrides maps the response headers of a GET to the values the server is asked to return instead,
+// using the response-* query parameters (response-content-type etc.).
+type responseOverrides map[string]string
+
+var overridableHeaders = []string{"Cache-Control", "Content-Disposition", "Content-Encoding", "Content-Language", "Content-Type", "Expires"}
+
+// Parses overrides formatted as 'content-type=text/plain&cache-control=no-cache'. Values may contain '=' but not '&'.
+func parseResponseOverrides(overrides string) (responseOverrides, error) {
+	if overrides == "" {
+		return nil, nil
+	}
+	parsed := make(responseOverrides)
+	for _, pair := range strings.Split(overrides, "&") {
+		keyvalue := strings.SplitN(pair, "=", 2)
+		if len(keyvalue) != 2 {
+			return nil, fmt.Errorf("Invalid response overrides supplied: %s. Format must be: 'content-type=text/plain&cache-control=no-cache...'", overrides)
+		}
+		header := http.CanonicalHeaderKey(keyvalue[0])
+		if !isOverridableHeader(header) {
+			return nil, fmt.Errorf("Cannot override response header %s, must be one of %s", keyvalue[0], strings.ToLower(strings.Join(overridableHeaders, ", ")))
+		}
+		if header == "Expires" {
+			if _, err := http.ParseTime(keyvalue[1]); err != nil {
+				return nil, fmt.Errorf("Invalid expires override %s, must be an HTTP date like %s", keyvalue[1], time.Unix(0, 0).UTC().Format(http.TimeFormat))
+			}
+		}
+		parsed[header] = keyvalue[1]
+	}
+	return parsed, nil
+}
+
+func isOverridableHeader(header string) bool {
+	for _, h := range overridableHeaders {
+		if h == header {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the response-* query parameters of the request.
+func (o responseOverrides) apply(input *s3.GetObjectInput) {
+	for header, value := range o {
+		switch header {
+		case "Cache-Control":
+			input.ResponseCacheControl = aws.String(value)
+		case "Content-Disposition":
+			input.ResponseContentDisposition = aws.String(value)
+		case "Content-Encoding":
+			input.ResponseContentEncoding = aws.String(value)
+		case "Content-Language":
+			input.ResponseContentLanguage = aws.String(value)
+		case "Content-Type":
+			input.ResponseContentType = aws.String(value)
+		case "Expires":
+			expires, _ := http.ParseTime(value)
+			input.ResponseExpires = aws.Time(expires)
+		}
+	}
+}
+
+// Returns an error if the response doesn't carry the header values the request asked for, e.g. because a proxy
+// dropped the response-* query parameters.
+func checkResponseOverrides(input *s3.GetObjectInput, header http.Header) error {
+	expected := map[string]*string{
+		"Cache-Control":       input.ResponseCacheControl,
+		"Content-Disposition": input.ResponseContentDisposition,
+		"Content-Encoding":    input.ResponseContentEncoding,
+		"Content-Language":    input.ResponseContentLanguage,
+		"Content-Type":        input.ResponseContentType,
+	}
+	for name, value := range expected {
+		if value != nil && header.Get(name) != *value {
+			return fmt.Errorf("Response header %s of %s/%s is '%s' instead of the requested override '%s'", name, *input.Bucket, *input.Key, header.Get(name), *value)
+		}
+	}
+	if input.ResponseExpires != nil {
+		expires, err := http.ParseTime(header.Get("Expires"))
+		if err != nil || !expires.Equal(*input.ResponseExpires) {
+			return fmt.Errorf("Response header Expires of %s/%s is '%s' instead of the requested override '%s'", *input.Bucket, *input.Key, header.Get("Expires"), input.ResponseExpires.Format(http.TimeFormat))
+		}
+	}
+	return nil
+}
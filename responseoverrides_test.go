@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestParseResponseOverrides(t *testing.T) {
+	overrides, err := parseResponseOverrides("content-type=text/plain&content-disposition=attachment; filename=a.txt")
+	if err != nil {
+		t.Fatalf("valid overrides should succeed: %v", err)
+	}
+
+	if overrides["Content-Type"] != "text/plain" || overrides["Content-Disposition"] != "attachment; filename=a.txt" {
+		t.Fatalf("wrong overrides: %v", overrides)
+	}
+
+	for _, invalid := range []string{"content-type", "x-amz-meta-a=b", "expires=tomorrow"} {
+		if _, err := parseResponseOverrides(invalid); err == nil {
+			t.Fatalf("overrides %q should fail", invalid)
+		}
+	}
+}
+
+func TestApplyResponseOverrides(t *testing.T) {
+	overrides, _ := parseResponseOverrides("cache-control=no-cache&expires=Thu, 01 Jan 1970 00:00:00 GMT")
+	input := &s3.GetObjectInput{Bucket: aws.String("b"), Key: aws.String("k")}
+	overrides.apply(input)
+
+	if aws.StringValue(input.ResponseCacheControl) != "no-cache" || input.ResponseContentType != nil {
+		t.Fatalf("wrong overrides applied: %v", input)
+	}
+
+	if input.ResponseExpires == nil || input.ResponseExpires.Unix() != 0 {
+		t.Fatalf("wrong expires override: %v", input.ResponseExpires)
+	}
+
+	var none responseOverrides
+	none.apply(input)
+}
+
+func TestCheckResponseOverrides(t *testing.T) {
+	overrides, _ := parseResponseOverrides("content-type=text/plain&expires=Thu, 01 Jan 1970 00:00:00 GMT")
+	input := &s3.GetObjectInput{Bucket: aws.String("b"), Key: aws.String("k")}
+	overrides.apply(input)
+
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain")
+	header.Set("Expires", "Thu, 01 Jan 1970 00:00:00 GMT")
+	if err := checkResponseOverrides(input, header); err != nil {
+		t.Fatalf("response carrying the overrides should pass: %v", err)
+	}
+
+	header.Set("Content-Type", "binary/octet-stream")
+	if err := checkResponseOverrides(input, header); err == nil {
+		t.Fatalf("response ignoring the content type override should fail")
+	}
+
+	header.Set("Content-Type", "text/plain")
+	header.Del("Expires")
+	if err := checkResponseOverrides(input, header); err == nil {
+		t.Fatalf("response ignoring the expires override should fail")
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Returns whether the x-amz-restore header of an object reports a completed restore, like
+// ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT". An object that is being restored
+// reports ongoing-request="true" and one that was never restored has no header.
+func restoreCompleted(header string) bool {
+	return strings.Contains(strings.ReplaceAll(header, " ", ""), `ongoing-request="false"`)
+}
+
+// Returns whether the error of a RestoreObject request means that a restore of the object is already in progress,
+// in which case polling waits for that restore.
+func restoreInProgress(err error) bool {
+	if err, ok := err.(awserr.Error); ok {
+		return err.Code() == "RestoreAlreadyInProgress"
+	}
+	return false
+}
+
+// WaitForRestore HEADs the object every interval until its restore is completed, failing once it has waited for
+// longer than the timeout.
+func WaitForRestore(svc s3iface.S3API, bucket, key string, interval, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		head, err := svc.HeadObject(&s3.HeadObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+		if restoreCompleted(aws.StringValue(head.Restore)) {
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("restore of %s/%s not completed after %s", bucket, key, timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (this *result) recordRestoreLatency(l time.Duration) {
+	if this.RestoreResult == nil {
+		this.RestoreResult = NewLatencyResult()
+	}
+	this.RestoreResult.record(l)
+}
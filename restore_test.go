@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestRestoreCompleted(t *testing.T) {
+	headers := map[string]bool{
+		`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`: true,
+		`ongoing-request="true"`: false,
+		"":                       false,
+	}
+	for header, expected := range headers {
+		if restoreCompleted(header) != expected {
+			t.Fatalf("Expected restore of %q to be completed: %v", header, expected)
+		}
+	}
+
+	if !restoreInProgress(awserr.New("RestoreAlreadyInProgress", "", nil)) || restoreInProgress(errors.New("other")) {
+		t.Fatalf("Wrong restore in progress errors")
+	}
+}
+
+func TestWaitForRestore(t *testing.T) {
+	heads := 0
+	handler := func(in interface{}) interface{} {
+		heads++
+		if heads < 3 {
+			return &s3.HeadObjectOutput{Restore: aws.String(`ongoing-request="true"`)}
+		}
+		return &s3.HeadObjectOutput{Restore: aws.String(`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`)}
+	}
+	svc := NewMockS3Client(handler)
+
+	if err := WaitForRestore(svc, "b", "k1", time.Millisecond, time.Minute); err != nil || heads != 3 {
+		t.Fatalf("Expected the restore to complete after 3 HEADs but got %d (%v)", heads, err)
+	}
+
+	heads = -100
+	if err := WaitForRestore(svc, "b", "k1", time.Millisecond, 5*time.Millisecond); err == nil {
+		t.Fatalf("Expected the restore to time out")
+	}
+}
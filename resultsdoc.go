@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// resultsDoc collects the results document of the run with -results-format=json or -results-file, nil otherwise.
+var resultsDoc *resultsDocument
+
+// resultsDocument is a summary of the results of every run of the process whose fields don't change with the options
+// of the run, for CI pipelines to parse instead of the text output.
+type resultsDocument struct {
+	Version  string       `json:"version"`
+	Command  []string     `json:"command"`
+	Started  time.Time    `json:"started"`
+	Finished time.Time    `json:"finished"`
+	Runs     []runSummary `json:"runs"`
+}
+
+// runSummary is the summary of a run, of all of its requests and of the requests of every operation.
+type runSummary struct {
+	Label       string                      `json:"label"`
+	Operation   string                      `json:"operation"`
+	Concurrency int                         `json:"concurrency"`
+	Elapsed     float64                     `json:"elapsedSeconds"`
+	Total       operationSummary            `json:"total"`
+	Operations  map[string]operationSummary `json:"operations"`
+	// the failed requests per error code, e.g. "SlowDown (503)"
+	Errors map[string]int `json:"errors"`
+}
+
+type operationSummary struct {
+	Requests       int                `json:"requests"`
+	Failed         int                `json:"failed"`
+	Bytes          int64              `json:"bytes"`
+	RequestsPerSec float64            `json:"requestsPerSec"`
+	Throughput     float64            `json:"throughputMiBPerSec"`
+	Latency        map[string]float64 `json:"latencyMs"`
+}
+
+// NewResultsDocument starts the document of the run. The values of the flags that can be secrets are left out of the
+// command line.
+func NewResultsDocument(command []string) *resultsDocument {
+	return &resultsDocument{Version: VERSION, Command: redactCommand(command), Started: time.Now(), Runs: []runSummary{}}
+}
+
+// Returns the summary of requests that took elapsed: their mean, percentiles and maximum latency, named like p99.9,
+// and their rates.
+func newOperationSummary(h *hdrhistogram.Histogram, requests, failed int, bytes int64, elapsed time.Duration) operationSummary {
+	s := operationSummary{Requests: requests, Failed: failed, Bytes: bytes, Latency: map[string]float64{}}
+	if elapsed > 0 {
+		s.RequestsPerSec = roundFloat(float64(requests)/elapsed.Seconds(), 3)
+		s.Throughput = roundFloat(float64(bytes)/1024/1024/elapsed.Seconds(), 3)
+	}
+	if h.TotalCount() == 0 {
+		return s
+	}
+	// the histograms count in units of 10us
+	s.Latency["mean"] = roundFloat(h.Mean()/1e2, 3)
+	for _, p := range summaryPercentiles {
+		s.Latency["p"+convertFloatToString(p)] = float64(h.ValueAtQuantile(p)) / 1e2
+	}
+	s.Latency["max"] = float64(h.Max()) / 1e2
+	return s
+}
+
+// Returns the summary of a run. Runs of a single operation have the summary of all requests as their only operation.
+func newRunSummary(label string, r result) runSummary {
+	s := runSummary{Label: label, Operation: r.Operation, Concurrency: r.Concurrency, Elapsed: roundFloat(r.elapsedTime.Seconds(), 3), Errors: map[string]int{}}
+	s.Total = newOperationSummary(r.latencies, r.Count, r.Failcount, r.sumObjSize, r.elapsedTime)
+	s.Operations = map[string]operationSummary{}
+	for op, o := range r.OperationResults {
+		s.Operations[op] = newOperationSummary(o.latencies, o.Count, o.Failcount, o.Bytes, r.elapsedTime)
+	}
+	if len(s.Operations) == 0 {
+		s.Operations[r.Operation] = s.Total
+	}
+	for code, count := range r.ErrorCodes {
+		s.Errors[code] = count
+	}
+	return s
+}
+
+// addRun adds the summary of a run. It is safe to call on a nil document.
+func (d *resultsDocument) addRun(label string, testResult results) {
+	if d == nil {
+		return
+	}
+	d.Runs = append(d.Runs, newRunSummary(label, testResult.CummulativeResult))
+}
+
+// finish writes the document to the file, or prints it on a single line without one. It is safe to call on a nil
+// document.
+func (d *resultsDocument) finish(path string) error {
+	if d == nil {
+		return nil
+	}
+	d.Finished = time.Now()
+	if path == "" {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
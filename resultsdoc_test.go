@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunSummary(t *testing.T) {
+	r := NewResult()
+	r.Operation, r.Concurrency, r.elapsedTime = "put", 4, 2*time.Second
+	for i := 1; i <= 100; i++ {
+		r.RecordLatency(time.Duration(i) * time.Millisecond)
+	}
+	r.Count, r.Failcount, r.sumObjSize = 100, 2, 4<<20
+	r.recordErrorCode("SlowDown (503)")
+	r.recordErrorCode("SlowDown (503)")
+
+	s := newRunSummary("put-4", r)
+	if s.Elapsed != 2 || s.Total.Requests != 100 || s.Total.Failed != 2 || s.Total.RequestsPerSec != 50 || s.Total.Throughput != 2 {
+		t.Fatalf("Unexpected summary %+v", s)
+	}
+	if s.Total.Latency["p50"] != 50 || s.Total.Latency["p99.9"] != 100 || s.Total.Latency["max"] != 100 || s.Total.Latency["mean"] != 50.5 {
+		t.Fatalf("Unexpected latency %v", s.Total.Latency)
+	}
+	if len(s.Operations) != 1 || s.Operations["put"].Requests != 100 {
+		t.Fatalf("Expected the run of a single operation to be its only operation but got %v", s.Operations)
+	}
+	if s.Errors["SlowDown (503)"] != 2 {
+		t.Fatalf("Unexpected errors %v", s.Errors)
+	}
+
+	mixed := NewResult()
+	mixed.Operation, mixed.elapsedTime = "mix", time.Second
+	mixed.recordOperation("get", time.Millisecond, 1<<20, false)
+	mixed.recordOperation("put", 2*time.Millisecond, 1<<20, true)
+	s = newRunSummary("mix-2", mixed)
+	if len(s.Operations) != 2 || s.Operations["put"].Failed != 1 || s.Operations["get"].Throughput != 1 || s.Operations["get"].Latency["p50"] != 1 {
+		t.Fatalf("Unexpected operations %+v", s.Operations)
+	}
+}
+
+func TestWriteResultsDocument(t *testing.T) {
+	dir, err := ioutil.TempDir("", "s3tester-results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.json")
+
+	var none *resultsDocument
+	none.addRun("put-1", results{})
+	if err := none.finish(path); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewResultsDocument([]string{"s3tester", "-results-file=" + path, "-header", "Authorization: Bearer token"})
+	r := NewResult()
+	r.Operation = "head"
+	d.addRun("head-1", results{CummulativeResult: r})
+	if err := d.finish(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read resultsDocument
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if read.Version != VERSION || len(read.Runs) != 1 || read.Runs[0].Label != "head-1" || read.Finished.IsZero() {
+		t.Fatalf("Unexpected document %s", data)
+	}
+	if len(read.Command) != 4 || read.Command[3] != "Authorization:REDACTED" {
+		t.Fatalf("Expected the header value to be redacted but got %v", read.Command)
+	}
+}
+
+func TestResultsFormatFlags(t *testing.T) {
+	args, err := parse([]string{"-results-format=json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.resultsFormat != "json" || !args.isJson {
+		t.Fatalf("Expected the results document on stdout and the other reports in JSON")
+	}
+	if args, err = parse([]string{"-results-file=results.json"}); err != nil {
+		t.Fatal(err)
+	}
+	if args.resultsFormat != "json" || args.isJson {
+		t.Fatalf("Expected the results document in the file and the usual output")
+	}
+
+	for _, cmdline := range [][]string{
+		{"-results-format=xml"},
+		{"-results-format=text", "-results-file=results.json"},
+	} {
+		if _, err := parse(cmdline); err == nil {
+			t.Fatalf("Expected %v to be rejected", cmdline)
+		}
+	}
+}
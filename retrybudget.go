@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// The retries a budget allows on top of its share of the requests, so that the first failures of a run can still be
+// retried.
+const retryBudgetReserve = 10
+
+// retryBudget caps the retries of all workers to a share of their requests, so that a failing backend isn't hit by a
+// storm of retries from the load generator on top of the regular load. Requests that would exceed the budget fail
+// without being retried.
+type retryBudget struct {
+	// retries allowed per request
+	ratio    float64
+	requests int64
+	retries  int64
+	// set while the budget is exhausted, so that every exhaustion is logged once
+	exhausted int32
+}
+
+// Returns a budget that allows retries of the given percentage of the requests. Returns nil if percent is 0.
+func NewRetryBudget(percent float64) *retryBudget {
+	if percent == 0 {
+		return nil
+	}
+	return &retryBudget{ratio: percent / 100}
+}
+
+func (b *retryBudget) request() {
+	atomic.AddInt64(&b.requests, 1)
+}
+
+// allow takes a retry from the budget and returns false if the budget is exhausted.
+func (b *retryBudget) allow() bool {
+	requests := atomic.LoadInt64(&b.requests)
+	retries := atomic.AddInt64(&b.retries, 1)
+	if float64(retries) <= b.ratio*float64(requests)+retryBudgetReserve {
+		if atomic.CompareAndSwapInt32(&b.exhausted, 1, 0) {
+			log.Printf("Retry budget available again after %d requests and %d retries", requests, retries)
+		}
+		return true
+	}
+	atomic.AddInt64(&b.retries, -1)
+	if atomic.CompareAndSwapInt32(&b.exhausted, 0, 1) {
+		log.Printf("Retry budget of %g%% exhausted after %d requests and %d retries, failing requests without retrying them", b.ratio*100, requests, retries-1)
+	}
+	return false
+}
+
+// retryResult holds the retries of the requests and the retries the retry budget denied.
+type retryResult struct {
+	Retries int64 `json:"retries"`
+	Denied  int64 `json:"deniedRetries"`
+}
+
+func (this *retryResult) merge(other *retryResult) *retryResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = &retryResult{}
+	}
+	this.Retries += other.Retries
+	this.Denied += other.Denied
+	return this
+}
+
+// recordRetries counts the requests of the service against the retry budget and takes every retry from it, the
+// retries and the retries denied are recorded into the result. It does nothing without a budget.
+func (this *result) recordRetries(svc *s3.S3, budget *retryBudget) {
+	if budget == nil {
+		return
+	}
+	this.Retries = &retryResult{}
+	// parts of multipart operations are sent concurrently
+	stats := this.Retries
+	// built once per request, not again on its retries
+	svc.Client.Handlers.Build.PushBack(func(r *request.Request) {
+		budget.request()
+	})
+	// runs before the retry is decided on, a request that isn't retryable is left alone
+	svc.Client.Handlers.Retry.PushBack(func(r *request.Request) {
+		retryable := aws.BoolValue(r.Retryable) || r.Retryable == nil && r.ShouldRetry(r)
+		if r.Error == nil || !retryable || r.RetryCount >= r.MaxRetries() {
+			return
+		}
+		if budget.allow() {
+			atomic.AddInt64(&stats.Retries, 1)
+		} else {
+			r.Retryable = aws.Bool(false)
+			atomic.AddInt64(&stats.Denied, 1)
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(10)
+	for i := 0; i < 100; i++ {
+		budget.request()
+	}
+	// 10% of the requests and the reserve
+	for i := 0; i < 20; i++ {
+		if !budget.allow() {
+			t.Fatalf("retry %d should be within the budget", i)
+		}
+	}
+	if budget.allow() {
+		t.Fatalf("retry beyond the budget should be denied")
+	}
+	if budget.exhausted != 1 || budget.retries != 20 {
+		t.Fatalf("expected an exhausted budget with 20 retries but got %+v", budget)
+	}
+
+	for i := 0; i < 10; i++ {
+		budget.request()
+	}
+	if !budget.allow() || budget.exhausted != 0 {
+		t.Fatalf("more requests should make the budget available again: %+v", budget)
+	}
+}
+
+func TestNoRetryBudget(t *testing.T) {
+	if NewRetryBudget(0) != nil {
+		t.Fatalf("no budget expected without a percentage")
+	}
+}
+
+func TestMergeRetryResults(t *testing.T) {
+	var merged *retryResult
+	merged = merged.merge(&retryResult{Retries: 3, Denied: 1})
+	merged = merged.merge(nil)
+	merged = merged.merge(&retryResult{Retries: 2, Denied: 4})
+	if merged.Retries != 5 || merged.Denied != 5 {
+		t.Fatalf("wrong merged retries: %+v", merged)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// retriedResult holds the latencies of the requests that succeeded after retries, of their first attempt and of the
+// whole request including the retries, so that the flakiness of the server isn't hidden in the overall latencies.
+type retriedResult struct {
+	FirstAttempt *latencyResult `json:"firstAttempt"`
+	Total        *latencyResult `json:"totalIncludingRetries"`
+}
+
+func NewRetriedResult() *retriedResult {
+	return &retriedResult{FirstAttempt: NewLatencyResult(), Total: NewLatencyResult()}
+}
+
+func (this *retriedResult) record(firstAttempt, total time.Duration) {
+	this.FirstAttempt.record(firstAttempt)
+	this.Total.record(total)
+}
+
+// merge adds the other result to this one and returns the merged result, which is newly allocated if this one is nil.
+func (this *retriedResult) merge(other *retriedResult) *retriedResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = NewRetriedResult()
+	}
+	this.FirstAttempt = this.FirstAttempt.merge(other.FirstAttempt)
+	this.Total = this.Total.merge(other.Total)
+	return this
+}
+
+func (this *retriedResult) setupStats() {
+	if this == nil {
+		return
+	}
+	this.FirstAttempt.setupStats()
+	this.Total.setupStats()
+}
+
+// retryLatencyRecorder times the first attempt of every request of a service and records the requests that succeed
+// after retries into the result. The attempts are timed until the response headers were received.
+type retryLatencyRecorder struct {
+	result *result
+	// parts of multipart operations are sent concurrently
+	mu       sync.Mutex
+	attempts map[*request.Request]*firstAttempt
+}
+
+type firstAttempt struct {
+	start time.Time
+	end   time.Time
+}
+
+func (this *retryLatencyRecorder) started(r *request.Request, now time.Time) {
+	if r.RetryCount != 0 {
+		return
+	}
+	this.mu.Lock()
+	this.attempts[r] = &firstAttempt{start: now}
+	this.mu.Unlock()
+}
+
+func (this *retryLatencyRecorder) sent(r *request.Request, now time.Time) {
+	if r.RetryCount != 0 {
+		return
+	}
+	this.mu.Lock()
+	if a, ok := this.attempts[r]; ok {
+		a.end = now
+	}
+	this.mu.Unlock()
+}
+
+func (this *retryLatencyRecorder) completed(r *request.Request, now time.Time) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	a, ok := this.attempts[r]
+	if !ok {
+		return
+	}
+	delete(this.attempts, r)
+	if r.Error != nil || r.RetryCount == 0 || a.end.IsZero() {
+		return
+	}
+	if this.result.RetriedResult == nil {
+		this.result.RetriedResult = NewRetriedResult()
+	}
+	this.result.RetriedResult.record(a.end.Sub(a.start), now.Sub(a.start))
+}
+
+// recordRetriedLatencies records the latencies of the requests of the service that succeed after retries.
+func (this *result) recordRetriedLatencies(svc *s3.S3) {
+	recorder := &retryLatencyRecorder{result: this, attempts: make(map[*request.Request]*firstAttempt)}
+	svc.Client.Handlers.Send.PushFront(func(r *request.Request) {
+		recorder.started(r, time.Now())
+	})
+	svc.Client.Handlers.Send.PushBack(func(r *request.Request) {
+		recorder.sent(r, time.Now())
+	})
+	svc.Client.Handlers.Complete.PushBack(func(r *request.Request) {
+		recorder.completed(r, time.Now())
+	})
+}
+
+func printRetriedResult(retried *retriedResult) {
+	fmt.Println("Requests succeeded after retries, first attempt")
+	printLatencyResult(retried.FirstAttempt)
+	fmt.Println("Requests succeeded after retries, total including retries")
+	printLatencyResult(retried.Total)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+func TestRetryLatencyRecorder(t *testing.T) {
+	r := NewResult()
+	recorder := &retryLatencyRecorder{result: &r, attempts: make(map[*request.Request]*firstAttempt)}
+	start := time.Now()
+
+	// succeeds at the first attempt
+	first := &request.Request{}
+	recorder.started(first, start)
+	recorder.sent(first, start.Add(10*time.Millisecond))
+	recorder.completed(first, start.Add(10*time.Millisecond))
+	if r.RetriedResult != nil {
+		t.Fatalf("A request that wasn't retried shouldn't be recorded: %v", r.RetriedResult)
+	}
+
+	// fails at the first attempt and succeeds at the second
+	retried := &request.Request{}
+	recorder.started(retried, start)
+	recorder.sent(retried, start.Add(20*time.Millisecond))
+	retried.RetryCount = 1
+	recorder.started(retried, start.Add(120*time.Millisecond))
+	recorder.sent(retried, start.Add(150*time.Millisecond))
+	recorder.completed(retried, start.Add(150*time.Millisecond))
+
+	// fails after retries
+	failed := &request.Request{}
+	recorder.started(failed, start)
+	recorder.sent(failed, start.Add(20*time.Millisecond))
+	failed.RetryCount = 3
+	failed.Error = errors.New("InternalError")
+	recorder.completed(failed, start.Add(500*time.Millisecond))
+
+	if r.RetriedResult == nil || r.RetriedResult.FirstAttempt.Count != 1 || r.RetriedResult.Total.Count != 1 {
+		t.Fatalf("Expected one retried request but got %v", r.RetriedResult)
+	}
+	if r.RetriedResult.FirstAttempt.elapsedSum != 20*time.Millisecond || r.RetriedResult.Total.elapsedSum != 150*time.Millisecond {
+		t.Fatalf("Wrong latencies: %v %v", r.RetriedResult.FirstAttempt.elapsedSum, r.RetriedResult.Total.elapsedSum)
+	}
+	if len(recorder.attempts) != 0 {
+		t.Fatalf("Completed requests should be forgotten: %v", recorder.attempts)
+	}
+}
+
+func TestRetriedResultMerge(t *testing.T) {
+	var merged *retriedResult
+	merged = merged.merge(nil)
+	if merged != nil {
+		t.Fatalf("Merging nothing should stay nil")
+	}
+
+	a := NewRetriedResult()
+	a.record(10*time.Millisecond, 100*time.Millisecond)
+	b := NewRetriedResult()
+	b.record(30*time.Millisecond, 300*time.Millisecond)
+	merged = merged.merge(a).merge(b)
+	merged.setupStats()
+	if merged.FirstAttempt.Count != 2 || merged.FirstAttempt.AverageRequestTime != 20 || merged.Total.AverageRequestTime != 200 {
+		t.Fatalf("Wrong merged result: %v %v", merged.FirstAttempt, merged.Total)
+	}
+}
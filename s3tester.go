@@ -2,18 +2,27 @@ package main
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"math"
 	"math/rand"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
 	"runtime/pprof"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"golang.org/x/net/context"
@@ -26,6 +35,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/codahale/hdrhistogram"
 )
 
@@ -37,14 +47,20 @@ const (
 )
 
 type results struct {
-	CummulativeResult result    `json:"cummulativeResult"`
-	PerEndpointResult []*result `json:"endpointResult,omitempty"`
+	CummulativeResult result        `json:"cummulativeResult"`
+	PerEndpointResult []*result     `json:"endpointResult,omitempty"`
+	Cost              *costEstimate `json:"estimatedCost,omitempty"`
+	// whether a run with requests and a max duration ran out of time
+	TimeBox *timeBox `json:"timeBox,omitempty"`
+	// how much the heavy traffic class degraded the sensitive class
+	QoS *qosReport `json:"qos,omitempty"`
 }
 
 // result holds the performance metrics for a single goroutine that are later aggregated.
 type result struct {
 	Category   string `json:"category,omitempty"`
 	UniqueName string `json:"uniqueName,omitempty"`
+	RunId      string `json:"runId,omitempty"`
 
 	Endpoint    string `json:"endpoint,omitempty"`
 	Operation   string `json:"operation,omitempty"`
@@ -52,6 +68,14 @@ type result struct {
 	UniqObjNum  int    `json:"totalUniqueObjects"`
 	Count       int    `json:"totalRequests"`
 	Failcount   int    `json:"failedRequests"`
+	// keys deleted by multidelete requests
+	KeyCount int `json:"totalKeys,omitempty"`
+	// requests whose operation panicked, they are counted as failed as well
+	Panics int `json:"recoveredPanics,omitempty"`
+	// retries taken from and denied by the retry budget
+	Retries *retryResult `json:"retryBudget,omitempty"`
+	// latencies of the requests that succeeded after retries, of their first attempt and in total
+	RetriedResult *retriedResult `json:"retriedRequests,omitempty"`
 
 	TotalElapsedTime   float64 `json:"totalElapsedTime (ms)"`
 	AverageRequestTime float64 `json:"averageRequestTime (ms)"`
@@ -62,12 +86,92 @@ type result struct {
 	ActualRequestsPerSec  float64 `json:"actualRequestsPerSec"`
 	ContentThroughput     float64 `json:"contentThroughput (MB/s)"`
 	AverageObjectSize     float64 `json:"averageObjectSize"`
+	KeysPerSec            float64 `json:"keysPerSec,omitempty"`
+	// presigned URLs generated per second by each of the cores the run could use (GOMAXPROCS)
+	PresignsPerSecPerCore float64 `json:"presignsPerSecPerCore,omitempty"`
+	// time spent signing the URLs and transferring the objects with them, with presign-transfer
+	PresignResult  *latencyResult `json:"presignTime,omitempty"`
+	TransferResult *latencyResult `json:"transferTime,omitempty"`
+	// requests of presignedurl sent with a URL after its expiry, which the server rejected or still accepted
+	ExpiredURLsRejected int `json:"expiredUrlsRejected,omitempty"`
+	ExpiredURLsAccepted int `json:"expiredUrlsAccepted,omitempty"`
 
 	Percentiles map[string]float64 `json:"responseTimePercentiles(ms)"`
+	// 95% confidence intervals of the percentiles bootstrapped from this many resamples, with bootstrap
+	PercentileIntervals map[string]confidenceInterval `json:"responseTimePercentileIntervals(ms),omitempty"`
+	Resamples           int                           `json:"bootstrapResamples,omitempty"`
+	// latency per read mode of the versionedget operation
+	ModeResults map[string]*latencyResult `json:"readModes,omitempty"`
+	// put requests sent as multipart uploads because the object was larger than the mpu threshold
+	MultipartCount int `json:"multipartRequests,omitempty"`
+	// time requests waited for a connection when the connections per host are limited
+	ConnWaitResult *latencyResult `json:"connectionWait,omitempty"`
+	// DNS lookups of the connections established, only reported if the endpoints were looked up
+	DNS *dnsResult `json:"dns,omitempty"`
+	// time requests of the arrival schedule were started after they were due, with rate
+	ScheduleLag *latencyResult `json:"scheduleLag,omitempty"`
+	// time PUTs waited for the external generator of data-pipe or data-cmd to supply their payload
+	PayloadWaitResult *latencyResult `json:"payloadSourceWait,omitempty"`
+	// latency of the individual part uploads of multipart operations
+	PartResult *latencyResult `json:"partResult,omitempty"`
+	// size of the parts of multipart operations
+	PartSize int64 `json:"partSize,omitempty"`
+	// latency of the individual pages of full listings
+	PageResult *latencyResult `json:"pageResult,omitempty"`
+	// throughput of the individual objects of parallelget
+	ObjectThroughput *throughputResult `json:"objectThroughput,omitempty"`
+	// latency per scheme when traffic is split between HTTP and HTTPS
+	SchemeResults map[string]*latencyResult `json:"schemes,omitempty"`
+	// bytes scanned and returned by select requests
+	Select *selectResult `json:"select,omitempty"`
+	// time from the restore request until the restore of the object was completed, with restore polling
+	RestoreResult *latencyResult `json:"restoreTurnaround,omitempty"`
+	// latency of the objects written per storage class
+	StorageClassResults map[string]*latencyResult `json:"storageClasses,omitempty"`
+	// latency per endpoint found with discover
+	DiscoveredResults map[string]*latencyResult `json:"discoveredEndpoints,omitempty"`
+	// requests, failures, bytes and latency per operation of a mix
+	OperationResults map[string]*operationResult `json:"operations,omitempty"`
+	// requests, failures, bytes and latency per species of the objects
+	SpeciesResults map[string]*operationResult `json:"species,omitempty"`
+	// requests, failures, bytes and latency per traffic class of a run with a heavy class
+	ClassResults map[string]*operationResult `json:"trafficClasses,omitempty"`
+	// number of key prefixes and the ones with the highest average latency, with the prefix heatmap
+	PrefixCount   int          `json:"keyPrefixes,omitempty"`
+	WorstPrefixes []prefixStat `json:"worstPrefixes,omitempty"`
+	// requests and throughput per bucket, with num-buckets
+	BucketStats []bucketStat `json:"buckets,omitempty"`
+	// latency per stage of the pipeline operation
+	StageResults map[string]*latencyResult `json:"stages,omitempty"`
+	// time from the start of the first stage of the pipeline operation until an object completed the last stage
+	LifecycleResult *latencyResult `json:"lifecycle,omitempty"`
+	// number of responses per combination of Server and x-amz-* headers
+	Fingerprints map[string]int `json:"headerFingerprints,omitempty"`
+	// number of responses per kind whose body didn't match their headers, they are not counted as failed
+	SoftFailures map[string]int `json:"softFailures,omitempty"`
+	// number of conditional requests per outcome, responses 304 and 412 are not counted as failed
+	Conditions map[string]int `json:"conditionalResponses,omitempty"`
+	// number of failed requests per S3 error code and HTTP status
+	ErrorCodes map[string]int `json:"errorCodes,omitempty"`
+	// number of responses per HTTP status, 0 for requests without a response, counted for the assertions of a stage
+	StatusCodes map[int]int `json:"statusCodes,omitempty"`
+	// garbage collections of the tester during the run, only in the total result
+	GC *gcResult `json:"gc,omitempty"`
+	// CPU time of the tester and the requests and bytes per CPU-second, only in the total result
+	Efficiency *efficiencyResult `json:"clientEfficiency,omitempty"`
+	// reads checked against the write generations read before
+	Generations *generationResult `json:"generations,omitempty"`
+	// reads of a read-while-write run checked against the writes acknowledged before
+	Freshness *freshnessResult `json:"readWhileWrite,omitempty"`
+	// checksums sent with uploads and verified on downloads
+	Checksums *checksumResult `json:"checksums,omitempty"`
 
 	sumObjSize  int64
 	elapsedSum  time.Duration
+	prefixStats map[string]*prefixStat
+	bucketStats map[string]*bucketStat
 	data        []detail
+	versions    []objectVersion
 	latencies   *hdrhistogram.Histogram
 	startTime   time.Time
 	elapsedTime time.Duration
@@ -88,6 +192,72 @@ func (this *result) RecordLatency(l time.Duration) {
 	this.latencies.RecordValue(l.Nanoseconds() / 1e4)
 }
 
+// latencyResult holds the latency of a subset of the requests, e.g. the part uploads of a multipart put.
+// Its histogram is only allocated once a request of the subset is recorded, so runs that don't use it don't pay for its memory.
+type latencyResult struct {
+	Count              int                `json:"totalRequests"`
+	AverageRequestTime float64            `json:"averageRequestTime (ms)"`
+	Percentiles        map[string]float64 `json:"responseTimePercentiles(ms)"`
+	MaximumRequestTime float64            `json:"maximumRequestTime (ms)"`
+
+	elapsedSum time.Duration
+	latencies  *hdrhistogram.Histogram
+}
+
+func NewLatencyResult() *latencyResult {
+	return &latencyResult{latencies: NewResult().latencies}
+}
+
+func (this *latencyResult) record(l time.Duration) {
+	this.Count++
+	this.elapsedSum += l
+	this.latencies.RecordValue(l.Nanoseconds() / 1e4)
+}
+
+// merge adds the other result to this one and returns the merged result, which is newly allocated if this one is nil.
+func (this *latencyResult) merge(other *latencyResult) *latencyResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = NewLatencyResult()
+	}
+	this.Count += other.Count
+	this.elapsedSum += other.elapsedSum
+	this.latencies.Merge(other.latencies)
+	return this
+}
+
+func (this *latencyResult) setupStats() {
+	if this == nil || this.Count == 0 {
+		return
+	}
+	this.AverageRequestTime = float64(this.elapsedSum/time.Duration(this.Count)) / float64(time.Millisecond)
+	this.Percentiles = make(map[string]float64)
+	for _, percentile := range percentiles {
+		this.Percentiles[convertFloatToString(percentile)] = float64(this.latencies.ValueAtQuantile(percentile)) / 1e2
+	}
+	this.MaximumRequestTime = float64(this.latencies.Max()) / 1e2
+}
+
+func (this *result) recordPartLatencies(latencies []time.Duration) {
+	for _, l := range latencies {
+		if this.PartResult == nil {
+			this.PartResult = NewLatencyResult()
+		}
+		this.PartResult.record(l)
+	}
+}
+
+func (this *result) recordPageLatencies(latencies []time.Duration) {
+	for _, l := range latencies {
+		if this.PageResult == nil {
+			this.PageResult = NewLatencyResult()
+		}
+		this.PageResult.record(l)
+	}
+}
+
 // detail holds metrics for individual S3 requests.
 type detail struct {
 	ts      time.Time
@@ -153,39 +323,189 @@ func randMinMax(source *rand.Rand, min int64, max int64) int64 {
 	return min + source.Int63n(max-min+1)
 }
 
+// interrupted is set when the run is interrupted, the workers stop once their requests in flight are done.
+var interrupted int32
+
+func wasInterrupted() bool {
+	return atomic.LoadInt32(&interrupted) != 0
+}
+
+// handleInterrupts stops the run cleanly on the first interrupt: the workers finish their requests in flight and the
+// results of the requests sent so far are reported. A second interrupt exits right away.
+func handleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Printf("Interrupted, finishing the requests in flight. Interrupt again to exit right away.")
+		atomic.StoreInt32(&interrupted, 1)
+		<-signals
+		os.Exit(130)
+	}()
+}
+
 type durationSetting struct {
 	applicable bool
 	runstart   time.Time
 	maxRunTime time.Duration
 }
 
-func NewDurationSetting(duration *intFlag, runStart time.Time) *durationSetting {
+func NewDurationSetting(duration *durationFlag, runStart time.Time) *durationSetting {
 	if duration.set {
-		maxRunTime := time.Duration(duration.value * 1e9)
-		return &durationSetting{applicable: true, runstart: runStart, maxRunTime: maxRunTime}
+		return &durationSetting{applicable: true, runstart: runStart, maxRunTime: duration.value}
 	} else {
 		return &durationSetting{applicable: false}
 	}
 }
 
+// Returns the duration setting of a run with requests, which stops at the max duration unless the overflow policy
+// extends the run until all requests were sent.
+func NewMaxDurationSetting(args *parameters, runStart time.Time) *durationSetting {
+	if args.maxDuration > 0 && args.overflow != overflowExtend {
+		return &durationSetting{applicable: true, runstart: runStart, maxRunTime: args.maxDuration}
+	}
+	return NewDurationSetting(args.duration, runStart)
+}
+
+// enabled returns whether a worker has to stop, because the duration is over or the run was interrupted.
 func (ds *durationSetting) enabled() bool {
+	if wasInterrupted() {
+		return true
+	}
 	if ds.applicable {
 		return time.Since(ds.runstart) >= ds.maxRunTime
 	}
 	return false
 }
 
+// phase marks the time span of a single workload stage (e.g. one step of a concurrency scan) so the
+// detailed log can be segmented by stage.
+type phase struct {
+	label string
+	start time.Time
+	end   time.Time
+}
+
+// writeLimit caps the number of objects and bytes written over the whole run to protect shared test clusters
+// from runaway fill workloads. It is shared by all workers and every write request counts as an object.
+type writeLimit struct {
+	maxObjects int64
+	maxBytes   int64
+	objects    int64
+	bytes      int64
+	reached    int32
+}
+
+func NewWriteLimit(maxObjects, maxBytes int64) *writeLimit {
+	if maxObjects == 0 && maxBytes == 0 {
+		return nil
+	}
+	return &writeLimit{maxObjects: maxObjects, maxBytes: maxBytes}
+}
+
+// Returns whether the operation writes objects, the uploads of put runs as well as copies and pipelines. The
+// presigned operations write with PUT URLs only, see writesObject.
+func isWriteOperation(op string) bool {
+	switch op {
+	case "put", "multipartput", "initmultipart", "copy", "copyacross", "pipeline":
+		return true
+	}
+	return false
+}
+
+// Returns whether the request of the operation on the key writes an object, including the uploads with presigned PUT
+// URLs.
+func writesObject(args *parameters, op, key string) bool {
+	switch op {
+	case "presign":
+		return args.presignTransfer && args.presignMethod == "PUT"
+	case "presignedurl":
+		return args.presignedURLs[key].method == "PUT"
+	}
+	return isWriteOperation(op)
+}
+
+// Returns the bytes a write is charged with before it is sent: the object size, or the size of the largest payload
+// file until the size of the file that was sent is known.
+func writeCharge(args *parameters) int64 {
+	if args.payload.files != nil {
+		return args.payload.files.largest
+	}
+	return args.osize
+}
+
+// Returns whether the operation reads or updates an existing key and can be sent to the same key again, so that a
+// duration based run of it can go over its keys until the time is up.
+func isRepeatableRead(op string) bool {
+	switch op {
+	case "get", "randget", "head", "rangeget", "parallelget", "select", "updatemeta", "copy", "copyacross", "puttagging", "gettagging", "deletetagging", "getretention", "getlegalhold", "putacl", "getacl":
+		return true
+	}
+	return false
+}
+
+// reserve accounts for a write of the given size and returns false once it would exceed a limit.
+// A nil writeLimit always allows the write.
+func (l *writeLimit) reserve(size int64) bool {
+	if l == nil {
+		return true
+	}
+	objects := atomic.AddInt64(&l.objects, 1)
+	bytes := atomic.AddInt64(&l.bytes, size)
+	if (l.maxObjects == 0 || objects <= l.maxObjects) && (l.maxBytes == 0 || bytes <= l.maxBytes) {
+		return true
+	}
+	atomic.AddInt64(&l.objects, -1)
+	atomic.AddInt64(&l.bytes, -size)
+	if atomic.CompareAndSwapInt32(&l.reached, 0, 1) {
+		log.Printf("Write limit reached (max-objects=%d, max-bytes=%d). Stopping write requests.", l.maxObjects, l.maxBytes)
+	}
+	return false
+}
+
+// settle charges the bytes sent by a write that reserved charged bytes instead. It is safe to call on a nil writeLimit.
+func (l *writeLimit) settle(charged, sent int64) {
+	if l == nil || charged == sent {
+		return
+	}
+	atomic.AddInt64(&l.bytes, sent-charged)
+}
+
 var detailed []detail
+var writtenVersions []objectVersion
+var phases []phase
 
 func runtest(args parameters) (float64, results) {
 	c := make(chan result, args.concurrency)
 	startTime := time.Now()
+	args.memWatchdog.start()
+	args.resultStream.start(args.optype)
+	args.presignExport.start()
+	gc := startGCMonitor()
+	cpu := startCPUMeter()
 	startTestWorker(c, args)
 	testResult := collectWorkerResult(c, args, startTime)
+	testResult.CummulativeResult.GC = gc.halt()
+	testResult.CummulativeResult.Efficiency = cpu.halt()
+	args.resultStream.halt()
+	args.presignExport.halt()
+	args.memWatchdog.halt()
+	phases = append(phases, phase{label: args.optype + "-" + strconv.Itoa(args.concurrency), start: startTime, end: time.Now()})
 
 	if args.optype != "validate" {
 		processTestResult(&testResult, args)
-		printTestResult(&testResult, args.isJson)
+		testResult.TimeBox = NewTimeBox(args.maxDuration, args.overflow, testResult.CummulativeResult.elapsedTime)
+		testResult.QoS = NewQoSReport(args.heavyConcurrency, args.heavyStart, testResult.CummulativeResult)
+		if args.cost && args.jsonDecoder == nil && args.replayAccessLog == "" && args.replayTrace == "" {
+			estimate := estimateRunCost(testResult.CummulativeResult, args)
+			testResult.Cost = &estimate
+		}
+		// the results document replaces the results of every run on stdout
+		if args.resultsFormat != "json" || args.resultsFile != "" {
+			printTestResult(&testResult, args.isJson)
+		}
+		artifacts.addRun(args.optype+"-"+strconv.Itoa(args.concurrency), testResult)
+		resultsDoc.addRun(args.optype+"-"+strconv.Itoa(args.concurrency), testResult)
 	}
 	return float64(testResult.CummulativeResult.Count) / testResult.CummulativeResult.elapsedTime.Seconds(), testResult
 }
@@ -196,16 +516,36 @@ func startTestWorker(c chan<- result, args parameters) {
 		fmt.Println("Failed loading credentials.\nPlease specify env variable AWS_SHARED_CREDENTIALS_FILE if you put credential file other than AWS CLI configuration directory.")
 		log.Fatal(err)
 	}
+	args.destCredentials = credential
+	if args.destProfile != "" {
+		if args.destCredentials, err = loadCredentialProfile(args.destProfile, args.nosign); err != nil {
+			fmt.Println("Failed loading the credentials of dest-profile.")
+			log.Fatal(err)
+		}
+	}
 	limiter := rate.NewLimiter(args.ratePerSecond, 1)
+	args.loadShape.start(limiter, time.Now())
+	// the heavy class of a run with traffic classes has a rate of its own
+	heavyLimiter := rate.NewLimiter(args.heavyRatePerSecond, 1)
+	args.arrivals = NewArrivalSchedule(args.rate)
+	args.bandwidth = NewBandwidthLimiter(args.maxBandwidth)
 	workersPerEndpoint := args.concurrency / len(args.endpoints)
 	var workerChans []*workerChan
 	var workersWG sync.WaitGroup
 
-	if args.jsonDecoder != nil {
+	// a replay or a mixed workload sends the requests to the workers through a channel of each worker
+	replay := args.jsonDecoder != nil || args.replayAccessLog != "" || args.replayTrace != ""
+	if replay {
 		workerChans = createChannels(args.concurrency, &workersWG)
 
 		go func() {
-			SetupOps(&args, workerChans, credential)
+			if args.replayAccessLog != "" {
+				ReplayAccessLog(&args, workerChans, credential)
+			} else if args.replayTrace != "" {
+				ReplayTrace(&args, workerChans, credential)
+			} else {
+				SetupOps(&args, workerChans, credential)
+			}
 			closeAllWorkerChannels(workerChans)
 		}()
 	}
@@ -216,14 +556,18 @@ func startTestWorker(c chan<- result, args parameters) {
 			workerId := i*workersPerEndpoint + currEndpointWorkerId
 			var workChan *workerChan
 			// if replay or a mixed workload setup a channel for each worker
-			if args.jsonDecoder != nil {
+			if replay {
 				workChan = workerChans[workerId]
 				workChan.wg.Add(1)
 			}
-			go worker(c, args, credential, workerId, endpoint, endpointStartTime, limiter, workChan)
+			workerLimiter := limiter
+			if workerId < args.heavyConcurrency {
+				workerLimiter = heavyLimiter
+			}
+			go worker(c, args, credential, workerId, endpoint, endpointStartTime, workerLimiter, workChan)
 		}
 	}
-	if args.jsonDecoder != nil {
+	if replay {
 		workersWG.Wait()
 	}
 }
@@ -243,35 +587,120 @@ func loadCredentialProfile(profile string, nosign bool) (*credentials.Credential
 }
 
 func ReceiveS3Op(svc *s3.S3, httpClient *http.Client, args *parameters, durationLimit *durationSetting, limiter *rate.Limiter, workersChan *workerChan, r *result) {
+	defer workersChan.wg.Done()
+	stopped := false
+	objrange := args.objrange
 	for op := range workersChan.workChan {
-		args.osize = int64(op.Size)
-		args.bucketname = op.Bucket + "s3tester"
-		// need to mock up garbage metadata if it is a SUPD S3 event
-		if op.Event == "updatemeta" {
-			args.metadata = metadataValue(int(op.Size))
-		}
-		sendRequest(svc, httpClient, op.Event, op.Key, args, r, limiter)
-		if durationLimit.enabled() {
-			return
+		// keep draining the channel so that the remaining non-write operations still get sent, and so that the
+		// workload doesn't block on a worker that stopped
+		if stopped {
+			continue
 		}
+		stopped = receiveS3Op(svc, httpClient, args, durationLimit, limiter, op, objrange, r)
+	}
+}
+
+// Sends an operation of the replay and returns true once the time is up. The worker carries on with the next
+// operation after a panic.
+func receiveS3Op(svc *s3.S3, httpClient *http.Client, args *parameters, durationLimit *durationSetting, limiter *rate.Limiter, op s3op, objrange string, r *result) bool {
+	defer recoverWorker(args.workerId, r)
+	args.osize = int64(op.Size)
+	write, charge := writesObject(args, op.Event, op.Key), writeCharge(args)
+	if write && !args.writeLimit.reserve(charge) {
+		return false
+	}
+	args.bucketname = op.Bucket + "s3tester"
+	args.objrange = objrange
+	if op.Range != "" {
+		args.objrange = op.Range
+	}
+	// need to mock up garbage metadata if it is a SUPD S3 event
+	if op.Event == "updatemeta" {
+		args.metadata = metadataValue(int(op.Size))
+		args.metadataTemplate = nil
+	}
+	sent := r.sumObjSize
+	sendRequest(svc, httpClient, op.Event, op.Key, args, r, limiter)
+	if write && args.payload.files != nil {
+		args.writeLimit.settle(charge, r.sumObjSize-sent)
+	}
+	return durationLimit.enabled()
+}
+
+// Keeps a worker going after a panic outside of an operation, e.g. while picking its next key, which only skips the
+// request it was preparing. Panics of operations are recovered per request by dispatchWithRecovery.
+func recoverWorker(id int, r *result) {
+	if p := recover(); p != nil {
+		r.Panics++
+		log.Printf("Worker %d recovered from a panic and carries on with its next request: %v\n%s", id, p, debug.Stack())
 	}
-	workersChan.wg.Done()
 }
 
 func sendRequest(svc *s3.S3, httpClient *http.Client, optype string, keyName string, args *parameters, r *result, limiter *rate.Limiter) {
 	r.Count++
+	sumObjSize := r.sumObjSize
 	start := time.Now()
-	err := DispatchOperation(svc, httpClient, optype, keyName, args, r, int64(args.nrequests.value))
+	if args.arrivals != nil {
+		// open loop, the latency includes the time the request was started late
+		start = args.arrivals.wait()
+		r.recordScheduleLag(time.Since(start))
+	}
+	args.responses.reset()
+	err := dispatchWithRecovery(svc, httpClient, optype, keyName, args, r)
 	elapsed := time.Since(start)
+	if detailedRequests != nil && !args.memWatchdog.sheds("detailed-log") {
+		detailedRequests.write(args.responses.record(start, elapsed, optype, args.bucketname, keyName, r.sumObjSize-sumObjSize, err))
+	}
+	if soft, ok := err.(*softFailure); ok {
+		// the response was received, only its body doesn't match its headers
+		r.recordSoftFailure(soft.kind)
+		log.Printf("Soft failure (%s) of %s on object '%s/%s': %v", soft.kind, args.optype, args.bucketname, keyName, err)
+		err = nil
+	}
+	if args.conditions != nil {
+		if outcome := conditionOutcome(err); outcome != "" {
+			r.recordCondition(outcome)
+			err = nil
+		}
+	}
 	r.RecordLatency(elapsed)
+	args.resultStream.record(elapsed, r.sumObjSize-sumObjSize, err != nil)
+	if optype == "versionedget" || optype == "versioneddelete" {
+		r.recordModeLatency(readMode(args.versionId), elapsed)
+	}
+	if args.scheme != "" {
+		r.recordSchemeLatency(args.scheme, elapsed)
+	}
+	if args.storageClass != "" {
+		r.recordStorageClassLatency(args.storageClass, elapsed)
+	}
+	if args.discovery != nil {
+		r.recordDiscoveredLatency(args.discovery.endpoint(args.workerId), elapsed)
+	}
+	if args.mix != nil || args.readWhileWrite > 0 || args.replayAccessLog != "" || args.replayTrace != "" {
+		r.recordOperation(optype, elapsed, r.sumObjSize-sumObjSize, err != nil)
+	}
+	if args.species != nil {
+		r.recordSpecies(args.speciesName, elapsed, r.sumObjSize-sumObjSize, err != nil)
+	}
+	if args.trafficClass != "" {
+		r.recordClass(args.trafficClass, start.Sub(r.startTime) < args.heavyStart, elapsed, r.sumObjSize-sumObjSize, err != nil)
+	}
+	if args.heatmapPrefix > 0 && !args.memWatchdog.sheds("heatmap-prefix-length") {
+		r.recordPrefixLatency(keyPrefix(keyName, args.heatmapPrefix), elapsed, err != nil)
+	}
+	if args.numBuckets > 0 {
+		r.recordBucket(args.bucketNumber, args.bucketname, r.sumObjSize-sumObjSize, err != nil)
+	}
 
 	if err != nil {
 		r.Failcount++
+		r.recordErrorCode(errorCode(err))
 		log.Printf("Failed %s on object '%s/%s': %v", args.optype, args.bucketname, keyName, err)
 	}
 	r.elapsedSum += elapsed
 
-	if args.logging {
+	if args.logging && !args.memWatchdog.sheds("logdetail") {
 		r.data = append(r.data, detail{start, elapsed})
 	}
 
@@ -280,14 +709,109 @@ func sendRequest(svc *s3.S3, httpClient *http.Client, optype string, keyName str
 	}
 }
 
+// Runs an operation, turning a panic into a failed request so that a bug in one operation implementation
+// doesn't abort the run and lose the statistics gathered so far. The worker carries on with its next request.
+func dispatchWithRecovery(svc s3iface.S3API, httpClient *http.Client, optype, keyName string, args *parameters, r *result) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.Panics++
+			err = fmt.Errorf("panic: %v\n%s", p, debug.Stack())
+		}
+	}()
+	return DispatchOperation(svc, httpClient, optype, keyName, args, r, int64(args.nrequests.value))
+}
+
 func worker(results chan<- result, args parameters, credentials *credentials.Credentials, id int, endpoint string, runstart time.Time, limiter *rate.Limiter, workerChan *workerChan) {
 	httpClient := MakeHTTPClient()
-	svc := MakeS3Service(httpClient, args.retrySleep, args.retries, endpoint, args.region, args.consistencyControl, credentials)
+	transport := httpClient.Transport.(*http.Transport)
+	var connWaits *connWaitRecorder
+	if args.maxConnsPerHost > 0 {
+		transport = sharedLimitedTransport(args.maxConnsPerHost)
+		connWaits = newConnWaitRecorder(transport)
+		httpClient = &http.Client{Transport: connWaits}
+	}
+	lookups := newDNSRecorder(httpClient.Transport)
+	httpClient.Transport = newThrottledTransport(lookups, args.bandwidth, NewBandwidthLimiter(args.workerBandwidth))
+	if args.dnsRefresh > 0 {
+		stop := make(chan struct{})
+		defer close(stop)
+		go refreshConnections(transport, args.dnsRefresh, stop)
+	}
+	serviceEndpoint := endpoint
+	if args.httpPercent > 0 {
+		workersPerEndpoint := args.concurrency / len(args.endpoints)
+		args.scheme = "https"
+		if usesPlainHTTP(args.httpPercent, id%workersPerEndpoint, workersPerEndpoint) {
+			// validated when parsing the arguments
+			serviceEndpoint, _ = plainHTTPEndpoint(endpoint, args.httpPort)
+			args.scheme = "http"
+		}
+	}
+	svc := MakeS3Service(httpClient, args.retrySleep, args.retries, serviceEndpoint, args.region, args.consistencyControl, credentials)
+	args.timeouts.install(svc)
+	args.objectLock.install(svc)
+	args.workerId = id
+	args.discovery.install(svc, id)
+	args.acl.install(svc)
+	args.species.install(svc)
+	args.conditions.install(svc)
+	args.requestExtras.install(svc)
+	args.encryption.install(svc)
+	created.install(svc)
+	audit.install(svc)
+	if args.destEndpoint != "" {
+		destination := MakeS3Service(httpClient, args.retrySleep, args.retries, args.destEndpoint, args.region, args.consistencyControl, args.destCredentials)
+		args.timeouts.install(destination)
+		args.requestExtras.install(destination)
+		args.encryption.install(destination)
+		created.destination().install(destination)
+		args.destination = destination
+	}
+	if args.bucketPerWorker {
+		if args.copyBucket == args.bucketname {
+			// copies stay within the worker's bucket
+			args.copyBucket = numberedBucket(args.copyBucket, id)
+		}
+		args.bucketname = numberedBucket(args.bucketname, id)
+		if err := ensureBucket(svc, args.bucketname, args.region); err != nil {
+			// the requests of the worker fail and are reported
+			log.Printf("Worker %d failed creating its bucket '%s': %v", id, args.bucketname, err)
+		}
+	}
 	var source *rand.Rand
 
 	r := NewResult()
 	r.Endpoint = endpoint
 	r.startTime = runstart
+	if connWaits != nil {
+		r.ConnWaitResult = connWaits.waits
+	}
+	r.DNS = lookups.result
+	r.recordFingerprints(svc, args.memWatchdog)
+	if args.statusCodes {
+		r.recordStatusCodes(svc)
+	}
+	if detailedRequests != nil {
+		args.responses = recordResponses(svc)
+	}
+	r.recordRetries(svc, args.retryBudget)
+	r.recordRetriedLatencies(svc)
+	if args.versionFile != "" && (isWriteOperation(args.optype) || args.mix.writes()) {
+		r.recordVersions(svc, args.memWatchdog)
+	}
+	if args.stampIdentity {
+		r.stampIdentity(svc, args.runId, id)
+	}
+
+	// Panics of operations are recovered per request and panics while sending them per key, this keeps the statistics
+	// of the worker if it panics while setting up.
+	defer func() {
+		if p := recover(); p != nil {
+			r.Panics++
+			log.Printf("Worker %d stopped after a panic: %v\n%s", id, p, debug.Stack())
+			results <- r
+		}
+	}()
 
 	if args.logging {
 		r.data = make([]detail, 0, args.nrequests.value/args.concurrency*args.attempts)
@@ -297,51 +821,243 @@ func worker(results chan<- result, args parameters, credentials *credentials.Cre
 		source = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
 
-	durationLimit := NewDurationSetting(args.duration, runstart)
+	if args.collision {
+		args.payload.seedSuffix = collisionSeedSuffix(id)
+	}
+
+	if args.generations {
+		r.Generations = NewGenerationResult()
+		args.payload.generations = r.Generations
+	}
+
+	// the first workers of a read-while-write run overwrite the keys the others read
+	writer := id < args.readWhileWrite
+	if writer {
+		args.optype = "put"
+	} else if args.readWhileWrite > 0 {
+		r.Freshness = NewFreshnessResult(args.writeAcks)
+		args.payload.freshness = r.Freshness
+	}
+
+	// the first workers of a run with traffic classes send the heavy requests, once the others ran alone for a while
+	if args.heavyConcurrency > 0 {
+		args.trafficClass = sensitiveClass
+		if id < args.heavyConcurrency {
+			args.trafficClass = heavyClass
+			args.optype, args.osize = args.heavyOperation, args.heavySize
+			args.objectprefix += "-heavy"
+			waitUntil(runstart.Add(args.heavyStart))
+		}
+	}
+
+	if args.checksumAlgorithm != "" {
+		r.Checksums = NewChecksumResult(args.checksumAlgorithm)
+		args.payload.checksums = r.Checksums
+	}
+
+	if args.payload.stream != nil {
+		r.PayloadWaitResult = NewLatencyResult()
+		args.payload.streamWaits = r.PayloadWaitResult
+	}
+
+	var picker *versionPicker
+	if args.optype == "versionedget" || args.optype == "versioneddelete" {
+		picker = NewVersionPicker(args.versionRatio, time.Now().UnixNano()+int64(id), args.recordedVersions, args.optype == "versioneddelete")
+	}
+
+	durationLimit := NewMaxDurationSetting(&args, runstart)
+
+	var pipe *pipeline
+	if args.optype == "pipeline" {
+		pipe = NewPipeline(svc, httpClient, args, endpoint, limiter)
+	}
 
 	if workerChan != nil {
 		ReceiveS3Op(svc, httpClient, &args, durationLimit, limiter, workerChan, &r)
 	} else {
 		maxRequestsPerWorker := int64(args.nrequests.value / args.concurrency)
-		if args.duration.set && args.optype != "get" {
+		// reads, and mixes with requests, go over the keys of the requests again and again until the time is up
+		cycle := args.duration.set && (args.nrequests.set || isRepeatableRead(args.optype))
+		if args.duration.set && !cycle {
 			maxRequestsPerWorker = math.MaxInt64 / int64(args.concurrency)
 		}
-		for j := int64(0); j < maxRequestsPerWorker; j++ {
-			var keyName string
-			switch args.overwrite {
-			case 1:
-				keyName = args.objectprefix
-			case 2:
-				keyName = args.objectprefix + "-" + strconv.FormatInt(j, 10)
-			default:
-				keyName = args.objectprefix + "-" + strconv.FormatInt(int64(id)*maxRequestsPerWorker+j, 10)
+		// a multidelete request deletes a batch of consecutive keys
+		step := int64(1)
+		if args.optype == "multidelete" {
+			step = int64(args.batchSize)
+		}
+		// the buckets the keys are spread over with num-buckets
+		bucket, copyBucket := args.bucketname, args.copyBucket
+		var order []int64
+		if args.shuffleSeed != 0 {
+			order = keyOrder(args.shuffleSeed, id, maxRequestsPerWorker)
+		}
+		// the keys are picked from all keys of the put run, or from the keys overwritten again and again
+		keySpace := int64(args.nrequests.value)
+		if args.overwriteKeys > 0 {
+			keySpace = args.overwriteKeys
+		}
+		keys := args.keyDistribution.picker(keySpace, time.Now().UnixNano()+int64(id))
+		rank := shapeRank(id, args.concurrency, len(args.endpoints))
+		for pass := 0; pass == 0 || cycle; pass++ {
+			// a worker without requests of its own has no keys to go over again
+			if maxRequestsPerWorker == 0 {
+				break
 			}
-
-			r.incrementUniqObjNumCount(args.duration.set)
-
-			for repcount := 0; repcount < args.attempts; repcount++ {
-				if source != nil {
-					//size command line arg usually sets the size for each request we need to overwrite
-					// with new random size per request
-					newSize := randMinMax(source, args.min, args.max)
-					args.osize = newSize
-				}
-
-				sendRequest(svc, httpClient, args.optype, keyName, &args, &r, limiter)
-
-				if durationLimit.enabled() {
+			for j := int64(0); j < maxRequestsPerWorker; j += step {
+				// a panic outside of an operation skips the key, the worker carries on with the next one
+				done := func() bool {
+					defer recoverWorker(id, &r)
+					if !args.loadShape.admit(rank) {
+						return true
+					}
+					index := j
+					if order != nil {
+						index = order[j]
+					}
+					keyName := objectKey(&args, id, maxRequestsPerWorker, index)
+					// the number of the key, which places it in a bucket of num-buckets
+					keyNumber := args.keyOffset + int64(id)*maxRequestsPerWorker + index
+					if keys != nil {
+						keyNumber = args.keyOffset + keys.next()
+						keyName = numberedKey(&args, keyNumber)
+					}
+					if size, ok := args.keyList.size(keyName); ok {
+						args.osize = size
+					}
+					if args.species != nil {
+						species := args.species.of(keyName)
+						args.osize = species.Size
+						args.payload.compressRatio = species.CompressRatio
+						args.speciesName = species.Name
+					}
+					if args.numBuckets > 0 {
+						args.bucketNumber = keyBucket(args.bucketPlacement, keyName, keyNumber, args.numBuckets)
+						args.bucketname = numberedBucket(bucket, args.bucketNumber)
+						if copyBucket == bucket {
+							// copies stay within the bucket of the key
+							args.copyBucket = args.bucketname
+						}
+					}
+					if args.optype == "multidelete" {
+						args.batchKeys = args.batchKeys[:0]
+						for k := j; k < j+step && k < maxRequestsPerWorker; k++ {
+							args.batchKeys = append(args.batchKeys, objectKey(&args, id, maxRequestsPerWorker, k))
+						}
+					}
+
+					for repcount := 0; repcount < args.attempts; repcount++ {
+						if args.mix != nil {
+							args.optype = args.mix.pick(rand.Intn)
+						}
+
+						if source != nil {
+							//size command line arg usually sets the size for each request we need to overwrite
+							// with new random size per request
+							newSize := randMinMax(source, args.min, args.max)
+							args.osize = newSize
+						}
+
+						write, charge := writesObject(&args, args.optype, keyName), writeCharge(&args)
+						if write && !args.writeLimit.reserve(charge) {
+							return true
+						}
+
+						if repcount == 0 && pass == 0 {
+							r.incrementUniqObjNumCount()
+						}
+
+						if args.storageClasses != nil && isWriteOperation(args.optype) {
+							args.storageClass = args.storageClasses.pick(rand.Intn)
+						}
+
+						if picker != nil {
+							var err error
+							if args.versionId, err = picker.pick(svc, args.bucketname, keyName); err != nil {
+								r.Count++
+								r.Failcount++
+								log.Printf("Failed listing versions of object '%s/%s': %v", args.bucketname, keyName, err)
+								continue
+							}
+						}
+
+						if args.optype == "listparts" {
+							// the upload is looked up before the request, so that only the ListParts request is measured
+							ids, err := uploadIds(svc, args.bucketname, keyName)
+							if err != nil {
+								r.Count++
+								r.Failcount++
+								log.Printf("Failed looking up the multipart upload of object '%s/%s': %v", args.bucketname, keyName, err)
+								continue
+							}
+							args.uploadId = aws.StringValue(ids[0])
+						}
+
+						sent := r.sumObjSize
+						if pipe != nil {
+							pipe.submit(keyName)
+						} else if writer {
+							// every write is a generation of its own, acknowledged to the readers once it succeeded
+							args.payload.generation = time.Now().UnixNano()
+							args.writeAcks.begin(args.payload.generation)
+							failed := r.Failcount
+							sendRequest(svc, httpClient, args.optype, keyName, &args, &r, limiter)
+							if r.Failcount == failed {
+								args.writeAcks.acknowledge(keyName, args.payload.generation, time.Now())
+							}
+						} else {
+							sendRequest(svc, httpClient, args.optype, keyName, &args, &r, limiter)
+						}
+						if write && pipe == nil && args.payload.files != nil {
+							args.writeLimit.settle(charge, r.sumObjSize-sent)
+						}
+
+						if durationLimit.enabled() {
+							return true
+						}
+					}
+					return false
+				}()
+				if done {
+					pipe.finish(&r)
 					results <- r
 					return
 				}
 			}
 		}
 	}
+	pipe.finish(&r)
 	results <- r
 }
 
-func (this *result) incrementUniqObjNumCount(isDurationSet bool) {
-	// This feature is very much tied to the args.attempts option and doesn't work when using duration to get the same values over and over again.
-	if this.Operation != "options" && !(isDurationSet && (this.Operation == "get" || this.Operation == "randget")) {
+// Returns the name of the j-th key of a worker.
+func objectKey(args *parameters, id int, maxRequestsPerWorker, j int64) string {
+	switch args.overwrite {
+	case 1:
+		return args.objectprefix
+	case 2:
+		return numberedKey(args, args.keyOffset+j)
+	}
+	return numberedKey(args, args.keyOffset+int64(id)*maxRequestsPerWorker+j)
+}
+
+// Returns the name of the key with the number, the n-th key of keys-from-file if one is given.
+func numberedKey(args *parameters, n int64) string {
+	if args.overwriteKeys > 0 {
+		n %= args.overwriteKeys
+	}
+	if args.keyList != nil {
+		return args.keyList.key(n)
+	}
+	if args.tree != nil {
+		return args.tree.key(args.objectprefix, n)
+	}
+	return args.keyTemplate.name(args.objectprefix, n)
+}
+
+func (this *result) incrementUniqObjNumCount() {
+	// keys revisited by a duration based run are only counted on the first pass
+	if this.Operation != "options" {
 		this.UniqObjNum++
 	}
 }
@@ -350,7 +1066,8 @@ func collectWorkerResult(c <-chan result, args parameters, startTime time.Time)
 	workersPerEndpoint := args.concurrency / len(args.endpoints)
 	workerWorkload := args.nrequests.value / args.concurrency
 	endpointResultMap := make(map[string]*result)
-	if args.logging {
+	// the detailed log accumulates across all phases of a run
+	if args.logging && detailed == nil {
 		detailed = make([]detail, 0)
 	}
 
@@ -360,6 +1077,8 @@ func collectWorkerResult(c <-chan result, args parameters, startTime time.Time)
 		if _, hasKey := endpointResultMap[r.Endpoint]; !hasKey {
 			// init the endpointResultMap, Concurrency is the counter of the workers that have been collected
 			r.Concurrency = 1
+			// the DNS lookups are left out without any lookups, as they are when merging
+			r.DNS = (*dnsResult)(nil).merge(r.DNS)
 			endpointResultMap[r.Endpoint] = &r
 		} else {
 			mergeResult(endpointResultMap[r.Endpoint], &r)
@@ -367,26 +1086,27 @@ func collectWorkerResult(c <-chan result, args parameters, startTime time.Time)
 		}
 
 		if endpointResultMap[r.Endpoint].Concurrency == workersPerEndpoint {
-			finishEndpointResultCollection(endpointResultMap[r.Endpoint], r.startTime, args.attempts, args.overwrite, workerWorkload)
+			finishEndpointResultCollection(endpointResultMap[r.Endpoint], r.startTime, args.attempts, args.overwrite, workerWorkload, args.overwriteKeys)
 		}
 
 		if args.logging {
 			detailed = append(detailed, r.data...)
 		}
+		writtenVersions = append(writtenVersions, r.versions...)
 	}
 	testResult := processEndpointResults(endpointResultMap, args.endpoints)
 	testResult.CummulativeResult.elapsedTime = time.Since(startTime)
 	return testResult
 }
 
-func finishEndpointResultCollection(endpointResult *result, startTime time.Time, repeat, overwrite, workload int) {
+func finishEndpointResultCollection(endpointResult *result, startTime time.Time, repeat, overwrite, workload int, overwriteKeys int64) {
 	endpointResult.elapsedTime = time.Since(startTime)
 	// TODO: not sure if we should also handle the case where Failcount > UniqObjNum
 	// if that won't happend we can simply remove the if statement
 	if endpointResult.Failcount > 0 && endpointResult.UniqObjNum >= endpointResult.Failcount {
 		endpointResult.UniqObjNum -= endpointResult.Failcount
 	}
-	endpointResult.correctEndpointUniqObjCountWithOverwriteSetting(overwrite, workload)
+	endpointResult.correctEndpointUniqObjCountWithOverwriteSetting(overwrite, workload, overwriteKeys)
 }
 
 func mergeResult(aggregateResults, r *result) {
@@ -395,16 +1115,102 @@ func mergeResult(aggregateResults, r *result) {
 	aggregateResults.UniqObjNum += r.UniqObjNum
 	aggregateResults.Count += r.Count
 	aggregateResults.Failcount += r.Failcount
+	aggregateResults.Panics += r.Panics
+	aggregateResults.Retries = aggregateResults.Retries.merge(r.Retries)
+	aggregateResults.RetriedResult = aggregateResults.RetriedResult.merge(r.RetriedResult)
+	aggregateResults.MultipartCount += r.MultipartCount
+	aggregateResults.KeyCount += r.KeyCount
 	aggregateResults.elapsedSum += r.elapsedSum
+	for mode, m := range r.ModeResults {
+		if aggregateResults.ModeResults == nil {
+			aggregateResults.ModeResults = make(map[string]*latencyResult)
+		}
+		aggregateResults.ModeResults[mode] = aggregateResults.ModeResults[mode].merge(m)
+	}
+	aggregateResults.PartResult = aggregateResults.PartResult.merge(r.PartResult)
+	aggregateResults.ConnWaitResult = aggregateResults.ConnWaitResult.merge(r.ConnWaitResult)
+	aggregateResults.PayloadWaitResult = aggregateResults.PayloadWaitResult.merge(r.PayloadWaitResult)
+	aggregateResults.ScheduleLag = aggregateResults.ScheduleLag.merge(r.ScheduleLag)
+	aggregateResults.DNS = aggregateResults.DNS.merge(r.DNS)
+	aggregateResults.PageResult = aggregateResults.PageResult.merge(r.PageResult)
+	aggregateResults.ObjectThroughput = aggregateResults.ObjectThroughput.merge(r.ObjectThroughput)
+	aggregateResults.Generations = aggregateResults.Generations.merge(r.Generations)
+	aggregateResults.Freshness = aggregateResults.Freshness.merge(r.Freshness)
+	aggregateResults.Checksums = aggregateResults.Checksums.merge(r.Checksums)
+	for stage, s := range r.StageResults {
+		aggregateResults.recordStage(stage, s)
+	}
+	aggregateResults.LifecycleResult = aggregateResults.LifecycleResult.merge(r.LifecycleResult)
+	aggregateResults.RestoreResult = aggregateResults.RestoreResult.merge(r.RestoreResult)
+	aggregateResults.PresignResult = aggregateResults.PresignResult.merge(r.PresignResult)
+	aggregateResults.TransferResult = aggregateResults.TransferResult.merge(r.TransferResult)
+	aggregateResults.ExpiredURLsRejected += r.ExpiredURLsRejected
+	aggregateResults.ExpiredURLsAccepted += r.ExpiredURLsAccepted
+	aggregateResults.Select = aggregateResults.Select.merge(r.Select)
+	for kind, count := range r.SoftFailures {
+		if aggregateResults.SoftFailures == nil {
+			aggregateResults.SoftFailures = make(map[string]int)
+		}
+		aggregateResults.SoftFailures[kind] += count
+	}
+	for code, count := range r.ErrorCodes {
+		if aggregateResults.ErrorCodes == nil {
+			aggregateResults.ErrorCodes = make(map[string]int)
+		}
+		aggregateResults.ErrorCodes[code] += count
+	}
+	for status, count := range r.StatusCodes {
+		if aggregateResults.StatusCodes == nil {
+			aggregateResults.StatusCodes = make(map[int]int)
+		}
+		aggregateResults.StatusCodes[status] += count
+	}
+	for outcome, count := range r.Conditions {
+		if aggregateResults.Conditions == nil {
+			aggregateResults.Conditions = make(map[string]int)
+		}
+		aggregateResults.Conditions[outcome] += count
+	}
+	for fingerprint, count := range r.Fingerprints {
+		if aggregateResults.Fingerprints == nil {
+			aggregateResults.Fingerprints = make(map[string]int)
+		}
+		aggregateResults.Fingerprints[fingerprint] += count
+	}
+	for scheme, s := range r.SchemeResults {
+		if aggregateResults.SchemeResults == nil {
+			aggregateResults.SchemeResults = make(map[string]*latencyResult)
+		}
+		aggregateResults.SchemeResults[scheme] = aggregateResults.SchemeResults[scheme].merge(s)
+	}
+	aggregateResults.mergePrefixStats(r)
+	aggregateResults.mergeBucketStats(r)
+	for class, s := range r.StorageClassResults {
+		if aggregateResults.StorageClassResults == nil {
+			aggregateResults.StorageClassResults = make(map[string]*latencyResult)
+		}
+		aggregateResults.StorageClassResults[class] = aggregateResults.StorageClassResults[class].merge(s)
+	}
+	for endpoint, s := range r.DiscoveredResults {
+		if aggregateResults.DiscoveredResults == nil {
+			aggregateResults.DiscoveredResults = make(map[string]*latencyResult)
+		}
+		aggregateResults.DiscoveredResults[endpoint] = aggregateResults.DiscoveredResults[endpoint].merge(s)
+	}
+	aggregateResults.mergeOperationResults(r)
+	aggregateResults.mergeSpeciesResults(r)
+	aggregateResults.mergeClassResults(r)
 }
 
-func (r *result) correctEndpointUniqObjCountWithOverwriteSetting(overwrite, workload int) {
+func (r *result) correctEndpointUniqObjCountWithOverwriteSetting(overwrite, workload int, overwriteKeys int64) {
 	// this function should only be invoked by an endpoint result
 	// unique obj count should be consist with overwrite setting
 	if overwrite == 1 && r.UniqObjNum > 0 {
 		r.UniqObjNum = 1
 	} else if overwrite == 2 && r.UniqObjNum > workload {
 		r.UniqObjNum = workload
+	} else if overwriteKeys > 0 && int64(r.UniqObjNum) > overwriteKeys {
+		r.UniqObjNum = int(overwriteKeys)
 	}
 }
 
@@ -433,9 +1239,23 @@ func processTestResult(testResult *results, args parameters) {
 	cummulativeResult.Operation = args.optype
 	cummulativeResult.Concurrency = args.concurrency
 	setupResultStat(cummulativeResult)
+	cummulativeResult.setupConfidenceIntervals(args.bootstrap, time.Now().UnixNano())
+	if cummulativeResult.PartResult != nil {
+		cummulativeResult.PartSize = args.partsize
+	}
+	cummulativeResult.setupPrefixStats(args.heatmapTop)
+	cummulativeResult.setupBucketStats()
+	if args.isolateRun || args.stampIdentity {
+		cummulativeResult.RunId = args.runId
+	}
 
 	for _, endpointResult := range testResult.PerEndpointResult {
 		setupResultStat(endpointResult)
+		if endpointResult.PartResult != nil {
+			endpointResult.PartSize = args.partsize
+		}
+		endpointResult.setupPrefixStats(args.heatmapTop)
+		endpointResult.setupBucketStats()
 	}
 
 	cummulativeResult.Category = args.bucketname + "-" + cummulativeResult.Operation + "-" + strconv.Itoa(cummulativeResult.Concurrency) + "-" + strconv.FormatInt(cummulativeResult.sumObjSize, 10)
@@ -448,6 +1268,51 @@ func setupResultStat(testResult *result) {
 	calcStats(testResult, testResult.Concurrency, elapsedTime)
 	roundResult(testResult)
 	processPercentiles(testResult)
+	for _, m := range testResult.ModeResults {
+		m.setupStats()
+	}
+	testResult.PartResult.setupStats()
+	testResult.ConnWaitResult.setupStats()
+	testResult.RetriedResult.setupStats()
+	testResult.PayloadWaitResult.setupStats()
+	testResult.ScheduleLag.setupStats()
+	if testResult.DNS != nil {
+		testResult.DNS.Lookups.setupStats()
+	}
+	testResult.PageResult.setupStats()
+	testResult.ObjectThroughput.setupStats()
+	for _, s := range testResult.StageResults {
+		s.setupStats()
+	}
+	testResult.LifecycleResult.setupStats()
+	testResult.RestoreResult.setupStats()
+	testResult.PresignResult.setupStats()
+	testResult.TransferResult.setupStats()
+	if testResult.Select != nil {
+		testResult.Select.FirstRecords.setupStats()
+	}
+	testResult.Freshness.setupStats()
+	testResult.Checksums.setupStats()
+	testResult.GC.setupStats()
+	testResult.Efficiency.setupStats(testResult.Count, testResult.sumObjSize)
+	for _, s := range testResult.SchemeResults {
+		s.setupStats()
+	}
+	for _, s := range testResult.StorageClassResults {
+		s.setupStats()
+	}
+	for _, s := range testResult.DiscoveredResults {
+		s.setupStats()
+	}
+	for _, s := range testResult.OperationResults {
+		s.setupStats()
+	}
+	for _, s := range testResult.SpeciesResults {
+		s.setupStats()
+	}
+	for _, s := range testResult.ClassResults {
+		s.setupStats()
+	}
 
 	minReqTime := time.Duration(testResult.latencies.Min() * 1e4)
 	maxReqTime := time.Duration(testResult.latencies.Max() * 1e4)
@@ -463,6 +1328,10 @@ func calcStats(results *result, concurrency int, elapsedTime time.Duration) {
 	results.ActualRequestsPerSec = float64(results.Count) / elapsedTime.Seconds()
 	results.ContentThroughput = float64(results.sumObjSize) / 1024 / 1024 / elapsedTime.Seconds()
 	results.AverageObjectSize = float64(results.sumObjSize) / float64(results.Count)
+	results.KeysPerSec = float64(results.KeyCount) / elapsedTime.Seconds()
+	if results.Operation == "presign" && results.TransferResult == nil {
+		results.PresignsPerSecPerCore = results.ActualRequestsPerSec / float64(runtime.GOMAXPROCS(0))
+	}
 }
 
 func roundResult(results *result) {
@@ -470,6 +1339,8 @@ func roundResult(results *result) {
 	results.ActualRequestsPerSec = roundFloat(results.ActualRequestsPerSec, 1)
 	results.ContentThroughput = roundFloat(results.ContentThroughput, 6)
 	results.AverageObjectSize = roundFloat(results.AverageObjectSize, 0)
+	results.KeysPerSec = roundFloat(results.KeysPerSec, 1)
+	results.PresignsPerSecPerCore = roundFloat(results.PresignsPerSecPerCore, 1)
 }
 
 var percentiles []float64 = []float64{50, 75, 90, 95, 99, 99.9}
@@ -518,6 +1389,27 @@ func printResults(testResult results) {
 	fmt.Println("\n\t--- Total Results ---")
 	printResult(testResult.CummulativeResult)
 	HistogramSummary(testResult.CummulativeResult.latencies)
+	printLatencySummary(testResult.CummulativeResult)
+	if len(testResult.CummulativeResult.Fingerprints) > 0 {
+		fmt.Println("\n\t--- Response Header Fingerprints ---")
+		printFingerprints(testResult.CummulativeResult.Fingerprints)
+	}
+	if gc := testResult.CummulativeResult.GC; gc != nil {
+		fmt.Printf("GC pauses of the tester: %d collections, %s in total (%.3f%% of the run), longest %s\n", gc.Collections, time.Duration(gc.TotalPause*float64(time.Millisecond)), gc.PauseFraction, time.Duration(gc.MaximumPause*float64(time.Millisecond)))
+	}
+	if efficiency := testResult.CummulativeResult.Efficiency; efficiency != nil {
+		printEfficiency(efficiency)
+	}
+	if len(testResult.CummulativeResult.ErrorCodes) > 0 {
+		fmt.Println("\n\t--- Failed Requests per Error Code ---")
+		printErrorCodes(testResult.CummulativeResult.ErrorCodes)
+	}
+	printTimeBox(testResult.TimeBox)
+	printQoSReport(testResult.QoS)
+	if testResult.Cost != nil {
+		fmt.Println("\n\t--- Cost ---")
+		printCost(*testResult.Cost)
+	}
 }
 
 func printResult(results result) {
@@ -525,6 +1417,9 @@ func printResult(results result) {
 		fmt.Printf("- Endpoint: %s\n", results.Endpoint)
 	} else { // Total result prints the operation & concurrency rather than endpoint
 		fmt.Printf("Operation: %s\n", results.Operation)
+		if results.RunId != "" {
+			fmt.Printf("Run id: %s\n", results.RunId)
+		}
 	}
 	fmt.Printf("Concurrency: %d\n", results.Concurrency)
 	fmt.Printf("Total number of requests: %d\n", results.Count)
@@ -534,6 +1429,20 @@ func printResult(results result) {
 	}
 
 	fmt.Printf("Failed requests: %d\n", results.Failcount)
+	if results.Panics != 0 {
+		fmt.Printf("Recovered panics: %d\n", results.Panics)
+	}
+	if results.Retries != nil {
+		fmt.Printf("Retries: %d, denied by the retry budget: %d\n", results.Retries.Retries, results.Retries.Denied)
+	}
+	for _, kind := range []string{softFailureTruncated, softFailureETag, softFailureChecksum} {
+		if count := results.SoftFailures[kind]; count != 0 {
+			fmt.Printf("Soft failures (%s): %d\n", kind, count)
+		}
+	}
+	if results.Conditions != nil {
+		fmt.Printf("Conditional responses: %d satisfied, %d not modified (304), %d precondition failed (412)\n", results.Conditions[conditionSatisfied], results.Conditions[conditionNotModified], results.Conditions[conditionPreconditionFailed])
+	}
 
 	fmt.Printf("Total elapsed time: %s\n", time.Duration(results.TotalElapsedTime*float64(time.Millisecond)))
 	fmt.Printf("Average request time: %s\n", time.Duration(results.AverageRequestTime*float64(time.Millisecond)))
@@ -544,8 +1453,192 @@ func printResult(results result) {
 	fmt.Printf("Actual requests/s: %.1f\n", results.ActualRequestsPerSec)
 	fmt.Printf("Content throughput: %.6f MB/s\n", results.ContentThroughput)
 	fmt.Printf("Average Object Size: %v\n", results.AverageObjectSize)
+	if results.KeyCount != 0 {
+		fmt.Printf("Total number of keys: %d\n", results.KeyCount)
+		fmt.Printf("Keys/s: %.1f\n", results.KeysPerSec)
+	}
+	if results.PresignsPerSecPerCore != 0 {
+		fmt.Printf("Presigned URLs/s per core: %.1f (GOMAXPROCS=%d)\n", results.PresignsPerSecPerCore, runtime.GOMAXPROCS(0))
+	}
+	if results.Generations != nil {
+		fmt.Printf("Reads checked for stale data: %d\n", results.Generations.Reads)
+		fmt.Printf("Stale reads: %d\n", results.Generations.StaleReads)
+		fmt.Printf("Maximum time travel: %s\n", time.Duration(results.Generations.MaxTimeTravel*float64(time.Millisecond)))
+	}
+	if results.Freshness != nil {
+		printFreshnessResult(results.Freshness)
+	}
+	if results.Checksums != nil {
+		fmt.Printf("Checksum algorithm: %s\n", results.Checksums.Algorithm)
+		fmt.Printf("Checksums computed: %d\n", results.Checksums.Computed)
+		fmt.Printf("Checksums verified: %d\n", results.Checksums.Verified)
+		fmt.Printf("Checksum time: %.3f ms\n", results.Checksums.Time)
+	}
 
 	printResponseTimeDistribution(results.Percentiles)
+	if results.PercentileIntervals != nil {
+		printConfidenceIntervals(results.PercentileIntervals, results.Resamples)
+	}
+
+	for _, mode := range []string{readLatest, readVersion} {
+		if m, ok := results.ModeResults[mode]; ok {
+			fmt.Printf("Read mode: %s\n", mode)
+			printLatencyResult(m)
+		}
+	}
+
+	if results.RetriedResult != nil {
+		printRetriedResult(results.RetriedResult)
+	}
+	if results.ConnWaitResult != nil {
+		fmt.Println("Connection wait")
+		printLatencyResult(results.ConnWaitResult)
+	}
+	if results.DNS != nil {
+		fmt.Printf("DNS lookups: %d, failed: %d, resolved to a different address: %d\n", results.DNS.Lookups.Count, results.DNS.Failures, results.DNS.AddressChanges)
+		printLatencyResult(results.DNS.Lookups)
+	}
+	if results.ScheduleLag != nil {
+		fmt.Println("Start lag behind the arrival schedule")
+		printLatencyResult(results.ScheduleLag)
+	}
+	if results.PayloadWaitResult != nil {
+		fmt.Println("Payload source wait")
+		printLatencyResult(results.PayloadWaitResult)
+	}
+	if results.MultipartCount != 0 {
+		fmt.Printf("Requests sent as multipart uploads: %d\n", results.MultipartCount)
+	}
+	if results.PartResult != nil {
+		fmt.Printf("Part uploads of %d bytes\n", results.PartSize)
+		printLatencyResult(results.PartResult)
+	}
+
+	if results.PageResult != nil {
+		fmt.Println("Pages")
+		printLatencyResult(results.PageResult)
+	}
+
+	if t := results.ObjectThroughput; t != nil {
+		fmt.Printf("Objects downloaded: %d\n", t.Count)
+		fmt.Printf("Average object throughput: %.3f MB/s\n", t.AverageThroughput)
+		fmt.Printf("Minimum object throughput: %.3f MB/s\n", t.MinThroughput)
+		fmt.Printf("Maximum object throughput: %.3f MB/s\n", t.MaxThroughput)
+		fmt.Println("Object Throughput Percentiles")
+		for _, percentile := range percentiles {
+			key := convertFloatToString(percentile)
+			fmt.Printf("%-5v  :   %-5v\n", key, convertFloatToString(t.Percentiles[key])+" MB/s")
+		}
+	}
+
+	for _, stage := range pipelineStageNames {
+		if s, ok := results.StageResults[stage]; ok {
+			fmt.Printf("Stage: %s\n", stage)
+			printLatencyResult(s)
+		}
+	}
+
+	if results.LifecycleResult != nil {
+		fmt.Println("Object lifecycle")
+		printLatencyResult(results.LifecycleResult)
+	}
+
+	if results.RestoreResult != nil {
+		fmt.Println("Restore turnaround")
+		printLatencyResult(results.RestoreResult)
+	}
+
+	if results.PresignResult != nil {
+		fmt.Println("Presign")
+		printLatencyResult(results.PresignResult)
+		fmt.Println("Transfer with presigned URL")
+		printLatencyResult(results.TransferResult)
+	}
+
+	if results.ExpiredURLsRejected != 0 || results.ExpiredURLsAccepted != 0 {
+		fmt.Printf("Expired URLs rejected: %d\n", results.ExpiredURLsRejected)
+		fmt.Printf("Expired URLs accepted: %d\n", results.ExpiredURLsAccepted)
+	}
+
+	if s := results.Select; s != nil {
+		fmt.Printf("Bytes scanned: %d\n", s.BytesScanned)
+		fmt.Printf("Bytes processed: %d\n", s.BytesProcessed)
+		fmt.Printf("Bytes returned: %d\n", s.BytesReturned)
+		if s.BytesScanned != 0 {
+			fmt.Printf("Returned/scanned: %.4f\n", float64(s.BytesReturned)/float64(s.BytesScanned))
+		}
+		if s.FirstRecords != nil {
+			fmt.Println("Time to first records")
+			printLatencyResult(s.FirstRecords)
+		}
+	}
+
+	for _, scheme := range []string{"http", "https"} {
+		if s, ok := results.SchemeResults[scheme]; ok {
+			fmt.Printf("Scheme: %s\n", scheme)
+			printLatencyResult(s)
+		}
+	}
+
+	for _, class := range s3.StorageClass_Values() {
+		if s, ok := results.StorageClassResults[class]; ok {
+			fmt.Printf("Storage class: %s\n", class)
+			printLatencyResult(s)
+		}
+	}
+
+	discovered := make([]string, 0, len(results.DiscoveredResults))
+	for endpoint := range results.DiscoveredResults {
+		discovered = append(discovered, endpoint)
+	}
+	sort.Strings(discovered)
+	for _, endpoint := range discovered {
+		fmt.Printf("Discovered endpoint: %s\n", endpoint)
+		printLatencyResult(results.DiscoveredResults[endpoint])
+	}
+
+	for _, op := range mixOperations {
+		if s, ok := results.OperationResults[op]; ok {
+			fmt.Printf("Mixed operation: %s (%.1f%% of requests)\n", op, 100*float64(s.Count)/float64(results.Count))
+			fmt.Printf("Failed requests: %d\n", s.Failcount)
+			fmt.Printf("Total bytes: %d\n", s.Bytes)
+			printLatencyResult(s.latencyResult)
+		}
+	}
+
+	printSpeciesResults(results.SpeciesResults, results.Count)
+
+	for _, class := range []string{sensitiveAloneClass, sensitiveContendedClass, heavyClass} {
+		if s, ok := results.ClassResults[class]; ok {
+			fmt.Printf("Traffic class: %s\n", class)
+			fmt.Printf("Failed requests: %d\n", s.Failcount)
+			fmt.Printf("Total bytes: %d\n", s.Bytes)
+			printLatencyResult(s.latencyResult)
+		}
+	}
+
+	if len(results.WorstPrefixes) > 0 {
+		fmt.Printf("Slowest key prefixes (of %d)\n", results.PrefixCount)
+		fmt.Printf("%-20s %10s %10s %12s %12s\n", "Prefix", "Requests", "Failed", "Avg (ms)", "Max (ms)")
+		for _, s := range results.WorstPrefixes {
+			fmt.Printf("%-20s %10d %10d %12.3f %12.3f\n", s.Prefix, s.Count, s.Failcount, s.AverageRequestTime, s.MaximumRequestTime)
+		}
+	}
+
+	if len(results.BucketStats) > 0 {
+		fmt.Println("Buckets")
+		fmt.Printf("%-30s %10s %10s %12s %12s\n", "Bucket", "Requests", "Failed", "Requests/s", "MB/s")
+		for _, s := range results.BucketStats {
+			fmt.Printf("%-30s %10d %10d %12.1f %12.6f\n", s.Bucket, s.Count, s.Failcount, s.ActualRequestsPerSec, s.ContentThroughput)
+		}
+	}
+}
+
+func printLatencyResult(l *latencyResult) {
+	fmt.Printf("Total number of requests: %d\n", l.Count)
+	fmt.Printf("Average request time: %s\n", time.Duration(l.AverageRequestTime*float64(time.Millisecond)))
+	printResponseTimeDistribution(l.Percentiles)
+	fmt.Printf("%-5v  :   %-5v\n", "max", convertFloatToString(l.MaximumRequestTime)+" ms")
 }
 
 func printJsonResult(testResult results) {
@@ -562,6 +1655,84 @@ func printJsonResult(testResult results) {
 func main() {
 	args := parseArgs()
 
+	if args.dryrun {
+		estimate := estimatePlannedCost(args)
+		if args.stages != nil {
+			estimate = costEstimate{}
+			for _, stage := range args.stages {
+				estimate = estimate.add(estimatePlannedCost(stage.args))
+			}
+		}
+		printCostEstimate(estimate, args.isJson)
+		return
+	}
+
+	applyGCSettings(args.gogc, args.gcMemoryLimit)
+	handleInterrupts()
+	if args.clientCert != nil {
+		clientCert = args.clientCert
+		clientCert.watch(args.clientCertRefresh)
+	}
+	if args.cleanup {
+		created = NewCreatedResources()
+	}
+
+	if args.bundle != "" {
+		var err error
+		if artifacts, err = NewBundle(args.bundle, os.Args); err != nil {
+			log.Fatalf("Failed starting the bundle %s: %v", args.bundle, err)
+		}
+	}
+	if args.resultsFormat == "json" {
+		resultsDoc = NewResultsDocument(os.Args)
+	}
+	if args.auditLog != "" {
+		var err error
+		if audit, err = NewAuditLog(args.auditLog); err != nil {
+			log.Fatalf("Failed creating the audit log %s: %v", args.auditLog, err)
+		}
+		audit.watchdog = args.memWatchdog
+	}
+	if args.detailedLog != "" {
+		var err error
+		if detailedRequests, err = NewRequestLog(args.detailedLog); err != nil {
+			log.Fatalf("Failed creating the detailed log %s: %v", args.detailedLog, err)
+		}
+	}
+
+	if args.stages == nil {
+		startDiscovery(&args)
+	}
+
+	if args.verifyManifest != "" {
+		report, err := verifyManifest(args)
+		if err != nil {
+			log.Printf("Failed verifying the manifest %s: %v", args.verifyManifest, err)
+		}
+		printManifestReport(report, args.isJson)
+		artifacts.finish()
+		if err != nil || report.failures() > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if args.abortIncomplete {
+		ok := abortAllIncomplete(args)
+		artifacts.finish()
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if args.stampIdentity {
+		log.Printf("Stamping the objects written with run id %s", args.runId)
+	}
+	if args.isolateRun {
+		log.Printf("Isolating the keys of the run under run id %s", args.runId)
+	}
+
 	if args.cpuprofile != "" {
 		f, err := os.Create(args.cpuprofile)
 		if err != nil {
@@ -571,19 +1742,96 @@ func main() {
 		defer pprof.StopCPUProfile()
 	}
 
+	if args.stages == nil {
+		prepareRun(&args)
+	}
+
 	var totalResults results
-	if args.concurrency != 0 {
-		_, totalResults = runtest(args)
+	collisionFailures := 0
+	benchFailures := 0
+	stageFailures := 0
+	var assertions []assertionOutcome
+	rampFailures := 0
+	phaseFailures := 0
+	if args.stages != nil {
+		for _, stage := range args.stages {
+			if !args.isJson {
+				fmt.Printf("\n\t--- Workload stage: %s ---\n", stage.Name)
+			}
+			startDiscovery(&stage.args)
+			prepareRun(&stage.args)
+			_, totalResults = runtest(stage.args)
+			stage.args.payload.stream.close()
+			if len(stage.Assert) > 0 {
+				outcomes := stage.checkAssertions(totalResults.CummulativeResult.StatusCodes)
+				assertions = append(assertions, outcomes...)
+				// the failed requests of a stage with assertions may be the expected outcome
+				stageFailures += assertionFailures(outcomes)
+			} else {
+				stageFailures += totalResults.CummulativeResult.Failcount
+			}
+			if totalResults.TimeBox.failed() {
+				stageFailures++
+			}
+			if wasInterrupted() {
+				break
+			}
+		}
+		if assertions != nil {
+			printAssertionReport(assertions, args.isJson)
+		}
+	} else if args.benchSuite != "" {
+		card := runBenchSuite(args, args.benchBaseline, func(phase parameters) results {
+			_, r := runtest(phase)
+			return r
+		})
+		printScorecard(card, args.isJson)
+		if args.benchOutput != "" {
+			if err := saveScorecard(args.benchOutput, card); err != nil {
+				log.Fatal(err)
+			}
+		}
+		benchFailures = card.failures()
+	} else if args.ramp != nil {
+		rampFailures = runRamp(args)
+	} else if args.concurrency != 0 {
+		totalResults, phaseFailures = runPhases(args, func(phase parameters) results {
+			_, r := runtest(phase)
+			return r
+		})
+		if args.collision {
+			report := verifyCollisions(args)
+			printCollisionReport(report, args.isJson)
+			collisionFailures = report.Mismatched + report.Failed
+		}
 	} else {
 		previous := 0.0
 		result := 0.0
-		for c := 8; c < 1024 && result >= previous; c = c + 8 {
+		for c := 8; c < 1024 && result >= previous && !wasInterrupted(); c = c + 8 {
 			previous = result
 			args.concurrency = c
 			result, _ = runtest(args)
 			fmt.Printf("Concurrency %d ===> %.1f requests/s\n", c, result)
 		}
 	}
+	args.payload.stream.close()
+	stateFailed := false
+	if args.stateDigest && !wasInterrupted() {
+		d, err := digestState(args)
+		if err != nil {
+			log.Printf("Failed digesting the objects under '%s/%s': %v", args.bucketname, args.objectprefix, err)
+		} else {
+			printStateDigest(d, args.isJson)
+		}
+		stateFailed = err != nil || d.Mismatch
+	}
+	cleanupFailed := !created.cleanup(args)
+	if err := audit.close(); err != nil {
+		log.Fatalf("Failed writing the audit log %s: %v", args.auditLog, err)
+	}
+	if err := detailedRequests.close(); err != nil {
+		log.Fatalf("Failed writing the detailed log %s: %v", args.detailedLog, err)
+	}
 
 	if args.logging {
 		f, err := os.Create(args.logdetail)
@@ -591,12 +1839,29 @@ func main() {
 			log.Fatal(err)
 		}
 		defer f.Close()
-		for _, v := range detailed {
-			fmt.Fprintf(f, "%f,%f\n", v.ts.Sub(detailed[0].ts).Seconds(), v.elapsed.Seconds())
+		writeDetailedLog(f, detailed, phases, args.logdetailPhase)
+	}
+
+	if args.versionFile != "" && (isWriteOperation(args.optype) || args.mix.writes()) {
+		f, err := os.Create(args.versionFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := writeVersionFile(f, writtenVersions); err != nil {
+			log.Fatal(err)
+		}
+		if args.versionsPerKey > 0 {
+			keys, incomplete := countVersions(writtenVersions, args.versionsPerKey)
+			log.Printf("Recorded %d versions of %d keys in %s, %d keys have fewer than %d versions", len(writtenVersions), keys, args.versionFile, incomplete, args.versionsPerKey)
 		}
 	}
 
-	if args.loglatency != "" {
+	if args.loglatency != "" && isHgrmFile(args.loglatency) {
+		if err := writeLatencyHistograms(args.loglatency, totalResults.CummulativeResult); err != nil {
+			log.Fatal(err)
+		}
+	} else if args.loglatency != "" {
 		f, err := os.Create(args.loglatency)
 		if err != nil {
 			log.Fatal(err)
@@ -609,37 +1874,182 @@ func main() {
 		}
 	}
 
-	if totalResults.CummulativeResult.Failcount > 0 {
+	if err := resultsDoc.finish(args.resultsFile); err != nil {
+		log.Printf("Failed writing the results document %s: %v", args.resultsFile, err)
+	}
+
+	artifacts.addFile(args.logdetail, "detailed log of the requests")
+	artifacts.addFile(args.loglatency, "latency histogram of the run")
+	if args.loglatency != "" && isHgrmFile(args.loglatency) {
+		for op := range totalResults.CummulativeResult.OperationResults {
+			artifacts.addFile(operationHgrmPath(args.loglatency, op), "latency histogram of the "+op+" requests")
+		}
+	}
+	artifacts.addFile(args.auditLog, "audit log of the requests in the S3 server access log format")
+	artifacts.addFile(args.detailedLog, "record of every request with its status and latency")
+	if isWriteOperation(args.optype) || args.mix.writes() {
+		artifacts.addFile(args.versionFile, "versions written by the run")
+	}
+	artifacts.addFile(args.benchOutput, "scorecard of the bench suite")
+	artifacts.addFile(args.resultsFile, "results document of every run")
+	artifacts.finish()
+
+	if totalResults.CummulativeResult.Failcount > 0 || totalResults.TimeBox.failed() || collisionFailures > 0 || benchFailures > 0 || stageFailures > 0 || rampFailures > 0 || phaseFailures > 0 || cleanupFailed || stateFailed {
 		os.Exit(1)
 	}
 }
 
-func MakeHTTPClient() *http.Client {
-	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+// startDiscovery looks up the endpoints of discover and keeps them up to date for the rest of the process.
+func startDiscovery(args *parameters) {
+	if args.discovery == nil {
+		return
+	}
+	if err := args.discovery.start(); err != nil {
+		log.Fatalf("Failed discovering the endpoints: %v", err)
+	}
+	// the preparations go to any node, the requests of the workers are sent to the endpoints discovered over time
+	args.endpoints = []string{args.discovery.endpoint(0)}
+}
+
+// prepareRun prepares the buckets and the payload source of a run before its workers start.
+func prepareRun(args *parameters) {
+	if args.listInventory {
+		if err := prepareInventory(args); err != nil {
+			log.Fatalf("Failed listing the inventory of '%s/%s': %v", args.bucketname, args.objectprefix, err)
+		}
+	}
+
+	if args.versionsPerKey > 0 {
+		if err := prepareVersions(*args); err != nil {
+			log.Fatalf("Failed enabling versioning on bucket '%s': %v", args.bucketname, err)
+		}
+	}
+
+	if args.overwriteVersioning != "" {
+		if err := prepareOverwriteVersioning(*args); err != nil {
+			log.Fatalf("Failed setting the versioning of bucket '%s' to %s: %v", args.bucketname, args.overwriteVersioning, err)
+		}
+	}
+
+	if args.numBuckets > 0 && (isWriteOperation(args.optype) || args.mix.writes() || args.phases[phasePrepare]) {
+		if err := prepareBuckets(*args); err != nil {
+			log.Fatalf("Failed creating the buckets of '%s': %v", args.bucketname, err)
+		}
+	}
+
+	if args.dataPipe != "" || args.dataCmd != "" {
+		var err error
+		if args.payload.stream, err = NewPayloadStream(args.dataPipe, args.dataCmd); err != nil {
+			log.Fatalf("Failed starting the payload source: %v", err)
+		}
+	}
+}
+
+// writeDetailedLog writes one "start,elapsed" line (in seconds) per request. Each phase of the run is
+// wrapped in "# phase-start,<label>,<time>" and "# phase-end,<label>,<time>" marker lines so graphs can
+// be segmented by workload stage, and with labels every line ends in the label of its phase as well.
+// Requests outside of every phase follow the phases.
+func writeDetailedLog(w io.Writer, details []detail, phases []phase, labels bool) {
+	if len(details) == 0 {
+		return
+	}
+	base := details[0].ts
+	// the requests of every phase in the order they were recorded, the last ones are outside of every phase
+	grouped := make([][]detail, len(phases)+1)
+	for _, v := range details {
+		// the phases follow each other, the first one that ends after the request started is the only candidate
+		i := sort.Search(len(phases), func(i int) bool { return v.ts.Before(phases[i].end) })
+		if i < len(phases) && v.ts.Before(phases[i].start) {
+			i = len(phases)
+		}
+		grouped[i] = append(grouped[i], v)
+	}
+	write := func(v detail, label string) {
+		if labels {
+			fmt.Fprintf(w, "%f,%f,%s\n", v.ts.Sub(base).Seconds(), v.elapsed.Seconds(), label)
+		} else {
+			fmt.Fprintf(w, "%f,%f\n", v.ts.Sub(base).Seconds(), v.elapsed.Seconds())
+		}
+	}
+	for i, p := range phases {
+		fmt.Fprintf(w, "# phase-start,%s,%f\n", p.label, p.start.Sub(base).Seconds())
+		for _, v := range grouped[i] {
+			write(v, p.label)
+		}
+		fmt.Fprintf(w, "# phase-end,%s,%f\n", p.label, p.end.Sub(base).Seconds())
+	}
+	for _, v := range grouped[len(phases)] {
+		write(v, "")
+	}
+}
 
+func MakeHTTPClient() *http.Client {
 	return &http.Client{
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   60 * time.Second,
-				KeepAlive: 180 * time.Second,
-				DualStack: true,
-			}).DialContext,
-			DisableCompression:  true, // Non-default
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 100, // Non-default
-			IdleConnTimeout:     90 * time.Second,
-			TLSClientConfig:     tlsConfig, // Non-default
-			TLSHandshakeTimeout: 60 * time.Second,
-		},
+		Transport: makeTransport(),
+	}
+}
+
+// caBundle holds the certificates of the CA bundle of AWS_CA_BUNDLE, loaded once, nil without one.
+var caBundle *x509.CertPool
+var caBundleOnce sync.Once
+
+// Returns the certificates of the CA bundle of AWS_CA_BUNDLE. A bundle that can't be loaded is left out here, the SDK
+// fails creating the sessions with it.
+func customCABundle() *x509.CertPool {
+	caBundleOnce.Do(func() {
+		path := os.Getenv("AWS_CA_BUNDLE")
+		if path == "" {
+			return
+		}
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return
+		}
+		if pool := x509.NewCertPool(); pool.AppendCertsFromPEM(pem) {
+			caBundle = pool
+		}
+	})
+	return caBundle
+}
+
+func makeTransport() *http.Transport {
+	// the transports of the workers are wrapped before the SDK sees them, so they get the CA bundle here
+	tlsConfig := &tls.Config{InsecureSkipVerify: true, RootCAs: customCABundle()}
+	if clientCert != nil {
+		// rotated certificates are used by the connections established after they were reloaded
+		tlsConfig.GetClientCertificate = clientCert.get
+	}
+
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   60 * time.Second,
+			KeepAlive: 180 * time.Second,
+			DualStack: true,
+		}).DialContext,
+		DisableCompression:  true, // Non-default
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100, // Non-default
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     tlsConfig, // Non-default
+		TLSHandshakeTimeout: 60 * time.Second,
 	}
 }
 
 func MakeS3Service(hclient *http.Client, retrySleep, retries int, endpoint, region, consistencyControl string, credentials *credentials.Credentials) *s3.S3 {
+	// the path of the endpoint is added to the requests once they are built
+	endpoint, prefix := splitEndpointPath(endpoint)
+	// The SDK loads the CA bundle of AWS_CA_BUNDLE into the transport of the client and rejects any transport but an
+	// *http.Transport, like the wrappers recording the DNS lookups or throttling the bandwidth of a worker. Their
+	// transport got the bundle from makeTransport, the session is created with a plain one and uses the client after.
+	sessionClient := hclient
+	if _, ok := hclient.Transport.(*http.Transport); !ok {
+		sessionClient = &http.Client{Transport: makeTransport()}
+	}
 	s3Config := aws.NewConfig().
 		WithRegion(region).
 		WithCredentials(credentials).
 		WithEndpoint(endpoint).
-		WithHTTPClient(hclient).
+		WithHTTPClient(sessionClient).
 		WithDisableComputeChecksums(true).
 		WithS3ForcePathStyle(true)
 	if retrySleep == 0 {
@@ -651,8 +2061,10 @@ func MakeS3Service(hclient *http.Client, retrySleep, retries int, endpoint, regi
 	if err != nil {
 		log.Fatal("Failed to create an S3 session", err)
 	}
+	s3Session.Config.HTTPClient = hclient
 
 	svc := s3.New(s3Session)
+	prefix.install(svc)
 
 	svc.Client.Handlers.Send.PushFront(func(r *request.Request) {
 		userAgent := userAgentString + r.HTTPRequest.UserAgent()
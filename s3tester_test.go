@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -14,10 +16,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -403,6 +407,173 @@ func TestMainWithGet(t *testing.T) {
 	}
 }
 
+func TestWriteDetailedLog(t *testing.T) {
+	start := time.Now()
+	details := []detail{
+		{ts: start, elapsed: time.Second},
+		{ts: start.Add(2 * time.Second), elapsed: time.Second},
+		{ts: start.Add(4 * time.Second), elapsed: time.Second},
+		// after the last phase
+		{ts: start.Add(6 * time.Second), elapsed: time.Second},
+	}
+	runPhases := []phase{
+		{label: "put-8", start: start, end: start.Add(3 * time.Second)},
+		{label: "put-16", start: start.Add(3 * time.Second), end: start.Add(5 * time.Second)},
+	}
+
+	var buf bytes.Buffer
+	writeDetailedLog(&buf, details, runPhases, false)
+
+	expected := "# phase-start,put-8,0.000000\n" +
+		"0.000000,1.000000\n" +
+		"2.000000,1.000000\n" +
+		"# phase-end,put-8,3.000000\n" +
+		"# phase-start,put-16,3.000000\n" +
+		"4.000000,1.000000\n" +
+		"# phase-end,put-16,5.000000\n" +
+		"6.000000,1.000000\n"
+
+	if buf.String() != expected {
+		t.Fatalf("Wrong detailed log. Expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	writeDetailedLog(&buf, details[:2], runPhases[:1], true)
+	expected = "# phase-start,put-8,0.000000\n" +
+		"0.000000,1.000000,put-8\n" +
+		"2.000000,1.000000,put-8\n" +
+		"# phase-end,put-8,3.000000\n"
+	if buf.String() != expected {
+		t.Fatalf("Wrong detailed log with phase labels. Expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+
+	if _, err := parse([]string{"-logdetail-phase"}); err == nil {
+		t.Fatalf("logdetail-phase without logdetail should fail")
+	}
+}
+
+func TestWriteLimitObjects(t *testing.T) {
+	l := NewWriteLimit(2, 0)
+
+	for i := 0; i < 2; i++ {
+		if !l.reserve(100) {
+			t.Fatalf("Write %d should be allowed", i)
+		}
+	}
+
+	if l.reserve(100) {
+		t.Fatalf("Write past max objects should not be allowed")
+	}
+}
+
+func TestWritesObject(t *testing.T) {
+	args := &parameters{presignedURLs: map[string]presignedURL{"up": {method: "PUT"}, "down": {method: "GET"}}}
+	for _, op := range []string{"put", "multipartput", "initmultipart", "copy", "copyacross", "pipeline"} {
+		if !writesObject(args, op, "up") {
+			t.Fatalf("%s should write objects", op)
+		}
+	}
+	for _, op := range []string{"get", "head", "delete", "presign"} {
+		if writesObject(args, op, "up") {
+			t.Fatalf("%s should not write objects", op)
+		}
+	}
+	if !writesObject(args, "presignedurl", "up") || writesObject(args, "presignedurl", "down") {
+		t.Fatalf("Only the presigned PUT URLs should write objects")
+	}
+	args.presignTransfer, args.presignMethod = true, "PUT"
+	if !writesObject(args, "presign", "up") {
+		t.Fatalf("Transfers with presigned PUT URLs should write objects")
+	}
+
+	mix, _ := parseMix("get:50,copy:50")
+	if !mix.writes() {
+		t.Fatalf("A mix with copies writes objects")
+	}
+}
+
+func TestWriteLimitBytes(t *testing.T) {
+	l := NewWriteLimit(0, 250)
+
+	if !l.reserve(100) || !l.reserve(100) {
+		t.Fatalf("Writes within max bytes should be allowed")
+	}
+
+	if l.reserve(100) {
+		t.Fatalf("Write past max bytes should not be allowed")
+	}
+
+	// a rejected write must not use up the remaining budget
+	if !l.reserve(50) {
+		t.Fatalf("Write that fits in the remaining bytes should be allowed")
+	}
+
+	// a write charged with more bytes than it sent gives the rest back
+	l.settle(50, 10)
+	if !l.reserve(40) || l.reserve(1) {
+		t.Fatalf("Expected the bytes of the write to be charged as sent")
+	}
+}
+
+func TestNoWriteLimit(t *testing.T) {
+	l := NewWriteLimit(0, 0)
+
+	if l != nil {
+		t.Fatalf("Write limit should be disabled")
+	}
+
+	if !l.reserve(math.MaxInt64) {
+		t.Fatalf("Writes should always be allowed without a limit")
+	}
+}
+
+func TestDispatchRecoversPanic(t *testing.T) {
+	svc := NewMockS3Client(func(in interface{}) interface{} {
+		panic("operation bug")
+	})
+	args := testArgs("head", "http://127.0.0.1:18082")
+	r := NewResult()
+
+	err := dispatchWithRecovery(svc, nil, "head", "object-0", &args, &r)
+	if err == nil || !strings.Contains(err.Error(), "operation bug") {
+		t.Fatalf("Expected the panic to be returned as an error but got: %v", err)
+	}
+
+	if r.Panics != 1 {
+		t.Fatalf("Expected 1 recovered panic but got %d", r.Panics)
+	}
+
+	merged := NewResult()
+	mergeResult(&merged, &r)
+	mergeResult(&merged, &r)
+	if merged.Panics != 2 {
+		t.Fatalf("Expected 2 merged panics but got %d", merged.Panics)
+	}
+}
+
+func TestReceiveS3OpRecoversPanic(t *testing.T) {
+	args := testArgs("head", "http://127.0.0.1:18082")
+	var wg sync.WaitGroup
+	workers := createChannels(1, &wg)
+	wg.Add(1)
+	workers[0].workChan <- s3op{Event: "head", Key: "object-0", Bucket: "test"}
+	workers[0].workChan <- s3op{Event: "head", Key: "object-1", Bucket: "test"}
+	closeAllWorkerChannels(workers)
+	r := NewResult()
+
+	// without a duration limit the worker panics after every request, outside of the operation
+	ReceiveS3Op(nil, nil, &args, nil, rate.NewLimiter(rate.Inf, 0), workers[0], &r)
+	wg.Wait()
+
+	if r.Count != 2 {
+		t.Fatalf("Expected the worker to carry on after a panic and send 2 requests but sent %d", r.Count)
+	}
+	// the operations on the missing service panic as well
+	if r.Panics != 4 {
+		t.Fatalf("Expected 4 recovered panics but got %d", r.Panics)
+	}
+}
+
 func TestSingleEndpointOverwrite1(t *testing.T) {
 	h := initS3TesterHelper(t, "put")
 	defer h.Shutdown()
@@ -544,8 +715,21 @@ func TestGetWhenLessDataReturnedThanContentLength(t *testing.T) {
 	h := S3TesterHelper{HttpHelper: &httpHelper, args: testArgs("get", httpHelper.Endpoint)}
 	defer h.Shutdown()
 	testResults := h.runTesterWithoutValidation(t)
-	if testResults.CummulativeResult.Failcount != 1 {
-		t.Fatalf("Test should have failed. %d failures.", testResults.CummulativeResult.Failcount)
+	// a truncated body is a soft failure, the response was received
+	if testResults.CummulativeResult.SoftFailures[softFailureTruncated] != 1 || testResults.CummulativeResult.Failcount != 0 {
+		t.Fatalf("Expected a truncated body but got %v soft failures and %d failures.", testResults.CummulativeResult.SoftFailures, testResults.CummulativeResult.Failcount)
+	}
+}
+
+func TestGetWhenDataDoesNotVerify(t *testing.T) {
+	httpHelper := NewHttpHelper(t, bodyString, nil)
+	h := S3TesterHelper{HttpHelper: &httpHelper, args: testArgs("get", httpHelper.Endpoint)}
+	h.args.verify = 1
+	defer h.Shutdown()
+	testResults := h.runTesterWithoutValidation(t)
+	// the whole body was read but isn't the data of the key, which still fails the request
+	if testResults.CummulativeResult.Failcount != 1 || len(testResults.CummulativeResult.SoftFailures) != 0 {
+		t.Fatalf("Test should have failed. %d failures, %v soft failures.", testResults.CummulativeResult.Failcount, testResults.CummulativeResult.SoftFailures)
 	}
 }
 
@@ -595,6 +779,144 @@ func TestGetWithVerification(t *testing.T) {
 	}
 }
 
+func TestParallelGet(t *testing.T) {
+	setValidAccessKeyEnv()
+	size := int64(4*objectDataBlockSize + 100)
+	data, _ := ioutil.ReadAll(NewDummyReader(size, "object-0"))
+
+	var mutex sync.Mutex
+	var ranges []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mutex.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	args := testArgs("parallelget", ts.URL)
+	args.rangeSize = objectDataBlockSize
+	args.rangeConcurrency = 3
+	args.verify = 1
+	_, testResults := runtest(args)
+
+	if testResults.CummulativeResult.Failcount > 0 {
+		t.Fatalf("Failed to run test. %d failures.", testResults.CummulativeResult.Failcount)
+	}
+
+	if testResults.CummulativeResult.sumObjSize != size {
+		t.Fatalf("sumObjSize is wrong size. Expected %d, but got %d", size, testResults.CummulativeResult.sumObjSize)
+	}
+
+	sort.Strings(ranges)
+	expected := []string{"bytes=0-4095", "bytes=12288-16383", "bytes=16384-20479", "bytes=4096-8191", "bytes=8192-12287"}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("Wrong ranges requested. Expected %v but got %v", expected, ranges)
+	}
+
+	if testResults.CummulativeResult.ObjectThroughput == nil || testResults.CummulativeResult.ObjectThroughput.Count != 1 {
+		t.Fatalf("Expected the throughput of 1 object but got %+v", testResults.CummulativeResult.ObjectThroughput)
+	}
+}
+
+func TestParallelGetWithThreshold(t *testing.T) {
+	setValidAccessKeyEnv()
+	size := int64(4*objectDataBlockSize + 100)
+	data, _ := ioutil.ReadAll(NewDummyReader(size, "object-0"))
+
+	var mutex sync.Mutex
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.Method+" "+r.Header.Get("Range"))
+		mutex.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	for threshold, expected := range map[int64][]string{
+		// objects up to the threshold are downloaded with a single GET
+		size: {"GET ", "HEAD "},
+		// larger ones with a ranged GET for every range, including the first
+		size - 1: {"GET bytes=0-4095", "GET bytes=12288-16383", "GET bytes=16384-20479", "GET bytes=4096-8191", "GET bytes=8192-12287", "HEAD "},
+	} {
+		requests = nil
+		args := testArgs("parallelget", ts.URL)
+		args.rangeSize = objectDataBlockSize
+		args.rangeThreshold = threshold
+		args.rangeConcurrency = 3
+		args.verify = 1
+		_, testResults := runtest(args)
+
+		if testResults.CummulativeResult.Failcount > 0 {
+			t.Fatalf("Failed to run test. %d failures.", testResults.CummulativeResult.Failcount)
+		}
+
+		if testResults.CummulativeResult.sumObjSize != size {
+			t.Fatalf("sumObjSize is wrong size. Expected %d, but got %d", size, testResults.CummulativeResult.sumObjSize)
+		}
+
+		sort.Strings(requests)
+		if !reflect.DeepEqual(requests, expected) {
+			t.Fatalf("Wrong requests with threshold %d. Expected %v but got %v", threshold, expected, requests)
+		}
+	}
+}
+
+func TestRangeGet(t *testing.T) {
+	setValidAccessKeyEnv()
+	data, _ := ioutil.ReadAll(NewDummyReader(1000, "object-0"))
+
+	var rangeHeader string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeader = r.Header.Get("Range")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+	defer ts.Close()
+
+	args := testArgs("rangeget", ts.URL)
+	args.osize = 1000
+	args.rangeDist = "fixed"
+	args.rangeLength = 100
+	args.verify = 1
+	_, testResults := runtest(args)
+
+	if testResults.CummulativeResult.Failcount > 0 {
+		t.Fatalf("Failed to run test. %d failures.", testResults.CummulativeResult.Failcount)
+	}
+
+	if rangeHeader != "bytes=0-99" {
+		t.Fatalf("Wrong range requested: %s", rangeHeader)
+	}
+
+	if testResults.CummulativeResult.sumObjSize != 100 {
+		t.Fatalf("sumObjSize is wrong size. Expected %d, but got %d", 100, testResults.CummulativeResult.sumObjSize)
+	}
+}
+
+func TestGetWithResponseOverrides(t *testing.T) {
+	httpHelper := NewHttpHelper(t, bodyString, map[string]string{"Content-Type": "text/plain"})
+	h := S3TesterHelper{HttpHelper: &httpHelper, args: testArgs("get", httpHelper.Endpoint)}
+	defer h.Shutdown()
+	h.args.responseOverrides, _ = parseResponseOverrides("content-type=text/plain")
+	h.runTester(t)
+
+	if h.Request(0).URL.Query().Get("response-content-type") != "text/plain" {
+		t.Fatalf("Get should have set response-content-type (actual: %s)", h.Request(0).URL.RawQuery)
+	}
+}
+
+func TestGetFailsWhenResponseOverrideIgnored(t *testing.T) {
+	h := initS3TesterHelper(t, "get")
+	defer h.Shutdown()
+	h.args.responseOverrides, _ = parseResponseOverrides("content-type=text/plain")
+	testResults := h.runTesterWithoutValidation(t)
+
+	if testResults.CummulativeResult.Failcount != 1 {
+		t.Fatalf("Test should have failed. %d failures.", testResults.CummulativeResult.Failcount)
+	}
+}
+
 func TestHead(t *testing.T) {
 	h := initS3TesterHelper(t, "head")
 	defer h.Shutdown()
@@ -672,6 +994,63 @@ func TestMultiplePuts(t *testing.T) {
 	}
 }
 
+func TestGetWithDurationCyclesKeys(t *testing.T) {
+	h := initS3TesterHelper(t, "get")
+	defer h.Shutdown()
+	h.args.nrequests = &intFlag{value: 3, set: true}
+	h.args.duration = &durationFlag{value: 200 * time.Millisecond, set: true}
+	testResults := h.runTester(t)
+
+	if h.Size() <= 3 {
+		t.Fatalf("Should go over the 3 keys more than once in the duration (%d)", h.Size())
+	}
+	for i := 0; i < h.Size(); i++ {
+		if h.Request(i).URL.Path != "/test/object-"+strconv.Itoa(i%3) {
+			t.Fatalf("Wrong url path of request %d: %s", i, h.Request(i).URL.Path)
+		}
+	}
+	if testResults.CummulativeResult.UniqObjNum != 3 {
+		t.Fatalf("uniqObjNum is %d. Expected 3.", testResults.CummulativeResult.UniqObjNum)
+	}
+	if testResults.CummulativeResult.Count != h.Size() {
+		t.Fatalf("Count is %d but %d requests were sent", testResults.CummulativeResult.Count, h.Size())
+	}
+}
+
+func TestDurationStopsWhenInterrupted(t *testing.T) {
+	limit := NewDurationSetting(&durationFlag{value: time.Hour, set: true}, time.Now())
+	if limit.enabled() {
+		t.Fatalf("The duration should not be over")
+	}
+	atomic.StoreInt32(&interrupted, 1)
+	defer atomic.StoreInt32(&interrupted, 0)
+	if !limit.enabled() || !NewDurationSetting(&durationFlag{}, time.Now()).enabled() {
+		t.Fatalf("An interrupted run should stop with and without a duration")
+	}
+}
+
+func TestMultiplePutsWithMaxObjects(t *testing.T) {
+	h := initS3TesterHelper(t, "put")
+	defer h.Shutdown()
+	nrequests := intFlag{value: 5, set: true}
+	h.args.nrequests = &nrequests
+	h.args.osize = 6
+	h.args.writeLimit = NewWriteLimit(3, 0)
+	testResults := h.runTester(t)
+
+	if h.Size() != 3 {
+		t.Fatalf("Should be 3 requests (%d)", h.Size())
+	}
+
+	if testResults.CummulativeResult.sumObjSize != 18 {
+		t.Fatalf("sumObjSize is wrong size. Expected 18, but got %d", testResults.CummulativeResult.sumObjSize)
+	}
+
+	if testResults.CummulativeResult.UniqObjNum != 3 {
+		t.Fatalf("uniqObjNum is %d. Expected 3.", testResults.CummulativeResult.UniqObjNum)
+	}
+}
+
 func TestMultiplePutsWithRepeat(t *testing.T) {
 	h := initS3TesterHelper(t, "put")
 	defer h.Shutdown()
@@ -758,6 +1137,73 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestMultiDelete(t *testing.T) {
+	h := initS3TesterHelper(t, "multidelete")
+	defer h.Shutdown()
+	h.args.nrequests.value = 5
+	h.args.batchSize = 2
+	testResults := h.runTester(t)
+
+	if h.NumRequests() != 3 {
+		t.Fatalf("Expected 3 requests for 5 keys in batches of 2 but got %d", h.NumRequests())
+	}
+
+	if _, ok := h.Request(0).URL.Query()["delete"]; h.Request(0).Method != "POST" || !ok {
+		t.Fatalf("Wrong request issued. Expected POST ?delete but got %s ?%s", h.Request(0).Method, h.Request(0).URL.RawQuery)
+	}
+
+	if !strings.Contains(h.Body(0), "<Key>object-0</Key>") || !strings.Contains(h.Body(0), "<Key>object-1</Key>") || strings.Contains(h.Body(0), "object-2") {
+		t.Fatalf("Wrong keys in first batch: %s", h.Body(0))
+	}
+
+	if testResults.CummulativeResult.KeyCount != 5 {
+		t.Fatalf("Expected 5 keys to be deleted but got %d", testResults.CummulativeResult.KeyCount)
+	}
+}
+
+func TestPresign(t *testing.T) {
+	h := initS3TesterHelper(t, "presign")
+	defer h.Shutdown()
+	h.args.nrequests.value = 10
+	testResults := h.runTesterWithoutValidation(t)
+
+	if !h.Empty() {
+		t.Fatalf("Presign should not send any requests but sent %d", h.NumRequests())
+	}
+
+	if testResults.CummulativeResult.Count != 10 || testResults.CummulativeResult.Failcount != 0 {
+		t.Fatalf("Expected 10 successful presigns but got %d with %d failures", testResults.CummulativeResult.Count, testResults.CummulativeResult.Failcount)
+	}
+
+	if testResults.CummulativeResult.PresignsPerSecPerCore <= 0 {
+		t.Fatalf("Expected presigns per second per core to be reported")
+	}
+}
+
+func TestPresignedURL(t *testing.T) {
+	svc := MakeS3Service(MakeHTTPClient(), 0, 0, "https://127.0.0.1:18082", "us-east-1", "", credentials.NewStaticCredentials("id", "secret", ""))
+
+	for _, method := range []string{"GET", "PUT"} {
+		url, err := Presign(svc, "test", "object-0", method, time.Hour, nil)
+		if err != nil {
+			t.Fatalf("Failed presigning %s: %v", method, err)
+		}
+
+		if !strings.HasPrefix(url, "https://127.0.0.1:18082/test/object-0?") || !strings.Contains(url, "X-Amz-Expires=3600") || !strings.Contains(url, "X-Amz-Signature=") {
+			t.Fatalf("Wrong presigned %s url: %s", method, url)
+		}
+	}
+
+	overrides, _ := parseResponseOverrides("content-type=text/plain&cache-control=no-cache")
+	url, err := Presign(svc, "test", "object-0", "GET", time.Hour, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(url, "response-content-type=text%2Fplain") || !strings.Contains(url, "response-cache-control=no-cache") {
+		t.Fatalf("Expected the response overrides in the presigned url: %s", url)
+	}
+}
+
 func TestPutTagging(t *testing.T) {
 	h := initS3TesterHelper(t, "puttagging")
 	defer h.Shutdown()
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// The formats of the objects queried by the select operation.
+var selectFormats = []string{"csv", "json", "parquet"}
+
+// Returns the serialization of the objects queried and of the records returned. CSV files have a header line,
+// JSON files hold one document per line and Parquet results are returned as CSV.
+func selectSerialization(format string) (*s3.InputSerialization, *s3.OutputSerialization) {
+	switch format {
+	case "json":
+		return &s3.InputSerialization{JSON: &s3.JSONInput{Type: aws.String(s3.JSONTypeLines)}},
+			&s3.OutputSerialization{JSON: &s3.JSONOutput{}}
+	case "parquet":
+		return &s3.InputSerialization{Parquet: &s3.ParquetInput{}}, &s3.OutputSerialization{CSV: &s3.CSVOutput{}}
+	}
+	return &s3.InputSerialization{CSV: &s3.CSVInput{FileHeaderInfo: aws.String(s3.FileHeaderInfoUse)}}, &s3.OutputSerialization{CSV: &s3.CSVOutput{}}
+}
+
+// selectResult holds the bytes the server scanned, processed and returned for the select requests, and the latency
+// of the event stream until the first records arrived.
+type selectResult struct {
+	BytesScanned   int64          `json:"bytesScanned"`
+	BytesProcessed int64          `json:"bytesProcessed"`
+	BytesReturned  int64          `json:"bytesReturned"`
+	FirstRecords   *latencyResult `json:"firstRecords,omitempty"`
+}
+
+// merge adds the other result to this one and returns the merged result, which is newly allocated if this one is nil.
+func (this *selectResult) merge(other *selectResult) *selectResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = &selectResult{}
+	}
+	this.BytesScanned += other.BytesScanned
+	this.BytesProcessed += other.BytesProcessed
+	this.BytesReturned += other.BytesReturned
+	this.FirstRecords = this.FirstRecords.merge(other.FirstRecords)
+	return this
+}
+
+// SelectObject runs the SQL expression against the object and reads the records returned. The bytes scanned,
+// processed and returned are added to the result, along with the time until the first records arrived.
+// Returns the number of bytes returned.
+func SelectObject(svc s3iface.S3API, bucket, key, expression, format string, r *selectResult) (int64, error) {
+	input, output := selectSerialization(format)
+	params := &s3.SelectObjectContentInput{
+		Bucket:              aws.String(bucket),
+		Key:                 aws.String(key),
+		Expression:          aws.String(expression),
+		ExpressionType:      aws.String(s3.ExpressionTypeSql),
+		InputSerialization:  input,
+		OutputSerialization: output,
+	}
+	start := time.Now()
+	out, err := svc.SelectObjectContent(params)
+	if err != nil {
+		return 0, err
+	}
+	defer out.EventStream.Close()
+
+	returned, err := readSelectEvents(out.EventStream.Events(), start, r)
+	if err != nil {
+		return returned, fmt.Errorf("select of %s: %v", key, err)
+	}
+	return returned, out.EventStream.Err()
+}
+
+// readSelectEvents reads the events of a select request that started at the given time until the stream is closed
+// and returns the number of bytes of records. A stream that is closed without an End event did not return all records.
+func readSelectEvents(events <-chan s3.SelectObjectContentEventStreamEvent, start time.Time, r *selectResult) (int64, error) {
+	var returned int64
+	first := true
+	ended := false
+	for event := range events {
+		switch e := event.(type) {
+		case *s3.RecordsEvent:
+			if first {
+				if r.FirstRecords == nil {
+					r.FirstRecords = NewLatencyResult()
+				}
+				r.FirstRecords.record(time.Since(start))
+				first = false
+			}
+			returned += int64(len(e.Payload))
+		case *s3.StatsEvent:
+			r.BytesScanned += aws.Int64Value(e.Details.BytesScanned)
+			r.BytesProcessed += aws.Int64Value(e.Details.BytesProcessed)
+		case *s3.EndEvent:
+			ended = true
+		}
+	}
+	r.BytesReturned += returned
+	if !ended {
+		return returned, errors.New("the event stream ended without an End event")
+	}
+	return returned, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestSelectSerialization(t *testing.T) {
+	in, out := selectSerialization("csv")
+	if aws.StringValue(in.CSV.FileHeaderInfo) != s3.FileHeaderInfoUse || out.CSV == nil {
+		t.Fatalf("Wrong csv serialization: %+v %+v", in, out)
+	}
+
+	in, out = selectSerialization("json")
+	if aws.StringValue(in.JSON.Type) != s3.JSONTypeLines || out.JSON == nil {
+		t.Fatalf("Wrong json serialization: %+v %+v", in, out)
+	}
+
+	in, out = selectSerialization("parquet")
+	if in.Parquet == nil || out.CSV == nil {
+		t.Fatalf("Wrong parquet serialization: %+v %+v", in, out)
+	}
+}
+
+func selectEvents(events ...s3.SelectObjectContentEventStreamEvent) <-chan s3.SelectObjectContentEventStreamEvent {
+	c := make(chan s3.SelectObjectContentEventStreamEvent, len(events))
+	for _, e := range events {
+		c <- e
+	}
+	close(c)
+	return c
+}
+
+func TestReadSelectEvents(t *testing.T) {
+	r := &selectResult{}
+	stats := &s3.StatsEvent{Details: &s3.Stats{BytesScanned: aws.Int64(1000), BytesProcessed: aws.Int64(900), BytesReturned: aws.Int64(15)}}
+	events := selectEvents(&s3.RecordsEvent{Payload: []byte("a,b\n")}, &s3.ContinuationEvent{}, &s3.RecordsEvent{Payload: []byte("c,d,e,f,g\n")}, stats, &s3.EndEvent{})
+
+	returned, err := readSelectEvents(events, time.Now(), r)
+	if err != nil || returned != 14 {
+		t.Fatalf("Expected 14 bytes returned but got %d (%v)", returned, err)
+	}
+
+	if r.BytesScanned != 1000 || r.BytesProcessed != 900 || r.BytesReturned != 14 || r.FirstRecords.Count != 1 {
+		t.Fatalf("Wrong select result: %+v", r)
+	}
+
+	// a stream that breaks off has no End event
+	if _, err := readSelectEvents(selectEvents(&s3.RecordsEvent{Payload: []byte("a,b\n")}), time.Now(), r); err == nil {
+		t.Fatalf("Expected an error without an End event")
+	}
+}
+
+func TestMergeSelectResult(t *testing.T) {
+	var merged *selectResult
+	first := &selectResult{BytesScanned: 10, BytesReturned: 1, FirstRecords: NewLatencyResult()}
+	first.FirstRecords.record(time.Millisecond)
+	merged = merged.merge(first)
+	merged = merged.merge(nil)
+	merged = merged.merge(&selectResult{BytesScanned: 20, BytesProcessed: 5, BytesReturned: 2})
+
+	if merged.BytesScanned != 30 || merged.BytesProcessed != 5 || merged.BytesReturned != 3 || merged.FirstRecords.Count != 1 {
+		t.Fatalf("Wrong merged select result: %+v", merged)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import "math/rand"
+
+// Returns the order in which a worker visits its n keys, a pseudo-random permutation of 0..n-1 that only depends on the
+// seed and the worker. Runs with the same seed, concurrency and requests access the keys in the same order.
+func keyOrder(seed int64, worker int, n int64) []int64 {
+	order := make([]int64, n)
+	for i := range order {
+		order[i] = int64(i)
+	}
+	r := rand.New(rand.NewSource(seed + int64(worker)))
+	r.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	return order
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestKeyOrder(t *testing.T) {
+	order := keyOrder(42, 3, 100)
+	if !reflect.DeepEqual(order, keyOrder(42, 3, 100)) {
+		t.Fatalf("Expected the same order for the same seed and worker")
+	}
+
+	if reflect.DeepEqual(order, keyOrder(43, 3, 100)) || reflect.DeepEqual(order, keyOrder(42, 4, 100)) {
+		t.Fatalf("Expected other seeds and workers to have other orders")
+	}
+
+	sorted := append([]int64(nil), order...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i, index := range sorted {
+		if index != int64(i) {
+			t.Fatalf("Expected a permutation of the keys but got %v", order)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Kinds of soft failures, downloads whose body doesn't match what the response headers promised.
+const (
+	softFailureTruncated = "truncated"
+	softFailureETag      = "etagMismatch"
+	softFailureChecksum  = "checksumMismatch"
+)
+
+// softFailure is an error of a request whose response was received but whose body doesn't match its headers, e.g. a
+// body that ends before Content-Length bytes were read. Silent truncation and corruption are counted separately from
+// failed requests.
+type softFailure struct {
+	kind string
+	err  error
+}
+
+func (e *softFailure) Error() string {
+	return e.err.Error()
+}
+
+// bodyReader counts the bytes of a download and computes their MD5 when the ETag is verified.
+type bodyReader struct {
+	io.Reader
+	read int64
+	// the body was read until it ended, cleanly or not
+	ended bool
+	md5   hash.Hash
+}
+
+func newBodyReader(body io.Reader, verifyETag bool) *bodyReader {
+	r := &bodyReader{Reader: body}
+	if verifyETag {
+		r.md5 = md5.New()
+	}
+	return r
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.read += int64(n)
+	if r.md5 != nil {
+		r.md5.Write(p[:n])
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		r.ended = true
+	}
+	return n, err
+}
+
+// Returns whether the ETag of the response is the MD5 of the data read. It isn't for ranges, multipart uploads and
+// objects encrypted with SSE-KMS or SSE-C.
+func md5ETag(etag string, header http.Header) bool {
+	if len(etag) != 2*md5.Size || header.Get("Content-Range") != "" || header.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" {
+		return false
+	}
+	if strings.HasPrefix(header.Get("X-Amz-Server-Side-Encryption"), s3.ServerSideEncryptionAwsKms) {
+		return false
+	}
+	_, err := hex.DecodeString(etag)
+	return err == nil
+}
+
+// check returns a soft failure if the body that was read doesn't match the response headers, which replaces the error
+// of reading a truncated body. Otherwise it returns the error of reading the body.
+func (r *bodyReader) check(key string, contentLength int64, header http.Header, err error) error {
+	if r.ended && contentLength >= 0 && r.read < contentLength {
+		return &softFailure{softFailureTruncated, fmt.Errorf("body of %s truncated: read %d of %d bytes", key, r.read, contentLength)}
+	}
+	if err != nil || r.md5 == nil || !r.ended {
+		return err
+	}
+	etag := strings.Trim(header.Get("ETag"), `"`)
+	if !md5ETag(etag, header) {
+		return nil
+	}
+	if actual := hex.EncodeToString(r.md5.Sum(nil)); actual != strings.ToLower(etag) {
+		return &softFailure{softFailureETag, fmt.Errorf("ETag mismatch of %s: expected %s but read %s", key, etag, actual)}
+	}
+	return nil
+}
+
+func (this *result) recordSoftFailure(kind string) {
+	if this.SoftFailures == nil {
+		this.SoftFailures = make(map[string]int)
+	}
+	this.SoftFailures[kind]++
+}
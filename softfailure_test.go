@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// reads the body like a download, returning the error of reading it
+func readBody(r *bodyReader) error {
+	_, err := io.Copy(ioutil.Discard, r)
+	return err
+}
+
+// failingReader fails every read with the error
+type failingReader struct {
+	err error
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestBodyReaderTruncated(t *testing.T) {
+	r := newBodyReader(io.MultiReader(strings.NewReader("hel"), failingReader{io.ErrUnexpectedEOF}), false)
+	err := r.check("k1", 5, http.Header{}, readBody(r))
+	if soft, ok := err.(*softFailure); !ok || soft.kind != softFailureTruncated {
+		t.Fatalf("Expected a truncated body but got: %v", err)
+	}
+
+	r = newBodyReader(strings.NewReader("hello"), false)
+	if err := r.check("k1", 5, http.Header{}, readBody(r)); err != nil {
+		t.Fatalf("Expected a complete body but got: %v", err)
+	}
+
+	// bodies of unknown length can't be truncated
+	r = newBodyReader(strings.NewReader("hel"), false)
+	if err := r.check("k1", -1, http.Header{}, readBody(r)); err != nil {
+		t.Fatalf("Expected a body of unknown length to be accepted but got: %v", err)
+	}
+
+	// other errors are kept
+	other := errors.New("connection reset")
+	r = newBodyReader(strings.NewReader("hello"), false)
+	if err := r.check("k1", 5, http.Header{}, other); err != other {
+		t.Fatalf("Expected the error of reading the body but got: %v", err)
+	}
+}
+
+func TestBodyReaderETag(t *testing.T) {
+	read := func(header http.Header) error {
+		r := newBodyReader(strings.NewReader("hello"), true)
+		return r.check("k1", 5, header, readBody(r))
+	}
+
+	if err := read(http.Header{"Etag": []string{`"5d41402abc4b2a76b9719d911017c592"`}}); err != nil {
+		t.Fatalf("Expected the ETag to match but got: %v", err)
+	}
+	err := read(http.Header{"Etag": []string{`"00000000000000000000000000000000"`}})
+	if soft, ok := err.(*softFailure); !ok || soft.kind != softFailureETag {
+		t.Fatalf("Expected an ETag mismatch but got: %v", err)
+	}
+	// ETags that aren't the MD5 of the data read are not verified
+	for _, header := range []http.Header{
+		{"Etag": []string{`"00000000000000000000000000000000-3"`}},
+		{"Etag": []string{`"00000000000000000000000000000000"`}, "Content-Range": []string{"bytes 0-4/10"}},
+		{"Etag": []string{`"00000000000000000000000000000000"`}, "X-Amz-Server-Side-Encryption": []string{"aws:kms"}},
+		{"Etag": []string{`"00000000000000000000000000000000"`}, "X-Amz-Server-Side-Encryption-Customer-Algorithm": []string{"AES256"}},
+	} {
+		if err := read(header); err != nil {
+			t.Fatalf("Expected %v to be skipped but got: %v", header, err)
+		}
+	}
+}
+
+func TestMergeSoftFailures(t *testing.T) {
+	r1, r2 := NewResult(), NewResult()
+	r1.recordSoftFailure(softFailureTruncated)
+	r2.recordSoftFailure(softFailureTruncated)
+	r2.recordSoftFailure(softFailureETag)
+
+	merged := NewResult()
+	mergeResult(&merged, &r1)
+	mergeResult(&merged, &r2)
+	if merged.SoftFailures[softFailureTruncated] != 2 || merged.SoftFailures[softFailureETag] != 1 || merged.Failcount != 0 {
+		t.Fatalf("Wrong soft failures %v", merged.SoftFailures)
+	}
+}
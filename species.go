@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// objectSpecies is a named kind of object of a workload, like thumbnails, documents or videos, with its own size,
+// content type and generated data.
+type objectSpecies struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// sent as the Content-Type of the objects written, none if empty
+	ContentType string `json:"contentType"`
+	// compressibility of the generated data like compress-ratio, 0 repeats the key
+	CompressRatio float64 `json:"compressRatio"`
+	// relative share of the keys
+	Share int `json:"share"`
+}
+
+// speciesMix spreads the keys of a run over the species by their shares.
+type speciesMix struct {
+	species []objectSpecies
+	total   int
+}
+
+// loadSpecies loads the species of a JSON file holding an array of species.
+func loadSpecies(path string) (*speciesMix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSpecies(f)
+}
+
+func readSpecies(r io.Reader) (*speciesMix, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	mix := &speciesMix{}
+	if err := decoder.Decode(&mix.species); err != nil {
+		return nil, fmt.Errorf("Error parsing species file: %s", err)
+	}
+	if len(mix.species) == 0 {
+		return nil, errors.New("The species file has no species")
+	}
+	names := make(map[string]bool)
+	for _, s := range mix.species {
+		if s.Name == "" || names[s.Name] {
+			return nil, fmt.Errorf("Every species needs a unique name: %q", s.Name)
+		}
+		names[s.Name] = true
+		if s.Size < 0 {
+			return nil, fmt.Errorf("Species %s: size must be >= 0", s.Name)
+		}
+		if s.CompressRatio != 0 && s.CompressRatio < 1 {
+			return nil, fmt.Errorf("Species %s: compressRatio must be >= 1", s.Name)
+		}
+		if s.Share < 1 {
+			return nil, fmt.Errorf("Species %s: share must be an integer >= 1", s.Name)
+		}
+		mix.total += s.Share
+	}
+	return mix, nil
+}
+
+// of returns the species of a key. The species is picked by the hash of the key, so that runs reading the objects
+// written by another run find the same species.
+func (m *speciesMix) of(key string) *objectSpecies {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	n := int(h.Sum32() % uint32(m.total))
+	for i := range m.species {
+		if n < m.species[i].Share {
+			return &m.species[i]
+		}
+		n -= m.species[i].Share
+	}
+	return &m.species[len(m.species)-1]
+}
+
+// largest returns the size of the largest species.
+func (m *speciesMix) largest() int64 {
+	var size int64
+	for _, s := range m.species {
+		if s.Size > size {
+			size = s.Size
+		}
+	}
+	return size
+}
+
+// apply sets the content type of the species of the key on the requests that write objects.
+func (m *speciesMix) apply(params interface{}) {
+	switch p := params.(type) {
+	case *s3.PutObjectInput:
+		if contentType := m.of(aws.StringValue(p.Key)).ContentType; contentType != "" {
+			p.ContentType = aws.String(contentType)
+		}
+	case *s3.CreateMultipartUploadInput:
+		if contentType := m.of(aws.StringValue(p.Key)).ContentType; contentType != "" {
+			p.ContentType = aws.String(contentType)
+		}
+	}
+}
+
+// install applies the content types of the species to every object written by the service. Does nothing without
+// species.
+func (m *speciesMix) install(svc *s3.S3) {
+	if m == nil {
+		return
+	}
+	svc.Client.Handlers.Validate.PushFront(func(r *request.Request) {
+		m.apply(r.Params)
+	})
+}
+
+func (this *result) recordSpecies(name string, l time.Duration, bytes int64, failed bool) {
+	if this.SpeciesResults == nil {
+		this.SpeciesResults = make(map[string]*operationResult)
+	}
+	s, ok := this.SpeciesResults[name]
+	if !ok {
+		s = &operationResult{latencyResult: NewLatencyResult()}
+		this.SpeciesResults[name] = s
+	}
+	s.record(l)
+	s.Bytes += bytes
+	if failed {
+		s.Failcount++
+	}
+}
+
+func (this *result) mergeSpeciesResults(other *result) {
+	for name, o := range other.SpeciesResults {
+		if this.SpeciesResults == nil {
+			this.SpeciesResults = make(map[string]*operationResult)
+		}
+		s, ok := this.SpeciesResults[name]
+		if !ok {
+			s = &operationResult{}
+			this.SpeciesResults[name] = s
+		}
+		s.latencyResult = s.latencyResult.merge(o.latencyResult)
+		s.Failcount += o.Failcount
+		s.Bytes += o.Bytes
+	}
+}
+
+func printSpeciesResults(speciesResults map[string]*operationResult, count int) {
+	names := make([]string, 0, len(speciesResults))
+	for name := range speciesResults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := speciesResults[name]
+		fmt.Printf("Species: %s (%.1f%% of requests)\n", name, 100*float64(s.Count)/float64(count))
+		fmt.Printf("Failed requests: %d\n", s.Failcount)
+		fmt.Printf("Total bytes: %d\n", s.Bytes)
+		printLatencyResult(s.latencyResult)
+	}
+}
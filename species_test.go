@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const testSpecies = `[
+	{"name": "thumb", "size": 16384, "contentType": "image/png", "compressRatio": 1, "share": 60},
+	{"name": "doc", "size": 1048576, "contentType": "application/pdf", "compressRatio": 2, "share": 30},
+	{"name": "video", "size": 2147483648, "share": 10}
+]`
+
+func TestReadSpecies(t *testing.T) {
+	mix, err := readSpecies(strings.NewReader(testSpecies))
+	if err != nil {
+		t.Fatalf("valid species should succeed: %v", err)
+	}
+	if len(mix.species) != 3 || mix.total != 100 || mix.largest() != 2147483648 {
+		t.Fatalf("Wrong species: %v", mix)
+	}
+
+	for _, invalid := range []string{
+		"", "[]", `{"name": "a"}`,
+		`[{"name": "a", "size": 1, "share": 1}, {"name": "a", "size": 2, "share": 1}]`,
+		`[{"size": 1, "share": 1}]`,
+		`[{"name": "a", "size": -1, "share": 1}]`,
+		`[{"name": "a", "size": 1, "share": 0}]`,
+		`[{"name": "a", "size": 1, "share": 1, "compressRatio": 0.5}]`,
+		`[{"name": "a", "size": 1, "share": 1, "generator": "png"}]`,
+	} {
+		if _, err := readSpecies(strings.NewReader(invalid)); err == nil {
+			t.Fatalf("species %q should be invalid", invalid)
+		}
+	}
+}
+
+func TestSpeciesOf(t *testing.T) {
+	mix, _ := readSpecies(strings.NewReader(testSpecies))
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		key := "object-" + strconv.Itoa(i)
+		s := mix.of(key)
+		if mix.of(key) != s {
+			t.Fatalf("The species of a key should not change")
+		}
+		counts[s.Name]++
+	}
+	if counts["thumb"] < 5700 || counts["thumb"] > 6300 || counts["doc"] < 2700 || counts["doc"] > 3300 || counts["video"] < 800 || counts["video"] > 1200 {
+		t.Fatalf("Keys should be spread by the shares of the species: %v", counts)
+	}
+}
+
+func TestSpeciesContentType(t *testing.T) {
+	mix, _ := readSpecies(strings.NewReader(testSpecies))
+	var thumb, video string
+	for i := 0; thumb == "" || video == ""; i++ {
+		key := "object-" + strconv.Itoa(i)
+		switch mix.of(key).Name {
+		case "thumb":
+			thumb = key
+		case "video":
+			video = key
+		}
+	}
+
+	put := &s3.PutObjectInput{Key: aws.String(thumb)}
+	mix.apply(put)
+	mpu := &s3.CreateMultipartUploadInput{Key: aws.String(video)}
+	mix.apply(mpu)
+	if aws.StringValue(put.ContentType) != "image/png" || mpu.ContentType != nil {
+		t.Fatalf("Wrong content types: %v %v", put.ContentType, mpu.ContentType)
+	}
+}
+
+func TestSpeciesResults(t *testing.T) {
+	a := NewResult()
+	a.recordSpecies("thumb", 10*time.Millisecond, 100, false)
+	a.recordSpecies("doc", 30*time.Millisecond, 1000, true)
+	b := NewResult()
+	b.recordSpecies("thumb", 30*time.Millisecond, 100, false)
+	a.mergeSpeciesResults(&b)
+	for _, s := range a.SpeciesResults {
+		s.setupStats()
+	}
+
+	thumb, doc := a.SpeciesResults["thumb"], a.SpeciesResults["doc"]
+	if thumb.Count != 2 || thumb.Bytes != 200 || thumb.Failcount != 0 || thumb.AverageRequestTime != 20 {
+		t.Fatalf("Wrong thumb result: %v", thumb)
+	}
+	if doc.Count != 1 || doc.Bytes != 1000 || doc.Failcount != 1 {
+		t.Fatalf("Wrong doc result: %v", doc)
+	}
+}
+
+func TestSpeciesOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "species")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "species.json")
+	ioutil.WriteFile(file, []byte(testSpecies), 0644)
+
+	args, err := parse([]string{"-operation=put", "-mpu-threshold=104857600", "-species=" + file})
+	if err != nil {
+		t.Fatalf("species should succeed: %v", err)
+	}
+	if args.species == nil || args.osize != 2147483648 || args.partsize != autoPartSize(2147483648) {
+		t.Fatalf("The part size should upload the largest species: %d %d", args.osize, args.partsize)
+	}
+
+	for _, invalid := range [][]string{
+		{"-operation=put", "-size=100", "-species=" + file},
+		{"-operation=put", "-compress-ratio=2", "-species=" + file},
+		{"-operation=get", "-keys-from-file=" + file, "-species=" + file},
+		{"-operation=put", "-species=" + filepath.Join(dir, "missing")},
+	} {
+		if _, err := parse(invalid); err == nil {
+			t.Fatalf("%v should be invalid", invalid)
+		}
+	}
+}
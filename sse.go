@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// encryption holds the server side encryption of the objects written and read.
+type encryption struct {
+	// AES256 (SSE-S3) or aws:kms (SSE-KMS) for the objects written, empty with SSE-C
+	sse      string
+	kmsKeyId string
+	// the raw 256 bit key of SSE-C, the SDK encodes it and adds its MD5
+	customerKey string
+}
+
+// Parses the encryption settings. sse is s3 or AES256 for SSE-S3, kms or aws:kms for SSE-KMS, where a KMS key id
+// implies SSE-KMS. The SSE-C key is base64 encoded. Returns nil if nothing is set.
+func parseEncryption(sse, kmsKeyId, customerKey string) (*encryption, error) {
+	if sse == "" && kmsKeyId == "" && customerKey == "" {
+		return nil, nil
+	}
+	e := &encryption{kmsKeyId: kmsKeyId}
+	switch strings.ToLower(sse) {
+	case "":
+		if kmsKeyId != "" {
+			e.sse = s3.ServerSideEncryptionAwsKms
+		}
+	case "s3", "aes256":
+		e.sse = s3.ServerSideEncryptionAes256
+	case "kms", "aws:kms":
+		e.sse = s3.ServerSideEncryptionAwsKms
+	default:
+		return nil, errors.New("sse must be one of s3 (AES256) or kms (aws:kms)")
+	}
+	if kmsKeyId != "" && e.sse != s3.ServerSideEncryptionAwsKms {
+		return nil, errors.New("sse-kms-key-id requires sse=kms")
+	}
+	if customerKey != "" {
+		if e.sse != "" {
+			return nil, errors.New("sse-c-key cannot be combined with sse or sse-kms-key-id")
+		}
+		key, err := base64.StdEncoding.DecodeString(customerKey)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("sse-c-key must be a base64 encoded 256 bit key")
+		}
+		e.customerKey = string(key)
+	}
+	return e, nil
+}
+
+// apply sets the encryption headers of the requests. SSE-S3 and SSE-KMS are set when objects are written,
+// the SSE-C key is sent with every request that reads or writes object data, including the source of copies.
+func (e *encryption) apply(params interface{}) {
+	var sse, kmsKeyId, algorithm, key, copyAlgorithm, copyKey **string
+	switch p := params.(type) {
+	case *s3.PutObjectInput:
+		sse, kmsKeyId, algorithm, key = &p.ServerSideEncryption, &p.SSEKMSKeyId, &p.SSECustomerAlgorithm, &p.SSECustomerKey
+	case *s3.CreateMultipartUploadInput:
+		sse, kmsKeyId, algorithm, key = &p.ServerSideEncryption, &p.SSEKMSKeyId, &p.SSECustomerAlgorithm, &p.SSECustomerKey
+	case *s3.CopyObjectInput:
+		sse, kmsKeyId, algorithm, key = &p.ServerSideEncryption, &p.SSEKMSKeyId, &p.SSECustomerAlgorithm, &p.SSECustomerKey
+		copyAlgorithm, copyKey = &p.CopySourceSSECustomerAlgorithm, &p.CopySourceSSECustomerKey
+	case *s3.UploadPartInput:
+		algorithm, key = &p.SSECustomerAlgorithm, &p.SSECustomerKey
+	case *s3.UploadPartCopyInput:
+		algorithm, key = &p.SSECustomerAlgorithm, &p.SSECustomerKey
+		copyAlgorithm, copyKey = &p.CopySourceSSECustomerAlgorithm, &p.CopySourceSSECustomerKey
+	case *s3.GetObjectInput:
+		algorithm, key = &p.SSECustomerAlgorithm, &p.SSECustomerKey
+	case *s3.HeadObjectInput:
+		algorithm, key = &p.SSECustomerAlgorithm, &p.SSECustomerKey
+	default:
+		return
+	}
+	if e.sse != "" && sse != nil {
+		*sse = aws.String(e.sse)
+		if e.kmsKeyId != "" {
+			*kmsKeyId = aws.String(e.kmsKeyId)
+		}
+	}
+	if e.customerKey != "" {
+		*algorithm, *key = aws.String(s3.ServerSideEncryptionAes256), aws.String(e.customerKey)
+		if copyKey != nil {
+			*copyAlgorithm, *copyKey = aws.String(s3.ServerSideEncryptionAes256), aws.String(e.customerKey)
+		}
+	}
+}
+
+// etagIsMD5 returns whether the ETags of the objects written with the encryption are the MD5 of their data, which
+// isn't the case with SSE-KMS and SSE-C.
+func (e *encryption) etagIsMD5() bool {
+	return e == nil || e.sse != s3.ServerSideEncryptionAwsKms && e.customerKey == ""
+}
+
+// install applies the encryption to every request sent by the service.
+func (e *encryption) install(svc *s3.S3) {
+	if e == nil {
+		return
+	}
+	svc.Client.Handlers.Validate.PushFront(func(r *request.Request) {
+		e.apply(r.Params)
+	})
+}
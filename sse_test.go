@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var testCustomerKey = strings.Repeat("k", 32)
+
+func TestParseEncryption(t *testing.T) {
+	if e, err := parseEncryption("", "", ""); e != nil || err != nil {
+		t.Fatalf("Expected no encryption but got %+v, %v", e, err)
+	}
+
+	settings := map[[2]string]string{{"s3", ""}: "AES256", {"AES256", ""}: "AES256", {"kms", ""}: "aws:kms", {"", "key-1"}: "aws:kms"}
+	for s, expected := range settings {
+		e, err := parseEncryption(s[0], s[1], "")
+		if err != nil || e.sse != expected || e.kmsKeyId != s[1] {
+			t.Fatalf("Expected %s for sse=%s sse-kms-key-id=%s but got %+v, %v", expected, s[0], s[1], e, err)
+		}
+	}
+
+	e, err := parseEncryption("", "", base64.StdEncoding.EncodeToString([]byte(testCustomerKey)))
+	if err != nil || e.customerKey != testCustomerKey || e.sse != "" {
+		t.Fatalf("Expected SSE-C but got %+v, %v", e, err)
+	}
+
+	invalid := [][3]string{{"des", "", ""}, {"s3", "key-1", ""}, {"s3", "", base64.StdEncoding.EncodeToString([]byte(testCustomerKey))}, {"", "", "short"}, {"", "", testCustomerKey}}
+	for _, s := range invalid {
+		if _, err := parseEncryption(s[0], s[1], s[2]); err == nil {
+			t.Fatalf("Expected invalid encryption %v to fail", s)
+		}
+	}
+}
+
+func TestApplyEncryption(t *testing.T) {
+	kms := &encryption{sse: s3.ServerSideEncryptionAwsKms, kmsKeyId: "key-1"}
+	put := &s3.PutObjectInput{}
+	kms.apply(put)
+	if aws.StringValue(put.ServerSideEncryption) != "aws:kms" || aws.StringValue(put.SSEKMSKeyId) != "key-1" {
+		t.Fatalf("Expected SSE-KMS with key-1 but got %s %s", aws.StringValue(put.ServerSideEncryption), aws.StringValue(put.SSEKMSKeyId))
+	}
+
+	// reads of SSE-S3 and SSE-KMS objects need no headers
+	get := &s3.GetObjectInput{}
+	kms.apply(get)
+	if get.SSECustomerKey != nil {
+		t.Fatalf("Expected no SSE-C key on GET")
+	}
+
+	customer := &encryption{customerKey: testCustomerKey}
+	head := &s3.HeadObjectInput{}
+	customer.apply(head)
+	if aws.StringValue(head.SSECustomerAlgorithm) != "AES256" || aws.StringValue(head.SSECustomerKey) != testCustomerKey {
+		t.Fatalf("Expected SSE-C key on HEAD")
+	}
+
+	copyPart := &s3.UploadPartCopyInput{}
+	customer.apply(copyPart)
+	if aws.StringValue(copyPart.SSECustomerKey) != testCustomerKey || aws.StringValue(copyPart.CopySourceSSECustomerKey) != testCustomerKey {
+		t.Fatalf("Expected SSE-C key for the copy and its source")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// workloadStage is a stage of a staged workload file. The stages run one after the other, each with the settings of
+// the command line overridden by its own.
+type workloadStage struct {
+	Name        string `json:"name"`
+	Operation   string `json:"operation"`
+	Mix         string `json:"mix"`
+	Concurrency int    `json:"concurrency"`
+	Requests    int    `json:"requests"`
+	// seconds
+	Duration int `json:"duration"`
+	// seconds the requests of the stage may take, and what happens when they take longer: truncate, fail or extend
+	MaxDuration int    `json:"maxDuration"`
+	Overflow    string `json:"overflow"`
+	Size        int64  `json:"size"`
+	// range of the key numbers like 0-99999, the stage sends a request per key unless it has requests or a duration
+	Keys string `json:"keys"`
+	// any other command line option of the stage by name, like {"prefix": "large", "partsize": 16777216}
+	Options map[string]interface{} `json:"options"`
+	// expected outcomes of the requests, a stage with assertions fails by them rather than by its failed requests
+	Assert []statusAssertion `json:"assert"`
+
+	args parameters
+}
+
+type stagedWorkload struct {
+	Stages []*workloadStage `json:"stages"`
+}
+
+// Returns whether the workload file describes stages rather than being a mixed workload or replay file.
+func isStagedWorkload(path string) (bool, error) {
+	decoder, err := openFile(path)
+	if err != nil {
+		return false, err
+	}
+	if _, err = decoder.Token(); err != nil {
+		return false, err
+	}
+	key, err := decoder.Token()
+	if err != nil {
+		return false, err
+	}
+	return key == "stages", nil
+}
+
+// Parses the stages of a staged workload file. The command line of a stage is the command line of the run without
+// the workload followed by the settings of the stage, so that a stage is validated like a run of its own.
+func parseStagedWorkload(cmdline []string, path string) (parameters, error) {
+	base, err := parse(withoutFlags(cmdline, "workload"))
+	if err != nil {
+		return parameters{}, err
+	}
+	if base.benchSuite != "" || base.abortIncomplete || base.ramp != nil || base.verifyManifest != "" {
+		return parameters{}, errors.New("A staged workload cannot be combined with bench-suite, abort-all-incomplete, ramp or verify-manifest")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return parameters{}, err
+	}
+	defer f.Close()
+	decoder := json.NewDecoder(f)
+	// keeps large numbers of the options exact
+	decoder.UseNumber()
+	var workload stagedWorkload
+	if err = decoder.Decode(&workload); err != nil {
+		return parameters{}, fmt.Errorf("Error parsing workload file: %s", err)
+	}
+	if len(workload.Stages) == 0 {
+		return parameters{}, errors.New("The workload file has no stages")
+	}
+
+	for i, stage := range workload.Stages {
+		if stage.Name == "" {
+			stage.Name = "stage-" + strconv.Itoa(i)
+		}
+		stageCmdline, err := stage.commandLine(cmdline)
+		if err != nil {
+			return parameters{}, fmt.Errorf("Stage %s: %s", stage.Name, err)
+		}
+		if stage.args, err = parse(stageCmdline); err != nil {
+			return parameters{}, fmt.Errorf("Stage %s: %s", stage.Name, err)
+		}
+		if stage.args.ramp != nil {
+			return parameters{}, fmt.Errorf("Stage %s: a stage cannot have a ramp", stage.Name)
+		}
+		for _, a := range stage.Assert {
+			if err = a.validate(); err != nil {
+				return parameters{}, fmt.Errorf("Stage %s: %s", stage.Name, err)
+			}
+		}
+		stage.args.statusCodes = len(stage.Assert) > 0
+	}
+	base.stages = workload.Stages
+	return base, nil
+}
+
+// commandLine returns the command line of the run with the settings of the stage. Settings of the run that conflict
+// with the settings of the stage, like the operation of a stage with a mix, are left out.
+func (s *workloadStage) commandLine(cmdline []string) ([]string, error) {
+	omit := []string{"workload"}
+	var settings []string
+	if s.Operation != "" {
+		omit = append(omit, "mix")
+		settings = append(settings, "-operation="+s.Operation)
+	}
+	if s.Mix != "" {
+		omit = append(omit, "operation")
+		settings = append(settings, "-mix="+s.Mix)
+	}
+	if s.Concurrency != 0 {
+		settings = append(settings, "-concurrency="+strconv.Itoa(s.Concurrency))
+	}
+	requests := s.Requests
+	if s.Keys != "" {
+		bounds := strings.Split(s.Keys, "-")
+		first, err := strconv.ParseInt(bounds[0], 10, 64)
+		var last int64
+		if err == nil && len(bounds) == 2 {
+			last, err = strconv.ParseInt(bounds[1], 10, 64)
+		}
+		if err != nil || len(bounds) != 2 || first < 0 || last < first {
+			return nil, errors.New("keys must be a range of key numbers like 0-99999")
+		}
+		settings = append(settings, "-key-offset="+strconv.FormatInt(first, 10))
+		if requests == 0 && s.Duration == 0 {
+			requests = int(last - first + 1)
+		}
+	}
+	if requests != 0 {
+		omit = append(omit, "duration")
+		settings = append(settings, "-requests="+strconv.Itoa(requests))
+	}
+	if s.Duration != 0 {
+		if requests == 0 {
+			omit = append(omit, "requests")
+		}
+		// the time box of the run is for stages with requests
+		omit = append(omit, "max-duration", "overflow")
+		settings = append(settings, "-duration="+strconv.Itoa(s.Duration))
+	}
+	if s.MaxDuration != 0 {
+		settings = append(settings, "-max-duration="+strconv.Itoa(s.MaxDuration))
+	}
+	if s.Overflow != "" {
+		settings = append(settings, "-overflow="+s.Overflow)
+	}
+	if s.Size != 0 {
+		settings = append(settings, "-size="+strconv.FormatInt(s.Size, 10))
+	}
+
+	// in a fixed order, so that a stage is parsed the same way every time
+	names := make([]string, 0, len(s.Options))
+	for name := range s.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "workload" {
+			return nil, errors.New("a stage cannot have a workload")
+		}
+		settings = append(settings, fmt.Sprintf("-%s=%v", name, s.Options[name]))
+	}
+	return append(withoutFlags(cmdline, omit...), settings...), nil
+}
+
+// Returns the command line without the given flags, which must take a value, and their values.
+func withoutFlags(cmdline []string, names ...string) []string {
+	kept := make([]string, 0, len(cmdline))
+	for i := 0; i < len(cmdline); i++ {
+		arg := cmdline[i]
+		name := strings.TrimLeft(arg, "-")
+		omitted := false
+		for _, n := range names {
+			if strings.HasPrefix(arg, "-") && (name == n || strings.HasPrefix(name, n+"=")) {
+				omitted = true
+				if name == n {
+					// the value is the next argument
+					i++
+				}
+			}
+		}
+		if !omitted {
+			kept = append(kept, arg)
+		}
+	}
+	return kept
+}
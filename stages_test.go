@@ -0,0 +1,140 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeWorkloadFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "s3tester-workload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "workload.json")
+	if err = ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWithoutFlags(t *testing.T) {
+	cmdline := []string{"-operation=get", "-workload", "w.json", "--mix=get:1", "-prefix", "operation", "-requests=10"}
+	expected := []string{"-prefix", "operation", "-requests=10"}
+	if kept := withoutFlags(cmdline, "workload", "operation", "mix"); !reflect.DeepEqual(kept, expected) {
+		t.Fatalf("Expected %v but got %v", expected, kept)
+	}
+}
+
+func TestStageCommandLine(t *testing.T) {
+	cmdline := []string{"-operation=put", "-requests=1000", "-workload=w.json", "-bucket=b"}
+
+	stage := workloadStage{Mix: "get:70,put:30", Concurrency: 8, Duration: 60, Options: map[string]interface{}{"prefix": "mixed", "size": 4096}}
+	stageCmdline, err := stage.commandLine(cmdline)
+	expected := []string{"-bucket=b", "-mix=get:70,put:30", "-concurrency=8", "-duration=60", "-prefix=mixed", "-size=4096"}
+	if err != nil || !reflect.DeepEqual(stageCmdline, expected) {
+		t.Fatalf("Expected %v but got %v (%v)", expected, stageCmdline, err)
+	}
+
+	stage = workloadStage{Operation: "delete", Keys: "1000-1999"}
+	stageCmdline, err = stage.commandLine(cmdline)
+	expected = []string{"-operation=put", "-requests=1000", "-bucket=b", "-operation=delete", "-key-offset=1000", "-requests=1000"}
+	if err != nil || !reflect.DeepEqual(stageCmdline, expected) {
+		t.Fatalf("Expected %v but got %v (%v)", expected, stageCmdline, err)
+	}
+
+	for _, keys := range []string{"1000", "a-b", "10-5", "-5-10"} {
+		stage = workloadStage{Keys: keys}
+		if _, err = stage.commandLine(cmdline); err == nil {
+			t.Fatalf("Expected keys %s to be rejected", keys)
+		}
+	}
+}
+
+func TestStageTimeBox(t *testing.T) {
+	cmdline := []string{"-operation=put", "-requests=1000", "-max-duration=60", "-overflow=fail"}
+
+	stage := workloadStage{Operation: "get", MaxDuration: 300, Overflow: "extend"}
+	stageCmdline, err := stage.commandLine(cmdline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := parse(stageCmdline)
+	if err != nil || args.maxDuration != 300*time.Second || args.overflow != overflowExtend {
+		t.Fatalf("Expected the time box of the stage but got %s %s (%v)", args.maxDuration, args.overflow, err)
+	}
+
+	// the time box of the run is dropped by stages with a duration
+	stage = workloadStage{Operation: "put", Duration: 30}
+	stageCmdline, err = stage.commandLine(cmdline)
+	expected := []string{"-operation=put", "-operation=put", "-duration=30"}
+	if err != nil || !reflect.DeepEqual(stageCmdline, expected) {
+		t.Fatalf("Expected %v but got %v (%v)", expected, stageCmdline, err)
+	}
+}
+
+func TestParseStagedWorkload(t *testing.T) {
+	path := writeWorkloadFile(t, `{"stages": [
+		{"name": "prepopulate", "operation": "put", "concurrency": 4, "keys": "0-999", "size": 1024},
+		{"name": "mixed", "mix": "get:70,put:30", "concurrency": 8, "duration": 60, "keys": "0-999"},
+		{"operation": "delete", "keys": "0-999", "options": {"prefix": "other"}}
+	]}`)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	args, err := parse([]string{"-workload=" + path, "-bucket=staged"})
+	if err != nil || len(args.stages) != 3 {
+		t.Fatalf("Expected 3 stages but got %v (%v)", args.stages, err)
+	}
+
+	prepopulate, mixed, cleanup := args.stages[0].args, args.stages[1].args, args.stages[2].args
+	if prepopulate.optype != "put" || prepopulate.concurrency != 4 || prepopulate.nrequests.value != 1000 || prepopulate.osize != 1024 {
+		t.Fatalf("Wrong prepopulate stage: %s %d %d %d", prepopulate.optype, prepopulate.concurrency, prepopulate.nrequests.value, prepopulate.osize)
+	}
+	if mixed.optype != "mix" || mixed.mix == nil || !mixed.duration.set || mixed.duration.value != 60*time.Second {
+		t.Fatalf("Wrong mixed stage: %s %+v", mixed.optype, mixed.duration)
+	}
+	if args.stages[2].Name != "stage-2" || cleanup.optype != "delete" || cleanup.objectprefix != "other" || cleanup.bucketname != "staged" {
+		t.Fatalf("Wrong cleanup stage %s: %s %s %s", args.stages[2].Name, cleanup.optype, cleanup.objectprefix, cleanup.bucketname)
+	}
+}
+
+func TestParseStagedWorkloadErrors(t *testing.T) {
+	for _, content := range []string{`{"stages": []}`, `{"stages": [{"name": "bad", "operation": "fly"}]}`, `{"stages": [{"options": {"workload": "w.json"}}]}`} {
+		path := writeWorkloadFile(t, content)
+		_, err := parse([]string{"-workload=" + path})
+		os.RemoveAll(filepath.Dir(path))
+		if err == nil {
+			t.Fatalf("Expected %s to be rejected", content)
+		}
+	}
+
+	path := writeWorkloadFile(t, `{"stages": [{"name": "bad", "operation": "fly"}]}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	if _, err := parse([]string{"-workload=" + path}); !strings.HasPrefix(err.Error(), "Stage bad:") {
+		t.Fatalf("Expected the error to name the stage but got %v", err)
+	}
+}
+
+func TestIsStagedWorkload(t *testing.T) {
+	path := writeWorkloadFile(t, `{"mixedWorkload": [{"operationType": "put", "ratio": 100}]}`)
+	defer os.RemoveAll(filepath.Dir(path))
+	if staged, err := isStagedWorkload(path); staged || err != nil {
+		t.Fatalf("A mixed workload should not be staged: %v", err)
+	}
+}
+
+func TestObjectKeyOffset(t *testing.T) {
+	args := parameters{objectprefix: "testobject", keyOffset: 1000}
+	if key := objectKey(&args, 2, 10, 3); key != "testobject-1023" {
+		t.Fatalf("Expected testobject-1023 but got %s", key)
+	}
+
+	args.overwrite = 2
+	if key := objectKey(&args, 2, 10, 3); key != "testobject-1003" {
+		t.Fatalf("Expected testobject-1003 but got %s", key)
+	}
+}
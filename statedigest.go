@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// stateDigest is the fingerprint of the objects under a prefix: the number of objects, their bytes and a digest of
+// their keys, sizes and ETags that doesn't depend on the order they were listed in, so that two runs or two sites that
+// ended in the same state have the same digest.
+type stateDigest struct {
+	Bucket  string `json:"bucket"`
+	Prefix  string `json:"prefix"`
+	Objects int    `json:"objects"`
+	Bytes   int64  `json:"totalBytes"`
+	Digest  string `json:"digest"`
+	// the digest the state was expected to have, with expect-state-digest
+	Expected string `json:"expectedDigest,omitempty"`
+	Mismatch bool   `json:"mismatch"`
+
+	sum [sha256.Size]byte
+}
+
+// add adds an object to the digest. The SHA-256 of every object is added up as a 256 bit number, which doesn't depend
+// on the order of the objects, unlike a hash of all of them.
+func (d *stateDigest) add(key string, size int64, etag string) {
+	d.Objects++
+	d.Bytes += size
+	etag = strings.ToLower(strings.Trim(etag, `"`))
+	h := sha256.Sum256([]byte(key + "\n" + strconv.FormatInt(size, 10) + "\n" + etag))
+	carry := 0
+	for i := len(d.sum) - 1; i >= 0; i-- {
+		s := int(d.sum[i]) + int(h[i]) + carry
+		d.sum[i], carry = byte(s), s>>8
+	}
+	d.Digest = hex.EncodeToString(d.sum[:])
+}
+
+// DigestState lists the objects under the prefix with the v1 or v2 listing API and returns their digest.
+func DigestState(svc s3iface.S3API, bucket, prefix, api string) (stateDigest, error) {
+	d := stateDigest{Bucket: bucket, Prefix: prefix}
+	d.Digest = hex.EncodeToString(d.sum[:])
+	marker := ""
+	for {
+		objects, next, err := listInventoryPage(svc, bucket, prefix, marker, api)
+		if err != nil {
+			return d, err
+		}
+		for _, object := range objects {
+			d.add(aws.StringValue(object.Key), aws.Int64Value(object.Size), aws.StringValue(object.ETag))
+		}
+		if next == "" {
+			return d, nil
+		}
+		marker = next
+	}
+}
+
+// digestState returns the digest of the objects under the prefix of the run in its bucket, compared with the expected
+// digest if there is one.
+func digestState(args parameters) (stateDigest, error) {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		return stateDigest{}, err
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+	args.requestExtras.install(svc)
+	d, err := DigestState(svc, args.bucketname, args.objectprefix, args.listApi)
+	if err != nil {
+		return d, err
+	}
+	if args.expectStateDigest != "" {
+		d.Expected = args.expectStateDigest
+		d.Mismatch = d.Digest != args.expectStateDigest
+	}
+	return d, nil
+}
+
+func printStateDigest(d stateDigest, isJson bool) {
+	if isJson {
+		jsonReport, err := json.Marshal(map[string]stateDigest{"stateDigest": d})
+		if err != nil {
+			fmt.Println("Error when parsing state digest to json")
+			return
+		}
+		fmt.Println(string(jsonReport))
+		return
+	}
+
+	fmt.Println("\n\t--- State Digest ---")
+	fmt.Printf("Objects under '%s/%s': %d\n", d.Bucket, d.Prefix, d.Objects)
+	fmt.Printf("Total bytes: %d\n", d.Bytes)
+	fmt.Printf("Digest of the keys, sizes and ETags: %s\n", d.Digest)
+	if d.Expected == "" {
+		return
+	}
+	if d.Mismatch {
+		fmt.Printf("MISMATCH, expected %s\n", d.Expected)
+	} else {
+		fmt.Println("Matches the expected digest")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStateDigestIgnoresListingOrder(t *testing.T) {
+	var a, b stateDigest
+	a.add("object-0", 1024, `"5d41402abc4b2a76b9719d911017c592"`)
+	a.add("object-1", 2048, `"7d793037a0760186574b0282f2f435e7"`)
+	a.add("object-2", 0, `"d41d8cd98f00b204e9800998ecf8427e"`)
+	b.add("object-2", 0, `"d41d8cd98f00b204e9800998ecf8427e"`)
+	b.add("object-0", 1024, `"5D41402ABC4B2A76B9719D911017C592"`)
+	b.add("object-1", 2048, "7d793037a0760186574b0282f2f435e7")
+	if a.Digest != b.Digest || a.Objects != 3 || a.Bytes != 3072 {
+		t.Fatalf("Expected the same digest of 3 objects of 3072 bytes but got %s %d %d and %s", a.Digest, a.Objects, a.Bytes, b.Digest)
+	}
+
+	var c stateDigest
+	c.add("object-0", 1024, `"5d41402abc4b2a76b9719d911017c592"`)
+	c.add("object-1", 2049, `"7d793037a0760186574b0282f2f435e7"`)
+	c.add("object-2", 0, `"d41d8cd98f00b204e9800998ecf8427e"`)
+	if c.Digest == a.Digest {
+		t.Fatalf("Expected a different digest after an object changed size")
+	}
+	if len(a.Digest) != 64 {
+		t.Fatalf("Expected a hex SHA-256 digest but got %s", a.Digest)
+	}
+}
+
+func TestStateDigestFlags(t *testing.T) {
+	digest := strings.Repeat("0A", 32)
+	args, err := parse([]string{"-operation=put", "-expect-state-digest=" + digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !args.stateDigest || args.expectStateDigest != strings.ToLower(digest) {
+		t.Fatalf("Expected expect-state-digest to digest the state but got %v %s", args.stateDigest, args.expectStateDigest)
+	}
+
+	for _, cmdline := range [][]string{
+		{"-state-digest", "-cleanup"},
+		{"-state-digest", "-dryrun"},
+		{"-state-digest", "-num-buckets=2"},
+		{"-expect-state-digest=abc"},
+		{"-expect-state-digest=" + strings.Repeat("z", 64)},
+	} {
+		if _, err := parse(cmdline); err == nil {
+			t.Fatalf("Expected %v to be rejected", cmdline)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// storageClassMix holds the storage classes objects are written to and their weights.
+type storageClassMix struct {
+	classes []string
+	weights []int
+	total   int
+}
+
+// Parses a single storage class like STANDARD_IA, or a weighted mix like STANDARD=70,STANDARD_IA=20,GLACIER_IR=10.
+// Returns nil if no storage class is given.
+func parseStorageClasses(classes string) (*storageClassMix, error) {
+	if classes == "" {
+		return nil, nil
+	}
+	mix := &storageClassMix{}
+	for _, entry := range strings.Split(classes, ",") {
+		class, weight := entry, 1
+		if i := strings.Index(entry, "="); i >= 0 {
+			var err error
+			if weight, err = strconv.Atoi(entry[i+1:]); err != nil || weight < 1 {
+				return nil, errors.New("storage class weights must be integers >= 1: " + entry)
+			}
+			class = entry[:i]
+		}
+		class = strings.ToUpper(class)
+		if !validStorageClass(class) {
+			return nil, errors.New("storage class must be one of " + strings.Join(s3.StorageClass_Values(), ", ") + ": " + class)
+		}
+		for _, c := range mix.classes {
+			if c == class {
+				return nil, errors.New("storage class " + class + " is listed more than once")
+			}
+		}
+		mix.classes = append(mix.classes, class)
+		mix.weights = append(mix.weights, weight)
+		mix.total += weight
+	}
+	return mix, nil
+}
+
+func validStorageClass(class string) bool {
+	for _, c := range s3.StorageClass_Values() {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns the storage class of the next object written, given a random number generator like rand.Intn.
+func (m *storageClassMix) pick(intn func(int) int) string {
+	n := intn(m.total)
+	for i, weight := range m.weights {
+		if n < weight {
+			return m.classes[i]
+		}
+		n -= weight
+	}
+	return m.classes[len(m.classes)-1]
+}
+
+func (this *result) recordStorageClassLatency(class string, l time.Duration) {
+	if this.StorageClassResults == nil {
+		this.StorageClassResults = make(map[string]*latencyResult)
+	}
+	s, ok := this.StorageClassResults[class]
+	if !ok {
+		s = NewLatencyResult()
+		this.StorageClassResults[class] = s
+	}
+	s.record(l)
+}
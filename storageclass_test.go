@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestParseStorageClasses(t *testing.T) {
+	mix, err := parseStorageClasses("standard_ia")
+	if err != nil || !reflect.DeepEqual(mix.classes, []string{"STANDARD_IA"}) || mix.total != 1 {
+		t.Fatalf("Wrong storage class: %+v (%v)", mix, err)
+	}
+
+	mix, err = parseStorageClasses("STANDARD=70,STANDARD_IA=20,GLACIER_IR=10")
+	if err != nil || !reflect.DeepEqual(mix.weights, []int{70, 20, 10}) || mix.total != 100 {
+		t.Fatalf("Wrong storage class mix: %+v (%v)", mix, err)
+	}
+
+	for _, invalid := range []string{"COLD", "STANDARD=0", "STANDARD=x", "STANDARD=1,standard=2"} {
+		if _, err := parseStorageClasses(invalid); err == nil {
+			t.Fatalf("Expected %s to be rejected", invalid)
+		}
+	}
+
+	if mix, err := parseStorageClasses(""); mix != nil || err != nil {
+		t.Fatalf("Expected no storage class but got %+v (%v)", mix, err)
+	}
+}
+
+func TestPickStorageClass(t *testing.T) {
+	mix, _ := parseStorageClasses("STANDARD=70,STANDARD_IA=20,GLACIER_IR=10")
+	counts := make(map[string]int)
+	for n := 0; n < mix.total; n++ {
+		counts[mix.pick(func(int) int { return n })]++
+	}
+
+	expected := map[string]int{"STANDARD": 70, "STANDARD_IA": 20, "GLACIER_IR": 10}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("Expected %v but picked %v", expected, counts)
+	}
+}
+
+func TestMergeStorageClassResults(t *testing.T) {
+	r1, r2, merged := NewResult(), NewResult(), NewResult()
+	r1.recordStorageClassLatency("STANDARD", time.Millisecond)
+	r2.recordStorageClassLatency("STANDARD", 3*time.Millisecond)
+	r2.recordStorageClassLatency("GLACIER_IR", time.Millisecond)
+	mergeResult(&merged, &r1)
+	mergeResult(&merged, &r2)
+
+	if merged.StorageClassResults["STANDARD"].Count != 2 || merged.StorageClassResults["GLACIER_IR"].Count != 1 {
+		t.Fatalf("Wrong merged storage class results: %v", merged.StorageClassResults)
+	}
+}
+
+func TestCopyStorageClass(t *testing.T) {
+	var class *string
+	handler := func(in interface{}) interface{} {
+		class = in.(*s3.CopyObjectInput).StorageClass
+		return in
+	}
+	svc := NewMockS3Client(handler)
+
+	if err := Copy(svc, "b", "k1", "b2", "k2", "GLACIER_IR", "COPY", nil); err != nil || aws.StringValue(class) != "GLACIER_IR" {
+		t.Fatalf("Expected a copy to GLACIER_IR but got %v (%v)", aws.StringValue(class), err)
+	}
+
+	// without a storage class the server picks its default
+	if err := Copy(svc, "b", "k1", "b2", "k2", "", "COPY", nil); err != nil || class != nil {
+		t.Fatalf("Expected no storage class but got %v (%v)", aws.StringValue(class), err)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resultStream sends a summary of the requests completed during every interval of the run to a remote
+// collector as JSON lines, so that a dashboard can aggregate many s3tester hosts while they run.
+type resultStream struct {
+	network  string
+	address  string
+	interval time.Duration
+	host     string
+
+	conn    io.WriteCloser
+	failing bool
+	stop    chan struct{}
+	done    chan struct{}
+
+	// requests are recorded by all workers
+	mu        sync.Mutex
+	current   *latencyResult
+	failed    int
+	bytes     int64
+	operation string
+	begin     time.Time
+}
+
+// streamSummary is the JSON line sent for every interval.
+type streamSummary struct {
+	Host               string             `json:"host"`
+	Operation          string             `json:"operation"`
+	Time               time.Time          `json:"time"`
+	Interval           float64            `json:"interval (s)"`
+	Count              int                `json:"totalRequests"`
+	Failcount          int                `json:"failedRequests"`
+	RequestsPerSec     float64            `json:"requestsPerSec"`
+	ContentThroughput  float64            `json:"contentThroughput (MB/s)"`
+	AverageRequestTime float64            `json:"averageRequestTime (ms)"`
+	Percentiles        map[string]float64 `json:"responseTimePercentiles(ms)"`
+}
+
+// NewResultStream parses the collector address, host:port for TCP or udp://host:port for UDP.
+func NewResultStream(address string, interval time.Duration) (*resultStream, error) {
+	if address == "" {
+		return nil, nil
+	}
+	network := "tcp"
+	if i := strings.Index(address, "://"); i >= 0 {
+		network, address = address[:i], address[i+3:]
+	}
+	if network != "tcp" && network != "udp" {
+		return nil, fmt.Errorf("unsupported protocol %s, must be tcp or udp", network)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval must be > 0")
+	}
+	host, _ := os.Hostname()
+	return &resultStream{network: network, address: address, interval: interval, host: host}, nil
+}
+
+// start connects to the collector and sends a summary every interval until halted.
+func (s *resultStream) start(operation string) {
+	if s == nil {
+		return
+	}
+	conn, err := net.Dial(s.network, s.address)
+	if err != nil {
+		log.Fatalf("Failed to connect to the result collector %s: %v", s.address, err)
+	}
+	s.conn = conn
+	s.operation = operation
+	s.reset(time.Now())
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.send(s.summarize(now))
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// halt sends the summary of the last, partial interval and closes the connection.
+func (s *resultStream) halt() {
+	if s == nil || s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+	if summary := s.summarize(time.Now()); summary.Count > 0 {
+		s.send(summary)
+	}
+	s.conn.Close()
+}
+
+// record is safe to call on a nil stream, which records nothing.
+func (s *resultStream) record(l time.Duration, bytes int64, failed bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current.record(l)
+	s.bytes += bytes
+	if failed {
+		s.failed++
+	}
+}
+
+func (s *resultStream) reset(now time.Time) {
+	s.current = NewLatencyResult()
+	s.failed = 0
+	s.bytes = 0
+	s.begin = now
+}
+
+// summarize returns the summary of the interval ending now and starts the next one.
+func (s *resultStream) summarize(now time.Time) streamSummary {
+	s.mu.Lock()
+	current, failed, bytes, begin := s.current, s.failed, s.bytes, s.begin
+	s.reset(now)
+	s.mu.Unlock()
+
+	current.setupStats()
+	elapsed := now.Sub(begin).Seconds()
+	summary := streamSummary{
+		Host:               s.host,
+		Operation:          s.operation,
+		Time:               now,
+		Interval:           roundFloat(elapsed, 3),
+		Count:              current.Count,
+		Failcount:          failed,
+		AverageRequestTime: current.AverageRequestTime,
+		Percentiles:        current.Percentiles,
+	}
+	if elapsed > 0 {
+		summary.RequestsPerSec = roundFloat(float64(current.Count)/elapsed, 1)
+		summary.ContentThroughput = roundFloat(float64(bytes)/1024/1024/elapsed, 6)
+	}
+	return summary
+}
+
+// send writes the summary as one JSON line. A collector that goes away doesn't stop the run, the failure is logged once.
+func (s *resultStream) send(summary streamSummary) {
+	line, _ := json.Marshal(summary)
+	if _, err := s.conn.Write(append(line, '\n')); err != nil {
+		if !s.failing {
+			log.Printf("WARNING: failed to send results to the collector %s: %v", s.address, err)
+		}
+		s.failing = true
+		return
+	}
+	s.failing = false
+}
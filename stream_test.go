@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNewResultStream(t *testing.T) {
+	if s, err := NewResultStream("", time.Second); s != nil || err != nil {
+		t.Fatalf("Expected no stream without an address but got %v, %v", s, err)
+	}
+
+	s, err := NewResultStream("collector:9000", time.Second)
+	if err != nil || s.network != "tcp" || s.address != "collector:9000" {
+		t.Fatalf("Expected a TCP stream to collector:9000 but got %+v, %v", s, err)
+	}
+
+	s, err = NewResultStream("udp://collector:9000", time.Second)
+	if err != nil || s.network != "udp" || s.address != "collector:9000" {
+		t.Fatalf("Expected a UDP stream to collector:9000 but got %+v, %v", s, err)
+	}
+
+	for _, address := range []string{"collector", "http://collector:9000"} {
+		if _, err := NewResultStream(address, time.Second); err == nil {
+			t.Fatalf("Expected invalid address %s to fail", address)
+		}
+	}
+
+	if _, err := NewResultStream("collector:9000", 0); err == nil {
+		t.Fatalf("Expected zero interval to fail")
+	}
+}
+
+func TestResultStreamSummarize(t *testing.T) {
+	s, _ := NewResultStream("collector:9000", time.Second)
+	begin := time.Now()
+	s.reset(begin)
+	s.record(10*time.Millisecond, 1024*1024, false)
+	s.record(30*time.Millisecond, 1024*1024, true)
+
+	summary := s.summarize(begin.Add(2 * time.Second))
+	if summary.Count != 2 || summary.Failcount != 1 || summary.RequestsPerSec != 1 || summary.ContentThroughput != 1 {
+		t.Fatalf("Wrong summary %+v", summary)
+	}
+
+	if summary.AverageRequestTime != 20 {
+		t.Fatalf("Expected average request time of 20ms but got %v", summary.AverageRequestTime)
+	}
+
+	// every interval starts from scratch
+	summary = s.summarize(begin.Add(3 * time.Second))
+	if summary.Count != 0 || summary.Failcount != 0 || summary.Interval != 1 {
+		t.Fatalf("Expected an empty interval of 1s but got %+v", summary)
+	}
+}
+
+func TestResultStreamSendsJSONLines(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	s, _ := NewResultStream(listener.Addr().String(), time.Hour)
+	s.start("put")
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	defer conn.Close()
+
+	s.record(10*time.Millisecond, 100, false)
+	// the last interval is sent when the stream is halted
+	s.halt()
+
+	var summary streamSummary
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("Failed to read summary: %v", err)
+	}
+
+	if err := json.Unmarshal(line, &summary); err != nil {
+		t.Fatalf("Failed to decode summary %s: %v", line, err)
+	}
+
+	if summary.Operation != "put" || summary.Count != 1 {
+		t.Fatalf("Wrong summary %+v", summary)
+	}
+}
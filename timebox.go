@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policies of a run with requests whose max duration is up before all its requests were sent.
+const (
+	// stop and report the requests sent
+	overflowTruncate = "truncate"
+	// stop and count the run as failed
+	overflowFail = "fail"
+	// send the rest of the requests and report the overrun
+	overflowExtend = "extend"
+)
+
+// timeBox is the outcome of a run with requests and a max duration.
+type timeBox struct {
+	MaxDuration float64 `json:"maxDuration (s)"`
+	Overflow    string  `json:"overflow"`
+	// whether the max duration was up before all requests were sent
+	Exceeded bool `json:"exceeded"`
+	// how long the run took beyond the max duration, with extend
+	Overrun float64 `json:"overrun (s),omitempty"`
+}
+
+// Returns the time box of a run with the given max duration that took elapsed, or nil without a max duration.
+func NewTimeBox(maxDuration time.Duration, overflow string, elapsed time.Duration) *timeBox {
+	if maxDuration == 0 {
+		return nil
+	}
+	t := &timeBox{MaxDuration: maxDuration.Seconds(), Overflow: overflow, Exceeded: elapsed >= maxDuration}
+	if t.Exceeded && overflow == overflowExtend {
+		t.Overrun = (elapsed - maxDuration).Seconds()
+	}
+	return t
+}
+
+// failed returns whether the run ran out of time and its policy counts that as a failure.
+func (t *timeBox) failed() bool {
+	return t != nil && t.Exceeded && t.Overflow == overflowFail
+}
+
+func printTimeBox(t *timeBox) {
+	if t == nil || !t.Exceeded {
+		return
+	}
+	maxDuration := time.Duration(t.MaxDuration * float64(time.Second))
+	switch t.Overflow {
+	case overflowTruncate:
+		fmt.Printf("Max duration of %s was up before all requests were sent, the run was truncated\n", maxDuration)
+	case overflowFail:
+		fmt.Printf("Max duration of %s was up before all requests were sent, the run failed\n", maxDuration)
+	case overflowExtend:
+		fmt.Printf("Max duration of %s was exceeded by %s to send all requests\n", maxDuration, time.Duration(t.Overrun*float64(time.Second)))
+	}
+}
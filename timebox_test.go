@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeBox(t *testing.T) {
+	if NewTimeBox(0, overflowTruncate, time.Minute) != nil {
+		t.Fatalf("Expected no time box without a max duration")
+	}
+
+	if box := NewTimeBox(time.Minute, overflowFail, 30*time.Second); box.Exceeded || box.failed() {
+		t.Fatalf("A run within its max duration should not be exceeded: %+v", box)
+	}
+
+	if box := NewTimeBox(time.Minute, overflowTruncate, time.Minute); !box.Exceeded || box.failed() || box.Overrun != 0 {
+		t.Fatalf("A truncated run should be exceeded but not failed: %+v", box)
+	}
+
+	if box := NewTimeBox(time.Minute, overflowFail, time.Minute); !box.failed() {
+		t.Fatalf("A run out of time should fail with the fail policy: %+v", box)
+	}
+
+	if box := NewTimeBox(time.Minute, overflowExtend, 90*time.Second); !box.Exceeded || box.failed() || box.Overrun != 30 {
+		t.Fatalf("An extended run should report its overrun: %+v", box)
+	}
+}
+
+func TestMaxDurationSetting(t *testing.T) {
+	start := time.Now().Add(-time.Minute)
+	args := parameters{duration: &durationFlag{}, maxDuration: time.Second, overflow: overflowTruncate}
+	if !NewMaxDurationSetting(&args, start).enabled() {
+		t.Fatalf("Workers should stop once the max duration is up")
+	}
+
+	args.overflow = overflowExtend
+	if NewMaxDurationSetting(&args, start).enabled() {
+		t.Fatalf("Workers should send all requests with extend")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// The classes of S3 requests that can have their own timeouts.
+var timeoutClasses = []string{"read", "write", "delete"}
+
+// timeouts bound the phases of a single S3 request. Zero means no limit.
+type timeouts struct {
+	// from asking for a connection until it is established, including the TLS handshake
+	connect time.Duration
+	// from writing the last byte of the request until the first byte of the response
+	header time.Duration
+	// from the start of the request until its response body is closed
+	total time.Duration
+}
+
+// timeoutConfig holds the timeouts of every request class.
+type timeoutConfig map[string]timeouts
+
+// Returns the class of the S3 request with the given operation name.
+func requestClass(operation string) string {
+	switch {
+	case strings.HasPrefix(operation, "Get"), strings.HasPrefix(operation, "Head"), strings.HasPrefix(operation, "List"):
+		return "read"
+	case strings.HasPrefix(operation, "Delete"), strings.HasPrefix(operation, "Abort"):
+		return "delete"
+	}
+	return "write"
+}
+
+// Parses the connect, header and total timeouts. Each is either empty, a duration that applies to every class,
+// or a comma separated list like '2s,write=10m' where class=duration entries override the default for that class.
+func parseTimeouts(connect, header, total string) (timeoutConfig, error) {
+	config := make(timeoutConfig)
+	set := func(spec, name string, field func(*timeouts) *time.Duration) error {
+		if spec == "" {
+			return nil
+		}
+		for _, entry := range strings.Split(spec, ",") {
+			classes := timeoutClasses
+			value := entry
+			if keyvalue := strings.SplitN(entry, "=", 2); len(keyvalue) == 2 {
+				if requestClassIndex(keyvalue[0]) < 0 {
+					return fmt.Errorf("Unknown request class %s in %s timeout, must be one of %s", keyvalue[0], name, strings.Join(timeoutClasses, ", "))
+				}
+				classes = []string{keyvalue[0]}
+				value = keyvalue[1]
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return fmt.Errorf("Invalid %s timeout: %s", name, entry)
+			}
+			for _, class := range classes {
+				t := config[class]
+				*field(&t) = d
+				config[class] = t
+			}
+		}
+		return nil
+	}
+
+	if err := set(connect, "connect", func(t *timeouts) *time.Duration { return &t.connect }); err != nil {
+		return nil, err
+	}
+	if err := set(header, "header", func(t *timeouts) *time.Duration { return &t.header }); err != nil {
+		return nil, err
+	}
+	if err := set(total, "request", func(t *timeouts) *time.Duration { return &t.total }); err != nil {
+		return nil, err
+	}
+	if len(config) == 0 {
+		return nil, nil
+	}
+	return config, nil
+}
+
+func requestClassIndex(class string) int {
+	for i, c := range timeoutClasses {
+		if c == class {
+			return i
+		}
+	}
+	return -1
+}
+
+type timeoutWatchKey struct{}
+
+// timeoutWatch cancels a request once one of its timeouts is exceeded and remembers which one it was.
+type timeoutWatch struct {
+	mu       sync.Mutex
+	exceeded string
+	cancel   func()
+	connect  *time.Timer
+	header   *time.Timer
+	total    *time.Timer
+}
+
+func (w *timeoutWatch) expire(name string, limit time.Duration) *time.Timer {
+	return time.AfterFunc(limit, func() {
+		w.mu.Lock()
+		if w.exceeded == "" {
+			w.exceeded = fmt.Sprintf("%s timeout of %s", name, limit)
+		}
+		w.mu.Unlock()
+		w.cancel()
+	})
+}
+
+func (w *timeoutWatch) stop(timer **time.Timer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if *timer != nil {
+		(*timer).Stop()
+	}
+}
+
+func (w *timeoutWatch) stopAll() {
+	w.stop(&w.connect)
+	w.stop(&w.header)
+	w.stop(&w.total)
+}
+
+func (w *timeoutWatch) start(timer **time.Timer, name string, limit time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	*timer = w.expire(name, limit)
+}
+
+// Returns a context for a request that is cancelled once one of the timeouts is exceeded.
+func (t timeouts) watch(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	w := &timeoutWatch{cancel: cancel}
+	trace := &httptrace.ClientTrace{}
+	if t.connect > 0 {
+		trace.GetConn = func(string) { w.start(&w.connect, "connect", t.connect) }
+		trace.GotConn = func(httptrace.GotConnInfo) { w.stop(&w.connect) }
+	}
+	if t.header > 0 {
+		trace.WroteRequest = func(httptrace.WroteRequestInfo) { w.start(&w.header, "header", t.header) }
+		trace.GotFirstResponseByte = func() { w.stop(&w.header) }
+	}
+	if t.total > 0 {
+		w.start(&w.total, "request", t.total)
+	}
+	return context.WithValue(httptrace.WithClientTrace(ctx, trace), timeoutWatchKey{}, w)
+}
+
+// closes the response body and stops the total timeout of its request
+type watchedBody struct {
+	io.ReadCloser
+	watch *timeoutWatch
+}
+
+func (b watchedBody) Close() error {
+	b.watch.stop(&b.watch.total)
+	return b.ReadCloser.Close()
+}
+
+// install applies the timeouts to every request sent by the service.
+func (c timeoutConfig) install(svc *s3.S3) {
+	if c == nil {
+		return
+	}
+	svc.Client.Handlers.Send.PushFront(func(r *request.Request) {
+		if t := c[requestClass(r.Operation.Name)]; t != (timeouts{}) {
+			r.HTTPRequest = r.HTTPRequest.WithContext(t.watch(r.HTTPRequest.Context()))
+		}
+	})
+	svc.Client.Handlers.Send.PushBack(func(r *request.Request) {
+		w, ok := r.HTTPRequest.Context().Value(timeoutWatchKey{}).(*timeoutWatch)
+		if !ok {
+			return
+		}
+		if r.Error == nil && r.HTTPResponse != nil && r.HTTPResponse.Body != nil {
+			r.HTTPResponse.Body = watchedBody{ReadCloser: r.HTTPResponse.Body, watch: w}
+		} else {
+			// a retry must not be cancelled by the timers of the failed attempt
+			w.stopAll()
+		}
+		w.mu.Lock()
+		exceeded := w.exceeded
+		w.mu.Unlock()
+		if r.Error != nil && exceeded != "" {
+			// a request cancelled by a timeout is not retried
+			r.Error = awserr.New(request.CanceledErrorCode, exceeded+" exceeded", r.Error)
+		}
+	})
+}
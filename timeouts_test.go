@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestParseTimeouts(t *testing.T) {
+	config, err := parseTimeouts("2s", "", "30s,write=1h")
+	if err != nil {
+		t.Fatalf("valid timeouts should succeed: %v", err)
+	}
+
+	if config["read"] != (timeouts{connect: 2 * time.Second, total: 30 * time.Second}) {
+		t.Fatalf("wrong read timeouts: %+v", config["read"])
+	}
+
+	if config["write"] != (timeouts{connect: 2 * time.Second, total: time.Hour}) {
+		t.Fatalf("wrong write timeouts: %+v", config["write"])
+	}
+
+	if config, _ := parseTimeouts("", "", ""); config != nil {
+		t.Fatalf("no timeouts should give a nil config: %+v", config)
+	}
+
+	for _, invalid := range []string{"soon", "-1s", "upload=1s"} {
+		if _, err := parseTimeouts(invalid, "", ""); err == nil {
+			t.Fatalf("timeout %q should fail", invalid)
+		}
+	}
+}
+
+func TestRequestClass(t *testing.T) {
+	classes := map[string]string{
+		"GetObject":               "read",
+		"HeadObject":              "read",
+		"ListObjectsV2":           "read",
+		"PutObject":               "write",
+		"UploadPart":              "write",
+		"CompleteMultipartUpload": "write",
+		"DeleteObjects":           "delete",
+		"AbortMultipartUpload":    "delete",
+	}
+
+	for operation, class := range classes {
+		if requestClass(operation) != class {
+			t.Fatalf("Expected class %s for %s but got %s", class, operation, requestClass(operation))
+		}
+	}
+}
+
+func TestHeaderTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer ts.Close()
+
+	ctx := timeouts{header: 20 * time.Millisecond}.watch(context.Background())
+	req, _ := http.NewRequest("GET", ts.URL, nil)
+	if _, err := http.DefaultClient.Do(req.WithContext(ctx)); err == nil {
+		t.Fatalf("Expected the request to be cancelled by the header timeout")
+	}
+
+	w := ctx.Value(timeoutWatchKey{}).(*timeoutWatch)
+	w.mu.Lock()
+	exceeded := w.exceeded
+	w.mu.Unlock()
+	if exceeded != "header timeout of 20ms" {
+		t.Fatalf("Wrong exceeded timeout: %q", exceeded)
+	}
+
+	ctx = timeouts{header: time.Second, total: time.Second}.watch(context.Background())
+	req, _ = http.NewRequest("GET", ts.URL, nil)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("Expected the request to finish within the timeouts but got: %v", err)
+	}
+	w = ctx.Value(timeoutWatchKey{}).(*timeoutWatch)
+	watchedBody{ReadCloser: resp.Body, watch: w}.Close()
+	w.stopAll()
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// traceRecord is a request of a trace file, a line of a CSV file with a header naming its columns or a JSON object.
+type traceRecord struct {
+	// RFC 3339 time or seconds, since the epoch or the start of the trace
+	Timestamp json.RawMessage `json:"timestamp"`
+	Op        string          `json:"op"`
+	Key       string          `json:"key"`
+	Size      uint64          `json:"size"`
+	Range     string          `json:"range"`
+	Bucket    string          `json:"bucket"`
+}
+
+// Parses the timestamp of a trace record, an RFC 3339 time or a number of seconds.
+func parseTraceTimestamp(timestamp string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(timestamp, 64); err == nil {
+		if seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+			return time.Time{}, fmt.Errorf("invalid timestamp %s", timestamp)
+		}
+		return time.Unix(0, 0).Add(time.Duration(seconds * float64(time.Second))), nil
+	}
+	at, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s, expected an RFC 3339 time or seconds", timestamp)
+	}
+	return at, nil
+}
+
+// Returns the Range header of the range of a trace record, bytes=0-1023 or 0-1023.
+func parseTraceRange(byteRange string) (string, error) {
+	if byteRange == "" {
+		return "", nil
+	}
+	byteRange = strings.TrimPrefix(byteRange, "bytes=")
+	dash := strings.Index(byteRange, "-")
+	if dash < 0 {
+		return "", fmt.Errorf("invalid range %s", byteRange)
+	}
+	first, last := byteRange[:dash], byteRange[dash+1:]
+	if first == "" && last == "" {
+		return "", fmt.Errorf("invalid range %s", byteRange)
+	}
+	for _, offset := range []string{first, last} {
+		if _, err := strconv.ParseUint(offset, 10, 64); offset != "" && err != nil {
+			return "", fmt.Errorf("invalid range %s", byteRange)
+		}
+	}
+	return "bytes=" + byteRange, nil
+}
+
+// request returns the request of the record, in the bucket if the record has none.
+func (t traceRecord) request(timestamp, bucket string) (replayedRequest, error) {
+	at, err := parseTraceTimestamp(timestamp)
+	if err != nil {
+		return replayedRequest{}, err
+	}
+	if t.Op != "put" && t.Op != "get" && t.Op != "head" && t.Op != "delete" {
+		return replayedRequest{}, fmt.Errorf("op must be one of put, get, head or delete but got '%s'", t.Op)
+	}
+	if t.Key == "" {
+		return replayedRequest{}, errors.New("missing key")
+	}
+	byteRange, err := parseTraceRange(t.Range)
+	if err != nil {
+		return replayedRequest{}, err
+	}
+	if byteRange != "" && t.Op != "get" {
+		return replayedRequest{}, errors.New("only a get can have a range")
+	}
+	if t.Bucket != "" {
+		bucket = t.Bucket
+	}
+	return replayedRequest{op: s3op{Event: t.Op, Size: t.Size, Bucket: bucket, Key: t.Key, Range: byteRange}, time: at}, nil
+}
+
+// Sends the requests of a CSV trace to the channel in the order of the file. The first line names the columns, the
+// timestamp, op and key columns are required and the size, range and bucket columns optional.
+func readCSVTrace(r io.Reader, bucket string, requests chan<- replayedRequest) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("missing header line: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"timestamp", "op", "key"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("missing column %s in the header line", required)
+		}
+	}
+	field := func(fields []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+
+	for n := 1; !wasInterrupted(); n++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		record := traceRecord{Op: field(fields, "op"), Key: field(fields, "key"), Range: field(fields, "range"), Bucket: field(fields, "bucket")}
+		if size := field(fields, "size"); size != "" {
+			if record.Size, err = strconv.ParseUint(size, 10, 64); err != nil {
+				return fmt.Errorf("request %d: invalid size %s", n, size)
+			}
+		}
+		request, err := record.request(field(fields, "timestamp"), bucket)
+		if err != nil {
+			return fmt.Errorf("request %d: %v", n, err)
+		}
+		requests <- request
+	}
+	return nil
+}
+
+// Sends the requests of a JSON trace to the channel in the order of the file, a JSON object per line or an array of
+// objects.
+func readJSONTrace(r io.Reader, bucket string, requests chan<- replayedRequest) error {
+	reader := bufio.NewReader(r)
+	decoder := json.NewDecoder(reader)
+	if first, err := firstNonSpace(reader); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if first == '[' {
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+	}
+	for n := 1; decoder.More() && !wasInterrupted(); n++ {
+		var record traceRecord
+		if err := decoder.Decode(&record); err != nil {
+			return fmt.Errorf("request %d: %v", n, err)
+		}
+		request, err := record.request(strings.Trim(string(record.Timestamp), `"`), bucket)
+		if err != nil {
+			return fmt.Errorf("request %d: %v", n, err)
+		}
+		requests <- request
+	}
+	return nil
+}
+
+// Returns the first byte of the reader that isn't white space, without consuming it.
+func firstNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return c, reader.UnreadByte()
+		}
+	}
+}
+
+// Returns the reader of the format of the trace file, CSV for files ending in .csv and JSON otherwise.
+func traceReader(path string) func(io.Reader, string, chan<- replayedRequest) error {
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		return readCSVTrace
+	}
+	return readJSONTrace
+}
+
+// ReplayTrace replays the requests of the trace file of the run to the workers.
+func ReplayTrace(args *parameters, workerChans []*workerChan, credential *credentials.Credentials) {
+	workload := setupWorkloadParams(workerChans, args.concurrency, credential)
+
+	requests := make(chan replayedRequest, 1000)
+	go func() {
+		defer close(requests)
+		f, err := os.Open(args.replayTrace)
+		if err != nil {
+			log.Fatalf("Failed reading the trace %s: %v", args.replayTrace, err)
+		}
+		defer f.Close()
+		if err := traceReader(args.replayTrace)(f, args.bucketname, requests); err != nil {
+			log.Fatalf("Failed reading the trace %s: %v", args.replayTrace, err)
+		}
+	}()
+
+	sendReplayedRequests(args, workload, requests)
+}
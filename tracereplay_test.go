@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readTrace(read func(r io.Reader, bucket string, requests chan<- replayedRequest) error, content string) ([]replayedRequest, error) {
+	requests := make(chan replayedRequest, 10)
+	err := read(strings.NewReader(content), "trace", requests)
+	close(requests)
+	var replayed []replayedRequest
+	for request := range requests {
+		replayed = append(replayed, request)
+	}
+	return replayed, err
+}
+
+func TestReadCSVTrace(t *testing.T) {
+	trace := "timestamp,op,key,size,range\n" +
+		"2024-03-01T10:00:00Z,put,photos/cat.jpg,65536,\n" +
+		"2024-03-01T10:00:00.5Z,get,photos/cat.jpg,65536,0-1023\n" +
+		"2024-03-01T10:00:02Z,delete,photos/cat.jpg,,\n"
+	requests, err := readTrace(readCSVTrace, trace)
+	if err != nil || len(requests) != 3 {
+		t.Fatalf("Expected 3 requests but got %v (%v)", requests, err)
+	}
+	expected := s3op{Event: "get", Size: 65536, Bucket: "trace", Key: "photos/cat.jpg", Range: "bytes=0-1023"}
+	if requests[1].op != expected || requests[1].time.Sub(requests[0].time) != 500*time.Millisecond {
+		t.Fatalf("Expected %+v half a second after the put but got %+v", expected, requests[1])
+	}
+
+	// the columns may come in any order and the optional ones may be missing
+	requests, err = readTrace(readCSVTrace, "key,bucket,op,timestamp\nk1,other,head,12.25\n")
+	if err != nil || len(requests) != 1 || requests[0].op != (s3op{Event: "head", Bucket: "other", Key: "k1"}) || !requests[0].time.Equal(time.Unix(12, 250000000)) {
+		t.Fatalf("Unexpected requests %+v (%v)", requests, err)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"op,key\nget,k1\n",
+		"timestamp,op,key\n1,list,k1\n",
+		"timestamp,op,key\nyesterday,get,k1\n",
+		"timestamp,op,key,size\n1,put,k1,big\n",
+		"timestamp,op,key,range\n1,put,k1,0-10\n",
+		"timestamp,op,key,range\n1,get,k1,10\n",
+		"timestamp,op,key\n1,get,\n",
+	} {
+		if _, err := readTrace(readCSVTrace, invalid); err == nil {
+			t.Fatalf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestReadJSONTrace(t *testing.T) {
+	lines := `{"timestamp": 0, "op": "put", "key": "k1", "size": 1024}
+{"timestamp": 1.5, "op": "get", "key": "k1", "range": "bytes=512-"}
+`
+	requests, err := readTrace(readJSONTrace, lines)
+	if err != nil || len(requests) != 2 || requests[1].op.Range != "bytes=512-" || requests[1].time.Sub(requests[0].time) != 1500*time.Millisecond {
+		t.Fatalf("Unexpected requests %+v (%v)", requests, err)
+	}
+
+	array := ` [{"timestamp": "2024-03-01T10:00:00Z", "op": "head", "key": "k1", "bucket": "b"}]`
+	requests, err = readTrace(readJSONTrace, array)
+	if err != nil || len(requests) != 1 || requests[0].op != (s3op{Event: "head", Bucket: "b", Key: "k1"}) {
+		t.Fatalf("Unexpected requests %+v (%v)", requests, err)
+	}
+
+	if requests, err := readTrace(readJSONTrace, "\n"); err != nil || len(requests) != 0 {
+		t.Fatalf("Expected an empty trace to replay nothing but got %v (%v)", requests, err)
+	}
+	if _, err := readTrace(readJSONTrace, `{"timestamp": 0, "op": "get", "key": "k1"} {"timestamp": -1, "op": "get", "key": "k1"}`); err == nil || !strings.Contains(err.Error(), "request 2") {
+		t.Fatalf("Expected the second request to be rejected but got %v", err)
+	}
+}
+
+func TestReplayTraceFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "incident.csv")
+	if err := ioutil.WriteFile(path, []byte("timestamp,op,key\n0,get,k1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := parse([]string{"-replay-trace=" + path, "-replay-speed=2", "-concurrency=4", "-requests=4"})
+	if err != nil || args.replayTrace != path || args.replaySpeed != 2 {
+		t.Fatalf("Expected the replay to parse but got %+v (%v)", args.replaySpeed, err)
+	}
+	if _, err := readTrace(traceReader("INCIDENT.CSV"), "timestamp,op,key\n0,get,k1\n"); err != nil {
+		t.Fatalf("Expected a .csv file to be read as CSV but got %v", err)
+	}
+	if _, err := readTrace(traceReader("incident.json"), "timestamp,op,key\n0,get,k1\n"); err == nil {
+		t.Fatalf("Expected other files to be read as JSON")
+	}
+	for _, cmdline := range [][]string{
+		{"-replay-trace=" + path, "-operation=get"},
+		{"-replay-trace=" + path, "-replay-accesslog=" + path},
+		{"-replay-trace=" + filepath.Join(dir, "missing.csv")},
+	} {
+		if _, err := parse(cmdline); err == nil {
+			t.Fatalf("Expected %v to be rejected", cmdline)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"time"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// throughputResult holds the throughput of the individual objects downloaded, which is what an application waiting for
+// a whole object sees, unlike the content throughput of the run that adds up all workers.
+type throughputResult struct {
+	Count             int                `json:"objects"`
+	AverageThroughput float64            `json:"averageObjectThroughput (MB/s)"`
+	MinThroughput     float64            `json:"minimumObjectThroughput (MB/s)"`
+	MaxThroughput     float64            `json:"maximumObjectThroughput (MB/s)"`
+	Percentiles       map[string]float64 `json:"objectThroughputPercentiles(MB/s)"`
+
+	// throughputs in KB/s from 1 KB/s up to 100 GB/s
+	throughputs *hdrhistogram.Histogram
+}
+
+func NewThroughputResult() *throughputResult {
+	return &throughputResult{throughputs: hdrhistogram.New(1, 100*1024*1024, 3)}
+}
+
+func (this *throughputResult) record(bytes int64, elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	this.Count++
+	kbs := int64(float64(bytes) / 1024 / elapsed.Seconds())
+	if kbs < 1 {
+		kbs = 1
+	}
+	this.throughputs.RecordValue(kbs)
+}
+
+// merge adds the other result to this one and returns the merged result, which is newly allocated if this one is nil.
+func (this *throughputResult) merge(other *throughputResult) *throughputResult {
+	if other == nil {
+		return this
+	}
+	if this == nil {
+		this = NewThroughputResult()
+	}
+	this.Count += other.Count
+	this.throughputs.Merge(other.throughputs)
+	return this
+}
+
+func (this *throughputResult) setupStats() {
+	if this == nil || this.Count == 0 {
+		return
+	}
+	this.AverageThroughput = roundFloat(this.throughputs.Mean()/1024, 3)
+	this.MinThroughput = roundFloat(float64(this.throughputs.Min())/1024, 3)
+	this.MaxThroughput = roundFloat(float64(this.throughputs.Max())/1024, 3)
+	this.Percentiles = make(map[string]float64)
+	for _, percentile := range percentiles {
+		this.Percentiles[convertFloatToString(percentile)] = roundFloat(float64(this.throughputs.ValueAtQuantile(percentile))/1024, 3)
+	}
+}
+
+func (this *result) recordObjectThroughput(bytes int64, elapsed time.Duration) {
+	if this.ObjectThroughput == nil {
+		this.ObjectThroughput = NewThroughputResult()
+	}
+	this.ObjectThroughput.record(bytes, elapsed)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestObjectThroughput(t *testing.T) {
+	var merged *throughputResult
+	r := NewThroughputResult()
+	// 10 MB in 1s and 2s
+	r.record(10<<20, time.Second)
+	r.record(10<<20, 2*time.Second)
+	merged = merged.merge(r)
+	merged = merged.merge(nil)
+	other := NewThroughputResult()
+	other.record(30<<20, time.Second)
+	merged = merged.merge(other)
+	merged.setupStats()
+
+	// throughputs are kept to 3 significant digits
+	near := func(actual, expected float64) bool {
+		return math.Abs(actual-expected) <= expected/1000
+	}
+	if merged.Count != 3 || !near(merged.MinThroughput, 5) || !near(merged.MaxThroughput, 30) || !near(merged.AverageThroughput, 15) {
+		t.Fatalf("Wrong object throughput: %+v", merged)
+	}
+	if !near(merged.Percentiles["50"], 10) {
+		t.Fatalf("Wrong median object throughput: %v", merged.Percentiles)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// The read modes of the versionedget and versioneddelete operations. Servers look up the latest version of an object and an
+// explicitly requested version through different paths, so their latencies are reported separately.
+const (
+	readLatest  = "latest"
+	readVersion = "version"
+)
+
+func (this *result) recordModeLatency(mode string, l time.Duration) {
+	if this.ModeResults == nil {
+		this.ModeResults = make(map[string]*latencyResult)
+	}
+	m, ok := this.ModeResults[mode]
+	if !ok {
+		m = NewLatencyResult()
+		this.ModeResults[mode] = m
+	}
+	m.record(l)
+}
+
+// versionPicker decides for every versionedget or versioneddelete request whether the latest version or an explicit version
+// of the object is targeted. Each worker has its own picker, which caches the version ids of the keys it has looked up.
+type versionPicker struct {
+	// percentage of requests that target an explicit version
+	ratio    int
+	source   *rand.Rand
+	versions map[string][]string
+	// versions recorded by an earlier put run, shared by all workers and looked up instead of listing the versions
+	recorded map[string][]string
+	// removes picked versions so that a deleted version isn't picked again
+	consume bool
+}
+
+func NewVersionPicker(ratio int, seed int64, recorded map[string][]string, consume bool) *versionPicker {
+	return &versionPicker{ratio: ratio, source: rand.New(rand.NewSource(seed)), versions: make(map[string][]string), recorded: recorded, consume: consume}
+}
+
+// pick returns the version id to target, or an empty string to target the latest version.
+// Version ids are listed before the request is timed so that the lookup doesn't add to the measured latency.
+func (p *versionPicker) pick(svc s3iface.S3API, bucket, key string) (string, error) {
+	if p.source.Intn(100) >= p.ratio {
+		return "", nil
+	}
+	versions, ok := p.versions[key]
+	if !ok {
+		if recorded, ok := p.recorded[key]; ok {
+			versions = append([]string(nil), recorded...)
+		} else {
+			var err error
+			if versions, err = ObjectVersions(svc, bucket, key); err != nil {
+				return "", err
+			}
+		}
+		p.versions[key] = versions
+	}
+	if len(versions) == 0 {
+		return "", errors.New("all versions of key " + key + " have been picked")
+	}
+	i := p.source.Intn(len(versions))
+	versionId := versions[i]
+	if p.consume {
+		p.versions[key] = append(versions[:i], versions[i+1:]...)
+	}
+	return versionId, nil
+}
+
+func readMode(versionId string) string {
+	if versionId == "" {
+		return readLatest
+	}
+	return readVersion
+}
+
+// objectVersion is a version of an object written by a put operation.
+type objectVersion struct {
+	key       string
+	versionId string
+}
+
+// recordVersions keeps the version id of every object the service writes to a versioned bucket, until the watchdog
+// sheds them.
+func (this *result) recordVersions(svc *s3.S3, watchdog *memoryWatchdog) {
+	// parts of multipart operations are sent concurrently
+	var mu sync.Mutex
+	svc.Client.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error != nil {
+			return
+		}
+		var version objectVersion
+		switch out := r.Data.(type) {
+		case *s3.PutObjectOutput:
+			version = objectVersion{aws.StringValue(r.Params.(*s3.PutObjectInput).Key), aws.StringValue(out.VersionId)}
+		case *s3.CompleteMultipartUploadOutput:
+			version = objectVersion{aws.StringValue(r.Params.(*s3.CompleteMultipartUploadInput).Key), aws.StringValue(out.VersionId)}
+		case *s3.CopyObjectOutput:
+			version = objectVersion{aws.StringValue(r.Params.(*s3.CopyObjectInput).Key), aws.StringValue(out.VersionId)}
+		}
+		if version.versionId == "" {
+			// not a write or the bucket is not versioned
+			return
+		}
+		if watchdog.sheds("version-file") {
+			return
+		}
+		mu.Lock()
+		this.versions = append(this.versions, version)
+		mu.Unlock()
+	})
+}
+
+// Writes the versions as 'key,versionId' CSV lines.
+func writeVersionFile(w io.Writer, versions []objectVersion) error {
+	writer := csv.NewWriter(w)
+	for _, v := range versions {
+		if err := writer.Write([]string{v.key, v.versionId}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Loads the versions written by writeVersionFile, mapping every key to its version ids.
+func loadVersionFile(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 2
+	versions := make(map[string][]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid version file %s: %s", path, err)
+		}
+		versions[record[0]] = append(versions[record[0]], record[1])
+	}
+	return versions, nil
+}
+
+func EnableVersioning(svc s3iface.S3API, bucket string) error {
+	return SetVersioning(svc, bucket, s3.BucketVersioningStatusEnabled)
+}
+
+// prepareVersions enables versioning on the bucket before versions-per-key writes the versions of every key.
+func prepareVersions(args parameters) error {
+	credential, err := loadCredentialProfile(args.profile, args.nosign)
+	if err != nil {
+		return err
+	}
+	svc := MakeS3Service(MakeHTTPClient(), args.retrySleep, args.retries, args.endpoints[0], args.region, args.consistencyControl, credential)
+	args.timeouts.install(svc)
+	return EnableVersioning(svc, args.bucketname)
+}
+
+// Returns the number of keys of the versions and the number of keys with fewer versions than expected.
+func countVersions(versions []objectVersion, expected int) (keys, incomplete int) {
+	perKey := make(map[string]int)
+	for _, v := range versions {
+		perKey[v.key]++
+	}
+	for _, n := range perKey {
+		if n < expected {
+			incomplete++
+		}
+	}
+	return len(perKey), incomplete
+}
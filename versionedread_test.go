@@ -0,0 +1,213 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func (this *mockS3Client) ListObjectVersions(in *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
+	return this.S3OpHandler(in).(*s3.ListObjectVersionsOutput), nil
+}
+
+func versionListing(lookups *int) *mockS3Client {
+	return NewMockS3Client(func(in interface{}) interface{} {
+		*lookups++
+		return &s3.ListObjectVersionsOutput{Versions: []*s3.ObjectVersion{
+			{Key: aws.String("k1"), VersionId: aws.String("v1")},
+			{Key: aws.String("k10"), VersionId: aws.String("v10")},
+			{Key: aws.String("k1"), VersionId: aws.String("v2")},
+		}}
+	})
+}
+
+func TestObjectVersionsOnlyMatchesKey(t *testing.T) {
+	lookups := 0
+	versions, err := ObjectVersions(versionListing(&lookups), "b", "k1")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(versions) != 2 || versions[0] != "v1" || versions[1] != "v2" {
+		t.Fatalf("wrong versions: %v", versions)
+	}
+
+	if _, err := ObjectVersions(versionListing(&lookups), "b", "k2"); err == nil {
+		t.Fatalf("Expected an error for a key without versions")
+	}
+}
+
+func TestVersionPickerRatio(t *testing.T) {
+	lookups := 0
+	svc := versionListing(&lookups)
+
+	latest := NewVersionPicker(0, 1, nil, false)
+	for i := 0; i < 100; i++ {
+		if version, _ := latest.pick(svc, "b", "k1"); version != "" {
+			t.Fatalf("ratio 0 should always read the latest version but got %s", version)
+		}
+	}
+	if lookups != 0 {
+		t.Fatalf("versions should not be listed when reading the latest version")
+	}
+
+	explicit := NewVersionPicker(100, 1, nil, false)
+	for i := 0; i < 100; i++ {
+		if version, err := explicit.pick(svc, "b", "k1"); err != nil || (version != "v1" && version != "v2") {
+			t.Fatalf("ratio 100 should always read an explicit version but got %q, %v", version, err)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("versions of a key should be listed once but were listed %d times", lookups)
+	}
+}
+
+func TestModeResults(t *testing.T) {
+	r := NewResult()
+	r.recordModeLatency(readLatest, 10*time.Millisecond)
+	r.recordModeLatency(readVersion, 30*time.Millisecond)
+
+	other := NewResult()
+	other.recordModeLatency(readVersion, 50*time.Millisecond)
+
+	merged := NewResult()
+	mergeResult(&merged, &r)
+	mergeResult(&merged, &other)
+	for _, m := range merged.ModeResults {
+		m.setupStats()
+	}
+
+	if merged.ModeResults[readLatest].Count != 1 || merged.ModeResults[readVersion].Count != 2 {
+		t.Fatalf("wrong mode counts: %d %d", merged.ModeResults[readLatest].Count, merged.ModeResults[readVersion].Count)
+	}
+
+	if merged.ModeResults[readVersion].AverageRequestTime != 40 {
+		t.Fatalf("wrong average for explicit version reads: %v", merged.ModeResults[readVersion].AverageRequestTime)
+	}
+}
+
+func TestVersionPickerRecordedVersions(t *testing.T) {
+	lookups := 0
+	svc := versionListing(&lookups)
+	recorded := map[string][]string{"k1": {"r1", "r2"}}
+
+	picker := NewVersionPicker(100, 1, recorded, true)
+	picked := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		version, err := picker.pick(svc, "b", "k1")
+		if err != nil || (version != "r1" && version != "r2") || picked[version] {
+			t.Fatalf("expected each recorded version to be picked once but got %q, %v", version, err)
+		}
+		picked[version] = true
+	}
+	if _, err := picker.pick(svc, "b", "k1"); err == nil {
+		t.Fatalf("expected an error once all versions have been picked")
+	}
+	if lookups != 0 {
+		t.Fatalf("recorded versions should not be listed")
+	}
+	if len(recorded["k1"]) != 2 {
+		t.Fatalf("recorded versions shared by the workers must not be modified: %v", recorded["k1"])
+	}
+
+	if version, err := picker.pick(svc, "b", "k10"); err != nil || version != "v10" || lookups != 1 {
+		t.Fatalf("versions of keys that weren't recorded should be listed but got %q, %v", version, err)
+	}
+}
+
+func TestVersionFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "versions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	written := []objectVersion{{"k1", "v1"}, {"k,2", "v2"}, {"k1", "v3"}}
+	if err := writeVersionFile(f, written); err != nil {
+		t.Fatalf("failed writing version file: %v", err)
+	}
+	f.Close()
+
+	versions, err := loadVersionFile(f.Name())
+	if err != nil {
+		t.Fatalf("failed loading version file: %v", err)
+	}
+	if len(versions["k1"]) != 2 || versions["k1"][1] != "v3" || versions["k,2"][0] != "v2" {
+		t.Fatalf("wrong versions: %v", versions)
+	}
+}
+
+func TestListVersionsOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.ListObjectVersionsInput)
+		if i.KeyMarker == nil {
+			return &s3.ListObjectVersionsOutput{IsTruncated: aws.Bool(true), NextKeyMarker: aws.String("k1"), NextVersionIdMarker: aws.String("v1"),
+				Versions: []*s3.ObjectVersion{{Key: aws.String("k1"), VersionId: aws.String("v1")}}, DeleteMarkers: []*s3.DeleteMarkerEntry{{Key: aws.String("k1")}}}
+		}
+		if *i.KeyMarker != "k1" || *i.VersionIdMarker != "v1" {
+			t.Fatalf("Expected markers k1 v1 but got: %s %s", *i.KeyMarker, *i.VersionIdMarker)
+		}
+		return &s3.ListObjectVersionsOutput{IsTruncated: aws.Bool(false)}
+	}
+
+	svc := NewMockS3Client(handler)
+
+	keyMarker, versionIdMarker, versions, err := ListVersions(svc, "b", "k", "", "", 0)
+	if err != nil || keyMarker != "k1" || versionIdMarker != "v1" || versions != 2 {
+		t.Fatalf("Expected markers k1 v1 after 2 versions but got: %q %q after %d, %v", keyMarker, versionIdMarker, versions, err)
+	}
+
+	if keyMarker, _, _, err = ListVersions(svc, "b", "k", keyMarker, versionIdMarker, 0); err != nil || keyMarker != "" {
+		t.Fatalf("Expected no markers after the last page but got: %q, %v", keyMarker, err)
+	}
+}
+
+func TestDeleteVersionOp(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.DeleteObjectInput)
+		if *i.Key != "k1" || aws.StringValue(i.VersionId) != "v1" {
+			t.Fatalf("Expected version v1 of k1 to be deleted but got: %s %v", *i.Key, i.VersionId)
+		}
+		return in
+	}
+
+	svc := NewMockS3Client(handler)
+
+	if err := DeleteVersion(svc, "b", "k1", "v1"); err != nil {
+		t.Fatalf("Failed delete version operation with error: %v", err)
+	}
+}
+
+func (this *mockS3Client) PutBucketVersioning(in *s3.PutBucketVersioningInput) (*s3.PutBucketVersioningOutput, error) {
+	this.S3OpHandler(in)
+
+	return &s3.PutBucketVersioningOutput{}, nil
+}
+
+func TestEnableVersioning(t *testing.T) {
+	handler := func(in interface{}) interface{} {
+		i := in.(*s3.PutBucketVersioningInput)
+
+		if *i.Bucket != "b" || *i.VersioningConfiguration.Status != "Enabled" {
+			t.Fatalf("Expected versioning of b to be enabled but got %s: %s", *i.Bucket, *i.VersioningConfiguration.Status)
+		}
+
+		return in
+	}
+
+	if err := EnableVersioning(NewMockS3Client(handler), "b"); err != nil {
+		t.Fatalf("Failed enabling versioning with error: %v", err)
+	}
+}
+
+func TestCountVersions(t *testing.T) {
+	versions := []objectVersion{{"k1", "v1"}, {"k1", "v2"}, {"k2", "v1"}, {"k1", "v3"}, {"k2", "v2"}, {"k3", "v1"}}
+
+	if keys, incomplete := countVersions(versions, 3); keys != 3 || incomplete != 2 {
+		t.Fatalf("Expected 3 keys of which 2 have fewer than 3 versions but got %d and %d", keys, incomplete)
+	}
+}